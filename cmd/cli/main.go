@@ -0,0 +1,443 @@
+// Command cli 提供不启动服务的离线工具：校验配置文件、离线生成设备标识与导出用量。
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"go_sign/internal/app"
+	"go_sign/internal/auth"
+	"go_sign/internal/browser"
+	"go_sign/internal/config"
+	"go_sign/internal/crypt"
+	"go_sign/internal/integrity"
+	"go_sign/internal/platform"
+	"go_sign/internal/storage"
+	"go_sign/internal/usage"
+	"go_sign/internal/webhook"
+	"go_sign/internal/xhs"
+	"gopkg.in/yaml.v3"
+)
+
+const usageText = `用法:
+  go_sign-cli config check [参数]   校验配置文件与 go_sign 启动参数，输出生效的配置
+  go_sign-cli generate a1 [参数]    离线生成设备标识（a1 与 webId），每行输出一个 JSON
+  go_sign-cli usage export [参数]   从配置的存储后端导出各 Key 或账号的用量
+各子命令的参数见 go_sign-cli <子命令> -h。`
+
+func main() {
+	if len(os.Args) < 3 {
+		fmt.Fprintln(os.Stderr, usageText)
+		os.Exit(2)
+	}
+	switch os.Args[1] + " " + os.Args[2] {
+	case "config check":
+		os.Exit(runConfigCheck(os.Args[3:]))
+	case "generate a1":
+		os.Exit(runGenerate(os.Args[3:]))
+	case "usage export":
+		os.Exit(runUsageExport(os.Args[3:]))
+	}
+	fmt.Fprintln(os.Stderr, usageText)
+	os.Exit(2)
+}
+
+// runConfigCheck 只校验配置文件与启动参数并输出生效的配置，参数与 go_sign 服务的同名参数一致。
+func runConfigCheck(args []string) int {
+	fs := flag.NewFlagSet("config check", flag.ExitOnError)
+	configPath := fs.String("config", "", "YAML 配置文件路径")
+	var opts checkOptions
+	fs.StringVar(&opts.StealthPath, "stealth", "./stealth.min.js", "stealth.min.js 文件路径")
+	fs.StringVar(&opts.CanaryStealthPath, "canary-stealth", "", "灰度 stealth.js 文件路径")
+	fs.Float64Var(&opts.CanaryWeight, "canary-weight", 0.1, "分流到灰度 stealth.js 页面的请求比例，取值 (0, 1)")
+	fs.StringVar(&opts.BrowserVersionCheck, "browser-version-check", browser.VersionCheckWarn, "启动时浏览器版本检查：warn、strict、off")
+	fs.StringVar(&opts.Platforms, "platforms", xhs.PlatformWeb, "启用的签名平台，逗号分隔；配置文件中的 platforms 优先")
+	fs.IntVar(&opts.PoolSize, "pool-size", 1, "页面池大小")
+	fs.BoolVar(&opts.Mock, "mock", false, "mock 模式")
+	fs.StringVar(&opts.RecordPath, "record", "", "录制文件路径")
+	fs.StringVar(&opts.ReplayPath, "replay", "", "回放文件路径")
+	fs.StringVar(&opts.JobStore, "job-store", "", "批量签名任务库（BoltDB）文件路径")
+	fs.StringVar(&opts.AccountStore, "account-store", "", "账号库（BoltDB）文件路径")
+	_ = fs.Parse(args)
+	problems := checkConfig(*configPath, opts, os.Stdout)
+	for _, p := range problems {
+		fmt.Fprintln(os.Stderr, "错误:", p)
+	}
+	if len(problems) > 0 {
+		return 1
+	}
+	fmt.Fprintln(os.Stderr, "配置校验通过")
+	return 0
+}
+
+// runGenerate 离线生成设备标识，不启动浏览器。
+func runGenerate(args []string) int {
+	fs := flag.NewFlagSet("generate a1", flag.ExitOnError)
+	count := fs.Int("n", 1, "生成的数量")
+	userAgent := fs.String("user-agent", "", "决定 a1 中平台编号的 User-Agent，默认为 Windows")
+	_ = fs.Parse(args)
+	enc := json.NewEncoder(os.Stdout)
+	for i := 0; i < *count; i++ {
+		_ = enc.Encode(xhs.NewIdentity(*userAgent))
+	}
+	return 0
+}
+
+// runUsageExport 从配置的存储后端导出各 Key 或账号的用量。
+func runUsageExport(args []string) int {
+	fs := flag.NewFlagSet("usage export", flag.ExitOnError)
+	configPath := fs.String("config", "", "YAML 配置文件路径，从其中的 storage 读取用量")
+	accountStore := fs.String("account-store", "", "未配置 storage 时读取的账号库（BoltDB）文件路径")
+	from := fs.String("from", "", "起始日期 YYYY-MM-DD（含），默认与 -to 相同")
+	to := fs.String("to", "", "结束日期 YYYY-MM-DD（含），默认为当天")
+	by := fs.String("by", usage.KindKey, "汇总维度：key 或 account")
+	tenant := fs.String("tenant", "", "只导出该租户的 Key")
+	daily := fs.Bool("daily", false, "按日分行输出")
+	format := fs.String("format", usage.FormatCSV, "输出格式：csv 或 json")
+	_ = fs.Parse(args)
+	q := usage.Query{From: *from, To: *to, Kind: *by, Tenant: *tenant, Daily: *daily}
+	if err := exportUsage(*configPath, *accountStore, q, *format, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "错误:", err)
+		return 1
+	}
+	return 0
+}
+
+// checkOptions 为 config check 需要结合配置文件校验的 go_sign 服务启动参数。
+type checkOptions struct {
+	StealthPath         string
+	CanaryStealthPath   string
+	CanaryWeight        float64
+	BrowserVersionCheck string
+	Platforms           string
+	PoolSize            int
+	Mock                bool
+	RecordPath          string
+	ReplayPath          string
+	JobStore            string
+	AccountStore        string
+}
+
+// checkConfig 校验配置文件（未知字段、取值、IP / CIDR 与互斥选项）、启动参数、引用的文件与平台配置项，
+// 全部通过时将合并默认值与启动参数后的生效配置以 YAML 写入 w，密钥脱敏。返回发现的全部问题。
+// 密钥引用（vault:、env:）不解析，原样输出。
+func checkConfig(path string, opts checkOptions, w io.Writer) []string {
+	// 平台工厂的日志与检查结果混在一起不便阅读，只输出告警以上
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelWarn})))
+	cfg, err := config.Load(path)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	var problems []string
+	fail := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	needFile := func(name, file string) {
+		if _, err := os.Stat(file); err != nil {
+			fail("%s: 文件 %q 不存在或不可读", name, file)
+		}
+	}
+	needDir := func(name, file string) {
+		if dir := filepath.Dir(file); dir != "" {
+			if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+				fail("%s: 目录 %q 不存在", name, dir)
+			}
+		}
+	}
+
+	// 启动参数
+	if opts.Mock && (opts.RecordPath != "" || opts.ReplayPath != "") || opts.RecordPath != "" && opts.ReplayPath != "" {
+		fail("--mock、--record 与 --replay 不能同时使用")
+	}
+	if !browser.ValidVersionCheck(opts.BrowserVersionCheck) {
+		fail("--browser-version-check: 不支持的模式 %q", opts.BrowserVersionCheck)
+	}
+	if opts.PoolSize <= 0 {
+		fail("--pool-size 须大于 0")
+	}
+	if !opts.Mock && opts.ReplayPath == "" {
+		needFile("--stealth", opts.StealthPath)
+	}
+	if opts.CanaryStealthPath != "" {
+		needFile("--canary-stealth", opts.CanaryStealthPath)
+		if opts.CanaryWeight <= 0 || opts.CanaryWeight >= 1 {
+			fail("--canary-weight 须在 (0, 1) 之间")
+		}
+	}
+	if opts.ReplayPath != "" {
+		needFile("--replay", opts.ReplayPath)
+	}
+	if opts.RecordPath != "" {
+		needDir("--record", opts.RecordPath)
+	}
+	if opts.JobStore != "" {
+		needDir("--job-store", opts.JobStore)
+	}
+	if opts.AccountStore != "" {
+		needDir("--account-store", opts.AccountStore)
+	}
+
+	// 配置文件引用的文件与可执行文件
+	for i, p := range cfg.Platforms {
+		if p.Plugin != nil {
+			if _, err := exec.LookPath(p.Plugin.Command); err != nil {
+				fail("platforms[%d]: plugin.command %q 不存在或不可执行", i, p.Plugin.Command)
+			}
+		}
+		if s := p.Script; s != nil {
+			if s.File != "" {
+				needFile(fmt.Sprintf("platforms[%d]: script.file", i), s.File)
+			}
+			if s.Backend == "node" {
+				node := s.Node
+				if node == "" {
+					node = "node"
+				}
+				if _, err := exec.LookPath(node); err != nil {
+					fail("platforms[%d]: 找不到 Node.js 可执行文件 %q", i, node)
+				}
+			}
+		}
+	}
+	if l := cfg.Log; l != nil && l.File != nil {
+		needDir("log.file.path", l.File.Path)
+	}
+	if j := cfg.JWT; j != nil && j.PublicKeyFile != "" {
+		if _, err := auth.NewJWTVerifier(j); err != nil {
+			fail("jwt.public_key_file: %v", err)
+		}
+	}
+	if rs := cfg.ResponseSigning; rs != nil && rs.PrivateKeyFile != "" {
+		if _, err := integrity.New(rs); err != nil {
+			fail("response_signing.private_key_file: %v", err)
+		}
+	}
+	if s := cfg.Storage; s != nil && (s.Driver == storage.DriverBolt || s.Driver == storage.DriverSQLite) {
+		needDir("storage.dsn", s.DSN)
+	}
+
+	// 平台名称与各平台的 options 由平台工厂校验
+	names, decoders, err := app.Platforms(cfg, opts.Platforms)
+	if err != nil {
+		fail("platforms: %v", err)
+	} else if _, err := platform.NewSet(names, decoders); err != nil {
+		fail("platforms: %v", err)
+	}
+	if len(problems) > 0 {
+		return problems
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(effectiveConfig(cfg, names, opts)); err != nil {
+		return []string{fmt.Sprintf("输出生效配置失败: %v", err)}
+	}
+	return nil
+}
+
+// effectiveConfig 返回填入默认值、启动参数并脱敏后的配置副本。
+func effectiveConfig(cfg *config.Config, names []string, opts checkOptions) *config.Config {
+	out := *cfg
+	if len(out.Platforms) == 0 {
+		for _, name := range names {
+			out.Platforms = append(out.Platforms, config.Platform{Name: name})
+		}
+	}
+	out.Platforms = slices.Clone(out.Platforms)
+	for i := range out.Platforms {
+		out.Platforms[i].Options = redactProxies(out.Platforms[i].Options)
+	}
+	out.Tenants = slices.Clone(cfg.Tenants)
+	if len(out.Tenants) == 0 {
+		out.Tenants = []config.Tenant{{Name: config.DefaultTenant}}
+	}
+	for i := range out.Tenants {
+		if out.Tenants[i].PoolSize == 0 {
+			out.Tenants[i].PoolSize = opts.PoolSize
+		}
+	}
+	out.APIKeys = slices.Clone(cfg.APIKeys)
+	for i := range out.APIKeys {
+		k := &out.APIKeys[i]
+		k.Key = redactSecret(k.Key)
+		k.Tenant = k.TenantName()
+		if k.Priority == "" {
+			k.Priority = "normal"
+		}
+		if len(k.Scopes) == 0 {
+			k.Scopes = []string{config.ScopeAdmin}
+		}
+	}
+	log := config.Log{Format: "json", Level: "info"}
+	if l := cfg.Log; l != nil {
+		log = *l
+		if log.Format == "" {
+			log.Format = "json"
+		}
+		if log.Level == "" {
+			log.Level = "info"
+		}
+	}
+	out.Log = &log
+	if s := cfg.Shadow; s != nil {
+		shadow := *s
+		shadow.APIKey = redactSecret(shadow.APIKey)
+		out.Shadow = &shadow
+	}
+	if e := cfg.ErrorReport; e != nil {
+		er := *e
+		er.SentryDSN = redactSecret(er.SentryDSN)
+		er.WebhookURL = redactSecret(er.WebhookURL)
+		if er.SignErrorThreshold == 0 {
+			er.SignErrorThreshold = 10
+		}
+		if er.SignErrorWindow == 0 {
+			er.SignErrorWindow = time.Minute
+		}
+		out.ErrorReport = &er
+	}
+	enc := config.Encryption{KeyEnv: "GO_SIGN_ENCRYPTION_KEY"}
+	if e := cfg.Encryption; e != nil {
+		enc = *e
+		enc.Key = redactSecret(enc.Key)
+		if enc.KeyEnv == "" {
+			enc.KeyEnv = "GO_SIGN_ENCRYPTION_KEY"
+		}
+	}
+	out.Encryption = &enc
+	if j := cfg.JWT; j != nil {
+		jwt := *j
+		jwt.Secret = redactSecret(jwt.Secret)
+		if jwt.ScopesClaim == "" {
+			jwt.ScopesClaim = "scope"
+		}
+		if jwt.TenantClaim == "" {
+			jwt.TenantClaim = "tenant"
+		}
+		out.JWT = &jwt
+	}
+	if rs := cfg.ResponseSigning; rs != nil {
+		signing := *rs
+		signing.Secret = redactSecret(signing.Secret)
+		if signing.Algorithm == "" {
+			signing.Algorithm = config.ResponseSigningHMAC
+		}
+		out.ResponseSigning = &signing
+	}
+	if re := cfg.RequestEncryption; re != nil {
+		enc := *re
+		enc.Key = redactSecret(enc.Key)
+		if enc.KeyEnv == "" {
+			enc.KeyEnv = crypt.DefaultRequestKeyEnv
+		}
+		out.RequestEncryption = &enc
+	}
+	out.Webhooks = make([]config.Webhook, len(cfg.Webhooks))
+	for i, w := range cfg.Webhooks {
+		w.Secret = redactSecret(w.Secret)
+		if w.Timeout == 0 {
+			w.Timeout = webhook.DefaultTimeout
+		}
+		if w.MaxRetries == 0 {
+			w.MaxRetries = webhook.DefaultMaxRetries
+		}
+		out.Webhooks[i] = w
+	}
+	if s := cfg.Storage; s != nil {
+		st := *s
+		st.DSN = redactDSN(st.DSN)
+		out.Storage = &st
+	}
+	if m := cfg.Maintenance; m != nil && m.Timeout == 0 {
+		maintenance := *m
+		maintenance.Timeout = 30 * time.Minute
+		out.Maintenance = &maintenance
+	}
+	return &out
+}
+
+// redactSecret 脱敏配置中的密钥，密钥引用（vault:、env:）不是密钥本身，原样保留。
+func redactSecret(v string) string {
+	if v == "" || strings.HasPrefix(v, "vault:") || strings.HasPrefix(v, "env:") {
+		return v
+	}
+	return "[redacted]"
+}
+
+// dsnPassword 匹配 postgres 键值对形式连接串中的密码，如 password=secret、password='a b'。
+var dsnPassword = regexp.MustCompile(`(?i)(\bpassword\s*=\s*)('(?:[^'\\]|\\.)*'|[^\s&]+)`)
+
+// redactDSN 脱敏 storage.dsn 中的凭据：URL 形式（postgres://、redis://）的密码替换为 xxxxx，
+// postgres 键值对形式的 password 同样替换，bolt、sqlite 的文件路径与密钥引用原样保留。
+func redactDSN(dsn string) string {
+	if strings.HasPrefix(dsn, "vault:") || strings.HasPrefix(dsn, "env:") {
+		return dsn
+	}
+	if u, err := url.Parse(dsn); err == nil && u.Scheme != "" && u.User != nil {
+		return u.Redacted()
+	}
+	return dsnPassword.ReplaceAllString(dsn, "${1}xxxxx")
+}
+
+// redactProxies 返回平台配置项的副本，proxies 中代理地址的密码替换为 xxxxx。
+func redactProxies(n yaml.Node) yaml.Node {
+	if n.Kind != yaml.MappingNode {
+		return n
+	}
+	n.Content = slices.Clone(n.Content)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value != "proxies" || n.Content[i+1].Kind != yaml.SequenceNode {
+			continue
+		}
+		seq := *n.Content[i+1]
+		seq.Content = slices.Clone(seq.Content)
+		for j, item := range seq.Content {
+			if u, err := url.Parse(item.Value); err == nil && u.User != nil {
+				redacted := *item
+				redacted.Value = u.Redacted()
+				seq.Content[j] = &redacted
+			}
+		}
+		n.Content[i+1] = &seq
+	}
+	return n
+}
+
+// exportUsage 打开配置文件 path 中的存储后端（未配置时为 accountStore 指定的 BoltDB），按 q 以 format 将用量写入 w。
+// BoltDB 文件被运行中的服务占用时无法打开，应改用管理接口 /admin/usage/export。
+func exportUsage(path, accountStore string, q usage.Query, format string, w io.Writer) error {
+	if format != usage.FormatCSV && format != usage.FormatJSON {
+		return fmt.Errorf("-format 须为 csv 或 json")
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+	if driver, _ := app.StoreDriver(cfg, accountStore); driver == storage.DriverMemory {
+		return fmt.Errorf("未配置 storage 或 -account-store，用量只保存在运行中服务的内存里，请使用 /admin/usage/export")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	store, err := app.OpenStore(ctx, cfg, accountStore)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	rows, err := usage.ExportFrom(ctx, store, q)
+	if err != nil {
+		return err
+	}
+	return usage.WriteRows(w, format, rows)
+}
@@ -0,0 +1,997 @@
+// Command server 启动签名 HTTP 服务：签名接口、管理接口与运维接口。离线工具见 cmd/cli，
+// 只运行全局任务的后台进程见 cmd/worker。
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/account"
+	"go_sign/internal/apikey"
+	"go_sign/internal/app"
+	"go_sign/internal/audit"
+	"go_sign/internal/auth"
+	"go_sign/internal/browser"
+	"go_sign/internal/config"
+	"go_sign/internal/crypt"
+	"go_sign/internal/fixture"
+	"go_sign/internal/gql"
+	"go_sign/internal/integrity"
+	"go_sign/internal/ipfilter"
+	"go_sign/internal/jobs"
+	"go_sign/internal/leader"
+	"go_sign/internal/logging"
+	"go_sign/internal/metrics"
+	"go_sign/internal/pagepool"
+	"go_sign/internal/platform"
+	"go_sign/internal/report"
+	"go_sign/internal/schedule"
+	"go_sign/internal/secrets"
+	"go_sign/internal/shadow"
+	"go_sign/internal/stealth"
+	"go_sign/internal/storage"
+	"go_sign/internal/timing"
+	"go_sign/internal/usage"
+	"go_sign/internal/webhook"
+	"go_sign/internal/wire"
+	"go_sign/internal/xhs"
+	"go_sign/pkg/middleware"
+	"gopkg.in/yaml.v3"
+)
+
+func main() {
+	// 解析配置
+	configPath := flag.String("config", "", "YAML 配置文件路径，为空时不加载")
+	stealthPath := flag.String("stealth", "./stealth.min.js", "stealth.min.js 文件路径")
+	canaryStealthPath := flag.String("canary-stealth", "", "灰度 stealth.js 文件路径，非空时额外预热一组注入该脚本的页面并分流部分请求")
+	canaryWeight := flag.Float64("canary-weight", 0.1, "分流到灰度 stealth.js 页面的请求比例，取值 (0, 1)")
+	addr := flag.String("addr", ":5005", "HTTP 监听地址")
+	adminAddr := flag.String("admin-addr", "127.0.0.1:5006", "运维接口（/metrics、/debug/pprof、/admin）的监听地址，应绑定本机或内网网卡")
+	basePath := flag.String("base-path", "/", "全部路由的前缀，如 /go_sign，用于反向代理按路径转发")
+	waitUntil := flag.String("wait-until", xhs.WaitUntilDOMContentLoaded, "首页导航等待策略：load、domcontentloaded、networkidle")
+	navTimeout := flag.Duration("nav-timeout", 30*time.Second, "首页导航超时时间")
+	signFuncTimeout := flag.Duration("sign-func-timeout", 10*time.Second, "导航后等待 window._webmsxyw 就绪的超时时间，0 表示不等待")
+	poolSize := flag.Int("pool-size", 1, "页面池大小，每个页面独占一个浏览器上下文")
+	warmupConcurrency := flag.Int("warmup-concurrency", 4, "启动时并发预热页面的上限")
+	pacePerMinute := flag.Int("pace-per-minute", 0, "同一 a1 每分钟最多签名次数，0 表示不限制")
+	paceJitter := flag.Duration("pace-jitter", 0, "每次签名前叠加的随机延迟上限")
+	paceMaxWait := flag.Duration("pace-max-wait", 5*time.Second, "a1 节流允许的最长等待，超出返回 429")
+	adaptiveMax := flag.Int("adaptive-concurrency", 0, "启用自适应并发控制，按签名耗时自动探测浏览器可并行执行的签名数，值为上限；0 表示不启用")
+	hedgeDelay := flag.Duration("hedge-delay", 0, "携带 X-Hedge: 1 的签名请求超过该时长未返回时，在另一页面再签名一次并取先完成者，0 表示不启用")
+	shedBudget := flag.Duration("shed-budget", 0, "页面池排队预算，最早的请求排队超出后低优先级请求直接返回 429，0 表示不启用")
+	banThreshold := flag.Int("ban-threshold", 3, "同一账号在 --ban-window 内收到该次数的上游风控响应（461、406）后进入冷却，0 表示不冷却")
+	banWindow := flag.Duration("ban-window", time.Minute, "统计上游风控响应的时间窗口")
+	banCooldown := flag.Duration("ban-cooldown", 30*time.Minute, "账号因上游风控进入冷却的时长")
+	recordPath := flag.String("record", "", "录制模式：将签名请求与结果追加到该 JSON Lines 文件")
+	replayPath := flag.String("replay", "", "回放模式：从录制文件返回签名结果，不启动浏览器")
+	mock := flag.Bool("mock", false, "mock 模式：立即返回格式合法的假签名，不启动浏览器，用于客户端集成测试")
+	jobConcurrency := flag.Int("job-concurrency", 4, "单个批量签名任务并发签名的请求数上限")
+	jobMaxItems := flag.Int("job-max-items", 1000, "单个批量签名任务最多包含的请求数")
+	jobRetention := flag.Duration("job-retention", time.Hour, "批量签名任务完成后保留结果的时长")
+	jobStore := flag.String("job-store", "", "批量签名任务库（BoltDB）文件路径，重启后恢复未完成的任务；为空时只保存在内存中")
+	accountStore := flag.String("account-store", "", "账号库（BoltDB）文件路径，保存导入的站点账号 cookie；为空时只保存在内存中，配置文件的 storage 优先")
+	platformNames := flag.String("platforms", xhs.PlatformWeb, "启用的签名平台，逗号分隔，如 xhs,xhs-creator；配置文件中的 platforms 优先")
+	signTimeout := flag.Duration("sign-timeout", 30*time.Second, "签名请求的默认超时，也是请求头 X-Timeout-Ms 的上限，0 表示不限制")
+	slowThreshold := flag.Duration("slow-threshold", time.Second, "签名请求耗时超过该阈值时输出带各阶段耗时的告警日志，0 表示不输出")
+	browserVersionCheck := flag.String("browser-version-check", browser.VersionCheckWarn, "启动时浏览器版本检查：warn（不在测试范围内时告警）、strict（拒绝启动）、off")
+	healthInterval := flag.Duration("health-interval", 30*time.Second, "后台健康检查间隔，用于更新 go_sign_health 指标与状态变化告警，0 表示不检查")
+	logSecrets := flag.Bool("log-secrets", false, "日志中输出 a1、web_session、x-s 等敏感字段的原值，仅用于调试")
+	globalTasks := flag.Bool("global-tasks", true, "运行全局任务（定时自检、账号清理、stealth.js 更新检查），由 go_sign-worker 运行时设为 false")
+	degradedStart := flag.Bool("degraded-start", false, "--stealth 文件不存在时不退出，以降级模式启动并等待通过 PUT /admin/stealth 上传脚本后再初始化签名平台")
+	flag.Parse()
+
+	if flag.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "未知的子命令 %q，config check、generate a1、usage export 等离线工具见 go_sign-cli\n", flag.Arg(0))
+		os.Exit(2)
+	}
+
+	// 加载配置前使用默认的 JSON 格式与 info 级别，默认对 a1、x-s 等敏感字段脱敏
+	app.DefaultLogger(*logSecrets)
+
+	slog.Info("启动参数", "stealth_path", *stealthPath, "addr", *addr, "wait_until", *waitUntil, "nav_timeout", *navTimeout, "pool_size", *poolSize)
+
+	if *canaryStealthPath != "" {
+		if *canaryWeight <= 0 || *canaryWeight >= 1 {
+			slog.Error("--canary-weight 须在 (0, 1) 之间", "canary_weight", *canaryWeight)
+			os.Exit(1)
+		}
+		if _, err := os.Stat(*canaryStealthPath); err != nil {
+			slog.Error("灰度 stealth.js 文件不存在", "err", err, "path", *canaryStealthPath)
+			os.Exit(1)
+		}
+		slog.Info("灰度 stealth.js 已开启", "path", *canaryStealthPath, "weight", *canaryWeight)
+	}
+	if !browser.ValidVersionCheck(*browserVersionCheck) {
+		slog.Error("不支持的浏览器版本检查模式", "browser_version_check", *browserVersionCheck)
+		os.Exit(1)
+	}
+
+	// 配置项中的 vault:、env: 引用替换为密钥后端中的值
+	cfg, resolver, err := app.LoadConfig(*configPath)
+	if err != nil {
+		slog.Error("加载配置文件失败", "err", err, "path", *configPath)
+		os.Exit(1)
+	}
+	slog.Info("配置加载完成", "path", *configPath, "api_keys", len(cfg.APIKeys), "tenants", len(cfg.Tenants))
+
+	// 按配置重新设置日志格式与级别，配置了日志文件时同时写入日志文件
+	logFile, err := app.SetupLogging(cfg, *logSecrets)
+	if err != nil {
+		slog.Error("设置日志失败", "err", err)
+		os.Exit(1)
+	}
+	app.SetGinMode(cfg)
+	if err := report.Init(cfg.ErrorReport); err != nil {
+		slog.Error("开启错误上报失败", "err", err)
+		os.Exit(1)
+	}
+	webhook.Init(cfg.Webhooks)
+	// 落盘的账号凭据（如录制文件中的 cookie）按配置加密，未配置密钥时为 nil
+	cipher, err := crypt.FromConfig(cfg.Encryption)
+	if err != nil {
+		slog.Error("加载加密密钥失败", "err", err)
+		os.Exit(1)
+	}
+	// 调用方以共享密钥加密传入的 a1、web_session 等 cookie 在签名前解密
+	requestCipher, err := crypt.ForRequests(cfg.RequestEncryption)
+	if err != nil {
+		slog.Error("加载请求凭据密钥失败", "err", err)
+		os.Exit(1)
+	}
+	if requestCipher != nil {
+		platform.SetCredentialCipher(requestCipher, cfg.RequestEncryption.Required)
+		slog.Info("已开启请求凭据加密", "required", cfg.RequestEncryption.Required)
+	}
+
+	// 创建启用的签名平台：优先使用配置文件中的 platforms，否则使用 --platforms
+	names, decoders, err := app.Platforms(cfg, *platformNames)
+	if err != nil {
+		slog.Error("注册平台失败", "err", err)
+		os.Exit(1)
+	}
+	platforms, err := platform.NewSet(names, decoders)
+	if err != nil {
+		slog.Error("创建签名平台失败", "err", err, "registered", platform.Names())
+		os.Exit(1)
+	}
+	// mock、录制或回放签名结果，须在双跑校验之前包装
+	if *recordPath != "" && *replayPath != "" || *mock && (*recordPath != "" || *replayPath != "") {
+		slog.Error("--mock、--record 与 --replay 不能同时使用")
+		os.Exit(1)
+	}
+	if *mock {
+		platforms.Wrap(fixture.Mock)
+		slog.Warn("mock 模式已开启，返回的签名均为假数据")
+	}
+	var recorder *fixture.Recorder
+	if *recordPath != "" {
+		if recorder, err = fixture.NewRecorder(*recordPath, cipher); err != nil {
+			slog.Error("开启录制模式失败", "err", err, "path", *recordPath)
+			os.Exit(1)
+		}
+		platforms.Wrap(recorder.Wrap)
+		slog.Info("录制模式已开启", "path", *recordPath)
+	}
+	if *replayPath != "" {
+		replayer, err := fixture.Load(*replayPath, cipher)
+		if err != nil {
+			slog.Error("开启回放模式失败", "err", err, "path", *replayPath)
+			os.Exit(1)
+		}
+		platforms.Wrap(replayer.Wrap)
+		slog.Info("回放模式已开启", "path", *replayPath)
+	}
+
+	// 对冲须在双跑校验之前包装
+	platforms.EnableHedging(*hedgeDelay)
+
+	var verifications []platform.Verification
+	for _, p := range cfg.Platforms {
+		if p.Verify != nil {
+			verifications = append(verifications, platform.Verification{
+				Platform:   p.Name,
+				Against:    p.Verify.Against,
+				SampleRate: p.Verify.SampleRate,
+				Ignore:     p.Verify.Ignore,
+			})
+		}
+	}
+	if err := platforms.EnableVerification(verifications); err != nil {
+		slog.Error("开启双跑校验失败", "err", err)
+		os.Exit(1)
+	}
+
+	// 初始化签名平台，每个租户拥有独立的页面池，浏览器在首个浏览器类平台初始化时启动
+	env := &platform.Env{
+		Browser:           &browser.Shared{VersionCheck: *browserVersionCheck},
+		StealthPath:       *stealthPath,
+		CanaryStealthPath: *canaryStealthPath,
+		CanaryWeight:      *canaryWeight,
+		WaitUntil:         *waitUntil,
+		NavigationTimeout: *navTimeout,
+		SignFuncTimeout:   *signFuncTimeout,
+		PoolSize:          *poolSize,
+		WarmupConcurrency: *warmupConcurrency,
+		PacePerMinute:     *pacePerMinute,
+		PaceJitter:        *paceJitter,
+		PaceMaxWait:       *paceMaxWait,
+		ShedBudget:        *shedBudget,
+		BanThreshold:      *banThreshold,
+		BanWindow:         *banWindow,
+		BanCooldown:       *banCooldown,
+	}
+	for _, t := range cfg.Tenants {
+		env.Tenants = append(env.Tenants, platform.Tenant{Name: t.Name, PoolSize: t.PoolSize})
+	}
+	if l := cfg.AccountLimits; l != nil {
+		env.AccountLimits = platform.AccountLimits{PerMinute: l.PerMinute, PerHour: l.PerHour, PerDay: l.PerDay}
+	}
+	if *adaptiveMax > 0 {
+		// 以 --pool-size 为初始上限，按签名耗时在 [1, --adaptive-concurrency] 内自动调整
+		pagepool.SetAdaptive(pagepool.NewAdaptive(*poolSize, 1, *adaptiveMax))
+		slog.Info("已启用自适应并发控制", "initial", *poolSize, "max", *adaptiveMax)
+	}
+	// 签名端口与运维端口的路由在初始化完成后切换，降级启动期间先提供降级路由
+	var handler, adminHandler switchHandler
+	srv := &http.Server{Addr: *addr, Handler: &handler}
+	if s := cfg.Server; s != nil {
+		srv.ReadTimeout, srv.WriteTimeout, srv.IdleTimeout, srv.MaxHeaderBytes = s.ReadTimeout, s.WriteTimeout, s.IdleTimeout, s.MaxHeaderBytes
+		if s.WriteTimeout > 0 && *signTimeout > 0 && s.WriteTimeout <= *signTimeout {
+			slog.Warn("server.write_timeout 不大于 --sign-timeout，超时的签名请求可能收不到 504", "write_timeout", s.WriteTimeout, "sign_timeout", *signTimeout)
+		}
+	}
+	adminSrv := &http.Server{Addr: *adminAddr, Handler: &adminHandler}
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	listening, uploaded := false, false
+	if _, err := os.Stat(*stealthPath); *degradedStart && !*mock && *replayPath == "" && errors.Is(err, fs.ErrNotExist) {
+		pending := stealth.NewPending(*stealthPath)
+		r, adminRouter, err := degradedRouters(pending, cfg, *basePath)
+		if err != nil {
+			slog.Error("创建降级路由失败", "err", err)
+			os.Exit(1)
+		}
+		handler.set(r)
+		adminHandler.set(adminRouter)
+		serve(srv, adminSrv)
+		listening = true
+		slog.Warn("stealth.js 不存在，以降级模式启动，通过 PUT /admin/stealth 上传后初始化签名平台", "stealth_path", *stealthPath, "addr", *addr, "admin_addr", *adminAddr)
+		select {
+		case <-pending.Ready():
+			uploaded = true
+		case <-quit:
+			slog.Info("收到退出信号，正在关闭降级模式的服务...")
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			_ = srv.Shutdown(ctx)
+			_ = adminSrv.Shutdown(ctx)
+			cancel()
+			os.Exit(0)
+		}
+	}
+	if err := platforms.Init(context.Background(), env); err != nil {
+		slog.Error("初始化签名服务失败", "err", err, "stealth_path", *stealthPath)
+		report.Fatal("初始化签名服务失败", err, "platforms", names, "stealth_path", *stealthPath)
+		app.CloseBrowser(env.Browser)
+		// 上传的脚本无法使用时移走，重启后仍以降级模式启动，而不是反复初始化失败
+		if uploaded {
+			if err := os.Rename(*stealthPath, *stealthPath+".rejected"); err != nil {
+				slog.Error("移走上传的 stealth.js 失败", "err", err, "path", *stealthPath)
+			}
+		}
+		os.Exit(1)
+	}
+	monitorCtx, stopMonitor := context.WithCancel(context.Background())
+	defer stopMonitor()
+	if *healthInterval > 0 {
+		go platforms.Monitor(monitorCtx, *healthInterval)
+	}
+	// 首次签名成功（自检或真实请求）前 /readyz 返回 503
+	go platforms.SelfTest(monitorCtx)
+	// 账号与签名记录保存在配置文件 storage 指定的后端；未配置时账号保存在 --account-store（BoltDB）或内存中，签名记录只在内存中
+	driver, _ := app.StoreDriver(cfg, *accountStore)
+	openCtx, cancelOpen := context.WithTimeout(context.Background(), 10*time.Second)
+	store, err := app.OpenStore(openCtx, cfg, *accountStore)
+	cancelOpen()
+	if err != nil {
+		slog.Error("打开存储后端失败", "err", err)
+		os.Exit(1)
+	}
+	if s := cfg.Storage; s != nil {
+		historySize := s.HistorySize
+		if historySize == 0 {
+			historySize = 1000
+		}
+		platform.DefaultHistory.Persist(monitorCtx, store, historySize)
+		slog.Info("账号与签名记录保存在存储后端", "driver", driver, "history_size", historySize)
+	}
+	// 按日汇总各 Key 与账号的用量，供 /admin/usage/export 与 go_sign-cli usage export 导出
+	usageStats := usage.NewStats(store)
+	loadCtx, cancelLoad := context.WithTimeout(context.Background(), 10*time.Second)
+	err = usageStats.Load(loadCtx)
+	cancelLoad()
+	if err != nil {
+		slog.Error("加载用量汇总失败", "err", err)
+		os.Exit(1)
+	}
+	platform.OnSign(usageStats.Observe)
+	go usageStats.Run(monitorCtx)
+	accounts := account.New(store, cipher)
+	// 管理操作的审计记录与账号保存在同一后端，未配置 storage 时只保存在内存中
+	auditSize := 0
+	if s := cfg.Storage; s != nil {
+		auditSize = s.AuditSize
+	}
+	auditLog := audit.New(store, auditSize)
+	// 多实例部署时通过共享后端选出 leader，全局任务只在 leader 上运行；未配置 cluster 时在本实例运行
+	var elector *leader.Elector
+	runGlobal := func(name string, fn func(ctx context.Context)) { go fn(monitorCtx) }
+	if !*globalTasks {
+		runGlobal = func(name string, _ func(ctx context.Context)) {
+			slog.Info("全局任务由 worker 运行，本实例不运行", "task", name)
+		}
+	}
+	if cl := cfg.Cluster; cl != nil && *globalTasks {
+		if driver == storage.DriverMemory || driver == storage.DriverBolt {
+			slog.Warn("当前存储后端的租约只在本进程内有效，多实例选举须使用 postgres 或 redis", "driver", driver)
+		}
+		elector = leader.New(store, cl.NodeID, cl.LeaseTTL)
+		runGlobal = elector.Go
+		if cl.SelfTestInterval > 0 {
+			runGlobal("self_test", func(ctx context.Context) { platforms.SelfTestEvery(ctx, cl.SelfTestInterval) })
+		}
+		if cl.AccountSweepInterval > 0 {
+			runGlobal("account_sweep", func(ctx context.Context) { app.SweepAccounts(ctx, accounts, cl.AccountSweepInterval) })
+		}
+		slog.Info("已开启 leader 选举", "node", elector.ID(), "driver", driver)
+	}
+	// 维护窗口内逐个重建浏览器上下文，释放内存与缓存
+	recycleTimeout := 30 * time.Minute
+	if m := cfg.Maintenance; m != nil {
+		if m.Timeout > 0 {
+			recycleTimeout = m.Timeout
+		}
+		cron, _ := schedule.Parse(m.Schedule) // 已通过 config.Validate 校验
+		go platforms.MaintainOn(monitorCtx, cron, recycleTimeout)
+	}
+	// 定期检查上游 stealth.js，新版本在临时页面上验证后提示发布或自动蓝绿发布。
+	// 只提示人工发布时为全局任务；自动发布须由每个实例升级自身，仍在每个实例运行
+	if u := cfg.StealthUpdate; u != nil {
+		checker := &stealth.Checker{
+			URL:            u.URL,
+			StagingPath:    u.StagingPath,
+			AutoPromote:    u.AutoPromote,
+			UpgradeTimeout: recycleTimeout,
+			Target:         platforms,
+		}
+		if u.AutoPromote {
+			go checker.Run(monitorCtx, u.Interval)
+		} else {
+			runGlobal("stealth_update", func(ctx context.Context) { checker.Run(ctx, u.Interval) })
+		}
+	}
+	electorDone := make(chan struct{})
+	if elector != nil {
+		go func() {
+			defer close(electorDone)
+			elector.Run(monitorCtx)
+		}()
+	} else {
+		close(electorDone)
+	}
+
+	// 全部应答携带 X-Request-Id，未知路由、请求方法不符与 panic 均返回带 code 与 request_id 的 JSON
+	r := gin.New()
+	r.Use(report.RequestID())
+	r.Use(accessLog("GIN"))
+	r.Use(report.Recovery())
+	report.HandleErrors(r)
+	if err := configureProxy(r, cfg.Proxy); err != nil {
+		slog.Error("配置可信代理失败", "err", err)
+		os.Exit(1)
+	}
+	// 配置 server.middlewares 时加载自定义中间件，作用于签名端口的全部路由
+	if err := useMiddlewares(r, cfg.Server); err != nil {
+		slog.Error("加载自定义中间件失败", "err", err)
+		os.Exit(1)
+	}
+
+	keyring := auth.NewKeyring(cfg.Tenants, cfg.APIKeys)
+	jwtVerifier, err := auth.NewJWTVerifier(cfg.JWT)
+	if err != nil {
+		slog.Error("加载 JWT 配置失败", "err", err)
+		os.Exit(1)
+	}
+	keyring.SetJWT(jwtVerifier)
+	// HMAC 签名的 nonce 默认只记录在本实例内存中，Redis 后端时多个实例共享
+	if nonces, ok := store.(auth.NonceStore); ok {
+		keyring.SetNonceStore(nonces)
+	}
+	// 管理接口创建的 Key 保存在存储后端，定期同步其他实例的修改
+	apiKeys := apikey.New(store, keyring)
+	loadCtx, cancelLoad = context.WithTimeout(context.Background(), 10*time.Second)
+	err = apiKeys.Load(loadCtx)
+	cancelLoad()
+	if err != nil {
+		slog.Error("加载 API Key 失败", "err", err)
+		os.Exit(1)
+	}
+	go apiKeys.Run(monitorCtx)
+	tracker := usage.NewTracker()
+
+	base := r.Group(*basePath)
+	base.GET("/usage", keyring.Middleware(), tracker.Handler(keyring))
+	base.GET("/healthz", platforms.HealthHandler())
+	base.GET("/readyz", platforms.ReadyHandler())
+	base.GET("/status", platforms.StatusHandler())
+	// IP 名单作用于全部签名路由，未配置时放行全部请求，重新加载配置时替换
+	var allow, deny []string
+	if f := cfg.IPFilter; f != nil {
+		allow, deny = f.Allow, f.Deny
+	}
+	filter, err := ipfilter.New(allow, deny)
+	if err != nil {
+		slog.Error("创建 IP 名单失败", "err", err)
+		os.Exit(1)
+	}
+	// 运维接口使用独立的监听地址，不在签名端口上暴露；管理接口与签名路由共用 IP 名单与鉴权
+	adminRouter := gin.New()
+	adminRouter.Use(report.RequestID())
+	adminRouter.Use(accessLog("GIN-ADMIN"))
+	adminRouter.Use(report.Recovery())
+	report.HandleErrors(adminRouter)
+	_ = adminRouter.SetTrustedProxies(nil)
+	adminRouter.GET("/metrics", gin.WrapH(metrics.Default.Handler()))
+	adminRouter.Any("/debug/pprof/*name", pprofHandler)
+	// 管理接口默认需要 admin 权限，查询账号只需 accounts:read
+	adminBase := adminRouter.Group("/admin", filter.Middleware(), keyring.Middleware(), auditLog.Middleware())
+	admin := adminBase.Group("", auth.Require(auth.ScopeAdmin))
+	accountsRead := adminBase.Group("", auth.Require(auth.ScopeAccountsRead))
+	admin.GET("/audit", auditLog.Handler())
+	admin.GET("/usage/export", usageStats.ExportHandler())
+	admin.GET("/log-level", logging.LevelHandler())
+	admin.PUT("/log-level", logging.LevelHandler())
+	platforms.RegisterBindingRoutes(admin, keyring)
+	apiKeys.RegisterRoutes(admin)
+	admin.POST("/recycle", platforms.RecycleHandler(recycleTimeout))
+	admin.POST("/upgrade", platforms.UpgradeHandler(recycleTimeout))
+	admin.GET("/scripts/:platform", platforms.DumpScriptsHandler())
+	admin.GET("/header-templates/:platform", platforms.HeaderTemplatesHandler())
+	accountsRead.GET("/accounts/health", platforms.AccountsHandler())
+	accounts.RegisterRoutes(accountsRead, admin, func(name string) bool { return platforms.Get(name) != nil })
+	admin.POST("/accounts/:platform/status", platforms.ReportStatusHandler())
+	// SIGHUP 或 POST /admin/reload 时重新加载配置，只替换可在运行时修改的配置项
+	cfgReloader := &reloader{path: *configPath, resolver: resolver, running: cfg, applied: cfg, keyring: keyring, filter: filter, audit: auditLog}
+	admin.POST("/reload", cfgReloader.Handler())
+	if s := cfg.Secrets; s != nil && s.RefreshInterval > 0 {
+		go cfgReloader.refreshKeys(s.RefreshInterval)
+	}
+	// 配置 response_signing 时签名、GraphQL 与生成 a1 接口的响应体附带完整性签名，验签的公钥见 /v1/response-signing/keys
+	responseSigner, err := integrity.New(cfg.ResponseSigning)
+	if err != nil {
+		slog.Error("加载响应签名配置失败", "err", err)
+		os.Exit(1)
+	}
+	base.GET("/"+platform.APIVersion+"/response-signing/keys", responseSigner.KeysHandler())
+	// 签名、GraphQL 与批量任务接口接受 gzip 压缩的请求体；签名与 GraphQL 请求受 --sign-timeout 与 X-Timeout-Ms 限制
+	signMiddlewares := []gin.HandlerFunc{timing.SlowLog(*slowThreshold), responseSigner.Middleware(), filter.Middleware(), keyring.Middleware(), auth.Require(auth.ScopeSign), tracker.Middleware(), wire.Decompress(), platform.Deadline(*signTimeout)}
+	if s := cfg.Shadow; s != nil {
+		signMiddlewares = append(signMiddlewares, shadow.Middleware(shadow.Options{
+			URL:        s.URL,
+			SampleRate: s.SampleRate,
+			Ignore:     s.Ignore,
+			APIKey:     s.APIKey,
+			Timeout:    s.Timeout,
+		}))
+		slog.Info("流量镜像已开启", "url", s.URL, "sample_rate", s.SampleRate)
+	}
+	// 签名路由为 /v1/...，未带版本的旧路由作为已废弃的别名保留
+	platforms.RegisterVersionedRoutes(base, signMiddlewares...)
+	if p := platforms.Get(xhs.PlatformWeb); p != nil {
+		xhs.RegisterCompatRoutes(base, xhs.ProfileWeb, p, append(signMiddlewares, platform.CallerContext())...)
+	}
+	// GraphQL 接口在 resolver 内按签名次数计配额，不经过配额中间件
+	graphqlHandler, err := gql.Handler(platforms, keyring, tracker, platform.DefaultHistory)
+	if err != nil {
+		slog.Error("创建 GraphQL 接口失败", "err", err)
+		os.Exit(1)
+	}
+	// 离线生成设备标识，不经过浏览器，不计配额
+	base.GET("/"+platform.APIVersion+"/generate/a1", responseSigner.Middleware(), filter.Middleware(), keyring.Middleware(), auth.Require(auth.ScopeSign), xhs.GenerateHandler())
+	// 调用方回传签名请求在上游的结果，不计配额
+	base.POST("/"+platform.APIVersion+"/feedback", filter.Middleware(), keyring.Middleware(), auth.Require(auth.ScopeSign), platforms.FeedbackHandler())
+	base.POST("/"+platform.APIVersion+"/graphql", timing.SlowLog(*slowThreshold), responseSigner.Middleware(), filter.Middleware(), keyring.Middleware(), auth.Require(auth.ScopeSign), wire.Decompress(), platform.Deadline(*signTimeout), platform.CallerContext(), graphqlHandler)
+	// 批量任务在执行时按请求数计配额，不经过配额中间件
+	// 配置任务库时恢复上次未完成的任务
+	jobManager, err := jobs.NewManager(platforms, tracker, keyring, jobs.Options{
+		Concurrency: *jobConcurrency,
+		MaxItems:    *jobMaxItems,
+		Retention:   *jobRetention,
+		StorePath:   *jobStore,
+		Cipher:      cipher,
+	})
+	if err != nil {
+		slog.Error("创建批量任务管理器失败", "err", err, "store", *jobStore)
+		os.Exit(1)
+	}
+	jobMiddlewares := []gin.HandlerFunc{filter.Middleware(), keyring.Middleware(), auth.Require(auth.ScopeSign), wire.Decompress(), platform.CallerContext()}
+	// 配置 server.write_timeout 时，批量任务接口（含 SSE 进度推送）改用 stream_write_timeout
+	if s := cfg.Server; s != nil && s.WriteTimeout > 0 {
+		jobMiddlewares = append([]gin.HandlerFunc{platform.WriteTimeout(s.StreamWriteTimeout)}, jobMiddlewares...)
+	}
+	jobManager.RegisterRoutes(base.Group("/"+platform.APIVersion), jobMiddlewares...)
+
+	// 用 http.Server 包裹 gin 实例，实现优雅关闭；降级启动时服务已在监听，只切换路由
+	handler.set(r)
+	adminHandler.set(adminRouter)
+	if !listening {
+		serve(srv, adminSrv)
+	}
+
+	slog.Info("服务启动", "addr", *addr, "admin_addr", *adminAddr, "base_path", *basePath)
+
+	// 收到 SIGHUP 时重新加载配置
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			res, err := cfgReloader.reload("sighup")
+			entry := audit.Entry{Actor: "sighup", Action: "SIGHUP reload", Status: http.StatusOK}
+			if err != nil {
+				entry.Status = http.StatusInternalServerError
+			} else {
+				entry.Diff = res.Changes
+			}
+			if err := auditLog.Record(context.Background(), entry); err != nil {
+				slog.Error("记录管理操作失败", "err", err, "action", entry.Action)
+			}
+		}
+	}()
+
+	// 优雅退出
+	<-quit
+	slog.Info("收到退出信号，正在关闭服务...")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		slog.Error("HTTP 服务优雅关闭失败", "err", err)
+	}
+	if err := adminSrv.Shutdown(ctx); err != nil {
+		slog.Error("运维接口优雅关闭失败", "err", err)
+	}
+	stopMonitor()
+	// 等待 leader 释放租约后再关闭存储后端，使其他实例立即接管全局任务
+	<-electorDone
+	// 先关闭任务库再关闭平台，避免关闭过程中失败的请求被记为已完成，下次启动时重新执行
+	if err := jobManager.Close(); err != nil {
+		slog.Error("关闭任务库失败", "err", err)
+	}
+	if err := usageStats.Flush(ctx); err != nil {
+		slog.Error("写入用量汇总失败", "err", err)
+	}
+	if err := store.Close(); err != nil {
+		slog.Error("关闭存储后端失败", "err", err)
+	}
+	_ = platforms.Close()
+	app.CloseBrowser(env.Browser)
+	if recorder != nil {
+		if err := recorder.Close(); err != nil {
+			slog.Error("关闭录制文件失败", "err", err)
+		}
+	}
+	report.Flush(5 * time.Second)
+	webhook.Flush(5 * time.Second)
+	if logFile != nil {
+		_ = logFile.Close()
+	}
+}
+
+// reloader 重新读取配置文件并应用可在运行时修改的配置项：API Key 与租户配额、日志级别、IP 名单，
+// 浏览器与页面池不受影响；其余配置项的变化只记录告警，须重启服务后生效。
+type reloader struct {
+	mu       sync.Mutex
+	path     string
+	resolver *secrets.Resolver
+	running  *config.Config // 启动时的配置，用于找出须重启才能生效的变化
+	applied  *config.Config // 最近一次加载的配置，用于审计记录中的差异
+	keyring  *auth.Keyring
+	filter   *ipfilter.Filter
+	audit    *audit.Log
+}
+
+// reloadResult 为一次重新加载的结果。
+type reloadResult struct {
+	APIKeys         int      `json:"api_keys"`
+	Tenants         int      `json:"tenants"`
+	LogLevel        string   `json:"log_level"`
+	RestartRequired []string `json:"restart_required,omitempty"`
+	// Changes 为相对上次加载的配置的差异，写入审计记录
+	Changes []audit.Change `json:"-"`
+}
+
+// reload 重新加载配置，source 为触发方式（sighup、admin），用于日志。
+// 配置无效时不做任何修改并返回错误。
+func (rl *reloader) reload(source string) (*reloadResult, error) {
+	res, err := rl.apply()
+	if err != nil {
+		slog.Error("重新加载配置失败，保留原配置", "err", err, "config", rl.path, "source", source)
+		return nil, err
+	}
+	slog.Info("配置已重新加载", "config", rl.path, "source", source, "api_keys", res.APIKeys, "tenants", res.Tenants, "log_level", res.LogLevel)
+	if len(res.RestartRequired) > 0 {
+		slog.Warn("以下配置项的变化须重启服务后生效", "fields", res.RestartRequired)
+	}
+	webhook.Emit(webhook.ConfigReloaded, "config", rl.path, "source", source, "restart_required", res.RestartRequired)
+	return res, nil
+}
+
+// apply 读取并校验配置文件，全部通过后再依次替换 IP 名单、日志级别与 API Key。
+func (rl *reloader) apply() (*reloadResult, error) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if rl.path == "" {
+		return nil, errors.New("未指定 --config，没有可重新加载的配置文件")
+	}
+	cfg, err := config.Load(rl.path)
+	if err == nil {
+		err = app.ResolveSecrets(rl.resolver, cfg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// 新增或删除的租户没有页面池，只能在重启时生效
+	if !sameTenants(rl.running.Tenants, cfg.Tenants) {
+		return nil, errors.New("租户列表有增删，须重启服务后生效")
+	}
+	var allow, deny []string
+	if f := cfg.IPFilter; f != nil {
+		allow, deny = f.Allow, f.Deny
+	}
+	verifier, err := auth.NewJWTVerifier(cfg.JWT)
+	if err != nil {
+		return nil, fmt.Errorf("jwt: %w", err)
+	}
+	if err := rl.filter.Update(allow, deny); err != nil {
+		return nil, fmt.Errorf("ip_filter: %w", err)
+	}
+	var level string
+	if l := cfg.Log; l != nil {
+		level = l.Level
+	}
+	_ = logging.SetLevel(level) // 已通过 config.Validate 校验
+	rl.keyring.Update(cfg.Tenants, cfg.APIKeys)
+	rl.keyring.SetJWT(verifier)
+	webhook.Init(cfg.Webhooks)
+	changes := audit.Diff(auditView(rl.applied), auditView(cfg))
+	rl.applied = cfg
+	return &reloadResult{
+		APIKeys:         len(cfg.APIKeys),
+		Tenants:         len(cfg.Tenants),
+		LogLevel:        strings.ToLower(logging.Level.Level().String()),
+		RestartRequired: restartRequired(rl.running, cfg),
+		Changes:         changes,
+	}, nil
+}
+
+// refreshKeys 每隔 interval 重新读取配置文件与密钥后端并替换 API Key 与 JWT 配置，用于密钥轮换；
+// 读取失败或租户列表有增删时保留原有的 Key。与 reload 共用 rl.mu，避免较早读取的 Key 覆盖较新的重新加载结果。
+func (rl *reloader) refreshKeys(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for range t.C {
+		n, err := rl.applyKeys()
+		if err != nil {
+			slog.Error("刷新 API Key 失败，保留原有的 Key", "err", err, "config", rl.path)
+			report.Error("刷新 API Key 失败", err, "config", rl.path)
+			continue
+		}
+		slog.Info("API Key 已刷新", "api_keys", n)
+	}
+}
+
+// applyKeys 读取配置文件并只替换 API Key 与 JWT 配置，返回 Key 数。
+func (rl *reloader) applyKeys() (int, error) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	cfg, err := config.Load(rl.path)
+	if err == nil {
+		err = app.ResolveSecrets(rl.resolver, cfg)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if !sameTenants(rl.running.Tenants, cfg.Tenants) {
+		return 0, errors.New("租户列表有增删，须重启服务后生效")
+	}
+	verifier, err := auth.NewJWTVerifier(cfg.JWT)
+	if err != nil {
+		return 0, fmt.Errorf("jwt: %w", err)
+	}
+	rl.keyring.Update(cfg.Tenants, cfg.APIKeys)
+	rl.keyring.SetJWT(verifier)
+	return len(cfg.APIKeys), nil
+}
+
+// Handler 返回重新加载配置的管理接口，成功时返回 reloadResult，失败时返回 500 并保留原配置。
+func (rl *reloader) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		res, err := rl.reload("admin")
+		if res != nil {
+			audit.SetChanges(c, res.Changes)
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "重新加载配置失败: " + err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, res)
+	}
+}
+
+// sameTenants 判断两份租户列表的名称是否一致（不计顺序）。
+func sameTenants(a, b []config.Tenant) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	names := make(map[string]bool, len(a))
+	for _, t := range a {
+		names[t.Name] = true
+	}
+	for _, t := range b {
+		if !names[t.Name] {
+			return false
+		}
+	}
+	return true
+}
+
+// restartRequired 返回 next 相对 running 有变化、但只能在重启时生效的配置项。
+// 可信代理由 gin 在处理请求时无锁读取，同样须重启生效。
+func restartRequired(running, next *config.Config) []string {
+	pools := make(map[string]int, len(running.Tenants))
+	for _, t := range running.Tenants {
+		pools[t.Name] = t.PoolSize
+	}
+	var out []string
+	for _, t := range next.Tenants {
+		if pools[t.Name] != t.PoolSize {
+			out = append(out, "tenants.pool_size")
+			break
+		}
+	}
+	// 日志级别可重新加载，比较其余日志配置时忽略
+	var runningLog, nextLog config.Log
+	if running.Log != nil {
+		runningLog = *running.Log
+	}
+	if next.Log != nil {
+		nextLog = *next.Log
+	}
+	runningLog.Level, nextLog.Level = "", ""
+	sections := []struct {
+		name          string
+		running, next any
+	}{
+		{"platforms", running.Platforms, next.Platforms},
+		{"server", running.Server, next.Server},
+		{"proxy", running.Proxy, next.Proxy},
+		{"shadow", running.Shadow, next.Shadow},
+		{"log", runningLog, nextLog},
+		{"error_report", running.ErrorReport, next.ErrorReport},
+		{"encryption", running.Encryption, next.Encryption},
+		{"secrets", running.Secrets, next.Secrets},
+		{"maintenance", running.Maintenance, next.Maintenance},
+		{"stealth_update", running.StealthUpdate, next.StealthUpdate},
+		{"storage", running.Storage, next.Storage},
+		{"cluster", running.Cluster, next.Cluster},
+		{"account_limits", running.AccountLimits, next.AccountLimits},
+		{"response_signing", running.ResponseSigning, next.ResponseSigning},
+		{"request_encryption", running.RequestEncryption, next.RequestEncryption},
+	}
+	for _, sec := range sections {
+		// 按 YAML 比较，忽略平台 options 中节点的行列号
+		a, _ := yaml.Marshal(sec.running)
+		b, _ := yaml.Marshal(sec.next)
+		if !bytes.Equal(a, b) {
+			out = append(out, sec.name)
+		}
+	}
+	return out
+}
+
+// auditView 返回用于审计差异的配置，字段名与配置文件一致：密钥替换为 logging.Mask 的结果，
+// 轮换后的差异仍可见而不泄露原值。
+func auditView(cfg *config.Config) map[string]any {
+	out := *cfg
+	out.APIKeys = slices.Clone(cfg.APIKeys)
+	for i := range out.APIKeys {
+		out.APIKeys[i].Key = logging.Mask(out.APIKeys[i].Key)
+	}
+	if s := cfg.Shadow; s != nil {
+		shadow := *s
+		shadow.APIKey = logging.Mask(shadow.APIKey)
+		out.Shadow = &shadow
+	}
+	if e := cfg.ErrorReport; e != nil {
+		er := *e
+		er.SentryDSN = logging.Mask(er.SentryDSN)
+		er.WebhookURL = logging.Mask(er.WebhookURL)
+		out.ErrorReport = &er
+	}
+	if e := cfg.Encryption; e != nil {
+		enc := *e
+		enc.Key = logging.Mask(enc.Key)
+		out.Encryption = &enc
+	}
+	if s := cfg.Storage; s != nil {
+		st := *s
+		st.DSN = logging.Mask(st.DSN)
+		out.Storage = &st
+	}
+	out.Webhooks = slices.Clone(cfg.Webhooks)
+	for i := range out.Webhooks {
+		out.Webhooks[i].Secret = logging.Mask(out.Webhooks[i].Secret)
+	}
+	if j := cfg.JWT; j != nil {
+		jwt := *j
+		jwt.Secret = logging.Mask(jwt.Secret)
+		out.JWT = &jwt
+	}
+	if rs := cfg.ResponseSigning; rs != nil {
+		signing := *rs
+		signing.Secret = logging.Mask(signing.Secret)
+		out.ResponseSigning = &signing
+	}
+	if re := cfg.RequestEncryption; re != nil {
+		enc := *re
+		enc.Key = logging.Mask(enc.Key)
+		out.RequestEncryption = &enc
+	}
+	raw, _ := yaml.Marshal(&out)
+	view := map[string]any{}
+	_ = yaml.Unmarshal(raw, &view)
+	return view
+}
+
+// configureProxy 按配置设置可信代理与客户端 IP 请求头，p 为 nil 时不信任任何代理。
+func configureProxy(r *gin.Engine, p *config.Proxy) error {
+	if p == nil {
+		return r.SetTrustedProxies(nil)
+	}
+	if len(p.Headers) > 0 {
+		r.RemoteIPHeaders = p.Headers
+	}
+	r.TrustedPlatform = p.ClientIPHeader
+	if err := r.SetTrustedProxies(p.Trusted); err != nil {
+		return err
+	}
+	slog.Info("可信代理已配置", "trusted", p.Trusted, "headers", r.RemoteIPHeaders, "client_ip_header", p.ClientIPHeader)
+	return nil
+}
+
+// switchHandler 为可在运行时替换的 http.Handler，降级启动的服务在签名平台初始化完成后切换到完整路由。
+type switchHandler struct {
+	h atomic.Pointer[http.Handler]
+}
+
+func (s *switchHandler) set(h http.Handler) { s.h.Store(&h) }
+
+func (s *switchHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	(*s.h.Load()).ServeHTTP(w, r)
+}
+
+// serve 在后台启动签名端口与运维端口的 HTTP 服务，监听失败时退出进程。
+func serve(srv, adminSrv *http.Server) {
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			slog.Error("服务启动失败", "err", err)
+			report.Fatal("服务启动失败", err, "addr", srv.Addr)
+			os.Exit(1)
+		}
+	}()
+	go func() {
+		if err := adminSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			slog.Error("运维接口启动失败", "err", err)
+			report.Fatal("运维接口启动失败", err, "admin_addr", adminSrv.Addr)
+			os.Exit(1)
+		}
+	}()
+}
+
+// accessLog 返回以 [prefix] 开头、带请求 ID 的单行访问日志中间件，便于日志采集与按 request_id 检索。
+func accessLog(prefix string) gin.HandlerFunc {
+	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
+		id, _ := param.Keys[report.RequestIDKey].(string)
+		return fmt.Sprintf("[%s] %s %s %s %s %s\n", prefix, param.Method, param.Path, param.ClientIP, id, param.ErrorMessage)
+	})
+}
+
+// degradedRouters 返回降级启动期间签名端口与运维端口的路由：签名端口只提供 /healthz、/readyz 与 /status，
+// 运维端口提供 /metrics、/debug/pprof 与上传 stealth.js 的 PUT /admin/stealth，其余路由返回 503。
+// 存储后端在签名平台初始化后才打开，上传接口只接受配置文件中的 API Key 与 JWT。
+func degradedRouters(pending *stealth.Pending, cfg *config.Config, basePath string) (http.Handler, http.Handler, error) {
+	r := gin.New()
+	r.Use(report.RequestID(), accessLog("GIN"), report.Recovery())
+	if err := configureProxy(r, cfg.Proxy); err != nil {
+		return nil, nil, fmt.Errorf("配置可信代理失败: %w", err)
+	}
+	if err := useMiddlewares(r, cfg.Server); err != nil {
+		return nil, nil, fmt.Errorf("加载自定义中间件失败: %w", err)
+	}
+	base := r.Group(basePath)
+	base.GET("/healthz", pending.StatusHandler())
+	base.GET("/readyz", pending.ReadyHandler())
+	base.GET("/status", pending.StatusHandler())
+	r.NoRoute(pending.Unavailable())
+
+	keyring := auth.NewKeyring(cfg.Tenants, cfg.APIKeys)
+	jwtVerifier, err := auth.NewJWTVerifier(cfg.JWT)
+	if err != nil {
+		return nil, nil, fmt.Errorf("加载 JWT 配置失败: %w", err)
+	}
+	keyring.SetJWT(jwtVerifier)
+	var allow, deny []string
+	if f := cfg.IPFilter; f != nil {
+		allow, deny = f.Allow, f.Deny
+	}
+	filter, err := ipfilter.New(allow, deny)
+	if err != nil {
+		return nil, nil, fmt.Errorf("创建 IP 名单失败: %w", err)
+	}
+	adminRouter := gin.New()
+	adminRouter.Use(report.RequestID(), accessLog("GIN-ADMIN"), report.Recovery())
+	_ = adminRouter.SetTrustedProxies(nil)
+	adminRouter.GET("/metrics", gin.WrapH(metrics.Default.Handler()))
+	adminRouter.Any("/debug/pprof/*name", pprofHandler)
+	adminRouter.PUT("/admin/stealth", filter.Middleware(), keyring.Middleware(), auth.Require(auth.ScopeAdmin), pending.UploadHandler())
+	adminRouter.NoRoute(pending.Unavailable())
+	return r, adminRouter, nil
+}
+
+// useMiddlewares 在 r 上按顺序加载 server.middlewares 中的自定义中间件，未配置时不做任何修改。
+func useMiddlewares(r *gin.Engine, s *config.Server) error {
+	if s == nil || len(s.Middlewares) == 0 {
+		return nil
+	}
+	mws, err := middleware.Build(s.Middlewares)
+	if err != nil {
+		return err
+	}
+	r.Use(mws...)
+	names := make([]string, len(s.Middlewares))
+	for i, m := range s.Middlewares {
+		names[i] = m.Name
+	}
+	slog.Info("自定义中间件已加载", "middlewares", names)
+	return nil
+}
+
+// pprofHandler 提供 net/http/pprof 的性能分析接口，路径与 http.DefaultServeMux 上的一致。
+func pprofHandler(c *gin.Context) {
+	switch c.Param("name") {
+	case "/cmdline":
+		pprof.Cmdline(c.Writer, c.Request)
+	case "/profile":
+		pprof.Profile(c.Writer, c.Request)
+	case "/symbol":
+		pprof.Symbol(c.Writer, c.Request)
+	case "/trace":
+		pprof.Trace(c.Writer, c.Request)
+	default:
+		pprof.Index(c.Writer, c.Request)
+	}
+}
@@ -0,0 +1,210 @@
+// Command worker 启动只运行全局任务的后台进程：定时自检、账号清理与 stealth.js 更新检查，不提供签名接口。
+// 与 go_sign 服务共享存储后端，配置 cluster 时参与 leader 选举，多个 worker 中只有 leader 运行全局任务；
+// 服务以 --global-tasks=false 启动后不再运行这些任务，签名实例与 worker 可分别部署与扩缩容。
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/account"
+	"go_sign/internal/app"
+	"go_sign/internal/browser"
+	"go_sign/internal/crypt"
+	"go_sign/internal/leader"
+	"go_sign/internal/metrics"
+	"go_sign/internal/platform"
+	"go_sign/internal/report"
+	"go_sign/internal/stealth"
+	"go_sign/internal/webhook"
+	"go_sign/internal/xhs"
+)
+
+func main() {
+	configPath := flag.String("config", "", "YAML 配置文件路径，全局任务的间隔取自其中的 cluster 与 stealth_update")
+	stealthPath := flag.String("stealth", "./stealth.min.js", "stealth.min.js 文件路径，自检与验证上游 stealth.js 时使用")
+	adminAddr := flag.String("admin-addr", "127.0.0.1:5007", "运维接口（/metrics、/healthz、/readyz）的监听地址")
+	waitUntil := flag.String("wait-until", xhs.WaitUntilDOMContentLoaded, "首页导航等待策略：load、domcontentloaded、networkidle")
+	navTimeout := flag.Duration("nav-timeout", 30*time.Second, "首页导航超时时间")
+	signFuncTimeout := flag.Duration("sign-func-timeout", 10*time.Second, "导航后等待 window._webmsxyw 就绪的超时时间，0 表示不等待")
+	platformNames := flag.String("platforms", xhs.PlatformWeb, "启用的签名平台，逗号分隔；配置文件中的 platforms 优先")
+	accountStore := flag.String("account-store", "", "账号库（BoltDB）文件路径，配置文件的 storage 优先")
+	browserVersionCheck := flag.String("browser-version-check", browser.VersionCheckWarn, "启动时浏览器版本检查：warn、strict、off")
+	logSecrets := flag.Bool("log-secrets", false, "日志中输出 a1、web_session、x-s 等敏感字段的原值，仅用于调试")
+	flag.Parse()
+
+	app.DefaultLogger(*logSecrets)
+	if !browser.ValidVersionCheck(*browserVersionCheck) {
+		slog.Error("不支持的浏览器版本检查模式", "browser_version_check", *browserVersionCheck)
+		os.Exit(1)
+	}
+	cfg, _, err := app.LoadConfig(*configPath)
+	if err != nil {
+		slog.Error("加载配置文件失败", "err", err, "path", *configPath)
+		os.Exit(1)
+	}
+	logFile, err := app.SetupLogging(cfg, *logSecrets)
+	if err != nil {
+		slog.Error("设置日志失败", "err", err)
+		os.Exit(1)
+	}
+	app.SetGinMode(cfg)
+	if err := report.Init(cfg.ErrorReport); err != nil {
+		slog.Error("开启错误上报失败", "err", err)
+		os.Exit(1)
+	}
+	webhook.Init(cfg.Webhooks)
+
+	// 全局任务：自检与 stealth.js 更新检查需要浏览器，账号清理只需要存储后端。
+	// 自动发布 stealth.js 须由每个签名实例升级自身，不由 worker 运行
+	var selfTestInterval, sweepInterval time.Duration
+	if cl := cfg.Cluster; cl != nil {
+		selfTestInterval, sweepInterval = cl.SelfTestInterval, cl.AccountSweepInterval
+	}
+	stealthUpdate := cfg.StealthUpdate
+	if stealthUpdate != nil && stealthUpdate.AutoPromote {
+		slog.Warn("stealth_update.auto_promote 开启时由各签名实例检查并升级自身，worker 不检查")
+		stealthUpdate = nil
+	}
+	if selfTestInterval <= 0 && sweepInterval <= 0 && stealthUpdate == nil {
+		slog.Error("没有可运行的全局任务，须配置 cluster.self_test_interval、cluster.account_sweep_interval 或 stealth_update")
+		os.Exit(1)
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	var platforms *platform.Set
+	env := &platform.Env{
+		Browser:           &browser.Shared{VersionCheck: *browserVersionCheck},
+		StealthPath:       *stealthPath,
+		WaitUntil:         *waitUntil,
+		NavigationTimeout: *navTimeout,
+		SignFuncTimeout:   *signFuncTimeout,
+		PoolSize:          1,
+		WarmupConcurrency: 1,
+	}
+	if selfTestInterval > 0 || stealthUpdate != nil {
+		names, decoders, err := app.Platforms(cfg, *platformNames)
+		if err == nil {
+			platforms, err = platform.NewSet(names, decoders)
+		}
+		if err == nil {
+			err = platforms.Init(context.Background(), env)
+		}
+		if err != nil {
+			slog.Error("初始化签名平台失败", "err", err, "stealth_path", *stealthPath)
+			report.Fatal("worker 初始化签名平台失败", err, "stealth_path", *stealthPath)
+			app.CloseBrowser(env.Browser)
+			os.Exit(1)
+		}
+		go platforms.SelfTest(ctx)
+	}
+	openCtx, cancelOpen := context.WithTimeout(context.Background(), 10*time.Second)
+	store, err := app.OpenStore(openCtx, cfg, *accountStore)
+	cancelOpen()
+	if err != nil {
+		slog.Error("打开存储后端失败", "err", err)
+		os.Exit(1)
+	}
+
+	// 配置 cluster 时与其他 worker（及未关闭全局任务的签名实例）选出 leader，全局任务只在 leader 上运行
+	var elector *leader.Elector
+	runGlobal := func(name string, fn func(ctx context.Context)) { go fn(ctx) }
+	if cl := cfg.Cluster; cl != nil {
+		elector = leader.New(store, cl.NodeID, cl.LeaseTTL)
+		runGlobal = elector.Go
+		slog.Info("已开启 leader 选举", "node", elector.ID())
+	}
+	if selfTestInterval > 0 {
+		runGlobal("self_test", func(ctx context.Context) { platforms.SelfTestEvery(ctx, selfTestInterval) })
+	}
+	if sweepInterval > 0 {
+		// 账号库中的 cookie 按配置加密，须与签名实例使用同一密钥
+		cipher, err := crypt.FromConfig(cfg.Encryption)
+		if err != nil {
+			slog.Error("加载加密密钥失败", "err", err)
+			os.Exit(1)
+		}
+		accounts := account.New(store, cipher)
+		runGlobal("account_sweep", func(ctx context.Context) { app.SweepAccounts(ctx, accounts, sweepInterval) })
+	}
+	if u := stealthUpdate; u != nil {
+		checker := &stealth.Checker{
+			URL:         u.URL,
+			StagingPath: u.StagingPath,
+			Target:      platforms,
+		}
+		runGlobal("stealth_update", func(ctx context.Context) { checker.Run(ctx, u.Interval) })
+	}
+	electorDone := make(chan struct{})
+	if elector != nil {
+		go func() {
+			defer close(electorDone)
+			elector.Run(ctx)
+		}()
+	} else {
+		close(electorDone)
+	}
+
+	adminRouter := gin.New()
+	adminRouter.Use(report.RequestID())
+	adminRouter.Use(gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
+		id, _ := param.Keys[report.RequestIDKey].(string)
+		return fmt.Sprintf("[GIN-WORKER] %s %s %s %s %s\n", param.Method, param.Path, param.ClientIP, id, param.ErrorMessage)
+	}))
+	adminRouter.Use(report.Recovery())
+	report.HandleErrors(adminRouter)
+	_ = adminRouter.SetTrustedProxies(nil)
+	adminRouter.GET("/metrics", gin.WrapH(metrics.Default.Handler()))
+	if platforms != nil {
+		adminRouter.GET("/healthz", platforms.HealthHandler())
+		adminRouter.GET("/readyz", platforms.ReadyHandler())
+	} else {
+		ok := func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"status": "ok"}) }
+		adminRouter.GET("/healthz", ok)
+		adminRouter.GET("/readyz", ok)
+	}
+	adminSrv := &http.Server{Addr: *adminAddr, Handler: adminRouter}
+	go func() {
+		if err := adminSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("运维接口启动失败", "err", err)
+			report.Fatal("worker 运维接口启动失败", err, "admin_addr", *adminAddr)
+			os.Exit(1)
+		}
+	}()
+	slog.Info("worker 启动", "admin_addr", *adminAddr, "self_test_interval", selfTestInterval, "account_sweep_interval", sweepInterval, "stealth_update", stealthUpdate != nil)
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	slog.Info("收到退出信号，正在关闭 worker...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := adminSrv.Shutdown(shutdownCtx); err != nil {
+		slog.Error("运维接口优雅关闭失败", "err", err)
+	}
+	stop()
+	// 等待 leader 释放租约后再关闭存储后端，使其他实例立即接管全局任务
+	<-electorDone
+	if err := store.Close(); err != nil {
+		slog.Error("关闭存储后端失败", "err", err)
+	}
+	if platforms != nil {
+		_ = platforms.Close()
+		app.CloseBrowser(env.Browser)
+	}
+	report.Flush(5 * time.Second)
+	webhook.Flush(5 * time.Second)
+	if logFile != nil {
+		_ = logFile.Close()
+	}
+}
@@ -0,0 +1,102 @@
+// Package account 保存从真实浏览器导出的站点账号（cookie），供调用方的账号池与管理工具使用。
+//
+// 账号库不参与签名：各平台的签名页面池不读取账号库，页面使用浏览器上下文自身的 cookie（小红书为页面生成的 a1）。
+// 调用方签出账号后，以账号的 cookie（如 a1、web_session）调用签名接口，停用账号只影响签出，不影响签名。
+package account
+
+import (
+	"strings"
+	"time"
+
+	"go_sign/internal/logging"
+)
+
+// Cookie 为账号的一个 cookie，各导入格式归一化为该结构。
+type Cookie struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Domain string `json:"domain,omitempty"`
+	Path   string `json:"path,omitempty"`
+	// Expires 为过期时间（Unix 秒），0 表示会话 cookie。
+	Expires  int64 `json:"expires,omitempty"`
+	HTTPOnly bool  `json:"http_only,omitempty"`
+	Secure   bool  `json:"secure,omitempty"`
+}
+
+// Account 为一个站点账号。
+type Account struct {
+	ID       string `json:"id"`
+	Platform string `json:"platform"`
+	// Identity 为账号在站点上的标识 cookie 的值（小红书为 a1），同一平台内唯一，重复导入时更新原账号。
+	Identity string   `json:"identity,omitempty"`
+	Cookies  []Cookie `json:"cookies"`
+	// Group 为账号所属分组，Tags 为标签，供管理工具按用途筛选。
+	Group string   `json:"group,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+	// Disabled 为 true 时账号停用，保留在账号库中但不能签出；账号库不参与签名，停用不影响签名页面。
+	Disabled bool `json:"disabled"`
+	// Source 为最近一次导入的格式，通过接口创建时为 api。
+	Source  string    `json:"source,omitempty"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+}
+
+// site 为平台所属站点：导入时只保留 Domain 及其子域名的 cookie，IdentityCookie 为标识账号的 cookie 名。
+type site struct {
+	Domain         string
+	IdentityCookie string
+}
+
+// sites 为内置平台的站点，键为平台名或其前缀（xhs-creator 等站点归入 xhs）；自定义平台不过滤 cookie。
+var sites = map[string]site{
+	"xhs":      {Domain: "xiaohongshu.com", IdentityCookie: "a1"},
+	"douyin":   {Domain: "douyin.com", IdentityCookie: "ttwid"},
+	"kuaishou": {Domain: "kuaishou.com", IdentityCookie: "did"},
+	"bilibili": {Domain: "bilibili.com", IdentityCookie: "buvid3"},
+}
+
+// siteOf 返回平台 name 所属的站点，未知平台返回 false。
+func siteOf(name string) (site, bool) {
+	prefix, _, _ := strings.Cut(name, "-")
+	s, ok := sites[prefix]
+	return s, ok
+}
+
+// matchDomain 判断 cookie 的 domain 是否为 domain 或其子域名。
+func matchDomain(cookieDomain, domain string) bool {
+	d := strings.TrimPrefix(strings.ToLower(cookieDomain), ".")
+	return d == domain || strings.HasSuffix(d, "."+domain)
+}
+
+// Cookie 返回名为 name 的 cookie 值，不存在时返回空串。
+func (a *Account) Cookie(name string) string {
+	for _, c := range a.Cookies {
+		if c.Name == name {
+			return c.Value
+		}
+	}
+	return ""
+}
+
+// HasTag 判断账号是否带有标签 tag。
+func (a *Account) HasTag(tag string) bool {
+	for _, t := range a.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Redacted 返回脱敏后的副本：Identity 与各 cookie 的值替换为 logging.Mask 的结果，用于接口返回。
+func (a *Account) Redacted() *Account {
+	out := *a
+	out.Identity = logging.Mask(a.Identity)
+	out.Tags = append([]string(nil), a.Tags...)
+	out.Cookies = make([]Cookie, len(a.Cookies))
+	for i, c := range a.Cookies {
+		c.Value = logging.Mask(c.Value)
+		out.Cookies[i] = c
+	}
+	return &out
+}
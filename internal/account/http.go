@@ -0,0 +1,249 @@
+package account
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/audit"
+	"go_sign/internal/auth"
+	"go_sign/internal/logging"
+	"go_sign/internal/storage"
+)
+
+// MaxImportSize 为导入内容的最大字节数。
+const MaxImportSize = 4 << 20
+
+// createRequest 为创建账号的请求体。
+type createRequest struct {
+	Platform string   `json:"platform" binding:"required"`
+	Cookies  []Cookie `json:"cookies" binding:"required"`
+	Group    string   `json:"group"`
+	Tags     []string `json:"tags"`
+	Disabled bool     `json:"disabled"`
+}
+
+// RegisterRoutes 注册账号管理接口，查询接口注册在 read 下，其余注册在 write 下，以便分别要求不同的权限；
+// 返回的账号均已脱敏，enabled 判断平台是否已启用：
+//   - GET /accounts：列出账号，可按 platform、group、tag、disabled 筛选；
+//   - POST /accounts：以 {platform, cookies, group, tags, disabled} 创建账号，返回 201；
+//   - GET /accounts/:id：返回单个账号；
+//   - PATCH /accounts/:id：修改 cookies、group、tags 或 disabled，未提供的字段不变，签出后修改 cookies 时带上 lease；
+//   - DELETE /accounts/:id：删除账号，返回 204；
+//   - POST /accounts/checkout/:id：以 {token, ttl} 签出账号（均可省略），返回 {lease, account}，见 Store.Checkout；
+//   - DELETE /accounts/checkout/:id?token=<token>：归还签出的账号，返回 204；
+//   - POST /accounts/import：导入浏览器导出的 cookie，见 ImportHandler。
+func (s *Store) RegisterRoutes(read, write gin.IRouter, enabled func(platform string) bool) {
+	r, g := read.Group("/accounts"), write.Group("/accounts")
+	r.GET("", func(c *gin.Context) {
+		f := Filter{Platform: c.Query("platform"), Group: c.Query("group"), Tag: c.Query("tag")}
+		if v := c.Query("disabled"); v != "" {
+			disabled, err := strconv.ParseBool(v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "disabled 须为 true 或 false"})
+				return
+			}
+			f.Disabled = &disabled
+		}
+		list, err := s.List(c.Request.Context(), f)
+		if err != nil {
+			slog.Error("列出账号失败", "err", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		out := make([]*Account, len(list))
+		for i, a := range list {
+			out[i] = a.Redacted()
+		}
+		c.JSON(http.StatusOK, gin.H{"accounts": out})
+	})
+	g.POST("", func(c *gin.Context) {
+		var req createRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "参数解析失败: " + err.Error()})
+			return
+		}
+		if !enabled(req.Platform) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "平台未启用: " + req.Platform})
+			return
+		}
+		a, err := s.Create(c.Request.Context(), Account{
+			Platform: req.Platform,
+			Cookies:  req.Cookies,
+			Group:    strings.TrimSpace(req.Group),
+			Tags:     cleanTags(req.Tags),
+			Disabled: req.Disabled,
+			Source:   SourceAPI,
+		})
+		if err != nil {
+			c.JSON(storeErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		audit.SetDiff(c, nil, a.Redacted())
+		slog.Info("管理接口创建账号", "platform", a.Platform, "id", a.ID, "identity", logging.Mask(a.Identity), "group", a.Group, "operator", auth.FromContext(c).Name)
+		c.JSON(http.StatusCreated, a.Redacted())
+	})
+	g.POST("/import", s.ImportHandler(enabled))
+	r.GET("/:id", func(c *gin.Context) {
+		a, err := s.Get(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			c.JSON(storeErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, a.Redacted())
+	})
+	g.PATCH("/:id", func(c *gin.Context) {
+		var p Patch
+		if err := c.ShouldBindJSON(&p); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "参数解析失败: " + err.Error()})
+			return
+		}
+		if p.Group != nil {
+			group := strings.TrimSpace(*p.Group)
+			p.Group = &group
+		}
+		if p.Tags != nil {
+			p.Tags = cleanTags(p.Tags)
+		}
+		prev, err := s.Get(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			c.JSON(storeErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		a, err := s.Update(c.Request.Context(), c.Param("id"), p)
+		if err != nil {
+			c.JSON(storeErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		audit.SetDiff(c, prev.Redacted(), a.Redacted())
+		slog.Info("管理接口修改账号", "platform", a.Platform, "id", a.ID, "cookies", p.Cookies != nil, "group", a.Group, "tags", a.Tags, "disabled", a.Disabled, "operator", auth.FromContext(c).Name)
+		c.JSON(http.StatusOK, a.Redacted())
+	})
+	g.DELETE("/:id", func(c *gin.Context) {
+		prev, err := s.Get(c.Request.Context(), c.Param("id"))
+		if err != nil {
+			c.JSON(storeErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		if err := s.Delete(c.Request.Context(), c.Param("id")); err != nil {
+			c.JSON(storeErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		audit.SetDiff(c, prev.Redacted(), nil)
+		slog.Info("管理接口删除账号", "id", c.Param("id"), "operator", auth.FromContext(c).Name)
+		c.Status(http.StatusNoContent)
+	})
+	g.POST("/checkout/:id", func(c *gin.Context) {
+		var req checkoutRequest
+		if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "参数解析失败: " + err.Error()})
+			return
+		}
+		var ttl time.Duration
+		if req.TTL != "" {
+			d, err := time.ParseDuration(req.TTL)
+			if err != nil || d <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "ttl 须为正的时长，如 5m"})
+				return
+			}
+			ttl = d
+		}
+		a, lease, err := s.Checkout(c.Request.Context(), c.Param("id"), req.Token, ttl)
+		if err != nil {
+			c.JSON(storeErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		slog.Info("管理接口签出账号", "platform", a.Platform, "id", a.ID, "expires", lease.Expires, "renew", req.Token != "", "operator", auth.FromContext(c).Name)
+		c.JSON(http.StatusOK, gin.H{"lease": lease, "account": a.Redacted()})
+	})
+	g.DELETE("/checkout/:id", func(c *gin.Context) {
+		if err := s.Checkin(c.Request.Context(), c.Param("id"), c.Query("token")); err != nil {
+			c.JSON(storeErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		slog.Info("管理接口归还账号", "id", c.Param("id"), "operator", auth.FromContext(c).Name)
+		c.Status(http.StatusNoContent)
+	})
+}
+
+// checkoutRequest 为签出账号的请求体，token 为空时生成新租约，ttl 为 Go 时长格式。
+type checkoutRequest struct {
+	Token string `json:"token"`
+	TTL   string `json:"ttl"`
+}
+
+// cleanTags 去除标签两端空白、空标签与重复标签，保持原顺序，结果为空时返回空切片而非 nil 以便清空标签。
+func cleanTags(tags []string) []string {
+	out := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, t := range tags {
+		t = strings.TrimSpace(t)
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		out = append(out, t)
+	}
+	return out
+}
+
+// storeErrorStatus 将账号库的错误映射为 HTTP 状态码。
+func storeErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrDuplicate), errors.Is(err, ErrCheckedOut), errors.Is(err, ErrDisabled), errors.Is(err, storage.ErrLeaseLost):
+		return http.StatusConflict
+	case errors.Is(err, ErrNoCookies):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// ImportHandler 返回导入 cookie 的管理接口 POST /accounts/import?platform=<平台>&format=<格式>，
+// 请求体为浏览器导出的 cookie（EditThisCookie JSON、cookies.txt 或 Playwright storageState，format 为空时自动识别），
+// 新建账号返回 201，更新已有账号返回 200，内容为脱敏后的账号。enabled 判断平台是否已启用。
+func (s *Store) ImportHandler(enabled func(platform string) bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := c.Query("platform")
+		if name == "" || !enabled(name) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "平台未启用: " + name})
+			return
+		}
+		raw, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, MaxImportSize))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "读取请求体失败: " + err.Error()})
+			return
+		}
+		cookies, format, err := ParseCookies(raw, c.Query("format"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		a, prev, err := s.Import(c.Request.Context(), name, format, cookies)
+		if err != nil {
+			if storeErrorStatus(err) == http.StatusInternalServerError {
+				slog.Error("导入账号失败", "err", err, "platform", name)
+			}
+			c.JSON(storeErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		created := prev == nil
+		if created {
+			audit.SetDiff(c, nil, a.Redacted())
+		} else {
+			audit.SetDiff(c, prev.Redacted(), a.Redacted())
+		}
+		slog.Info("管理接口导入账号", "platform", name, "id", a.ID, "identity", logging.Mask(a.Identity), "format", format, "cookies", len(a.Cookies), "created", created, "operator", auth.FromContext(c).Name)
+		status := http.StatusOK
+		if created {
+			status = http.StatusCreated
+		}
+		c.JSON(status, a.Redacted())
+	}
+}
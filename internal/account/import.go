@@ -0,0 +1,189 @@
+package account
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// 支持导入的 cookie 格式。
+const (
+	// FormatEditThisCookie 为 EditThisCookie 等浏览器扩展导出的 JSON 数组。
+	FormatEditThisCookie = "editthiscookie"
+	// FormatNetscape 为 curl、wget 与 cookies.txt 扩展使用的 Netscape cookies.txt。
+	FormatNetscape = "netscape"
+	// FormatStorageState 为 Playwright 的 storageState JSON。
+	FormatStorageState = "storage_state"
+)
+
+// SourceAPI 为通过账号接口直接提交 cookie 时账号的 Source。
+const SourceAPI = "api"
+
+// ErrUnknownFormat 表示不支持的导入格式。
+var ErrUnknownFormat = errors.New("不支持的 cookie 格式")
+
+// DetectFormat 根据内容推断导入格式：JSON 数组为 EditThisCookie，JSON 对象为 storageState，其余按 cookies.txt 解析。
+func DetectFormat(raw []byte) string {
+	switch trimmed := bytes.TrimSpace(raw); {
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		return FormatEditThisCookie
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		return FormatStorageState
+	}
+	return FormatNetscape
+}
+
+// ParseCookies 按 format 解析导出的 cookie，format 为空时自动推断，返回 cookie 与实际使用的格式。
+func ParseCookies(raw []byte, format string) ([]Cookie, string, error) {
+	if format == "" {
+		format = DetectFormat(raw)
+	}
+	var (
+		cookies []Cookie
+		err     error
+	)
+	switch format {
+	case FormatEditThisCookie:
+		cookies, err = parseEditThisCookie(raw)
+	case FormatNetscape:
+		cookies, err = parseNetscape(raw)
+	case FormatStorageState:
+		cookies, err = parseStorageState(raw)
+	default:
+		return nil, format, fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+	}
+	if err != nil {
+		return nil, format, fmt.Errorf("解析 %s 格式失败: %w", format, err)
+	}
+	if len(cookies) == 0 {
+		return nil, format, fmt.Errorf("%s 格式中没有 cookie", format)
+	}
+	return cookies, format, nil
+}
+
+// editThisCookie 为 EditThisCookie 导出的单个 cookie，expirationDate 为带小数的 Unix 秒。
+type editThisCookie struct {
+	Domain         string  `json:"domain"`
+	Name           string  `json:"name"`
+	Value          string  `json:"value"`
+	Path           string  `json:"path"`
+	ExpirationDate float64 `json:"expirationDate"`
+	Session        bool    `json:"session"`
+	HTTPOnly       bool    `json:"httpOnly"`
+	Secure         bool    `json:"secure"`
+}
+
+func parseEditThisCookie(raw []byte) ([]Cookie, error) {
+	var in []editThisCookie
+	if err := json.Unmarshal(raw, &in); err != nil {
+		return nil, err
+	}
+	out := make([]Cookie, 0, len(in))
+	for _, c := range in {
+		ck := Cookie{Name: c.Name, Value: c.Value, Domain: c.Domain, Path: c.Path, HTTPOnly: c.HTTPOnly, Secure: c.Secure}
+		if !c.Session && c.ExpirationDate > 0 {
+			ck.Expires = int64(math.Floor(c.ExpirationDate))
+		}
+		out = append(out, ck)
+	}
+	return out, nil
+}
+
+// storageState 为 Playwright storageState 中的 cookies 部分，expires 为 -1 表示会话 cookie。
+type storageState struct {
+	Cookies []struct {
+		Name     string  `json:"name"`
+		Value    string  `json:"value"`
+		Domain   string  `json:"domain"`
+		Path     string  `json:"path"`
+		Expires  float64 `json:"expires"`
+		HTTPOnly bool    `json:"httpOnly"`
+		Secure   bool    `json:"secure"`
+	} `json:"cookies"`
+}
+
+func parseStorageState(raw []byte) ([]Cookie, error) {
+	var in storageState
+	if err := json.Unmarshal(raw, &in); err != nil {
+		return nil, err
+	}
+	out := make([]Cookie, 0, len(in.Cookies))
+	for _, c := range in.Cookies {
+		ck := Cookie{Name: c.Name, Value: c.Value, Domain: c.Domain, Path: c.Path, HTTPOnly: c.HTTPOnly, Secure: c.Secure}
+		if c.Expires > 0 {
+			ck.Expires = int64(math.Floor(c.Expires))
+		}
+		out = append(out, ck)
+	}
+	return out, nil
+}
+
+// httpOnlyPrefix 为 cookies.txt 中 HttpOnly cookie 行的前缀。
+const httpOnlyPrefix = "#HttpOnly_"
+
+// parseNetscape 解析 cookies.txt：每行为制表符分隔的 domain、include_subdomains、path、secure、expires、name、value，
+// # 开头的行为注释（#HttpOnly_ 前缀除外）。
+func parseNetscape(raw []byte) ([]Cookie, error) {
+	var out []Cookie
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	scanner.Buffer(make([]byte, 64*1024), len(raw)+1)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		httpOnly := strings.HasPrefix(line, httpOnlyPrefix)
+		if httpOnly {
+			line = strings.TrimPrefix(line, httpOnlyPrefix)
+		}
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			return nil, fmt.Errorf("第 %d 行应为 7 个制表符分隔的字段，实际为 %d 个", n, len(fields))
+		}
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行 expires 不是整数: %q", n, fields[4])
+		}
+		out = append(out, Cookie{
+			Domain:   fields[0],
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			Expires:  expires,
+			Name:     fields[5],
+			Value:    fields[6],
+			HTTPOnly: httpOnly,
+		})
+	}
+	return out, scanner.Err()
+}
+
+// normalize 整理导入的 cookie：只保留平台所属站点的 cookie（自定义平台不过滤），丢弃空名称与已过期的 cookie，
+// 同名同 domain、path 的 cookie 以后出现的为准，domain 统一为小写，path 默认为 /。
+func normalize(platform string, cookies []Cookie, now int64) []Cookie {
+	s, known := siteOf(platform)
+	type key struct{ name, domain, path string }
+	index := make(map[key]int)
+	var out []Cookie
+	for _, c := range cookies {
+		c.Domain = strings.ToLower(c.Domain)
+		if c.Path == "" {
+			c.Path = "/"
+		}
+		if c.Name == "" || (c.Expires > 0 && c.Expires <= now) || (known && !matchDomain(c.Domain, s.Domain)) {
+			continue
+		}
+		k := key{c.Name, c.Domain, c.Path}
+		if i, ok := index[k]; ok {
+			out[i] = c
+			continue
+		}
+		index[k] = len(out)
+		out = append(out, c)
+	}
+	return out
+}
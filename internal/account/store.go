@@ -0,0 +1,501 @@
+package account
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"go_sign/internal/crypt"
+	"go_sign/internal/storage"
+)
+
+// collection 为账号在存储后端中的集合，键为账号 ID，值为 Account 的 JSON。
+const collection = "accounts"
+
+// ErrNotFound 表示账号不存在。
+var ErrNotFound = errors.New("账号不存在")
+
+// ErrNoCookies 表示导入内容中没有属于该平台站点的 cookie。
+var ErrNoCookies = errors.New("没有属于该平台站点的 cookie")
+
+// ErrDuplicate 表示平台内已有相同标识的账号。
+var ErrDuplicate = errors.New("已有相同标识的账号")
+
+// ErrCheckedOut 表示账号已被其他持有者签出，暂时不能修改其 cookie 或再次签出。
+var ErrCheckedOut = errors.New("账号已被其他持有者签出")
+
+// ErrDisabled 表示账号已停用，不能签出。
+var ErrDisabled = errors.New("账号已停用")
+
+// 账号会话租约的有效期。
+const (
+	// DefaultCheckoutTTL 为签出未指定有效期时的租约时长，也是提交 lease 修改 cookie 时续期的时长。
+	DefaultCheckoutTTL = 5 * time.Minute
+	// MaxCheckoutTTL 为签出允许的最长租约时长。
+	MaxCheckoutTTL = time.Hour
+	// mutateTTL 为未签出时修改 cookie 临时取得的租约时长，修改完成后立即释放。
+	mutateTTL = 30 * time.Second
+)
+
+// Lease 为账号会话的租约：持有期间只有持有者可以修改账号的 cookie。
+type Lease struct {
+	Token   string    `json:"token"`
+	Expires time.Time `json:"expires"`
+}
+
+// Store 在存储后端中保存账号，并发安全。每次读取都从后端加载，多个实例共享同一后端时可看到彼此的修改；
+// 本实例内的写入串行执行，保证同一平台内标识不重复。修改账号 cookie 前须取得该账号的会话租约（见 Checkout），
+// 租约保存在后端中，多个实例共享同一后端时同一时刻只有一个持有者可以修改账号的会话。
+type Store struct {
+	mu      sync.Mutex
+	backend storage.Store
+	// cipher 加密写入后端的 cookie 与标识，为 nil 时明文保存
+	cipher crypt.Cipher
+}
+
+// New 返回保存在 backend 中的账号库，backend 由调用方关闭。cipher 非 nil 时账号的 cookie 与标识加密后写入后端；
+// 读取时兼容未加密的旧记录，下次修改账号时加密。
+func New(backend storage.Store, cipher crypt.Cipher) *Store {
+	return &Store{backend: backend, cipher: cipher}
+}
+
+// record 为账号在后端中的保存格式：加密时 Identity 与 Cookies 清空，以密文保存在 EncryptedCookies 中。
+type record struct {
+	Account
+	EncryptedCookies string `json:"encrypted_cookies,omitempty"`
+}
+
+// credentials 为 record.EncryptedCookies 加密的内容。
+type credentials struct {
+	Identity string   `json:"identity,omitempty"`
+	Cookies  []Cookie `json:"cookies"`
+}
+
+// load 从后端读取全部账号，键为账号 ID。
+func (s *Store) load(ctx context.Context) (map[string]*Account, error) {
+	raw, err := s.backend.List(ctx, collection)
+	if err != nil {
+		return nil, fmt.Errorf("读取账号库失败: %w", err)
+	}
+	out := make(map[string]*Account, len(raw))
+	for id, v := range raw {
+		var r record
+		if err := json.Unmarshal(v, &r); err != nil {
+			return nil, fmt.Errorf("账号 %s: %w", id, err)
+		}
+		if r.EncryptedCookies != "" {
+			if s.cipher == nil {
+				return nil, fmt.Errorf("账号 %s: %w", id, crypt.ErrNoKey)
+			}
+			plain, err := s.cipher.Decrypt(r.EncryptedCookies)
+			if err != nil {
+				return nil, fmt.Errorf("账号 %s: %w", id, err)
+			}
+			var c credentials
+			if err := json.Unmarshal(plain, &c); err != nil {
+				return nil, fmt.Errorf("账号 %s cookie 格式错误: %w", id, err)
+			}
+			r.Identity, r.Cookies = c.Identity, c.Cookies
+		}
+		a := r.Account
+		out[id] = &a
+	}
+	return out, nil
+}
+
+// put 将账号 a 写入后端，配置了加密密钥时加密其 cookie 与标识。
+func (s *Store) put(ctx context.Context, a *Account) error {
+	r := record{Account: *a}
+	if s.cipher != nil {
+		plain, err := json.Marshal(credentials{Identity: a.Identity, Cookies: a.Cookies})
+		if err != nil {
+			return err
+		}
+		if r.EncryptedCookies, err = s.cipher.Encrypt(plain); err != nil {
+			return fmt.Errorf("加密账号 cookie 失败: %w", err)
+		}
+		r.Identity, r.Cookies = "", nil
+	}
+	raw, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if err := s.backend.Put(ctx, collection, a.ID, raw); err != nil {
+		return fmt.Errorf("保存账号失败: %w", err)
+	}
+	return nil
+}
+
+// setCookies 整理 cookie 并写入 a，同时更新标识，整理后没有 cookie 时返回 ErrNoCookies。
+func (a *Account) setCookies(cookies []Cookie, now time.Time) error {
+	cookies = normalize(a.Platform, cookies, now.Unix())
+	if len(cookies) == 0 {
+		return ErrNoCookies
+	}
+	a.Cookies = cookies
+	a.Identity = ""
+	if st, ok := siteOf(a.Platform); ok {
+		a.Identity = a.Cookie(st.IdentityCookie)
+	}
+	return nil
+}
+
+// newID 生成账号 ID。
+func newID() (string, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("生成账号 ID 失败: %w", err)
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// newToken 生成租约 token。
+func newToken() (string, error) {
+	t := make([]byte, 16)
+	if _, err := rand.Read(t); err != nil {
+		return "", fmt.Errorf("生成租约 token 失败: %w", err)
+	}
+	return hex.EncodeToString(t), nil
+}
+
+// leaseName 返回账号 id 的会话租约在后端中的名称。
+func leaseName(id string) string {
+	return "account:" + id
+}
+
+// lockSession 在修改账号 id 的 cookie 前取得其会话租约并返回释放函数：lease 非空时须为签出时的 token，
+// 租约续期 DefaultCheckoutTTL 且不释放；否则以临时 token 取得 mutateTTL 的租约，释放函数将其释放。
+// 账号已被其他持有者签出时返回 ErrCheckedOut。
+func (s *Store) lockSession(ctx context.Context, id, lease string) (func(), error) {
+	token, ttl := lease, DefaultCheckoutTTL
+	if token == "" {
+		t, err := newToken()
+		if err != nil {
+			return nil, err
+		}
+		token, ttl = t, mutateTTL
+	}
+	if _, err := s.backend.Acquire(ctx, leaseName(id), token, ttl); err != nil {
+		if errors.Is(err, storage.ErrLocked) {
+			return nil, fmt.Errorf("%w: %s", ErrCheckedOut, id)
+		}
+		return nil, fmt.Errorf("取得账号租约失败: %w", err)
+	}
+	if lease != "" {
+		return func() {}, nil
+	}
+	return func() {
+		// 租约到期后自动失效，释放失败不影响本次修改
+		_ = s.backend.Release(context.WithoutCancel(ctx), leaseName(id), token)
+	}, nil
+}
+
+// Checkout 签出账号 id：以 token（为空时生成）取得其会话租约，有效期 ttl（0 为 DefaultCheckoutTTL，至多 MaxCheckoutTTL），
+// 租约期间其他持有者不能修改账号的 cookie 或再次签出，以同一 token 再次签出即为续期。
+// 账号已被其他持有者签出时返回 ErrCheckedOut，已停用时返回 ErrDisabled。
+func (s *Store) Checkout(ctx context.Context, id, token string, ttl time.Duration) (*Account, *Lease, error) {
+	if ttl <= 0 {
+		ttl = DefaultCheckoutTTL
+	}
+	ttl = min(ttl, MaxCheckoutTTL)
+	a, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if a.Disabled {
+		return nil, nil, fmt.Errorf("%w: %s", ErrDisabled, id)
+	}
+	if token == "" {
+		if token, err = newToken(); err != nil {
+			return nil, nil, err
+		}
+	}
+	expires, err := s.backend.Acquire(ctx, leaseName(id), token, ttl)
+	if err != nil {
+		if errors.Is(err, storage.ErrLocked) {
+			return nil, nil, fmt.Errorf("%w: %s", ErrCheckedOut, id)
+		}
+		return nil, nil, fmt.Errorf("取得账号租约失败: %w", err)
+	}
+	return a, &Lease{Token: token, Expires: expires}, nil
+}
+
+// Checkin 归还签出的账号 id，租约已过期或 token 不是当前持有者时返回 storage.ErrLeaseLost。
+func (s *Store) Checkin(ctx context.Context, id, token string) error {
+	if err := s.backend.Release(ctx, leaseName(id), token); err != nil {
+		return fmt.Errorf("归还账号 %s 失败: %w", id, err)
+	}
+	return nil
+}
+
+// Import 将导入的 cookie 整理后写入平台 platform 的账号：标识 cookie 与已有账号相同时替换其 cookie
+// （保留分组、标签与停用状态，账号已被其他持有者签出时返回 ErrCheckedOut），否则创建新账号。
+// 返回写入后的账号及更新前的账号，新建时后者为 nil。
+func (s *Store) Import(ctx context.Context, platform, source string, cookies []Cookie) (*Account, *Account, error) {
+	now := time.Now()
+	a := &Account{Platform: platform, Source: source, Created: now, Updated: now}
+	if err := a.setCookies(cookies, now); err != nil {
+		return nil, nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	accounts, err := s.load(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	prev := find(accounts, platform, a.Identity)
+	if prev != nil {
+		release, err := s.lockSession(ctx, prev.ID, "")
+		if err != nil {
+			return nil, nil, err
+		}
+		defer release()
+		// 取得租约前读取的账号可能已被其他实例修改，重新读取分组、标签与停用状态
+		if prev, err = s.Get(ctx, prev.ID); err != nil {
+			return nil, nil, err
+		}
+		a.ID, a.Created, a.Group, a.Tags, a.Disabled = prev.ID, prev.Created, prev.Group, prev.Tags, prev.Disabled
+	} else {
+		id, err := newID()
+		if err != nil {
+			return nil, nil, err
+		}
+		a.ID = id
+	}
+	if err := s.put(ctx, a); err != nil {
+		return nil, nil, err
+	}
+	return a, prev, nil
+}
+
+// Create 创建账号 a：整理其 cookie 并分配 ID，平台内已有相同标识的账号时返回 ErrDuplicate。
+func (s *Store) Create(ctx context.Context, a Account) (*Account, error) {
+	now := time.Now()
+	a.Created, a.Updated = now, now
+	if err := a.setCookies(a.Cookies, now); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	accounts, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if prev := find(accounts, a.Platform, a.Identity); prev != nil {
+		return nil, fmt.Errorf("%w: %s", ErrDuplicate, prev.ID)
+	}
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	a.ID = id
+	if err := s.put(ctx, &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// Patch 为 Update 的修改内容，nil 字段保持不变。修改 Cookies 时 Lease 为签出时的租约 token，
+// 未签出时为空，由 Update 临时取得租约。
+type Patch struct {
+	Lease    string   `json:"lease"`
+	Cookies  []Cookie `json:"cookies"`
+	Group    *string  `json:"group"`
+	Tags     []string `json:"tags"`
+	Disabled *bool    `json:"disabled"`
+}
+
+// Update 按 p 修改账号 id。修改 cookie 后标识与平台内其他账号相同时返回 ErrDuplicate，
+// 账号已被其他持有者签出时返回 ErrCheckedOut。
+func (s *Store) Update(ctx context.Context, id string, p Patch) (*Account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p.Cookies != nil {
+		release, err := s.lockSession(ctx, id, p.Lease)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+	accounts, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	prev, ok := accounts[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	a := *prev
+	a.Updated = time.Now()
+	if p.Cookies != nil {
+		if err := a.setCookies(p.Cookies, a.Updated); err != nil {
+			return nil, err
+		}
+		if other := find(accounts, a.Platform, a.Identity); other != nil && other.ID != id {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicate, other.ID)
+		}
+		a.Source = SourceAPI
+	}
+	if p.Group != nil {
+		a.Group = *p.Group
+	}
+	if p.Tags != nil {
+		a.Tags = p.Tags
+	}
+	if p.Disabled != nil {
+		a.Disabled = *p.Disabled
+	}
+	if err := s.put(ctx, &a); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// Delete 删除账号 id。
+func (s *Store) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	accounts, err := s.load(ctx)
+	if err != nil {
+		return err
+	}
+	if _, ok := accounts[id]; !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if err := s.backend.Delete(ctx, collection, id); err != nil {
+		return fmt.Errorf("删除账号失败: %w", err)
+	}
+	return nil
+}
+
+// find 返回 accounts 中平台 platform 标识为 identity 的账号，identity 为空或不存在时返回 nil。
+func find(accounts map[string]*Account, platform, identity string) *Account {
+	if identity == "" {
+		return nil
+	}
+	for _, a := range accounts {
+		if a.Platform == platform && a.Identity == identity {
+			return a
+		}
+	}
+	return nil
+}
+
+// Get 返回 ID 为 id 的账号。
+func (s *Store) Get(ctx context.Context, id string) (*Account, error) {
+	accounts, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	a, ok := accounts[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return a, nil
+}
+
+// Filter 为 List 的筛选条件，零值字段不筛选。
+type Filter struct {
+	Platform string
+	Group    string
+	Tag      string
+	Disabled *bool
+}
+
+// match 判断账号 a 是否满足筛选条件。
+func (f Filter) match(a *Account) bool {
+	return (f.Platform == "" || a.Platform == f.Platform) &&
+		(f.Group == "" || a.Group == f.Group) &&
+		(f.Tag == "" || a.HasTag(f.Tag)) &&
+		(f.Disabled == nil || a.Disabled == *f.Disabled)
+}
+
+// List 返回满足 f 的账号，按创建时间排序。
+func (s *Store) List(ctx context.Context, f Filter) ([]*Account, error) {
+	accounts, err := s.load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*Account, 0, len(accounts))
+	for _, a := range accounts {
+		if f.match(a) {
+			out = append(out, a)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Created.Before(out[j].Created) })
+	return out, nil
+}
+
+// Sweep 清理各启用账号中已过期的 cookie：标识 cookie（或全部 cookie）已过期的账号停用并保留原 cookie 供排查，
+// 已被签出或清理期间被修改的账号跳过，留待下次清理。返回清理了 cookie 与停用的账号数。多实例部署时应只在 leader 上运行。
+func (s *Store) Sweep(ctx context.Context) (cleaned, disabled int, err error) {
+	enabled := false
+	list, err := s.List(ctx, Filter{Disabled: &enabled})
+	if err != nil {
+		return 0, 0, err
+	}
+	now := time.Now()
+	for _, a := range list {
+		fresh := normalize(a.Platform, a.Cookies, now.Unix())
+		if len(fresh) == len(a.Cookies) {
+			continue
+		}
+		expired := len(fresh) == 0
+		if st, ok := siteOf(a.Platform); ok && a.Identity != "" {
+			expired = expired || (&Account{Cookies: fresh}).Cookie(st.IdentityCookie) == ""
+		}
+		swept, err := s.sweep(ctx, a, fresh, expired, now)
+		if err != nil {
+			return cleaned, disabled, err
+		}
+		switch {
+		case !swept:
+		case expired:
+			disabled++
+			slog.Warn("账号标识 cookie 已过期，停用账号", "platform", a.Platform, "id", a.ID)
+		default:
+			cleaned++
+		}
+	}
+	return cleaned, disabled, nil
+}
+
+// sweep 以 fresh 替换账号 a 的 cookie，expired 为 true 时改为停用账号。账号已被签出或在读取后被修改时返回 false。
+func (s *Store) sweep(ctx context.Context, a *Account, fresh []Cookie, expired bool, now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	release, err := s.lockSession(ctx, a.ID, "")
+	if errors.Is(err, ErrCheckedOut) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer release()
+	cur, err := s.Get(ctx, a.ID)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !cur.Updated.Equal(a.Updated) {
+		return false, nil
+	}
+	b := *cur
+	b.Updated = now
+	if expired {
+		b.Disabled = true
+	} else {
+		b.Cookies = fresh
+	}
+	return true, s.put(ctx, &b)
+}
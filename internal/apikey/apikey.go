@@ -0,0 +1,459 @@
+// Package apikey 通过管理接口管理 API Key 的生命周期：创建带有效期的 Key、轮换（旧密钥在宽限期内仍有效）、
+// 立即吊销，并记录各 Key 最近一次使用的时间。Key 保存在存储后端中，多个实例共享后端时定期同步。
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go_sign/internal/auth"
+	"go_sign/internal/config"
+	"go_sign/internal/storage"
+)
+
+// 存储后端中的集合。
+const (
+	collection     = "api_keys"
+	usedCollection = "api_key_used"
+)
+
+// SyncInterval 为从存储后端同步 Key、写入最近使用时间的间隔，其他实例上的吊销至多经过该时长生效。
+const SyncInterval = 10 * time.Second
+
+// 轮换宽限期。
+const (
+	DefaultGrace = 24 * time.Hour
+	MaxGrace     = 30 * 24 * time.Hour
+)
+
+// secretPrefix 为生成的密钥前缀，便于在日志与代码仓库中识别泄露的密钥。
+const secretPrefix = "gsk_"
+
+// mutateTTL 为修改一个 Key 时持有其租约的时长。
+const mutateTTL = 30 * time.Second
+
+// Key 的状态。
+const (
+	StateActive  = "active"
+	StateExpired = "expired"
+	StateRevoked = "revoked"
+)
+
+var (
+	// ErrNotFound 表示 Key 不存在。
+	ErrNotFound = errors.New("API Key 不存在")
+	// ErrDuplicate 表示同名的 Key 已存在（含配置文件中的 Key 与已吊销的 Key）。
+	ErrDuplicate = errors.New("同名的 API Key 已存在")
+	// ErrRevoked 表示 Key 已吊销。
+	ErrRevoked = errors.New("API Key 已吊销")
+	// ErrConfigKey 表示 Key 来自配置文件，须修改配置文件后重新加载。
+	ErrConfigKey = errors.New("配置文件中的 API Key 不能通过管理接口修改，请修改配置文件后重新加载")
+	// ErrBusy 表示其他请求正在修改该 Key。
+	ErrBusy = errors.New("其他请求正在修改该 API Key")
+	// ErrInvalid 表示创建 Key 的参数不合法。
+	ErrInvalid = errors.New("参数不合法")
+)
+
+// Info 为接口返回的 Key 信息，不含密钥及其摘要。
+type Info struct {
+	Name         string   `json:"name"`
+	Source       string   `json:"source"`
+	State        string   `json:"state"`
+	Tenant       string   `json:"tenant"`
+	Priority     string   `json:"priority"`
+	Scopes       []string `json:"scopes"`
+	DailyQuota   int64    `json:"daily_quota,omitempty"`
+	MonthlyQuota int64    `json:"monthly_quota,omitempty"`
+	// PreviousExpires 为轮换前的密钥中最晚的失效时间，宽限期已过时为空。
+	PreviousExpires *time.Time `json:"previous_expires,omitempty"`
+	Expires         *time.Time `json:"expires,omitempty"`
+	Revoked         *time.Time `json:"revoked,omitempty"`
+	Created         *time.Time `json:"created,omitempty"`
+	Rotated         *time.Time `json:"rotated,omitempty"`
+	LastUsed        *time.Time `json:"last_used,omitempty"`
+}
+
+// CreateRequest 为创建 Key 的参数。
+type CreateRequest struct {
+	Name         string   `json:"name" binding:"required"`
+	Tenant       string   `json:"tenant"`
+	Priority     string   `json:"priority"`
+	Scopes       []string `json:"scopes"`
+	DailyQuota   int64    `json:"daily_quota"`
+	MonthlyQuota int64    `json:"monthly_quota"`
+	// TTL 为有效期（Go 时长格式，如 720h），为空时不过期。
+	TTL string `json:"ttl"`
+}
+
+// Store 管理存储后端中的 API Key，并同步到 Keyring，并发安全。
+type Store struct {
+	backend storage.Store
+	keyring *auth.Keyring
+
+	mu   sync.Mutex
+	keys map[string]*auth.ManagedKey
+	// used 为存储后端中各 Key 的最近使用时间（含其他实例写入的）
+	used map[string]time.Time
+}
+
+// New 返回保存在 backend 中的 Key 管理，调用 Load 后生效。
+func New(backend storage.Store, keyring *auth.Keyring) *Store {
+	return &Store{backend: backend, keyring: keyring, keys: map[string]*auth.ManagedKey{}, used: map[string]time.Time{}}
+}
+
+// Load 从存储后端读取全部 Key 与最近使用时间，并替换 Keyring 中管理接口创建的 Key。
+func (s *Store) Load(ctx context.Context) error {
+	raws, err := s.backend.List(ctx, collection)
+	if err != nil {
+		return fmt.Errorf("读取 API Key 失败: %w", err)
+	}
+	keys := make(map[string]*auth.ManagedKey, len(raws))
+	for name, raw := range raws {
+		var k auth.ManagedKey
+		if err := json.Unmarshal(raw, &k); err != nil {
+			slog.Warn("跳过无法解析的 API Key", "err", err, "api_key", name)
+			continue
+		}
+		keys[name] = &k
+	}
+	rawUsed, err := s.backend.List(ctx, usedCollection)
+	if err != nil {
+		return fmt.Errorf("读取 API Key 使用时间失败: %w", err)
+	}
+	used := make(map[string]time.Time, len(rawUsed))
+	for name, raw := range rawUsed {
+		if t, err := time.Parse(time.RFC3339Nano, string(raw)); err == nil {
+			used[name] = t
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys, s.used = keys, used
+	s.publish()
+	return nil
+}
+
+// publish 将 s.keys 同步到 Keyring，调用方须持有 s.mu。
+func (s *Store) publish() {
+	list := make([]*auth.ManagedKey, 0, len(s.keys))
+	for _, k := range s.keys {
+		list = append(list, k)
+	}
+	s.keyring.SetManaged(list)
+}
+
+// Run 每隔 SyncInterval 写入本实例记录的最近使用时间并从存储后端同步 Key，直到 ctx 取消。
+func (s *Store) Run(ctx context.Context) {
+	t := time.NewTicker(SyncInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		if err := s.flushUsed(ctx); err != nil {
+			slog.Warn("写入 API Key 使用时间失败", "err", err)
+		}
+		if err := s.Load(ctx); err != nil {
+			slog.Warn("同步 API Key 失败，保留上次的 Key", "err", err)
+		}
+	}
+}
+
+// flushUsed 将本实例中比存储后端更新的最近使用时间写入后端。
+func (s *Store) flushUsed(ctx context.Context) error {
+	local := s.keyring.LastUsed()
+	s.mu.Lock()
+	stale := make(map[string]time.Time)
+	for name, t := range local {
+		if t.After(s.used[name]) {
+			stale[name] = t
+		}
+	}
+	s.mu.Unlock()
+	for name, t := range stale {
+		if err := s.backend.Put(ctx, usedCollection, name, []byte(t.UTC().Format(time.RFC3339Nano))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// List 返回配置文件中的 Key 与管理接口创建的全部 Key（含已过期与已吊销的），按名称排序。
+func (s *Store) List() []Info {
+	local := s.keyring.LastUsed()
+	var out []Info
+	for _, k := range s.keyring.Keys() {
+		if k.Source != auth.SourceConfig {
+			continue
+		}
+		info := Info{Name: k.Name, Source: auth.SourceConfig, State: StateActive, Tenant: k.Tenant.Name, Priority: k.Priority, Scopes: k.Scopes, DailyQuota: k.DailyQuota, MonthlyQuota: k.MonthlyQuota}
+		s.mu.Lock()
+		info.LastUsed = latest(s.used[k.Name], local[k.Name])
+		s.mu.Unlock()
+		out = append(out, info)
+	}
+	s.mu.Lock()
+	for _, k := range s.keys {
+		info := s.info(k, time.Now())
+		info.LastUsed = latest(s.used[k.Name], local[k.Name])
+		out = append(out, info)
+	}
+	s.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// info 返回 k 在 now 时的信息，不含最近使用时间。
+func (s *Store) info(k *auth.ManagedKey, now time.Time) Info {
+	created := k.Created
+	info := Info{
+		Name:         k.Name,
+		Source:       auth.SourceAPI,
+		State:        StateActive,
+		Tenant:       k.Tenant,
+		Priority:     k.Priority,
+		Scopes:       k.Scopes,
+		DailyQuota:   k.DailyQuota,
+		MonthlyQuota: k.MonthlyQuota,
+		Expires:      k.Expires,
+		Revoked:      k.Revoked,
+		Created:      &created,
+		Rotated:      k.Rotated,
+	}
+	switch {
+	case k.Revoked != nil:
+		info.State = StateRevoked
+	case k.Expires != nil && !now.Before(*k.Expires):
+		info.State = StateExpired
+	}
+	for _, p := range k.Previous {
+		if p.Expires.After(now) && (info.PreviousExpires == nil || p.Expires.After(*info.PreviousExpires)) {
+			expires := p.Expires
+			info.PreviousExpires = &expires
+		}
+	}
+	return info
+}
+
+// latest 返回 a、b 中较晚的时间，均为零值时返回 nil。
+func latest(a, b time.Time) *time.Time {
+	if b.After(a) {
+		a = b
+	}
+	if a.IsZero() {
+		return nil
+	}
+	return &a
+}
+
+// Create 按 req 创建 Key，返回其信息与密钥；密钥只在此时返回一次。
+func (s *Store) Create(ctx context.Context, req CreateRequest) (Info, string, error) {
+	k := &auth.ManagedKey{
+		Name:         req.Name,
+		Tenant:       req.Tenant,
+		Priority:     req.Priority,
+		Scopes:       req.Scopes,
+		DailyQuota:   req.DailyQuota,
+		MonthlyQuota: req.MonthlyQuota,
+		Created:      time.Now(),
+	}
+	if k.Tenant == "" {
+		k.Tenant = config.DefaultTenant
+	}
+	if k.Priority == "" {
+		k.Priority = "normal"
+	}
+	if len(k.Scopes) == 0 {
+		k.Scopes = []string{auth.ScopeSign}
+	}
+	if err := s.validate(k); err != nil {
+		return Info{}, "", err
+	}
+	if req.TTL != "" {
+		ttl, err := time.ParseDuration(req.TTL)
+		if err != nil || ttl <= 0 {
+			return Info{}, "", fmt.Errorf("%w: ttl 须为正的时长，如 720h", ErrInvalid)
+		}
+		expires := k.Created.Add(ttl)
+		k.Expires = &expires
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return Info{}, "", err
+	}
+	k.Hash = auth.HashSecret(secret)
+	err = s.mutate(ctx, k.Name, func(prev *auth.ManagedKey) (*auth.ManagedKey, error) {
+		if prev != nil {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicate, k.Name)
+		}
+		return k, nil
+	})
+	if err != nil {
+		return Info{}, "", err
+	}
+	return s.info(k, time.Now()), secret, nil
+}
+
+// Rotate 为 Key name 生成新密钥，旧密钥在 grace 内仍然有效（0 表示立即失效），返回轮换前后的信息与新密钥。
+func (s *Store) Rotate(ctx context.Context, name string, grace time.Duration) (before, after Info, secret string, err error) {
+	if secret, err = newSecret(); err != nil {
+		return Info{}, Info{}, "", err
+	}
+	now := time.Now()
+	var prev, next *auth.ManagedKey
+	err = s.mutate(ctx, name, func(k *auth.ManagedKey) (*auth.ManagedKey, error) {
+		if k == nil {
+			return nil, s.missing(name)
+		}
+		if k.Revoked != nil {
+			return nil, fmt.Errorf("%w: %s", ErrRevoked, name)
+		}
+		prev = k
+		n := *k
+		// 丢弃已过期的旧密钥
+		n.Previous = nil
+		for _, p := range k.Previous {
+			if p.Expires.After(now) {
+				n.Previous = append(n.Previous, p)
+			}
+		}
+		if grace > 0 {
+			n.Previous = append(n.Previous, auth.PreviousSecret{Hash: k.Hash, Expires: now.Add(grace)})
+		}
+		n.Hash = auth.HashSecret(secret)
+		n.Rotated = &now
+		next = &n
+		return next, nil
+	})
+	if err != nil {
+		return Info{}, Info{}, "", err
+	}
+	return s.info(prev, now), s.info(next, now), secret, nil
+}
+
+// Revoke 立即吊销 Key name 的全部密钥，返回吊销前后的信息；记录保留，名称不能再次使用。
+func (s *Store) Revoke(ctx context.Context, name string) (before, after Info, err error) {
+	now := time.Now()
+	var prev, next *auth.ManagedKey
+	err = s.mutate(ctx, name, func(k *auth.ManagedKey) (*auth.ManagedKey, error) {
+		if k == nil {
+			return nil, s.missing(name)
+		}
+		if k.Revoked != nil {
+			return nil, fmt.Errorf("%w: %s", ErrRevoked, name)
+		}
+		prev = k
+		n := *k
+		n.Revoked, n.Previous = &now, nil
+		next = &n
+		return next, nil
+	})
+	if err != nil {
+		return Info{}, Info{}, err
+	}
+	return s.info(prev, now), s.info(next, now), nil
+}
+
+// missing 返回 Key name 不存在时的错误：配置文件中的 Key 返回 ErrConfigKey。
+func (s *Store) missing(name string) error {
+	for _, k := range s.keyring.Keys() {
+		if k.Name == name && k.Source == auth.SourceConfig {
+			return fmt.Errorf("%w: %s", ErrConfigKey, name)
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrNotFound, name)
+}
+
+// mutate 取得 Key name 的租约，以存储后端中的最新值（不存在时为 nil）调用 fn，写入其返回值并同步到 Keyring。
+func (s *Store) mutate(ctx context.Context, name string, fn func(*auth.ManagedKey) (*auth.ManagedKey, error)) error {
+	token, err := newSecret()
+	if err != nil {
+		return err
+	}
+	lease := "api_key:" + name
+	if _, err := s.backend.Acquire(ctx, lease, token, mutateTTL); err != nil {
+		if errors.Is(err, storage.ErrLocked) {
+			return fmt.Errorf("%w: %s", ErrBusy, name)
+		}
+		return fmt.Errorf("取得 API Key 租约失败: %w", err)
+	}
+	defer func() {
+		if err := s.backend.Release(context.WithoutCancel(ctx), lease, token); err != nil {
+			slog.Warn("释放 API Key 租约失败", "err", err, "api_key", name)
+		}
+	}()
+	// 其他实例可能刚修改过该 Key，先从后端读取最新的记录
+	if err := s.Load(ctx); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	next, err := fn(s.keys[name])
+	if err != nil {
+		return err
+	}
+	raw, err := json.Marshal(next)
+	if err != nil {
+		return fmt.Errorf("序列化 API Key 失败: %w", err)
+	}
+	if err := s.backend.Put(ctx, collection, name, raw); err != nil {
+		return fmt.Errorf("保存 API Key 失败: %w", err)
+	}
+	s.keys[name] = next
+	s.publish()
+	return nil
+}
+
+// validate 校验新建的 Key：租户须已定义，名称不能与配置文件中的 Key 重复。
+func (s *Store) validate(k *auth.ManagedKey) error {
+	if strings.TrimSpace(k.Name) != k.Name || strings.ContainsAny(k.Name, "/:") {
+		return fmt.Errorf("%w: name 不能包含空白、/ 或 :", ErrInvalid)
+	}
+	switch k.Priority {
+	case "high", "normal", "low":
+	default:
+		return fmt.Errorf("%w: 不支持的优先级 %q", ErrInvalid, k.Priority)
+	}
+	for _, scope := range k.Scopes {
+		if !slices.Contains(config.Scopes, scope) {
+			return fmt.Errorf("%w: 未知权限 %q，可选 %s", ErrInvalid, scope, strings.Join(config.Scopes, "、"))
+		}
+	}
+	if k.DailyQuota < 0 || k.MonthlyQuota < 0 {
+		return fmt.Errorf("%w: 配额不能为负数", ErrInvalid)
+	}
+	found := false
+	for _, t := range s.keyring.Tenants() {
+		found = found || t.Name == k.Tenant
+	}
+	if !found {
+		return fmt.Errorf("%w: 租户 %q 未定义", ErrInvalid, k.Tenant)
+	}
+	for _, key := range s.keyring.Keys() {
+		if key.Name == k.Name && key.Source == auth.SourceConfig {
+			return fmt.Errorf("%w: %s", ErrDuplicate, k.Name)
+		}
+	}
+	return nil
+}
+
+// newSecret 返回以 secretPrefix 开头的随机密钥。
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("生成密钥失败: %w", err)
+	}
+	return secretPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,97 @@
+package apikey
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/audit"
+	"go_sign/internal/auth"
+)
+
+// rotateRequest 为轮换接口的请求体。
+type rotateRequest struct {
+	// Grace 为旧密钥继续有效的时长（Go 时长格式），为空时为 DefaultGrace，0 表示立即失效。
+	Grace *string `json:"grace"`
+}
+
+// RegisterRoutes 在 r 下注册 Key 管理接口，返回的信息不含密钥，密钥只在创建与轮换时返回一次：
+//   - GET /keys：列出配置文件中的 Key 与管理接口创建的 Key，含状态与最近使用时间；
+//   - POST /keys：以 {name, tenant, priority, scopes, daily_quota, monthly_quota, ttl} 创建 Key，返回 201 与 {key, secret}；
+//   - POST /keys/:name/rotate：以 {grace} 轮换密钥，旧密钥在宽限期内仍然有效，返回 {key, secret}；
+//   - DELETE /keys/:name：立即吊销 Key，返回 204。
+func (s *Store) RegisterRoutes(r gin.IRouter) {
+	g := r.Group("/keys")
+	g.GET("", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"keys": s.List()})
+	})
+	g.POST("", func(c *gin.Context) {
+		var req CreateRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "参数解析失败: " + err.Error()})
+			return
+		}
+		info, secret, err := s.Create(c.Request.Context(), req)
+		if err != nil {
+			respondError(c, err, "创建 API Key 失败")
+			return
+		}
+		audit.SetDiff(c, nil, info)
+		slog.Info("管理接口创建 API Key", "api_key", info.Name, "tenant", info.Tenant, "scopes", info.Scopes, "expires", info.Expires, "operator", auth.FromContext(c).Name)
+		c.JSON(http.StatusCreated, gin.H{"key": info, "secret": secret})
+	})
+	g.POST("/:name/rotate", func(c *gin.Context) {
+		var req rotateRequest
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "参数解析失败: " + err.Error()})
+				return
+			}
+		}
+		grace := DefaultGrace
+		if req.Grace != nil {
+			d, err := time.ParseDuration(*req.Grace)
+			if err != nil || d < 0 || d > MaxGrace {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "grace 须为 0～" + MaxGrace.String() + " 的时长，如 24h"})
+				return
+			}
+			grace = d
+		}
+		before, after, secret, err := s.Rotate(c.Request.Context(), c.Param("name"), grace)
+		if err != nil {
+			respondError(c, err, "轮换 API Key 失败")
+			return
+		}
+		audit.SetDiff(c, before, after)
+		slog.Info("管理接口轮换 API Key", "api_key", after.Name, "grace", grace, "operator", auth.FromContext(c).Name)
+		c.JSON(http.StatusOK, gin.H{"key": after, "secret": secret})
+	})
+	g.DELETE("/:name", func(c *gin.Context) {
+		before, after, err := s.Revoke(c.Request.Context(), c.Param("name"))
+		if err != nil {
+			respondError(c, err, "吊销 API Key 失败")
+			return
+		}
+		audit.SetDiff(c, before, after)
+		slog.Info("管理接口吊销 API Key", "api_key", after.Name, "operator", auth.FromContext(c).Name)
+		c.Status(http.StatusNoContent)
+	})
+}
+
+// respondError 按 err 返回对应的状态码，内部错误同时记录日志 msg。
+func respondError(c *gin.Context, err error, msg string) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrDuplicate), errors.Is(err, ErrRevoked), errors.Is(err, ErrConfigKey), errors.Is(err, ErrBusy):
+		status = http.StatusConflict
+	case errors.Is(err, ErrInvalid):
+		status = http.StatusBadRequest
+	default:
+		slog.Error(msg, "err", err, "api_key", c.Param("name"))
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,196 @@
+// Package app 为各可执行程序（cmd/server、cmd/worker、cmd/cli）共用的启动逻辑：
+// 加载配置与密钥、设置日志、注册与创建签名平台、打开存储后端。
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/account"
+	_ "go_sign/internal/bilibili"
+	"go_sign/internal/browser"
+	"go_sign/internal/config"
+	_ "go_sign/internal/douyin"
+	_ "go_sign/internal/kuaishou"
+	"go_sign/internal/logging"
+	"go_sign/internal/platform"
+	"go_sign/internal/plugin"
+	"go_sign/internal/script"
+	"go_sign/internal/secrets"
+	"go_sign/internal/storage"
+	_ "go_sign/internal/xhs"
+)
+
+// DefaultLogger 设置加载配置前使用的日志：JSON 格式、info 级别，logSecrets 为 false 时对 a1、x-s 等敏感字段脱敏。
+func DefaultLogger(logSecrets bool) {
+	handler, _ := logging.NewHandler(os.Stdout, nil, !logSecrets)
+	slog.SetDefault(slog.New(handler))
+}
+
+// LoadConfig 加载配置文件 path（为空时为空配置），并将配置项中的 vault:、env: 引用替换为密钥后端中的值。
+// 返回的 Resolver 供之后刷新密钥使用。
+func LoadConfig(path string) (*config.Config, *secrets.Resolver, error) {
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	resolver, err := secrets.New(cfg.Secrets)
+	if err != nil {
+		return nil, nil, fmt.Errorf("创建密钥后端失败: %w", err)
+	}
+	if err := ResolveSecrets(resolver, cfg); err != nil {
+		return nil, nil, fmt.Errorf("读取密钥失败: %w", err)
+	}
+	return cfg, resolver, nil
+}
+
+// ResolveSecrets 解析 cfg 中的密钥引用并重新校验配置。
+func ResolveSecrets(r *secrets.Resolver, cfg *config.Config) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := r.Resolve(ctx, cfg); err != nil {
+		return err
+	}
+	return cfg.Validate()
+}
+
+// SetGinMode 按配置 server.gin_mode 设置 gin 的运行模式，须在创建 gin 路由前调用；未配置时沿用环境变量 GIN_MODE。
+func SetGinMode(cfg *config.Config) {
+	if s := cfg.Server; s != nil && s.GinMode != "" {
+		gin.SetMode(s.GinMode)
+	}
+}
+
+// SetupLogging 按配置重新设置日志格式与级别；配置了日志文件时，slog 与 gin 访问日志同时写入标准输出与日志文件，
+// 返回打开的日志文件（未配置时为 nil），由调用方在退出时关闭。
+func SetupLogging(cfg *config.Config, logSecrets bool) (*logging.File, error) {
+	var w io.Writer = os.Stdout
+	var file *logging.File
+	if l := cfg.Log; l != nil && l.File != nil {
+		var err error
+		if file, err = logging.OpenFile(l.File); err != nil {
+			return nil, fmt.Errorf("打开日志文件 %s 失败: %w", l.File.Path, err)
+		}
+		w = io.MultiWriter(os.Stdout, file)
+		gin.DefaultWriter = w
+	}
+	handler, err := logging.NewHandler(w, cfg.Log, !logSecrets)
+	if err != nil {
+		if file != nil {
+			_ = file.Close()
+		}
+		return nil, fmt.Errorf("创建日志处理器失败: %w", err)
+	}
+	slog.SetDefault(slog.New(handler))
+	if file != nil {
+		slog.Info("日志同时写入文件", "path", cfg.Log.File.Path, "max_size_mb", cfg.Log.File.MaxSizeMB, "rotate_interval", cfg.Log.File.RotateInterval)
+	}
+	return file, nil
+}
+
+// RegisterConfigured 注册配置文件中定义的外部插件平台与自定义脚本平台，其余平台无需注册。
+func RegisterConfigured(p config.Platform) error {
+	switch {
+	case p.Plugin != nil:
+		return plugin.Register(p.Name, plugin.Command{
+			Path:    p.Plugin.Command,
+			Args:    p.Plugin.Args,
+			Env:     p.Plugin.Env,
+			Timeout: p.Plugin.Timeout,
+		})
+	case p.Script != nil:
+		return script.Register(p.Name, script.Script{
+			Backend:  p.Script.Backend,
+			URL:      p.Script.URL,
+			File:     p.Script.File,
+			Node:     p.Script.Node,
+			Function: p.Script.Function,
+			Output:   p.Script.Output,
+		})
+	}
+	return nil
+}
+
+// Platforms 返回启用的签名平台名称与各平台的配置解码函数：优先使用配置文件中的 platforms（同时注册其中的
+// 插件与脚本平台），否则使用逗号分隔的 names（如 --platforms 参数）。
+func Platforms(cfg *config.Config, names string) ([]string, []platform.Decoder, error) {
+	var out []string
+	var decoders []platform.Decoder
+	if len(cfg.Platforms) > 0 {
+		for _, p := range cfg.Platforms {
+			if err := RegisterConfigured(p); err != nil {
+				return nil, nil, fmt.Errorf("注册平台 %s 失败: %w", p.Name, err)
+			}
+			out = append(out, p.Name)
+			decoders = append(decoders, p.Decode)
+		}
+		return out, decoders, nil
+	}
+	for _, name := range strings.Split(names, ",") {
+		out = append(out, strings.TrimSpace(name))
+	}
+	return out, nil, nil
+}
+
+// StoreDriver 返回账号与签名记录的存储后端：配置文件的 storage 优先，其次为 accountStore 指定的 BoltDB 文件，
+// 均未指定时为内存。
+func StoreDriver(cfg *config.Config, accountStore string) (driver, dsn string) {
+	if s := cfg.Storage; s != nil {
+		return s.Driver, s.DSN
+	}
+	if accountStore != "" {
+		return storage.DriverBolt, accountStore
+	}
+	return storage.DriverMemory, ""
+}
+
+// OpenStore 打开 StoreDriver 选出的存储后端，同时配置 storage 与 accountStore 时告警并忽略 accountStore。
+func OpenStore(ctx context.Context, cfg *config.Config, accountStore string) (storage.Store, error) {
+	driver, dsn := StoreDriver(cfg, accountStore)
+	if cfg.Storage != nil && accountStore != "" {
+		slog.Warn("已配置 storage，忽略 --account-store", "account_store", accountStore, "driver", driver)
+	}
+	store, err := storage.Open(ctx, driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("打开存储后端 %s 失败: %w", driver, err)
+	}
+	return store, nil
+}
+
+// SweepAccounts 每隔 interval 清理账号库中过期的 cookie，直至 ctx 取消。
+func SweepAccounts(ctx context.Context, accounts *account.Store, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		cleaned, disabled, err := accounts.Sweep(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Error("清理账号库失败", "err", err)
+			}
+			continue
+		}
+		if cleaned > 0 || disabled > 0 {
+			slog.Info("已清理账号库中过期的 cookie", "cleaned", cleaned, "disabled", disabled)
+		}
+	}
+}
+
+// CloseBrowser 关闭共享的浏览器。
+func CloseBrowser(b *browser.Shared) {
+	if err := b.Close(); err != nil {
+		slog.Error("关闭 Playwright 资源失败", "err", err)
+	} else {
+		slog.Info("Playwright 资源已成功关闭")
+	}
+}
@@ -0,0 +1,280 @@
+// Package audit 记录管理接口的操作（重新加载配置、修改日志级别、账号增删改、绑定专属槽位等）：
+// 操作者、时间、接口、结果与修改前后的差异，写入存储后端的审计日志，可通过管理接口查询。
+package audit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/auth"
+	"go_sign/internal/storage"
+)
+
+// LogName 为审计记录在存储后端中的日志名。
+const LogName = "audit"
+
+// DefaultKeep 为默认保留的审计记录条数。
+const DefaultKeep = 10000
+
+// 查询接口的条数限制。
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+// recordTimeout 为写入一条审计记录的超时。
+const recordTimeout = 5 * time.Second
+
+// diffKey 为 gin.Context 中保存操作差异的键。
+const diffKey = "go_sign.audit.diff"
+
+// Entry 为一条审计记录。
+type Entry struct {
+	ID   string    `json:"id"`
+	Time time.Time `json:"time"`
+	// Actor 为操作者：管理接口为 API Key 名称（未启用鉴权时为 anonymous），SIGHUP 触发时为 sighup。
+	Actor  string `json:"actor"`
+	Tenant string `json:"tenant,omitempty"`
+	// Action 为操作，管理接口为方法与路由，如 PATCH /admin/accounts/:id。
+	Action   string `json:"action"`
+	Path     string `json:"path,omitempty"`
+	ClientIP string `json:"client_ip,omitempty"`
+	// Status 为接口返回的状态码，失败的操作同样记录。
+	Status int    `json:"status,omitempty"`
+	Host   string `json:"host"`
+	// Diff 为操作前后的差异，密钥与 cookie 值为脱敏后的形式。
+	Diff []Change `json:"diff,omitempty"`
+}
+
+// Change 为一个字段的修改，Before 或 After 为空分别表示新增或删除。
+type Change struct {
+	Field  string `json:"field"`
+	Before any    `json:"before,omitempty"`
+	After  any    `json:"after,omitempty"`
+}
+
+// Log 为审计日志，并发安全。
+type Log struct {
+	store storage.Store
+	keep  int
+	host  string
+}
+
+// New 返回写入 store 的审计日志，保留最近 keep 条，keep 为 0 时为 DefaultKeep。
+func New(store storage.Store, keep int) *Log {
+	if keep <= 0 {
+		keep = DefaultKeep
+	}
+	host, _ := os.Hostname()
+	return &Log{store: store, keep: keep, host: host}
+}
+
+// Record 写入一条审计记录，ID、Time 与 Host 为空时自动填入。
+func (l *Log) Record(ctx context.Context, e Entry) error {
+	if e.ID == "" {
+		e.ID = newID()
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if e.Host == "" {
+		e.Host = l.host
+	}
+	raw, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("序列化审计记录失败: %w", err)
+	}
+	if err := l.store.Append(ctx, LogName, raw, l.keep); err != nil {
+		return fmt.Errorf("写入审计记录失败: %w", err)
+	}
+	return nil
+}
+
+// Filter 为查询条件，零值字段不参与筛选。
+type Filter struct {
+	Actor  string
+	Action string
+	Since  time.Time
+	Limit  int
+}
+
+// List 返回符合条件的审计记录，新记录在前，至多 f.Limit 条（0 为 DefaultLimit）。
+func (l *Log) List(ctx context.Context, f Filter) ([]Entry, error) {
+	if f.Limit <= 0 {
+		f.Limit = DefaultLimit
+	}
+	// 有筛选条件时扫描全部保留的记录
+	scan := f.Limit
+	if f.Actor != "" || f.Action != "" || !f.Since.IsZero() {
+		scan = l.keep
+	}
+	raws, err := l.store.Tail(ctx, LogName, scan)
+	if err != nil {
+		return nil, fmt.Errorf("读取审计记录失败: %w", err)
+	}
+	out := []Entry{}
+	for _, raw := range raws {
+		var e Entry
+		if err := json.Unmarshal(raw, &e); err != nil {
+			slog.Warn("跳过无法解析的审计记录", "err", err)
+			continue
+		}
+		if !f.Since.IsZero() && e.Time.Before(f.Since) {
+			break
+		}
+		if (f.Actor != "" && e.Actor != f.Actor) || (f.Action != "" && e.Action != f.Action) {
+			continue
+		}
+		out = append(out, e)
+		if len(out) == f.Limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+// Middleware 在处理完管理接口中除 GET、HEAD、OPTIONS 以外的请求后写入审计记录，
+// 须注册在鉴权中间件之后；处理函数可通过 SetDiff 附上修改前后的差异。
+func (l *Log) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		c.Next()
+		key := auth.FromContext(c)
+		e := Entry{
+			Actor:    key.Name,
+			Tenant:   key.Tenant.Name,
+			Action:   c.Request.Method + " " + c.FullPath(),
+			Path:     c.Request.URL.Path,
+			ClientIP: c.ClientIP(),
+			Status:   c.Writer.Status(),
+		}
+		if v, ok := c.Get(diffKey); ok {
+			e.Diff, _ = v.([]Change)
+		}
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(c.Request.Context()), recordTimeout)
+		defer cancel()
+		if err := l.Record(ctx, e); err != nil {
+			slog.Error("记录管理操作失败", "err", err, "action", e.Action, "actor", e.Actor)
+		}
+	}
+}
+
+// Handler 返回查询审计记录的管理接口 GET /admin/audit，可选参数 actor、action、
+// since（RFC 3339 时间）与 limit（默认 DefaultLimit，至多 MaxLimit），返回 {"entries": [...]}，新记录在前。
+func (l *Log) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		f := Filter{Actor: c.Query("actor"), Action: c.Query("action")}
+		if v := c.Query("since"); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "since 须为 RFC 3339 时间，如 2024-01-02T15:04:05Z"})
+				return
+			}
+			f.Since = t
+		}
+		if v := c.Query("limit"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > MaxLimit {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("limit 须为 1～%d 的整数", MaxLimit)})
+				return
+			}
+			f.Limit = n
+		}
+		entries, err := l.List(c.Request.Context(), f)
+		if err != nil {
+			slog.Error("查询审计记录失败", "err", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"entries": entries})
+	}
+}
+
+// SetDiff 为当前请求的审计记录附上 before 与 after 的差异，见 Diff；调用方负责事先脱敏。
+func SetDiff(c *gin.Context, before, after any) {
+	SetChanges(c, Diff(before, after))
+}
+
+// SetChanges 为当前请求的审计记录附上已计算好的差异。
+func SetChanges(c *gin.Context, changes []Change) {
+	c.Set(diffKey, changes)
+}
+
+// Diff 比较 before 与 after 的 JSON 形式，返回按字段路径排序的差异，字段路径形如 tenants[0].daily_quota。
+// before 或 after 为 nil 时分别视为新建或删除。
+func Diff(before, after any) []Change {
+	a, b := map[string]any{}, map[string]any{}
+	flatten("", toJSON(before), a)
+	flatten("", toJSON(after), b)
+	var out []Change
+	for k, v := range a {
+		if w, ok := b[k]; !ok {
+			out = append(out, Change{Field: k, Before: v})
+		} else if !reflect.DeepEqual(v, w) {
+			out = append(out, Change{Field: k, Before: v, After: w})
+		}
+	}
+	for k, w := range b {
+		if _, ok := a[k]; !ok {
+			out = append(out, Change{Field: k, After: w})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Field < out[j].Field })
+	return out
+}
+
+// toJSON 将 v 转换为 JSON 解码后的通用形式，nil 或无法序列化时返回 nil。
+func toJSON(v any) any {
+	if v == nil {
+		return nil
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var out any
+	_ = json.Unmarshal(raw, &out)
+	return out
+}
+
+// flatten 将 JSON 值展开为字段路径到标量值的映射，空对象与空数组不产生字段。
+func flatten(prefix string, v any, out map[string]any) {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, child := range v {
+			if prefix != "" {
+				k = prefix + "." + k
+			}
+			flatten(k, child, out)
+		}
+	case []any:
+		for i, child := range v {
+			flatten(prefix+"["+strconv.Itoa(i)+"]", child, out)
+		}
+	case nil:
+	default:
+		out[prefix] = v
+	}
+}
+
+// newID 返回随机的记录 ID。
+func newID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
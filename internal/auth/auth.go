@@ -0,0 +1,325 @@
+// Package auth 提供基于 API Key 的接口鉴权中间件。
+package auth
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/config"
+)
+
+// contextKey 为 gin 上下文中保存调用方信息的键。
+const contextKey = "go_sign.api_key"
+
+// Tenant 为调用方所属租户的信息。
+type Tenant struct {
+	Name         string
+	DailyQuota   int64
+	MonthlyQuota int64
+}
+
+// Key 为通过鉴权的调用方信息。
+type Key struct {
+	Name         string
+	Priority     string
+	DailyQuota   int64
+	MonthlyQuota int64
+	Tenant       *Tenant
+	// Scopes 为调用方的权限，见 Allows。
+	Scopes []string
+	// Source 为 Key 的来源：config 为配置文件，api 为管理接口创建，jwt 为 JWT 令牌。
+	Source string
+	// secret 为 HMAC 签名的密钥，即配置中的 key。
+	secret string
+	// requireHMAC 为 true 时拒绝直接携带密钥的请求。
+	requireHMAC bool
+	// requireNonce 为 true 时 HMAC 签名的请求须携带 X-Nonce。
+	requireNonce bool
+}
+
+// DefaultTenant 为未配置租户时的默认租户。
+var DefaultTenant = &Tenant{Name: config.DefaultTenant}
+
+// Anonymous 为未启用鉴权时的默认调用方。
+var Anonymous = &Key{Name: "anonymous", Priority: "normal", Tenant: DefaultTenant, Scopes: defaultScopes}
+
+// Key 的来源。
+const (
+	SourceConfig = "config"
+	SourceAPI    = "api"
+	SourceJWT    = "jwt"
+)
+
+// Keyring 保存已配置的 API Key 与租户，可通过 Update 在运行时整体替换（如密钥轮换），并发安全。
+// 管理接口创建的 Key 由 KeyStore 通过 SetManaged 同步，只以密钥的 SHA-256 摘要索引。
+type Keyring struct {
+	mu      sync.RWMutex
+	index   map[string]*Key
+	hashed  map[string]hashedKey // SHA-256(密钥) -> 管理接口创建的 Key
+	byName  map[string]*Key      // HMAC 签名的 key id -> Key
+	keys    []*Key
+	tenants []*Tenant
+	jwt     *JWTVerifier
+	nonces  NonceStore
+
+	// 重建索引的来源，分别由 Update 与 SetManaged 替换
+	cfgTenants []config.Tenant
+	cfgKeys    []config.APIKey
+	managed    []*ManagedKey
+
+	usedMu sync.Mutex
+	used   map[string]time.Time // Key 名称 -> 最近一次通过鉴权的时间
+}
+
+// hashedKey 为管理接口创建的 Key 的一个有效密钥，expires 为零值时不过期。
+type hashedKey struct {
+	key     *Key
+	expires time.Time
+}
+
+// NewKeyring 根据配置创建 Keyring，keys 为空时不启用鉴权。
+// 配置需已通过 config.Validate 校验。
+func NewKeyring(tenants []config.Tenant, keys []config.APIKey) *Keyring {
+	kr := &Keyring{nonces: newMemoryNonces()}
+	kr.Update(tenants, keys)
+	return kr
+}
+
+// Update 以新的配置替换全部租户与 API Key，配置需已通过 config.Validate 校验。
+func (kr *Keyring) Update(tenants []config.Tenant, keys []config.APIKey) {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	kr.cfgTenants, kr.cfgKeys = tenants, keys
+	kr.rebuild()
+}
+
+// SetManaged 以 keys 替换管理接口创建的 Key，已吊销的 Key 不再有效。
+func (kr *Keyring) SetManaged(keys []*ManagedKey) {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	kr.managed = keys
+	kr.rebuild()
+}
+
+// rebuild 按配置与管理接口创建的 Key 重建索引，调用方须持有写锁。
+func (kr *Keyring) rebuild() {
+	next := &Keyring{
+		index:  make(map[string]*Key, len(kr.cfgKeys)),
+		hashed: make(map[string]hashedKey, len(kr.managed)),
+		byName: make(map[string]*Key, len(kr.cfgKeys)),
+	}
+	next.build(kr.cfgTenants, kr.cfgKeys)
+	next.buildManaged(kr.managed)
+	kr.index, kr.hashed, kr.byName, kr.keys, kr.tenants = next.index, next.hashed, next.byName, next.keys, next.tenants
+}
+
+// SetJWT 替换校验 JWT 令牌的 JWTVerifier，v 为 nil 时不接受 JWT。
+func (kr *Keyring) SetJWT(v *JWTVerifier) {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	kr.jwt = v
+}
+
+// build 根据配置填充尚未共享的 kr。
+func (kr *Keyring) build(tenants []config.Tenant, keys []config.APIKey) {
+	byName := make(map[string]*Tenant, len(tenants))
+	for _, t := range tenants {
+		tenant := &Tenant{Name: t.Name, DailyQuota: t.DailyQuota, MonthlyQuota: t.MonthlyQuota}
+		byName[t.Name] = tenant
+		kr.tenants = append(kr.tenants, tenant)
+	}
+	if len(kr.tenants) == 0 {
+		byName[DefaultTenant.Name] = DefaultTenant
+		kr.tenants = []*Tenant{DefaultTenant}
+	}
+	for _, k := range keys {
+		prio := k.Priority
+		if prio == "" {
+			prio = "normal"
+		}
+		name := k.Name
+		if name == "" {
+			name = "unnamed"
+		}
+		scopes := k.Scopes
+		if len(scopes) == 0 {
+			scopes = defaultScopes
+		}
+		key := &Key{
+			Name:         name,
+			Priority:     prio,
+			DailyQuota:   k.DailyQuota,
+			MonthlyQuota: k.MonthlyQuota,
+			Tenant:       byName[k.TenantName()],
+			Scopes:       scopes,
+			Source:       SourceConfig,
+			secret:       k.Key,
+			requireHMAC:  k.RequireHMAC,
+			requireNonce: k.NonceRequired(),
+		}
+		kr.index[k.Key] = key
+		if k.Name != "" {
+			kr.byName[k.Name] = key
+		}
+		kr.keys = append(kr.keys, key)
+	}
+}
+
+// Keys 返回全部调用方信息；未启用鉴权时只包含 Anonymous。
+func (kr *Keyring) Keys() []*Key {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	if len(kr.keys) == 0 {
+		return []*Key{Anonymous}
+	}
+	return kr.keys
+}
+
+// Tenants 返回全部租户。
+func (kr *Keyring) Tenants() []*Tenant {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	return kr.tenants
+}
+
+// lookup 按密钥查找 Key，enabled 为 false 表示未配置 Key 与 JWT、管理接口也未创建过 Key，不启用鉴权。
+// 管理接口创建的 Key 全部吊销后仍启用鉴权。
+func (kr *Keyring) lookup(secret string) (key *Key, enabled bool) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	enabled = len(kr.index) > 0 || len(kr.managed) > 0 || kr.jwt != nil
+	if key = kr.index[secret]; key != nil || secret == "" || len(kr.hashed) == 0 {
+		return key, enabled
+	}
+	if h, ok := kr.hashed[HashSecret(secret)]; ok && (h.expires.IsZero() || time.Now().Before(h.expires)) {
+		return h.key, enabled
+	}
+	return nil, enabled
+}
+
+// markUsed 记录 Key name 通过鉴权的时间。
+func (kr *Keyring) markUsed(name string) {
+	now := time.Now()
+	kr.usedMu.Lock()
+	if kr.used == nil {
+		kr.used = make(map[string]time.Time)
+	}
+	kr.used[name] = now
+	kr.usedMu.Unlock()
+}
+
+// LastUsed 返回各 Key 在本实例最近一次通过鉴权的时间，键为 Key 名称。
+func (kr *Keyring) LastUsed() map[string]time.Time {
+	kr.usedMu.Lock()
+	defer kr.usedMu.Unlock()
+	out := make(map[string]time.Time, len(kr.used))
+	for name, t := range kr.used {
+		out[name] = t
+	}
+	return out
+}
+
+// verifyJWT 校验 JWT 令牌并返回对应的调用方，未配置 JWT 时返回 nil。
+func (kr *Keyring) verifyJWT(token string) (*Key, error) {
+	kr.mu.RLock()
+	v, tenants := kr.jwt, kr.tenants
+	kr.mu.RUnlock()
+	if v == nil {
+		return nil, nil
+	}
+	id, err := v.verify(token)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range tenants {
+		if t.Name == id.Tenant {
+			return &Key{Name: id.Subject, Priority: "normal", Tenant: t, Scopes: id.Scopes, Source: SourceJWT}, nil
+		}
+	}
+	return nil, fmt.Errorf("租户 %q 未定义", id.Tenant)
+}
+
+// lookupName 按 HMAC 签名的 key id 查找 Key。
+func (kr *Keyring) lookupName(name string) (*Key, bool) {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	key, ok := kr.byName[name]
+	return key, ok
+}
+
+// Middleware 返回校验 API Key 的中间件。
+// 密钥可通过 X-API-Key 请求头或 Authorization: Bearer 传递，也可不传密钥而以 HMAC 签名请求（见 VerifyHMAC）；
+// 配置 JWT 时 Bearer 值不是已配置的 Key 时按 JWT 令牌校验。未配置 Key 与 JWT 时不校验。
+// 调用方的权限由 Require 在各路由组上检查。
+func (kr *Keyring) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		secret := extractKey(c.Request)
+		key, enabled := kr.lookup(secret)
+		if !enabled {
+			c.Set(contextKey, Anonymous)
+			c.Next()
+			return
+		}
+		if c.GetHeader(HeaderSignature) != "" {
+			key, err := kr.VerifyHMAC(c.Request)
+			if err != nil {
+				slog.Warn("HMAC 签名校验失败", "err", err, "key_id", c.GetHeader(HeaderKeyID), "path", c.FullPath(), "client_ip", c.ClientIP())
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "HMAC 签名校验失败: " + err.Error()})
+				return
+			}
+			kr.markUsed(key.Name)
+			c.Set(contextKey, key)
+			c.Next()
+			return
+		}
+		if key == nil && looksLikeJWT(secret) {
+			var err error
+			if key, err = kr.verifyJWT(secret); err != nil {
+				slog.Warn("JWT 校验失败", "err", err, "path", c.FullPath(), "client_ip", c.ClientIP())
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "JWT 校验失败: " + err.Error()})
+				return
+			}
+		}
+		if key == nil {
+			slog.Warn("API Key 校验失败", "path", c.FullPath(), "client_ip", c.ClientIP())
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "API Key 无效或缺失"})
+			return
+		}
+		if key.requireHMAC {
+			slog.Warn("API Key 须使用 HMAC 签名", "api_key", key.Name, "path", c.FullPath(), "client_ip", c.ClientIP())
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "该 API Key 须使用 HMAC 签名请求"})
+			return
+		}
+		if key.Source != SourceJWT {
+			kr.markUsed(key.Name)
+		}
+		c.Set(contextKey, key)
+		c.Next()
+	}
+}
+
+// extractKey 从请求头中读取 API Key。
+func extractKey(r *http.Request) string {
+	if k := r.Header.Get("X-API-Key"); k != "" {
+		return k
+	}
+	if v := r.Header.Get("Authorization"); strings.HasPrefix(v, "Bearer ") {
+		return strings.TrimPrefix(v, "Bearer ")
+	}
+	return ""
+}
+
+// FromContext 返回当前请求的调用方信息，未经过鉴权中间件时返回 Anonymous。
+func FromContext(c *gin.Context) *Key {
+	if v, ok := c.Get(contextKey); ok {
+		if k, ok := v.(*Key); ok {
+			return k
+		}
+	}
+	return Anonymous
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// HMAC 签名的请求头。
+const (
+	// HeaderKeyID 为 API Key 的 name。
+	HeaderKeyID = "X-Key-Id"
+	// HeaderTimestamp 为签名时的 Unix 秒级时间戳。
+	HeaderTimestamp = "X-Timestamp"
+	// HeaderSignature 为十六进制编码的 HMAC-SHA256 签名。
+	HeaderSignature = "X-Signature"
+)
+
+// HMACWindow 为 HMAC 签名时间戳与服务端时钟的最大允许偏差，超出视为重放。
+const HMACWindow = 5 * time.Minute
+
+// VerifyHMAC 校验请求的 HMAC 签名并返回对应的 Key。签名为以 Key 的密钥对
+//
+//	<timestamp>\n<METHOD>\n<path?query>\n<hex(sha256(body))>
+//
+// 计算的 HMAC-SHA256，path?query 为服务收到的原始请求路径（含 --base-path 前缀）。
+// 携带 X-Nonce 时 nonce 参与签名（见 SignHMAC），签名通过后同一 Key 的 nonce 在 NonceWindow 内只能使用一次；
+// 默认必须携带，Key 显式配置 require_nonce: false 时允许不携带（兼容旧客户端）。校验会读取请求体，读取后将其还原供后续处理使用。
+func (kr *Keyring) VerifyHMAC(r *http.Request) (*Key, error) {
+	key, ok := kr.lookupName(r.Header.Get(HeaderKeyID))
+	if !ok {
+		return nil, errors.New("key id 无效或缺失")
+	}
+	raw := r.Header.Get(HeaderTimestamp)
+	ts, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("时间戳无效: %q", raw)
+	}
+	if skew := time.Since(time.Unix(ts, 0)); skew > HMACWindow || skew < -HMACWindow {
+		return nil, fmt.Errorf("时间戳与服务端相差 %s，超过允许的 %s", skew.Round(time.Second), HMACWindow)
+	}
+	nonce := r.Header.Get(HeaderNonce)
+	if nonce == "" && key.requireNonce {
+		return nil, errors.New("该 API Key 须携带 " + HeaderNonce + "（旧客户端可为该 Key 配置 require_nonce: false）")
+	}
+	if nonce != "" && !validNonce(nonce) {
+		return nil, fmt.Errorf("nonce 须为 %d～%d 个可打印 ASCII 字符", minNonceLen, maxNonceLen)
+	}
+	sig, err := hex.DecodeString(r.Header.Get(HeaderSignature))
+	if err != nil {
+		return nil, errors.New("签名不是合法的十六进制")
+	}
+	var body []byte
+	if r.Body != nil {
+		if body, err = io.ReadAll(r.Body); err != nil {
+			return nil, fmt.Errorf("读取请求体失败: %w", err)
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	if !hmac.Equal(sig, SignHMAC(key.secret, raw, nonce, r.Method, r.URL.RequestURI(), body)) {
+		return nil, errors.New("签名不匹配")
+	}
+	if nonce != "" {
+		fresh, err := kr.claimNonce(r.Context(), key.Name, nonce)
+		if err != nil {
+			return nil, fmt.Errorf("记录 nonce 失败: %w", err)
+		}
+		if !fresh {
+			nonceReplays.Inc(key.Name)
+			return nil, errors.New("nonce 已使用过，疑似重放")
+		}
+	}
+	return key, nil
+}
+
+// SignHMAC 计算请求的 HMAC-SHA256 签名，timestamp 为请求头中的原始字符串。nonce 非空时签名内容为
+// <timestamp>\n<nonce>\n<METHOD>\n<path?query>\n<hex(sha256(body))>，为空时不含 nonce 一行。
+func SignHMAC(secret, timestamp, nonce, method, requestURI string, body []byte) []byte {
+	sum := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, []byte(secret))
+	if nonce != "" {
+		fmt.Fprintf(mac, "%s\n%s\n%s\n%s\n%s", timestamp, nonce, method, requestURI, hex.EncodeToString(sum[:]))
+	} else {
+		fmt.Fprintf(mac, "%s\n%s\n%s\n%s", timestamp, method, requestURI, hex.EncodeToString(sum[:]))
+	}
+	return mac.Sum(nil)
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go_sign/internal/config"
+)
+
+// signedRequest 构造以 secret 签名的请求，sig 非空时替换计算出的签名。
+func signedRequest(keyID, secret string, ts time.Time, nonce, body, sig string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/v1/sign?x=1", strings.NewReader(body))
+	raw := strconv.FormatInt(ts.Unix(), 10)
+	if sig == "" {
+		sig = hex.EncodeToString(SignHMAC(secret, raw, nonce, r.Method, r.URL.RequestURI(), []byte(body)))
+	}
+	r.Header.Set(HeaderKeyID, keyID)
+	r.Header.Set(HeaderTimestamp, raw)
+	r.Header.Set(HeaderSignature, sig)
+	if nonce != "" {
+		r.Header.Set(HeaderNonce, nonce)
+	}
+	return r
+}
+
+func TestVerifyHMAC(t *testing.T) {
+	optOut := false
+	kr := NewKeyring(nil, []config.APIKey{
+		{Name: "crawler", Key: "s3cret"},
+		{Name: "legacy", Key: "legacy-secret", RequireNonce: &optOut},
+	})
+	now := time.Now()
+	nonce := "0123456789abcdef"
+	tests := []struct {
+		name    string
+		req     *http.Request
+		wantErr string
+	}{
+		{name: "签名正确", req: signedRequest("crawler", "s3cret", now, nonce+"01", `{"uri":"/a"}`, "")},
+		{name: "未知 key id", req: signedRequest("nobody", "s3cret", now, nonce+"02", "", ""), wantErr: "key id"},
+		{name: "密钥错误", req: signedRequest("crawler", "wrong", now, nonce+"03", "", ""), wantErr: "签名不匹配"},
+		{name: "签名非十六进制", req: signedRequest("crawler", "s3cret", now, nonce+"04", "", "zz"), wantErr: "十六进制"},
+		{name: "时钟快于服务端", req: signedRequest("crawler", "s3cret", now.Add(HMACWindow+time.Minute), nonce+"05", "", ""), wantErr: "时间戳"},
+		{name: "时钟慢于服务端", req: signedRequest("crawler", "s3cret", now.Add(-HMACWindow-time.Minute), nonce+"06", "", ""), wantErr: "时间戳"},
+		{name: "窗口内的偏差", req: signedRequest("crawler", "s3cret", now.Add(-HMACWindow+time.Minute), nonce+"07", "", "")},
+		{name: "缺少 nonce", req: signedRequest("crawler", "s3cret", now, "", "", ""), wantErr: "require_nonce"},
+		{name: "nonce 过短", req: signedRequest("crawler", "s3cret", now, "short", "", ""), wantErr: "nonce"},
+		{name: "显式关闭 nonce", req: signedRequest("legacy", "legacy-secret", now, "", "", "")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := kr.VerifyHMAC(tt.req)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Fatalf("期望通过，实际 %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Fatalf("期望包含 %q 的错误，实际 %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestVerifyHMACBodyTampered(t *testing.T) {
+	kr := NewKeyring(nil, []config.APIKey{{Name: "crawler", Key: "s3cret"}})
+	r := signedRequest("crawler", "s3cret", time.Now(), "0123456789abcdef", `{"uri":"/a"}`, "")
+	r.Body = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"uri":"/b"}`)).Body
+	if _, err := kr.VerifyHMAC(r); err == nil {
+		t.Fatal("请求体被篡改后签名仍通过")
+	}
+}
+
+func TestVerifyHMACNonceReplay(t *testing.T) {
+	kr := NewKeyring(nil, []config.APIKey{{Name: "a", Key: "secret-a"}, {Name: "b", Key: "secret-b"}})
+	now := time.Now()
+	nonce := "replay-nonce-0001"
+	if _, err := kr.VerifyHMAC(signedRequest("a", "secret-a", now, nonce, "", "")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kr.VerifyHMAC(signedRequest("a", "secret-a", now, nonce, "", "")); err == nil || !strings.Contains(err.Error(), "重放") {
+		t.Fatalf("同一 nonce 再次使用应被拒绝，实际 %v", err)
+	}
+	// nonce 按 Key 区分
+	if _, err := kr.VerifyHMAC(signedRequest("b", "secret-b", now, nonce, "", "")); err != nil {
+		t.Fatalf("其他 Key 使用相同 nonce 应通过，实际 %v", err)
+	}
+	// 签名不匹配的请求不占用 nonce
+	fresh := "unclaimed-nonce-01"
+	if _, err := kr.VerifyHMAC(signedRequest("a", "wrong", now, fresh, "", "")); err == nil {
+		t.Fatal("密钥错误应被拒绝")
+	}
+	if _, err := kr.VerifyHMAC(signedRequest("a", "secret-a", now, fresh, "", "")); err != nil {
+		t.Fatalf("签名失败的 nonce 不应被记为已使用，实际 %v", err)
+	}
+}
+
+func TestMemoryNoncesExpire(t *testing.T) {
+	now := time.Now()
+	m := newMemoryNonces()
+	m.now = func() time.Time { return now }
+	if ok, _ := m.Claim(context.Background(), "n", time.Minute); !ok {
+		t.Fatal("首次使用应返回 true")
+	}
+	now = now.Add(90 * time.Second)
+	if ok, _ := m.Claim(context.Background(), "n", time.Minute); ok {
+		t.Fatal("ttl 后仍在上一代中，应视为重放")
+	}
+	now = now.Add(2 * time.Minute)
+	if ok, _ := m.Claim(context.Background(), "n", time.Minute); !ok {
+		t.Fatal("超过 2*ttl 后应可再次使用")
+	}
+}
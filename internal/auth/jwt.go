@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go_sign/internal/config"
+)
+
+// JWT 声明的默认名称。
+const (
+	defaultScopesClaim = "scope"
+	defaultTenantClaim = "tenant"
+)
+
+// JWTVerifier 校验 JWT 令牌并取出调用方名称、租户与权限。
+type JWTVerifier struct {
+	key         any // HMAC 密钥或公钥
+	parser      *jwt.Parser
+	scopesClaim string
+	tenantClaim string
+}
+
+// NewJWTVerifier 按配置创建 JWTVerifier，c 为 nil 时返回 nil。
+func NewJWTVerifier(c *config.JWT) (*JWTVerifier, error) {
+	if c == nil {
+		return nil, nil
+	}
+	v := &JWTVerifier{scopesClaim: c.ScopesClaim, tenantClaim: c.TenantClaim}
+	if v.scopesClaim == "" {
+		v.scopesClaim = defaultScopesClaim
+	}
+	if v.tenantClaim == "" {
+		v.tenantClaim = defaultTenantClaim
+	}
+	var methods []string
+	if c.Secret != "" {
+		v.key = []byte(c.Secret)
+		methods = []string{"HS256", "HS384", "HS512"}
+	} else {
+		pem, err := os.ReadFile(c.PublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取 JWT 公钥失败: %w", err)
+		}
+		if v.key, methods, err = parsePublicKey(pem); err != nil {
+			return nil, fmt.Errorf("解析 JWT 公钥 %s 失败: %w", c.PublicKeyFile, err)
+		}
+	}
+	opts := []jwt.ParserOption{jwt.WithValidMethods(methods), jwt.WithExpirationRequired(), jwt.WithLeeway(HMACWindow)}
+	if c.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(c.Issuer))
+	}
+	if c.Audience != "" {
+		opts = append(opts, jwt.WithAudience(c.Audience))
+	}
+	v.parser = jwt.NewParser(opts...)
+	return v, nil
+}
+
+// parsePublicKey 解析 RSA 或 ECDSA 的 PEM 公钥，返回公钥与可用的签名算法。
+func parsePublicKey(pem []byte) (crypto.PublicKey, []string, error) {
+	if key, err := jwt.ParseRSAPublicKeyFromPEM(pem); err == nil {
+		return key, []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512"}, nil
+	}
+	if key, err := jwt.ParseECPublicKeyFromPEM(pem); err == nil {
+		return key, []string{"ES256", "ES384", "ES512"}, nil
+	}
+	return nil, nil, errors.New("不是 RSA 或 ECDSA 公钥")
+}
+
+// jwtIdentity 为令牌中的调用方信息。
+type jwtIdentity struct {
+	Subject string
+	Tenant  string
+	Scopes  []string
+}
+
+// verify 校验令牌的签名、有效期、iss 与 aud，返回其中的调用方信息；令牌须带 sub。
+func (v *JWTVerifier) verify(token string) (*jwtIdentity, error) {
+	claims := jwt.MapClaims{}
+	if _, err := v.parser.ParseWithClaims(token, claims, func(*jwt.Token) (any, error) { return v.key, nil }); err != nil {
+		return nil, err
+	}
+	sub, _ := claims.GetSubject()
+	if sub == "" {
+		return nil, errors.New("令牌缺少 sub")
+	}
+	id := &jwtIdentity{Subject: sub, Tenant: config.DefaultTenant, Scopes: []string{ScopeSign}}
+	if t, ok := claims[v.tenantClaim].(string); ok && t != "" {
+		id.Tenant = t
+	}
+	switch s := claims[v.scopesClaim].(type) {
+	case string:
+		id.Scopes = strings.Fields(s)
+	case []any:
+		id.Scopes = nil
+		for _, item := range s {
+			if str, ok := item.(string); ok {
+				id.Scopes = append(id.Scopes, str)
+			}
+		}
+	}
+	return id, nil
+}
+
+// looksLikeJWT 判断 token 是否形如 JWT（三段以 . 分隔）。
+func looksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
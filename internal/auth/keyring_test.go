@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/config"
+)
+
+// caller 以 X-API-Key 携带 secret 请求经过 kr 鉴权的路由，返回状态码与调用方名称。
+func caller(t *testing.T, kr *Keyring, secret string) (int, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/", kr.Middleware(), func(c *gin.Context) { c.String(http.StatusOK, FromContext(c).Name) })
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if secret != "" {
+		req.Header.Set("X-API-Key", secret)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code, w.Body.String()
+}
+
+func TestKeyringRotation(t *testing.T) {
+	kr := NewKeyring(nil, []config.APIKey{{Name: "crawler", Key: "old-secret"}})
+	if code, name := caller(t, kr, "old-secret"); code != http.StatusOK || name != "crawler" {
+		t.Fatalf("轮换前 = %d %s", code, name)
+	}
+
+	// 配置文件中的密钥轮换后旧密钥立即失效，HMAC 使用新密钥
+	kr.Update(nil, []config.APIKey{{Name: "crawler", Key: "new-secret"}})
+	tests := []struct {
+		secret string
+		code   int
+	}{
+		{"old-secret", http.StatusUnauthorized},
+		{"new-secret", http.StatusOK},
+		{"", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		if code, _ := caller(t, kr, tt.secret); code != tt.code {
+			t.Errorf("轮换后使用 %q = %d，期望 %d", tt.secret, code, tt.code)
+		}
+	}
+	if _, err := kr.VerifyHMAC(signedRequest("crawler", "old-secret", time.Now(), "rotation-nonce-001", "", "")); err == nil {
+		t.Error("轮换后旧密钥的 HMAC 签名仍通过")
+	}
+	if _, err := kr.VerifyHMAC(signedRequest("crawler", "new-secret", time.Now(), "rotation-nonce-002", "", "")); err != nil {
+		t.Errorf("轮换后新密钥的 HMAC 签名失败: %v", err)
+	}
+
+	// 全部 Key 删除后不启用鉴权
+	kr.Update(nil, nil)
+	if code, name := caller(t, kr, ""); code != http.StatusOK || name != Anonymous.Name {
+		t.Errorf("删除全部 Key 后 = %d %s，期望匿名放行", code, name)
+	}
+}
+
+func TestManagedKeyRotation(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Minute), now.Add(time.Hour)
+	kr := NewKeyring(nil, nil)
+	kr.SetManaged([]*ManagedKey{
+		{
+			Name:   "rotated",
+			Tenant: config.DefaultTenant,
+			Scopes: []string{ScopeSign},
+			Hash:   HashSecret("gsk_current"),
+			Previous: []PreviousSecret{
+				{Hash: HashSecret("gsk_grace"), Expires: future},
+				{Hash: HashSecret("gsk_expired"), Expires: past},
+			},
+		},
+		{Name: "expired", Tenant: config.DefaultTenant, Hash: HashSecret("gsk_old_key"), Expires: &past},
+		{Name: "revoked", Tenant: config.DefaultTenant, Hash: HashSecret("gsk_revoked"), Revoked: &past},
+		{Name: "orphan", Tenant: "missing", Hash: HashSecret("gsk_orphan")},
+	})
+	tests := []struct {
+		name   string
+		secret string
+		code   int
+	}{
+		{"当前密钥", "gsk_current", http.StatusOK},
+		{"宽限期内的旧密钥", "gsk_grace", http.StatusOK},
+		{"宽限期已过的旧密钥", "gsk_expired", http.StatusUnauthorized},
+		{"已过期的 Key", "gsk_old_key", http.StatusUnauthorized},
+		{"已吊销的 Key", "gsk_revoked", http.StatusUnauthorized},
+		{"租户不存在的 Key", "gsk_orphan", http.StatusUnauthorized},
+		{"未知密钥", "gsk_unknown", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		code, name := caller(t, kr, tt.secret)
+		if code != tt.code {
+			t.Errorf("%s: 状态码 = %d，期望 %d", tt.name, code, tt.code)
+		}
+		if code == http.StatusOK && name != "rotated" {
+			t.Errorf("%s: 调用方 = %s，期望 rotated", tt.name, name)
+		}
+	}
+
+	// 全部吊销后仍启用鉴权
+	kr.SetManaged([]*ManagedKey{{Name: "revoked", Tenant: config.DefaultTenant, Hash: HashSecret("gsk_revoked"), Revoked: &past}})
+	if code, _ := caller(t, kr, ""); code != http.StatusUnauthorized {
+		t.Errorf("管理接口的 Key 全部吊销后 = %d，期望 401", code)
+	}
+}
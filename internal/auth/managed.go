@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"time"
+)
+
+// ManagedKey 为通过管理接口创建的 API Key，只保存密钥的 SHA-256 摘要，不支持 HMAC 签名。
+type ManagedKey struct {
+	Name         string   `json:"name"`
+	Tenant       string   `json:"tenant"`
+	Priority     string   `json:"priority"`
+	Scopes       []string `json:"scopes"`
+	DailyQuota   int64    `json:"daily_quota,omitempty"`
+	MonthlyQuota int64    `json:"monthly_quota,omitempty"`
+	// Hash 为当前密钥的摘要，见 HashSecret。
+	Hash string `json:"hash"`
+	// Previous 为轮换前的密钥，在各自的 Expires 之前仍然有效。
+	Previous []PreviousSecret `json:"previous,omitempty"`
+	// Expires 为 Key 的过期时间，为空时不过期。
+	Expires *time.Time `json:"expires,omitempty"`
+	// Revoked 为吊销时间，吊销后全部密钥立即失效，记录保留以供查询。
+	Revoked *time.Time `json:"revoked,omitempty"`
+	Created time.Time  `json:"created"`
+	Rotated *time.Time `json:"rotated,omitempty"`
+}
+
+// PreviousSecret 为轮换前仍在宽限期内的密钥。
+type PreviousSecret struct {
+	Hash    string    `json:"hash"`
+	Expires time.Time `json:"expires"`
+}
+
+// HashSecret 返回密钥的十六进制 SHA-256 摘要。
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// buildManaged 将管理接口创建且未吊销的 Key 加入尚未共享的 kr，租户不存在的 Key 跳过；
+// 与配置文件中的 Key 同名时以配置文件为准。
+func (kr *Keyring) buildManaged(keys []*ManagedKey) {
+	tenants := make(map[string]*Tenant, len(kr.tenants))
+	for _, t := range kr.tenants {
+		tenants[t.Name] = t
+	}
+	names := make(map[string]bool, len(kr.keys))
+	for _, k := range kr.keys {
+		names[k.Name] = true
+	}
+	for _, m := range keys {
+		if m.Revoked != nil || names[m.Name] {
+			continue
+		}
+		tenant := tenants[m.Tenant]
+		if tenant == nil {
+			slog.Warn("管理接口创建的 API Key 所属租户不存在，已忽略", "api_key", m.Name, "tenant", m.Tenant)
+			continue
+		}
+		key := &Key{
+			Name:         m.Name,
+			Priority:     m.Priority,
+			DailyQuota:   m.DailyQuota,
+			MonthlyQuota: m.MonthlyQuota,
+			Tenant:       tenant,
+			Scopes:       m.Scopes,
+			Source:       SourceAPI,
+		}
+		var expires time.Time
+		if m.Expires != nil {
+			expires = *m.Expires
+		}
+		kr.hashed[m.Hash] = hashedKey{key: key, expires: expires}
+		for _, p := range m.Previous {
+			prev := p.Expires
+			if !expires.IsZero() && expires.Before(prev) {
+				prev = expires
+			}
+			kr.hashed[p.Hash] = hashedKey{key: key, expires: prev}
+		}
+		kr.keys = append(kr.keys, key)
+	}
+}
@@ -0,0 +1,97 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go_sign/internal/metrics"
+)
+
+// HeaderNonce 为 HMAC 签名请求的一次性随机串，参与签名，同一 Key 的 nonce 在 NonceWindow 内只能使用一次。
+const HeaderNonce = "X-Nonce"
+
+// nonce 的长度限制。
+const (
+	minNonceLen = 16
+	maxNonceLen = 128
+)
+
+// NonceWindow 为记住已使用 nonce 的时长。时间戳在服务端时钟前后 HMACWindow 内的请求均被接受，
+// nonce 须至少记住整个区间，超出区间的重放由时间戳校验拒绝。
+const NonceWindow = 2 * HMACWindow
+
+// nonceReplays 统计因 nonce 重复而拒绝的 HMAC 请求数。
+var nonceReplays = metrics.Default.NewCounterVec(
+	"go_sign_hmac_nonce_replays_total",
+	"因 nonce 已使用过而拒绝的 HMAC 签名请求数",
+	"api_key",
+)
+
+// NonceStore 记录已使用的 nonce，实现须并发安全。
+type NonceStore interface {
+	// Claim 在 name 于 ttl 内未被使用过时记为已使用并返回 true，已使用过时返回 false。
+	Claim(ctx context.Context, name string, ttl time.Duration) (bool, error)
+}
+
+// SetNonceStore 替换记录已使用 nonce 的后端，如多个实例共享的 Redis；默认只记录在本实例内存中。
+func (kr *Keyring) SetNonceStore(s NonceStore) {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	kr.nonces = s
+}
+
+// claimNonce 将 Key key 的 nonce 记为已使用，重放时返回 false。
+func (kr *Keyring) claimNonce(ctx context.Context, key, nonce string) (bool, error) {
+	kr.mu.RLock()
+	s := kr.nonces
+	kr.mu.RUnlock()
+	return s.Claim(ctx, "nonce:"+key+":"+nonce, NonceWindow)
+}
+
+// validNonce 判断 nonce 的长度与字符是否合法：16～128 个可打印的 ASCII 字符。
+func validNonce(nonce string) bool {
+	if len(nonce) < minNonceLen || len(nonce) > maxNonceLen {
+		return false
+	}
+	for i := 0; i < len(nonce); i++ {
+		if nonce[i] <= ' ' || nonce[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// memoryNonces 以两代集合在内存中记录已使用的 nonce：当前一代存满 ttl 后成为上一代，
+// 再过 ttl 后整体丢弃，每个 nonce 被记住 ttl 到 2*ttl，无需逐条清理。
+type memoryNonces struct {
+	mu      sync.Mutex
+	cur     map[string]struct{}
+	prev    map[string]struct{}
+	rotated time.Time
+	now     func() time.Time
+}
+
+// newMemoryNonces 返回空的内存 nonce 记录。
+func newMemoryNonces() *memoryNonces {
+	return &memoryNonces{cur: map[string]struct{}{}, prev: map[string]struct{}{}, rotated: time.Now(), now: time.Now}
+}
+
+func (m *memoryNonces) Claim(_ context.Context, name string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	if elapsed := now.Sub(m.rotated); elapsed >= 2*ttl {
+		m.cur, m.prev, m.rotated = map[string]struct{}{}, map[string]struct{}{}, now
+	} else if elapsed >= ttl {
+		m.cur, m.prev, m.rotated = map[string]struct{}{}, m.cur, now
+	}
+	if _, ok := m.cur[name]; ok {
+		return false, nil
+	}
+	if _, ok := m.prev[name]; ok {
+		return false, nil
+	}
+	m.cur[name] = struct{}{}
+	return true, nil
+}
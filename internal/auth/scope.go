@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/config"
+)
+
+// 调用方的权限，见 config.Scopes。
+const (
+	ScopeSign         = config.ScopeSign
+	ScopeAccountsRead = config.ScopeAccountsRead
+	ScopeAdmin        = config.ScopeAdmin
+)
+
+// defaultScopes 为未配置 scopes 的 API Key 及 Anonymous 的权限，只能签名；管理接口须显式授予。
+var defaultScopes = []string{ScopeSign}
+
+// Allows 判断调用方是否具有权限 scope，admin 包含全部权限。
+func (k *Key) Allows(scope string) bool {
+	return slices.Contains(k.Scopes, ScopeAdmin) || slices.Contains(k.Scopes, scope)
+}
+
+// Require 返回要求调用方具有权限 scope 的中间件，须注册在 Middleware 之后，权限不足时返回 403。
+func Require(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := FromContext(c)
+		if !key.Allows(scope) {
+			slog.Warn("调用方权限不足", "api_key", key.Name, "scopes", key.Scopes, "required", scope, "path", c.FullPath(), "client_ip", c.ClientIP())
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "权限不足，该接口需要 " + scope + " 权限"})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,293 @@
+// Package bilibili 提供哔哩哔哩 WBI 签名平台（w_rid / wts）。
+//
+// WBI 签名只需 img_key、sub_key 两个密钥与 MD5，由纯 Go 实现，不依赖浏览器。
+// 密钥从 nav 接口获取并缓存，过期后由下一次签名在后台刷新，刷新期间与刷新失败后继续使用旧密钥。
+package bilibili
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go_sign/internal/platform"
+	"golang.org/x/sync/singleflight"
+)
+
+// Name 为哔哩哔哩平台的注册名，签名接口为 POST /bilibili/sign。
+const Name = "bilibili"
+
+const (
+	defaultNavURL = "https://api.bilibili.com/x/web-interface/nav"
+	defaultKeyTTL = time.Hour
+	// 刷新密钥失败后的重试间隔，每次失败翻倍，至多 maxRetryBackoff
+	minRetryBackoff  = time.Second
+	maxRetryBackoff  = 5 * time.Minute
+	defaultUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+)
+
+// mixinKeyEncTab 为由 img_key + sub_key 生成 mixin_key 的字符重排表。
+var mixinKeyEncTab = [...]int{
+	46, 47, 18, 2, 53, 8, 23, 32, 15, 50, 10, 31, 58, 3, 45, 35, 27, 43, 5, 49,
+	33, 9, 42, 19, 29, 28, 14, 39, 12, 38, 41, 13, 37, 48, 7, 16, 24, 55, 40,
+	61, 26, 17, 0, 1, 60, 51, 30, 4, 22, 25, 54, 21, 56, 59, 6, 63, 57, 62, 11,
+	36, 20, 34, 44, 52,
+}
+
+func init() {
+	platform.Register(Name, func(decode platform.Decoder) (platform.Platform, error) {
+		p := &Platform{
+			options: options{NavURL: defaultNavURL, KeyTTL: defaultKeyTTL},
+			client:  &http.Client{Timeout: 10 * time.Second},
+		}
+		if err := decode(&p.options); err != nil {
+			return nil, err
+		}
+		if (p.options.ImgKey == "") != (p.options.SubKey == "") {
+			return nil, fmt.Errorf("哔哩哔哩平台的 img_key 与 sub_key 需同时配置")
+		}
+		return p, nil
+	})
+}
+
+// options 为哔哩哔哩平台在配置文件中的可选项。
+type options struct {
+	// NavURL 为获取 WBI 密钥的接口。
+	NavURL string `yaml:"nav_url"`
+	// KeyTTL 为密钥缓存时长，站点约每日轮换一次密钥。
+	KeyTTL time.Duration `yaml:"key_ttl"`
+	// ImgKey、SubKey 为固定密钥，配置后不再请求 NavURL。
+	ImgKey string `yaml:"img_key"`
+	SubKey string `yaml:"sub_key"`
+}
+
+// Platform 为哔哩哔哩 WBI 签名平台。
+type Platform struct {
+	options options
+	client  *http.Client
+
+	// flight 合并并发的密钥刷新，请求 nav 接口期间不持有 mu
+	flight singleflight.Group
+
+	mu        sync.Mutex
+	mixinKey  string
+	fetchedAt time.Time
+	// 最近一次刷新失败的错误、连续失败次数与下次重试的时间，重试前不再请求 nav 接口
+	lastErr  error
+	failures int
+	retryAt  time.Time
+}
+
+// Name 返回平台注册名。
+func (p *Platform) Name() string { return Name }
+
+// Init 获取一次 WBI 密钥，确认签名可用。
+func (p *Platform) Init(ctx context.Context, _ *platform.Env) error {
+	_, err := p.key(ctx)
+	return err
+}
+
+// Sign 生成 w_rid 与 wts，结果以查询参数返回，调用方需与原查询参数一并发送。
+// 参与签名的参数为 uri 中的查询串与 params 合并的结果，wts 取 timestamp（未指定时为当前时间）。
+func (p *Platform) Sign(ctx context.Context, req *platform.SignRequest) (*platform.SignResponse, error) {
+	mixinKey, err := p.key(ctx)
+	if err != nil {
+		return nil, err
+	}
+	values, err := queryValues(req)
+	if err != nil {
+		return nil, err
+	}
+	now := platform.SignTime(req.Timestamp).Unix()
+	wts := strconv.FormatInt(now, 10)
+	return &platform.SignResponse{
+		Params: map[string]string{
+			"w_rid": sign(values, wts, mixinKey),
+			"wts":   wts,
+		},
+		Timestamp: now * 1000,
+	}, nil
+}
+
+// Mock 返回格式与真实签名一致的假 w_rid 与当前 wts。
+func (p *Platform) Mock(req *platform.SignRequest) *platform.SignResponse {
+	now := platform.SignTime(req.Timestamp).Unix()
+	return &platform.SignResponse{
+		Params: map[string]string{
+			"w_rid": platform.MockHex(md5.Size),
+			"wts":   strconv.FormatInt(now, 10),
+		},
+		Timestamp: now * 1000,
+	}
+}
+
+// queryValues 合并 uri 中的查询串与 params。
+func queryValues(req *platform.SignRequest) (url.Values, error) {
+	_, rawQuery, _ := strings.Cut(req.URI, "?")
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, fmt.Errorf("解析查询串失败: %w", err)
+	}
+	for k, v := range req.Params {
+		values.Set(k, v)
+	}
+	return values, nil
+}
+
+// sign 按 WBI 规则计算 w_rid：加入 wts 后按键名排序、过滤值中的 !'()* 并编码，
+// 拼接 mixin_key 后取 MD5。
+func sign(values url.Values, wts, mixinKey string) string {
+	filtered := make(url.Values, len(values)+1)
+	for k, vs := range values {
+		if k == "w_rid" {
+			continue
+		}
+		filtered.Set(k, strings.Map(func(r rune) rune {
+			if strings.ContainsRune("!'()*", r) {
+				return -1
+			}
+			return r
+		}, vs[0]))
+	}
+	filtered.Set("wts", wts)
+	// url.Values.Encode 按键名排序，空格编码为 +，站点要求 %20
+	query := strings.ReplaceAll(filtered.Encode(), "+", "%20")
+	sum := md5.Sum([]byte(query + mixinKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// mixin 由 img_key + sub_key 按重排表生成 32 位 mixin_key。
+func mixin(imgKey, subKey string) string {
+	raw := imgKey + subKey
+	var b strings.Builder
+	for _, i := range mixinKeyEncTab {
+		if i < len(raw) {
+			b.WriteByte(raw[i])
+		}
+	}
+	s := b.String()
+	if len(s) > 32 {
+		s = s[:32]
+	}
+	return s
+}
+
+// key 返回缓存的 mixin_key。密钥过期时已有旧密钥的请求触发后台刷新并立即返回旧密钥；
+// 尚无密钥时等待刷新完成。刷新失败后按退避间隔重试，期间直接返回旧密钥或上次的错误。
+func (p *Platform) key(ctx context.Context) (string, error) {
+	if p.options.ImgKey != "" {
+		return mixin(p.options.ImgKey, p.options.SubKey), nil
+	}
+	p.mu.Lock()
+	key, lastErr := p.mixinKey, p.lastErr
+	fresh := key != "" && time.Since(p.fetchedAt) < p.options.KeyTTL
+	backoff := time.Now().Before(p.retryAt)
+	p.mu.Unlock()
+	switch {
+	case fresh:
+		return key, nil
+	case backoff && key != "":
+		return key, nil
+	case backoff:
+		return "", lastErr
+	}
+	ch := p.flight.DoChan("nav", p.refresh)
+	if key != "" {
+		return key, nil
+	}
+	select {
+	case r := <-ch:
+		if r.Err != nil {
+			return "", r.Err
+		}
+		return r.Val.(string), nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// refresh 请求 nav 接口并更新 mixin_key，失败时记录错误并延长重试间隔。
+// 请求不随调用方的 ctx 取消，超时由 client 控制，避免一个请求取消使等待同一刷新的其他请求失败。
+func (p *Platform) refresh() (any, error) {
+	imgKey, subKey, err := p.fetchKeys(context.Background())
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		p.failures++
+		wait := min(minRetryBackoff<<min(p.failures-1, 16), maxRetryBackoff)
+		p.lastErr, p.retryAt = err, time.Now().Add(wait)
+		if p.mixinKey != "" {
+			slog.Warn("刷新 WBI 密钥失败，继续使用旧密钥", "err", err, "fetched_at", p.fetchedAt, "retry_in", wait)
+		} else {
+			slog.Warn("获取 WBI 密钥失败", "err", err, "retry_in", wait)
+		}
+		return "", err
+	}
+	p.mixinKey, p.fetchedAt = mixin(imgKey, subKey), time.Now()
+	p.lastErr, p.failures, p.retryAt = nil, 0, time.Time{}
+	slog.Info("已刷新 WBI 密钥", "img_key", imgKey, "sub_key", subKey)
+	return p.mixinKey, nil
+}
+
+// fetchKeys 从 nav 接口读取 img_key 与 sub_key（图片文件名去掉扩展名）。
+// 未登录时接口返回 -101，但仍包含 wbi_img。
+func (p *Platform) fetchKeys(ctx context.Context) (imgKey, subKey string, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.options.NavURL, nil)
+	if err != nil {
+		return "", "", fmt.Errorf("创建 WBI 密钥请求失败: %w", err)
+	}
+	req.Header.Set("User-Agent", defaultUserAgent)
+	req.Header.Set("Referer", "https://www.bilibili.com/")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("获取 WBI 密钥失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("获取 WBI 密钥失败: HTTP %d", resp.StatusCode)
+	}
+	var body struct {
+		Data struct {
+			WbiImg struct {
+				ImgURL string `json:"img_url"`
+				SubURL string `json:"sub_url"`
+			} `json:"wbi_img"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", "", fmt.Errorf("解析 WBI 密钥响应失败: %w", err)
+	}
+	imgKey = keyFromURL(body.Data.WbiImg.ImgURL)
+	subKey = keyFromURL(body.Data.WbiImg.SubURL)
+	if imgKey == "" || subKey == "" {
+		return "", "", fmt.Errorf("WBI 密钥响应中缺少 wbi_img")
+	}
+	return imgKey, subKey, nil
+}
+
+// keyFromURL 取图片 URL 的文件名并去掉扩展名。
+func keyFromURL(u string) string {
+	base := path.Base(u)
+	if base == "." || base == "/" {
+		return ""
+	}
+	return strings.TrimSuffix(base, path.Ext(base))
+}
+
+// HealthCheck 检查 WBI 密钥是否可用。
+func (p *Platform) HealthCheck(ctx context.Context) error {
+	_, err := p.key(ctx)
+	return err
+}
+
+// Close 无需释放资源。
+func (p *Platform) Close() error { return nil }
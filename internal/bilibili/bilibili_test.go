@@ -0,0 +1,124 @@
+package bilibili
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// 公开文档中的 WBI 示例：img_key、sub_key 与 wts=1702204169 时 foo=114&bar=514&zab=1919810 的签名结果。
+const (
+	vectorImgKey   = "7cd084941338484aae1ad9425b84077c"
+	vectorSubKey   = "4932caff0ff746eab6f01bf08b70ac45"
+	vectorMixinKey = "ea1db124af3c7062474693fa704f4ff8"
+)
+
+func TestMixin(t *testing.T) {
+	if got := mixin(vectorImgKey, vectorSubKey); got != vectorMixinKey {
+		t.Errorf("mixin = %s，期望 %s", got, vectorMixinKey)
+	}
+}
+
+func TestSign(t *testing.T) {
+	tests := []struct {
+		name   string
+		values url.Values
+		want   string
+	}{
+		{
+			name:   "公开示例",
+			values: url.Values{"foo": {"114"}, "bar": {"514"}, "zab": {"1919810"}},
+			want:   "8f6f2b5b3d485fe1886cec6a0be8c5d4",
+		},
+		{
+			name:   "忽略已有的 w_rid 与 wts",
+			values: url.Values{"foo": {"114"}, "bar": {"514"}, "zab": {"1919810"}, "w_rid": {"stale"}, "wts": {"1"}},
+			want:   "8f6f2b5b3d485fe1886cec6a0be8c5d4",
+		},
+		{
+			name:   "过滤值中的 !'()*",
+			values: url.Values{"foo": {"1!1'4"}, "bar": {"(514)*"}, "zab": {"1919810"}},
+			want:   "8f6f2b5b3d485fe1886cec6a0be8c5d4",
+		},
+	}
+	for _, tt := range tests {
+		if got := sign(tt.values, "1702204169", vectorMixinKey); got != tt.want {
+			t.Errorf("%s: w_rid = %s，期望 %s", tt.name, got, tt.want)
+		}
+	}
+
+	// 空格编码为 %20 而非 +，与 + 号本身的编码不同
+	space := sign(url.Values{"keyword": {"a b"}}, "1702204169", vectorMixinKey)
+	plus := sign(url.Values{"keyword": {"a+b"}}, "1702204169", vectorMixinKey)
+	if space == plus {
+		t.Error("空格与 + 号的签名不应相同")
+	}
+}
+
+func TestKeyFromURL(t *testing.T) {
+	for in, want := range map[string]string{
+		"https://i0.hdslb.com/bfs/wbi/7cd084941338484aae1ad9425b84077c.png": vectorImgKey,
+		"https://i0.hdslb.com/bfs/wbi/4932caff0ff746eab6f01bf08b70ac45":     vectorSubKey,
+		"": "",
+	} {
+		if got := keyFromURL(in); got != want {
+			t.Errorf("keyFromURL(%q) = %q，期望 %q", in, got, want)
+		}
+	}
+}
+
+func TestKeyRefresh(t *testing.T) {
+	var hits atomic.Int32
+	var failing atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		fmt.Fprintf(w, `{"data":{"wbi_img":{"img_url":"https://i0.hdslb.com/bfs/wbi/%s.png","sub_url":"https://i0.hdslb.com/bfs/wbi/%s.png"}}}`, vectorImgKey, vectorSubKey)
+	}))
+	defer srv.Close()
+	p := &Platform{options: options{NavURL: srv.URL, KeyTTL: time.Hour}, client: srv.Client()}
+	ctx := context.Background()
+
+	if key, err := p.key(ctx); err != nil || key != vectorMixinKey {
+		t.Fatalf("key = %q, %v", key, err)
+	}
+
+	// 密钥过期且刷新失败：继续返回旧密钥，退避期间不再请求 nav 接口
+	failing.Store(true)
+	p.mu.Lock()
+	p.fetchedAt = time.Now().Add(-2 * time.Hour)
+	p.mu.Unlock()
+	before := hits.Load()
+	for i := 0; i < 20; i++ {
+		if key, err := p.key(ctx); err != nil || key != vectorMixinKey {
+			t.Fatalf("刷新失败时 key = %q, %v，期望旧密钥", key, err)
+		}
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		p.mu.Lock()
+		retryAt := p.retryAt
+		p.mu.Unlock()
+		if !retryAt.IsZero() {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("后台刷新未完成")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := p.key(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := hits.Load() - before; n != 1 {
+		t.Errorf("刷新失败后请求 nav 接口 %d 次，期望 1 次", n)
+	}
+}
@@ -0,0 +1,147 @@
+// Package browser 管理可在多个签名平台间共享的 Playwright 浏览器进程。
+package browser
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/mxschmitt/playwright-go"
+)
+
+// Browser 封装 Playwright 进程与 Chromium 实例。
+type Browser struct {
+	pw       *playwright.Playwright
+	browser  playwright.Browser
+	versions Versions
+}
+
+// Launch 启动 Playwright 与无头 Chromium，浏览器版本不在测试范围内时输出告警。
+func Launch() (*Browser, error) {
+	return LaunchChecked(VersionCheckWarn)
+}
+
+// LaunchChecked 启动 Playwright 与无头 Chromium，并按 check 模式检查浏览器版本。
+func LaunchChecked(check string) (*Browser, error) {
+	b := &Browser{}
+	var err error
+	slog.Info("启动 Playwright...")
+	b.pw, err = playwright.Run()
+	if err != nil {
+		slog.Error("Playwright 启动失败", "err", err)
+		return nil, fmt.Errorf("启动 Playwright 失败: %w", err)
+	}
+	slog.Info("启动 Chromium...")
+	b.browser, err = b.pw.Chromium.Launch(playwright.BrowserTypeLaunchOptions{
+		Headless: playwright.Bool(true),
+	})
+	if err != nil {
+		slog.Error("Chromium 启动失败", "err", err)
+		_ = b.Close()
+		return nil, fmt.Errorf("启动 Chromium 失败: %w", err)
+	}
+	b.versions = readVersions(b.browser)
+	slog.Info("Chromium 已启动", "chromium", b.versions.Chromium, "driver", b.versions.Driver)
+	if err := checkVersions(b.versions, check); err != nil {
+		slog.Error("浏览器版本检查未通过", "err", err)
+		_ = b.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+// Versions 返回 Playwright 驱动与浏览器的版本。
+func (b *Browser) Versions() Versions {
+	return b.versions
+}
+
+// Connected 判断浏览器进程是否仍然连接，崩溃或已关闭时返回 false。
+func (b *Browser) Connected() bool {
+	return b.browser != nil && b.browser.IsConnected()
+}
+
+// NewContext 创建一个新的浏览器上下文。
+func (b *Browser) NewContext(options ...playwright.BrowserNewContextOptions) (playwright.BrowserContext, error) {
+	return b.browser.NewContext(options...)
+}
+
+// Close 关闭 Chromium 与 Playwright 进程。
+func (b *Browser) Close() error {
+	var firstErr error
+	if b.browser != nil {
+		if err := b.browser.Close(); err != nil {
+			slog.Warn("关闭浏览器失败", "err", err)
+			firstErr = fmt.Errorf("关闭浏览器失败: %w", err)
+		}
+	}
+	if b.pw != nil {
+		if err := b.pw.Stop(); err != nil {
+			slog.Warn("关闭 Playwright 失败", "err", err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("关闭 Playwright 失败: %w", err)
+			}
+		}
+	}
+	return firstErr
+}
+
+// Shared 为按需启动的共享浏览器：首次 Get 时启动，纯 Go 平台不会触发浏览器启动。
+type Shared struct {
+	// VersionCheck 为启动时浏览器版本检查的模式，为空时使用 VersionCheckWarn。
+	VersionCheck string
+
+	mu      sync.Mutex
+	browser *Browser
+}
+
+// Get 返回共享浏览器，尚未启动时启动之；启动失败时下次调用会重试。
+func (s *Shared) Get() (*Browser, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.browser != nil {
+		return s.browser, nil
+	}
+	check := s.VersionCheck
+	if check == "" {
+		check = VersionCheckWarn
+	}
+	b, err := LaunchChecked(check)
+	if err != nil {
+		return nil, err
+	}
+	s.browser = b
+	return b, nil
+}
+
+// Versions 返回已启动浏览器的版本，尚未启动时返回 nil。
+func (s *Shared) Versions() *Versions {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.browser == nil {
+		return nil
+	}
+	v := s.browser.versions
+	return &v
+}
+
+// Replace 以已启动的浏览器 b 替换共享浏览器（用于蓝绿升级），返回原浏览器，尚未启动时返回 nil。
+// 调用方负责在原浏览器上的页面全部关闭后关闭原浏览器。
+func (s *Shared) Replace(b *Browser) *Browser {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old := s.browser
+	s.browser = b
+	return old
+}
+
+// Close 关闭已启动的共享浏览器，未启动时不做任何事。
+func (s *Shared) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.browser == nil {
+		return nil
+	}
+	err := s.browser.Close()
+	s.browser = nil
+	return err
+}
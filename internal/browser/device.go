@@ -0,0 +1,115 @@
+package browser
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mxschmitt/playwright-go"
+)
+
+// Device 描述浏览器上下文模拟的设备。UserAgent、视口、触屏与 navigator.platform 须相互一致，
+// 否则页面脚本可据此识别模拟环境。
+type Device struct {
+	// Name 为预设名称，用于配置与日志。
+	Name string
+	// UserAgent 中的 {chromium} 替换为实际启动的 Chromium 版本，使 UA 与浏览器行为一致。
+	UserAgent         string
+	Width             int
+	Height            int
+	DeviceScaleFactor int
+	IsMobile          bool
+	HasTouch          bool
+	// Platform 为页面中 navigator.platform 的值，为空时不覆盖。
+	Platform string
+}
+
+// defaultChromium 为无法读取 Chromium 版本时 UA 中使用的版本号。
+const defaultChromium = "120.0.0.0"
+
+// 内置的设备预设。
+var (
+	// DeviceDesktopChromeWin 模拟 Windows 上 1080p 显示器的 Chrome。
+	DeviceDesktopChromeWin = &Device{
+		Name:              "desktop-chrome-win",
+		UserAgent:         "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/{chromium} Safari/537.36",
+		Width:             1920,
+		Height:            1080,
+		DeviceScaleFactor: 1,
+		Platform:          "Win32",
+	}
+	// DeviceMacBook 模拟 13 英寸 MacBook 上的 Chrome。
+	DeviceMacBook = &Device{
+		Name:              "macbook",
+		UserAgent:         "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/{chromium} Safari/537.36",
+		Width:             1440,
+		Height:            900,
+		DeviceScaleFactor: 2,
+		Platform:          "MacIntel",
+	}
+	// DeviceIPhoneSafari 模拟 iPhone 13 上的 Safari。
+	DeviceIPhoneSafari = &Device{
+		Name:              "iphone-safari",
+		UserAgent:         "Mozilla/5.0 (iPhone; CPU iPhone OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/16.6 Mobile/15E148 Safari/604.1",
+		Width:             390,
+		Height:            844,
+		DeviceScaleFactor: 3,
+		IsMobile:          true,
+		HasTouch:          true,
+		Platform:          "iPhone",
+	}
+)
+
+// devices 为按名称索引的设备预设。
+var devices = map[string]*Device{}
+
+func init() {
+	for _, d := range []*Device{DeviceDesktopChromeWin, DeviceMacBook, DeviceIPhoneSafari} {
+		devices[d.Name] = d
+	}
+}
+
+// LookupDevice 返回名为 name 的设备预设，不存在时返回错误并列出可用的预设。
+func LookupDevice(name string) (*Device, error) {
+	if d, ok := devices[name]; ok {
+		return d, nil
+	}
+	return nil, fmt.Errorf("未知的设备预设 %q，可用: %s", name, strings.Join(DeviceNames(), ", "))
+}
+
+// DeviceNames 返回全部设备预设的名称，按字母序排列。
+func DeviceNames() []string {
+	names := make([]string, 0, len(devices))
+	for name := range devices {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Apply 返回设置了设备模拟参数（UA、视口、缩放、移动端与触屏）的浏览器上下文参数，其余参数沿用 o；
+// chromium 为实际启动的 Chromium 版本。d 为 nil 时原样返回 o，使用浏览器默认值。
+func (d *Device) Apply(o playwright.BrowserNewContextOptions, chromium string) playwright.BrowserNewContextOptions {
+	if d == nil {
+		return o
+	}
+	if chromium == "" {
+		chromium = defaultChromium
+	}
+	o.UserAgent = playwright.String(strings.ReplaceAll(d.UserAgent, "{chromium}", chromium))
+	o.Viewport = &playwright.BrowserNewContextViewport{Width: playwright.Int(d.Width), Height: playwright.Int(d.Height)}
+	o.DeviceScaleFactor = playwright.Int(d.DeviceScaleFactor)
+	o.IsMobile = playwright.Bool(d.IsMobile)
+	o.HasTouch = playwright.Bool(d.HasTouch)
+	return o
+}
+
+// InitScript 返回在页面脚本之前执行的覆盖脚本，使 navigator.platform 与 UA 一致；无需覆盖时返回空字符串。
+func (d *Device) InitScript() string {
+	if d == nil || d.Platform == "" {
+		return ""
+	}
+	platform, _ := json.Marshal(d.Platform)
+	return fmt.Sprintf(`Object.defineProperty(Navigator.prototype, 'platform', { get: () => %s, configurable: true });`, platform)
+}
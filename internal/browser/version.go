@@ -0,0 +1,65 @@
+package browser
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/mxschmitt/playwright-go"
+)
+
+// 启动时浏览器版本检查的模式。
+const (
+	// VersionCheckWarn 在版本不在测试范围内时输出告警日志并继续启动。
+	VersionCheckWarn = "warn"
+	// VersionCheckStrict 在版本不在测试范围内时拒绝启动。
+	VersionCheckStrict = "strict"
+	// VersionCheckOff 不检查版本。
+	VersionCheckOff = "off"
+)
+
+// 经过测试的 Chromium 主版本范围，超出范围时签名 JS 可能因浏览器行为差异而静默失败。
+const (
+	MinTestedChromium = 88
+	MaxTestedChromium = 90
+)
+
+// Versions 为 Playwright 驱动与浏览器的版本。
+type Versions struct {
+	Driver   string `json:"driver"`
+	Chromium string `json:"chromium"`
+	// Tested 为 Chromium 版本是否在经过测试的范围内。
+	Tested bool `json:"tested"`
+}
+
+// ValidVersionCheck 判断版本检查模式是否合法。
+func ValidVersionCheck(mode string) bool {
+	switch mode {
+	case VersionCheckWarn, VersionCheckStrict, VersionCheckOff:
+		return true
+	}
+	return false
+}
+
+// readVersions 读取已启动浏览器的版本。
+func readVersions(b playwright.Browser) Versions {
+	v := Versions{Driver: playwright.PLAYWRIGHT_CLI_VERSION, Chromium: b.Version()}
+	major, err := strconv.Atoi(strings.SplitN(v.Chromium, ".", 2)[0])
+	v.Tested = err == nil && major >= MinTestedChromium && major <= MaxTestedChromium
+	return v
+}
+
+// checkVersions 按 mode 检查版本：warn 时只输出告警，strict 时返回错误。
+func checkVersions(v Versions, mode string) error {
+	if mode == VersionCheckOff || v.Tested {
+		return nil
+	}
+	if mode == VersionCheckStrict {
+		return fmt.Errorf("Chromium %s 不在经过测试的版本范围 %d-%d 内（Playwright 驱动 %s），可通过 --browser-version-check=warn 忽略",
+			v.Chromium, MinTestedChromium, MaxTestedChromium, v.Driver)
+	}
+	slog.Warn("Chromium 版本不在经过测试的范围内，签名可能失败",
+		"chromium", v.Chromium, "driver", v.Driver, "tested_min", MinTestedChromium, "tested_max", MaxTestedChromium)
+	return nil
+}
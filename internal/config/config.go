@@ -0,0 +1,739 @@
+// Package config 负责加载与校验服务的 YAML 配置文件。
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"go_sign/internal/schedule"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultTenant 为未配置租户时使用的默认租户名。
+const DefaultTenant = "default"
+
+// Config 为配置文件的顶层结构。
+type Config struct {
+	// Platforms 为启用的签名平台列表，为空时使用 --platforms 参数。
+	Platforms []Platform `yaml:"platforms"`
+	// Tenants 为租户列表，每个租户拥有独立的浏览器上下文、配额与指标标签。
+	// 为空时所有 API Key 归属 DefaultTenant。
+	Tenants []Tenant `yaml:"tenants"`
+	// APIKeys 为允许访问签名接口的 API Key 列表，与 JWT 均为空时不启用鉴权。
+	APIKeys []APIKey `yaml:"api_keys"`
+	// JWT 为 JWT 令牌鉴权配置，为空时只接受 API Key。
+	JWT *JWT `yaml:"jwt"`
+	// Shadow 为流量镜像配置，为空时不镜像。
+	Shadow *Shadow `yaml:"shadow"`
+	// Server 为 HTTP 服务配置，为空时使用默认的 gin 运行模式，不加载自定义中间件。
+	Server *Server `yaml:"server"`
+	// Proxy 为服务前的反向代理配置，为空时不信任任何代理，客户端 IP 取 TCP 连接的对端地址。
+	Proxy *Proxy `yaml:"proxy"`
+	// IPFilter 为签名路由的客户端 IP 名单，为空时不限制；重新加载配置时替换。
+	IPFilter *IPFilter `yaml:"ip_filter"`
+	// Log 为日志配置，为空时只输出到标准输出。
+	Log *Log `yaml:"log"`
+	// ErrorReport 为错误上报配置，为空时不上报。
+	ErrorReport *ErrorReport `yaml:"error_report"`
+	// Encryption 为落盘账号凭据的加密配置，为空时从环境变量 GO_SIGN_ENCRYPTION_KEY 读取密钥，
+	// 仍未配置时不加密。
+	Encryption *Encryption `yaml:"encryption"`
+	// Secrets 为外部密钥后端配置，配置项可写作 vault:<path>#<field> 或 env:NAME 引用后端中的值。
+	Secrets *Secrets `yaml:"secrets"`
+	// Maintenance 为定时回收浏览器上下文的维护窗口，为空时不定时回收。
+	Maintenance *Maintenance `yaml:"maintenance"`
+	// StealthUpdate 为定期检查上游 stealth.min.js 更新的配置，为空时不检查。
+	StealthUpdate *StealthUpdate `yaml:"stealth_update"`
+	// Storage 为账号与签名记录的持久化后端，为空时账号保存在 --account-store 指定的 BoltDB 文件中
+	// （未指定时只保存在内存中），签名记录只保存在内存中。
+	Storage *Storage `yaml:"storage"`
+	// Cluster 为多实例部署的 leader 选举与全局任务配置，为空时不选举，全局任务在本实例运行。
+	Cluster *Cluster `yaml:"cluster"`
+	// AccountLimits 为单个账号的签名次数上限，为空时不限制。
+	AccountLimits *AccountLimits `yaml:"account_limits"`
+	// Webhooks 为生命周期事件的回调地址，为空时不回调，重新加载配置时生效。
+	Webhooks []Webhook `yaml:"webhooks"`
+	// ResponseSigning 为签名类接口响应体的完整性签名配置，为空时不签名。
+	ResponseSigning *ResponseSigning `yaml:"response_signing"`
+	// RequestEncryption 为请求中账号凭据（a1、web_session 等 cookie）的传输加密配置，为空时只接受明文。
+	RequestEncryption *RequestEncryption `yaml:"request_encryption"`
+}
+
+// Webhook 描述一个生命周期事件回调：事件以 JSON POST 到 URL，失败时按指数退避重试。
+type Webhook struct {
+	URL string `yaml:"url"`
+	// Secret 为计算 X-Webhook-Signature 的 HMAC 密钥，可写作 vault:/env: 引用，为空时不签名。
+	Secret string `yaml:"secret"`
+	// Events 为订阅的事件：signer.restarted、captcha.detected、account.banned、job.completed、config.reloaded，
+	// 为空时订阅全部事件。
+	Events []string `yaml:"events"`
+	// Timeout 为单次请求的超时，默认 10 秒。
+	Timeout time.Duration `yaml:"timeout"`
+	// MaxRetries 为失败后的重试次数，默认 3 次，-1 表示不重试。
+	MaxRetries int `yaml:"max_retries"`
+}
+
+// WebhookEvents 为可订阅的回调事件。
+var WebhookEvents = []string{"signer.restarted", "captcha.detected", "account.banned", "job.completed", "config.reloaded"}
+
+// 响应签名的算法。
+const (
+	ResponseSigningHMAC    = "hmac-sha256"
+	ResponseSigningEd25519 = "ed25519"
+)
+
+// ResponseSigning 描述响应体的完整性签名：下游经中间环节转发签名结果时，可按 X-Response-Key-Id 选择密钥验签，
+// 确认结果未被篡改。
+type ResponseSigning struct {
+	// KeyID 为签名密钥的标识，随 X-Response-Key-Id 返回，便于下游在轮换期间选择验签密钥。
+	KeyID string `yaml:"key_id"`
+	// Algorithm 为 hmac-sha256（默认）或 ed25519。
+	Algorithm string `yaml:"algorithm"`
+	// Secret 为 hmac-sha256 的密钥，可写作 vault:/env: 引用。
+	Secret string `yaml:"secret"`
+	// PrivateKeyFile 为 ed25519 的 PKCS#8 PEM 私钥文件，如 openssl genpkey -algorithm ed25519 生成的文件。
+	PrivateKeyFile string `yaml:"private_key_file"`
+}
+
+// Storage 描述持久化后端。单机部署可使用 bolt 或 sqlite 文件，多实例部署使用 postgres 或 redis 共享账号与签名记录。
+type Storage struct {
+	// Driver 为后端类型：memory、bolt、sqlite、postgres 或 redis。
+	Driver string `yaml:"driver"`
+	// DSN 为连接信息：bolt、sqlite 为文件路径，postgres 为连接串或 postgres:// URL，redis 为 redis:// URL；
+	// 可写作 vault: 或 env: 引用。memory 不需要。
+	DSN string `yaml:"dsn"`
+	// HistorySize 为每个租户在后端中保留的签名记录条数，默认 1000。
+	HistorySize int `yaml:"history_size"`
+	// AuditSize 为后端中保留的管理操作审计记录条数，默认 10000。
+	AuditSize int `yaml:"audit_size"`
+}
+
+// Cluster 描述多实例部署：各实例通过 storage 配置的共享后端（postgres、redis，同一主机上也可为 sqlite）选出一个 leader，
+// 定时自检、账号清理与 stealth.js 更新检查只在 leader 上运行。
+type Cluster struct {
+	// NodeID 为本实例的标识，默认由主机名、进程号与随机后缀生成。
+	NodeID string `yaml:"node_id"`
+	// LeaseTTL 为 leader 租约的有效期，leader 宕机后至多经过该时长由其他实例接管，默认 15 秒。
+	LeaseTTL time.Duration `yaml:"lease_ttl"`
+	// SelfTestInterval 为 leader 定时自检签名的间隔，0 表示不定时自检。
+	SelfTestInterval time.Duration `yaml:"self_test_interval"`
+	// AccountSweepInterval 为 leader 清理账号库中过期 cookie 的间隔，0 表示不清理。
+	AccountSweepInterval time.Duration `yaml:"account_sweep_interval"`
+}
+
+// AccountLimits 描述单个账号（小红书为 a1）在每分钟、每小时、每天内的签名次数上限，0 表示该窗口不限制。
+// 超出上限的签名请求直接以 429 拒绝，不排队。
+type AccountLimits struct {
+	PerMinute int `yaml:"per_minute"`
+	PerHour   int `yaml:"per_hour"`
+	PerDay    int `yaml:"per_day"`
+}
+
+// StealthUpdate 描述定期检查上游 stealth.min.js 更新：下载到暂存路径，在临时页面上验证后提示发布或自动发布。
+type StealthUpdate struct {
+	// URL 为上游 stealth.min.js 的下载地址。
+	URL string `yaml:"url"`
+	// Interval 为检查间隔，默认 6 小时。
+	Interval time.Duration `yaml:"interval"`
+	// StagingPath 为新版本的暂存路径，默认为 --stealth 路径加 .staging 后缀。
+	StagingPath string `yaml:"staging_path"`
+	// AutoPromote 为 true 时新版本验证通过后自动替换 --stealth 文件并蓝绿升级，否则只提示人工发布。
+	AutoPromote bool `yaml:"auto_promote"`
+}
+
+// Maintenance 描述定时回收浏览器上下文的维护窗口。
+type Maintenance struct {
+	// Schedule 为 5 段 cron 表达式（分 时 日 月 周，按本地时区），如 "0 4 * * *" 表示每天 04:00。
+	Schedule string `yaml:"schedule"`
+	// Timeout 为单次回收的最长时间，默认 30 分钟。
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Secrets 描述外部密钥后端。
+type Secrets struct {
+	// Vault 为 HashiCorp Vault 后端，为空时只支持 env: 引用。
+	Vault *Vault `yaml:"vault"`
+	// RefreshInterval 为重新读取 API Key 的间隔，用于密钥轮换，0 表示只在启动时读取。
+	RefreshInterval time.Duration `yaml:"refresh_interval"`
+}
+
+// Vault 描述 HashiCorp Vault 的连接参数。
+type Vault struct {
+	// Address 为 Vault 地址，如 https://vault:8200，为空时读取 VAULT_ADDR。
+	Address string `yaml:"address"`
+	// TokenEnv 为读取 Vault token 的环境变量名，默认为 VAULT_TOKEN。
+	TokenEnv string `yaml:"token_env"`
+	// Namespace 为 Vault Enterprise 的命名空间。
+	Namespace string `yaml:"namespace"`
+	// Timeout 为请求超时时间，0 表示 10 秒。
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Encryption 描述落盘账号凭据（a1、web_session 等 cookie）的 AES-GCM 加密密钥。
+type Encryption struct {
+	// Key 为 base64 编码的 16、24 或 32 字节密钥，建议改用 KeyEnv 避免密钥与配置一同泄露。
+	Key string `yaml:"key"`
+	// KeyEnv 为读取密钥的环境变量名，Key 为空时使用，默认为 GO_SIGN_ENCRYPTION_KEY。
+	KeyEnv string `yaml:"key_env"`
+}
+
+// RequestEncryption 描述调用方与服务共享的 AES-GCM 密钥：调用方以该密钥加密 a1、web_session 等 cookie 后传入，
+// 服务在签名前解密，明文凭据不经过中间代理与其访问日志。须与落盘加密的 encryption 使用不同的密钥。
+type RequestEncryption struct {
+	// Key 为 base64 编码的 16、24 或 32 字节密钥，可写作 vault:/env: 引用。
+	Key string `yaml:"key"`
+	// KeyEnv 为读取密钥的环境变量名，Key 为空时使用，默认为 GO_SIGN_REQUEST_KEY。
+	KeyEnv string `yaml:"key_env"`
+	// Required 为 true 时拒绝明文传入的 a1 与 web_session。
+	Required bool `yaml:"required"`
+}
+
+// ErrorReport 描述将 panic、初始化失败与持续签名失败上报到 Sentry 或通用 webhook 的配置。
+type ErrorReport struct {
+	// SentryDSN 为 Sentry 项目的 DSN，为空时不上报 Sentry。
+	SentryDSN string `yaml:"sentry_dsn"`
+	// WebhookURL 为通用上报地址，事件以 JSON POST 到该地址，为空时不上报。
+	WebhookURL string `yaml:"webhook_url"`
+	// Environment 为事件的环境标签，如 production。
+	Environment string `yaml:"environment"`
+	// SignErrorThreshold 为同一平台在 SignErrorWindow 内签名失败达到该次数时上报一次，0 表示 10 次。
+	SignErrorThreshold int `yaml:"sign_error_threshold"`
+	// SignErrorWindow 为统计签名失败的窗口，0 表示 1 分钟。
+	SignErrorWindow time.Duration `yaml:"sign_error_window"`
+}
+
+// Log 为日志配置。
+type Log struct {
+	// Format 为日志格式：json（默认）或 text。
+	Format string `yaml:"format"`
+	// Level 为日志级别：debug、info（默认）、warn、error，运行时可通过管理接口修改，重新加载配置时生效。
+	Level string `yaml:"level"`
+	// AddSource 为 true 时在日志中输出调用位置（文件与行号）。
+	AddSource bool `yaml:"add_source"`
+	// File 为额外写入的日志文件，为空时只输出到标准输出。
+	File *LogFile `yaml:"file"`
+}
+
+// LogFile 描述按大小与时间轮转的日志文件，适合没有日志采集的物理机部署。
+type LogFile struct {
+	// Path 为日志文件路径，轮转出的旧文件以时间戳为后缀保存在同一目录。
+	Path string `yaml:"path"`
+	// MaxSizeMB 为单个文件的大小上限（MB），超出时轮转，0 表示 100MB。
+	MaxSizeMB int `yaml:"max_size_mb"`
+	// RotateInterval 为按时间轮转的间隔，如 24h，0 表示只按大小轮转。
+	RotateInterval time.Duration `yaml:"rotate_interval"`
+	// MaxAge 为旧文件的保留时长，0 表示不按时间清理。
+	MaxAge time.Duration `yaml:"max_age"`
+	// MaxBackups 为旧文件的保留个数，0 表示不按个数清理。
+	MaxBackups int `yaml:"max_backups"`
+	// Compress 为 true 时以 gzip 压缩旧文件。
+	Compress bool `yaml:"compress"`
+}
+
+// IPFilter 为基于 IP / CIDR 的访问名单：命中 Deny 时拒绝，Allow 非空时只允许其中的地址。
+type IPFilter struct {
+	Allow []string `yaml:"allow"`
+	Deny  []string `yaml:"deny"`
+}
+
+// gin 的运行模式，与 gin.ReleaseMode 等取值一致。
+const (
+	GinRelease = "release"
+	GinDebug   = "debug"
+	GinTest    = "test"
+)
+
+// Server 描述签名端口的 HTTP 服务。
+type Server struct {
+	// GinMode 为 gin 的运行模式：release、debug 或 test，为空时沿用环境变量 GIN_MODE（未设置时为 debug）。
+	GinMode string `yaml:"gin_mode"`
+	// Middlewares 为签名端口上按顺序加载的自定义中间件，位于访问日志与 panic 恢复之后、全部路由之前，
+	// 名称须为已通过 middleware.Register 注册的中间件。
+	Middlewares []Middleware `yaml:"middlewares"`
+	// ReadTimeout 为读取整个请求（含请求体）的超时，0 表示不限制。
+	ReadTimeout time.Duration `yaml:"read_timeout"`
+	// WriteTimeout 为从读完请求头到写完应答的超时，0 表示不限制；应大于 --sign-timeout，否则超时的签名请求收不到 504。
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+	// StreamWriteTimeout 为批量任务接口（含 SSE 进度推送）的写超时，覆盖 WriteTimeout，0 表示不限制。
+	StreamWriteTimeout time.Duration `yaml:"stream_write_timeout"`
+	// IdleTimeout 为 keep-alive 连接的空闲超时，0 表示取 ReadTimeout。
+	IdleTimeout time.Duration `yaml:"idle_timeout"`
+	// MaxHeaderBytes 为请求头大小上限（字节），0 表示 1 MiB。
+	MaxHeaderBytes int `yaml:"max_header_bytes"`
+}
+
+// Middleware 描述一个自定义中间件。
+type Middleware struct {
+	Name string `yaml:"name"`
+	// Options 为中间件自定义配置项，由中间件自行解码。
+	Options yaml.Node `yaml:"options"`
+}
+
+// Decode 将中间件配置项解码到 v，未配置 options 时不修改 v。
+func (m Middleware) Decode(v any) error {
+	if m.Options.Kind == 0 {
+		return nil
+	}
+	if err := m.Options.Decode(v); err != nil {
+		return fmt.Errorf("解析中间件 %s 配置失败: %w", m.Name, err)
+	}
+	return nil
+}
+
+// Proxy 描述服务前的反向代理或负载均衡，用于获取真实客户端 IP（日志、限流与 IP 名单均依赖客户端 IP）。
+type Proxy struct {
+	// Trusted 为可信代理的 IP 或 CIDR，只有来自这些地址的请求才从 Headers 中读取客户端 IP。
+	Trusted []string `yaml:"trusted"`
+	// Headers 为携带客户端 IP 的请求头，按顺序取第一个有效值，为空时为 X-Forwarded-For、X-Real-IP。
+	Headers []string `yaml:"headers"`
+	// ClientIPHeader 为云平台直接设置的客户端 IP 请求头（如 CF-Connecting-IP），非空时优先于 Headers。
+	// 须确保服务只能经由该平台访问，否则请求头可被伪造。
+	ClientIPHeader string `yaml:"client_ip_header"`
+}
+
+// Shadow 描述将签名请求镜像到备用签名服务的配置。
+type Shadow struct {
+	// URL 为备用签名服务地址，如 http://canary:5005。
+	URL string `yaml:"url"`
+	// SampleRate 为镜像比例，取值 (0, 1]。
+	SampleRate float64 `yaml:"sample_rate"`
+	// Ignore 为不参与比较的响应字段，如 x-t。
+	Ignore []string `yaml:"ignore"`
+	// APIKey 为访问备用服务的 API Key，为空时转发调用方的鉴权请求头。
+	APIKey string `yaml:"api_key"`
+	// Timeout 为镜像请求超时时间，0 表示 10 秒。
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Platform 描述一个启用的签名平台。
+type Platform struct {
+	// Name 为平台注册名，如 xhs、xhs-creator。
+	Name string `yaml:"name"`
+	// Options 为平台自定义配置项，由平台自行解码。
+	Options yaml.Node `yaml:"options"`
+	// Plugin 为外部插件平台的启动方式，配置后 Name 注册为插件平台，无需重新编译。
+	Plugin *Plugin `yaml:"plugin"`
+	// Script 为自定义签名脚本平台的页面与签名函数，配置后 Name 注册为脚本平台。
+	Script *Script `yaml:"script"`
+	// Verify 为双跑校验配置，抽样用另一个已启用的平台重新签名并比较结果。
+	Verify *Verify `yaml:"verify"`
+}
+
+// Verify 描述一个平台的双跑校验。
+type Verify struct {
+	// Against 为对照平台名，须同时在 platforms 中启用。
+	Against string `yaml:"against"`
+	// SampleRate 为抽样比例，取值 (0, 1]。
+	SampleRate float64 `yaml:"sample_rate"`
+	// Ignore 为不参与比较的请求头或查询参数名，如含时间戳的 x-t。
+	Ignore []string `yaml:"ignore"`
+}
+
+// Script 描述一个调用页面 window 级签名函数的自定义站点。
+type Script struct {
+	// Backend 为脚本执行后端：browser（默认，在浏览器页面中执行）或 node（Node.js 子进程池）。
+	Backend string `yaml:"backend"`
+	// URL 为浏览器后端预热时访问的页面。
+	URL string `yaml:"url"`
+	// File 为注入页面的 JS 文件，可用于自行定义签名函数，为空时只使用页面自带的函数。
+	// node 后端必填，签名函数由该文件定义。
+	File string `yaml:"file"`
+	// Node 为 node 后端使用的 Node.js 可执行文件，为空时为 PATH 中的 node。
+	Node string `yaml:"node"`
+	// Function 为签名函数在 window 上的路径，如 _webmsxyw、byted.sign。
+	Function string `yaml:"function"`
+	// Output 为签名结果的返回位置：headers（默认）或 params。
+	Output string `yaml:"output"`
+}
+
+// Plugin 描述一个以子进程运行的外部签名插件。
+type Plugin struct {
+	// Command 为插件可执行文件路径。
+	Command string `yaml:"command"`
+	// Args 为插件启动参数。
+	Args []string `yaml:"args"`
+	// Env 为追加到插件进程的环境变量，格式为 KEY=VALUE。
+	Env []string `yaml:"env"`
+	// Timeout 为单次调用插件的超时时间，0 表示只受请求本身的超时限制。
+	Timeout time.Duration `yaml:"timeout"`
+}
+
+// Decode 将平台配置项解码到 v，未配置 options 时不修改 v。
+func (p Platform) Decode(v any) error {
+	if p.Options.Kind == 0 {
+		return nil
+	}
+	if err := p.Options.Decode(v); err != nil {
+		return fmt.Errorf("解析平台 %s 配置失败: %w", p.Name, err)
+	}
+	return nil
+}
+
+// Tenant 描述一个租户。
+type Tenant struct {
+	// Name 为租户名称，同时作为指标标签。
+	Name string `yaml:"name"`
+	// PoolSize 为该租户独占的页面池大小，0 表示使用 --pool-size。
+	PoolSize int `yaml:"pool_size"`
+	// DailyQuota 为租户内所有 Key 合计的每日签名次数上限，0 表示不限制。
+	DailyQuota int64 `yaml:"daily_quota"`
+	// MonthlyQuota 为租户内所有 Key 合计的每月签名次数上限，0 表示不限制。
+	MonthlyQuota int64 `yaml:"monthly_quota"`
+}
+
+// APIKey 描述一个调用方的 API Key。
+type APIKey struct {
+	// Name 为调用方名称，用于日志与指标，同时作为 HMAC 签名的 key id。
+	Name string `yaml:"name"`
+	// Key 为请求时携带的密钥，同时作为 HMAC 签名的密钥。
+	Key string `yaml:"key"`
+	// RequireHMAC 为 true 时只接受 HMAC 签名的请求，拒绝直接携带密钥的请求。
+	RequireHMAC bool `yaml:"require_hmac"`
+	// RequireNonce 为 HMAC 签名的请求是否须携带一次性的 X-Nonce（同一 nonce 在重放窗口内只能使用一次），
+	// 不填时为 true；尚未支持 nonce 的旧客户端可显式配置为 false，见 NonceRequired。
+	RequireNonce *bool `yaml:"require_nonce"`
+	// Tenant 为所属租户名称，为空时归属 DefaultTenant。
+	Tenant string `yaml:"tenant"`
+	// Priority 为争用页面时的优先级：high、normal、low，默认为 normal。
+	Priority string `yaml:"priority"`
+	// DailyQuota 为每日签名次数上限，0 表示不限制。
+	DailyQuota int64 `yaml:"daily_quota"`
+	// MonthlyQuota 为每月签名次数上限，0 表示不限制。
+	MonthlyQuota int64 `yaml:"monthly_quota"`
+	// Scopes 为 Key 的权限，见 Scopes；为空时只有 sign。
+	Scopes []string `yaml:"scopes"`
+}
+
+// 调用方的权限，admin 包含全部权限。
+const (
+	// ScopeSign 允许调用签名、GraphQL、批量任务、离线生成与回传接口。
+	ScopeSign = "sign"
+	// ScopeAccountsRead 允许查询账号（cookie 已脱敏）与账号健康状态。
+	ScopeAccountsRead = "accounts:read"
+	// ScopeAdmin 允许调用全部接口，包括修改账号、重新加载配置与导出签名脚本。
+	ScopeAdmin = "admin"
+)
+
+// Scopes 为全部权限。
+var Scopes = []string{ScopeSign, ScopeAccountsRead, ScopeAdmin}
+
+// JWT 描述以 JWT 作为 Bearer 令牌鉴权：令牌的 sub 为调用方名称，tenant 与 scope 声明为所属租户与权限。
+// 与 api_keys 可同时配置，Bearer 值不是已配置的 API Key 时按 JWT 校验。
+type JWT struct {
+	// Secret 为 HS256、HS384、HS512 的密钥，可写作 vault:/env: 引用，与 PublicKeyFile 二选一。
+	Secret string `yaml:"secret"`
+	// PublicKeyFile 为 RS*、PS*、ES* 签名的 PEM 公钥文件。
+	PublicKeyFile string `yaml:"public_key_file"`
+	// Issuer、Audience 不为空时须与令牌的 iss、aud 一致。
+	Issuer   string `yaml:"issuer"`
+	Audience string `yaml:"audience"`
+	// ScopesClaim 为权限声明名，值为空格分隔的字符串或字符串数组，默认为 scope；缺失时令牌只有 sign 权限。
+	ScopesClaim string `yaml:"scopes_claim"`
+	// TenantClaim 为租户声明名，默认为 tenant；缺失时归属 DefaultTenant。
+	TenantClaim string `yaml:"tenant_claim"`
+}
+
+// Load 读取并校验 path 指定的配置文件，path 为空时返回默认配置。
+func Load(path string) (*Config, error) {
+	cfg := &Config{}
+	if path == "" {
+		return cfg, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(raw))
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("解析配置文件失败: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate 校验配置项的合法性。
+func (c *Config) Validate() error {
+	platforms := make(map[string]bool, len(c.Platforms))
+	for i, p := range c.Platforms {
+		if p.Name == "" {
+			return fmt.Errorf("platforms[%d]: name 不能为空", i)
+		}
+		if platforms[p.Name] {
+			return fmt.Errorf("platforms[%d]: 平台 %q 重复", i, p.Name)
+		}
+		if p.Plugin != nil && p.Plugin.Command == "" {
+			return fmt.Errorf("platforms[%d]: plugin.command 不能为空", i)
+		}
+		if p.Plugin != nil && p.Script != nil {
+			return fmt.Errorf("platforms[%d]: plugin 与 script 不能同时配置", i)
+		}
+		if s := p.Script; s != nil {
+			if s.Function == "" {
+				return fmt.Errorf("platforms[%d]: script.function 不能为空", i)
+			}
+			switch s.Backend {
+			case "", "browser":
+				if s.URL == "" {
+					return fmt.Errorf("platforms[%d]: browser 后端的 script.url 不能为空", i)
+				}
+			case "node":
+				if s.File == "" {
+					return fmt.Errorf("platforms[%d]: node 后端的 script.file 不能为空", i)
+				}
+			default:
+				return fmt.Errorf("platforms[%d]: 不支持的 script.backend %q", i, s.Backend)
+			}
+			if s.Output != "" && s.Output != "headers" && s.Output != "params" {
+				return fmt.Errorf("platforms[%d]: 不支持的 script.output %q", i, s.Output)
+			}
+		}
+		platforms[p.Name] = true
+	}
+	for i, p := range c.Platforms {
+		if v := p.Verify; v != nil {
+			if v.Against == "" || v.Against == p.Name || !platforms[v.Against] {
+				return fmt.Errorf("platforms[%d]: verify.against 须为另一个已启用的平台", i)
+			}
+			if v.SampleRate <= 0 || v.SampleRate > 1 {
+				return fmt.Errorf("platforms[%d]: verify.sample_rate 须在 (0, 1] 之间", i)
+			}
+		}
+	}
+	if s := c.Shadow; s != nil {
+		if s.URL == "" {
+			return fmt.Errorf("shadow.url 不能为空")
+		}
+		if s.SampleRate <= 0 || s.SampleRate > 1 {
+			return fmt.Errorf("shadow.sample_rate 须在 (0, 1] 之间")
+		}
+	}
+	if s := c.Server; s != nil {
+		switch s.GinMode {
+		case "", GinRelease, GinDebug, GinTest:
+		default:
+			return fmt.Errorf("server.gin_mode 须为 %s、%s 或 %s，实际为 %q", GinRelease, GinDebug, GinTest, s.GinMode)
+		}
+		for i, m := range s.Middlewares {
+			if m.Name == "" {
+				return fmt.Errorf("server.middlewares[%d]: name 不能为空", i)
+			}
+		}
+		if s.ReadTimeout < 0 || s.WriteTimeout < 0 || s.StreamWriteTimeout < 0 || s.IdleTimeout < 0 || s.MaxHeaderBytes < 0 {
+			return fmt.Errorf("server 的超时与 max_header_bytes 不能为负数")
+		}
+		if s.StreamWriteTimeout > 0 && s.StreamWriteTimeout < s.WriteTimeout {
+			return fmt.Errorf("server.stream_write_timeout 不能小于 write_timeout")
+		}
+	}
+	if p := c.Proxy; p != nil {
+		for i, t := range p.Trusted {
+			if !validNet(t) {
+				return fmt.Errorf("proxy.trusted[%d]: %q 不是合法的 IP 或 CIDR", i, t)
+			}
+		}
+	}
+	if f := c.IPFilter; f != nil {
+		for i, e := range f.Allow {
+			if !validNet(e) {
+				return fmt.Errorf("ip_filter.allow[%d]: %q 不是合法的 IP 或 CIDR", i, e)
+			}
+		}
+		for i, e := range f.Deny {
+			if !validNet(e) {
+				return fmt.Errorf("ip_filter.deny[%d]: %q 不是合法的 IP 或 CIDR", i, e)
+			}
+		}
+	}
+	if l := c.Log; l != nil {
+		switch l.Format {
+		case "", "json", "text":
+		default:
+			return fmt.Errorf("log.format 须为 json 或 text")
+		}
+		switch strings.ToLower(l.Level) {
+		case "", "debug", "info", "warn", "error":
+		default:
+			return fmt.Errorf("log.level 须为 debug、info、warn 或 error")
+		}
+	}
+	if l := c.Log; l != nil && l.File != nil {
+		f := l.File
+		if f.Path == "" {
+			return fmt.Errorf("log.file.path 不能为空")
+		}
+		if f.MaxSizeMB < 0 || f.RotateInterval < 0 || f.MaxAge < 0 || f.MaxBackups < 0 {
+			return fmt.Errorf("log.file 的大小、间隔与保留配置不能为负数")
+		}
+	}
+	if e := c.ErrorReport; e != nil {
+		if e.SentryDSN == "" && e.WebhookURL == "" {
+			return fmt.Errorf("error_report: sentry_dsn 与 webhook_url 至少配置一项")
+		}
+		if e.SignErrorThreshold < 0 || e.SignErrorWindow < 0 {
+			return fmt.Errorf("error_report: sign_error_threshold 与 sign_error_window 不能为负数")
+		}
+	}
+	if s := c.Secrets; s != nil && s.RefreshInterval < 0 {
+		return fmt.Errorf("secrets.refresh_interval 不能为负数")
+	}
+	if m := c.Maintenance; m != nil {
+		if _, err := schedule.Parse(m.Schedule); err != nil {
+			return fmt.Errorf("maintenance.schedule: %w", err)
+		}
+		if m.Timeout < 0 {
+			return fmt.Errorf("maintenance.timeout 不能为负数")
+		}
+	}
+	if u := c.StealthUpdate; u != nil {
+		if parsed, err := url.Parse(u.URL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("stealth_update.url: %q 不是合法的 http(s) 地址", u.URL)
+		}
+		if u.Interval < 0 {
+			return fmt.Errorf("stealth_update.interval 不能为负数")
+		}
+	}
+	if s := c.Storage; s != nil {
+		switch s.Driver {
+		case "memory":
+		case "bolt", "sqlite", "postgres", "redis":
+			if s.DSN == "" {
+				return fmt.Errorf("storage.dsn 不能为空")
+			}
+		default:
+			return fmt.Errorf("storage.driver 须为 memory、bolt、sqlite、postgres 或 redis")
+		}
+		if s.HistorySize < 0 || s.AuditSize < 0 {
+			return fmt.Errorf("storage.history_size 与 audit_size 不能为负数")
+		}
+	}
+	if cl := c.Cluster; cl != nil {
+		if cl.LeaseTTL < 0 || cl.SelfTestInterval < 0 || cl.AccountSweepInterval < 0 {
+			return fmt.Errorf("cluster 的时长配置不能为负数")
+		}
+		if cl.LeaseTTL > 0 && cl.LeaseTTL < 3*time.Second {
+			return fmt.Errorf("cluster.lease_ttl 不能小于 3s")
+		}
+	}
+	if l := c.AccountLimits; l != nil && (l.PerMinute < 0 || l.PerHour < 0 || l.PerDay < 0) {
+		return fmt.Errorf("account_limits 的次数上限不能为负数")
+	}
+	for i, w := range c.Webhooks {
+		if parsed, err := url.Parse(w.URL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("webhooks[%d].url: %q 不是合法的 http(s) 地址", i, w.URL)
+		}
+		if w.Timeout < 0 || w.MaxRetries < -1 {
+			return fmt.Errorf("webhooks[%d]: timeout 不能为负数，max_retries 不能小于 -1", i)
+		}
+		for _, ev := range w.Events {
+			if !slices.Contains(WebhookEvents, ev) {
+				return fmt.Errorf("webhooks[%d].events: 未知事件 %q，可选 %s", i, ev, strings.Join(WebhookEvents, "、"))
+			}
+		}
+	}
+	if rs := c.ResponseSigning; rs != nil {
+		if rs.KeyID == "" {
+			return fmt.Errorf("response_signing.key_id 不能为空")
+		}
+		switch rs.Algorithm {
+		case "", ResponseSigningHMAC:
+			if rs.Secret == "" || rs.PrivateKeyFile != "" {
+				return fmt.Errorf("response_signing: %s 须配置 secret，不能配置 private_key_file", ResponseSigningHMAC)
+			}
+		case ResponseSigningEd25519:
+			if rs.PrivateKeyFile == "" || rs.Secret != "" {
+				return fmt.Errorf("response_signing: %s 须配置 private_key_file，不能配置 secret", ResponseSigningEd25519)
+			}
+		default:
+			return fmt.Errorf("response_signing.algorithm: 不支持的算法 %q，可选 %s、%s", rs.Algorithm, ResponseSigningHMAC, ResponseSigningEd25519)
+		}
+	}
+	tenants := map[string]bool{DefaultTenant: len(c.Tenants) == 0}
+	for i, t := range c.Tenants {
+		if t.Name == "" {
+			return fmt.Errorf("tenants[%d]: name 不能为空", i)
+		}
+		if tenants[t.Name] {
+			return fmt.Errorf("tenants[%d]: 租户 %q 重复", i, t.Name)
+		}
+		if t.PoolSize < 0 || t.DailyQuota < 0 || t.MonthlyQuota < 0 {
+			return fmt.Errorf("tenants[%d]: pool_size 与配额不能为负数", i)
+		}
+		tenants[t.Name] = true
+	}
+	if len(c.Tenants) > 0 && len(c.APIKeys) == 0 {
+		return fmt.Errorf("配置 tenants 时必须配置 api_keys，租户由 API Key 解析")
+	}
+	seen := make(map[string]bool, len(c.APIKeys))
+	names := make(map[string]bool, len(c.APIKeys))
+	for i, k := range c.APIKeys {
+		if k.Key == "" {
+			return fmt.Errorf("api_keys[%d]: key 不能为空", i)
+		}
+		if seen[k.Key] {
+			return fmt.Errorf("api_keys[%d]: key 重复", i)
+		}
+		seen[k.Key] = true
+		if k.Name != "" {
+			if names[k.Name] {
+				return fmt.Errorf("api_keys[%d]: name %q 重复，name 用作 HMAC 签名的 key id", i, k.Name)
+			}
+			names[k.Name] = true
+		} else if k.RequireHMAC {
+			return fmt.Errorf("api_keys[%d]: require_hmac 时 name 不能为空", i)
+		} else if k.RequireNonce != nil && *k.RequireNonce {
+			return fmt.Errorf("api_keys[%d]: require_nonce 时 name 不能为空", i)
+		}
+		switch k.Priority {
+		case "", "high", "normal", "low":
+		default:
+			return fmt.Errorf("api_keys[%d]: 不支持的优先级 %q", i, k.Priority)
+		}
+		if k.DailyQuota < 0 || k.MonthlyQuota < 0 {
+			return fmt.Errorf("api_keys[%d]: 配额不能为负数", i)
+		}
+		if !tenants[k.TenantName()] {
+			return fmt.Errorf("api_keys[%d]: 租户 %q 未定义", i, k.TenantName())
+		}
+		for _, scope := range k.Scopes {
+			if !slices.Contains(Scopes, scope) {
+				return fmt.Errorf("api_keys[%d].scopes: 未知权限 %q，可选 %s", i, scope, strings.Join(Scopes, "、"))
+			}
+		}
+	}
+	if j := c.JWT; j != nil && (j.Secret == "") == (j.PublicKeyFile == "") {
+		return fmt.Errorf("jwt: secret 与 public_key_file 须配置且只能配置一项")
+	}
+	return nil
+}
+
+// TenantName 返回 Key 所属租户，未指定时为 DefaultTenant。
+func (k APIKey) TenantName() string {
+	if k.Tenant == "" {
+		return DefaultTenant
+	}
+	return k.Tenant
+}
+
+// NonceRequired 返回 HMAC 签名的请求是否须携带 X-Nonce，未配置 require_nonce 时为 true。
+func (k APIKey) NonceRequired() bool {
+	return k.RequireNonce == nil || *k.RequireNonce
+}
+
+// validNet 判断 s 是否为合法的 IP 或 CIDR。
+func validNet(s string) bool {
+	if _, _, err := net.ParseCIDR(s); err == nil {
+		return true
+	}
+	return net.ParseIP(s) != nil
+}
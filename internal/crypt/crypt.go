@@ -0,0 +1,132 @@
+// Package crypt 加解密落盘的账号凭据（a1、web_session 等 cookie），
+// 使泄露的数据目录无法直接得到可用的登录态。
+//
+// 内置实现为 AES-GCM，密钥来自配置文件或环境变量；接入外部 KMS 时实现 Cipher 即可。
+package crypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"go_sign/internal/config"
+)
+
+// DefaultKeyEnv 为未在配置文件中指定密钥时读取的环境变量。
+const DefaultKeyEnv = "GO_SIGN_ENCRYPTION_KEY"
+
+// prefix 为密文的版本前缀，格式为 v1:<base64(nonce || ciphertext)>。
+const prefix = "v1:"
+
+// Cipher 加解密敏感数据，密文为可直接写入 JSON 或文本文件的字符串。
+type Cipher interface {
+	Encrypt(plaintext []byte) (string, error)
+	Decrypt(ciphertext string) ([]byte, error)
+}
+
+// ErrNoKey 表示读取到密文但未配置密钥。
+var ErrNoKey = errors.New("数据已加密，但未配置加密密钥")
+
+// aesGCM 为 AES-GCM 实现，每次加密使用随机 nonce。
+type aesGCM struct {
+	aead cipher.AEAD
+}
+
+// NewAESGCM 以 16、24 或 32 字节的 key 创建 AES-GCM Cipher。
+func NewAESGCM(key []byte) (Cipher, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("创建 AES 密钥失败: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("创建 AES-GCM 失败: %w", err)
+	}
+	return &aesGCM{aead: aead}, nil
+}
+
+// Encrypt 加密 plaintext。
+func (c *aesGCM) Encrypt(plaintext []byte) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("生成 nonce 失败: %w", err)
+	}
+	sealed := c.aead.Seal(nonce, nonce, plaintext, nil)
+	return prefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密 Encrypt 生成的密文，密钥不匹配或密文被篡改时返回错误。
+func (c *aesGCM) Decrypt(ciphertext string) ([]byte, error) {
+	raw, ok := strings.CutPrefix(ciphertext, prefix)
+	if !ok {
+		return nil, errors.New("不支持的密文格式")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, fmt.Errorf("密文不是合法的 base64: %w", err)
+	}
+	n := c.aead.NonceSize()
+	if len(sealed) < n {
+		return nil, errors.New("密文长度不足")
+	}
+	plaintext, err := c.aead.Open(nil, sealed[:n], sealed[n:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("解密失败，密钥不匹配或数据被篡改: %w", err)
+	}
+	return plaintext, nil
+}
+
+// FromConfig 按配置创建 Cipher：密钥依次取自 encryption.key、encryption.key_env 指定的环境变量
+// 与 GO_SIGN_ENCRYPTION_KEY，均为 base64 编码的 16、24 或 32 字节。均未配置时返回 nil，表示不加密。
+func FromConfig(c *config.Encryption) (Cipher, error) {
+	if c == nil {
+		c = &config.Encryption{}
+	}
+	return fromKey(c.Key, "encryption.key", c.KeyEnv, DefaultKeyEnv)
+}
+
+// DefaultRequestKeyEnv 为未在配置文件中指定请求凭据密钥时读取的环境变量。
+const DefaultRequestKeyEnv = "GO_SIGN_REQUEST_KEY"
+
+// ForRequests 按配置创建解密请求中账号凭据的 Cipher：密钥依次取自 request_encryption.key 与
+// request_encryption.key_env 指定的环境变量（默认 GO_SIGN_REQUEST_KEY）。c 为 nil 时返回 nil，
+// 配置了 request_encryption 却读取不到密钥时返回错误。
+func ForRequests(c *config.RequestEncryption) (Cipher, error) {
+	if c == nil {
+		return nil, nil
+	}
+	cipher, err := fromKey(c.Key, "request_encryption.key", c.KeyEnv, DefaultRequestKeyEnv)
+	if err == nil && cipher == nil {
+		err = errors.New("request_encryption 未配置密钥")
+	}
+	return cipher, err
+}
+
+// IsEncrypted 判断 s 是否为 Encrypt 生成的密文格式。
+func IsEncrypted(s string) bool {
+	return strings.HasPrefix(s, prefix)
+}
+
+// fromKey 以 base64 编码的 key 创建 AES-GCM Cipher，key 为空时读取环境变量 env（为空时取 defaultEnv），
+// 仍为空时返回 nil。source 为错误信息中 key 的来源。
+func fromKey(encoded, source, env, defaultEnv string) (Cipher, error) {
+	if encoded == "" {
+		if env == "" {
+			env = defaultEnv
+		}
+		encoded, source = os.Getenv(env), env
+	}
+	if encoded == "" {
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%s 不是合法的 base64: %w", source, err)
+	}
+	return NewAESGCM(key)
+}
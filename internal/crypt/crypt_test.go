@@ -0,0 +1,98 @@
+package crypt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"go_sign/internal/config"
+)
+
+func TestAESGCM(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	c, err := NewAESGCM(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := NewAESGCM(bytes.Repeat([]byte{2}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext := []byte("a1=18c5f0e1c2dabc; web_session=040069b3")
+	ct, err := c.Encrypt(plaintext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(ct) || strings.Contains(ct, "web_session") {
+		t.Fatalf("密文格式不符: %s", ct)
+	}
+	if again, _ := c.Encrypt(plaintext); again == ct {
+		t.Error("两次加密得到相同密文，nonce 未随机")
+	}
+	sealed, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(ct, prefix))
+	flip := func(i int) string {
+		b := bytes.Clone(sealed)
+		b[i] ^= 0x01
+		return prefix + base64.StdEncoding.EncodeToString(b)
+	}
+
+	tests := []struct {
+		name    string
+		cipher  Cipher
+		in      string
+		wantErr bool
+	}{
+		{name: "往返", cipher: c, in: ct},
+		{name: "密钥错误", cipher: other, in: ct, wantErr: true},
+		{name: "篡改密文", cipher: c, in: flip(len(sealed) - 20), wantErr: true},
+		{name: "篡改 nonce", cipher: c, in: flip(0), wantErr: true},
+		{name: "篡改认证标签", cipher: c, in: flip(len(sealed) - 1), wantErr: true},
+		{name: "截断", cipher: c, in: prefix + base64.StdEncoding.EncodeToString(sealed[:8]), wantErr: true},
+		{name: "缺少版本前缀", cipher: c, in: strings.TrimPrefix(ct, prefix), wantErr: true},
+		{name: "非 base64", cipher: c, in: prefix + "!!", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.cipher.Decrypt(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("期望解密失败，实际得到 %q", got)
+				}
+				return
+			}
+			if err != nil || !bytes.Equal(got, plaintext) {
+				t.Fatalf("解密 = %q, %v，期望 %q", got, err, plaintext)
+			}
+		})
+	}
+}
+
+func TestFromConfig(t *testing.T) {
+	t.Setenv(DefaultKeyEnv, "")
+	if c, err := FromConfig(nil); c != nil || err != nil {
+		t.Fatalf("未配置密钥时应不加密，实际 %v, %v", c, err)
+	}
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{3}, 16))
+	t.Setenv("MY_KEY", key)
+	tests := []struct {
+		name    string
+		cfg     *config.Encryption
+		wantErr bool
+	}{
+		{name: "配置文件中的密钥", cfg: &config.Encryption{Key: key}},
+		{name: "环境变量中的密钥", cfg: &config.Encryption{KeyEnv: "MY_KEY"}},
+		{name: "非 base64", cfg: &config.Encryption{Key: "!!"}, wantErr: true},
+		{name: "长度不符", cfg: &config.Encryption{Key: base64.StdEncoding.EncodeToString([]byte("short"))}, wantErr: true},
+	}
+	for _, tt := range tests {
+		c, err := FromConfig(tt.cfg)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && c == nil) {
+			t.Errorf("%s: FromConfig = %v, %v", tt.name, c, err)
+		}
+	}
+	t.Setenv("UNSET_KEY_ENV", "")
+	if _, err := ForRequests(&config.RequestEncryption{KeyEnv: "UNSET_KEY_ENV"}); err == nil {
+		t.Error("配置了 request_encryption 却没有密钥时应返回错误")
+	}
+}
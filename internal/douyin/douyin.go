@@ -0,0 +1,250 @@
+// Package douyin 提供抖音网页端 a_bogus / X-Bogus 签名平台。
+//
+// 与小红书相同，签名在预热好的抖音首页中执行页面自带的签名 JS，
+// 调用方式可通过配置覆盖，以便站点更新签名入口后无需重新编译。
+package douyin
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	"go_sign/internal/browser"
+	"go_sign/internal/pagepool"
+	"go_sign/internal/platform"
+)
+
+// Name 为抖音平台的注册名，签名接口为 POST /douyin/sign。
+const Name = "douyin"
+
+// 支持的签名算法，即返回的查询参数名。
+const (
+	AlgorithmABogus = "a_bogus"
+	AlgorithmXBogus = "X-Bogus"
+)
+
+// 默认的页面签名调用，参数均为 [query, body, ua]，其中 X-Bogus 的 body 为请求体的 MD5。
+// X-Bogus 与 a_bogus 一样绑定查询串：frontierSign 除请求体摘要 X-MS-STUB 外还需传入查询串，
+// 否则不同查询串得到相同的 X-Bogus，站点校验不通过。
+const (
+	mockUserAgent   = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+	defaultHomeURL  = "https://www.douyin.com"
+	defaultReadyJS  = `() => !!(window.bdms || window.byted_acrawler)`
+	defaultABogusJS = `([query, body, ua]) => window.bdms.init._v[2].p[42].apply(null, [0, 1, 8, query, body, ua])`
+	defaultXBogusJS = `([query, bodyMD5, ua]) => {
+		const r = window.byted_acrawler.frontierSign(query, {"X-MS-STUB": bodyMD5});
+		return r && r["X-Bogus"];
+	}`
+)
+
+func init() {
+	platform.Register(Name, func(decode platform.Decoder) (platform.Platform, error) {
+		p := &Platform{options: options{
+			HomeURL:    defaultHomeURL,
+			ABogusJS:   defaultABogusJS,
+			XBogusJS:   defaultXBogusJS,
+			Algorithms: []string{AlgorithmABogus},
+		}}
+		if err := decode(&p.options); err != nil {
+			return nil, err
+		}
+		for _, a := range p.options.Algorithms {
+			if a != AlgorithmABogus && a != AlgorithmXBogus {
+				return nil, fmt.Errorf("抖音平台不支持的签名算法: %s", a)
+			}
+		}
+		return p, nil
+	})
+}
+
+// options 为抖音平台在配置文件中的可选项。
+type options struct {
+	// HomeURL 为预热时访问的页面。
+	HomeURL string `yaml:"home_url"`
+	// PoolSize 覆盖平台默认的页面池大小。
+	PoolSize int `yaml:"pool_size"`
+	// ABogusJS、XBogusJS 为生成签名的页面函数，参数为 [query, body, ua]。
+	ABogusJS string `yaml:"a_bogus_js"`
+	XBogusJS string `yaml:"x_bogus_js"`
+	// Algorithms 为默认生成的签名，可选 a_bogus、X-Bogus，默认只生成 a_bogus。
+	Algorithms []string `yaml:"algorithms"`
+}
+
+// Platform 为抖音签名平台。
+type Platform struct {
+	options options
+	browser *browser.Browser
+	env     *platform.Env
+	pools   pagepool.Set
+}
+
+// Name 返回平台注册名。
+func (p *Platform) Name() string { return Name }
+
+// Init 启动（或复用）共享浏览器并预热抖音首页。
+func (p *Platform) Init(ctx context.Context, env *platform.Env) error {
+	b, err := env.Browser.Get()
+	if err != nil {
+		return err
+	}
+	p.browser, p.env = b, env
+	tenants := pagepool.TenantsFromEnv(env, p.options.PoolSize)
+	p.pools, err = pagepool.Warmup(ctx, Name, tenants, env.WarmupConcurrency, p.newSlot)
+	return err
+}
+
+func (p *Platform) newSlot(ctx context.Context, tenant string, id int) (*pagepool.Slot, error) {
+	return pagepool.Open(ctx, p.browser, tenant, id, pagepool.OpenOptions{
+		Platform:          Name,
+		StealthPath:       p.env.StealthPath,
+		URL:               p.options.HomeURL,
+		WaitUntil:         p.env.WaitUntil,
+		NavigationTimeout: p.env.NavigationTimeout,
+		ReadyJS:           defaultReadyJS,
+		ReadyTimeout:      p.env.SignFuncTimeout,
+	})
+}
+
+// Sign 生成 a_bogus / X-Bogus，结果以查询参数返回，调用方需追加到原查询串末尾。
+// 指定 timestamp 时签名期间固定页面时钟。
+// 查询串取自 uri 中的 ? 之后部分；uri 不含查询串时使用 params 按键名排序编码后的结果。
+func (p *Platform) Sign(ctx context.Context, req *platform.SignRequest) (*platform.SignResponse, error) {
+	pool, err := p.pools.For(ctx)
+	if err != nil {
+		return nil, err
+	}
+	query, body, err := canonicalize(req)
+	if err != nil {
+		return nil, err
+	}
+
+	slot, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("等待空闲页面失败: %w", err)
+	}
+	res, err := p.signOnSlot(ctx, slot, req, query, body)
+	pool.Done(slot, err)
+	return res, err
+}
+
+// signOnSlot 在页面上依次执行各算法的签名 JS。
+func (p *Platform) signOnSlot(ctx context.Context, slot *pagepool.Slot, req *platform.SignRequest, query, body string) (*platform.SignResponse, error) {
+	ua := req.UserAgent
+	if ua == "" {
+		v, err := slot.Page.Evaluate(`() => navigator.userAgent`)
+		if err != nil {
+			return nil, fmt.Errorf("读取页面 User-Agent 失败: %w", err)
+		}
+		ua, _ = v.(string)
+	}
+
+	res := &platform.SignResponse{Params: make(map[string]string, len(p.options.Algorithms))}
+	for _, alg := range p.options.Algorithms {
+		js, args := p.signArgs(alg, query, body, ua)
+		v, err := pagepool.Evaluate(ctx, slot.Page, pagepool.WithClock(js), []any{req.Timestamp, args})
+		if err != nil {
+			slog.Error("执行抖音签名 JS 失败", "err", err, "algorithm", alg, "uri", req.URI)
+			return nil, fmt.Errorf("执行 %s 签名 JS 失败: %w", alg, err)
+		}
+		sig, _ := v.(string)
+		if sig == "" {
+			return nil, fmt.Errorf("%s 签名结果为空", alg)
+		}
+		res.Params[alg] = sig
+	}
+	res.Headers = map[string]string{"user-agent": ua}
+	res.Timestamp = platform.SignTime(req.Timestamp).UnixMilli()
+	return res, nil
+}
+
+// signArgs 返回算法 alg 的签名 JS 及其参数 [query, body, ua]，X-Bogus 的 body 为请求体的 MD5。
+func (p *Platform) signArgs(alg, query, body, ua string) (js string, args []any) {
+	if alg == AlgorithmXBogus {
+		sum := md5.Sum([]byte(body))
+		return p.options.XBogusJS, []any{query, hex.EncodeToString(sum[:]), ua}
+	}
+	return p.options.ABogusJS, []any{query, body, ua}
+}
+
+// Mock 返回长度与字符集与真实签名一致的假 a_bogus / X-Bogus，以及请求的 User-Agent。
+func (p *Platform) Mock(req *platform.SignRequest) *platform.SignResponse {
+	res := &platform.SignResponse{Params: make(map[string]string, len(p.options.Algorithms))}
+	for _, alg := range p.options.Algorithms {
+		n := 168
+		if alg == AlgorithmXBogus {
+			n = 28
+		}
+		raw, _ := hex.DecodeString(platform.MockHex(n))
+		res.Params[alg] = base64.RawURLEncoding.EncodeToString(raw)[:n]
+	}
+	ua := req.UserAgent
+	if ua == "" {
+		ua = mockUserAgent
+	}
+	res.Headers = map[string]string{"user-agent": ua}
+	res.Timestamp = platform.SignTime(req.Timestamp).UnixMilli()
+	return res
+}
+
+// canonicalize 计算参与签名的查询串与请求体字符串。
+func canonicalize(req *platform.SignRequest) (query, body string, err error) {
+	if i := strings.IndexByte(req.URI, '?'); i >= 0 {
+		query = req.URI[i+1:]
+	} else if len(req.Params) > 0 {
+		values := make(url.Values, len(req.Params))
+		for k, v := range req.Params {
+			values.Set(k, v)
+		}
+		query = values.Encode()
+	}
+	switch d := req.Data.(type) {
+	case nil:
+	case string:
+		body = d
+	default:
+		raw, err := json.Marshal(d)
+		if err != nil {
+			return "", "", fmt.Errorf("data 参数序列化失败: %w", err)
+		}
+		body = string(raw)
+	}
+	return query, body, nil
+}
+
+// HealthCheck 检查各租户页面上的签名入口是否可用。
+func (p *Platform) HealthCheck(ctx context.Context) error {
+	return p.pools.Check(ctx, defaultReadyJS, nil)
+}
+
+// ProbeStealth 在注入 path 的临时页面上检查签名模块可用，实现 platform.StealthProber。
+func (p *Platform) ProbeStealth(ctx context.Context, path string) error {
+	return p.pools.Probe(ctx, path, func(slot *pagepool.Slot) error {
+		return pagepool.CheckSlot(slot, defaultReadyJS, nil)
+	})
+}
+
+// StageUpgrade 在新浏览器 b 上预热与当前规模相同的页面，实现 platform.Upgrader。
+func (p *Platform) StageUpgrade(ctx context.Context, b *browser.Browser, stealthPath string) (platform.Staged, error) {
+	return p.pools.Stage(ctx, pagepool.Generation{Browser: b, StealthPath: stealthPath}, p.env.WarmupConcurrency)
+}
+
+// RenameStealth 将页面池中注入 from 的 stealth.js 路径改为 to，实现 platform.StealthRenamer。
+func (p *Platform) RenameStealth(from, to string) {
+	p.pools.RenameStealth(from, to)
+}
+
+// Recycle 逐个重建各租户的浏览器上下文，实现 platform.Recycler。
+func (p *Platform) Recycle(ctx context.Context) error {
+	return p.pools.Recycle(ctx)
+}
+
+// Close 关闭全部页面。
+func (p *Platform) Close() error {
+	return p.pools.Close()
+}
@@ -0,0 +1,42 @@
+package douyin
+
+import (
+	"encoding/json"
+	"os/exec"
+	"reflect"
+	"testing"
+)
+
+func TestXBogusBindsQuery(t *testing.T) {
+	p := &Platform{options: options{ABogusJS: defaultABogusJS, XBogusJS: defaultXBogusJS}}
+	for _, alg := range []string{AlgorithmABogus, AlgorithmXBogus} {
+		_, a := p.signArgs(alg, "aid=6383&count=10", `{"x":1}`, "ua")
+		_, b := p.signArgs(alg, "aid=6383&count=20", `{"x":1}`, "ua")
+		if reflect.DeepEqual(a, b) {
+			t.Errorf("%s: 不同查询串的签名参数相同: %v", alg, a)
+		}
+	}
+
+	// 在 Node.js 中以记录入参的 frontierSign 执行默认的 X-Bogus 调用，确认查询串传入了站点签名函数
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("未安装 node，跳过执行默认 X-Bogus 调用")
+	}
+	frontier := func(query string) string {
+		_, args := p.signArgs(AlgorithmXBogus, query, "", "ua")
+		raw, err := json.Marshal(args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		src := `globalThis.window = {byted_acrawler: {frontierSign: (...a) => ({"X-Bogus": JSON.stringify(a)})}};
+process.stdout.write((` + defaultXBogusJS + `)(` + string(raw) + `));`
+		out, err := exec.Command(node, "-e", src).Output()
+		if err != nil {
+			t.Fatalf("执行默认 X-Bogus 调用失败: %v", err)
+		}
+		return string(out)
+	}
+	if a, b := frontier("aid=6383&count=10"), frontier("aid=6383&count=20"); a == b {
+		t.Errorf("frontierSign 的入参与查询串无关: %s", a)
+	}
+}
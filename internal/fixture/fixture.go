@@ -0,0 +1,195 @@
+// Package fixture 提供签名结果的录制与回放。
+//
+// 录制模式将每次成功签名的请求与结果按行追加到 JSON Lines 文件；
+// 回放模式从该文件加载结果，按平台与请求内容原样返回，不启动浏览器，
+// 便于下游爬虫与本服务 HTTP 层的确定性集成测试。
+//
+// 配置加密密钥时，请求中的 cookie（a1、web_session 等账号凭据）加密后写入 encrypted_cookies 字段。
+package fixture
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+
+	"go_sign/internal/crypt"
+	"go_sign/internal/platform"
+)
+
+// ErrNotRecorded 表示回放文件中没有与请求匹配的录制结果。
+var ErrNotRecorded = errors.New("未找到匹配的录制结果")
+
+// Entry 为录制文件中的一行。
+type Entry struct {
+	Platform string                 `json:"platform"`
+	Request  *platform.SignRequest  `json:"request"`
+	Response *platform.SignResponse `json:"response"`
+	// EncryptedCookies 为加密后的 request.cookies，此时 request 中不含 cookies。
+	EncryptedCookies string `json:"encrypted_cookies,omitempty"`
+}
+
+// key 返回匹配回放结果使用的键：平台名与请求的规范 JSON（map 按键名排序）。
+func key(name string, req *platform.SignRequest) (string, error) {
+	raw, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("序列化签名请求失败: %w", err)
+	}
+	return name + "\x00" + string(raw), nil
+}
+
+// Recorder 将签名结果追加写入录制文件。
+type Recorder struct {
+	mu     sync.Mutex
+	f      *os.File
+	enc    *json.Encoder
+	cipher crypt.Cipher
+}
+
+// NewRecorder 打开（不存在时创建）录制文件，新结果追加到末尾；cipher 非 nil 时加密请求中的 cookie。
+func NewRecorder(path string, cipher crypt.Cipher) (*Recorder, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("打开录制文件失败: %w", err)
+	}
+	return &Recorder{f: f, enc: json.NewEncoder(f), cipher: cipher}, nil
+}
+
+// Wrap 包装平台，签名成功后写入录制文件。
+func (r *Recorder) Wrap(p platform.Platform) platform.Platform {
+	return &recording{Platform: p, r: r}
+}
+
+// write 写入一条录制结果。
+func (r *Recorder) write(e Entry) {
+	if r.cipher != nil && len(e.Request.Cookies) > 0 {
+		raw, err := json.Marshal(e.Request.Cookies)
+		if err == nil {
+			e.EncryptedCookies, err = r.cipher.Encrypt(raw)
+		}
+		if err != nil {
+			slog.Error("加密 cookie 失败，不写入录制文件", "err", err, "platform", e.Platform, "uri", e.Request.URI)
+			return
+		}
+		req := *e.Request
+		req.Cookies = nil
+		e.Request = &req
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.enc.Encode(e); err != nil {
+		slog.Error("写入录制文件失败", "err", err, "platform", e.Platform, "uri", e.Request.URI)
+	}
+}
+
+// Close 关闭录制文件。
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.f.Close()
+}
+
+type recording struct {
+	platform.Platform
+	r *Recorder
+}
+
+// Sign 调用原平台签名，成功时录制请求与结果。
+func (p *recording) Sign(ctx context.Context, req *platform.SignRequest) (*platform.SignResponse, error) {
+	res, err := p.Platform.Sign(ctx, req)
+	if err == nil {
+		p.r.write(Entry{Platform: p.Name(), Request: req, Response: res})
+	}
+	return res, err
+}
+
+// Replayer 按请求返回录制结果。
+type Replayer struct {
+	results map[string]*platform.SignResponse
+}
+
+// Load 读取录制文件，同一请求录制多次时使用最后一次的结果；cipher 用于解密 encrypted_cookies。
+func Load(path string, cipher crypt.Cipher) (*Replayer, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("打开回放文件失败: %w", err)
+	}
+	defer f.Close()
+
+	r := &Replayer{results: make(map[string]*platform.SignResponse)}
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64<<10), 4<<20)
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			return nil, fmt.Errorf("回放文件第 %d 行格式错误: %w", line, err)
+		}
+		if e.Platform == "" || e.Request == nil || e.Response == nil {
+			return nil, fmt.Errorf("回放文件第 %d 行缺少 platform、request 或 response", line)
+		}
+		if e.EncryptedCookies != "" {
+			if cipher == nil {
+				return nil, fmt.Errorf("回放文件第 %d 行: %w", line, crypt.ErrNoKey)
+			}
+			raw, err := cipher.Decrypt(e.EncryptedCookies)
+			if err != nil {
+				return nil, fmt.Errorf("回放文件第 %d 行: %w", line, err)
+			}
+			if err := json.Unmarshal(raw, &e.Request.Cookies); err != nil {
+				return nil, fmt.Errorf("回放文件第 %d 行 cookie 格式错误: %w", line, err)
+			}
+		}
+		k, err := key(e.Platform, e.Request)
+		if err != nil {
+			return nil, fmt.Errorf("回放文件第 %d 行: %w", line, err)
+		}
+		r.results[k] = e.Response
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("读取回放文件失败: %w", err)
+	}
+	slog.Info("回放文件加载完成", "path", path, "results", len(r.results))
+	return r, nil
+}
+
+// Wrap 将平台替换为回放实现：不初始化原平台，签名时返回录制结果。
+func (r *Replayer) Wrap(p platform.Platform) platform.Platform {
+	return &replaying{name: p.Name(), r: r}
+}
+
+type replaying struct {
+	name string
+	r    *Replayer
+}
+
+func (p *replaying) Name() string { return p.name }
+
+// Init 不初始化原平台，回放无需浏览器等资源。
+func (p *replaying) Init(context.Context, *platform.Env) error { return nil }
+
+// Sign 返回与请求匹配的录制结果。
+func (p *replaying) Sign(_ context.Context, req *platform.SignRequest) (*platform.SignResponse, error) {
+	k, err := key(p.name, req)
+	if err != nil {
+		return nil, err
+	}
+	res, ok := p.r.results[k]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s %s", ErrNotRecorded, p.name, req.URI)
+	}
+	return res, nil
+}
+
+func (p *replaying) HealthCheck(context.Context) error { return nil }
+
+// SelfTest 无需签名，回放结果已在加载时校验。
+func (p *replaying) SelfTest(context.Context) error { return nil }
+
+func (p *replaying) Close() error { return nil }
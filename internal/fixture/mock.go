@@ -0,0 +1,43 @@
+package fixture
+
+import (
+	"context"
+
+	"go_sign/internal/platform"
+)
+
+// MockHeader 为未实现 platform.Mocker 的平台在 mock 模式下返回的请求头。
+const MockHeader = "x-mock-signature"
+
+// Mock 将平台替换为 mock 实现：不初始化原平台，签名时立即返回格式合法的假结果。
+// 原平台实现 platform.Mocker 时由其生成假结果，否则返回 MockHeader 请求头。
+// 须作为第一个包装调用，以便识别原平台。
+func Mock(p platform.Platform) platform.Platform {
+	m, _ := p.(platform.Mocker)
+	return &mocking{name: p.Name(), mocker: m}
+}
+
+type mocking struct {
+	name   string
+	mocker platform.Mocker
+}
+
+func (p *mocking) Name() string { return p.name }
+
+// Init 不初始化原平台，mock 模式无需浏览器等资源。
+func (p *mocking) Init(context.Context, *platform.Env) error { return nil }
+
+// Sign 返回假签名结果。
+func (p *mocking) Sign(_ context.Context, req *platform.SignRequest) (*platform.SignResponse, error) {
+	if p.mocker != nil {
+		return p.mocker.Mock(req), nil
+	}
+	return &platform.SignResponse{Headers: map[string]string{MockHeader: platform.MockHex(16)}}, nil
+}
+
+func (p *mocking) HealthCheck(context.Context) error { return nil }
+
+// SelfTest 无需签名，mock 结果始终可用。
+func (p *mocking) SelfTest(context.Context) error { return nil }
+
+func (p *mocking) Close() error { return nil }
@@ -0,0 +1,254 @@
+// Package geo 查询代理出口 IP 的地理位置，检查其与浏览器上下文模拟的时区、语言是否一致。
+// 出口在海外而时区为 Asia/Shanghai、语言为 zh-CN 之类的组合会明显提高验证码出现的比例，
+// 不一致时输出告警，或按出口位置调整时区与语言。
+package geo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata" // 精简镜像中可能没有系统时区数据库
+
+	"go_sign/internal/metrics"
+)
+
+// 不一致时的处理方式。
+const (
+	// ModeWarn 只输出告警日志。
+	ModeWarn = "warn"
+	// ModeAdjust 按出口位置调整时区与语言。
+	ModeAdjust = "adjust"
+)
+
+// DefaultURL 为默认的出口位置查询接口，返回 JSON 中的 query、countryCode、timezone 字段。
+const DefaultURL = "http://ip-api.com/json/?fields=status,message,query,countryCode,timezone"
+
+// 查询的限制。
+const (
+	lookupTimeout = 10 * time.Second
+	// cacheTTL 内同一代理不重复查询，预热多个共用代理的上下文时只查询一次
+	cacheTTL = 10 * time.Minute
+	maxBody  = 64 << 10
+)
+
+// checks 统计出口位置检查的结果。
+var checks = metrics.Default.NewCounterVec(
+	"go_sign_geo_checks_total",
+	"代理出口位置与浏览器上下文时区、语言的一致性检查次数，result 为 consistent、mismatch、adjusted 或 error",
+	"result",
+)
+
+// Location 为出口 IP 的地理位置。
+type Location struct {
+	IP string
+	// Country 为 ISO 3166-1 两位国家或地区代码，如 CN。
+	Country string
+	// Timezone 为 IANA 时区名，如 Asia/Shanghai。
+	Timezone string
+}
+
+// Emulation 为浏览器上下文模拟的时区与语言，为空时使用浏览器默认值（时区为本机时区）。
+type Emulation struct {
+	Timezone string
+	Locale   string
+}
+
+// locales 为按国家或地区调整语言时使用的默认语言。
+var locales = map[string]string{
+	"CN": "zh-CN", "HK": "zh-HK", "MO": "zh-MO", "TW": "zh-TW", "SG": "en-SG",
+	"US": "en-US", "GB": "en-GB", "CA": "en-CA", "AU": "en-AU",
+	"JP": "ja-JP", "KR": "ko-KR", "DE": "de-DE", "FR": "fr-FR",
+}
+
+// Conflicts 返回出口位置与 e 不一致之处：时区的当前 UTC 偏移不同，或语言的地区与出口所在国家或地区不同。
+// 未设置语言或语言不带地区时不检查语言。
+func (l *Location) Conflicts(e Emulation) []string {
+	var out []string
+	if l.Timezone != "" {
+		if want, err := time.LoadLocation(l.Timezone); err == nil {
+			have, name := time.Local, "本机时区 "+time.Local.String()
+			if e.Timezone != "" {
+				if have, err = time.LoadLocation(e.Timezone); err != nil {
+					have = nil
+				}
+				name = e.Timezone
+			}
+			now := time.Now()
+			if have != nil && offset(now, have) != offset(now, want) {
+				out = append(out, fmt.Sprintf("时区 %s 与出口时区 %s 不一致", name, l.Timezone))
+			}
+		}
+	}
+	if region := localeRegion(e.Locale); region != "" && l.Country != "" && region != l.Country {
+		out = append(out, fmt.Sprintf("语言 %s 与出口所在地区 %s 不一致", e.Locale, l.Country))
+	}
+	return out
+}
+
+// offset 返回 loc 在 t 时刻的 UTC 偏移秒数。
+func offset(t time.Time, loc *time.Location) int {
+	_, off := t.In(loc).Zone()
+	return off
+}
+
+// localeRegion 返回语言标签中的地区，如 zh-CN 为 CN，不带地区时返回空字符串。
+func localeRegion(locale string) string {
+	parts := strings.FieldsFunc(locale, func(r rune) bool { return r == '-' || r == '_' })
+	if len(parts) < 2 {
+		return ""
+	}
+	return strings.ToUpper(parts[len(parts)-1])
+}
+
+// Adjust 返回按出口位置调整后的时区与语言：时区取出口时区，语言取出口所在国家或地区的默认语言，
+// 没有对应的默认语言时保留原值。
+func (l *Location) Adjust(e Emulation) Emulation {
+	if l.Timezone != "" {
+		e.Timezone = l.Timezone
+	}
+	if locale, ok := locales[l.Country]; ok {
+		e.Locale = locale
+	}
+	return e
+}
+
+// Checker 经代理查询出口位置并检查浏览器上下文的时区与语言，查询结果按代理缓存。并发安全。
+type Checker struct {
+	url  string
+	mode string
+
+	mu    sync.Mutex
+	cache map[string]cached
+}
+
+// cached 为一次查询的结果。
+type cached struct {
+	loc *Location
+	at  time.Time
+}
+
+// NewChecker 创建检查器，lookupURL 为空时使用 DefaultURL，mode 为 ModeWarn 或 ModeAdjust，为空时为 ModeWarn。
+func NewChecker(lookupURL, mode string) (*Checker, error) {
+	if lookupURL == "" {
+		lookupURL = DefaultURL
+	}
+	if u, err := url.Parse(lookupURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("url: %q 不是合法的 http(s) 地址", lookupURL)
+	}
+	switch mode {
+	case "":
+		mode = ModeWarn
+	case ModeWarn, ModeAdjust:
+	default:
+		return nil, fmt.Errorf("mode: 不支持 %q，可选 %s、%s", mode, ModeWarn, ModeAdjust)
+	}
+	return &Checker{url: lookupURL, mode: mode, cache: make(map[string]cached)}, nil
+}
+
+// Check 查询 proxy 的出口位置并与 e 比较，返回浏览器上下文应使用的时区与语言：
+// 一致或 ModeWarn 时返回 e，ModeAdjust 且不一致时返回调整后的值。查询失败时只输出告警并返回 e。
+func (c *Checker) Check(ctx context.Context, proxy *url.URL, e Emulation, log *slog.Logger) Emulation {
+	loc, err := c.Lookup(ctx, proxy)
+	if err != nil {
+		checks.Inc("error")
+		log.Warn("查询代理出口位置失败，跳过时区与语言检查", "err", err, "proxy", proxy.Redacted())
+		return e
+	}
+	conflicts := loc.Conflicts(e)
+	if len(conflicts) == 0 {
+		checks.Inc("consistent")
+		return e
+	}
+	if c.mode == ModeAdjust {
+		adjusted := loc.Adjust(e)
+		checks.Inc("adjusted")
+		log.Warn("代理出口位置与浏览器上下文不一致，已按出口位置调整时区与语言", "conflicts", conflicts, "proxy", proxy.Redacted(),
+			"exit_ip", loc.IP, "country", loc.Country, "timezone", adjusted.Timezone, "locale", adjusted.Locale)
+		return adjusted
+	}
+	checks.Inc("mismatch")
+	log.Warn("代理出口位置与浏览器上下文不一致，验证码比例可能升高", "conflicts", conflicts, "proxy", proxy.Redacted(),
+		"exit_ip", loc.IP, "country", loc.Country, "exit_timezone", loc.Timezone)
+	return e
+}
+
+// Lookup 经 proxy 请求查询接口，返回出口 IP 的位置；cacheTTL 内同一代理返回缓存的结果。
+func (c *Checker) Lookup(ctx context.Context, proxy *url.URL) (*Location, error) {
+	key := proxy.String()
+	c.mu.Lock()
+	if hit, ok := c.cache[key]; ok && time.Since(hit.at) < cacheTTL {
+		c.mu.Unlock()
+		return hit.loc, nil
+	}
+	c.mu.Unlock()
+
+	loc, err := c.lookup(ctx, proxy)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.cache[key] = cached{loc: loc, at: time.Now()}
+	c.mu.Unlock()
+	return loc, nil
+}
+
+// lookupResponse 兼容 ip-api.com（query、countryCode）与 ipapi.co（ip、country_code）的返回格式。
+type lookupResponse struct {
+	Status       string `json:"status"`
+	Message      string `json:"message"`
+	Query        string `json:"query"`
+	IP           string `json:"ip"`
+	CountryCode  string `json:"countryCode"`
+	CountryCode2 string `json:"country_code"`
+	Timezone     string `json:"timezone"`
+}
+
+// lookup 经 proxy 请求查询接口。
+func (c *Checker) lookup(ctx context.Context, proxy *url.URL) (*Location, error) {
+	ctx, cancel := context.WithTimeout(ctx, lookupTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxy)}}
+	defer client.CloseIdleConnections()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求查询接口失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("查询接口返回 HTTP %d", resp.StatusCode)
+	}
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxBody))
+	if err != nil {
+		return nil, fmt.Errorf("读取查询结果失败: %w", err)
+	}
+	var out lookupResponse
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, fmt.Errorf("解析查询结果失败: %w", err)
+	}
+	if out.Status != "" && out.Status != "success" {
+		return nil, fmt.Errorf("查询失败: %s", out.Message)
+	}
+	loc := &Location{IP: out.Query, Country: strings.ToUpper(out.CountryCode), Timezone: out.Timezone}
+	if loc.IP == "" {
+		loc.IP = out.IP
+	}
+	if loc.Country == "" {
+		loc.Country = strings.ToUpper(out.CountryCode2)
+	}
+	if loc.Country == "" && loc.Timezone == "" {
+		return nil, errors.New("查询结果中没有国家或地区与时区")
+	}
+	return loc, nil
+}
@@ -0,0 +1,93 @@
+package geo_test
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"go_sign/internal/geo"
+)
+
+// newProxy 返回充当 HTTP 代理的测试服务：代理请求一律以 body 应答，并统计收到的请求数。
+func newProxy(t *testing.T, body string) (*url.URL, *atomic.Int32) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		if !r.URL.IsAbs() {
+			http.Error(w, "不是代理请求", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	u, _ := url.Parse(srv.URL)
+	return u, &hits
+}
+
+func TestCheck(t *testing.T) {
+	const tokyo = `{"status":"success","query":"203.0.113.7","countryCode":"JP","timezone":"Asia/Tokyo"}`
+	tests := []struct {
+		name string
+		mode string
+		body string
+		in   geo.Emulation
+		want geo.Emulation
+	}{
+		{name: "一致", mode: geo.ModeAdjust, body: tokyo, in: geo.Emulation{Timezone: "Asia/Tokyo", Locale: "ja-JP"}, want: geo.Emulation{Timezone: "Asia/Tokyo", Locale: "ja-JP"}},
+		{name: "不一致只告警", mode: geo.ModeWarn, body: tokyo, in: geo.Emulation{Timezone: "Asia/Shanghai", Locale: "zh-CN"}, want: geo.Emulation{Timezone: "Asia/Shanghai", Locale: "zh-CN"}},
+		{name: "不一致时调整", mode: geo.ModeAdjust, body: tokyo, in: geo.Emulation{Timezone: "Asia/Shanghai", Locale: "zh-CN"}, want: geo.Emulation{Timezone: "Asia/Tokyo", Locale: "ja-JP"}},
+		{name: "ipapi.co 格式", mode: geo.ModeAdjust, body: `{"ip":"198.51.100.1","country_code":"cn","timezone":"Asia/Shanghai"}`, in: geo.Emulation{Timezone: "Asia/Tokyo", Locale: "en-US"}, want: geo.Emulation{Timezone: "Asia/Shanghai", Locale: "zh-CN"}},
+		{name: "查询失败时保留原值", mode: geo.ModeAdjust, body: `{"status":"fail","message":"reserved range"}`, in: geo.Emulation{Timezone: "Asia/Shanghai"}, want: geo.Emulation{Timezone: "Asia/Shanghai"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy, _ := newProxy(t, tt.body)
+			c, err := geo.NewChecker("http://geo.example/json", tt.mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := c.Check(context.Background(), proxy, tt.in, slog.Default()); got != tt.want {
+				t.Errorf("Check = %+v，期望 %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookupCache(t *testing.T) {
+	proxy, hits := newProxy(t, `{"status":"success","query":"203.0.113.7","countryCode":"US","timezone":"America/New_York"}`)
+	c, err := geo.NewChecker("http://geo.example/json", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		loc, err := c.Lookup(context.Background(), proxy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := (&geo.Location{IP: "203.0.113.7", Country: "US", Timezone: "America/New_York"}); !reflect.DeepEqual(loc, want) {
+			t.Fatalf("Lookup = %+v，期望 %+v", loc, want)
+		}
+	}
+	if n := hits.Load(); n != 1 {
+		t.Errorf("同一代理查询了 %d 次，期望只查询 1 次", n)
+	}
+}
+
+func TestConflicts(t *testing.T) {
+	loc := &geo.Location{Country: "CN", Timezone: "Asia/Shanghai"}
+	if got := loc.Conflicts(geo.Emulation{Timezone: "Asia/Chongqing", Locale: "zh-CN"}); len(got) != 0 {
+		t.Errorf("UTC 偏移相同的时区不应视为不一致: %v", got)
+	}
+	if got := loc.Conflicts(geo.Emulation{Timezone: "Asia/Shanghai", Locale: "zh"}); len(got) != 0 {
+		t.Errorf("不带地区的语言不应检查: %v", got)
+	}
+	if got := loc.Conflicts(geo.Emulation{Timezone: "Europe/London", Locale: "en-GB"}); len(got) != 2 {
+		t.Errorf("时区与语言均不一致，conflicts = %v", got)
+	}
+}
@@ -0,0 +1,343 @@
+// Package gql 提供 GraphQL 接口，覆盖签名 mutation 与账号、用量、平台状态、签名记录查询，
+// 供需要一次请求组合多项数据的控制台与脚本使用。schema 见 schema.graphql。
+package gql
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	graphql "github.com/graph-gophers/graphql-go"
+	"go_sign/internal/auth"
+	"go_sign/internal/platform"
+	"go_sign/internal/usage"
+)
+
+//go:embed schema.graphql
+var schemaSource string
+
+// keyContext 为 context 中保存调用方的键。
+type keyContext struct{}
+
+// caller 返回 context 中的调用方，未设置时为 auth.Anonymous。
+func caller(ctx context.Context) *auth.Key {
+	if k, ok := ctx.Value(keyContext{}).(*auth.Key); ok {
+		return k
+	}
+	return auth.Anonymous
+}
+
+// Handler 返回 GraphQL 接口，需放在鉴权与 platform.CallerContext 中间件之后。
+// 签名 mutation 在 resolver 内按调用方计入配额，因此无需配额中间件。
+func Handler(platforms *platform.Set, keyring *auth.Keyring, tracker *usage.Tracker, history *platform.History) (gin.HandlerFunc, error) {
+	schema, err := graphql.ParseSchema(schemaSource, &resolver{
+		platforms: platforms,
+		keyring:   keyring,
+		tracker:   tracker,
+		history:   history,
+	}, graphql.UseFieldResolvers())
+	if err != nil {
+		return nil, fmt.Errorf("解析 GraphQL schema 失败: %w", err)
+	}
+	return func(c *gin.Context) {
+		var req struct {
+			Query         string         `json:"query"`
+			OperationName string         `json:"operationName"`
+			Variables     map[string]any `json:"variables"`
+		}
+		if err := c.ShouldBindJSON(&req); err != nil {
+			slog.Warn("GraphQL 请求解析失败", "err", err, "client_ip", c.ClientIP())
+			c.JSON(http.StatusBadRequest, gin.H{"error": "参数解析失败: " + err.Error()})
+			return
+		}
+		ctx := context.WithValue(c.Request.Context(), keyContext{}, auth.FromContext(c))
+		c.JSON(http.StatusOK, schema.Exec(ctx, req.Query, req.OperationName, req.Variables))
+	}, nil
+}
+
+// resolver 为 Query 与 Mutation 的根 resolver。
+type resolver struct {
+	platforms *platform.Set
+	keyring   *auth.Keyring
+	tracker   *usage.Tracker
+	history   *platform.History
+}
+
+type platformStatus struct {
+	Name    string
+	State   string
+	Healthy bool
+	Error   *string
+}
+
+// Platforms 返回已启用平台的健康状态，按名称排序。
+func (r *resolver) Platforms(ctx context.Context) []platformStatus {
+	var out []platformStatus
+	for _, ps := range r.platforms.Status(ctx).Platforms {
+		s := platformStatus{Name: ps.Name, State: string(ps.State), Healthy: ps.State != platform.HealthDown}
+		if ps.Reason != "" {
+			reason := ps.Reason
+			s.Error = &reason
+		}
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+type account struct {
+	Name     string
+	Tenant   string
+	Priority string
+	Usage    *usageReport
+}
+
+// Me 返回当前调用方。
+func (r *resolver) Me(ctx context.Context) *account {
+	return r.accounts([]*auth.Key{caller(ctx)})[0]
+}
+
+// Accounts 返回当前调用方所属租户的全部 API Key，调用方只能看到自己租户的数据。
+func (r *resolver) Accounts(ctx context.Context) []*account {
+	tenant := caller(ctx).Tenant
+	var keys []*auth.Key
+	for _, k := range r.keyring.Keys() {
+		if k.Tenant == tenant {
+			keys = append(keys, k)
+		}
+	}
+	return r.accounts(keys)
+}
+
+// accounts 为 keys 附上当前用量，保持 keys 的顺序。
+func (r *resolver) accounts(keys []*auth.Key) []*account {
+	reports, _ := r.tracker.Reports(keys, nil)
+	byName := make(map[string]usage.Report, len(reports))
+	for _, rep := range reports {
+		byName[rep.Name] = rep
+	}
+	out := make([]*account, len(keys))
+	for i, k := range keys {
+		out[i] = &account{Name: k.Name, Tenant: k.Tenant.Name, Priority: k.Priority, Usage: newUsageReport(byName[k.Name])}
+	}
+	return out
+}
+
+// Tenant 返回当前调用方所属租户的用量。
+func (r *resolver) Tenant(ctx context.Context) *usageReport {
+	_, reports := r.tracker.Reports(nil, []*auth.Tenant{caller(ctx).Tenant})
+	return newUsageReport(reports[0])
+}
+
+type usageReport struct {
+	Name             string
+	Day              string
+	DailyCount       float64
+	DailyQuota       float64
+	DailyRemaining   *float64
+	Month            string
+	MonthlyCount     float64
+	MonthlyQuota     float64
+	MonthlyRemaining *float64
+}
+
+func newUsageReport(r usage.Report) *usageReport {
+	return &usageReport{
+		Name:             r.Name,
+		Day:              r.Day,
+		DailyCount:       float64(r.DailyCount),
+		DailyQuota:       float64(r.DailyQuota),
+		DailyRemaining:   floatPtr(r.DailyRemaining),
+		Month:            r.Month,
+		MonthlyCount:     float64(r.MonthlyCount),
+		MonthlyQuota:     float64(r.MonthlyQuota),
+		MonthlyRemaining: floatPtr(r.MonthlyRemaining),
+	}
+}
+
+func floatPtr(v *int64) *float64 {
+	if v == nil {
+		return nil
+	}
+	f := float64(*v)
+	return &f
+}
+
+type signRecord struct {
+	Time       string
+	Platform   string
+	APIKey     string
+	URI        string
+	DurationMs float64
+	Error      *string
+}
+
+// History 返回当前租户最近的签名记录。
+func (r *resolver) History(ctx context.Context, args struct {
+	Limit    int32
+	Platform *string
+}) []signRecord {
+	var out []signRecord
+	for _, rec := range r.history.Recent(ctx, caller(ctx).Tenant.Name, int(max(args.Limit, 0))) {
+		if args.Platform != nil && rec.Platform != *args.Platform {
+			continue
+		}
+		s := signRecord{
+			Time:       rec.Time.Format(time.RFC3339Nano),
+			Platform:   rec.Platform,
+			APIKey:     rec.APIKey,
+			URI:        rec.URI,
+			DurationMs: float64(rec.Duration.Microseconds()) / 1000,
+		}
+		if rec.Err != "" {
+			msg := rec.Err
+			s.Error = &msg
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+type pairInput struct {
+	Name  string
+	Value string
+}
+
+type signInput struct {
+	URI       string
+	Method    *string
+	DataJSON  *string
+	Params    *[]pairInput
+	Cookies   *[]pairInput
+	UserAgent *string
+	Timestamp *float64
+}
+
+// request 将 signInput 转换为 platform.SignRequest。
+func (in *signInput) request() (*platform.SignRequest, error) {
+	req := &platform.SignRequest{URI: in.URI, Params: pairs(in.Params), Cookies: pairs(in.Cookies)}
+	if in.Method != nil {
+		req.Method = *in.Method
+	}
+	if in.UserAgent != nil {
+		req.UserAgent = *in.UserAgent
+	}
+	if in.Timestamp != nil {
+		req.Timestamp = int64(*in.Timestamp)
+	}
+	if in.DataJSON != nil && *in.DataJSON != "" {
+		if err := json.Unmarshal([]byte(*in.DataJSON), &req.Data); err != nil {
+			return nil, fmt.Errorf("dataJson 解析失败: %w", err)
+		}
+	}
+	return req, nil
+}
+
+func pairs(in *[]pairInput) map[string]string {
+	if in == nil {
+		return nil
+	}
+	out := make(map[string]string, len(*in))
+	for _, p := range *in {
+		out[p.Name] = p.Value
+	}
+	return out
+}
+
+type pair struct {
+	Name  string
+	Value string
+}
+
+type signResult struct {
+	Headers    []pair
+	Params     []pair
+	Timestamp  float64
+	ServerTime float64
+	Source     *signSource
+	// ScriptVersion 为签名脚本的版本指纹，平台未提供时为 null。
+	ScriptVersion *string
+}
+
+type signSource struct {
+	Identity  string
+	ContextID string
+	UserAgent string
+	URI       string
+}
+
+// errQuotaExceeded 表示调用方或其租户的配额已耗尽。
+var errQuotaExceeded = errors.New("配额已耗尽")
+
+// Sign 使用名为 platform 的平台签名，与 POST /v1/<platform>/sign 相同地记录指标与签名记录。
+func (r *resolver) Sign(ctx context.Context, args struct {
+	Platform string
+	Input    *signInput
+}) (*signResult, error) {
+	p := r.platforms.Get(args.Platform)
+	if p == nil {
+		return nil, fmt.Errorf("平台未启用: %s", args.Platform)
+	}
+	req, err := args.Input.request()
+	if err != nil {
+		return nil, fmt.Errorf("参数解析失败: %w", err)
+	}
+	if err := platform.CheckTimestamp(req.Timestamp); err != nil {
+		return nil, fmt.Errorf("参数校验失败: %w", err)
+	}
+	if req.Cookies, err = platform.DecryptCookies("cookies.", req.Cookies); err != nil {
+		return nil, err
+	}
+	key := caller(ctx)
+	if _, _, ok := r.tracker.Take(key); !ok {
+		slog.Warn("配额已耗尽", "api_key", key.Name, "tenant", key.Tenant.Name)
+		return nil, errQuotaExceeded
+	}
+	slog.Info("GraphQL 签名请求", "platform", p.Name(), "uri", req.URI, "api_key", key.Name, "tenant", key.Tenant.Name)
+	start := time.Now()
+	res, err := p.Sign(ctx, req)
+	platform.ObserveSign(platform.SignRecord{
+		Time:     start,
+		Platform: p.Name(),
+		Tenant:   key.Tenant.Name,
+		APIKey:   key.Name,
+		URI:      req.URI,
+		Account:  platform.AccountOf(res),
+		Result:   res,
+		Duration: time.Since(start),
+	}, err)
+	if err != nil {
+		slog.Error("签名失败", "err", err, "platform", p.Name(), "uri", req.URI)
+		return nil, fmt.Errorf("签名失败: %w", err)
+	}
+	out := &signResult{
+		Headers:    sortedPairs(res.Headers),
+		Params:     sortedPairs(res.Params),
+		Timestamp:  float64(res.Timestamp),
+		ServerTime: float64(time.Now().UnixMilli()),
+	}
+	if s := res.Source; s != nil {
+		out.Source = &signSource{Identity: s.Identity, ContextID: s.ContextID, UserAgent: s.UserAgent, URI: s.URI}
+	}
+	if res.ScriptVersion != "" {
+		out.ScriptVersion = &res.ScriptVersion
+	}
+	return out, nil
+}
+
+// sortedPairs 将 m 转换为按名称排序的键值对列表。
+func sortedPairs(m map[string]string) []pair {
+	out := make([]pair, 0, len(m))
+	for k, v := range m {
+		out = append(out, pair{Name: k, Value: v})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
@@ -0,0 +1,193 @@
+// Package integrity 为签名类接口的响应体附上完整性签名：下游经消息队列、代理等中间环节转发签名结果时，
+// 可按 X-Response-Key-Id 选择密钥验签，确认结果未被篡改。支持 HMAC-SHA256 与 Ed25519。
+package integrity
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/config"
+)
+
+// 响应签名的响应头。
+const (
+	// HeaderKeyID 为签名密钥的标识，即配置中的 key_id。
+	HeaderKeyID = "X-Response-Key-Id"
+	// HeaderTimestamp 为签名时的 Unix 秒级时间戳。
+	HeaderTimestamp = "X-Response-Timestamp"
+	// HeaderSignature 为十六进制编码的签名，签名内容为 <timestamp>\n<响应体>。
+	HeaderSignature = "X-Response-Signature"
+)
+
+// Signer 以配置的密钥签名响应体。
+type Signer struct {
+	keyID     string
+	algorithm string
+	secret    []byte
+	private   ed25519.PrivateKey
+}
+
+// New 按配置创建 Signer，c 为 nil 时返回 nil（不签名）。ed25519 私钥须为 PKCS#8 PEM。
+func New(c *config.ResponseSigning) (*Signer, error) {
+	if c == nil {
+		return nil, nil
+	}
+	s := &Signer{keyID: c.KeyID, algorithm: c.Algorithm}
+	if s.algorithm == "" {
+		s.algorithm = config.ResponseSigningHMAC
+	}
+	if s.algorithm == config.ResponseSigningHMAC {
+		s.secret = []byte(c.Secret)
+		return s, nil
+	}
+	raw, err := os.ReadFile(c.PrivateKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应签名私钥失败: %w", err)
+	}
+	if s.private, err = parsePrivateKey(raw); err != nil {
+		return nil, fmt.Errorf("解析响应签名私钥 %s 失败: %w", c.PrivateKeyFile, err)
+	}
+	return s, nil
+}
+
+// parsePrivateKey 解析 PKCS#8 PEM 格式的 Ed25519 私钥。
+func parsePrivateKey(raw []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, errors.New("不是 PEM 格式")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	private, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("不是 Ed25519 私钥: %T", key)
+	}
+	return private, nil
+}
+
+// Sign 返回 timestamp 与 body 的十六进制签名。
+func (s *Signer) Sign(timestamp string, body []byte) string {
+	msg := make([]byte, 0, len(timestamp)+1+len(body))
+	msg = append(append(append(msg, timestamp...), '\n'), body...)
+	if s.private != nil {
+		return hex.EncodeToString(ed25519.Sign(s.private, msg))
+	}
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write(msg)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// KeyInfo 为公开的验签密钥信息，HMAC 密钥不公开。
+type KeyInfo struct {
+	KeyID     string `json:"key_id"`
+	Algorithm string `json:"algorithm"`
+	// PublicKey 为 Ed25519 公钥（PKIX PEM），HMAC 时为空。
+	PublicKey string `json:"public_key,omitempty"`
+}
+
+// Info 返回验签所需的公开信息。
+func (s *Signer) Info() KeyInfo {
+	info := KeyInfo{KeyID: s.keyID, Algorithm: s.algorithm}
+	if s.private != nil {
+		der, err := x509.MarshalPKIXPublicKey(s.private.Public())
+		if err == nil {
+			info.PublicKey = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+		}
+	}
+	return info
+}
+
+// KeysHandler 返回列出验签密钥的接口，未配置响应签名时返回空列表。
+func (s *Signer) KeysHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		keys := []KeyInfo{}
+		if s != nil {
+			keys = append(keys, s.Info())
+		}
+		c.JSON(http.StatusOK, gin.H{"keys": keys})
+	}
+}
+
+// Middleware 返回签名响应体的中间件：缓存处理函数写出的响应，写出前附上 X-Response-Key-Id、
+// X-Response-Timestamp 与 X-Response-Signature。s 为 nil 时不做任何处理。不能用于 SSE 等流式响应。
+func (s *Signer) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s == nil {
+			c.Next()
+			return
+		}
+		w := &bufferedWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = w
+		// 处理函数 panic 时恢复原始的 Writer，由外层的 Recovery 写出错误响应
+		defer func() { c.Writer = w.ResponseWriter }()
+		c.Next()
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		body := w.body.Bytes()
+		h := w.ResponseWriter.Header()
+		h.Set(HeaderKeyID, s.keyID)
+		h.Set(HeaderTimestamp, ts)
+		h.Set(HeaderSignature, s.Sign(ts, body))
+		w.ResponseWriter.WriteHeader(w.status)
+		_, _ = w.ResponseWriter.Write(body)
+	}
+}
+
+// bufferedWriter 缓存响应状态码与响应体，由 Middleware 在签名后写出。
+type bufferedWriter struct {
+	gin.ResponseWriter
+	body    bytes.Buffer
+	status  int
+	written bool
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {
+	w.written = true
+}
+
+func (w *bufferedWriter) Write(b []byte) (int, error) {
+	w.written = true
+	return w.body.Write(b)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	w.written = true
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.written
+}
+
+// Flush 不写出缓存的响应，签名须在响应体完整后计算。
+func (w *bufferedWriter) Flush() {}
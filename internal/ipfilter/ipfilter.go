@@ -0,0 +1,123 @@
+// Package ipfilter 提供基于 CIDR 的客户端 IP 允许 / 拒绝名单中间件，
+// 适合单团队部署时代替 API Key 鉴权限制访问来源。名单可在运行时替换。
+package ipfilter
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/metrics"
+)
+
+// rejected 统计被名单拒绝的请求。
+var rejected = metrics.Default.NewCounterVec(
+	"go_sign_ip_filter_rejected_total",
+	"被 IP 名单拒绝的请求数，reason 为 deny 或 not_allowed",
+	"reason",
+)
+
+// rules 为一份解析后的名单。
+type rules struct {
+	allow []*net.IPNet
+	deny  []*net.IPNet
+}
+
+// Filter 为可在运行时替换名单的 IP 过滤器，并发安全。
+type Filter struct {
+	rules atomic.Pointer[rules]
+}
+
+// New 创建过滤器，allow、deny 均为空时放行全部请求。
+func New(allow, deny []string) (*Filter, error) {
+	f := &Filter{}
+	if err := f.Update(allow, deny); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Update 替换名单，任一条目不合法时保留原名单并返回错误。
+func (f *Filter) Update(allow, deny []string) error {
+	r := &rules{}
+	var err error
+	if r.allow, err = ParseNets(allow); err != nil {
+		return fmt.Errorf("allow: %w", err)
+	}
+	if r.deny, err = ParseNets(deny); err != nil {
+		return fmt.Errorf("deny: %w", err)
+	}
+	f.rules.Store(r)
+	return nil
+}
+
+// Allowed 判断 ip 是否允许访问：命中 deny 时拒绝；allow 非空时须命中 allow。
+// 返回的 reason 用于日志与指标，放行时为空。
+func (f *Filter) Allowed(ip net.IP) (ok bool, reason string) {
+	r := f.rules.Load()
+	if contains(r.deny, ip) {
+		return false, "deny"
+	}
+	if len(r.allow) > 0 && !contains(r.allow, ip) {
+		return false, "not_allowed"
+	}
+	return true, ""
+}
+
+// Middleware 返回按客户端 IP 过滤请求的中间件，被拒绝时返回 403。
+// 客户端 IP 取 gin 的 ClientIP，部署在代理之后时需配置可信代理。
+func (f *Filter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ip := net.ParseIP(c.ClientIP())
+		if ok, reason := f.Allowed(ip); !ok {
+			rejected.Inc(reason)
+			slog.Warn("IP 名单拒绝请求", "reason", reason, "path", c.FullPath(), "client_ip", c.ClientIP())
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "客户端 IP 不允许访问"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// ParseNets 将 IP 或 CIDR 列表解析为网段，单个 IP 视为 /32 或 /128。
+func ParseNets(entries []string) ([]*net.IPNet, error) {
+	var out []*net.IPNet
+	for _, e := range entries {
+		e = strings.TrimSpace(e)
+		if !strings.Contains(e, "/") {
+			ip := net.ParseIP(e)
+			if ip == nil {
+				return nil, fmt.Errorf("%q 不是合法的 IP 或 CIDR", e)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			out = append(out, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(e)
+		if err != nil {
+			return nil, fmt.Errorf("%q 不是合法的 IP 或 CIDR", e)
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+// contains 判断 ip 是否落在 nets 的任一网段内，ip 为 nil 时返回 false。
+func contains(nets []*net.IPNet, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
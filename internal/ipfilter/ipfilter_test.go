@@ -0,0 +1,88 @@
+package ipfilter
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		name        string
+		allow, deny []string
+		ip          string
+		ok          bool
+		reason      string
+	}{
+		{name: "名单为空时放行", ip: "203.0.113.7", ok: true},
+		{name: "命中 allow", allow: []string{"10.0.0.0/8"}, ip: "10.1.2.3", ok: true},
+		{name: "未命中 allow", allow: []string{"10.0.0.0/8"}, ip: "192.168.1.1", reason: "not_allowed"},
+		{name: "只有 deny 时其余放行", deny: []string{"10.0.0.0/8"}, ip: "192.168.1.1", ok: true},
+		{name: "命中 deny", deny: []string{"10.0.0.0/8"}, ip: "10.1.2.3", reason: "deny"},
+		{name: "deny 优先于 allow", allow: []string{"10.0.0.0/8"}, deny: []string{"10.1.0.0/16"}, ip: "10.1.2.3", reason: "deny"},
+		{name: "allow 内未被 deny 的地址", allow: []string{"10.0.0.0/8"}, deny: []string{"10.1.0.0/16"}, ip: "10.2.0.1", ok: true},
+		{name: "更宽的 deny 覆盖更窄的 allow", allow: []string{"10.1.2.3"}, deny: []string{"10.0.0.0/8"}, ip: "10.1.2.3", reason: "deny"},
+		{name: "单个 IP 视为 /32", allow: []string{"10.1.2.3"}, ip: "10.1.2.4", reason: "not_allowed"},
+		{name: "网段边界", allow: []string{"192.168.0.0/24"}, ip: "192.168.0.255", ok: true},
+		{name: "网段边界外", allow: []string{"192.168.0.0/24"}, ip: "192.168.1.0", reason: "not_allowed"},
+		{name: "IPv4 映射的 IPv6 地址", allow: []string{"10.0.0.0/8"}, ip: "::ffff:10.1.2.3", ok: true},
+		{name: "IPv6 网段", allow: []string{"2001:db8::/32"}, ip: "2001:db8::1", ok: true},
+		{name: "IPv6 命中 deny", allow: []string{"2001:db8::/32"}, deny: []string{"2001:db8:1::/48"}, ip: "2001:db8:1::1", reason: "deny"},
+		{name: "无法解析的 IP 不命中 allow", allow: []string{"10.0.0.0/8"}, ip: "", reason: "not_allowed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := New(tt.allow, tt.deny)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ok, reason := f.Allowed(net.ParseIP(tt.ip))
+			if ok != tt.ok || reason != tt.reason {
+				t.Errorf("Allowed(%s) = %v, %q，期望 %v, %q", tt.ip, ok, reason, tt.ok, tt.reason)
+			}
+		})
+	}
+}
+
+func TestUpdateKeepsRulesOnError(t *testing.T) {
+	f, err := New([]string{"10.0.0.0/8"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []string{"10.0.0.0/33", "not-an-ip", "10.0.0"} {
+		if err := f.Update(nil, []string{bad}); err == nil {
+			t.Errorf("非法条目 %q 应返回错误", bad)
+		}
+	}
+	if ok, _ := f.Allowed(net.ParseIP("192.168.1.1")); ok {
+		t.Error("更新失败后应保留原名单")
+	}
+	if err := f.Update(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := f.Allowed(net.ParseIP("192.168.1.1")); !ok {
+		t.Error("清空名单后应放行")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	f, err := New(nil, []string{"192.0.2.0/24"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := gin.New()
+	r.GET("/", f.Middleware(), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	for addr, want := range map[string]int{"192.0.2.10:1234": http.StatusForbidden, "198.51.100.1:1234": http.StatusNoContent} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = addr
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != want {
+			t.Errorf("%s: 状态码 = %d，期望 %d", addr, w.Code, want)
+		}
+	}
+}
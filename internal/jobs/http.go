@@ -0,0 +1,81 @@
+package jobs
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/auth"
+	"go_sign/internal/platform"
+)
+
+// submitRequest 为提交任务的请求体。
+type submitRequest struct {
+	Platform string                 `json:"platform" binding:"required"`
+	Requests []platform.SignRequest `json:"requests" binding:"required"`
+}
+
+// RegisterRoutes 注册任务路由，middlewares 需包含鉴权与 platform.CallerContext：
+//   - POST /jobs：提交任务，返回 202 与任务概要；
+//   - GET /jobs/:id：返回任务概要与已完成请求的结果；
+//   - GET /jobs/:id/events：以 Server-Sent Events 推送进度，先补发已完成的请求，
+//     每条请求完成时发送 item 事件，全部完成后发送 done 事件并关闭连接。
+func (m *Manager) RegisterRoutes(router gin.IRouter, middlewares ...gin.HandlerFunc) {
+	g := router.Group("/jobs", middlewares...)
+	g.POST("", func(c *gin.Context) {
+		var req submitRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			slog.Warn("批量任务参数解析失败", "err", err, "client_ip", c.ClientIP())
+			c.JSON(http.StatusBadRequest, gin.H{"error": "参数解析失败: " + err.Error()})
+			return
+		}
+		j, err := m.Submit(c.Request.Context(), auth.FromContext(c), req.Platform, req.Requests)
+		var verr *platform.ValidationError
+		if errors.As(err, &verr) {
+			c.JSON(http.StatusBadRequest, platform.ErrorBody(err))
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "参数校验失败: " + err.Error()})
+			return
+		}
+		c.JSON(http.StatusAccepted, j.Summary())
+	})
+	g.GET("/:id", func(c *gin.Context) {
+		j := m.Get(auth.FromContext(c).Tenant, c.Param("id"))
+		if j == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"job": j.Summary(), "items": j.Items()})
+	})
+	g.GET("/:id/events", func(c *gin.Context) {
+		j := m.Get(auth.FromContext(c).Tenant, c.Param("id"))
+		if j == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "任务不存在"})
+			return
+		}
+		past, events, cancel := j.Subscribe()
+		defer cancel()
+		c.Header("Cache-Control", "no-cache")
+		c.Header("X-Accel-Buffering", "no")
+		for i := range past {
+			c.SSEvent("item", past[i])
+		}
+		c.Stream(func(w io.Writer) bool {
+			select {
+			case ev := <-events:
+				if ev.Item != nil {
+					c.SSEvent("item", ev.Item)
+					return true
+				}
+				c.SSEvent("done", ev.Summary)
+				return false
+			case <-c.Request.Context().Done():
+				return false
+			}
+		})
+	})
+}
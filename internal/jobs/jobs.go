@@ -0,0 +1,470 @@
+// Package jobs 提供异步批量签名任务：一次提交多条签名请求，后台并发签名，
+// 调用方可轮询任务状态，或通过 Server-Sent Events 实时接收每条请求的完成事件。
+// 配置任务库后任务持久化到 BoltDB 文件，重启或崩溃后恢复未完成的任务。
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go_sign/internal/auth"
+	"go_sign/internal/crypt"
+	"go_sign/internal/platform"
+	"go_sign/internal/usage"
+	"go_sign/internal/webhook"
+)
+
+// 任务状态。
+const (
+	StateRunning = "running"
+	StateDone    = "done"
+)
+
+// Options 为批量任务的配置。
+type Options struct {
+	// Concurrency 为单个任务并发签名的请求数上限。
+	Concurrency int
+	// MaxItems 为单个任务最多包含的请求数。
+	MaxItems int
+	// Retention 为任务完成后保留结果的时长，超时后不再可查询。
+	Retention time.Duration
+	// StorePath 为任务库（BoltDB）文件路径，为空时任务只保存在内存中。
+	StorePath string
+	// Cipher 加密任务库中请求携带的 cookie（a1、web_session 等），为 nil 时 cookie 不落盘，
+	// 重启后携带 cookie 的未完成请求记为失败。
+	Cipher crypt.Cipher
+}
+
+// Item 为任务中一条请求的签名结果。
+type Item struct {
+	// Index 为该请求在提交列表中的下标。
+	Index  int                    `json:"index"`
+	Result *platform.SignResponse `json:"result,omitempty"`
+	Error  string                 `json:"error,omitempty"`
+}
+
+// Summary 为任务的概要，也是 SSE done 事件的内容。
+type Summary struct {
+	ID        string `json:"id"`
+	Platform  string `json:"platform"`
+	State     string `json:"state"`
+	Total     int    `json:"total"`
+	Completed int    `json:"completed"`
+	Failed    int    `json:"failed"`
+	// Resumed 为服务重启后重新执行的请求数。
+	Resumed  int        `json:"resumed,omitempty"`
+	Created  time.Time  `json:"created"`
+	Finished *time.Time `json:"finished,omitempty"`
+}
+
+// Job 为一个批量签名任务，并发安全。
+type Job struct {
+	id       string
+	platform platform.Platform
+	// platformName 为任务的平台名称，恢复的任务对应平台可能已不再启用
+	platformName string
+	key          *auth.Key
+	requests     []platform.SignRequest
+	created      time.Time
+	// pending 为待执行的请求下标，resumed 为重启后重新执行的请求数
+	pending []int
+	resumed int
+
+	mu       sync.Mutex
+	items    []Item // 按完成顺序
+	failed   int
+	finished time.Time
+	// subscribers 的缓冲足以容纳任务剩余的全部事件，发送时不会阻塞
+	subscribers map[chan Event]struct{}
+}
+
+// Event 为推送给订阅方的任务事件：Item 非 nil 时为单条请求完成，否则为任务完成。
+type Event struct {
+	Item    *Item
+	Summary *Summary
+}
+
+// ID 返回任务 ID。
+func (j *Job) ID() string {
+	return j.id
+}
+
+// Summary 返回任务当前的概要。
+func (j *Job) Summary() Summary {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.summary()
+}
+
+// summary 在持有锁的前提下生成概要。
+func (j *Job) summary() Summary {
+	s := Summary{
+		ID:        j.id,
+		Platform:  j.platformName,
+		State:     StateRunning,
+		Total:     len(j.requests),
+		Completed: len(j.items),
+		Failed:    j.failed,
+		Resumed:   j.resumed,
+		Created:   j.created,
+	}
+	if !j.finished.IsZero() {
+		finished := j.finished
+		s.State = StateDone
+		s.Finished = &finished
+	}
+	return s
+}
+
+// Items 返回已完成请求的结果，按完成顺序排列。
+func (j *Job) Items() []Item {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return append([]Item(nil), j.items...)
+}
+
+// Subscribe 返回已完成请求的结果与后续事件的通道；任务已完成时通道中只有 done 事件。
+// 调用方须在不再接收时调用 cancel。
+func (j *Job) Subscribe() (past []Item, events <-chan Event, cancel func()) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	past = append([]Item(nil), j.items...)
+	ch := make(chan Event, len(j.requests)-len(j.items)+1)
+	if !j.finished.IsZero() {
+		s := j.summary()
+		ch <- Event{Summary: &s}
+		return past, ch, func() {}
+	}
+	j.subscribers[ch] = struct{}{}
+	return past, ch, func() {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+		delete(j.subscribers, ch)
+	}
+}
+
+// complete 记录一条请求的结果并通知订阅方。
+func (j *Job) complete(item Item) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.items = append(j.items, item)
+	if item.Error != "" {
+		j.failed++
+	}
+	for ch := range j.subscribers {
+		ch <- Event{Item: &item}
+	}
+}
+
+// finish 将任务标记为完成并通知订阅方。
+func (j *Job) finish() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.finished = time.Now()
+	s := j.summary()
+	for ch := range j.subscribers {
+		ch <- Event{Summary: &s}
+	}
+	j.subscribers = nil
+}
+
+// Manager 创建、执行并保存批量任务，并发安全。
+type Manager struct {
+	platforms *platform.Set
+	tracker   *usage.Tracker
+	opts      Options
+	store     *store // 为 nil 时不持久化
+
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+// NewManager 创建任务管理器，任务中的每条请求按调用方计一次配额。
+// 配置 StorePath 时从任务库恢复任务：未完成的任务以原调用方（按名称在 keyring 中查找）重新执行未完成的请求。
+func NewManager(platforms *platform.Set, tracker *usage.Tracker, keyring *auth.Keyring, opts Options) (*Manager, error) {
+	m := &Manager{platforms: platforms, tracker: tracker, opts: opts, jobs: make(map[string]*Job)}
+	if opts.StorePath == "" {
+		return m, nil
+	}
+	var err error
+	if m.store, err = openStore(opts.StorePath, opts.Cipher); err != nil {
+		return nil, err
+	}
+	stored, err := m.store.load()
+	if err != nil {
+		_ = m.store.close()
+		return nil, fmt.Errorf("读取任务库失败: %w", err)
+	}
+	for _, sj := range stored {
+		m.restore(sj, keyring)
+	}
+	return m, nil
+}
+
+// restore 恢复一个落盘的任务：已过保留期的任务删除，已完成的任务只供查询，未完成的任务在后台继续执行；
+// 平台未启用或 API Key 已不存在时，未完成的请求直接记为失败。
+func (m *Manager) restore(sj storedJob, keyring *auth.Keyring) {
+	if !sj.Finished.IsZero() && time.Since(sj.Finished) > m.opts.Retention {
+		m.deleteStored(sj.ID)
+		return
+	}
+	j := &Job{
+		id:           sj.ID,
+		platform:     m.platforms.Get(sj.Platform),
+		platformName: sj.Platform,
+		key:          &auth.Key{Name: sj.APIKey, Tenant: &auth.Tenant{Name: sj.Tenant}},
+		requests:     sj.Requests,
+		created:      sj.Created,
+		finished:     sj.Finished,
+		resumed:      sj.Resumed,
+		subscribers:  make(map[chan Event]struct{}),
+	}
+	done := make(map[int]bool, len(sj.Items))
+	for _, item := range sj.Items {
+		j.items = append(j.items, item)
+		done[item.Index] = true
+		if item.Error != "" {
+			j.failed++
+		}
+	}
+	found := false
+	for _, key := range keyring.Keys() {
+		if key.Name == sj.APIKey && key.Tenant.Name == sj.Tenant {
+			j.key, found = key, true
+		}
+	}
+	m.mu.Lock()
+	m.jobs[j.id] = j
+	m.mu.Unlock()
+	if !sj.Finished.IsZero() {
+		return
+	}
+	for i := range j.requests {
+		switch {
+		case done[i]:
+		case sj.Stripped[i]:
+			// 未配置加密密钥时 cookie 未落盘，不能以不同的账号重新签名
+			m.complete(j, Item{Index: i, Error: "重启后请求的 cookie 未保存（未配置加密密钥），请重新提交"})
+		default:
+			j.pending = append(j.pending, i)
+		}
+	}
+	j.resumed += len(j.pending)
+	reason := ""
+	switch {
+	case j.platform == nil:
+		reason = "重启后平台未启用: " + sj.Platform
+	case !found:
+		reason = "重启后 API Key 已不存在: " + sj.APIKey
+	}
+	if reason != "" {
+		slog.Warn("无法恢复批量签名任务", "job", j.id, "reason", reason, "pending", len(j.pending))
+		for _, i := range j.pending {
+			m.complete(j, Item{Index: i, Error: reason})
+		}
+		m.finish(j)
+		return
+	}
+	slog.Info("恢复未完成的批量签名任务", "job", j.id, "platform", sj.Platform, "total", len(j.requests), "resumed", j.resumed, "api_key", sj.APIKey)
+	prio, _ := platform.ParsePriority(j.key.Priority)
+	ctx := platform.WithAPIKey(platform.WithTenant(platform.WithPriority(context.Background(), prio), j.key.Tenant.Name), j.key.Name)
+	go m.run(ctx, j)
+}
+
+// Submit 校验并提交任务，在后台以调用方的租户与优先级执行。
+func (m *Manager) Submit(ctx context.Context, key *auth.Key, platformName string, requests []platform.SignRequest) (*Job, error) {
+	p := m.platforms.Get(platformName)
+	if p == nil {
+		return nil, fmt.Errorf("平台未启用: %s", platformName)
+	}
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("requests 不能为空")
+	}
+	if len(requests) > m.opts.MaxItems {
+		return nil, fmt.Errorf("requests 共 %d 条，超过上限 %d", len(requests), m.opts.MaxItems)
+	}
+	for i, req := range requests {
+		if err := platform.CheckTimestamp(req.Timestamp); err != nil {
+			return nil, fmt.Errorf("requests[%d]: %w", i, err)
+		}
+		// 加密传入的 cookie 以密文保存在任务中，签名时再解密
+		if _, err := platform.DecryptCookies(fmt.Sprintf("requests[%d].cookies.", i), req.Cookies); err != nil {
+			return nil, err
+		}
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, fmt.Errorf("生成任务 ID 失败: %w", err)
+	}
+	j := &Job{
+		id:           hex.EncodeToString(id),
+		platform:     p,
+		platformName: p.Name(),
+		key:          key,
+		requests:     requests,
+		created:      time.Now(),
+		subscribers:  make(map[chan Event]struct{}),
+	}
+	for i := range requests {
+		j.pending = append(j.pending, i)
+	}
+	if m.store != nil {
+		if err := m.store.put(j.stored()); err != nil {
+			slog.Error("保存批量签名任务失败", "err", err, "job", j.id)
+			return nil, fmt.Errorf("保存任务失败: %w", err)
+		}
+	}
+
+	m.mu.Lock()
+	m.prune()
+	m.jobs[j.id] = j
+	m.mu.Unlock()
+
+	slog.Info("提交批量签名任务", "job", j.id, "platform", p.Name(), "total", len(requests), "api_key", key.Name, "tenant", key.Tenant.Name)
+	go m.run(context.WithoutCancel(ctx), j)
+	return j, nil
+}
+
+// Get 返回租户 tenant 的任务，不存在或属于其他租户时返回 nil。
+func (m *Manager) Get(tenant *auth.Tenant, id string) *Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j := m.jobs[id]
+	if j == nil || j.key.Tenant != tenant {
+		return nil
+	}
+	return j
+}
+
+// prune 在持有锁的前提下删除完成时间超过保留时长的任务。
+func (m *Manager) prune() {
+	for id, j := range m.jobs {
+		j.mu.Lock()
+		expired := !j.finished.IsZero() && time.Since(j.finished) > m.opts.Retention
+		j.mu.Unlock()
+		if expired {
+			delete(m.jobs, id)
+			m.deleteStored(id)
+		}
+	}
+}
+
+// stored 返回任务的落盘信息。
+func (j *Job) stored() storedJob {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return storedJob{
+		ID:       j.id,
+		Platform: j.platformName,
+		APIKey:   j.key.Name,
+		Tenant:   j.key.Tenant.Name,
+		Requests: j.requests,
+		Created:  j.created,
+		Finished: j.finished,
+		Resumed:  j.resumed,
+	}
+}
+
+// complete 记录一条请求的结果，配置任务库时同时落盘。
+func (m *Manager) complete(j *Job, item Item) {
+	j.complete(item)
+	if m.store == nil {
+		return
+	}
+	if err := m.store.putItem(j.id, item); err != nil {
+		slog.Error("保存批量任务结果失败", "err", err, "job", j.id, "index", item.Index)
+	}
+}
+
+// finish 将任务标记为完成，配置任务库时同时落盘。
+func (m *Manager) finish(j *Job) {
+	j.finish()
+	if m.store == nil {
+		return
+	}
+	if err := m.store.put(j.stored()); err != nil {
+		slog.Error("保存批量签名任务失败", "err", err, "job", j.id)
+	}
+}
+
+// deleteStored 从任务库中删除任务，未配置任务库时不做任何事。
+func (m *Manager) deleteStored(id string) {
+	if m.store == nil {
+		return
+	}
+	if err := m.store.delete(id); err != nil {
+		slog.Warn("从任务库删除任务失败", "err", err, "job", id)
+	}
+}
+
+// Close 关闭任务库，未完成的任务在下次启动时恢复。
+func (m *Manager) Close() error {
+	if m.store == nil {
+		return nil
+	}
+	return m.store.close()
+}
+
+// run 以至多 Concurrency 个并发签名任务中待执行的请求。
+func (m *Manager) run(ctx context.Context, j *Job) {
+	sem := make(chan struct{}, max(m.opts.Concurrency, 1))
+	var wg sync.WaitGroup
+	for _, i := range j.pending {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			m.complete(j, m.sign(ctx, j, i))
+		}(i)
+	}
+	wg.Wait()
+	m.finish(j)
+	s := j.Summary()
+	slog.Info("批量签名任务完成", "job", j.id, "platform", s.Platform, "total", s.Total, "failed", s.Failed)
+	webhook.Emit(webhook.JobCompleted, "job", j.id, "platform", s.Platform, "tenant", j.key.Tenant.Name, "api_key", j.key.Name, "total", s.Total, "failed", s.Failed, "resumed", s.Resumed)
+}
+
+// sign 签名任务中的第 i 条请求，配额耗尽时该条请求失败。
+func (m *Manager) sign(ctx context.Context, j *Job, i int) Item {
+	item := Item{Index: i}
+	if _, _, ok := m.tracker.Take(j.key); !ok {
+		item.Error = "配额已耗尽"
+		return item
+	}
+	req := j.requests[i]
+	cookies, err := platform.DecryptCookies("cookies.", req.Cookies)
+	if err != nil {
+		item.Error = err.Error()
+		return item
+	}
+	req.Cookies = cookies
+	start := time.Now()
+	res, err := j.platform.Sign(ctx, &req)
+	platform.ObserveSign(platform.SignRecord{
+		Time:     start,
+		Platform: j.platform.Name(),
+		Tenant:   j.key.Tenant.Name,
+		APIKey:   j.key.Name,
+		URI:      req.URI,
+		Account:  platform.AccountOf(res),
+		Result:   res,
+		Duration: time.Since(start),
+	}, err)
+	if err != nil {
+		slog.Error("批量任务签名失败", "err", err, "job", j.id, "index", i, "uri", req.URI)
+		item.Error = "签名失败: " + err.Error()
+		return item
+	}
+	out := *res
+	out.ServerTime = time.Now().UnixMilli()
+	item.Result = &out
+	return item
+}
@@ -0,0 +1,206 @@
+package jobs
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.etcd.io/bbolt"
+	"go_sign/internal/crypt"
+	"go_sign/internal/platform"
+)
+
+// jobsBucket 为保存任务的顶层 bucket，每个任务一个子 bucket：meta 为任务信息，items 为已完成请求的结果。
+var (
+	jobsBucket  = []byte("jobs")
+	metaKey     = []byte("meta")
+	itemsBucket = []byte("items")
+)
+
+// storedJob 为落盘的任务信息。
+type storedJob struct {
+	ID       string                 `json:"id"`
+	Platform string                 `json:"platform"`
+	APIKey   string                 `json:"api_key"`
+	Tenant   string                 `json:"tenant"`
+	Requests []platform.SignRequest `json:"requests"`
+	Created  time.Time              `json:"created"`
+	Finished time.Time              `json:"finished,omitempty"`
+	Resumed  int                    `json:"resumed,omitempty"`
+	// Credentials 为各请求 cookie 的密文，与 Requests 按下标对应，没有 cookie 的请求为空；Requests 中不保存 cookie。
+	// 未配置加密密钥时 cookie 不落盘，对应的取值为 strippedCookies。
+	Credentials []string `json:"credentials,omitempty"`
+	// Items 为已完成请求的结果，仅 load 时填充。
+	Items []Item `json:"-"`
+	// Stripped 为 cookie 未落盘的请求下标，仅 load 时填充，重启后这些请求无法以原 cookie 重新执行。
+	Stripped map[int]bool `json:"-"`
+}
+
+// strippedCookies 为未配置加密密钥时 storedJob.Credentials 中 cookie 未落盘的标记。
+const strippedCookies = "-"
+
+// store 以 BoltDB 文件持久化任务，使重启或崩溃后可恢复未完成的任务。
+type store struct {
+	db *bbolt.DB
+	// cipher 加密落盘的请求 cookie，为 nil 时 cookie 不落盘
+	cipher crypt.Cipher
+}
+
+// openStore 打开（或创建）path 处的任务库，cipher 见 store.cipher。
+func openStore(path string, cipher crypt.Cipher) (*store, error) {
+	db, err := bbolt.Open(path, 0o600, &bbolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("打开任务库失败: %w", err)
+	}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(jobsBucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("初始化任务库失败: %w", err)
+	}
+	return &store{db: db, cipher: cipher}, nil
+}
+
+// sealCookies 将 j 中各请求的 cookie 移入 Credentials：配置了加密密钥时加密保存，否则丢弃。
+func (s *store) sealCookies(j *storedJob) error {
+	requests := make([]platform.SignRequest, len(j.Requests))
+	var creds []string
+	for i, req := range j.Requests {
+		if len(req.Cookies) > 0 {
+			if creds == nil {
+				creds = make([]string, len(j.Requests))
+			}
+			creds[i] = strippedCookies
+			if s.cipher != nil {
+				plain, err := json.Marshal(req.Cookies)
+				if err != nil {
+					return err
+				}
+				if creds[i], err = s.cipher.Encrypt(plain); err != nil {
+					return fmt.Errorf("加密请求 cookie 失败: %w", err)
+				}
+			}
+			req.Cookies = nil
+		}
+		requests[i] = req
+	}
+	j.Requests, j.Credentials = requests, creds
+	return nil
+}
+
+// openCookies 将 sealCookies 保存的 cookie 还原到 j 的各请求中，未落盘的请求记入 Stripped。
+func (s *store) openCookies(j *storedJob) error {
+	for i, c := range j.Credentials {
+		switch {
+		case c == "" || i >= len(j.Requests):
+		case c == strippedCookies:
+			if j.Stripped == nil {
+				j.Stripped = make(map[int]bool)
+			}
+			j.Stripped[i] = true
+		case s.cipher == nil:
+			return crypt.ErrNoKey
+		default:
+			plain, err := s.cipher.Decrypt(c)
+			if err != nil {
+				return err
+			}
+			if err := json.Unmarshal(plain, &j.Requests[i].Cookies); err != nil {
+				return fmt.Errorf("请求 %d 的 cookie 格式错误: %w", i, err)
+			}
+		}
+	}
+	j.Credentials = nil
+	return nil
+}
+
+// put 写入任务信息，任务不存在时创建。请求的 cookie 按 sealCookies 加密或丢弃。
+func (s *store) put(j storedJob) error {
+	if err := s.sealCookies(&j); err != nil {
+		return err
+	}
+	raw, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		b, err := tx.Bucket(jobsBucket).CreateBucketIfNotExists([]byte(j.ID))
+		if err != nil {
+			return err
+		}
+		if _, err := b.CreateBucketIfNotExists(itemsBucket); err != nil {
+			return err
+		}
+		return b.Put(metaKey, raw)
+	})
+}
+
+// putItem 写入任务 id 中一条请求的结果。
+func (s *store) putItem(id string, item Item) error {
+	raw, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket(jobsBucket).Bucket([]byte(id))
+		if b == nil {
+			return fmt.Errorf("任务 %s 不存在", id)
+		}
+		return b.Bucket(itemsBucket).Put(itemKey(item.Index), raw)
+	})
+}
+
+// delete 删除任务及其结果。
+func (s *store) delete(id string) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		err := tx.Bucket(jobsBucket).DeleteBucket([]byte(id))
+		if err == bbolt.ErrBucketNotFound {
+			return nil
+		}
+		return err
+	})
+}
+
+// load 读取全部任务及其已完成请求的结果。
+func (s *store) load() ([]storedJob, error) {
+	var out []storedJob
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(jobsBucket).ForEachBucket(func(id []byte) error {
+			b := tx.Bucket(jobsBucket).Bucket(id)
+			var j storedJob
+			if err := json.Unmarshal(b.Get(metaKey), &j); err != nil {
+				return fmt.Errorf("任务 %s: %w", id, err)
+			}
+			if err := s.openCookies(&j); err != nil {
+				return fmt.Errorf("任务 %s: %w", id, err)
+			}
+			err := b.Bucket(itemsBucket).ForEach(func(_, v []byte) error {
+				var item Item
+				if err := json.Unmarshal(v, &item); err != nil {
+					return fmt.Errorf("任务 %s 结果: %w", id, err)
+				}
+				j.Items = append(j.Items, item)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			out = append(out, j)
+			return nil
+		})
+	})
+	return out, err
+}
+
+// close 关闭任务库。
+func (s *store) close() error {
+	return s.db.Close()
+}
+
+// itemKey 返回请求下标的大端编码，使结果按下标排序。
+func itemKey(index int) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(index))
+}
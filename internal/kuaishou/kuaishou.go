@@ -0,0 +1,187 @@
+// Package kuaishou 提供快手网页端 __NS_sig3 签名平台。
+//
+// 签名在预热好的快手页面中调用页面加载的 Jose 签名模块完成，
+// 调用方式可通过配置覆盖，以便站点更新签名入口后无需重新编译。
+package kuaishou
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+
+	"go_sign/internal/browser"
+	"go_sign/internal/pagepool"
+	"go_sign/internal/platform"
+)
+
+// Name 为快手平台的注册名，签名接口为 POST /kuaishou/sign。
+const Name = "kuaishou"
+
+// 默认的页面签名调用，参数为 [path, query, body]，返回 Promise<string>。
+const (
+	defaultHomeURL   = "https://www.kuaishou.com"
+	defaultParamName = "__NS_sig3"
+	defaultReadyJS   = `() => !!(window.Jose && typeof window.Jose.call === 'function')`
+	defaultSignJS    = `([path, query, body]) => new Promise((resolve, reject) => {
+		window.Jose.call("$encode", [
+			{url: path, query: query, form: {}, requestBody: body || {}},
+			{suc: resolve, err: reject},
+		]);
+	})`
+)
+
+func init() {
+	platform.Register(Name, func(decode platform.Decoder) (platform.Platform, error) {
+		p := &Platform{options: options{
+			HomeURL:   defaultHomeURL,
+			ParamName: defaultParamName,
+			ReadyJS:   defaultReadyJS,
+			SignJS:    defaultSignJS,
+		}}
+		if err := decode(&p.options); err != nil {
+			return nil, err
+		}
+		return p, nil
+	})
+}
+
+// options 为快手平台在配置文件中的可选项。
+type options struct {
+	// HomeURL 为预热时访问的页面。
+	HomeURL string `yaml:"home_url"`
+	// PoolSize 覆盖平台默认的页面池大小。
+	PoolSize int `yaml:"pool_size"`
+	// ParamName 为签名结果的查询参数名。
+	ParamName string `yaml:"param_name"`
+	// ReadyJS 为页面签名模块是否就绪的判断函数。
+	ReadyJS string `yaml:"ready_js"`
+	// SignJS 为生成签名的页面函数，参数为 [path, query, body]。
+	SignJS string `yaml:"sign_js"`
+}
+
+// Platform 为快手签名平台。
+type Platform struct {
+	options options
+	browser *browser.Browser
+	env     *platform.Env
+	pools   pagepool.Set
+}
+
+// Name 返回平台注册名。
+func (p *Platform) Name() string { return Name }
+
+// Init 启动（或复用）共享浏览器并预热快手页面。
+func (p *Platform) Init(ctx context.Context, env *platform.Env) error {
+	b, err := env.Browser.Get()
+	if err != nil {
+		return err
+	}
+	p.browser, p.env = b, env
+	tenants := pagepool.TenantsFromEnv(env, p.options.PoolSize)
+	p.pools, err = pagepool.Warmup(ctx, Name, tenants, env.WarmupConcurrency, p.newSlot)
+	return err
+}
+
+func (p *Platform) newSlot(ctx context.Context, tenant string, id int) (*pagepool.Slot, error) {
+	return pagepool.Open(ctx, p.browser, tenant, id, pagepool.OpenOptions{
+		Platform:          Name,
+		StealthPath:       p.env.StealthPath,
+		URL:               p.options.HomeURL,
+		WaitUntil:         p.env.WaitUntil,
+		NavigationTimeout: p.env.NavigationTimeout,
+		ReadyJS:           p.options.ReadyJS,
+		ReadyTimeout:      p.env.SignFuncTimeout,
+	})
+}
+
+// Sign 生成 __NS_sig3，结果以查询参数返回。
+// 签名路径取 uri 的路径部分，查询参数为 uri 中的查询串与 params 合并的结果，请求体为 data。
+// 指定 timestamp 时签名期间固定页面时钟（仅作用于签名模块的同步部分）。
+func (p *Platform) Sign(ctx context.Context, req *platform.SignRequest) (*platform.SignResponse, error) {
+	pool, err := p.pools.For(ctx)
+	if err != nil {
+		return nil, err
+	}
+	path, query, err := splitURI(req)
+	if err != nil {
+		return nil, err
+	}
+
+	slot, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("等待空闲页面失败: %w", err)
+	}
+	v, err := pagepool.Evaluate(ctx, slot.Page, pagepool.WithClock(p.options.SignJS), []any{req.Timestamp, []any{path, query, req.Data}})
+	sig, _ := v.(string)
+	if err == nil && sig == "" {
+		err = fmt.Errorf("%s 签名结果为空", p.options.ParamName)
+	}
+	pool.Done(slot, err)
+	if err != nil {
+		slog.Error("执行快手签名 JS 失败", "err", err, "uri", req.URI)
+		return nil, fmt.Errorf("执行签名 JS 失败: %w", err)
+	}
+	return &platform.SignResponse{
+		Params:    map[string]string{p.options.ParamName: sig},
+		Timestamp: platform.SignTime(req.Timestamp).UnixMilli(),
+	}, nil
+}
+
+// Mock 返回格式与真实签名一致的假 __NS_sig3（56 位十六进制）。
+func (p *Platform) Mock(*platform.SignRequest) *platform.SignResponse {
+	return &platform.SignResponse{Params: map[string]string{p.options.ParamName: platform.MockHex(28)}}
+}
+
+// splitURI 将 uri 拆分为路径与查询参数，并合并 params。
+func splitURI(req *platform.SignRequest) (string, map[string]string, error) {
+	path, rawQuery, _ := strings.Cut(req.URI, "?")
+	if u, err := url.Parse(path); err == nil && u.Host != "" {
+		path = u.Path
+	}
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "", nil, fmt.Errorf("解析查询串失败: %w", err)
+	}
+	query := make(map[string]string, len(values)+len(req.Params))
+	for k := range values {
+		query[k] = values.Get(k)
+	}
+	for k, v := range req.Params {
+		query[k] = v
+	}
+	return path, query, nil
+}
+
+// HealthCheck 检查各租户页面上的签名模块是否可用。
+func (p *Platform) HealthCheck(ctx context.Context) error {
+	return p.pools.Check(ctx, p.options.ReadyJS, nil)
+}
+
+// ProbeStealth 在注入 path 的临时页面上检查签名模块可用，实现 platform.StealthProber。
+func (p *Platform) ProbeStealth(ctx context.Context, path string) error {
+	return p.pools.Probe(ctx, path, func(slot *pagepool.Slot) error {
+		return pagepool.CheckSlot(slot, p.options.ReadyJS, nil)
+	})
+}
+
+// StageUpgrade 在新浏览器 b 上预热与当前规模相同的页面，实现 platform.Upgrader。
+func (p *Platform) StageUpgrade(ctx context.Context, b *browser.Browser, stealthPath string) (platform.Staged, error) {
+	return p.pools.Stage(ctx, pagepool.Generation{Browser: b, StealthPath: stealthPath}, p.env.WarmupConcurrency)
+}
+
+// RenameStealth 将页面池中注入 from 的 stealth.js 路径改为 to，实现 platform.StealthRenamer。
+func (p *Platform) RenameStealth(from, to string) {
+	p.pools.RenameStealth(from, to)
+}
+
+// Recycle 逐个重建各租户的浏览器上下文，实现 platform.Recycler。
+func (p *Platform) Recycle(ctx context.Context) error {
+	return p.pools.Recycle(ctx)
+}
+
+// Close 关闭全部页面。
+func (p *Platform) Close() error {
+	return p.pools.Close()
+}
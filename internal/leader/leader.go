@@ -0,0 +1,136 @@
+// Package leader 在共享同一存储后端的多个实例中选出一个 leader，只在 leader 上运行全局任务
+// （定时自检、账号清理、stealth.js 更新检查等），使这些任务在集群中只执行一次。
+//
+// leader 身份为存储后端中名为 "leader" 的租约（见 storage.Locker）：leader 每隔 TTL/3 续期，
+// 宕机或与后端失联后租约到期，由其他实例接管。
+package leader
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go_sign/internal/metrics"
+	"go_sign/internal/storage"
+)
+
+// leaseName 为 leader 租约的名称。
+const leaseName = "leader"
+
+// DefaultTTL 为 leader 租约的默认有效期。
+const DefaultTTL = 15 * time.Second
+
+// isLeader 标记本实例是否为 leader。
+var isLeader = metrics.Default.NewGaugeVec(
+	"go_sign_cluster_leader",
+	"本实例是否为集群 leader（1 为是），全局任务只在 leader 上运行",
+)
+
+// task 为只在 leader 上运行的任务。
+type task struct {
+	name string
+	fn   func(ctx context.Context)
+}
+
+// Elector 竞选并保持 leader 身份，成为 leader 时启动已注册的任务，失去身份时取消任务。
+type Elector struct {
+	locker storage.Locker
+	id     string
+	ttl    time.Duration
+
+	tasks  []task
+	leader atomic.Bool
+}
+
+// New 返回以 locker 竞选的 Elector，id 为本实例在集群中的标识（为空时由主机名、进程号与随机后缀生成），
+// ttl 为租约有效期（0 为 DefaultTTL）。
+func New(locker storage.Locker, id string, ttl time.Duration) *Elector {
+	if id == "" {
+		id = NodeID()
+	}
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	return &Elector{locker: locker, id: id, ttl: ttl}
+}
+
+// NodeID 返回由主机名、进程号与随机后缀组成的实例标识，重启后不同，避免新进程误续旧进程的租约。
+func NodeID() string {
+	host, _ := os.Hostname()
+	suffix := make([]byte, 4)
+	_, _ = rand.Read(suffix)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(suffix))
+}
+
+// ID 返回本实例的标识。
+func (e *Elector) ID() string { return e.id }
+
+// IsLeader 返回本实例当前是否为 leader。
+func (e *Elector) IsLeader() bool { return e.leader.Load() }
+
+// Go 注册只在 leader 上运行的任务 fn：成为 leader 时以独立的 ctx 启动，失去 leader 身份时取消该 ctx，
+// fn 须在 ctx 取消后尽快返回。须在 Run 之前调用。
+func (e *Elector) Go(name string, fn func(ctx context.Context)) {
+	e.tasks = append(e.tasks, task{name: name, fn: fn})
+}
+
+// Run 每隔 TTL/3 取得或续期 leader 租约，直至 ctx 取消；退出时取消任务并释放租约，使其他实例立即接管。
+// 续期失败（包括与后端失联）时立即放弃 leader 身份，宁可短暂没有 leader，也不与新 leader 同时运行任务。
+func (e *Elector) Run(ctx context.Context) {
+	isLeader.Set(0)
+	ticker := time.NewTicker(e.ttl / 3)
+	defer ticker.Stop()
+	var (
+		cancel context.CancelFunc
+		wg     sync.WaitGroup
+	)
+	stepDown := func() {
+		cancel()
+		wg.Wait()
+		cancel = nil
+		e.leader.Store(false)
+		isLeader.Set(0)
+	}
+	for {
+		_, err := e.locker.Acquire(ctx, leaseName, e.id, e.ttl)
+		switch {
+		case err == nil && cancel == nil:
+			var taskCtx context.Context
+			taskCtx, cancel = context.WithCancel(ctx)
+			e.leader.Store(true)
+			isLeader.Set(1)
+			slog.Info("本实例成为集群 leader，启动全局任务", "node", e.id, "tasks", len(e.tasks))
+			for _, t := range e.tasks {
+				wg.Add(1)
+				go func(t task) {
+					defer wg.Done()
+					t.fn(taskCtx)
+				}(t)
+			}
+		case err != nil && cancel != nil:
+			slog.Warn("续期 leader 租约失败，停止全局任务", "err", err, "node", e.id)
+			stepDown()
+		case err != nil && ctx.Err() == nil && !errors.Is(err, storage.ErrLocked):
+			slog.Warn("竞选 leader 失败", "err", err, "node", e.id)
+		}
+		select {
+		case <-ctx.Done():
+			if cancel != nil {
+				stepDown()
+				rctx, rcancel := context.WithTimeout(context.Background(), 5*time.Second)
+				_ = e.locker.Release(rctx, leaseName, e.id)
+				rcancel()
+				slog.Info("已释放 leader 租约", "node", e.id)
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
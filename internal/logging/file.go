@@ -0,0 +1,63 @@
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go_sign/internal/config"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// File 为按大小与时间轮转的日志文件，轮转出的旧文件可按配置压缩与清理。
+type File struct {
+	*lumberjack.Logger
+	stop chan struct{}
+}
+
+// OpenFile 按配置创建日志文件，目录不存在时自动创建。
+func OpenFile(c *config.LogFile) (*File, error) {
+	if err := os.MkdirAll(filepath.Dir(c.Path), 0o755); err != nil {
+		return nil, fmt.Errorf("创建日志目录失败: %w", err)
+	}
+	f := &File{
+		Logger: &lumberjack.Logger{
+			Filename:   c.Path,
+			MaxSize:    c.MaxSizeMB,
+			MaxBackups: c.MaxBackups,
+			// lumberjack 以天为单位清理旧文件，不足一天按一天计
+			MaxAge:    int((c.MaxAge + 24*time.Hour - 1) / (24 * time.Hour)),
+			Compress:  c.Compress,
+			LocalTime: true,
+		},
+		stop: make(chan struct{}),
+	}
+	if c.RotateInterval > 0 {
+		go f.rotateEvery(c.RotateInterval)
+	}
+	return f, nil
+}
+
+// rotateEvery 每隔 interval 轮转一次日志文件，直到 Close。
+func (f *File) rotateEvery(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if err := f.Rotate(); err != nil {
+				slog.Error("轮转日志文件失败", "err", err, "path", f.Filename)
+			}
+		case <-f.stop:
+			return
+		}
+	}
+}
+
+// Close 停止定时轮转并关闭日志文件。
+func (f *File) Close() error {
+	close(f.stop)
+	return f.Logger.Close()
+}
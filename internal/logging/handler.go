@@ -0,0 +1,81 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/audit"
+	"go_sign/internal/config"
+)
+
+// 日志格式。
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// Level 为全部日志处理器共用的级别，可在运行时通过 LevelHandler 修改。
+var Level = new(slog.LevelVar)
+
+// NewHandler 按配置创建写入 w 的日志处理器，c 为 nil 时为 JSON 格式、info 级别。
+// redact 为 true 时对敏感字段脱敏（见 Redact），并对日志文本中的敏感值脱敏（见 Secret、RedactText）。
+func NewHandler(w io.Writer, c *config.Log, redact bool) (slog.Handler, error) {
+	if c == nil {
+		c = &config.Log{}
+	}
+	if err := SetLevel(c.Level); err != nil {
+		return nil, err
+	}
+	opts := &slog.HandlerOptions{Level: Level, AddSource: c.AddSource}
+	plain.Store(!redact)
+	if redact {
+		opts.ReplaceAttr = Redact
+	}
+	switch c.Format {
+	case "", FormatJSON:
+		return slog.NewJSONHandler(w, opts), nil
+	case FormatText:
+		return slog.NewTextHandler(w, opts), nil
+	}
+	return nil, fmt.Errorf("不支持的日志格式: %s", c.Format)
+}
+
+// SetLevel 设置日志级别（debug、info、warn、error，不区分大小写），空串表示 info。
+func SetLevel(level string) error {
+	var l slog.Level
+	if level != "" {
+		if err := l.UnmarshalText([]byte(level)); err != nil {
+			return fmt.Errorf("不支持的日志级别: %s", level)
+		}
+	}
+	Level.Set(l)
+	return nil
+}
+
+// LevelHandler 返回查询与修改日志级别的接口：GET 返回 {"level"}，PUT 以 {"level": "debug"} 修改，
+// 修改立即生效，进程重启后恢复为配置文件中的级别。
+func LevelHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodPut {
+			var req struct {
+				Level string `json:"level" binding:"required"`
+			}
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "参数解析失败: " + err.Error()})
+				return
+			}
+			old := Level.Level()
+			if err := SetLevel(req.Level); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "参数校验失败: " + err.Error()})
+				return
+			}
+			audit.SetDiff(c, gin.H{"level": strings.ToLower(old.String())}, gin.H{"level": strings.ToLower(Level.Level().String())})
+			slog.Warn("日志级别已修改", "from", old.String(), "to", Level.Level().String(), "client_ip", c.ClientIP())
+		}
+		c.JSON(http.StatusOK, gin.H{"level": strings.ToLower(Level.Level().String())})
+	}
+}
@@ -0,0 +1,83 @@
+// Package logging 提供服务日志的公共处理，如敏感字段脱敏。
+package logging
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// sensitiveKeys 为需脱敏的日志字段（不区分大小写）：账号凭据、签名结果与可能携带它们的请求数据。
+var sensitiveKeys = map[string]bool{
+	"a1":          true,
+	"web_session": true,
+	"x-s":         true,
+	"x-s-common":  true,
+	"cookie":      true,
+	"cookies":     true,
+	"data":        true,
+	"body":        true,
+}
+
+// Redact 为 slog.HandlerOptions.ReplaceAttr，将敏感字段替换为长度与 SHA-256 前缀，
+// 同一取值在不同日志行中脱敏结果相同，便于关联排查而不泄露原值。
+// 敏感字段为分组（如 slog.Group("cookies", ...)）时，组内的全部字段均脱敏。
+func Redact(groups []string, a slog.Attr) slog.Attr {
+	if !sensitiveKeys[strings.ToLower(a.Key)] && !slices.ContainsFunc(groups, func(g string) bool { return sensitiveKeys[strings.ToLower(g)] }) {
+		return a
+	}
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		return a
+	}
+	return slog.String(a.Key, Mask(v.String()))
+}
+
+// Mask 返回 s 的脱敏形式，空串原样返回。
+func Mask(s string) string {
+	if s == "" {
+		return s
+	}
+	sum := sha256.Sum256([]byte(s))
+	return fmt.Sprintf("[redacted len=%d sha256=%s]", len(s), hex.EncodeToString(sum[:4]))
+}
+
+// plain 为 true 时日志处理器未启用脱敏（--log-secrets），由 NewHandler 设置，Secret 与 RedactText 据此决定是否脱敏。
+var plain atomic.Bool
+
+// Secret 返回启用脱敏时 s 的脱敏形式（见 Mask），未启用时原样返回。用于无法按字段名脱敏、
+// 须拼接进日志文本的敏感值，如双跑校验中不一致的签名结果。
+func Secret(s string) string {
+	if plain.Load() {
+		return s
+	}
+	return Mask(s)
+}
+
+// sensitiveText 匹配自由文本中敏感字段的赋值，如 a1=xxx、"x-s": "xxx"，第 1 组为字段名及分隔符，第 2 组为取值。
+var sensitiveText = func() *regexp.Regexp {
+	keys := make([]string, 0, len(sensitiveKeys))
+	for k := range sensitiveKeys {
+		keys = append(keys, regexp.QuoteMeta(k))
+	}
+	// 较长的字段名在前，避免 x-s-common 只匹配到 x-s
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+	return regexp.MustCompile(`(?i)((?:^|[^\w-])"?(?:` + strings.Join(keys, "|") + `)"?\s*[:=]\s*)("(?:[^"\\]|\\.)*"|[^\s;,&}]+)`)
+}()
+
+// RedactText 在启用脱敏时将自由文本（如插件输出）中敏感字段的取值替换为脱敏形式，未启用时原样返回。
+func RedactText(s string) string {
+	if plain.Load() {
+		return s
+	}
+	return sensitiveText.ReplaceAllStringFunc(s, func(m string) string {
+		g := sensitiveText.FindStringSubmatch(m)
+		return g[1] + Mask(strings.Trim(g[2], `"`))
+	})
+}
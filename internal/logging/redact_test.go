@@ -0,0 +1,83 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"go_sign/internal/config"
+)
+
+func TestRedact(t *testing.T) {
+	const secret = "s3cret-value"
+	var buf bytes.Buffer
+	h, err := NewHandler(&buf, &config.Log{}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := slog.New(h)
+	t.Cleanup(func() { plain.Store(false) })
+
+	tests := []struct {
+		name string
+		log  func()
+	}{
+		{name: "a1", log: func() { log.Info("m", "a1", secret) }},
+		{name: "web_session", log: func() { log.Info("m", "web_session", secret) }},
+		{name: "x-s", log: func() { log.Info("m", "x-s", secret) }},
+		{name: "x-s-common", log: func() { log.Info("m", "x-s-common", secret) }},
+		{name: "cookie", log: func() { log.Info("m", "cookie", "a1="+secret) }},
+		{name: "cookies", log: func() { log.Info("m", "cookies", map[string]string{"a1": secret}) }},
+		{name: "data", log: func() { log.Info("m", "data", map[string]any{"note": secret}) }},
+		{name: "body", log: func() { log.Info("m", "body", []byte(secret)) }},
+		{name: "不区分大小写", log: func() { log.Info("m", "X-S", secret) }},
+		{name: "普通分组中的敏感字段", log: func() { log.Info("m", slog.Group("req", "a1", secret)) }},
+		{name: "多层分组中的敏感字段", log: func() { log.Info("m", slog.Group("req", slog.Group("headers", "x-s", secret))) }},
+		{name: "敏感分组中的任意字段", log: func() { log.Info("m", slog.Group("cookies", "gid", secret)) }},
+		{name: "With 添加的分组", log: func() { log.WithGroup("cookie").Info("m", "session", secret) }},
+		{name: "With 添加的字段", log: func() { log.With("a1", secret).Info("m") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			tt.log()
+			if out := buf.String(); strings.Contains(out, secret) || !strings.Contains(out, "[redacted len=") {
+				t.Errorf("日志未脱敏: %s", out)
+			}
+		})
+	}
+
+	buf.Reset()
+	log.Info("m", "uri", "/api/sns/web/v1/feed", "tenant", "team-a")
+	if out := buf.String(); !strings.Contains(out, "/api/sns/web/v1/feed") || strings.Contains(out, "redacted") {
+		t.Errorf("非敏感字段不应脱敏: %s", out)
+	}
+	if Mask(secret) != Mask(secret) || Mask("") != "" {
+		t.Error("Mask 应对同一取值给出相同结果，空串原样返回")
+	}
+}
+
+func TestRedactText(t *testing.T) {
+	t.Cleanup(func() { plain.Store(false) })
+	plain.Store(false)
+	tests := []struct {
+		in, leak, keep string
+	}{
+		{in: "sign a1=abc123 uri=/x", leak: "abc123", keep: "uri=/x"},
+		{in: `{"x-s": "XYW_secret", "x-t": "1700000000"}`, leak: "XYW_secret", keep: `"x-t": "1700000000"`},
+		{in: `{"x-s-common":"2UQAPs"}`, leak: "2UQAPs", keep: `"x-s-common":`},
+		{in: "Cookie: a1=aa; web_session=bb", leak: "bb", keep: "Cookie:"},
+		{in: "data=payload&x=1", leak: "payload", keep: "&x=1"},
+	}
+	for _, tt := range tests {
+		out := RedactText(tt.in)
+		if strings.Contains(out, tt.leak) || !strings.Contains(out, tt.keep) {
+			t.Errorf("RedactText(%q) = %q", tt.in, out)
+		}
+	}
+	plain.Store(true)
+	if out := RedactText("a1=abc123"); out != "a1=abc123" || Secret("x") != "x" {
+		t.Errorf("--log-secrets 时不应脱敏: %q", out)
+	}
+}
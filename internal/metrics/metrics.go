@@ -0,0 +1,260 @@
+// Package metrics 提供轻量的 Prometheus 文本格式指标注册与导出。
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets 为耗时类直方图的默认分桶（秒）。
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// collector 为可导出的指标族。
+type collector interface {
+	write(b *strings.Builder)
+}
+
+// Registry 保存全部已注册的指标族。
+type Registry struct {
+	mu         sync.Mutex
+	names      []string
+	collectors map[string]collector
+}
+
+// NewRegistry 创建一个空的指标注册表。
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]collector)}
+}
+
+// Default 为进程级默认注册表。
+var Default = NewRegistry()
+
+func (r *Registry) register(name string, c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.collectors[name]; ok {
+		panic("metrics: 重复注册指标 " + name)
+	}
+	r.names = append(r.names, name)
+	r.collectors[name] = c
+}
+
+// Handler 返回以 Prometheus 文本格式导出指标的 HTTP 处理器。
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_, _ = w.Write([]byte(r.Text()))
+	})
+}
+
+// Text 返回全部指标的文本格式快照。
+func (r *Registry) Text() string {
+	r.mu.Lock()
+	names := append([]string(nil), r.names...)
+	cs := make([]collector, len(names))
+	for i, n := range names {
+		cs[i] = r.collectors[n]
+	}
+	r.mu.Unlock()
+
+	var b strings.Builder
+	for _, c := range cs {
+		c.write(&b)
+	}
+	return b.String()
+}
+
+// family 为带标签的指标族的公共部分。
+type family struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+}
+
+func (f *family) header(b *strings.Builder) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.typ)
+}
+
+// key 将标签值拼接为 map 键，并校验数量。
+func (f *family) key(values []string) string {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s 需要 %d 个标签值，实际 %d 个", f.name, len(f.labels), len(values)))
+	}
+	return strings.Join(values, "\xff")
+}
+
+// labelString 生成 {a="x",b="y"} 形式的标签串，extra 为附加的键值对。
+func (f *family) labelString(k string, extra ...string) string {
+	var pairs []string
+	if len(f.labels) > 0 {
+		for i, v := range strings.Split(k, "\xff") {
+			pairs = append(pairs, fmt.Sprintf("%s=%q", f.labels[i], v))
+		}
+	}
+	for i := 0; i+1 < len(extra); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", extra[i], extra[i+1]))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// CounterVec 为单调递增的计数器族。
+type CounterVec struct {
+	family
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// NewCounterVec 在注册表中创建计数器族。
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	c := &CounterVec{family: family{name: name, help: help, typ: "counter", labels: labels}, values: make(map[string]float64)}
+	r.register(name, c)
+	return c
+}
+
+// Add 为指定标签的计数器增加 v。
+func (c *CounterVec) Add(v float64, labels ...string) {
+	k := c.key(labels)
+	c.mu.Lock()
+	c.values[k] += v
+	c.mu.Unlock()
+}
+
+// Inc 为指定标签的计数器加一。
+func (c *CounterVec) Inc(labels ...string) {
+	c.Add(1, labels...)
+}
+
+func (c *CounterVec) write(b *strings.Builder) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.header(b)
+	for _, k := range sortedKeys(c.values) {
+		fmt.Fprintf(b, "%s%s %s\n", c.name, c.labelString(k), formatFloat(c.values[k]))
+	}
+}
+
+// GaugeVec 为可增可减的仪表族。
+type GaugeVec struct {
+	family
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// NewGaugeVec 在注册表中创建仪表族。
+func (r *Registry) NewGaugeVec(name, help string, labels ...string) *GaugeVec {
+	g := &GaugeVec{family: family{name: name, help: help, typ: "gauge", labels: labels}, values: make(map[string]float64)}
+	r.register(name, g)
+	return g
+}
+
+// Set 设置指定标签的仪表值。
+func (g *GaugeVec) Set(v float64, labels ...string) {
+	k := g.key(labels)
+	g.mu.Lock()
+	g.values[k] = v
+	g.mu.Unlock()
+}
+
+// Add 为指定标签的仪表增加 v（可为负数）。
+func (g *GaugeVec) Add(v float64, labels ...string) {
+	k := g.key(labels)
+	g.mu.Lock()
+	g.values[k] += v
+	g.mu.Unlock()
+}
+
+func (g *GaugeVec) write(b *strings.Builder) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.header(b)
+	for _, k := range sortedKeys(g.values) {
+		fmt.Fprintf(b, "%s%s %s\n", g.name, g.labelString(k), formatFloat(g.values[k]))
+	}
+}
+
+// HistogramVec 为分桶统计的直方图族。
+type HistogramVec struct {
+	family
+	buckets []float64
+	mu      sync.Mutex
+	values  map[string]*histogram
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewHistogramVec 在注册表中创建直方图族，buckets 为空时使用 DefaultBuckets。
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	h := &HistogramVec{
+		family:  family{name: name, help: help, typ: "histogram", labels: labels},
+		buckets: buckets,
+		values:  make(map[string]*histogram),
+	}
+	r.register(name, h)
+	return h
+}
+
+// Observe 记录一次观测值。
+func (h *HistogramVec) Observe(v float64, labels ...string) {
+	k := h.key(labels)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	hv, ok := h.values[k]
+	if !ok {
+		hv = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.values[k] = hv
+	}
+	for i, ub := range h.buckets {
+		if v <= ub {
+			hv.counts[i]++
+		}
+	}
+	hv.sum += v
+	hv.count++
+}
+
+func (h *HistogramVec) write(b *strings.Builder) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.header(b)
+	for _, k := range sortedKeys(h.values) {
+		hv := h.values[k]
+		for i, ub := range h.buckets {
+			fmt.Fprintf(b, "%s_bucket%s %d\n", h.name, h.labelString(k, "le", formatFloat(ub)), hv.counts[i])
+		}
+		fmt.Fprintf(b, "%s_bucket%s %d\n", h.name, h.labelString(k, "le", "+Inf"), hv.count)
+		fmt.Fprintf(b, "%s_sum%s %s\n", h.name, h.labelString(k), formatFloat(hv.sum))
+		fmt.Fprintf(b, "%s_count%s %d\n", h.name, h.labelString(k), hv.count)
+	}
+}
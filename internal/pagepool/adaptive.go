@@ -0,0 +1,164 @@
+package pagepool
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go_sign/internal/metrics"
+	"go_sign/internal/timing"
+)
+
+// 自适应并发相关指标。
+var (
+	adaptiveLimit = metrics.Default.NewGaugeVec(
+		"go_sign_adaptive_concurrency_limit",
+		"自适应并发控制当前允许同时执行的页面签名数",
+	)
+	adaptiveInflight = metrics.Default.NewGaugeVec(
+		"go_sign_adaptive_concurrency_inflight",
+		"当前正在页面中执行的签名数",
+	)
+)
+
+// AIMD 参数：每个未过载的完成将上限增加 1/limit（约每轮 +1），
+// 耗时超过基线 adaptiveTolerance 倍时将上限乘以 adaptiveBackoff，两次降低至少间隔 adaptiveCooldown。
+// 基线为最近 adaptiveWindow 次签名中的最小耗时，用于跟随浏览器整体变慢或变快。
+const (
+	adaptiveTolerance = 2.0
+	adaptiveBackoff   = 0.9
+	adaptiveCooldown  = time.Second
+	adaptiveWindow    = 200
+)
+
+// Adaptive 以 AIMD 方式探测浏览器能承受的并行页面执行数：耗时稳定时逐步放宽上限，
+// 耗时明显变长（浏览器开始排队、延迟崩塌）时成倍收紧。全部平台与租户共享同一浏览器，因此为全局上限。
+type Adaptive struct {
+	mu       sync.Mutex
+	limit    float64
+	min, max int
+	inflight int
+	waiters  []chan struct{}
+
+	baseline     time.Duration // 上一窗口的最小耗时
+	windowMin    time.Duration // 当前窗口的最小耗时
+	samples      int           // 当前窗口已采样次数
+	lastDecrease time.Time
+}
+
+// NewAdaptive 创建初始上限为 initial、在 [lo, hi] 内调整的自适应并发控制，hi <= 0 时不设上界。
+func NewAdaptive(initial, lo, hi int) *Adaptive {
+	lo = max(lo, 1)
+	if hi > 0 {
+		initial = min(initial, hi)
+	}
+	initial = max(initial, lo)
+	a := &Adaptive{limit: float64(initial), min: lo, max: hi}
+	adaptiveLimit.Set(a.limit)
+	return a
+}
+
+// adaptive 为 Evaluate 使用的全局并发控制，为 nil 时不限制。
+var adaptive atomic.Pointer[Adaptive]
+
+// SetAdaptive 设置 Evaluate 使用的自适应并发控制，a 为 nil 时关闭。
+func SetAdaptive(a *Adaptive) {
+	adaptive.Store(a)
+}
+
+// Limit 返回当前并发上限。
+func (a *Adaptive) Limit() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return int(a.limit)
+}
+
+// acquire 等待执行名额，ctx 结束时返回错误。
+func (a *Adaptive) acquire(ctx context.Context) error {
+	a.mu.Lock()
+	if a.inflight < int(a.limit) {
+		a.inflight++
+		a.mu.Unlock()
+		adaptiveInflight.Add(1)
+		return nil
+	}
+	ch := make(chan struct{}, 1)
+	a.waiters = append(a.waiters, ch)
+	a.mu.Unlock()
+
+	start := time.Now()
+	defer func() { timing.Observe(ctx, timing.QueueWait, time.Since(start)) }()
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		a.mu.Lock()
+		for i, w := range a.waiters {
+			if w == ch {
+				a.waiters = append(a.waiters[:i], a.waiters[i+1:]...)
+				a.mu.Unlock()
+				return ctx.Err()
+			}
+		}
+		a.mu.Unlock()
+		// 取消的同时已获得名额，需要归还
+		a.release(0, false)
+		return ctx.Err()
+	}
+}
+
+// release 归还执行名额，并按本次耗时 d 调整上限；observe 为 false 时只归还不调整。
+func (a *Adaptive) release(d time.Duration, observe bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.inflight--
+	adaptiveInflight.Add(-1)
+	if observe {
+		a.observeLocked(d)
+	}
+	for len(a.waiters) > 0 && a.inflight < int(a.limit) {
+		ch := a.waiters[0]
+		a.waiters = a.waiters[1:]
+		a.inflight++
+		adaptiveInflight.Add(1)
+		ch <- struct{}{}
+	}
+}
+
+// observeLocked 根据一次页面执行的耗时调整上限。调用方须持有 a.mu。
+func (a *Adaptive) observeLocked(d time.Duration) {
+	if a.windowMin == 0 || d < a.windowMin {
+		a.windowMin = d
+	}
+	if a.samples++; a.samples >= adaptiveWindow {
+		a.baseline, a.windowMin, a.samples = a.windowMin, 0, 0
+	}
+	baseline := a.baseline
+	if baseline == 0 || (a.windowMin > 0 && a.windowMin < baseline) {
+		baseline = a.windowMin
+	}
+
+	prev := int(a.limit)
+	switch {
+	case float64(d) > adaptiveTolerance*float64(baseline):
+		if time.Since(a.lastDecrease) < adaptiveCooldown {
+			return
+		}
+		a.lastDecrease = time.Now()
+		a.limit = max(float64(a.min), a.limit*adaptiveBackoff)
+	case a.inflight+1 >= prev:
+		// 只有上限确实被用满时才放宽，避免空闲时上限无限增长
+		a.limit += 1 / a.limit
+		if a.max > 0 {
+			a.limit = min(a.limit, float64(a.max))
+		}
+	default:
+		return
+	}
+	adaptiveLimit.Set(a.limit)
+	if next := int(a.limit); next != prev {
+		slog.Debug("自适应并发上限调整", "from", prev, "to", next, "latency", d, "baseline", baseline)
+	}
+}
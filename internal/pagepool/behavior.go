@@ -0,0 +1,80 @@
+package pagepool
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/mxschmitt/playwright-go"
+)
+
+// Behavior 为页面导航后模拟的真人浏览行为：随机移动鼠标、滚动页面并停留，
+// 降低与快速封禁相关的“全新无头访客”特征。各步骤的位置、距离与间隔均带随机性。
+type Behavior struct {
+	// MouseMoves 为随机移动鼠标的次数。
+	MouseMoves int
+	// Scrolls 为向下滚动页面的次数。
+	Scrolls int
+	// Dwell 为每个页面的停留时长，实际停留时长在 [Dwell/2, Dwell*3/2) 内随机，0 表示不停留。
+	Dwell time.Duration
+	// Script 为在内置行为之后于页面中执行的 JS 函数或表达式源码，为空时不执行。
+	Script string
+}
+
+// defaultViewport 为页面未设置视口时鼠标移动的范围。
+var defaultViewport = playwright.ViewportSize{Width: 1280, Height: 720}
+
+// Run 在页面上执行模拟行为，ctx 取消时提前返回。
+func (b *Behavior) Run(ctx context.Context, page playwright.Page) error {
+	vp := page.ViewportSize()
+	if vp.Width <= 0 || vp.Height <= 0 {
+		vp = defaultViewport
+	}
+	for i := 0; i < max(b.MouseMoves, b.Scrolls); i++ {
+		if i < b.MouseMoves {
+			x, y := rand.Float64()*float64(vp.Width), rand.Float64()*float64(vp.Height)
+			if err := page.Mouse().Move(x, y, playwright.MouseMoveOptions{Steps: playwright.Int(5 + rand.Intn(20))}); err != nil {
+				return fmt.Errorf("移动鼠标失败: %w", err)
+			}
+			if err := sleep(ctx, randomBetween(50*time.Millisecond, 300*time.Millisecond)); err != nil {
+				return err
+			}
+		}
+		if i < b.Scrolls {
+			if _, err := page.Evaluate(`(dy) => window.scrollBy(0, dy)`, 200+rand.Intn(600)); err != nil {
+				return fmt.Errorf("滚动页面失败: %w", err)
+			}
+			if err := sleep(ctx, randomBetween(200*time.Millisecond, 800*time.Millisecond)); err != nil {
+				return err
+			}
+		}
+	}
+	if b.Script != "" {
+		if _, err := page.Evaluate(b.Script); err != nil {
+			return fmt.Errorf("执行行为脚本失败: %w", err)
+		}
+	}
+	if b.Dwell > 0 {
+		return sleep(ctx, randomBetween(b.Dwell/2, b.Dwell*3/2))
+	}
+	return nil
+}
+
+// randomBetween 返回 [lo, hi) 内的随机时长。
+func randomBetween(lo, hi time.Duration) time.Duration {
+	if hi <= lo {
+		return lo
+	}
+	return lo + time.Duration(rand.Int63n(int64(hi-lo)))
+}
+
+// sleep 等待 d，ctx 取消时返回其错误。
+func sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
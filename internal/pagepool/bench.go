@@ -0,0 +1,107 @@
+package pagepool
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Bench 使槽位 slot 在 until 之前不参与普通分配，用于账号被站点风控后的冷却：空闲的槽位立即移出，
+// 正在使用的槽位在归还时移出，冷却结束后自动放回。绑定了 API Key 的专属槽位不受影响。
+// 同一槽位重复冷却时以较晚的 until 为准。
+func (p *Pool) Bench(slot *Slot, until time.Time) {
+	g := p
+	if slot.Group == GroupCanary && p.canary != nil {
+		g = p.canary
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.dedicated[slot.ID]; ok {
+		return
+	}
+	if prev, ok := g.benched[slot.ID]; ok && !until.After(prev) {
+		return
+	}
+	if g.benched == nil {
+		g.benched = make(map[int]time.Time)
+	}
+	g.benched[slot.ID] = until
+	for i, s := range g.free {
+		if s == slot {
+			g.free = append(g.free[:i], g.free[i+1:]...)
+			g.resting = append(g.resting, s)
+			break
+		}
+	}
+	id := slot.ID
+	time.AfterFunc(time.Until(until), func() { g.wake(id) })
+	slog.Info("槽位进入冷却", "platform", g.name, "context_id", slot.ContextID(), "until", until)
+}
+
+// Benched 返回冷却中的槽位编号及冷却结束时间（含灰度页面组）。
+func (p *Pool) Benched() map[int]time.Time {
+	out := make(map[int]time.Time)
+	for _, g := range p.groups() {
+		g.mu.Lock()
+		for id, until := range g.benched {
+			out[id] = until
+		}
+		g.mu.Unlock()
+	}
+	return out
+}
+
+// wake 在冷却到期后将编号为 id 的空闲槽位放回，冷却已被延长时不处理。
+func (p *Pool) wake(id int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	until, ok := p.benched[id]
+	if !ok || time.Now().Before(until) {
+		return
+	}
+	delete(p.benched, id)
+	for i, slot := range p.resting {
+		if slot.ID != id {
+			continue
+		}
+		p.resting = append(p.resting[:i], p.resting[i+1:]...)
+		if w := p.popWaiterLocked(); w != nil {
+			w.ch <- slot
+		} else {
+			p.free = append(p.free, slot)
+		}
+		slog.Info("槽位冷却结束", "platform", p.name, "context_id", slot.ContextID())
+		return
+	}
+}
+
+// restLocked 在槽位 slot 冷却中时将其移入 resting 并返回 true。调用方须持有 p.mu。
+func (p *Pool) restLocked(slot *Slot) bool {
+	until, ok := p.benched[slot.ID]
+	if !ok || !time.Now().Before(until) {
+		return false
+	}
+	p.resting = append(p.resting, slot)
+	return true
+}
+
+// benchedLocked 在本组全部共享槽位（不含专属槽位）都在冷却时返回 true 与最早结束前的等待时间，
+// 此时排队只会等到冷却结束，调用方应稍后重试。调用方须持有 p.mu。
+func (p *Pool) benchedLocked() (time.Duration, bool) {
+	if len(p.benched) == 0 {
+		return 0, false
+	}
+	var wait time.Duration
+	for _, slot := range p.slots {
+		if _, ok := p.dedicated[slot.ID]; ok {
+			continue
+		}
+		until, ok := p.benched[slot.ID]
+		if !ok {
+			return 0, false
+		}
+		if d := time.Until(until); wait == 0 || d < wait {
+			wait = d
+		}
+	}
+	return wait, wait > 0
+}
@@ -0,0 +1,198 @@
+package pagepool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"go_sign/internal/platform"
+)
+
+// Bind 将 API Key key 绑定到本池编号为 id 的槽位：该槽位从普通分配中移出，只供 key 的请求使用，
+// 使下游调用方获得稳定的 cookie 与指纹。槽位正在签名时等待其空闲；key 已绑定其他槽位时先解绑。
+// 至少保留一个未绑定的槽位供其他调用方使用，灰度页面不可绑定。
+func (p *Pool) Bind(ctx context.Context, key string, id int) error {
+	p.mu.Lock()
+	cur, rebind := p.bindings[key]
+	if rebind && cur == id {
+		p.mu.Unlock()
+		return nil
+	}
+	var err error
+	switch {
+	case p.slotByIDLocked(id) == nil:
+		err = fmt.Errorf("槽位 %d 不存在", id)
+	case p.dedicated[id] != nil:
+		err = fmt.Errorf("槽位 %d 已绑定其他 API Key", id)
+	case !rebind && len(p.slots)-len(p.dedicated) <= 1:
+		err = errors.New("至少需保留一个未绑定的槽位")
+	}
+	p.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if rebind {
+		if err := p.Unbind(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	slot, err := p.hold(ctx, id)
+	if err != nil {
+		return err
+	}
+	ch := make(chan *Slot, 1)
+	ch <- slot
+	p.mu.Lock()
+	if p.bindings == nil {
+		p.bindings, p.dedicated = make(map[string]int), make(map[int]chan *Slot)
+	}
+	p.bindings[key], p.dedicated[id] = id, ch
+	p.mu.Unlock()
+	slog.Info("API Key 已绑定专属槽位", "platform", p.name, "api_key", key, "context_id", slot.ContextID())
+	return nil
+}
+
+// Unbind 解除 API Key key 的绑定，等待其专属槽位空闲后放回普通分配；key 未绑定时不做任何事。
+func (p *Pool) Unbind(ctx context.Context, key string) error {
+	p.mu.Lock()
+	id, ok := p.bindings[key]
+	if !ok {
+		p.mu.Unlock()
+		return nil
+	}
+	ch := p.dedicated[id]
+	delete(p.bindings, key)
+	p.mu.Unlock()
+
+	select {
+	case slot := <-ch:
+		p.mu.Lock()
+		delete(p.dedicated, id)
+		p.mu.Unlock()
+		p.Release(slot)
+		slog.Info("API Key 已解除专属槽位绑定", "platform", p.name, "api_key", key, "context_id", slot.ContextID())
+		return nil
+	case <-ctx.Done():
+		// 未能取回槽位时恢复绑定，避免槽位既不属于该 Key 也不参与普通分配
+		p.mu.Lock()
+		p.bindings[key] = id
+		p.mu.Unlock()
+		return fmt.Errorf("等待槽位 %d 空闲失败: %w", id, ctx.Err())
+	}
+}
+
+// Bindings 返回 API Key 名称到专属槽位 ContextID 的映射。
+func (p *Pool) Bindings() map[string]string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make(map[string]string, len(p.bindings))
+	for key, id := range p.bindings {
+		out[key] = (&Slot{Tenant: p.tenant, Group: GroupStable, ID: id}).ContextID()
+	}
+	return out
+}
+
+// slotByID 返回本组编号为 id 的槽位。
+func (p *Pool) slotByID(id int) *Slot {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.slotByIDLocked(id)
+}
+
+// slotByIDLocked 在持有锁的前提下返回本组编号为 id 的槽位。
+func (p *Pool) slotByIDLocked(id int) *Slot {
+	for _, s := range p.slots {
+		if s.ID == id {
+			return s
+		}
+	}
+	return nil
+}
+
+// hold 等待本组编号为 id 的槽位空闲（含专属槽位）并取出，调用方须通过 Release 归还。
+// 等待期间槽位可能被重建，需按编号重新查找。
+func (p *Pool) hold(ctx context.Context, id int) (*Slot, error) {
+	for {
+		p.mu.Lock()
+		ch := p.dedicated[id]
+		p.mu.Unlock()
+		if ch != nil {
+			select {
+			case slot := <-ch:
+				return slot, nil
+			default:
+			}
+		} else if slot := p.slotByID(id); slot != nil && p.TryAcquire(slot) {
+			return slot, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("等待槽位 %d 空闲失败: %w", id, ctx.Err())
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// acquireBound 在 ctx 中的 API Key 绑定了专属槽位时等待并返回该槽位；未绑定时 ok 为 false。
+func (p *Pool) acquireBound(ctx context.Context) (slot *Slot, ok bool, err error) {
+	key := platform.APIKeyFrom(ctx)
+	if key == "" {
+		return nil, false, nil
+	}
+	p.mu.Lock()
+	id, bound := p.bindings[key]
+	ch := p.dedicated[id]
+	p.mu.Unlock()
+	if !bound {
+		return nil, false, nil
+	}
+	start := time.Now()
+	defer func() {
+		poolWaitSeconds.Observe(time.Since(start).Seconds(), p.name, p.tenant, platform.PriorityFrom(ctx).String())
+	}()
+	select {
+	case slot := <-ch:
+		return slot, true, nil
+	case <-ctx.Done():
+		return nil, true, ctx.Err()
+	}
+}
+
+// Bind 将租户 tenant 的 API Key key 绑定到编号为 id 的槽位，见 Pool.Bind。
+func (s Set) Bind(ctx context.Context, tenant, key string, id int) error {
+	p := s[tenant]
+	if p == nil {
+		return fmt.Errorf("租户 %s 页面未初始化", tenant)
+	}
+	return p.Bind(ctx, key, id)
+}
+
+// Unbind 解除租户 tenant 的 API Key key 的绑定，见 Pool.Unbind。
+func (s Set) Unbind(ctx context.Context, tenant, key string) error {
+	p := s[tenant]
+	if p == nil {
+		return fmt.Errorf("租户 %s 页面未初始化", tenant)
+	}
+	return p.Unbind(ctx, key)
+}
+
+// Bindings 返回全部租户的绑定，按租户与 API Key 排序，Platform 由调用方填充。
+func (s Set) Bindings() []platform.Binding {
+	var out []platform.Binding
+	for tenant, p := range s {
+		for key, id := range p.Bindings() {
+			out = append(out, platform.Binding{Tenant: tenant, APIKey: key, ContextID: id})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Tenant != out[j].Tenant {
+			return out[i].Tenant < out[j].Tenant
+		}
+		return out[i].APIKey < out[j].APIKey
+	})
+	return out
+}
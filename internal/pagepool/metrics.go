@@ -0,0 +1,32 @@
+package pagepool
+
+import "go_sign/internal/metrics"
+
+// 页面池相关指标。
+var (
+	poolWaitSeconds = metrics.Default.NewHistogramVec(
+		"go_sign_pool_wait_seconds",
+		"签名请求等待空闲页面的耗时（秒）",
+		nil, "platform", "tenant", "priority",
+	)
+	poolWaiting = metrics.Default.NewGaugeVec(
+		"go_sign_pool_waiting",
+		"当前排队等待空闲页面的请求数",
+		"platform", "tenant", "priority",
+	)
+	poolShed = metrics.Default.NewCounterVec(
+		"go_sign_pool_shed_total",
+		"排队超出预算而被提前拒绝（429）的低优先级签名请求数",
+		"platform", "tenant",
+	)
+	groupSigns = metrics.Default.NewCounterVec(
+		"go_sign_stealth_group_signs_total",
+		"各 stealth.js 分组页面上的签名次数，group 为 stable 或 canary，result 为 success 或 error",
+		"platform", "group", "result",
+	)
+	slotRecreates = metrics.Default.NewCounterVec(
+		"go_sign_slot_recreates_total",
+		"重建页面槽位的次数，result 为 success 或 error",
+		"platform", "result",
+	)
+)
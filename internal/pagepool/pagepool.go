@@ -0,0 +1,993 @@
+// Package pagepool 提供浏览器类签名平台共用的页面池：按租户隔离、按优先级排队、并发预热。
+package pagepool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mxschmitt/playwright-go"
+	"go_sign/internal/browser"
+	"go_sign/internal/config"
+	"go_sign/internal/geo"
+	"go_sign/internal/platform"
+	"go_sign/internal/timing"
+)
+
+// 槽位分组：注入默认 stealth.js 的页面为 stable，注入灰度 stealth.js 的页面为 canary。
+const (
+	GroupStable = "stable"
+	GroupCanary = "canary"
+)
+
+// Slot 表示页面池中的一个槽位，独占一个浏览器上下文和页面。
+type Slot struct {
+	ID     int
+	Tenant string
+	// Group 为槽位所属分组，GroupStable 或 GroupCanary。
+	Group string
+	// Identity 为页面自身的账号标识（如小红书 a1），由平台在预热时填充。
+	Identity string
+	// UserAgent 为页面的 User-Agent，由平台在预热时填充。
+	UserAgent string
+	Context   playwright.BrowserContext
+	Page      playwright.Page
+
+	navMu sync.Mutex
+	navs  []string // 主框架最近的跳转地址，用于诊断重定向
+
+	// scriptVersion 为页面签名脚本的版本指纹，见 ScriptVersion
+	scriptVersion atomic.Value
+
+	// traffic 统计页面的请求与流量，见 trackTraffic
+	traffic *traffic
+
+	// retired 为 true 时槽位已被新一代页面替换（见 Staged.Commit），归还时关闭；由所属页面池的 mu 保护
+	retired bool
+}
+
+// ScriptVersion 返回页面当前加载的签名脚本的版本指纹，由平台在预热与重新加载后填充，未填充时为空。
+func (s *Slot) ScriptVersion() string {
+	v, _ := s.scriptVersion.Load().(string)
+	return v
+}
+
+// SetScriptVersion 记录页面当前加载的签名脚本的版本指纹。
+func (s *Slot) SetScriptVersion(v string) {
+	s.scriptVersion.Store(v)
+}
+
+// maxNavigations 为每个槽位保留的主框架跳转记录数。
+const maxNavigations = 10
+
+// Navigations 返回页面主框架最近的跳转地址，按时间先后排列。
+func (s *Slot) Navigations() []string {
+	s.navMu.Lock()
+	defer s.navMu.Unlock()
+	return append([]string(nil), s.navs...)
+}
+
+// recordNavigation 记录一次主框架跳转，超出 maxNavigations 时丢弃最早的记录。
+func (s *Slot) recordNavigation(u string) {
+	s.navMu.Lock()
+	defer s.navMu.Unlock()
+	s.navs = append(s.navs, u)
+	if len(s.navs) > maxNavigations {
+		s.navs = s.navs[len(s.navs)-maxNavigations:]
+	}
+}
+
+// ContextID 返回槽位浏览器上下文的标识，格式为 <租户>/<分组>/<槽位>。
+func (s *Slot) ContextID() string {
+	return fmt.Sprintf("%s/%s/%d", s.Tenant, s.Group, s.ID)
+}
+
+// Close 关闭槽位持有的页面与浏览器上下文。
+func (s *Slot) Close() error {
+	var firstErr error
+	if s.Page != nil {
+		if err := s.Page.Close(); err != nil {
+			slog.Warn("关闭页面失败", "err", err, "tenant", s.Tenant, "slot", s.ID)
+			firstErr = fmt.Errorf("关闭页面失败: %w", err)
+		}
+	}
+	if s.Context != nil {
+		if err := s.Context.Close(); err != nil {
+			slog.Warn("关闭浏览器上下文失败", "err", err, "tenant", s.Tenant, "slot", s.ID)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("关闭浏览器上下文失败: %w", err)
+			}
+		}
+	}
+	return firstErr
+}
+
+// OpenOptions 定义打开一个槽位时的浏览器上下文与导航参数。
+type OpenOptions struct {
+	// Platform 为槽位所属平台，用于浏览器流量指标的标签。
+	Platform string
+	// Context 为创建浏览器上下文的参数，如附加的请求头。
+	Context playwright.BrowserNewContextOptions
+	// Device 为模拟的设备预设，覆盖 Context 中的 UA、视口等参数并注入 navigator.platform；为 nil 时使用浏览器默认值。
+	Device *browser.Device
+	// Proxy 为浏览器上下文的出口代理，为 nil 时直连。
+	Proxy *url.URL
+	// Timezone 与 Locale 为模拟的时区（IANA 时区名）与语言，为空时使用浏览器默认值。
+	Timezone string
+	Locale   string
+	// Geo 非 nil 且配置了 Proxy 时，创建上下文前检查代理出口位置与 Timezone、Locale 是否一致。
+	Geo *geo.Checker
+	// StealthPath 为注入的 stealth.min.js 路径，为空时不注入。
+	StealthPath string
+	// InitScripts 为在 stealth.js 之后注入的脚本文件路径，在页面自身脚本之前执行。
+	InitScripts []string
+	// URL 为预热时访问的页面地址，签名在该页面上执行。
+	URL string
+	// WarmupURLs 为访问 URL 之前依次访问的页面，用于模拟正常浏览并填充站点的 localStorage 等存储；
+	// 单个页面访问失败时只记录告警。
+	WarmupURLs []string
+	// Behavior 为预热时每次导航后模拟的真人浏览行为，为 nil 时不模拟；执行失败时只记录告警。
+	Behavior *Behavior
+	// WaitUntil 为导航等待策略，NavigationTimeout 为导航超时，0 表示使用 Playwright 默认值。
+	WaitUntil         string
+	NavigationTimeout time.Duration
+	// ReadyJS 为导航完成后轮询的就绪判断函数，返回 true 表示可签名；为空时不等待。
+	ReadyJS      string
+	ReadyArg     any
+	ReadyTimeout time.Duration
+	// OnPage 在页面创建后、首次导航前调用，用于注入页面级脚本与监听页面事件，为 nil 时不调用。
+	OnPage func(page playwright.Page) error
+}
+
+// stealthKey 为 ctx 中覆盖 OpenOptions.StealthPath 的键，预热灰度页面时写入。
+type stealthKey struct{}
+
+// Open 创建浏览器上下文与页面，注入 stealth.js，跳转 o.URL 并等待页面就绪。
+// 预热灰度页面时注入 Canary.StealthPath，而非 o.StealthPath；预热新一代页面时使用 Generation 的浏览器，而非 b。
+func Open(ctx context.Context, b *browser.Browser, tenant string, id int, o OpenOptions) (*Slot, error) {
+	log := slog.With("tenant", tenant, "slot", id)
+	if path, ok := ctx.Value(stealthKey{}).(string); ok && o.StealthPath != "" {
+		o.StealthPath = path
+	}
+	if gb, ok := ctx.Value(browserKey{}).(*browser.Browser); ok {
+		b = gb
+	}
+	if o.Device != nil {
+		log = log.With("device", o.Device.Name)
+	}
+	contextOptions := o.Device.Apply(o.Context, b.Versions().Chromium)
+	emulation := geo.Emulation{Timezone: o.Timezone, Locale: o.Locale}
+	if o.Proxy != nil {
+		log = log.With("proxy", o.Proxy.Redacted())
+		contextOptions.Proxy = proxyOptions(o.Proxy)
+		if o.Geo != nil {
+			emulation = o.Geo.Check(ctx, o.Proxy, emulation, log)
+		}
+	}
+	if emulation.Timezone != "" {
+		contextOptions.TimezoneId = playwright.String(emulation.Timezone)
+	}
+	if emulation.Locale != "" {
+		contextOptions.Locale = playwright.String(emulation.Locale)
+	}
+	bctx, err := b.NewContext(contextOptions)
+	if err != nil {
+		log.Error("创建浏览器上下文失败", "err", err)
+		return nil, fmt.Errorf("创建浏览器上下文失败: %w", err)
+	}
+	slot := &Slot{ID: id, Tenant: tenant, Context: bctx}
+	if script := o.Device.InitScript(); script != "" {
+		if err := bctx.AddInitScript(playwright.BrowserContextAddInitScriptOptions{Script: playwright.String(script)}); err != nil {
+			log.Error("注入设备模拟脚本失败", "err", err)
+			_ = slot.Close()
+			return nil, fmt.Errorf("注入设备模拟脚本失败: %w", err)
+		}
+	}
+
+	if o.StealthPath != "" {
+		log.Info("注入 stealth.js", "path", o.StealthPath)
+		err = bctx.AddInitScript(playwright.BrowserContextAddInitScriptOptions{
+			Path: playwright.String(o.StealthPath),
+		})
+		if err != nil {
+			log.Error("注入 stealth.js 失败", "err", err)
+			_ = slot.Close()
+			return nil, fmt.Errorf("注入 stealth.js 失败: %w", err)
+		}
+	}
+	for _, path := range o.InitScripts {
+		log.Info("注入初始化脚本", "path", path)
+		err = bctx.AddInitScript(playwright.BrowserContextAddInitScriptOptions{
+			Path: playwright.String(path),
+		})
+		if err != nil {
+			log.Error("注入初始化脚本失败", "err", err, "path", path)
+			_ = slot.Close()
+			return nil, fmt.Errorf("注入初始化脚本 %s 失败: %w", path, err)
+		}
+	}
+	// 新建页面并访问站点首页
+	slot.Page, err = bctx.NewPage()
+	if err != nil {
+		log.Error("新建页面失败", "err", err)
+		_ = slot.Close()
+		return nil, fmt.Errorf("新建页面失败: %w", err)
+	}
+	slot.Page.On("framenavigated", func(f playwright.Frame) {
+		if f == slot.Page.MainFrame() {
+			slot.recordNavigation(f.URL())
+		}
+	})
+	slot.trackTraffic(o.Platform)
+	if o.OnPage != nil {
+		if err := o.OnPage(slot.Page); err != nil {
+			log.Error("初始化页面失败", "err", err)
+			_ = slot.Close()
+			return nil, fmt.Errorf("初始化页面失败: %w", err)
+		}
+	}
+	for _, u := range o.WarmupURLs {
+		if err := visit(slot.Page, u, o); err != nil {
+			log.Warn("访问预热页面失败", "err", err, "url", u)
+			continue
+		}
+		o.behave(ctx, slot.Page, log)
+	}
+	if err = Navigate(ctx, slot.Page, o, log); err != nil {
+		_ = slot.Close()
+		return nil, err
+	}
+	o.behave(ctx, slot.Page, log)
+	slot.setPhase(PhaseIdle)
+	return slot, nil
+}
+
+// proxyOptions 将代理地址转换为浏览器上下文的代理参数，地址中的用户名与密码作为代理认证。
+func proxyOptions(u *url.URL) *playwright.BrowserNewContextProxy {
+	p := &playwright.BrowserNewContextProxy{Server: playwright.String(u.Scheme + "://" + u.Host)}
+	if u.User != nil {
+		p.Username = playwright.String(u.User.Username())
+		if password, ok := u.User.Password(); ok {
+			p.Password = playwright.String(password)
+		}
+	}
+	return p
+}
+
+// Navigate 将页面跳转到 o.URL 并等待 o.ReadyJS 就绪，用于预热及签名函数丢失后重新加载页面。
+func Navigate(ctx context.Context, page playwright.Page, o OpenOptions, log *slog.Logger) error {
+	log.Info("跳转站点首页...", "url", o.URL, "wait_until", o.WaitUntil, "timeout", o.NavigationTimeout)
+	start := time.Now()
+	if err := visit(page, o.URL, o); err != nil {
+		log.Error("跳转站点首页失败", "err", err)
+		return fmt.Errorf("跳转站点首页失败: %w", err)
+	}
+	if o.ReadyJS != "" && o.ReadyTimeout > 0 {
+		if err := WaitFor(ctx, page, o.ReadyJS, o.ReadyArg, o.ReadyTimeout); err != nil {
+			log.Error("等待签名函数就绪失败", "err", err)
+			return err
+		}
+	}
+	log.Info("站点首页就绪", "elapsed", time.Since(start))
+	return nil
+}
+
+// behave 在页面上执行 o.Behavior，失败时只记录告警。
+func (o OpenOptions) behave(ctx context.Context, page playwright.Page, log *slog.Logger) {
+	if o.Behavior == nil {
+		return
+	}
+	if err := o.Behavior.Run(ctx, page); err != nil {
+		log.Warn("模拟浏览行为失败", "err", err, "url", page.URL())
+	}
+}
+
+// visit 按 o 的等待策略与超时将页面跳转到 u。
+func visit(page playwright.Page, u string, o OpenOptions) error {
+	var gotoOpts playwright.PageGotoOptions
+	if o.WaitUntil != "" {
+		gotoOpts.WaitUntil = playwright.String(o.WaitUntil)
+	}
+	if o.NavigationTimeout > 0 {
+		gotoOpts.Timeout = playwright.Int(int(o.NavigationTimeout.Milliseconds()))
+	}
+	_, err := page.Goto(u, gotoOpts)
+	return err
+}
+
+// WaitFor 轮询页面直到 js(arg) 返回 true 或超时。
+// 在 domcontentloaded 策略下，签名函数可能稍晚于导航完成才被注入。
+func WaitFor(ctx context.Context, page playwright.Page, js string, arg any, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		ok, err := page.Evaluate(js, arg)
+		if err != nil {
+			return fmt.Errorf("检查签名函数失败: %w", err)
+		}
+		if ok == true {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("等待签名函数就绪超时（%s）", timeout)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// Evaluate 在页面中执行签名 JS，并将耗时计入请求的 timing.Evaluate 阶段；ctx 已结束时不执行。
+// 启用自适应并发（见 SetAdaptive）时，先等待执行名额，并以执行耗时调整并发上限。
+func Evaluate(ctx context.Context, page playwright.Page, js string, arg ...any) (any, error) {
+	a := adaptive.Load()
+	if a != nil {
+		if err := a.acquire(ctx); err != nil {
+			return nil, err
+		}
+	}
+	// 页面执行无法中途取消：请求已超时（如排队耗尽了 X-Timeout-Ms）时不再占用页面
+	if err := ctx.Err(); err != nil {
+		if a != nil {
+			a.release(0, false)
+		}
+		return nil, err
+	}
+	start := time.Now()
+	v, err := page.Evaluate(js, arg...)
+	d := time.Since(start)
+	timing.Observe(ctx, timing.Evaluate, d)
+	if a != nil {
+		// 执行出错（如页面崩溃）的耗时不反映浏览器负载，不参与调整
+		a.release(d, err == nil)
+	}
+	return v, err
+}
+
+// WithClock 包装页面函数 js，返回以 [ts, arg] 为参数的新函数：ts 非 0 时，
+// 在 js(arg) 同步执行期间将 Date.now() 与 new Date() 固定为 ts 毫秒，执行后恢复。
+// 用于按调用方时钟生成签名中的时间戳。
+func WithClock(js string) string {
+	return `([ts, arg]) => {
+	const fn = (` + js + `);
+	if (!ts) return fn(arg);
+	const RealDate = window.Date;
+	class PinnedDate extends RealDate {
+		constructor(...args) { if (args.length) { super(...args); } else { super(ts); } }
+		static now() { return ts; }
+	}
+	window.Date = PinnedDate;
+	try { return fn(arg); } finally { window.Date = RealDate; }
+}`
+}
+
+// Pool 管理一组可复用的页面槽位，同一时刻每个槽位只被一个签名请求占用。
+// 页面不足时按优先级排队，归还的槽位优先交给高优先级中最早等待的请求。
+// 配置灰度 stealth.js 时，按 canaryWeight 的比例将请求分流到灰度页面组。
+type Pool struct {
+	name    string // 所属平台，用于指标标签
+	tenant  string
+	slots   []*Slot
+	mu      sync.Mutex
+	free    []*Slot
+	waiters [platform.NumPriorities][]*waiter
+
+	// shedBudget 为排队等待的预算，超出时提前拒绝低优先级请求（见 acquire），0 表示不限制
+	shedBudget time.Duration
+
+	canary       *Pool
+	canaryWeight float64
+
+	// newSlot、stealthPath 与 browser 用于 Recreate 重建槽位；stealthPath 在灰度页面组及升级后非空，
+	// browser 在升级后为新一代页面所在的浏览器
+	newSlot     SlotFactory
+	stealthPath string
+	browser     *browser.Browser
+
+	// draining 为升级后仍在使用、待归还时关闭的旧槽位数
+	draining int
+
+	// bindings 为 API Key 名称到专属槽位编号的映射；dedicated 为专属槽位空闲时所在的通道，
+	// 专属槽位不在 free 中，不参与普通分配（见 Bind）
+	bindings  map[string]int
+	dedicated map[int]chan *Slot
+
+	// recreating 为正在重建的槽位编号及开始时间；recreateCost 为近期重建的平均耗时，用于估算 Retry-After
+	recreating   map[int]time.Time
+	recreateCost time.Duration
+
+	// benched 为冷却中的槽位编号及结束时间（见 Bench）；resting 为冷却中的空闲槽位，不在 free 中
+	benched map[int]time.Time
+	resting []*Slot
+
+	// rank 为分配空闲槽位时的优先级，见 Set.SetRank
+	rank RankFunc
+}
+
+// waiter 为排队等待空闲槽位的请求，since 为开始排队的时间。
+type waiter struct {
+	ch    chan *Slot
+	since time.Time
+}
+
+// defaultRecreateCost 为尚未观测到重建耗时时估算的重建耗时。
+const defaultRecreateCost = 15 * time.Second
+
+// New 使用平台 name 下租户已预热的槽位创建页面池。
+func New(name, tenant string, slots []*Slot) *Pool {
+	return &Pool{name: name, tenant: tenant, slots: slots, free: append([]*Slot(nil), slots...)}
+}
+
+// Slots 返回池中全部槽位。
+func (p *Pool) Slots() []*Slot {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*Slot(nil), p.slots...)
+}
+
+// Browser 返回蓝绿升级后页面所在的浏览器，未升级时为 nil（页面在平台创建时的浏览器上）。
+func (p *Pool) Browser() *browser.Browser {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.browser
+}
+
+// AllSlots 返回池中全部槽位，包括灰度页面组。
+func (p *Pool) AllSlots() []*Slot {
+	var out []*Slot
+	for _, g := range p.groups() {
+		out = append(out, g.Slots()...)
+	}
+	return out
+}
+
+// Recreate 以相同编号与分组重新创建调用方持有的槽位 slot 并关闭原槽位，用于页面崩溃等无法继续签名的情况。
+// 成功时返回新槽位，失败时返回原槽位与错误；调用方须归还返回的槽位。
+func (p *Pool) Recreate(ctx context.Context, slot *Slot) (*Slot, error) {
+	g := p
+	if slot.Group == GroupCanary && p.canary != nil {
+		g = p.canary
+	}
+	start := time.Now()
+	g.mu.Lock()
+	newSlot, gen := g.newSlot, Generation{Browser: g.browser, StealthPath: g.stealthPath}
+	switch {
+	case newSlot == nil:
+		g.mu.Unlock()
+		return slot, errors.New("页面池不支持重建槽位")
+	case slot.retired:
+		// 升级后的旧槽位归还时即关闭，无需重建
+		g.mu.Unlock()
+		return slot, fmt.Errorf("槽位 %s 已被新页面替换", slot.ContextID())
+	}
+	if g.recreating == nil {
+		g.recreating = make(map[int]time.Time)
+	}
+	g.recreating[slot.ID] = start
+	g.mu.Unlock()
+	defer func() {
+		g.mu.Lock()
+		delete(g.recreating, slot.ID)
+		g.mu.Unlock()
+	}()
+	next, err := newSlot(gen.context(ctx), slot.Tenant, slot.ID)
+	if err != nil {
+		slotRecreates.Inc(p.name, "error")
+		return slot, fmt.Errorf("重建槽位 %s 失败: %w", slot.ContextID(), err)
+	}
+	next.Group = slot.Group
+	g.mu.Lock()
+	if slot.retired {
+		// 重建期间已切换到新一代页面，新建的槽位属于旧一代，直接丢弃
+		g.mu.Unlock()
+		_ = next.Close()
+		return slot, fmt.Errorf("槽位 %s 已被新页面替换", slot.ContextID())
+	}
+	g.mu.Unlock()
+	_ = slot.Close()
+	g.mu.Lock()
+	for i, s := range g.slots {
+		if s == slot {
+			g.slots[i] = next
+		}
+	}
+	if d := time.Since(start); g.recreateCost == 0 {
+		g.recreateCost = d
+	} else {
+		g.recreateCost = (g.recreateCost*7 + d*3) / 10
+	}
+	g.mu.Unlock()
+	slotRecreates.Inc(p.name, "success")
+	slog.Info("已重建槽位", "platform", p.name, "context_id", next.ContextID())
+	return next, nil
+}
+
+// Acquire 按 ctx 中的优先级取出一个空闲槽位，ctx 取消时返回错误。
+// ctx 中的 API Key 绑定了专属槽位时只使用该槽位；否则配置灰度页面组时，按比例从灰度组中取出。
+func (p *Pool) Acquire(ctx context.Context) (*Slot, error) {
+	if slot, ok, err := p.acquireBound(ctx); ok {
+		return slot, err
+	}
+	if p.canary != nil && rand.Float64() < p.canaryWeight {
+		return p.canary.acquire(ctx)
+	}
+	return p.acquire(ctx)
+}
+
+// acquire 从本组页面中取出一个空闲槽位。
+func (p *Pool) acquire(ctx context.Context) (*Slot, error) {
+	prio := platform.PriorityFrom(ctx)
+	start := time.Now()
+	defer func() {
+		waited := time.Since(start)
+		poolWaitSeconds.Observe(waited.Seconds(), p.name, p.tenant, prio.String())
+		timing.Observe(ctx, timing.QueueWait, waited)
+	}()
+
+	p.mu.Lock()
+	if len(p.free) > 0 {
+		slot := p.takeRankedLocked()
+		p.mu.Unlock()
+		return slot, nil
+	}
+	if wait, ok := p.recoveringLocked(); ok {
+		p.mu.Unlock()
+		return nil, &platform.UnavailableError{Reason: "浏览器页面正在重建", RetryAfter: wait}
+	}
+	if wait, ok := p.benchedLocked(); ok {
+		p.mu.Unlock()
+		return nil, &platform.UnavailableError{Reason: "全部账号冷却中", RetryAfter: wait}
+	}
+	if p.shedLocked(prio, start) {
+		p.mu.Unlock()
+		poolShed.Inc(p.name, p.tenant)
+		return nil, platform.ErrOverloaded
+	}
+	w := &waiter{ch: make(chan *Slot, 1), since: start}
+	p.waiters[prio] = append(p.waiters[prio], w)
+	p.mu.Unlock()
+	poolWaiting.Add(1, p.name, p.tenant, prio.String())
+	defer poolWaiting.Add(-1, p.name, p.tenant, prio.String())
+
+	// 低优先级请求最多排队 shedBudget，超出后让出队列
+	var shed <-chan time.Time
+	if p.shedBudget > 0 && prio == platform.PriorityLow {
+		timer := time.NewTimer(p.shedBudget)
+		defer timer.Stop()
+		shed = timer.C
+	}
+	select {
+	case slot := <-w.ch:
+		return slot, nil
+	case <-shed:
+		if !p.removeWaiter(prio, w) {
+			return <-w.ch, nil
+		}
+		poolShed.Inc(p.name, p.tenant)
+		return nil, platform.ErrOverloaded
+	case <-ctx.Done():
+		if !p.removeWaiter(prio, w) {
+			// 取消的同时已被分配到槽位，需要归还
+			p.Release(<-w.ch)
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// shedLocked 判断是否应直接拒绝优先级为 prio 的新请求：配置了 shedBudget、请求为低优先级，
+// 且队列中最早的等待者已排队超过 shedBudget。调用方须持有 p.mu。
+func (p *Pool) shedLocked(prio platform.Priority, now time.Time) bool {
+	if p.shedBudget <= 0 || prio != platform.PriorityLow {
+		return false
+	}
+	for _, q := range p.waiters {
+		if len(q) > 0 && now.Sub(q[0].since) > p.shedBudget {
+			return true
+		}
+	}
+	return false
+}
+
+// removeWaiter 将 w 移出优先级 prio 的等待队列，w 已被分配槽位时返回 false。
+func (p *Pool) removeWaiter(prio platform.Priority, w *waiter) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	q := p.waiters[prio]
+	for i, x := range q {
+		if x == w {
+			p.waiters[prio] = append(q[:i], q[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// recoveringLocked 在本组全部共享槽位（不含专属槽位）都在重建时返回 true 与预计完成前的等待时间，
+// 此时排队只会等到重建完成，调用方应稍后重试。调用方须持有 p.mu。
+func (p *Pool) recoveringLocked() (time.Duration, bool) {
+	if len(p.recreating) == 0 {
+		return 0, false
+	}
+	bound := make(map[int]bool, len(p.bindings))
+	for _, id := range p.bindings {
+		bound[id] = true
+	}
+	cost := p.recreateCost
+	if cost == 0 {
+		cost = defaultRecreateCost
+	}
+	var wait time.Duration
+	shared := 0
+	for _, slot := range p.slots {
+		if bound[slot.ID] {
+			continue
+		}
+		shared++
+		start, ok := p.recreating[slot.ID]
+		if !ok {
+			return 0, false
+		}
+		// 已超出平均耗时的重建按还需 1 秒估算
+		wait = max(wait, time.Until(start.Add(cost)), time.Second)
+	}
+	return wait, shared > 0
+}
+
+// TryAcquire 在槽位 slot 空闲时将其取出并返回 true，正在使用时返回 false；用于维护指定槽位，
+// 取出的槽位须通过 Release 归还。
+func (p *Pool) TryAcquire(slot *Slot) bool {
+	g := p
+	if slot.Group == GroupCanary && p.canary != nil {
+		g = p.canary
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for i, s := range g.free {
+		if s == slot {
+			g.free = append(g.free[:i], g.free[i+1:]...)
+			return true
+		}
+	}
+	// 冷却中的空闲槽位同样可取出维护，归还时仍回到冷却
+	for i, s := range g.resting {
+		if s == slot {
+			g.resting = append(g.resting[:i], g.resting[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Release 将槽位归还页面池，有等待者时直接交给优先级最高的等待者。
+func (p *Pool) Release(slot *Slot) {
+	if slot.Group == GroupCanary && p.canary != nil {
+		p.canary.Release(slot)
+		return
+	}
+	p.mu.Lock()
+	if slot.retired {
+		p.mu.Unlock()
+		p.retire(slot)
+		return
+	}
+	defer p.mu.Unlock()
+	if ch, ok := p.dedicated[slot.ID]; ok && slot.Group != GroupCanary {
+		ch <- slot
+		return
+	}
+	if p.restLocked(slot) {
+		return
+	}
+	if w := p.popWaiterLocked(); w != nil {
+		w.ch <- slot
+		return
+	}
+	p.free = append(p.free, slot)
+}
+
+// Done 归还签名使用的槽位，并按槽位分组记录签名结果，err 为签名错误。
+func (p *Pool) Done(slot *Slot, err error) {
+	result := "success"
+	if err != nil {
+		result = "error"
+	}
+	groupSigns.Inc(p.name, slot.Group, result)
+	p.Release(slot)
+}
+
+// Defer 在 until 时归还槽位 slot，期间槽位既不空闲也不属于任何请求，用于按账号节流：
+// 与 Bench 不同，全部槽位都被推迟时新请求照常排队，槽位到期归还后交给等待者。
+func (p *Pool) Defer(slot *Slot, until time.Time) {
+	d := time.Until(until)
+	if d <= 0 {
+		p.Release(slot)
+		return
+	}
+	time.AfterFunc(d, func() { p.Release(slot) })
+}
+
+// groups 返回页面池自身及灰度页面组。
+func (p *Pool) groups() []*Pool {
+	if p.canary == nil {
+		return []*Pool{p}
+	}
+	return []*Pool{p, p.canary}
+}
+
+// Recycle 逐个重建池中的全部槽位（包括灰度页面组）以释放浏览器内存与缓存：每个槽位等待其当前签名完成后取出、
+// 重建并归还，同一时刻只有一个槽位不可用。单个槽位重建失败时保留原槽位继续服务，返回全部失败的汇总。
+func (p *Pool) Recycle(ctx context.Context) error {
+	var errs []error
+	for _, g := range p.groups() {
+		for _, old := range g.Slots() {
+			slot, err := g.hold(ctx, old.ID)
+			if err != nil {
+				return errors.Join(append(errs, err)...)
+			}
+			slot, err = p.Recreate(ctx, slot)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			p.Release(slot)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close 关闭页面池中的全部槽位，包括灰度页面组。
+func (p *Pool) Close() error {
+	var firstErr error
+	for _, g := range p.groups() {
+		for _, slot := range g.Slots() {
+			if err := slot.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	return firstErr
+}
+
+// Set 为一个平台下各租户的页面池，键为租户名。
+type Set map[string]*Pool
+
+// For 返回 ctx 所属租户的页面池。
+func (s Set) For(ctx context.Context) (*Pool, error) {
+	tenant := platform.TenantFrom(ctx)
+	if p := s[tenant]; p != nil {
+		return p, nil
+	}
+	return nil, fmt.Errorf("租户 %s 页面未初始化", tenant)
+}
+
+// Check 在每个租户（及其灰度页面组）的一个空闲页面上执行 js(arg)，返回值不为 true 时视为不健康。
+// 全部页面组均不健康时返回错误；仅部分不健康时返回包装 platform.ErrDegraded 的错误。
+func (s Set) Check(ctx context.Context, js string, arg any) error {
+	if len(s) == 0 {
+		return errors.New("页面池未初始化")
+	}
+	var errs []error
+	total := 0
+	for tenant, pool := range s {
+		for _, g := range pool.groups() {
+			total++
+			if err := g.check(ctx, js, arg); err != nil {
+				errs = append(errs, fmt.Errorf("租户 %s %w", tenant, err))
+			}
+		}
+	}
+	switch {
+	case len(errs) == 0:
+		return nil
+	case len(errs) < total:
+		return fmt.Errorf("%w: %d/%d 个页面组不可用: %w", platform.ErrDegraded, len(errs), total, errors.Join(errs...))
+	}
+	return errors.Join(errs...)
+}
+
+// check 在一个空闲页面上执行 js(arg)，不参与灰度分流。
+func (p *Pool) check(ctx context.Context, js string, arg any) error {
+	slot, err := p.acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("等待空闲页面失败: %w", err)
+	}
+	defer p.Release(slot)
+	return CheckSlot(slot, js, arg)
+}
+
+// CheckSlot 在槽位 slot 的页面上执行 js(arg)，返回值不为 true 时返回错误。
+func CheckSlot(slot *Slot, js string, arg any) error {
+	ok, err := slot.Page.Evaluate(js, arg)
+	if err != nil {
+		return fmt.Errorf("%s 页面检查签名函数失败: %w", slot.Group, err)
+	}
+	if ok != true {
+		return fmt.Errorf("%s 页面上签名函数不可用", slot.Group)
+	}
+	return nil
+}
+
+// Recycle 依次重建全部租户页面池中的槽位，见 Pool.Recycle。
+func (s Set) Recycle(ctx context.Context) error {
+	var errs []error
+	for tenant, p := range s {
+		if err := p.Recycle(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("租户 %s: %w", tenant, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close 关闭全部租户的页面池。
+func (s Set) Close() error {
+	var errs []error
+	for _, p := range s {
+		if err := p.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Tenant 为预热时单个租户的页面数量。
+type Tenant struct {
+	Name string
+	Size int
+	// Canary 为灰度 stealth.js 配置，为 nil 时不创建灰度页面组。
+	Canary *Canary
+	// ShedBudget 为排队等待的预算，超出时提前拒绝低优先级请求，0 表示不限制。
+	ShedBudget time.Duration
+}
+
+// Canary 为灰度 stealth.js 配置：在租户原有页面之外额外预热 Size 个注入 StealthPath 的页面，
+// 并将 Weight 比例的请求分流到这些页面，用于在全量替换前验证新版 stealth.js。
+type Canary struct {
+	StealthPath string
+	Weight      float64
+	Size        int
+}
+
+// NewCanary 返回大小为 size 的页面池对应的灰度配置，stealthPath 为空时返回 nil。
+// 灰度页面数为 size 按 weight 比例向上取整。
+func NewCanary(stealthPath string, weight float64, size int) *Canary {
+	if stealthPath == "" {
+		return nil
+	}
+	return &Canary{StealthPath: stealthPath, Weight: weight, Size: int(math.Ceil(float64(size) * weight))}
+}
+
+// SlotFactory 为租户创建第 id 个槽位。
+type SlotFactory func(ctx context.Context, tenant string, id int) (*Slot, error)
+
+// Warmup 以有限并发初始化平台 name 下全部租户的页面槽位，并发上限在租户间共享。
+// 租户部分槽位失败时以成功的槽位继续提供服务，任一租户全部失败时返回错误。
+func Warmup(ctx context.Context, name string, tenants []Tenant, concurrency int, newSlot SlotFactory) (Set, error) {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	total := 0
+	for _, t := range tenants {
+		total += t.Size + t.canarySize()
+	}
+	slog.Info("开始预热页面池", "platform", name, "tenants", len(tenants), "pages", total, "concurrency", concurrency)
+	start := time.Now()
+
+	type result struct {
+		slot *Slot
+		err  error
+	}
+	results := make([][]result, len(tenants))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for ti, t := range tenants {
+		// 灰度页面编号接在原有页面之后
+		results[ti] = make([]result, t.Size+t.canarySize())
+		for i := range results[ti] {
+			slotCtx, group := ctx, GroupStable
+			if i >= t.Size {
+				slotCtx, group = context.WithValue(ctx, stealthKey{}, t.Canary.StealthPath), GroupCanary
+			}
+			wg.Add(1)
+			go func(ti, i int, tenant string) {
+				defer wg.Done()
+				select {
+				case sem <- struct{}{}:
+				case <-ctx.Done():
+					results[ti][i].err = ctx.Err()
+					return
+				}
+				defer func() { <-sem }()
+				slot, err := newSlot(slotCtx, tenant, i)
+				if err == nil {
+					slot.Group = group
+				}
+				results[ti][i].slot, results[ti][i].err = slot, err
+			}(ti, i, t.Name)
+		}
+	}
+	wg.Wait()
+
+	pools := make(Set, len(tenants))
+	var fatal error
+	for ti, t := range tenants {
+		var slots, canary []*Slot
+		var failed, canaryFailed []error
+		for i, r := range results[ti] {
+			switch {
+			case r.err != nil && i >= t.Size:
+				canaryFailed = append(canaryFailed, fmt.Errorf("灰度槽位 %d: %w", i, r.err))
+			case r.err != nil:
+				failed = append(failed, fmt.Errorf("槽位 %d: %w", i, r.err))
+			case i >= t.Size:
+				canary = append(canary, r.slot)
+			default:
+				slots = append(slots, r.slot)
+			}
+		}
+		if len(slots) == 0 {
+			slog.Error("租户页面池预热失败", "platform", name, "tenant", t.Name, "err", errors.Join(failed...))
+			if fatal == nil {
+				fatal = fmt.Errorf("租户 %s 页面池预热失败: %w", t.Name, errors.Join(failed...))
+			}
+		} else if len(failed) > 0 {
+			slog.Warn("部分页面预热失败，以剩余页面继续服务", "platform", name, "tenant", t.Name, "ready", len(slots), "failed", len(failed), "err", errors.Join(failed...))
+		}
+		pool := New(name, t.Name, slots)
+		pool.newSlot, pool.shedBudget = newSlot, t.ShedBudget
+		if len(canary) > 0 {
+			pool.canary, pool.canaryWeight = New(name, t.Name, canary), t.Canary.Weight
+			pool.canary.newSlot, pool.canary.stealthPath, pool.canary.shedBudget = newSlot, t.Canary.StealthPath, t.ShedBudget
+			slog.Info("灰度 stealth.js 页面组就绪", "platform", name, "tenant", t.Name, "stealth_path", t.Canary.StealthPath, "pages", len(canary), "weight", t.Canary.Weight)
+		}
+		if len(canaryFailed) > 0 {
+			// 灰度页面失败不影响服务，全部失败时不分流
+			slog.Warn("部分灰度页面预热失败", "platform", name, "tenant", t.Name, "ready", len(canary), "failed", len(canaryFailed), "err", errors.Join(canaryFailed...))
+		}
+		pools[t.Name] = pool
+	}
+	if fatal != nil {
+		for _, p := range pools {
+			_ = p.Close()
+		}
+		return nil, fatal
+	}
+	slog.Info("页面池预热完成", "platform", name, "tenants", len(pools), "elapsed", time.Since(start))
+	return pools, nil
+}
+
+// canarySize 返回租户灰度页面的数量。
+func (t Tenant) canarySize() int {
+	if t.Canary == nil {
+		return 0
+	}
+	return t.Canary.Size
+}
+
+// TenantsFromEnv 根据平台环境生成各租户的页面数量，poolSize 为平台默认池大小（<=0 时使用 env.PoolSize）。
+func TenantsFromEnv(env *platform.Env, poolSize int) []Tenant {
+	if poolSize <= 0 {
+		poolSize = env.PoolSize
+	}
+	if poolSize <= 0 {
+		poolSize = 1
+	}
+	if len(env.Tenants) == 0 {
+		return []Tenant{{Name: config.DefaultTenant, Size: poolSize, Canary: NewCanary(env.CanaryStealthPath, env.CanaryWeight, poolSize), ShedBudget: env.ShedBudget}}
+	}
+	out := make([]Tenant, 0, len(env.Tenants))
+	for _, t := range env.Tenants {
+		size := t.PoolSize
+		if size <= 0 {
+			size = poolSize
+		}
+		out = append(out, Tenant{Name: t.Name, Size: size, Canary: NewCanary(env.CanaryStealthPath, env.CanaryWeight, size), ShedBudget: env.ShedBudget})
+	}
+	return out
+}
@@ -0,0 +1,31 @@
+package pagepool
+
+// RankFunc 返回槽位的分配优先级，值越大越优先，如账号的健康评分。
+type RankFunc func(*Slot) float64
+
+// SetRank 设置各租户页面池（含灰度页面组）分配空闲槽位时的优先级，rank 为 nil 时取最近归还的槽位。
+func (s Set) SetRank(rank RankFunc) {
+	for _, p := range s {
+		for _, g := range p.groups() {
+			g.mu.Lock()
+			g.rank = rank
+			g.mu.Unlock()
+		}
+	}
+}
+
+// takeRankedLocked 取出优先级最高的空闲槽位，优先级相同时取最近归还的。调用方须持有 p.mu 且 p.free 非空。
+func (p *Pool) takeRankedLocked() *Slot {
+	best := len(p.free) - 1
+	if p.rank != nil {
+		score := p.rank(p.free[best])
+		for i := best - 1; i >= 0; i-- {
+			if r := p.rank(p.free[i]); r > score {
+				best, score = i, r
+			}
+		}
+	}
+	slot := p.free[best]
+	p.free = append(p.free[:best], p.free[best+1:]...)
+	return slot
+}
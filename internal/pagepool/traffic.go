@@ -0,0 +1,103 @@
+package pagepool
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/mxschmitt/playwright-go"
+	"go_sign/internal/metrics"
+)
+
+// 浏览器流量所属的阶段。
+const (
+	// PhaseWarmup 为预热：创建上下文、访问预热页面与首页，直至签名函数就绪。
+	PhaseWarmup = "warmup"
+	// PhaseReload 为签名函数丢失或页面离开站点后重新加载首页（见 Slot.Navigate）。
+	PhaseReload = "reload"
+	// PhaseIdle 为页面常驻期间（含签名）页面自身发出的请求，如埋点与轮询。
+	PhaseIdle = "idle"
+)
+
+// 浏览器流量指标，用于评估重新加载首页的开销与屏蔽图片、字体等资源的收益。
+// 接收字节数取自响应的 Content-Length（压缩后的传输大小），没有该响应头的响应计入 go_sign_browser_unsized_responses_total。
+var (
+	browserRequests = metrics.Default.NewCounterVec(
+		"go_sign_browser_requests_total",
+		"浏览器页面发出的请求数，phase 为 warmup、reload 或 idle，resource_type 为 document、script、xhr、image 等",
+		"platform", "phase", "resource_type",
+	)
+	browserRequestFailures = metrics.Default.NewCounterVec(
+		"go_sign_browser_request_failures_total",
+		"浏览器页面失败（网络错误、被取消）的请求数",
+		"platform", "phase", "resource_type",
+	)
+	browserReceivedBytes = metrics.Default.NewCounterVec(
+		"go_sign_browser_received_bytes_total",
+		"浏览器页面收到的响应体字节数（Content-Length）",
+		"platform", "phase", "resource_type",
+	)
+	browserSentBytes = metrics.Default.NewCounterVec(
+		"go_sign_browser_sent_bytes_total",
+		"浏览器页面发出的请求体字节数",
+		"platform", "phase",
+	)
+	browserUnsized = metrics.Default.NewCounterVec(
+		"go_sign_browser_unsized_responses_total",
+		"没有 Content-Length、未计入接收字节数的响应数（如分块传输）",
+		"platform", "resource_type",
+	)
+)
+
+// traffic 统计一个页面的请求与流量，phase 为当前阶段。
+type traffic struct {
+	platform string
+	phase    atomic.Value
+}
+
+// trackTraffic 在页面上监听请求与响应事件并计入浏览器流量指标，初始阶段为预热。
+// 只读取事件自带的请求与响应头，不在事件回调中向浏览器发起调用。
+func (s *Slot) trackTraffic(platform string) {
+	t := &traffic{platform: platform}
+	t.phase.Store(PhaseWarmup)
+	s.traffic = t
+	s.Page.On("request", func(req playwright.Request) {
+		phase := t.current()
+		browserRequests.Inc(platform, phase, req.ResourceType())
+		if body, err := req.PostDataBuffer(); err == nil && len(body) > 0 {
+			browserSentBytes.Add(float64(len(body)), platform, phase)
+		}
+	})
+	s.Page.On("requestfailed", func(req playwright.Request) {
+		browserRequestFailures.Inc(platform, t.current(), req.ResourceType())
+	})
+	s.Page.On("response", func(resp playwright.Response) {
+		resourceType := resp.Request().ResourceType()
+		size, err := strconv.ParseInt(resp.Headers()["content-length"], 10, 64)
+		if err != nil || size < 0 {
+			browserUnsized.Inc(platform, resourceType)
+			return
+		}
+		browserReceivedBytes.Add(float64(size), platform, t.current(), resourceType)
+	})
+}
+
+// current 返回当前阶段。
+func (t *traffic) current() string {
+	return t.phase.Load().(string)
+}
+
+// setPhase 切换页面流量的阶段，未统计流量的槽位不做任何事。
+func (s *Slot) setPhase(phase string) {
+	if s.traffic != nil {
+		s.traffic.phase.Store(phase)
+	}
+}
+
+// Navigate 将槽位页面重新加载到 o.URL 并等待就绪，期间页面的请求计入 reload 阶段。
+func (s *Slot) Navigate(ctx context.Context, o OpenOptions, log *slog.Logger) error {
+	s.setPhase(PhaseReload)
+	defer s.setPhase(PhaseIdle)
+	return Navigate(ctx, s.Page, o, log)
+}
@@ -0,0 +1,246 @@
+package pagepool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go_sign/internal/browser"
+)
+
+// browserKey 为 ctx 中覆盖 Open 所用浏览器的键，预热新一代页面（见 Set.Stage）及在其上重建槽位时写入。
+type browserKey struct{}
+
+// Generation 为一代页面所在的浏览器与注入的 stealth.js，StealthPath 为空时沿用平台配置。
+type Generation struct {
+	Browser     *browser.Browser
+	StealthPath string
+}
+
+// context 返回在本代浏览器上、注入本代 stealth.js 创建槽位的 ctx。
+func (g Generation) context(ctx context.Context) context.Context {
+	if g.Browser != nil {
+		ctx = context.WithValue(ctx, browserKey{}, g.Browser)
+	}
+	if g.StealthPath != "" {
+		ctx = context.WithValue(ctx, stealthKey{}, g.StealthPath)
+	}
+	return ctx
+}
+
+// GroupProbe 为验证 stealth.js 时临时创建的槽位分组，见 Set.Probe。
+const GroupProbe = "probe"
+
+// Probe 在当前浏览器上临时创建一个注入 stealthPath 的槽位（不加入页面池、不接收流量），
+// 以 check 验证该页面可以签名后关闭，用于发布前验证新版 stealth.js。
+func (s Set) Probe(ctx context.Context, stealthPath string, check func(*Slot) error) error {
+	for tenant, p := range s {
+		p.mu.Lock()
+		newSlot, gen, id := p.newSlot, Generation{Browser: p.browser, StealthPath: stealthPath}, len(p.slots)
+		p.mu.Unlock()
+		if newSlot == nil {
+			return errors.New("页面池不支持创建槽位")
+		}
+		slot, err := newSlot(gen.context(ctx), tenant, id)
+		if err != nil {
+			return fmt.Errorf("创建验证页面失败: %w", err)
+		}
+		slot.Group = GroupProbe
+		defer slot.Close()
+		return check(slot)
+	}
+	return errors.New("页面池未初始化")
+}
+
+// RenameStealth 将注入 from 的页面组改为在重建槽位时注入 to，用于发布后将暂存的 stealth.js 改名为正式文件。
+func (s Set) RenameStealth(from, to string) {
+	for _, p := range s {
+		for _, g := range p.groups() {
+			g.mu.Lock()
+			if g.stealthPath == from {
+				g.stealthPath = to
+			}
+			g.mu.Unlock()
+		}
+	}
+}
+
+// Staged 为已在后台预热、尚未接收流量的新一代页面池，见 Set.Stage。
+type Staged struct {
+	current Set
+	next    Set
+}
+
+// Stage 在 gen 指定的浏览器上按当前各租户的页面数（含灰度页面组）预热一组新页面，不影响当前流量；
+// 通过返回值的 Commit 切换流量，Abort 丢弃。任一租户预热失败时关闭已预热的页面并返回错误。
+func (s Set) Stage(ctx context.Context, gen Generation, concurrency int) (*Staged, error) {
+	if len(s) == 0 {
+		return nil, errors.New("页面池未初始化")
+	}
+	var name string
+	var newSlot SlotFactory
+	tenants := make([]Tenant, 0, len(s))
+	for tenant, p := range s {
+		name, newSlot = p.name, p.newSlot
+		t := Tenant{Name: tenant, Size: len(p.Slots()), ShedBudget: p.shedBudget}
+		if p.canary != nil {
+			t.Canary = &Canary{StealthPath: p.canary.stealthPath, Weight: p.canaryWeight, Size: len(p.canary.Slots())}
+		}
+		tenants = append(tenants, t)
+	}
+	if newSlot == nil {
+		return nil, errors.New("页面池不支持重建槽位")
+	}
+	next, err := Warmup(gen.context(ctx), name, tenants, concurrency, newSlot)
+	if err != nil {
+		return nil, err
+	}
+	for tenant, p := range next {
+		// 灰度页面组全部失败时，切换后分流到灰度组的请求将无页面可用
+		if s[tenant].canary != nil && p.canary == nil {
+			_ = next.Close()
+			return nil, fmt.Errorf("租户 %s 灰度页面预热失败", tenant)
+		}
+		for _, g := range p.groups() {
+			g.browser = gen.Browser
+			if g == p && gen.StealthPath != "" {
+				g.stealthPath = gen.StealthPath
+			}
+		}
+	}
+	return &Staged{current: s, next: next}, nil
+}
+
+// Commit 将各租户的流量切换到新一代页面：空闲的旧页面立即关闭，正在签名的旧页面在归还时关闭，
+// 专属槽位的绑定按编号迁移到新页面。等待全部旧页面关闭后返回，ctx 结束时不再等待并返回错误，
+// 剩余的旧页面仍会在归还时关闭。
+func (st *Staged) Commit(ctx context.Context) error {
+	var pending []*Pool
+	for tenant, p := range st.current {
+		next := st.next[tenant]
+		p.adopt(next)
+		pending = append(pending, p)
+		if p.canary != nil {
+			p.canary.adopt(next.canary)
+			pending = append(pending, p.canary)
+		}
+	}
+	for {
+		remaining := 0
+		for _, p := range pending {
+			p.mu.Lock()
+			remaining += p.draining
+			p.mu.Unlock()
+		}
+		if remaining == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("等待 %d 个旧页面签名完成失败: %w", remaining, ctx.Err())
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// Abort 丢弃预热的新一代页面。
+func (st *Staged) Abort() {
+	_ = st.next.Close()
+}
+
+// adopt 以 next 的槽位替换本组槽位（不含灰度页面组），next 此后不再使用。
+// 空闲的旧槽位立即关闭；正在使用的旧槽位标记为退役，由 Release 关闭。
+func (p *Pool) adopt(next *Pool) {
+	p.mu.Lock()
+	idle := make(map[*Slot]bool, len(p.slots))
+	for _, slot := range p.free {
+		idle[slot] = true
+	}
+	for _, slot := range p.resting {
+		idle[slot] = true
+	}
+	for _, ch := range p.dedicated {
+		select {
+		case slot := <-ch:
+			idle[slot] = true
+		default:
+		}
+	}
+	var closing []*Slot
+	for _, slot := range p.slots {
+		if idle[slot] {
+			closing = append(closing, slot)
+			continue
+		}
+		slot.retired = true
+		p.draining++
+	}
+	retired := len(p.slots) - len(closing)
+
+	p.slots, p.free = next.slots, next.free
+	p.newSlot, p.stealthPath, p.browser = next.newSlot, next.stealthPath, next.browser
+	p.recreating = nil
+	// 冷却针对旧页面的账号，新页面不继承
+	p.benched, p.resting = nil, nil
+	// 专属槽位沿用原通道，等待中的绑定请求可直接取得新槽位
+	for key, id := range p.bindings {
+		ch := p.dedicated[id]
+		if slot := p.takeFreeLocked(id); slot != nil {
+			ch <- slot
+			continue
+		}
+		delete(p.bindings, key)
+		delete(p.dedicated, id)
+		slog.Warn("新页面中没有对应的专属槽位，已解除绑定", "platform", p.name, "tenant", p.tenant, "api_key", key, "slot", id)
+	}
+	// 将新的空闲槽位交给排队中的请求
+	for len(p.free) > 0 {
+		w := p.popWaiterLocked()
+		if w == nil {
+			break
+		}
+		n := len(p.free)
+		w.ch <- p.free[n-1]
+		p.free = p.free[:n-1]
+	}
+	p.mu.Unlock()
+
+	for _, slot := range closing {
+		_ = slot.Close()
+	}
+	slog.Info("页面池已切换到新页面", "platform", p.name, "tenant", p.tenant, "pages", len(next.slots), "closed", len(closing), "draining", retired)
+}
+
+// takeFreeLocked 从空闲槽位中取出编号为 id 的槽位，不存在时返回 nil。调用方须持有 p.mu。
+func (p *Pool) takeFreeLocked(id int) *Slot {
+	for i, slot := range p.free {
+		if slot.ID == id {
+			p.free = append(p.free[:i], p.free[i+1:]...)
+			return slot
+		}
+	}
+	return nil
+}
+
+// popWaiterLocked 取出优先级最高的最早等待者，没有等待者时返回 nil。调用方须持有 p.mu。
+func (p *Pool) popWaiterLocked() *waiter {
+	for prio := len(p.waiters) - 1; prio >= 0; prio-- {
+		if q := p.waiters[prio]; len(q) > 0 {
+			p.waiters[prio] = q[1:]
+			return q[0]
+		}
+	}
+	return nil
+}
+
+// retire 关闭已退役的槽位 slot，并通知等待旧页面关闭的 Commit。
+func (p *Pool) retire(slot *Slot) {
+	start := time.Now()
+	_ = slot.Close()
+	p.mu.Lock()
+	p.draining--
+	p.mu.Unlock()
+	slog.Debug("已关闭退役的旧页面", "platform", p.name, "context_id", slot.ContextID(), "elapsed", time.Since(start))
+}
@@ -0,0 +1,149 @@
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/auth"
+)
+
+// Cooldown 描述一个因站点风控（如小红书返回 461、406）暂停签名的账号。
+type Cooldown struct {
+	// Account 为脱敏后的账号标识。
+	Account string `json:"account"`
+	// Status 为触发冷却的上游响应状态码，Count 为冷却前窗口内的风控响应数。
+	Status int       `json:"status"`
+	Count  int       `json:"count"`
+	Until  time.Time `json:"until"`
+}
+
+// AccountHealth 为账号的健康评分，评分为上游响应成功率的加权平均，取值 [0, 1]。
+type AccountHealth struct {
+	// Account 为脱敏后的账号标识。
+	Account   string  `json:"account"`
+	Score     float64 `json:"score"`
+	Successes int     `json:"successes"`
+	Failures  int     `json:"failures"`
+	// Degraded 为 true 时评分过低，只在没有更健康的账号可用时使用。
+	Degraded bool `json:"degraded"`
+	// CooldownUntil 为冷却结束时间，未冷却时为空。
+	CooldownUntil *time.Time `json:"cooldown_until,omitempty"`
+	// Usage 为账号的签名用量，没有签名记录时为空。
+	Usage *AccountUsage `json:"usage,omitempty"`
+}
+
+// AccountLimits 为单个账号在当前分钟、小时、天内的签名次数上限，0 表示该窗口不限制。
+type AccountLimits struct {
+	PerMinute int
+	PerHour   int
+	PerDay    int
+}
+
+// AccountUsage 为账号的签名用量：Minute、Hour、Day 为当前自然分钟、小时、天内的签名次数，
+// 其余字段为服务启动以来的累计值，耗时为签名函数的执行时间（毫秒）。
+type AccountUsage struct {
+	Minute int `json:"minute"`
+	Hour   int `json:"hour"`
+	Day    int `json:"day"`
+	Signs  int `json:"signs"`
+	Errors int `json:"errors"`
+	// Limited 为因超出 AccountLimits 被拒绝的签名请求数。
+	Limited      int       `json:"limited"`
+	AvgLatencyMs float64   `json:"avg_latency_ms"`
+	MaxLatencyMs float64   `json:"max_latency_ms"`
+	LastUsed     time.Time `json:"last_used"`
+}
+
+// AccountReporter 为可根据上游接口的响应评估账号健康的平台：评分更高的账号优先用于签名，
+// 短时间内多次收到风控状态码的账号进入冷却，冷却期间不再使用该账号签名，请求分配到其他账号的页面。
+type AccountReporter interface {
+	// ReportStatus 记录使用账号 account 签名的请求在上游得到的 HTTP 状态码。
+	ReportStatus(account string, status int)
+	// Cooldowns 返回冷却中的账号。
+	Cooldowns() []Cooldown
+	// Accounts 返回各账号的健康评分。
+	Accounts() []AccountHealth
+}
+
+// ErrNotReportable 表示平台未启用或不支持上报上游响应。
+var ErrNotReportable = errors.New("平台未启用或不支持上报上游响应")
+
+// ReportStatus 将账号 account 的上游响应状态码 status 交给平台 name 处理。
+func (s *Set) ReportStatus(name, account string, status int) error {
+	for i, p := range s.platforms {
+		if p.Name() != name {
+			continue
+		}
+		if r, ok := s.base[i].(AccountReporter); ok {
+			r.ReportStatus(account, status)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrNotReportable, name)
+}
+
+// AccountsHandler 返回列出各平台账号健康评分与签名用量的管理接口 GET /accounts/health，
+// 默认按评分升序；sort=usage 时按当天签名次数降序，便于在账号被封前找出用量过高的账号。
+func (s *Set) AccountsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		byUsage := false
+		switch c.Query("sort") {
+		case "", "score":
+		case "usage":
+			byUsage = true
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "sort 须为 score 或 usage"})
+			return
+		}
+		out := make(map[string][]AccountHealth)
+		for i, p := range s.platforms {
+			r, ok := s.base[i].(AccountReporter)
+			if !ok {
+				continue
+			}
+			list := r.Accounts()
+			if byUsage {
+				sort.SliceStable(list, func(i, j int) bool { return usageDay(list[i]) > usageDay(list[j]) })
+			}
+			out[p.Name()] = list
+		}
+		c.JSON(http.StatusOK, gin.H{"accounts": out})
+	}
+}
+
+// usageDay 返回账号当天的签名次数，没有用量记录时为 0。
+func usageDay(h AccountHealth) int {
+	if h.Usage == nil {
+		return 0
+	}
+	return h.Usage.Day
+}
+
+// reportRequest 为上报上游响应接口的请求体。
+type reportRequest struct {
+	Account string `json:"account" binding:"required"`
+	Status  int    `json:"status" binding:"required"`
+}
+
+// ReportStatusHandler 返回上报上游响应的管理接口 POST /accounts/:platform/status，
+// 请求体为 {"account": 账号标识, "status": 上游 HTTP 状态码}，供转发签名请求的代理回传结果。
+func (s *Set) ReportStatusHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req reportRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "参数解析失败: " + err.Error()})
+			return
+		}
+		if err := s.ReportStatus(c.Param("platform"), req.Account, req.Status); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		slog.Debug("管理接口上报上游响应", "platform", c.Param("platform"), "a1", req.Account, "status", req.Status, "operator", auth.FromContext(c).Name)
+		c.Status(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,138 @@
+package platform
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/audit"
+	"go_sign/internal/auth"
+)
+
+// Binding 为一个 API Key 与专属浏览器上下文的绑定。
+type Binding struct {
+	Platform  string `json:"platform"`
+	Tenant    string `json:"tenant"`
+	APIKey    string `json:"api_key"`
+	ContextID string `json:"context_id"`
+}
+
+// Binder 为支持将 API Key 绑定到专属浏览器上下文（及其账号）的平台，
+// 绑定后该 Key 的请求只在该上下文上签名，cookie 与指纹保持稳定，其他调用方不再使用该上下文。
+type Binder interface {
+	// Bind 将租户 tenant 的 API Key key 绑定到编号为 slot 的页面槽位。
+	Bind(ctx context.Context, tenant, key string, slot int) error
+	// Unbind 解除绑定，未绑定时不做任何事。
+	Unbind(ctx context.Context, tenant, key string) error
+	// Bindings 返回当前全部绑定，Platform 可为空。
+	Bindings() []Binding
+}
+
+// bindTimeout 为管理接口等待槽位空闲的最长时间。
+const bindTimeout = 30 * time.Second
+
+// binder 返回名为 name 且支持绑定的平台。
+func (s *Set) binder(name string) Binder {
+	for i, p := range s.platforms {
+		if p.Name() != name {
+			continue
+		}
+		if b, ok := s.base[i].(Binder); ok {
+			return b
+		}
+	}
+	return nil
+}
+
+// bindingOf 返回 API Key key 当前的绑定，未绑定时返回 nil。
+func bindingOf(b Binder, key *auth.Key) *Binding {
+	for _, binding := range b.Bindings() {
+		if binding.Tenant == key.Tenant.Name && binding.APIKey == key.Name {
+			return &binding
+		}
+	}
+	return nil
+}
+
+// bindRequest 为绑定接口的请求体。
+type bindRequest struct {
+	Slot *int `json:"slot" binding:"required"`
+}
+
+// RegisterBindingRoutes 注册 API Key 专属上下文的管理接口，绑定只保存在内存中，重启后需重新绑定：
+//   - GET /bindings：列出全部绑定；
+//   - PUT /bindings/:platform/:key：将 API Key 绑定到请求体 {"slot": 编号} 指定的槽位；
+//   - DELETE /bindings/:platform/:key：解除绑定。
+func (s *Set) RegisterBindingRoutes(router gin.IRouter, keyring *auth.Keyring) {
+	router.GET("/bindings", func(c *gin.Context) {
+		out := []Binding{}
+		for i, p := range s.platforms {
+			b, ok := s.base[i].(Binder)
+			if !ok {
+				continue
+			}
+			for _, binding := range b.Bindings() {
+				binding.Platform = p.Name()
+				out = append(out, binding)
+			}
+		}
+		c.JSON(http.StatusOK, gin.H{"bindings": out})
+	})
+	router.PUT("/bindings/:platform/:key", func(c *gin.Context) {
+		b, key, ok := s.bindingTarget(c, keyring)
+		if !ok {
+			return
+		}
+		var req bindRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "参数解析失败: " + err.Error()})
+			return
+		}
+		prev := bindingOf(b, key)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), bindTimeout)
+		defer cancel()
+		if err := b.Bind(ctx, key.Tenant.Name, key.Name, *req.Slot); err != nil {
+			slog.Warn("绑定专属槽位失败", "err", err, "platform", c.Param("platform"), "api_key", key.Name, "slot", *req.Slot)
+			c.JSON(http.StatusConflict, gin.H{"error": "绑定失败: " + err.Error()})
+			return
+		}
+		audit.SetDiff(c, prev, bindingOf(b, key))
+		slog.Info("管理接口绑定专属槽位", "platform", c.Param("platform"), "api_key", key.Name, "slot", *req.Slot, "operator", auth.FromContext(c).Name)
+		c.JSON(http.StatusOK, gin.H{"platform": c.Param("platform"), "api_key": key.Name, "slot": *req.Slot})
+	})
+	router.DELETE("/bindings/:platform/:key", func(c *gin.Context) {
+		b, key, ok := s.bindingTarget(c, keyring)
+		if !ok {
+			return
+		}
+		prev := bindingOf(b, key)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), bindTimeout)
+		defer cancel()
+		if err := b.Unbind(ctx, key.Tenant.Name, key.Name); err != nil {
+			slog.Warn("解除专属槽位绑定失败", "err", err, "platform", c.Param("platform"), "api_key", key.Name)
+			c.JSON(http.StatusConflict, gin.H{"error": "解除绑定失败: " + err.Error()})
+			return
+		}
+		audit.SetDiff(c, prev, nil)
+		slog.Info("管理接口解除专属槽位绑定", "platform", c.Param("platform"), "api_key", key.Name, "operator", auth.FromContext(c).Name)
+		c.Status(http.StatusNoContent)
+	})
+}
+
+// bindingTarget 解析路径中的平台与 API Key 名称，不存在时写入 404 并返回 ok 为 false。
+func (s *Set) bindingTarget(c *gin.Context, keyring *auth.Keyring) (Binder, *auth.Key, bool) {
+	b := s.binder(c.Param("platform"))
+	if b == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "平台未启用或不支持绑定: " + c.Param("platform")})
+		return nil, nil, false
+	}
+	for _, key := range keyring.Keys() {
+		if key.Name == c.Param("key") {
+			return b, key, true
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "API Key 不存在: " + c.Param("key")})
+	return nil, nil, false
+}
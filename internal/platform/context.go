@@ -0,0 +1,107 @@
+package platform
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/auth"
+	"go_sign/internal/config"
+)
+
+// Priority 为争用页面时的请求优先级，数值越大越优先。
+type Priority int
+
+// 请求优先级。
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+	NumPriorities
+)
+
+// ParsePriority 将 high、normal、low 解析为 Priority，空串视为 normal。
+func ParsePriority(s string) (Priority, error) {
+	switch s {
+	case "high":
+		return PriorityHigh, nil
+	case "", "normal":
+		return PriorityNormal, nil
+	case "low":
+		return PriorityLow, nil
+	}
+	return PriorityNormal, fmt.Errorf("不支持的优先级: %s", s)
+}
+
+// String 返回优先级名称，用于日志与指标标签。
+func (p Priority) String() string {
+	switch p {
+	case PriorityHigh:
+		return "high"
+	case PriorityLow:
+		return "low"
+	}
+	return "normal"
+}
+
+type priorityKey struct{}
+
+// WithPriority 返回携带请求优先级的 context。
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFrom 读取 context 中的请求优先级，未设置时为 normal。
+func PriorityFrom(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok && p >= 0 && p < NumPriorities {
+		return p
+	}
+	return PriorityNormal
+}
+
+type tenantKey struct{}
+
+// WithTenant 返回携带租户名的 context，平台据此选择租户资源。
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFrom 读取 context 中的租户名，未设置时为 config.DefaultTenant。
+func TenantFrom(ctx context.Context) string {
+	if t, ok := ctx.Value(tenantKey{}).(string); ok && t != "" {
+		return t
+	}
+	return config.DefaultTenant
+}
+
+type apiKeyKey struct{}
+
+// WithAPIKey 返回携带调用方 API Key 名称的 context。
+func WithAPIKey(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, apiKeyKey{}, name)
+}
+
+// APIKeyFrom 读取 context 中的 API Key 名称，未设置时为空。
+func APIKeyFrom(ctx context.Context) string {
+	name, _ := ctx.Value(apiKeyKey{}).(string)
+	return name
+}
+
+// CallerContext 返回中间件，将鉴权得到的租户、优先级与 API Key 名称，以及 X-Hedge 请求头写入请求 context，
+// 需放在鉴权中间件之后。
+func CallerContext() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := auth.FromContext(c)
+		prio, err := ParsePriority(key.Priority)
+		if err != nil {
+			slog.Warn("API Key 优先级无效，按 normal 处理", "err", err, "api_key", key.Name)
+		}
+		ctx := WithAPIKey(WithTenant(WithPriority(c.Request.Context(), prio), key.Tenant.Name), key.Name)
+		if hedgeRequested(c.GetHeader(HeaderHedge)) {
+			ctx = WithHedge(ctx)
+		}
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
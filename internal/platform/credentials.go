@@ -0,0 +1,90 @@
+package platform
+
+import (
+	"sort"
+	"sync"
+
+	"go_sign/internal/crypt"
+)
+
+// CredentialCookies 为 request_encryption.required 时必须加密传入的 cookie。
+var CredentialCookies = []string{"a1", "web_session"}
+
+// credentials 为解密请求中账号凭据的共享密钥，由 SetCredentialCipher 设置。
+var credentials struct {
+	mu       sync.RWMutex
+	cipher   crypt.Cipher
+	required bool
+}
+
+// SetCredentialCipher 设置解密请求中 cookie 的共享密钥，c 为 nil 时不接受加密的 cookie；
+// required 为 true 时拒绝明文传入的 CredentialCookies。
+func SetCredentialCipher(c crypt.Cipher, required bool) {
+	credentials.mu.Lock()
+	defer credentials.mu.Unlock()
+	credentials.cipher, credentials.required = c, required
+}
+
+// DecryptCookies 返回解密后的 cookie 副本：值为 crypt 密文格式（v1:<base64(nonce || ciphertext)>）的 cookie
+// 以共享密钥解密，其余原样保留。cookies 中无密文且不要求加密时原样返回。
+// 解密失败或要求加密时传入明文凭据，返回列出各 cookie 原因的 *ValidationError，字段名为 field 前缀加 cookie 名。
+func DecryptCookies(field string, cookies map[string]string) (map[string]string, error) {
+	credentials.mu.RLock()
+	cipher, required := credentials.cipher, credentials.required
+	credentials.mu.RUnlock()
+	verr := &ValidationError{}
+	if required {
+		for _, name := range CredentialCookies {
+			if v := cookies[name]; v != "" && !crypt.IsEncrypted(v) {
+				verr.Add(field+name, "须以共享密钥加密后传入")
+			}
+		}
+	}
+	names := make([]string, 0, len(cookies))
+	for name := range cookies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var out map[string]string
+	for _, name := range names {
+		v := cookies[name]
+		if !crypt.IsEncrypted(v) {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]string, len(cookies))
+			for k, v := range cookies {
+				out[k] = v
+			}
+		}
+		if cipher == nil {
+			verr.Add(field+name, "为密文，但服务未配置 request_encryption")
+			continue
+		}
+		plain, err := cipher.Decrypt(v)
+		if err != nil {
+			verr.Add(field+name, "%v", err)
+			continue
+		}
+		out[name] = string(plain)
+	}
+	if err := verr.Err(); err != nil {
+		return nil, err
+	}
+	if out == nil {
+		return cookies, nil
+	}
+	return out, nil
+}
+
+// EchoCookies 返回去掉密文值后的 cookie 副本，用于 ?format=headers、curl 等回显 cookie 的结果：
+// 调用方加密传入的凭据不以明文出现在应答中，由调用方自行附加。
+func EchoCookies(cookies map[string]string) map[string]string {
+	out := make(map[string]string, len(cookies))
+	for k, v := range cookies {
+		if !crypt.IsEncrypted(v) {
+			out[k] = v
+		}
+	}
+	return out
+}
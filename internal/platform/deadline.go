@@ -0,0 +1,56 @@
+package platform
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderTimeout 为调用方指定本次请求超时（毫秒）的请求头。
+const HeaderTimeout = "X-Timeout-Ms"
+
+// Deadline 返回为请求设置超时的中间件：超时取 X-Timeout-Ms 与 max 中较小者，未携带该请求头时为 max；
+// max 为 0 时不设默认超时，只使用请求头。请求头不是正整数时返回 400。
+// 超时后排队与签名立即失败，签名接口返回 504。
+func Deadline(max time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timeout := max
+		if v := c.GetHeader(HeaderTimeout); v != "" {
+			ms, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || ms <= 0 {
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "参数校验失败: " + HeaderTimeout + " 须为正整数（毫秒）"})
+				return
+			}
+			if d := time.Duration(ms) * time.Millisecond; max <= 0 || d < max {
+				timeout = d
+			}
+		}
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// WriteTimeout 返回为路由覆盖 http.Server.WriteTimeout 的中间件，用于批量任务、SSE 等耗时长于普通签名的接口：
+// 写超时改为从进入该中间件起 d 后，d 为 0 时不限制。
+func WriteTimeout(d time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var deadline time.Time
+		if d > 0 {
+			deadline = time.Now().Add(d)
+		}
+		if err := http.NewResponseController(c.Writer).SetWriteDeadline(deadline); err != nil {
+			slog.Warn("设置写超时失败", "err", err, "route", c.FullPath())
+		}
+		c.Next()
+	}
+}
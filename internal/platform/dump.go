@@ -0,0 +1,75 @@
+package platform
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/auth"
+)
+
+// dumpTimeout 为导出签名脚本的最长时间，含逐个下载外链脚本。
+const dumpTimeout = time.Minute
+
+// ScriptDump 为签名页面上的签名函数源码与已加载的脚本，供签名开始被拒时分析站点的算法变化。
+type ScriptDump struct {
+	// ContextID 为导出脚本的页面所在的浏览器上下文。
+	ContextID string `json:"context_id"`
+	// URL 为页面当前地址。
+	URL string `json:"url"`
+	// SignFunc 为签名函数名，SignFuncSource 为其源码（Function.prototype.toString 的结果）。
+	SignFunc       string `json:"sign_func"`
+	SignFuncSource string `json:"sign_func_source"`
+	// ScriptVersion 为签名脚本的版本指纹，见 ScriptVersioner。
+	ScriptVersion string   `json:"script_version,omitempty"`
+	Scripts       []Script `json:"scripts"`
+}
+
+// Script 为页面上的一个脚本。
+type Script struct {
+	// Src 为外链脚本的地址，内联脚本为空。
+	Src  string `json:"src,omitempty"`
+	Body string `json:"body,omitempty"`
+	// Error 为读取外链脚本失败的原因。
+	Error string `json:"error,omitempty"`
+}
+
+// ScriptDumper 为可导出签名页面脚本的平台：取一个空闲页面读取签名函数源码与已加载的脚本，
+// all 为 false 时只返回引用了签名函数的脚本。
+type ScriptDumper interface {
+	DumpScripts(ctx context.Context, all bool) (*ScriptDump, error)
+}
+
+// DumpScriptsHandler 返回导出签名页面脚本的管理接口 GET /scripts/:platform，
+// 可选参数 tenant 指定租户页面池（默认为默认租户），all=true 时返回页面上的全部脚本。
+func (s *Set) DumpScriptsHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var d ScriptDumper
+		for i, p := range s.platforms {
+			if p.Name() == c.Param("platform") {
+				d, _ = s.base[i].(ScriptDumper)
+			}
+		}
+		if d == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "平台未启用或不支持导出脚本: " + c.Param("platform")})
+			return
+		}
+		all, _ := strconv.ParseBool(c.Query("all"))
+		ctx, cancel := context.WithTimeout(c.Request.Context(), dumpTimeout)
+		defer cancel()
+		if tenant := c.Query("tenant"); tenant != "" {
+			ctx = WithTenant(ctx, tenant)
+		}
+		dump, err := d.DumpScripts(ctx, all)
+		if err != nil {
+			slog.Warn("导出签名脚本失败", "err", err, "platform", c.Param("platform"))
+			c.JSON(SignErrorStatus(c, err), gin.H{"error": "导出签名脚本失败: " + err.Error()})
+			return
+		}
+		slog.Info("管理接口导出签名脚本", "platform", c.Param("platform"), "context_id", dump.ContextID, "scripts", len(dump.Scripts), "operator", auth.FromContext(c).Name)
+		c.JSON(http.StatusOK, dump)
+	}
+}
@@ -0,0 +1,88 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrUnavailable 为签名暂时不可用的哨兵错误，如浏览器页面正在重建；HTTP 接口返回 503 与 Retry-After。
+var ErrUnavailable = errors.New("签名服务暂时不可用")
+
+// UnavailableError 为签名暂时不可用，RetryAfter 为预计恢复前的等待时间。
+type UnavailableError struct {
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("%s: %s，预计 %d 秒后恢复", ErrUnavailable, e.Reason, retryAfterSeconds(e.RetryAfter))
+}
+
+func (e *UnavailableError) Unwrap() error { return ErrUnavailable }
+
+// ErrOverloaded 表示签名队列排队超出预算，低优先级请求被提前拒绝；HTTP 接口返回 429。
+var ErrOverloaded = errors.New("签名队列过载，低优先级请求已被拒绝")
+
+// ErrInvalidResult 表示签名函数返回了空值或格式不符的结果，通常是页面状态异常或站点更换了签名算法；HTTP 接口返回 502。
+var ErrInvalidResult = errors.New("签名结果无效")
+
+// 错误应答中的 code：recovering 为签名暂时不可用，overloaded 为排队过载，调用方可据此按 Retry-After 重试；
+// invalid_result 为签名结果无效，不应将其用于请求。
+const (
+	ErrorCodeRecovering    = "recovering"
+	ErrorCodeOverloaded    = "overloaded"
+	ErrorCodeInvalidResult = "invalid_result"
+)
+
+// retryAfterSeconds 将等待时间向上取整为秒，至少 1 秒。
+func retryAfterSeconds(d time.Duration) int {
+	return max(1, int(math.Ceil(d.Seconds())))
+}
+
+// SignErrorStatus 返回签名失败时应答的 HTTP 状态码：暂时不可用为 503（同时设置 Retry-After 响应头），
+// 排队过载为 429，签名结果无效为 502，超过请求超时为 504，其余为 500。
+func SignErrorStatus(c *gin.Context, err error) int {
+	var uerr *UnavailableError
+	switch {
+	case errors.As(err, &uerr):
+		c.Header("Retry-After", strconv.Itoa(retryAfterSeconds(uerr.RetryAfter)))
+		return http.StatusServiceUnavailable
+	case errors.Is(err, ErrOverloaded):
+		c.Header("Retry-After", "1")
+		return http.StatusTooManyRequests
+	case errors.Is(err, ErrInvalidResult):
+		return http.StatusBadGateway
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusInternalServerError
+}
+
+// ErrorBody 返回错误应答的 JSON：{"error": err}；err 包装 ValidationError 时附带 fields，
+// 包装 UnavailableError 时附带 code 与 retry_after（秒），包装 ErrOverloaded 或 ErrInvalidResult 时附带 code。
+func ErrorBody(err error) gin.H {
+	body := gin.H{"error": err.Error()}
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		body["fields"] = verr.Fields
+	}
+	var uerr *UnavailableError
+	if errors.As(err, &uerr) {
+		body["code"] = ErrorCodeRecovering
+		body["retry_after"] = retryAfterSeconds(uerr.RetryAfter)
+	}
+	if errors.Is(err, ErrOverloaded) {
+		body["code"] = ErrorCodeOverloaded
+	}
+	if errors.Is(err, ErrInvalidResult) {
+		body["code"] = ErrorCodeInvalidResult
+	}
+	return body
+}
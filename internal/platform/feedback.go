@@ -0,0 +1,291 @@
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/auth"
+	"go_sign/internal/logging"
+	"go_sign/internal/metrics"
+)
+
+// 上游拒绝签名的状态码：小红书对签名校验未通过返回 406，对账号或设备风控返回 461。
+const (
+	statusRejected = 406
+	statusBanned   = 461
+)
+
+// Feedback 为调用方回传的签名结果：使用签名发出的请求最终在上游得到的 HTTP 状态码。
+type Feedback struct {
+	Platform string `json:"platform" binding:"required"`
+	Status   int    `json:"status" binding:"required"`
+	// A1 为签名使用的 a1（签名响应中的 a1），为空时只计入指标与签名脚本失效检测，不更新账号健康。
+	A1 string `json:"a1"`
+	// ScriptVersion 为签名响应中的 script_version，用于判断是哪个版本的签名脚本失效。
+	ScriptVersion string `json:"script_version"`
+	URI           string `json:"uri"`
+}
+
+// ErrUnknownPlatform 表示回传结果的平台未启用。
+var ErrUnknownPlatform = errors.New("平台未启用")
+
+// ErrNotIssued 表示回传结果中的 a1 近期未签发给调用方所属的租户，不能据此更新账号健康。
+var ErrNotIssued = errors.New("a1 近期未签发给调用方所属的租户")
+
+// ErrUnknownScriptVersion 表示回传结果中的 script_version 不是平台近期签名使用的版本。
+var ErrUnknownScriptVersion = errors.New("未知的 script_version")
+
+// 签发记录的保留时间与容量。
+const (
+	// issuedTTL 为签名结果可被回传的时间，超过后回传的 a1 与 script_version 视为未签发
+	issuedTTL = 24 * time.Hour
+	// maxIssued 为保留的 (平台, 租户, a1) 签发记录数上限，超出时先清理过期记录，仍超出时随机淘汰
+	maxIssued = 100000
+)
+
+// issuedKey 为一条 a1 签发记录。
+type issuedKey struct {
+	platform, tenant, identity string
+}
+
+// issuedLog 记录 issuedTTL 内签发给各租户的 a1 与平台签名使用的脚本版本，用于校验回传结果：
+// 调用方只能回传签发给本租户的 a1，script_version 只能为平台近期使用的版本，指标标签与统计维度不随回传内容增长。
+type issuedLog struct {
+	mu       sync.Mutex
+	accounts map[issuedKey]time.Time
+	versions map[feedbackKey]time.Time
+}
+
+// defaultIssued 为 ObserveSign 写入的全局签发记录。
+var defaultIssued = &issuedLog{
+	accounts: make(map[issuedKey]time.Time),
+	versions: make(map[feedbackKey]time.Time),
+}
+
+// add 记录签名结果 res 中签发给 tenant 的 a1 与签名脚本版本，res 为 nil 时不记录。
+func (l *issuedLog) add(platform, tenant string, res *SignResponse) {
+	if res == nil {
+		return
+	}
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if res.ScriptVersion != "" {
+		l.versions[feedbackKey{platform, res.ScriptVersion}] = now
+	}
+	if res.Source == nil || res.Source.Identity == "" {
+		return
+	}
+	if len(l.accounts) >= maxIssued {
+		l.pruneLocked(now)
+	}
+	l.accounts[issuedKey{platform, tenant, res.Source.Identity}] = now
+}
+
+// pruneLocked 清理过期的签发记录，a1 记录仍达到 maxIssued 时随机淘汰至上限以下。调用方须持有 l.mu。
+func (l *issuedLog) pruneLocked(now time.Time) {
+	for k, at := range l.accounts {
+		if now.Sub(at) > issuedTTL {
+			delete(l.accounts, k)
+		}
+	}
+	for k, at := range l.versions {
+		if now.Sub(at) > issuedTTL {
+			delete(l.versions, k)
+		}
+	}
+	for k := range l.accounts {
+		if len(l.accounts) < maxIssued {
+			break
+		}
+		delete(l.accounts, k)
+	}
+}
+
+// check 校验回传结果 fb：a1 非空时须在 issuedTTL 内签发给 tenant，script_version 非空时须为平台 issuedTTL 内使用的版本。
+func (l *issuedLog) check(tenant string, fb Feedback) error {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if fb.ScriptVersion != "" {
+		if at, ok := l.versions[feedbackKey{fb.Platform, fb.ScriptVersion}]; !ok || now.Sub(at) > issuedTTL {
+			return fmt.Errorf("%w: %q", ErrUnknownScriptVersion, fb.ScriptVersion)
+		}
+	}
+	if fb.A1 != "" {
+		if at, ok := l.accounts[issuedKey{fb.Platform, tenant, fb.A1}]; !ok || now.Sub(at) > issuedTTL {
+			return ErrNotIssued
+		}
+	}
+	return nil
+}
+
+// feedbackResult 将上游状态码归类为 ok（2xx、3xx）、rejected（406、461）或 other（与签名无关的错误）。
+func feedbackResult(status int) string {
+	switch {
+	case status < 400:
+		return "ok"
+	case status == statusRejected || status == statusBanned:
+		return "rejected"
+	}
+	return "other"
+}
+
+// feedbackTotal 统计调用方回传的签名结果。
+var feedbackTotal = metrics.Default.NewCounterVec(
+	"go_sign_feedback_total",
+	"调用方回传的签名结果数，result 为 ok、rejected（上游返回 406/461）或 other",
+	"platform", "script_version", "result",
+)
+
+// scriptRegression 为疑似失效的签名脚本版本，失效时为 1。
+var scriptRegression = metrics.Default.NewGaugeVec(
+	"go_sign_script_regression",
+	"签名脚本版本近期被上游拒绝的比例超过阈值时为 1",
+	"platform", "version",
+)
+
+// feedbackKey 为签名脚本失效检测的统计维度。
+type feedbackKey struct {
+	platform string
+	version  string
+}
+
+// feedbackEntry 为一次回传结果。
+type feedbackEntry struct {
+	at       time.Time
+	rejected bool
+}
+
+// feedbackLog 按平台与签名脚本版本保存 ErrorRateWindow 内的回传结果，用于检测签名脚本失效：
+// 至少 ErrorRateMinSamples 次回传且被上游拒绝的比例超过 ErrorRateThreshold 时视为该版本失效。
+type feedbackLog struct {
+	mu      sync.Mutex
+	entries map[feedbackKey][]feedbackEntry
+	suspect map[feedbackKey]bool
+}
+
+// defaultFeedback 为 Set.Feedback 写入的全局回传记录。
+var defaultFeedback = &feedbackLog{
+	entries: make(map[feedbackKey][]feedbackEntry),
+	suspect: make(map[feedbackKey]bool),
+}
+
+// pruneLocked 丢弃 key 在 since 之前的记录，返回剩余记录的总数与被拒绝数。调用方须持有 l.mu。
+func (l *feedbackLog) pruneLocked(key feedbackKey, since time.Time) (total, rejected int) {
+	entries := l.entries[key]
+	for len(entries) > 0 && entries[0].at.Before(since) {
+		entries = entries[1:]
+	}
+	if len(entries) == 0 {
+		delete(l.entries, key)
+		return 0, 0
+	}
+	l.entries[key] = entries
+	for _, e := range entries {
+		if e.rejected {
+			rejected++
+		}
+	}
+	return len(entries), rejected
+}
+
+// regressed 判断统计结果是否达到失效阈值。
+func regressed(total, rejected int) bool {
+	return total >= ErrorRateMinSamples && float64(rejected) > ErrorRateThreshold*float64(total)
+}
+
+// record 记录一次回传结果，签名脚本版本由正常转为失效或由失效恢复时记录日志并更新指标。
+func (l *feedbackLog) record(fb Feedback, rejected bool) {
+	now := time.Now()
+	key := feedbackKey{fb.Platform, fb.ScriptVersion}
+	l.mu.Lock()
+	l.entries[key] = append(l.entries[key], feedbackEntry{at: now, rejected: rejected})
+	total, n := l.pruneLocked(key, now.Add(-ErrorRateWindow))
+	was, is := l.suspect[key], regressed(total, n)
+	if is {
+		l.suspect[key] = true
+	} else {
+		delete(l.suspect, key)
+	}
+	l.mu.Unlock()
+
+	switch {
+	case is && !was:
+		scriptRegression.Set(1, fb.Platform, fb.ScriptVersion)
+		slog.Error("签名脚本疑似失效，上游拒绝比例过高", "platform", fb.Platform, "script_version", fb.ScriptVersion, "rejected", n, "total", total, "window", ErrorRateWindow)
+	case was && !is:
+		scriptRegression.Set(0, fb.Platform, fb.ScriptVersion)
+		slog.Info("签名脚本上游拒绝比例已恢复", "platform", fb.Platform, "script_version", fb.ScriptVersion, "rejected", n, "total", total)
+	}
+}
+
+// regression 返回平台 platform 近 ErrorRateWindow 内失效的签名脚本版本及其统计，没有时 ok 为 false。
+func (l *feedbackLog) regression(platform string) (version string, total, rejected int, ok bool) {
+	since := time.Now().Add(-ErrorRateWindow)
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for key := range l.entries {
+		if key.platform != platform {
+			continue
+		}
+		if t, r := l.pruneLocked(key, since); regressed(t, r) {
+			return key.version, t, r, true
+		}
+	}
+	return "", 0, 0, false
+}
+
+// Feedback 处理租户 tenant 的调用方回传的签名结果：计入指标与签名脚本失效检测，并交给平台更新账号健康（见 AccountReporter）。
+// a1 须为近期签发给该租户的账号，script_version 须为平台近期签名使用的版本，否则返回 ErrNotIssued 或 ErrUnknownScriptVersion，
+// 避免调用方冷却其他租户的账号或以任意版本号制造指标标签。
+func (s *Set) Feedback(tenant string, fb Feedback) error {
+	if s.Get(fb.Platform) == nil {
+		return fmt.Errorf("%w: %s", ErrUnknownPlatform, fb.Platform)
+	}
+	if err := defaultIssued.check(tenant, fb); err != nil {
+		return err
+	}
+	result := feedbackResult(fb.Status)
+	feedbackTotal.Inc(fb.Platform, fb.ScriptVersion, result)
+	if result != "other" {
+		defaultFeedback.record(fb, result == "rejected")
+	}
+	if fb.A1 != "" {
+		// 平台不支持账号健康时只计入指标
+		_ = s.ReportStatus(fb.Platform, fb.A1, fb.Status)
+	}
+	return nil
+}
+
+// FeedbackHandler 返回回传签名结果的接口 POST /feedback，请求体见 Feedback，成功时返回 204；
+// 平台未启用返回 404，a1 未签发给调用方所属租户返回 403，script_version 未知返回 400。
+func (s *Set) FeedbackHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var fb Feedback
+		if err := c.ShouldBindJSON(&fb); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "参数解析失败: " + err.Error()})
+			return
+		}
+		key := auth.FromContext(c)
+		if err := s.Feedback(key.Tenant.Name, fb); err != nil {
+			status := http.StatusBadRequest
+			switch {
+			case errors.Is(err, ErrUnknownPlatform):
+				status = http.StatusNotFound
+			case errors.Is(err, ErrNotIssued):
+				status = http.StatusForbidden
+			}
+			slog.Warn("签名结果回传被拒绝", "err", err, "platform", fb.Platform, "a1", logging.Mask(fb.A1), "api_key", key.Name, "tenant", key.Tenant.Name)
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
+		slog.Debug("收到签名结果回传", "platform", fb.Platform, "a1", logging.Mask(fb.A1), "status", fb.Status, "script_version", fb.ScriptVersion, "uri", fb.URI, "api_key", key.Name)
+		c.Status(http.StatusNoContent)
+	}
+}
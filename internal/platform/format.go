@@ -0,0 +1,147 @@
+package platform
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// 签名接口 format 查询参数的取值，为空时返回各平台默认的结果格式。
+const (
+	// FormatHeaders 返回可直接展开到请求上的扁平请求头与 cookie 字符串。
+	FormatHeaders = "headers"
+	// FormatCurl 返回携带签名的完整 curl 命令（纯文本），便于手工验证与反馈问题。
+	FormatCurl = "curl"
+)
+
+// CheckFormat 检查 format 查询参数是否受支持，空值表示默认格式。
+func CheckFormat(format string) error {
+	switch format {
+	case "", FormatHeaders, FormatCurl:
+		return nil
+	}
+	return fmt.Errorf("不支持的 format: %s", format)
+}
+
+// HeadersResponse 为 ?format=headers 的返回结果。
+type HeadersResponse struct {
+	// Headers 为签名请求头及签名页面的 user-agent。
+	Headers map[string]string `json:"headers"`
+	// Cookie 为请求应携带的 Cookie 请求头。
+	Cookie string `json:"cookie,omitempty"`
+	// Params 为需追加到查询串的签名参数。
+	Params map[string]string `json:"params,omitempty"`
+	// Order 为 Headers 的发送顺序，按请求头模板填充时取自站点前端的真实请求，为空时顺序不限。
+	Order []string `json:"order,omitempty"`
+	// Template 为填充 Headers 使用的请求头模板名称，见 HeaderTemplate。
+	Template string `json:"template,omitempty"`
+}
+
+// Headers 将签名结果整理为 HeadersResponse：合并签名请求头与 user-agent
+// （签名页面的 User-Agent 优先，其次为请求中的 UserAgent），cookies 按名称排序拼接。
+func Headers(req *SignRequest, res *SignResponse, cookies map[string]string) *HeadersResponse {
+	out := &HeadersResponse{Headers: make(map[string]string, len(res.Headers)+1), Params: res.Params}
+	for k, v := range res.Headers {
+		out.Headers[k] = v
+	}
+	ua := req.UserAgent
+	if res.Source != nil && res.Source.UserAgent != "" {
+		ua = res.Source.UserAgent
+	}
+	if _, ok := out.Headers["user-agent"]; !ok && ua != "" {
+		out.Headers["user-agent"] = ua
+	}
+	out.Cookie = CookieString(cookies)
+	return out
+}
+
+// CookieString 将 cookies 按名称排序拼接为 Cookie 请求头，忽略空值。
+func CookieString(cookies map[string]string) string {
+	names := make([]string, 0, len(cookies))
+	for name, v := range cookies {
+		if v != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + "=" + cookies[name]
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Curl 返回携带签名的完整 curl 命令。uri 为相对路径时以 base 为前缀；
+// 查询串依次为 uri 自带的查询串、请求的 params 与签名参数，请求头与 cookie 取自 h，
+// 请求头先按 h.Order 排列，其余按名称排序；请求体为 data（字符串原样发送，其余按 JSON 序列化）。
+func Curl(req *SignRequest, h *HeadersResponse, base string) (string, error) {
+	target := req.URI
+	if !strings.Contains(target, "://") {
+		target = strings.TrimRight(base, "/") + target
+	}
+	for _, params := range []map[string]string{req.Params, h.Params} {
+		if len(params) == 0 {
+			continue
+		}
+		values := make(url.Values, len(params))
+		for k, v := range params {
+			values.Set(k, v)
+		}
+		sep := "?"
+		if strings.Contains(target, "?") {
+			sep = "&"
+		}
+		target += sep + values.Encode()
+	}
+
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+		if req.Data != nil {
+			method = http.MethodPost
+		}
+	}
+	lines := []string{"curl -X " + method + " " + shellQuote(target)}
+	names := make([]string, 0, len(h.Headers))
+	for name := range h.Headers {
+		if !slices.Contains(h.Order, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range h.Order {
+		if _, ok := h.Headers[name]; ok {
+			lines = append(lines, "-H "+shellQuote(name+": "+h.Headers[name]))
+		}
+	}
+	for _, name := range names {
+		lines = append(lines, "-H "+shellQuote(name+": "+h.Headers[name]))
+	}
+	if h.Cookie != "" {
+		lines = append(lines, "-H "+shellQuote("cookie: "+h.Cookie))
+	}
+	if req.Data != nil {
+		body, ok := req.Data.(string)
+		if !ok {
+			raw, err := json.Marshal(req.Data)
+			if err != nil {
+				return "", fmt.Errorf("data 参数序列化失败: %w", err)
+			}
+			body = string(raw)
+			if _, ok := h.Headers["content-type"]; !ok {
+				lines = append(lines, "-H "+shellQuote("content-type: application/json;charset=UTF-8"))
+			}
+		}
+		lines = append(lines, "--data-raw "+shellQuote(body))
+	}
+	return strings.Join(lines, " \\\n  "), nil
+}
+
+// shellQuote 以单引号包裹 s，供 POSIX shell 原样使用。
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
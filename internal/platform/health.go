@@ -0,0 +1,195 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/browser"
+	"go_sign/internal/metrics"
+	"go_sign/internal/report"
+)
+
+// Health 为平台的健康状态。
+type Health string
+
+// 健康状态，按严重程度递增。
+const (
+	// HealthOK 表示平台完全可用。
+	HealthOK Health = "ok"
+	// HealthDegraded 表示平台仍可签名但能力下降，如部分页面组不可用、近期失败率过高。
+	HealthDegraded Health = "degraded"
+	// HealthDown 表示平台不可用。
+	HealthDown Health = "down"
+)
+
+// healthStates 为全部健康状态，用于设置指标。
+var healthStates = []Health{HealthOK, HealthDegraded, HealthDown}
+
+// ErrDegraded 为降级的哨兵错误：HealthCheck 返回包装它的错误时平台视为降级而非不可用。
+var ErrDegraded = errors.New("服务降级")
+
+// 近期失败率的判定参数：ErrorRateWindow 内至少 ErrorRateMinSamples 次签名且失败比例超过 ErrorRateThreshold 时视为降级。
+const (
+	ErrorRateWindow     = 5 * time.Minute
+	ErrorRateMinSamples = 20
+	ErrorRateThreshold  = 0.5
+)
+
+// healthGauge 为各平台当前的健康状态，当前状态为 1，其余为 0。
+var healthGauge = metrics.Default.NewGaugeVec(
+	"go_sign_health",
+	"平台健康状态，state 为 ok、degraded 或 down，当前状态为 1",
+	"platform", "state",
+)
+
+// HealthOf 将 HealthCheck 的结果映射为健康状态：nil 为 ok，包装 ErrDegraded 为 degraded，其余为 down。
+func HealthOf(err error) Health {
+	switch {
+	case err == nil:
+		return HealthOK
+	case errors.Is(err, ErrDegraded):
+		return HealthDegraded
+	}
+	return HealthDown
+}
+
+// worse 返回 a、b 中更严重的状态。
+func worse(a, b Health) Health {
+	if a == HealthDown || b == HealthDown {
+		return HealthDown
+	}
+	if a == HealthDegraded || b == HealthDegraded {
+		return HealthDegraded
+	}
+	return HealthOK
+}
+
+// PlatformStatus 为单个平台的健康状态。
+type PlatformStatus struct {
+	Name  string `json:"name"`
+	State Health `json:"state"`
+	// Reason 为非 ok 时的原因。
+	Reason string `json:"reason,omitempty"`
+	// Signed 为自启动以来是否已成功签名（含自检签名）。
+	Signed bool `json:"signed"`
+	// ScriptVersions 为各签名脚本版本（见 ScriptVersioner）及加载该版本的页面数，多个版本并存说明站点正在更新签名算法。
+	ScriptVersions map[string]int `json:"script_versions,omitempty"`
+	// Cooldowns 为因上游风控冷却中的账号，见 AccountReporter。
+	Cooldowns []Cooldown `json:"cooldowns,omitempty"`
+}
+
+// ScriptVersioner 为可识别页面所加载签名脚本版本的平台，返回各版本的页面数。
+type ScriptVersioner interface {
+	ScriptVersions() map[string]int
+}
+
+// Status 为服务整体的健康状态，State 取各平台中最严重的状态。
+type Status struct {
+	State     Health           `json:"state"`
+	Platforms []PlatformStatus `json:"platforms"`
+	// Browser 为 Playwright 驱动与浏览器的版本，浏览器未启动时为空。
+	Browser *browser.Versions `json:"browser,omitempty"`
+}
+
+// Status 检查全部平台并结合近期失败率与调用方回传的上游拒绝率得出健康状态，同时更新 go_sign_health 指标。
+func (s *Set) Status(ctx context.Context) Status {
+	st := Status{State: HealthOK}
+	if s.browser != nil {
+		st.Browser = s.browser.Versions()
+	}
+	since := time.Now().Add(-ErrorRateWindow)
+	for i, p := range s.platforms {
+		err := p.HealthCheck(ctx)
+		if err == nil {
+			if total, failed := DefaultHistory.Failures(p.Name(), since); total >= ErrorRateMinSamples && float64(failed) > ErrorRateThreshold*float64(total) {
+				err = fmt.Errorf("%w: 近 %s 内签名失败率 %d/%d", ErrDegraded, ErrorRateWindow, failed, total)
+			}
+		}
+		if err == nil {
+			if version, total, rejected, ok := defaultFeedback.regression(p.Name()); ok {
+				err = fmt.Errorf("%w: 签名脚本 %q 疑似失效，近 %s 内回传结果被上游拒绝 %d/%d", ErrDegraded, version, ErrorRateWindow, rejected, total)
+			}
+		}
+		ps := PlatformStatus{Name: p.Name(), State: HealthOf(err), Signed: hasSigned(p.Name())}
+		if err != nil {
+			ps.Reason = err.Error()
+		}
+		if v, ok := s.base[i].(ScriptVersioner); ok {
+			ps.ScriptVersions = v.ScriptVersions()
+		}
+		if r, ok := s.base[i].(AccountReporter); ok {
+			ps.Cooldowns = r.Cooldowns()
+		}
+		for _, state := range healthStates {
+			v := 0.0
+			if state == ps.State {
+				v = 1
+			}
+			healthGauge.Set(v, ps.Name, string(state))
+		}
+		st.State = worse(st.State, ps.State)
+		st.Platforms = append(st.Platforms, ps)
+	}
+	return st
+}
+
+// StatusHandler 返回健康详情接口，始终返回 200 与各平台的状态及原因。
+func (s *Set) StatusHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, s.Status(c.Request.Context()))
+	}
+}
+
+// ReadyHandler 返回就绪检查接口，供负载均衡摘除实例：ok 与 degraded 时返回 200，down 时返回 503；
+// 任一平台自启动以来尚未成功签名（含自检签名，见 SelfTest）时同样返回 503，
+// 避免签名 JS 未能加载的实例接收流量。
+func (s *Set) ReadyHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if pending := s.unsigned(); len(pending) > 0 {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"state": "starting", "reason": "尚未成功签名", "platforms": pending})
+			return
+		}
+		st := s.Status(c.Request.Context())
+		code := http.StatusOK
+		if st.State == HealthDown {
+			code = http.StatusServiceUnavailable
+		}
+		c.JSON(code, gin.H{"state": st.State})
+	}
+}
+
+// Monitor 每隔 interval 检查一次健康状态，直至 ctx 取消：保持 go_sign_health 指标最新，
+// 平台转为 down 时上报错误，转为 degraded 时记录告警日志，恢复时记录日志。
+func (s *Set) Monitor(ctx context.Context, interval time.Duration) {
+	last := make(map[string]Health)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		for _, ps := range s.Status(ctx).Platforms {
+			prev, seen := last[ps.Name]
+			last[ps.Name] = ps.State
+			if ps.State == prev || (!seen && ps.State == HealthOK) {
+				continue
+			}
+			switch ps.State {
+			case HealthDown:
+				slog.Error("平台不可用", "platform", ps.Name, "reason", ps.Reason, "prev", prev)
+				report.Error("平台不可用", errors.New(ps.Reason), "platform", ps.Name)
+			case HealthDegraded:
+				slog.Warn("平台降级", "platform", ps.Name, "reason", ps.Reason, "prev", prev)
+			default:
+				slog.Info("平台恢复健康", "platform", ps.Name, "prev", prev)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package platform
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"go_sign/internal/metrics"
+)
+
+// HeaderHedge 为调用方开启对冲签名的请求头，值为 1 或 true 时开启（须同时配置 --hedge-delay）。
+const HeaderHedge = "X-Hedge"
+
+// hedgeResults 统计开启对冲的签名请求，outcome 为 not_hedged（对冲延迟内已返回）、
+// primary（已对冲，原请求先完成）或 hedge（已对冲，对冲请求先完成）。
+var hedgeResults = metrics.Default.NewCounterVec(
+	"go_sign_hedge_total",
+	"开启对冲的签名请求数，outcome 为 not_hedged、primary 或 hedge",
+	"platform", "outcome",
+)
+
+type hedgeKey struct{}
+
+// WithHedge 返回开启对冲签名的 context。
+func WithHedge(ctx context.Context) context.Context {
+	return context.WithValue(ctx, hedgeKey{}, true)
+}
+
+// HedgeFrom 判断 context 是否开启了对冲签名。
+func HedgeFrom(ctx context.Context) bool {
+	on, _ := ctx.Value(hedgeKey{}).(bool)
+	return on
+}
+
+// hedgeRequested 解析 X-Hedge 请求头，无法解析时视为未开启。
+func hedgeRequested(v string) bool {
+	on, _ := strconv.ParseBool(v)
+	return on
+}
+
+// hedging 对开启对冲的请求，在 delay 内未返回时于另一页面再签名一次，取先成功的结果。
+type hedging struct {
+	Platform
+	delay time.Duration
+}
+
+// EnableHedging 为集合中的全部平台开启对冲签名，delay 为 0 时不开启。
+// 须在 EnableVerification 之前、Init 之前调用，使对冲的两次签名不重复触发双跑校验。
+func (s *Set) EnableHedging(delay time.Duration) {
+	if delay <= 0 {
+		return
+	}
+	s.Wrap(func(p Platform) Platform { return &hedging{Platform: p, delay: delay} })
+	slog.Info("开启对冲签名", "delay", delay)
+}
+
+// Unwrap 返回被包装的平台。
+func (h *hedging) Unwrap() Platform { return h.Platform }
+
+// Sign 调用原平台签名；请求开启对冲且 delay 内未返回时，再发起一次签名（另取空闲页面），
+// 返回先成功的结果并取消另一次。两次都失败时返回后完成的错误。
+func (h *hedging) Sign(ctx context.Context, req *SignRequest) (*SignResponse, error) {
+	if !HedgeFrom(ctx) {
+		return h.Platform.Sign(ctx, req)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	type result struct {
+		res   *SignResponse
+		err   error
+		hedge bool
+	}
+	ch := make(chan result, 2)
+	run := func(hedge bool) {
+		// 平台可能修改请求，两次签名各用一份副本
+		r := *req
+		res, err := h.Platform.Sign(ctx, &r)
+		ch <- result{res, err, hedge}
+	}
+	go run(false)
+
+	timer := time.NewTimer(h.delay)
+	defer timer.Stop()
+	select {
+	case r := <-ch:
+		hedgeResults.Inc(h.Name(), "not_hedged")
+		return r.res, r.err
+	case <-timer.C:
+	}
+	go run(true)
+	first := <-ch
+	if first.err != nil {
+		// 先完成的失败时等待另一次
+		first = <-ch
+	}
+	outcome := "primary"
+	if first.hedge {
+		outcome = "hedge"
+	}
+	hedgeResults.Inc(h.Name(), outcome)
+	return first.res, first.err
+}
@@ -0,0 +1,178 @@
+package platform
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go_sign/internal/metrics"
+	"go_sign/internal/storage"
+)
+
+// SignRecord 为一次签名请求的记录。
+type SignRecord struct {
+	Time     time.Time `json:"time"`
+	Platform string    `json:"platform"`
+	Tenant   string    `json:"tenant"`
+	APIKey   string    `json:"api_key"`
+	URI      string    `json:"uri"`
+	// Account 为签名页面脱敏后的账号标识，见 AccountOf，平台未提供时为空。
+	Account  string        `json:"account,omitempty"`
+	Duration time.Duration `json:"duration"`
+	// Err 为签名失败的原因，成功时为空。
+	Err string `json:"err,omitempty"`
+	// Result 为签名结果，ObserveSign 据此记录签发给租户的账号与签名脚本版本（见 Set.Feedback），不保存。
+	Result *SignResponse `json:"-"`
+}
+
+// historyDropped 统计因写入队列已满而未持久化的签名记录数。
+var historyDropped = metrics.Default.NewCounterVec(
+	"go_sign_history_dropped_total",
+	"存储后端写入过慢、队列已满而未持久化的签名记录数",
+)
+
+// History 以环形缓冲保存最近的签名记录，并发安全。调用 Persist 后记录同时异步写入存储后端。
+type History struct {
+	mu      sync.Mutex
+	records []SignRecord
+	next    int
+	full    bool
+
+	// store 为持久化签名记录的后端，为 nil 时只保存在内存中；pending 为待写入后端的记录。
+	store   storage.Store
+	pending chan SignRecord
+}
+
+// NewHistory 创建最多保存 size 条记录的 History。
+func NewHistory(size int) *History {
+	return &History{records: make([]SignRecord, size)}
+}
+
+// DefaultHistory 为 ObserveSign 写入的全局签名记录，保存最近 1000 条。
+var DefaultHistory = NewHistory(1000)
+
+// historyLog 返回租户 tenant 的签名记录在存储后端中的日志名。
+func historyLog(tenant string) string {
+	return "history:" + tenant
+}
+
+// Persist 使之后的签名记录同时写入 store，每个租户保留最近 keep 条，Recent 改为从 store 读取，
+// 多个实例共享同一后端时可看到全部实例的记录并在重启后保留。写入在后台进行，ctx 取消时停止。
+// 健康检查使用的 Failures 仍只统计本实例内存中的记录。
+func (h *History) Persist(ctx context.Context, store storage.Store, keep int) {
+	pending := make(chan SignRecord, 1024)
+	h.mu.Lock()
+	h.store, h.pending = store, pending
+	h.mu.Unlock()
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case r := <-pending:
+				raw, err := json.Marshal(r)
+				if err == nil {
+					err = store.Append(ctx, historyLog(r.Tenant), raw, keep)
+				}
+				if err != nil && ctx.Err() == nil {
+					slog.Warn("写入签名记录失败", "err", err, "tenant", r.Tenant)
+				}
+			}
+		}
+	}()
+}
+
+// Add 追加一条记录，缓冲已满时覆盖最早的记录。
+func (h *History) Add(r SignRecord) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records[h.next] = r
+	h.next = (h.next + 1) % len(h.records)
+	if h.next == 0 {
+		h.full = true
+	}
+	if h.pending != nil {
+		select {
+		case h.pending <- r:
+		default:
+			historyDropped.Inc()
+		}
+	}
+}
+
+// Recent 返回租户 tenant 最近的至多 limit 条记录，新记录在前。已调用 Persist 时从存储后端读取，
+// 读取失败时退回本实例内存中的记录。
+func (h *History) Recent(ctx context.Context, tenant string, limit int) []SignRecord {
+	h.mu.Lock()
+	store := h.store
+	h.mu.Unlock()
+	if store != nil {
+		out, err := recentFrom(ctx, store, tenant, limit)
+		if err == nil {
+			return out
+		}
+		slog.Warn("从存储后端读取签名记录失败，返回本实例内存中的记录", "err", err, "tenant", tenant)
+	}
+	return h.recent(tenant, limit)
+}
+
+// recentFrom 从 store 读取租户 tenant 最近的至多 limit 条记录。
+func recentFrom(ctx context.Context, store storage.Store, tenant string, limit int) ([]SignRecord, error) {
+	raw, err := store.Tail(ctx, historyLog(tenant), limit)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]SignRecord, 0, len(raw))
+	for _, v := range raw {
+		var r SignRecord
+		if err := json.Unmarshal(v, &r); err != nil {
+			return nil, err
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+// recent 返回内存中租户 tenant 最近的至多 limit 条记录。
+func (h *History) recent(tenant string, limit int) []SignRecord {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n := h.next
+	if h.full {
+		n = len(h.records)
+	}
+	var out []SignRecord
+	for i := 1; i <= n && len(out) < limit; i++ {
+		r := h.records[(h.next-i+len(h.records))%len(h.records)]
+		if r.Tenant == tenant {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// Failures 返回平台 platform 在 since 之后的签名次数与其中失败的次数。
+func (h *History) Failures(platform string, since time.Time) (total, failed int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n := h.next
+	if h.full {
+		n = len(h.records)
+	}
+	for i := 1; i <= n; i++ {
+		r := h.records[(h.next-i+len(h.records))%len(h.records)]
+		if r.Time.Before(since) {
+			break
+		}
+		if r.Platform != platform {
+			continue
+		}
+		total++
+		if r.Err != "" {
+			failed++
+		}
+	}
+	return total, failed
+}
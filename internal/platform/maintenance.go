@@ -0,0 +1,84 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/report"
+	"go_sign/internal/schedule"
+)
+
+// Recycler 为支持回收浏览器资源的平台：逐个重建浏览器上下文以释放内存与缓存，重建前等待其上的签名完成。
+type Recycler interface {
+	Recycle(ctx context.Context) error
+}
+
+// Recycle 依次回收全部支持回收的平台，已有回收或升级在进行时返回错误。
+func (s *Set) Recycle(ctx context.Context) error {
+	if !s.recycling.CompareAndSwap(false, true) {
+		return errors.New("回收或升级正在进行")
+	}
+	defer s.recycling.Store(false)
+	var errs []error
+	for i, p := range s.platforms {
+		r, ok := s.base[i].(Recycler)
+		if !ok {
+			continue
+		}
+		start := time.Now()
+		slog.Info("开始回收浏览器上下文", "platform", p.Name())
+		err := r.Recycle(ctx)
+		// 回收失败时部分槽位也可能已重建
+		s.retest(i)
+		if err != nil {
+			slog.Error("回收浏览器上下文失败", "err", err, "platform", p.Name())
+			errs = append(errs, err)
+			continue
+		}
+		slog.Info("回收浏览器上下文完成", "platform", p.Name(), "elapsed", time.Since(start))
+	}
+	return errors.Join(errs...)
+}
+
+// MaintainOn 按 cron 表达式在维护窗口回收浏览器上下文，每次至多执行 timeout，直至 ctx 取消。
+func (s *Set) MaintainOn(ctx context.Context, cron *schedule.Cron, timeout time.Duration) {
+	for {
+		next := cron.Next(time.Now())
+		if next.IsZero() {
+			slog.Error("维护计划五年内没有可执行的时间，停止定时回收")
+			return
+		}
+		slog.Info("下次定时回收浏览器上下文", "at", next)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+		rctx, cancel := context.WithTimeout(ctx, timeout)
+		if err := s.Recycle(rctx); err != nil {
+			report.Error("定时回收浏览器上下文失败", err)
+		}
+		cancel()
+	}
+}
+
+// RecycleHandler 返回手动触发回收的管理接口：在后台回收并立即返回 202，已有回收或升级在进行时返回 409。
+func (s *Set) RecycleHandler(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.recycling.Load() {
+			c.JSON(http.StatusConflict, gin.H{"error": "回收或升级正在进行"})
+			return
+		}
+		slog.Info("管理接口触发回收浏览器上下文", "client_ip", c.ClientIP())
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			_ = s.Recycle(ctx)
+		}()
+		c.JSON(http.StatusAccepted, gin.H{"status": "recycling"})
+	}
+}
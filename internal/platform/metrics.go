@@ -0,0 +1,57 @@
+package platform
+
+import (
+	"go_sign/internal/logging"
+	"go_sign/internal/metrics"
+	"go_sign/internal/report"
+)
+
+// signRequests 统计各平台的签名请求数。
+var signRequests = metrics.Default.NewCounterVec(
+	"go_sign_sign_requests_total",
+	"签名请求数，result 为 ok 或 error",
+	"platform", "tenant", "result",
+)
+
+// ObserveSign 记录一次签名请求的结果：计入指标并写入 DefaultHistory，err 为签名错误。
+// 成功的签名同时使平台满足就绪检查的首次签名条件。
+func ObserveSign(rec SignRecord, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+		rec.Err = err.Error()
+	} else {
+		markSigned(rec.Platform)
+		defaultIssued.add(rec.Platform, rec.Tenant, rec.Result)
+	}
+	rec.Result = nil
+	signRequests.Inc(rec.Platform, rec.Tenant, result)
+	DefaultHistory.Add(rec)
+	report.ObserveSign(rec.Platform, err)
+	for _, fn := range signObservers {
+		fn(rec)
+	}
+}
+
+// signObservers 为 OnSign 注册的回调。
+var signObservers []func(SignRecord)
+
+// OnSign 注册在 ObserveSign 记录每次签名请求后调用的 fn，须在开始处理请求前调用；fn 不能阻塞。
+func OnSign(fn func(SignRecord)) {
+	signObservers = append(signObservers, fn)
+}
+
+// AccountOf 返回签名结果中脱敏后的账号标识，与账号管理接口返回的 identity 一致；res 未提供时返回空。
+func AccountOf(res *SignResponse) string {
+	if res == nil || res.Source == nil {
+		return ""
+	}
+	return logging.Mask(res.Source.Identity)
+}
+
+// deprecatedRequests 统计已废弃路由的请求数，用于判断何时可以移除。
+var deprecatedRequests = metrics.Default.NewCounterVec(
+	"go_sign_deprecated_requests_total",
+	"已废弃（未带版本）路由的请求数",
+	"route",
+)
@@ -0,0 +1,213 @@
+// Package platform 定义可插拔的签名平台接口与注册表。
+//
+// 每个平台（小红书各站点、其他网站）是一个自包含模块，在 init 中调用 Register 注册工厂，
+// 由配置文件的 platforms 列表决定启用哪些平台，启用后各自注册 HTTP 路由。
+package platform
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/browser"
+	"go_sign/internal/timing"
+)
+
+// Signer 为签名接口的处理函数依赖的最小接口，测试中可以假实现代替浏览器类平台。
+type Signer interface {
+	// Name 返回平台名称，与注册名一致，用于路由、日志与指标。
+	Name() string
+	// Sign 为请求生成签名。
+	Sign(ctx context.Context, req *SignRequest) (*SignResponse, error)
+}
+
+// Platform 为一个签名平台。
+type Platform interface {
+	Signer
+	// Init 初始化平台资源（如启动页面池），在注册路由前调用一次。
+	Init(ctx context.Context, env *Env) error
+	// HealthCheck 检查平台是否可以正常签名。
+	HealthCheck(ctx context.Context) error
+	// Close 释放平台资源。
+	Close() error
+}
+
+// Router 由需要自定义路由的平台实现；未实现时使用通用路由 POST /<name>/sign。
+// signer 为路由签名时应调用的实例，可能是经 Set.Wrap 包装后的平台本身。
+type Router interface {
+	RegisterRoutes(router gin.IRouter, signer Signer, middlewares ...gin.HandlerFunc)
+}
+
+// Mocker 由平台可选实现，返回格式与真实签名一致的假结果，用于 --mock 模式。
+type Mocker interface {
+	Mock(req *SignRequest) *SignResponse
+}
+
+// MockHex 返回 n 个随机字节的十六进制字符串，供 Mocker 生成假签名。
+func MockHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// SignRequest 为通用签名请求。
+type SignRequest struct {
+	// URI 为待签名请求的路径或完整 URL。
+	URI string `json:"uri"`
+	// Method 为待签名请求的 HTTP 方法，为空时视为 GET（无 data）或 POST（有 data）。
+	Method string `json:"method,omitempty"`
+	// Data 为待签名请求的请求体。
+	Data any `json:"data,omitempty"`
+	// Params 为待签名请求的查询参数。
+	Params map[string]string `json:"params,omitempty"`
+	// Cookies 为待签名请求携带的 cookie。
+	Cookies map[string]string `json:"cookies,omitempty"`
+	// UserAgent 为待签名请求使用的 User-Agent。
+	UserAgent string `json:"user_agent,omitempty"`
+	// Timestamp 为签名使用的毫秒时间戳，0 表示使用签名端当前时间。
+	// 调用方与签名端时钟偏差较大时，可传入调用方时钟以固定签名中的时间。
+	Timestamp int64 `json:"timestamp,omitempty"`
+}
+
+// SignResponse 为通用签名结果：需要附加到请求上的请求头与查询参数。
+type SignResponse struct {
+	Headers map[string]string `json:"headers,omitempty"`
+	Params  map[string]string `json:"params,omitempty"`
+	// Timestamp 为签名中实际使用的毫秒时间戳。
+	Timestamp int64 `json:"timestamp,omitempty"`
+	// ServerTime 为签名端返回结果时的毫秒时间，调用方可据此估算时钟偏差。
+	ServerTime int64 `json:"server_time,omitempty"`
+	// Source 为产生签名的页面信息，由平台可选填充。
+	Source *SignSource `json:"source,omitempty"`
+	// ScriptVersion 为签名页面加载的签名脚本的版本指纹，由平台可选填充。
+	ScriptVersion string `json:"script_version,omitempty"`
+	// Timing 为请求带 ?debug=timing 时的耗时分解。
+	Timing *timing.Breakdown `json:"timing,omitempty"`
+}
+
+// SignSource 描述产生签名的页面，调用方可据此构造完全一致的请求并排查签名不匹配。
+type SignSource struct {
+	// Identity 为签名页面的账号标识（如小红书页面 cookie 中的 a1）。
+	Identity string `json:"identity,omitempty"`
+	// ContextID 为签名使用的浏览器上下文，格式为 <租户>/<分组>/<槽位>。
+	ContextID string `json:"context_id,omitempty"`
+	// UserAgent 为签名页面的 User-Agent。
+	UserAgent string `json:"user_agent,omitempty"`
+	// URI 为实际参与签名的规范化 uri。
+	URI string `json:"uri,omitempty"`
+}
+
+// MaxTimestampSkew 为调用方指定的签名时间戳与签名端时钟的最大允许偏差。
+const MaxTimestampSkew = 24 * time.Hour
+
+// CheckTimestamp 检查调用方指定的毫秒时间戳，0 表示未指定。
+func CheckTimestamp(ts int64) error {
+	if ts == 0 {
+		return nil
+	}
+	skew := time.Since(time.UnixMilli(ts))
+	if skew > MaxTimestampSkew || skew < -MaxTimestampSkew {
+		return fmt.Errorf("timestamp 与签名端时钟相差 %s，超过允许的 %s", skew.Round(time.Second), MaxTimestampSkew)
+	}
+	return nil
+}
+
+// SignTime 返回签名应使用的时间：ts 非 0 时为 ts，否则为当前时间。
+func SignTime(ts int64) time.Time {
+	if ts != 0 {
+		return time.UnixMilli(ts)
+	}
+	return time.Now()
+}
+
+// Env 为平台初始化时可用的共享资源与通用配置。
+type Env struct {
+	// Browser 为按需启动的共享浏览器，浏览器类平台通过 Get 获取。
+	Browser *browser.Shared
+	// StealthPath 为 stealth.min.js 的文件路径。
+	StealthPath string
+	// CanaryStealthPath 为灰度 stealth.js 的文件路径，非空时浏览器类平台额外预热一组注入该脚本的页面，
+	// 并将 CanaryWeight 比例的请求分流到这组页面。
+	CanaryStealthPath string
+	CanaryWeight      float64
+	// WaitUntil、NavigationTimeout、SignFuncTimeout 为浏览器类平台预热页面时的导航参数。
+	WaitUntil         string
+	NavigationTimeout time.Duration
+	SignFuncTimeout   time.Duration
+	// PoolSize、WarmupConcurrency 为浏览器类平台的默认页面池大小与预热并发上限。
+	PoolSize          int
+	WarmupConcurrency int
+	// Tenants 为各租户的页面池配置。
+	Tenants []Tenant
+	// PacePerMinute、PaceJitter、PaceMaxWait 为按账号的全局签名节流参数。
+	PacePerMinute int
+	PaceJitter    time.Duration
+	PaceMaxWait   time.Duration
+	// ShedBudget 为浏览器类平台页面池的排队预算，超出时提前拒绝低优先级请求，0 表示不限制。
+	ShedBudget time.Duration
+	// BanThreshold、BanWindow、BanCooldown 为账号风控冷却参数：BanWindow 内同一账号收到 BanThreshold 次
+	// 风控响应时冷却 BanCooldown，见 AccountReporter。
+	BanThreshold int
+	BanWindow    time.Duration
+	BanCooldown  time.Duration
+	// AccountLimits 为单个账号在各时间窗口内的签名次数上限。
+	AccountLimits AccountLimits
+}
+
+// Tenant 为单个租户的页面池配置。
+type Tenant struct {
+	Name     string
+	PoolSize int
+}
+
+// Decoder 将平台的配置项解码到 v，未配置时不修改 v。
+type Decoder func(v any) error
+
+// Factory 根据配置项创建平台实例。
+type Factory func(options Decoder) (Platform, error)
+
+var (
+	mu        sync.RWMutex
+	factories = make(map[string]Factory)
+)
+
+// Register 注册平台工厂，通常在平台模块的 init 中调用。重复注册会 panic。
+func Register(name string, f Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := factories[name]; ok {
+		panic("platform: 重复注册平台 " + name)
+	}
+	factories[name] = f
+}
+
+// New 使用已注册的工厂创建平台实例。
+func New(name string, options Decoder) (Platform, error) {
+	mu.RLock()
+	f, ok := factories[name]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("未注册的平台: %s", name)
+	}
+	if options == nil {
+		options = func(any) error { return nil }
+	}
+	return f(options)
+}
+
+// Names 返回全部已注册的平台名称。
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(factories))
+	for n := range factories {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
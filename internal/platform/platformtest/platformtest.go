@@ -0,0 +1,132 @@
+// Package platformtest 提供 HTTP 层测试使用的假签名平台与基于 httptest 的请求工具，
+// 签名接口的请求校验、错误应答格式等约定可在不启动浏览器的情况下测试。
+package platformtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/platform"
+)
+
+// Fake 为假签名平台，实现 platform.Platform。SignFunc 为空时返回固定的 x-s、x-t 请求头。
+type Fake struct {
+	PlatformName string
+	SignFunc     func(ctx context.Context, req *platform.SignRequest) (*platform.SignResponse, error)
+
+	mu       sync.Mutex
+	requests []*platform.SignRequest
+}
+
+// Name 返回 PlatformName，为空时为 fake。
+func (f *Fake) Name() string {
+	if f.PlatformName == "" {
+		return "fake"
+	}
+	return f.PlatformName
+}
+
+func (f *Fake) Init(context.Context, *platform.Env) error { return nil }
+
+// Sign 记录请求后调用 SignFunc。
+func (f *Fake) Sign(ctx context.Context, req *platform.SignRequest) (*platform.SignResponse, error) {
+	f.mu.Lock()
+	f.requests = append(f.requests, req)
+	f.mu.Unlock()
+	if f.SignFunc != nil {
+		return f.SignFunc(ctx, req)
+	}
+	return &platform.SignResponse{Headers: map[string]string{"x-s": "XYW_fake", "x-t": "1700000000000"}, Timestamp: 1700000000000}, nil
+}
+
+func (f *Fake) HealthCheck(context.Context) error { return nil }
+
+func (f *Fake) Close() error { return nil }
+
+// Requests 返回 Sign 收到的全部请求。
+func (f *Fake) Requests() []*platform.SignRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*platform.SignRequest(nil), f.requests...)
+}
+
+// Harness 为注册了待测路由的 httptest 服务。
+type Harness struct {
+	t      testing.TB
+	server *httptest.Server
+}
+
+// New 以 gin 测试模式创建路由，由 register 注册待测路由后启动 httptest 服务，测试结束时关闭。
+func New(t testing.TB, register func(r *gin.Engine)) *Harness {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	register(r)
+	h := &Harness{t: t, server: httptest.NewServer(r)}
+	t.Cleanup(h.server.Close)
+	return h
+}
+
+// Response 为一次请求的应答。
+type Response struct {
+	Status int
+	Header http.Header
+	Body   []byte
+}
+
+// JSON 将应答体解码为 map，不是 JSON 对象时测试失败。
+func (r *Response) JSON(t testing.TB) map[string]any {
+	t.Helper()
+	var out map[string]any
+	if err := json.Unmarshal(r.Body, &out); err != nil {
+		t.Fatalf("应答体不是 JSON 对象: %v: %s", err, r.Body)
+	}
+	return out
+}
+
+// Do 发送请求并读取应答。body 为 string 或 []byte 时原样发送，为 nil 时不带请求体，其余编码为 JSON；
+// header 为额外的请求头。
+func (h *Harness) Do(method, path string, body any, header map[string]string) *Response {
+	h.t.Helper()
+	var r io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		r = bytes.NewBufferString(b)
+	case []byte:
+		r = bytes.NewReader(b)
+	default:
+		raw, err := json.Marshal(b)
+		if err != nil {
+			h.t.Fatalf("编码请求体失败: %v", err)
+		}
+		r = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequest(method, h.server.URL+path, r)
+	if err != nil {
+		h.t.Fatalf("创建请求失败: %v", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := h.server.Client().Do(req)
+	if err != nil {
+		h.t.Fatalf("请求 %s %s 失败: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		h.t.Fatalf("读取应答失败: %v", err)
+	}
+	return &Response{Status: resp.StatusCode, Header: resp.Header, Body: raw}
+}
@@ -0,0 +1,121 @@
+package platform
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"go_sign/internal/wire"
+)
+
+// Protobuf 编解码，消息定义见 api/sign.proto 中的 SignRequest、SignResponse。
+
+// MarshalProto 编码 SignRequest。
+func (r *SignRequest) MarshalProto() ([]byte, error) {
+	b := wire.AppendString(nil, 1, r.URI)
+	b = wire.AppendString(b, 2, r.Method)
+	if r.Data != nil {
+		data, err := json.Marshal(r.Data)
+		if err != nil {
+			return nil, fmt.Errorf("data 参数序列化失败: %w", err)
+		}
+		b = wire.AppendBytes(b, 3, data)
+	}
+	b = wire.AppendMap(b, 4, r.Params)
+	b = wire.AppendMap(b, 5, r.Cookies)
+	b = wire.AppendString(b, 6, r.UserAgent)
+	return wire.AppendInt64(b, 7, r.Timestamp), nil
+}
+
+// UnmarshalProto 解码 SignRequest。
+func (r *SignRequest) UnmarshalProto(b []byte) error {
+	return wire.RangeFields(b, func(f wire.Field) error {
+		switch f.Num {
+		case 1:
+			r.URI = string(f.Bytes)
+		case 2:
+			r.Method = string(f.Bytes)
+		case 3:
+			if err := json.Unmarshal(f.Bytes, &r.Data); err != nil {
+				return fmt.Errorf("解析 data_json 失败: %w", err)
+			}
+		case 4, 5:
+			k, v, err := wire.MapEntry(f.Bytes)
+			if err != nil {
+				return err
+			}
+			m := &r.Params
+			if f.Num == 5 {
+				m = &r.Cookies
+			}
+			if *m == nil {
+				*m = make(map[string]string)
+			}
+			(*m)[k] = v
+		case 6:
+			r.UserAgent = string(f.Bytes)
+		case 7:
+			r.Timestamp = int64(f.Varint)
+		}
+		return nil
+	})
+}
+
+// MarshalProto 编码 SignResponse。
+func (r *SignResponse) MarshalProto() ([]byte, error) {
+	b := wire.AppendMap(nil, 1, r.Headers)
+	b = wire.AppendMap(b, 2, r.Params)
+	b = wire.AppendInt64(b, 3, r.Timestamp)
+	b = wire.AppendInt64(b, 4, r.ServerTime)
+	if s := r.Source; s != nil {
+		src := wire.AppendString([]byte{}, 1, s.Identity)
+		src = wire.AppendString(src, 2, s.ContextID)
+		src = wire.AppendString(src, 3, s.UserAgent)
+		src = wire.AppendString(src, 4, s.URI)
+		b = wire.AppendMessage(b, 5, src)
+	}
+	b = wire.AppendString(b, 6, r.ScriptVersion)
+	return b, nil
+}
+
+// UnmarshalProto 解码 SignResponse。
+func (r *SignResponse) UnmarshalProto(b []byte) error {
+	return wire.RangeFields(b, func(f wire.Field) error {
+		switch f.Num {
+		case 1, 2:
+			k, v, err := wire.MapEntry(f.Bytes)
+			if err != nil {
+				return err
+			}
+			m := &r.Headers
+			if f.Num == 2 {
+				m = &r.Params
+			}
+			if *m == nil {
+				*m = make(map[string]string)
+			}
+			(*m)[k] = v
+		case 3:
+			r.Timestamp = int64(f.Varint)
+		case 4:
+			r.ServerTime = int64(f.Varint)
+		case 5:
+			r.Source = &SignSource{}
+			return wire.RangeFields(f.Bytes, func(f wire.Field) error {
+				switch f.Num {
+				case 1:
+					r.Source.Identity = string(f.Bytes)
+				case 2:
+					r.Source.ContextID = string(f.Bytes)
+				case 3:
+					r.Source.UserAgent = string(f.Bytes)
+				case 4:
+					r.Source.URI = string(f.Bytes)
+				}
+				return nil
+			})
+		case 6:
+			r.ScriptVersion = string(f.Bytes)
+		}
+		return nil
+	})
+}
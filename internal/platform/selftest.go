@@ -0,0 +1,160 @@
+package platform
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go_sign/internal/metrics"
+	"go_sign/internal/report"
+)
+
+// SelfTester 由包装后不再调用原平台的实现（如 mock、回放）提供，代替自检签名；
+// 未实现时自检直接调用原平台的 Sign，不经过录制、双跑校验等包装。
+type SelfTester interface {
+	SelfTest(ctx context.Context) error
+}
+
+// unwrap 返回平台内部包装功能（如对冲、双跑校验）的下一层实例，不是此类包装时返回 nil。
+func unwrap(p Platform) Platform {
+	if u, ok := p.(interface{ Unwrap() Platform }); ok {
+		return u.Unwrap()
+	}
+	return nil
+}
+
+// selfTestRequest 为自检签名的请求，只用于确认签名函数可用，结果不返回给任何调用方。
+var selfTestRequest = SignRequest{URI: "/api/go_sign/self-test", Method: "GET"}
+
+// 自检签名的单次超时与失败后的重试间隔。
+const (
+	selfTestTimeout = 30 * time.Second
+	selfTestRetry   = 5 * time.Second
+)
+
+// signed 记录进程启动以来已成功签名（含自检）的平台名。
+var signed sync.Map
+
+// markSigned 记录平台 name 已成功签名。
+func markSigned(name string) {
+	signed.Store(name, struct{}{})
+}
+
+// forgetSigned 清除平台 name 的已签名记录。
+func forgetSigned(name string) {
+	signed.Delete(name)
+}
+
+// hasSigned 判断平台 name 自启动以来是否已成功签名。
+func hasSigned(name string) bool {
+	_, ok := signed.Load(name)
+	return ok
+}
+
+// SelfTest 为尚未成功签名的平台执行自检签名，失败时每隔 selfTestRetry 重试，
+// 直至全部平台签名成功或 ctx 取消。应在 Init 之后调用。
+func (s *Set) SelfTest(ctx context.Context) {
+	for i := range s.platforms {
+		if !s.selfTestUntilSigned(ctx, i) {
+			return
+		}
+	}
+}
+
+// selfTestUntilSigned 对第 i 个平台重复自检签名，直至该平台已成功签名，ctx 取消时返回 false。
+func (s *Set) selfTestUntilSigned(ctx context.Context, i int) bool {
+	p := s.platforms[i]
+	for attempt := 1; !hasSigned(p.Name()); attempt++ {
+		err := s.selfTest(ctx, i)
+		if err == nil {
+			markSigned(p.Name())
+			slog.Info("自检签名成功", "platform", p.Name(), "attempt", attempt)
+			break
+		}
+		slog.Warn("自检签名失败，稍后重试", "err", err, "platform", p.Name(), "attempt", attempt, "retry", selfTestRetry)
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(selfTestRetry):
+		}
+	}
+	return true
+}
+
+// retest 在回收或升级替换第 i 个平台的页面后清除其已签名记录，并在后台重新自检直至签名成功：
+// 此前的签名发生在旧页面上，不能说明新页面可以签名。同一平台的重新自检正在进行时不重复启动。
+func (s *Set) retest(i int) {
+	name := s.platforms[i].Name()
+	forgetSigned(name)
+	if _, running := s.retesting.LoadOrStore(name, struct{}{}); running {
+		return
+	}
+	slog.Info("页面已替换，重新自检签名", "platform", name)
+	go func() {
+		defer s.retesting.Delete(name)
+		s.selfTestUntilSigned(context.Background(), i)
+	}()
+}
+
+// scheduledSelfTests 统计定时自检的结果。
+var scheduledSelfTests = metrics.Default.NewCounterVec(
+	"go_sign_scheduled_self_tests_total",
+	"定时自检签名次数，result 为 ok 或 error",
+	"platform", "result",
+)
+
+// SelfTestEvery 每隔 interval 对全部平台执行一次自检签名，直至 ctx 取消：失败时记录错误并上报，
+// 用于及早发现站点改版导致的签名失效。多实例部署时应只在 leader 上运行。
+func (s *Set) SelfTestEvery(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for i, p := range s.platforms {
+			if err := s.selfTest(ctx, i); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				scheduledSelfTests.Inc(p.Name(), "error")
+				slog.Error("定时自检签名失败", "err", err, "platform", p.Name())
+				report.Error("定时自检签名失败", err, "platform", p.Name())
+				continue
+			}
+			scheduledSelfTests.Inc(p.Name(), "ok")
+			slog.Debug("定时自检签名成功", "platform", p.Name())
+		}
+	}
+}
+
+// selfTest 对第 i 个平台执行一次自检签名。
+func (s *Set) selfTest(ctx context.Context, i int) error {
+	ctx, cancel := context.WithTimeout(ctx, selfTestTimeout)
+	defer cancel()
+	for p := s.platforms[i]; p != nil; p = unwrap(p) {
+		if t, ok := p.(SelfTester); ok {
+			return t.SelfTest(ctx)
+		}
+	}
+	if s.tenant != "" {
+		ctx = WithTenant(ctx, s.tenant)
+	}
+	req := selfTestRequest
+	_, err := s.base[i].Sign(ctx, &req)
+	return err
+}
+
+// unsigned 返回自启动以来尚未成功签名的平台名。
+func (s *Set) unsigned() []string {
+	var out []string
+	for _, p := range s.platforms {
+		if !hasSigned(p.Name()) {
+			out = append(out, p.Name())
+		}
+	}
+	return out
+}
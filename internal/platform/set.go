@@ -0,0 +1,249 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/auth"
+	"go_sign/internal/browser"
+	"go_sign/internal/timing"
+	"go_sign/internal/wire"
+)
+
+// Set 为一组已启用的平台实例。
+type Set struct {
+	// base 为平台模块创建的原始实例，用于识别 Router
+	base []Platform
+	// platforms 为对外使用的实例，可能经 Wrap 包装
+	platforms []Platform
+	// browser 为 Init 时传入的共享浏览器，用于在健康状态中报告版本
+	browser *browser.Shared
+	// recycling 为 true 时正在回收浏览器上下文或蓝绿升级
+	recycling atomic.Bool
+	// tenant 为自检签名使用的租户，取 Init 时的第一个租户
+	tenant string
+	// stealthPath 为当前注入的 stealth.js，蓝绿升级后更新
+	stealthPath atomic.Value
+	// retesting 为回收或升级后正在重新自检的平台名，见 retest
+	retesting sync.Map
+}
+
+// NewSet 创建平台集合，names 与 options 一一对应，options 可为 nil。
+func NewSet(names []string, options []Decoder) (*Set, error) {
+	s := &Set{}
+	for i, name := range names {
+		var opt Decoder
+		if i < len(options) {
+			opt = options[i]
+		}
+		p, err := New(name, opt)
+		if err != nil {
+			return nil, err
+		}
+		s.base = append(s.base, p)
+	}
+	s.platforms = slices.Clone(s.base)
+	return s, nil
+}
+
+// Wrap 用 w 包装全部平台（如录制、回放、双跑校验），w 可原样返回无需包装的平台。
+// 包装后的实例负责 Init、Sign、HealthCheck 与 Close，须在 Init 前调用。
+func (s *Set) Wrap(w func(Platform) Platform) {
+	for i, p := range s.platforms {
+		s.platforms[i] = w(p)
+	}
+}
+
+// Platforms 返回全部平台实例（包装后）。
+func (s *Set) Platforms() []Platform {
+	return s.platforms
+}
+
+// Get 返回名为 name 的平台实例（包装后），未启用时返回 nil。
+func (s *Set) Get(name string) Platform {
+	for _, p := range s.platforms {
+		if p.Name() == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// Init 依次初始化全部平台，任一失败时关闭已初始化的平台并返回错误。
+func (s *Set) Init(ctx context.Context, env *Env) error {
+	s.browser = env.Browser
+	s.stealthPath.Store(env.StealthPath)
+	if len(env.Tenants) > 0 {
+		s.tenant = env.Tenants[0].Name
+	}
+	for i, p := range s.platforms {
+		slog.Info("初始化签名平台", "platform", p.Name())
+		if err := p.Init(ctx, env); err != nil {
+			for _, done := range s.platforms[:i] {
+				_ = done.Close()
+			}
+			return fmt.Errorf("初始化平台 %s 失败: %w", p.Name(), err)
+		}
+	}
+	return nil
+}
+
+// RegisterRoutes 为全部平台注册路由；middlewares 作用于签名路由（如鉴权、配额）。
+func (s *Set) RegisterRoutes(router gin.IRouter, middlewares ...gin.HandlerFunc) {
+	middlewares = append(middlewares, CallerContext())
+	for i, p := range s.platforms {
+		if r, ok := s.base[i].(Router); ok {
+			r.RegisterRoutes(router, p, middlewares...)
+			continue
+		}
+		router.Group("/"+p.Name(), middlewares...).POST("/sign", signHandler(p))
+	}
+}
+
+// APIVersion 为当前接口版本，版本化路由的前缀为 /<APIVersion>。
+// 返回格式发生不兼容变更时启用新版本，旧版本路由继续保留。
+const APIVersion = "v1"
+
+// RegisterVersionedRoutes 在 router 下注册 /v1 版本化的签名路由，并保留未带版本的旧路由作为已废弃的别名，
+// 旧路由的返回与 /v1 相同，额外携带 Deprecation 与指向 /v1 路由的 Link 响应头。
+func (s *Set) RegisterVersionedRoutes(router *gin.RouterGroup, middlewares ...gin.HandlerFunc) {
+	versioned := router.Group("/" + APIVersion)
+	s.RegisterRoutes(versioned, middlewares...)
+	deprecated := Deprecated(router.BasePath(), versioned.BasePath())
+	s.RegisterRoutes(router, append([]gin.HandlerFunc{deprecated}, middlewares...)...)
+}
+
+// Deprecated 返回标记已废弃路由的中间件：设置 Deprecation 响应头，并以 Link 指向将 base 前缀替换为
+// successor 后的新路由。
+func Deprecated(base, successor string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		c.Header("Deprecation", "true")
+		c.Header("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", path.Join(successor, strings.TrimPrefix(route, base))))
+		deprecatedRequests.Inc(route)
+		c.Next()
+	}
+}
+
+// signHandler 为未自定义路由的平台提供通用签名接口。
+func signHandler(p Signer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req SignRequest
+		if err := wire.Bind(c, &req); err != nil {
+			slog.Warn("签名参数解析失败", "err", err, "platform", p.Name(), "client_ip", c.ClientIP())
+			wire.Render(c, http.StatusBadRequest, gin.H{"error": "参数解析失败: " + err.Error()})
+			return
+		}
+		if err := CheckTimestamp(req.Timestamp); err != nil {
+			wire.Render(c, http.StatusBadRequest, gin.H{"error": "参数校验失败: " + err.Error()})
+			return
+		}
+		format := c.Query("format")
+		if err := CheckFormat(format); err != nil {
+			wire.Render(c, http.StatusBadRequest, gin.H{"error": "参数校验失败: " + err.Error()})
+			return
+		}
+		// 加密传入的 cookie 在签名前解密，回显的 cookie 不含这些凭据
+		echo := EchoCookies(req.Cookies)
+		cookies, err := DecryptCookies("cookies.", req.Cookies)
+		if err != nil {
+			slog.Warn("签名参数校验失败", "err", err, "platform", p.Name(), "client_ip", c.ClientIP())
+			wire.Render(c, http.StatusBadRequest, ErrorBody(err))
+			return
+		}
+		req.Cookies = cookies
+		key := auth.FromContext(c)
+		slog.Info("签名请求", "platform", p.Name(), "uri", req.URI, "api_key", key.Name, "tenant", key.Tenant.Name, "client_ip", c.ClientIP())
+		start := time.Now()
+		res, err := p.Sign(c.Request.Context(), &req)
+		ObserveSign(SignRecord{
+			Time:     start,
+			Platform: p.Name(),
+			Tenant:   key.Tenant.Name,
+			APIKey:   key.Name,
+			URI:      req.URI,
+			Account:  AccountOf(res),
+			Result:   res,
+			Duration: time.Since(start),
+		}, err)
+		var verr *ValidationError
+		if errors.As(err, &verr) {
+			slog.Warn("签名参数校验失败", "err", err, "platform", p.Name(), "client_ip", c.ClientIP())
+			wire.Render(c, http.StatusBadRequest, ErrorBody(err))
+			return
+		}
+		if err != nil {
+			slog.Error("签名失败", "err", err, "platform", p.Name(), "uri", req.URI, "client_ip", c.ClientIP())
+			wire.Render(c, SignErrorStatus(c, err), ErrorBody(fmt.Errorf("签名失败: %w", err)))
+			return
+		}
+		switch format {
+		case FormatHeaders:
+			wire.Render(c, http.StatusOK, Headers(&req, res, echo))
+			return
+		case FormatCurl:
+			cmd, err := Curl(&req, Headers(&req, res, echo), "")
+			if err != nil {
+				wire.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			c.String(http.StatusOK, cmd+"\n")
+			return
+		}
+		out := *res
+		out.ServerTime = time.Now().UnixMilli()
+		out.Timing = timing.Debug(c)
+		wire.Render(c, http.StatusOK, &out)
+	}
+}
+
+// HealthCheck 检查全部平台，返回各平台的错误（nil 表示健康）。
+func (s *Set) HealthCheck(ctx context.Context) map[string]error {
+	out := make(map[string]error, len(s.platforms))
+	for _, p := range s.platforms {
+		out[p.Name()] = p.HealthCheck(ctx)
+	}
+	return out
+}
+
+// HealthHandler 返回健康检查接口：任一平台不可用（down）时返回 503，降级时仍返回 200。
+// platforms 中健康的平台为 "ok"，其余为原因。
+func (s *Set) HealthHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		st := s.Status(c.Request.Context())
+		status := http.StatusOK
+		if st.State == HealthDown {
+			status = http.StatusServiceUnavailable
+		}
+		body := make(map[string]string, len(st.Platforms))
+		for _, ps := range st.Platforms {
+			body[ps.Name] = string(HealthOK)
+			if ps.Reason != "" {
+				body[ps.Name] = ps.Reason
+			}
+		}
+		c.JSON(status, gin.H{"state": st.State, "platforms": body})
+	}
+}
+
+// Close 关闭全部平台。
+func (s *Set) Close() error {
+	var errs []error
+	for _, p := range s.platforms {
+		if err := p.Close(); err != nil {
+			slog.Error("关闭签名平台失败", "err", err, "platform", p.Name())
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,176 @@
+package platform_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/platform"
+	"go_sign/internal/platform/platformtest"
+)
+
+// genericFake 为注册到平台注册表的假平台，使用通用签名路由。
+var genericFake = &platformtest.Fake{PlatformName: "fake-generic"}
+
+func init() {
+	platform.Register(genericFake.Name(), func(platform.Decoder) (platform.Platform, error) { return genericFake, nil })
+}
+
+func TestGenericSignRoute(t *testing.T) {
+	set, err := platform.NewSet([]string{genericFake.Name()}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := platformtest.New(t, func(r *gin.Engine) {
+		set.RegisterVersionedRoutes(r.Group("/"))
+	})
+	var signErr error
+	genericFake.SignFunc = func(context.Context, *platform.SignRequest) (*platform.SignResponse, error) {
+		if signErr != nil {
+			return nil, signErr
+		}
+		return &platform.SignResponse{Headers: map[string]string{"x-sign": "ok"}, Timestamp: 1700000000000}, nil
+	}
+	t.Cleanup(func() { genericFake.SignFunc = nil })
+
+	verr := &platform.ValidationError{}
+	verr.Add("uri", "不能为空")
+	tests := []struct {
+		name    string
+		path    string
+		body    any
+		err     error
+		status  int
+		fields  string
+		headers map[string]string
+	}{
+		{name: "成功", path: "/v1/fake-generic/sign", body: map[string]any{"uri": "/api/x"}, status: http.StatusOK},
+		{name: "非 JSON", path: "/v1/fake-generic/sign", body: "{", status: http.StatusBadRequest},
+		{name: "时间戳无效", path: "/v1/fake-generic/sign", body: map[string]any{"uri": "/api/x", "timestamp": 1}, status: http.StatusBadRequest},
+		{name: "不支持的 format", path: "/v1/fake-generic/sign?format=xml", body: map[string]any{"uri": "/api/x"}, status: http.StatusBadRequest},
+		{name: "平台校验失败", path: "/v1/fake-generic/sign", body: map[string]any{}, err: verr, status: http.StatusBadRequest, fields: "uri"},
+		{name: "暂时不可用", path: "/v1/fake-generic/sign", body: map[string]any{"uri": "/api/x"}, err: &platform.UnavailableError{Reason: "重建"}, status: http.StatusServiceUnavailable, headers: map[string]string{"Retry-After": "1"}},
+		{name: "签名失败", path: "/v1/fake-generic/sign", body: map[string]any{"uri": "/api/x"}, err: fmt.Errorf("崩溃"), status: http.StatusInternalServerError},
+		{name: "已废弃的旧路由", path: "/fake-generic/sign", body: map[string]any{"uri": "/api/x"}, status: http.StatusOK, headers: map[string]string{"Deprecation": "true", "Link": "</v1/fake-generic/sign>; rel=\"successor-version\""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signErr = tt.err
+			resp := h.Do(http.MethodPost, tt.path, tt.body, nil)
+			if resp.Status != tt.status {
+				t.Fatalf("状态码 = %d，期望 %d: %s", resp.Status, tt.status, resp.Body)
+			}
+			body := resp.JSON(t)
+			if tt.status == http.StatusOK {
+				if body["server_time"] == nil || body["headers"] == nil {
+					t.Errorf("成功应答缺少 headers 或 server_time: %s", resp.Body)
+				}
+			} else if msg, _ := body["error"].(string); msg == "" {
+				t.Errorf("错误应答缺少 error: %s", resp.Body)
+			}
+			if tt.fields != "" && !strings.Contains(string(resp.Body), `"field":"`+tt.fields+`"`) {
+				t.Errorf("应答缺少字段 %s 的校验错误: %s", tt.fields, resp.Body)
+			}
+			for k, v := range tt.headers {
+				if got := resp.Header.Get(k); got != v {
+					t.Errorf("%s = %q，期望 %q", k, got, v)
+				}
+			}
+		})
+	}
+}
+
+func TestGenericSignHeadersFormat(t *testing.T) {
+	set, err := platform.NewSet([]string{genericFake.Name()}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := platformtest.New(t, func(r *gin.Engine) {
+		set.RegisterRoutes(r.Group("/v1"))
+	})
+	resp := h.Do(http.MethodPost, "/v1/fake-generic/sign?format=headers", map[string]any{"uri": "/api/x", "cookies": map[string]string{"a1": "abc"}}, nil)
+	if resp.Status != http.StatusOK {
+		t.Fatalf("状态码 = %d: %s", resp.Status, resp.Body)
+	}
+	body := resp.JSON(t)
+	headers, _ := body["headers"].(map[string]any)
+	if headers["x-s"] != "XYW_fake" || body["cookie"] != "a1=abc" {
+		t.Errorf("应答 = %s，期望包含签名结果与请求中的 cookie", resp.Body)
+	}
+}
+
+func TestFeedbackOnlyIssued(t *testing.T) {
+	set, err := platform.NewSet([]string{genericFake.Name()}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := platformtest.New(t, func(r *gin.Engine) {
+		set.RegisterVersionedRoutes(r.Group("/"))
+		r.POST("/v1/feedback", set.FeedbackHandler())
+	})
+	genericFake.SignFunc = func(context.Context, *platform.SignRequest) (*platform.SignResponse, error) {
+		return &platform.SignResponse{Headers: map[string]string{"x-sign": "ok"}, ScriptVersion: "v1", Source: &platform.SignSource{Identity: "issued-a1"}}, nil
+	}
+	t.Cleanup(func() { genericFake.SignFunc = nil })
+	if resp := h.Do(http.MethodPost, "/v1/fake-generic/sign", map[string]any{"uri": "/api/x"}, nil); resp.Status != http.StatusOK {
+		t.Fatalf("签名 = %d: %s", resp.Status, resp.Body)
+	}
+
+	tests := []struct {
+		name   string
+		body   map[string]any
+		status int
+	}{
+		{name: "已签发的 a1", body: map[string]any{"platform": "fake-generic", "status": 461, "a1": "issued-a1", "script_version": "v1"}, status: http.StatusNoContent},
+		{name: "未签发的 a1", body: map[string]any{"platform": "fake-generic", "status": 461, "a1": "other-a1"}, status: http.StatusForbidden},
+		{name: "未知的 script_version", body: map[string]any{"platform": "fake-generic", "status": 200, "script_version": "v9"}, status: http.StatusBadRequest},
+		{name: "未启用的平台", body: map[string]any{"platform": "nope", "status": 200}, status: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if resp := h.Do(http.MethodPost, "/v1/feedback", tt.body, nil); resp.Status != tt.status {
+			t.Errorf("%s: 状态码 = %d，期望 %d: %s", tt.name, resp.Status, tt.status, resp.Body)
+		}
+	}
+}
+
+// recyclerFake 为支持回收的假平台，回收不做任何事，只用于触发回收后的重新自检。
+type recyclerFake struct{ *platformtest.Fake }
+
+func (recyclerFake) Recycle(context.Context) error { return nil }
+
+func TestRecycleClearsSigned(t *testing.T) {
+	fake := recyclerFake{&platformtest.Fake{PlatformName: "fake-recycler"}}
+	var broken atomic.Bool
+	fake.SignFunc = func(context.Context, *platform.SignRequest) (*platform.SignResponse, error) {
+		if broken.Load() {
+			return nil, fmt.Errorf("签名函数不可用")
+		}
+		return &platform.SignResponse{Headers: map[string]string{"x-sign": "ok"}}, nil
+	}
+	platform.Register(fake.Name(), func(platform.Decoder) (platform.Platform, error) { return fake, nil })
+	set, err := platform.NewSet([]string{fake.Name()}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := platformtest.New(t, func(r *gin.Engine) {
+		r.GET("/readyz", set.ReadyHandler())
+	})
+	set.SelfTest(context.Background())
+	if resp := h.Do(http.MethodGet, "/readyz", nil, nil); resp.Status != http.StatusOK {
+		t.Fatalf("自检成功后 /readyz = %d: %s", resp.Status, resp.Body)
+	}
+
+	// 回收后旧页面上的签名记录失效，新页面自检失败前不就绪
+	broken.Store(true)
+	t.Cleanup(func() { broken.Store(false) })
+	if err := set.Recycle(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if resp := h.Do(http.MethodGet, "/readyz", nil, nil); resp.Status != http.StatusServiceUnavailable {
+		t.Fatalf("回收后自检失败时 /readyz = %d，期望 503: %s", resp.Status, resp.Body)
+	}
+}
@@ -0,0 +1,55 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// StealthProber 为可在发布前验证新版 stealth.js 的浏览器类平台：临时创建一个注入 path 的页面，
+// 确认其上可以签名后关闭，不影响正在服务的页面。
+type StealthProber interface {
+	ProbeStealth(ctx context.Context, path string) error
+}
+
+// StealthRenamer 为记录了 stealth.js 路径的浏览器类平台，见 Set.RenameStealth。
+type StealthRenamer interface {
+	RenameStealth(from, to string)
+}
+
+// RenameStealth 在 from 被改名为 to 后，将当前路径及各平台重建页面时注入的路径由 from 改为 to。
+func (s *Set) RenameStealth(from, to string) {
+	s.stealthPath.CompareAndSwap(from, to)
+	for _, p := range s.base {
+		if r, ok := p.(StealthRenamer); ok {
+			r.RenameStealth(from, to)
+		}
+	}
+}
+
+// StealthPath 返回当前注入的 stealth.js 路径，蓝绿升级后为新的路径。
+func (s *Set) StealthPath() string {
+	path, _ := s.stealthPath.Load().(string)
+	return path
+}
+
+// ProbeStealth 在全部支持验证的平台上验证 path 指向的 stealth.js，任一平台失败时返回错误。
+func (s *Set) ProbeStealth(ctx context.Context, path string) error {
+	probed := 0
+	for i, p := range s.platforms {
+		sp, ok := s.base[i].(StealthProber)
+		if !ok {
+			continue
+		}
+		if err := sp.ProbeStealth(ctx, path); err != nil {
+			return fmt.Errorf("平台 %s 验证 stealth.js 失败: %w", p.Name(), err)
+		}
+		slog.Info("stealth.js 验证通过", "platform", p.Name(), "path", path)
+		probed++
+	}
+	if probed == 0 {
+		return errors.New("没有可验证 stealth.js 的平台")
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package platform
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/auth"
+)
+
+// HeaderTemplate 为从签名页面向站点接口发出的真实请求中学习到的请求头模板。
+type HeaderTemplate struct {
+	// Name 为模板名称，由请求方法与接口路径生成，如 post_sns_web_v1_feed。
+	Name   string `json:"name"`
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	// UserAgent 为发出请求的页面的 User-Agent，sec-ch-ua 等请求头与之对应。
+	UserAgent string `json:"user_agent"`
+	// Headers 为按发送顺序排列的请求头，每次请求不同的值（如 x-s）为 {x-s} 形式的占位符。
+	Headers []TemplateHeader `json:"headers"`
+	// Structure 为请求头取值的结构，如 x-s-common 解码后的字段顺序。
+	Structure map[string][]string `json:"structure,omitempty"`
+	// Query 为最近一次请求的原始查询串，Body 为其 JSON 请求体，重放模板时以调用方的参数覆盖。
+	Query string          `json:"query,omitempty"`
+	Body  json.RawMessage `json:"body,omitempty"`
+	// Requests 为学习到的请求数，UpdatedAt 为最近一次请求的时间。
+	Requests  int       `json:"requests"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TemplateHeader 为模板中的一个请求头。
+type TemplateHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HeaderLearner 为可从签名页面的真实请求学习请求头模板的平台，未开启学习时返回错误。
+type HeaderLearner interface {
+	HeaderTemplates() ([]HeaderTemplate, error)
+}
+
+// HeaderTemplatesHandler 返回查看请求头模板的管理接口 GET /header-templates/:platform。
+func (s *Set) HeaderTemplatesHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var l HeaderLearner
+		for i, p := range s.platforms {
+			if p.Name() == c.Param("platform") {
+				l, _ = s.base[i].(HeaderLearner)
+			}
+		}
+		if l == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "平台未启用或不支持学习请求头: " + c.Param("platform")})
+			return
+		}
+		templates, err := l.HeaderTemplates()
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		slog.Info("管理接口查看请求头模板", "platform", c.Param("platform"), "templates", len(templates), "operator", auth.FromContext(c).Name)
+		c.JSON(http.StatusOK, gin.H{"templates": templates})
+	}
+}
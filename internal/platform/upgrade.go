@@ -0,0 +1,150 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/browser"
+	"go_sign/internal/report"
+)
+
+// Staged 为平台在新浏览器上预热完成、尚未接收流量的一组页面。
+type Staged interface {
+	// Commit 将流量切换到新页面，并等待旧页面上的签名完成后关闭旧页面。
+	Commit(ctx context.Context) error
+	// Abort 丢弃新页面。
+	Abort()
+}
+
+// Upgrader 为支持蓝绿升级的浏览器类平台：在新浏览器 b 上注入 stealthPath 预热与当前规模相同的一组页面，
+// 预热期间旧页面照常签名。不使用浏览器（如 Node.js 后端）时返回 nil, nil。
+type Upgrader interface {
+	StageUpgrade(ctx context.Context, b *browser.Browser, stealthPath string) (Staged, error)
+}
+
+// Upgrade 以蓝绿方式升级浏览器与 stealth.js：启动新浏览器，在其上为全部支持升级的平台预热页面，
+// 全部预热成功后再切换流量，等待旧页面上的签名完成后关闭旧浏览器。stealthPath 为空时沿用当前的 stealth.js。
+// 任一平台预热失败时丢弃新浏览器，继续使用旧页面。已有回收或升级在进行时返回错误。
+func (s *Set) Upgrade(ctx context.Context, stealthPath string) error {
+	if !s.recycling.CompareAndSwap(false, true) {
+		return errors.New("回收或升级正在进行")
+	}
+	defer s.recycling.Store(false)
+	if s.browser == nil || s.browser.Versions() == nil {
+		return errors.New("浏览器未启动，无需升级")
+	}
+	if stealthPath == "" {
+		stealthPath = s.StealthPath()
+	} else if _, err := os.Stat(stealthPath); err != nil {
+		return fmt.Errorf("stealth.js 文件不存在: %w", err)
+	}
+
+	start := time.Now()
+	slog.Info("开始蓝绿升级浏览器", "stealth_path", stealthPath)
+	next := &browser.Shared{VersionCheck: s.browser.VersionCheck}
+	b, err := next.Get()
+	if err != nil {
+		return fmt.Errorf("启动新浏览器失败: %w", err)
+	}
+	var staged []Staged
+	var upgraded []int
+	abort := func() {
+		for _, st := range staged {
+			st.Abort()
+		}
+		_ = next.Close()
+	}
+	for i, p := range s.platforms {
+		u, ok := s.base[i].(Upgrader)
+		if !ok {
+			continue
+		}
+		slog.Info("在新浏览器上预热页面", "platform", p.Name())
+		st, err := u.StageUpgrade(ctx, b, stealthPath)
+		if err != nil {
+			abort()
+			slog.Error("新浏览器预热失败，继续使用旧页面", "err", err, "platform", p.Name())
+			return fmt.Errorf("平台 %s 预热新页面失败: %w", p.Name(), err)
+		}
+		if st != nil {
+			staged, upgraded = append(staged, st), append(upgraded, i)
+		}
+	}
+	if len(staged) == 0 {
+		abort()
+		return errors.New("没有支持升级的平台")
+	}
+
+	// 预热全部成功后各平台同时切换，此后新签名只使用新浏览器
+	errs := make([]error, len(staged))
+	var wg sync.WaitGroup
+	for i, st := range staged {
+		wg.Add(1)
+		go func(i int, st Staged) {
+			defer wg.Done()
+			errs[i] = st.Commit(ctx)
+		}(i, st)
+	}
+	wg.Wait()
+	old := s.browser.Replace(b)
+	if err := errors.Join(errs...); err != nil {
+		slog.Warn("部分旧页面未在超时前完成签名，关闭旧浏览器", "err", err)
+	}
+	if old != nil {
+		if err := old.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	s.stealthPath.Store(stealthPath)
+	for _, i := range upgraded {
+		s.retest(i)
+	}
+	slog.Info("蓝绿升级完成", "stealth_path", stealthPath, "browser", b.Versions().Chromium, "elapsed", time.Since(start))
+	return errors.Join(errs...)
+}
+
+// upgradeRequest 为升级接口的请求体。
+type upgradeRequest struct {
+	// StealthPath 为新的 stealth.js 路径，为空时沿用当前的 stealth.js。
+	StealthPath string `json:"stealth_path"`
+}
+
+// UpgradeHandler 返回手动触发蓝绿升级的管理接口：在后台升级并立即返回 202，已有回收或升级在进行时返回 409。
+func (s *Set) UpgradeHandler(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req upgradeRequest
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "参数解析失败: " + err.Error()})
+				return
+			}
+		}
+		if req.StealthPath != "" {
+			if _, err := os.Stat(req.StealthPath); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "stealth.js 文件不存在: " + err.Error()})
+				return
+			}
+		}
+		if s.recycling.Load() {
+			c.JSON(http.StatusConflict, gin.H{"error": "回收或升级正在进行"})
+			return
+		}
+		slog.Info("管理接口触发蓝绿升级", "stealth_path", req.StealthPath, "client_ip", c.ClientIP())
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			if err := s.Upgrade(ctx, req.StealthPath); err != nil {
+				slog.Error("蓝绿升级失败", "err", err)
+				report.Error("蓝绿升级失败", err)
+			}
+		}()
+		c.JSON(http.StatusAccepted, gin.H{"status": "upgrading"})
+	}
+}
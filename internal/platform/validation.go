@@ -0,0 +1,40 @@
+package platform
+
+import (
+	"fmt"
+	"strings"
+)
+
+// FieldError 为单个请求字段的校验错误。
+type FieldError struct {
+	// Field 为请求中的字段名，如 uri、a1。
+	Field string `json:"field"`
+	// Message 为不合法的原因及期望的取值。
+	Message string `json:"message"`
+}
+
+// ValidationError 为签名请求参数校验失败，Fields 给出各字段的原因；HTTP 接口返回 400。
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// Add 追加字段 field 的校验错误。
+func (e *ValidationError) Add(field, format string, args ...any) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// Err 在有校验错误时返回 e，否则返回 nil。
+func (e *ValidationError) Err() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		parts[i] = f.Field + ": " + f.Message
+	}
+	return "参数校验失败: " + strings.Join(parts, "；")
+}
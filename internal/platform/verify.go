@@ -0,0 +1,133 @@
+package platform
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"slices"
+	"sort"
+	"time"
+
+	"go_sign/internal/logging"
+	"go_sign/internal/metrics"
+)
+
+// verifyTimeout 为影子平台单次签名的超时时间。
+const verifyTimeout = 30 * time.Second
+
+// verifyResults 统计双跑校验结果。
+var verifyResults = metrics.Default.NewCounterVec(
+	"go_sign_verify_total",
+	"双跑校验次数，result 为 match、mismatch 或 error",
+	"platform", "against", "result",
+)
+
+// Verification 为一个平台的双跑校验配置：按比例抽样，用另一个平台重新签名并比较结果。
+type Verification struct {
+	// Platform 为处理请求的主平台。
+	Platform string
+	// Against 为用于对照的影子平台，通常为同一站点的另一种执行后端。
+	Against string
+	// SampleRate 为抽样比例，取值 (0, 1]。
+	SampleRate float64
+	// Ignore 为不参与比较的请求头或查询参数名，如含时间戳的 x-t。
+	Ignore []string
+}
+
+// verifying 在主平台签名成功后，对抽中的请求异步执行影子签名并比较。
+type verifying struct {
+	Platform
+	cfg    Verification
+	shadow Platform
+}
+
+// EnableVerification 为集合中的平台开启双跑校验，须在 Init 前调用。
+func (s *Set) EnableVerification(vs []Verification) error {
+	byName := make(map[string]Platform, len(s.platforms))
+	for _, p := range s.platforms {
+		byName[p.Name()] = p
+	}
+	wrap := make(map[string]*verifying, len(vs))
+	for _, v := range vs {
+		if _, ok := byName[v.Platform]; !ok {
+			return fmt.Errorf("双跑校验的平台 %s 未启用", v.Platform)
+		}
+		shadow, ok := byName[v.Against]
+		if !ok {
+			return fmt.Errorf("双跑校验的对照平台 %s 未启用", v.Against)
+		}
+		if v.Platform == v.Against {
+			return fmt.Errorf("平台 %s 不能与自身双跑校验", v.Platform)
+		}
+		wrap[v.Platform] = &verifying{cfg: v, shadow: shadow}
+		slog.Info("开启双跑校验", "platform", v.Platform, "against", v.Against, "sample_rate", v.SampleRate)
+	}
+	s.Wrap(func(p Platform) Platform {
+		v, ok := wrap[p.Name()]
+		if !ok {
+			return p
+		}
+		v.Platform = p
+		return v
+	})
+	return nil
+}
+
+// Unwrap 返回被包装的主平台。
+func (v *verifying) Unwrap() Platform { return v.Platform }
+
+// Sign 调用主平台签名；请求被抽中时，以低优先级在影子平台上异步重新签名并比较结果。
+func (v *verifying) Sign(ctx context.Context, req *SignRequest) (*SignResponse, error) {
+	res, err := v.Platform.Sign(ctx, req)
+	if err != nil || rand.Float64() >= v.cfg.SampleRate {
+		return res, err
+	}
+	// 脱离请求的取消信号，保留租户等信息，低优先级避免影响正常流量
+	sctx, cancel := context.WithTimeout(WithPriority(context.WithoutCancel(ctx), PriorityLow), verifyTimeout)
+	go func() {
+		defer cancel()
+		v.compare(sctx, req, res)
+	}()
+	return res, nil
+}
+
+// compare 执行影子签名并记录比较结果。
+func (v *verifying) compare(ctx context.Context, req *SignRequest, want *SignResponse) {
+	got, err := v.shadow.Sign(ctx, req)
+	if err != nil {
+		verifyResults.Inc(v.cfg.Platform, v.cfg.Against, "error")
+		slog.Warn("双跑校验影子签名失败", "err", err, "platform", v.cfg.Platform, "against", v.cfg.Against, "uri", req.URI)
+		return
+	}
+	diffs := append(v.diff("headers", want.Headers, got.Headers), v.diff("params", want.Params, got.Params)...)
+	if len(diffs) == 0 {
+		verifyResults.Inc(v.cfg.Platform, v.cfg.Against, "match")
+		return
+	}
+	verifyResults.Inc(v.cfg.Platform, v.cfg.Against, "mismatch")
+	slog.Warn("双跑校验结果不一致", "platform", v.cfg.Platform, "against", v.cfg.Against, "uri", req.URI, "diffs", diffs)
+}
+
+// diff 比较两组键值，返回不一致的键及两侧取值。
+func (v *verifying) diff(kind string, want, got map[string]string) []string {
+	keys := make(map[string]bool, len(want)+len(got))
+	for k := range want {
+		keys[k] = true
+	}
+	for k := range got {
+		keys[k] = true
+	}
+	var out []string
+	for k := range keys {
+		if slices.Contains(v.cfg.Ignore, k) {
+			continue
+		}
+		if want[k] != got[k] {
+			// 签名结果均为敏感值，脱敏后仍可从长度与摘要看出差异
+			out = append(out, fmt.Sprintf("%s.%s: %q != %q", kind, k, logging.Secret(want[k]), logging.Secret(got[k])))
+		}
+	}
+	sort.Strings(out)
+	return out
+}
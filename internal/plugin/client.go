@@ -0,0 +1,232 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go_sign/internal/logging"
+)
+
+// maxMessageSize 为单条插件消息的长度上限。
+const maxMessageSize = 4 << 20
+
+// ErrExited 表示插件进程已退出。
+var ErrExited = errors.New("插件进程已退出")
+
+type request struct {
+	ID     uint64 `json:"id"`
+	Method string `json:"method"`
+	Params any    `json:"params,omitempty"`
+}
+
+type response struct {
+	ID     uint64          `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// Client 为一个按本包协议通信的子进程，可并发调用。
+type Client struct {
+	name    string
+	timeout time.Duration
+
+	proc  *exec.Cmd
+	done  chan struct{} // 进程退出后关闭
+	wmu   sync.Mutex    // 串行写入 stdin
+	stdin io.WriteCloser
+	// closing 在 Close 时置位，此后进程退出属于预期
+	closing atomic.Bool
+
+	mu      sync.Mutex
+	nextID  uint64
+	pending map[uint64]chan response
+	exitErr error
+}
+
+// Start 启动 cmd 描述的子进程，name 用于日志。
+func Start(name string, cmd Command) (*Client, error) {
+	proc := exec.Command(cmd.Path, cmd.Args...)
+	proc.Env = append(os.Environ(), cmd.Env...)
+	stdin, err := proc.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("创建插件 stdin 失败: %w", err)
+	}
+	stdout, err := proc.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("创建插件 stdout 失败: %w", err)
+	}
+	stderr, err := proc.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("创建插件 stderr 失败: %w", err)
+	}
+	if err := proc.Start(); err != nil {
+		return nil, fmt.Errorf("启动插件 %s 失败: %w", cmd.Path, err)
+	}
+	slog.Info("插件进程已启动", "platform", name, "command", cmd.Path, "pid", proc.Process.Pid)
+	c := &Client{
+		name:    name,
+		timeout: cmd.Timeout,
+		proc:    proc,
+		done:    make(chan struct{}),
+		stdin:   stdin,
+		pending: make(map[uint64]chan response),
+	}
+	stderrDone := make(chan struct{})
+	go func() {
+		defer close(stderrDone)
+		c.logStderr(stderr)
+	}()
+	go c.readLoop(stdout, stderrDone)
+	return c, nil
+}
+
+// readLoop 读取插件响应并分发给等待中的调用，stdout、stderr 均关闭后回收进程。
+func (c *Client) readLoop(stdout io.Reader, stderrDone <-chan struct{}) {
+	sc := bufio.NewScanner(stdout)
+	sc.Buffer(make([]byte, 64<<10), maxMessageSize)
+	for sc.Scan() {
+		var resp response
+		if err := json.Unmarshal(sc.Bytes(), &resp); err != nil {
+			slog.Warn("插件响应不是合法 JSON，已忽略", "err", err, "platform", c.name, "line", logging.RedactText(sc.Text()))
+			continue
+		}
+		c.mu.Lock()
+		ch, ok := c.pending[resp.ID]
+		delete(c.pending, resp.ID)
+		c.mu.Unlock()
+		if !ok {
+			slog.Warn("插件响应没有对应的请求，已忽略", "platform", c.name, "id", resp.ID)
+			continue
+		}
+		ch <- resp
+	}
+
+	exitErr := ErrExited
+	if err := sc.Err(); err != nil {
+		exitErr = fmt.Errorf("%w: 读取 stdout 失败: %v", ErrExited, err)
+	}
+	<-stderrDone
+	if err := c.proc.Wait(); err != nil {
+		exitErr = fmt.Errorf("%w: %v", ErrExited, err)
+	}
+	c.mu.Lock()
+	c.exitErr = exitErr
+	c.pending = nil
+	c.mu.Unlock()
+	close(c.done)
+	if c.closing.Load() {
+		slog.Info("插件进程已退出", "platform", c.name)
+		return
+	}
+	slog.Warn("插件进程意外退出", "platform", c.name, "err", exitErr)
+}
+
+// logStderr 将插件 stderr 按行写入日志，行中敏感字段的取值脱敏。
+func (c *Client) logStderr(stderr io.Reader) {
+	sc := bufio.NewScanner(stderr)
+	sc.Buffer(make([]byte, 64<<10), maxMessageSize)
+	for sc.Scan() {
+		slog.Info("插件输出", "platform", c.name, "line", logging.RedactText(sc.Text()))
+	}
+}
+
+// Call 发送一次请求并等待响应，result 非 nil 时解码响应结果。
+func (c *Client) Call(ctx context.Context, method string, params, result any) error {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	ch := make(chan response, 1)
+	c.mu.Lock()
+	if c.pending == nil {
+		err := c.exitErr
+		c.mu.Unlock()
+		return err
+	}
+	c.nextID++
+	id := c.nextID
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	line, err := json.Marshal(request{ID: id, Method: method, Params: params})
+	if err == nil {
+		c.wmu.Lock()
+		_, err = c.stdin.Write(append(line, '\n'))
+		c.wmu.Unlock()
+	}
+	if err != nil {
+		c.forget(id)
+		return fmt.Errorf("发送插件请求失败: %w", err)
+	}
+
+	select {
+	case resp := <-ch:
+		if resp.Error != "" {
+			return errors.New(resp.Error)
+		}
+		if result != nil {
+			if err := json.Unmarshal(resp.Result, result); err != nil {
+				return fmt.Errorf("解析插件响应失败: %w", err)
+			}
+		}
+		return nil
+	case <-c.done:
+		return c.exitErr
+	case <-ctx.Done():
+		c.forget(id)
+		return fmt.Errorf("等待插件响应超时: %w", ctx.Err())
+	}
+}
+
+// forget 移除未完成的请求。
+func (c *Client) forget(id uint64) {
+	c.mu.Lock()
+	delete(c.pending, id)
+	c.mu.Unlock()
+}
+
+// Exited 判断进程是否已退出。
+func (c *Client) Exited() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Pending 返回尚未收到响应的请求数。
+func (c *Client) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.pending)
+}
+
+// Close 调用 shutdown 通知进程退出，5 秒内未退出时强制结束。
+func (c *Client) Close() error {
+	c.closing.Store(true)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = c.Call(ctx, "shutdown", nil, nil)
+	_ = c.stdin.Close()
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+		slog.Warn("插件未按时退出，强制结束", "platform", c.name)
+		_ = c.proc.Process.Kill()
+		<-c.done
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+// Package plugin 支持以子进程运行的外部签名插件。
+//
+// 插件是任意语言编写的可执行文件，与本服务通过标准输入输出交换按行分隔的 JSON 消息：
+//
+//	请求（stdin）：{"id": 1, "method": "sign", "params": {...}}
+//	响应（stdout）：{"id": 1, "result": {...}} 或 {"id": 1, "error": "..."}
+//
+// 方法依次为：
+//   - init：启动后调用一次，params 为 {"name": 平台名, "options": 平台配置项}；
+//   - sign：params 为通用签名请求（uri、method、data、params、cookies、user_agent）
+//     及调用方的 tenant、priority，result 为 {"headers": {...}, "params": {...}}；
+//   - health：健康检查，返回任意 result 表示健康；
+//   - shutdown：退出前调用，插件应在响应后退出。
+//
+// 请求可能并发发出，插件可按任意顺序返回响应，以 id 对应。stderr 输出写入服务日志。
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"go_sign/internal/platform"
+)
+
+// Command 描述插件的启动方式。
+type Command struct {
+	// Path 为插件可执行文件路径。
+	Path string
+	// Args 为启动参数。
+	Args []string
+	// Env 为追加到插件进程的环境变量，格式为 KEY=VALUE。
+	Env []string
+	// Timeout 为单次调用的超时时间，0 表示只受 ctx 限制。
+	Timeout time.Duration
+}
+
+// Register 将 name 注册为由 cmd 提供签名的插件平台，name 已被内置平台占用时返回错误。
+func Register(name string, cmd Command) error {
+	if slices.Contains(platform.Names(), name) {
+		return fmt.Errorf("插件平台 %s 与已注册的平台重名", name)
+	}
+	platform.Register(name, func(decode platform.Decoder) (platform.Platform, error) {
+		p := &Platform{name: name, cmd: cmd}
+		if err := decode(&p.options); err != nil {
+			return nil, err
+		}
+		return p, nil
+	})
+	return nil
+}
+
+// signParams 为 sign 方法的参数。
+type signParams struct {
+	*platform.SignRequest
+	Tenant   string `json:"tenant"`
+	Priority string `json:"priority"`
+}
+
+// Platform 为一个外部插件平台，调用转发给插件子进程。
+type Platform struct {
+	name    string
+	cmd     Command
+	options map[string]any
+	client  *Client
+}
+
+// Name 返回平台名称。
+func (p *Platform) Name() string { return p.name }
+
+// Init 启动插件进程并调用 init。
+func (p *Platform) Init(ctx context.Context, _ *platform.Env) error {
+	c, err := Start(p.name, p.cmd)
+	if err != nil {
+		return err
+	}
+	args := map[string]any{"name": p.name, "options": p.options}
+	if err := c.Call(ctx, "init", args, nil); err != nil {
+		_ = c.Close()
+		return fmt.Errorf("插件 init 失败: %w", err)
+	}
+	p.client = c
+	return nil
+}
+
+// Sign 将签名请求转发给插件。
+func (p *Platform) Sign(ctx context.Context, req *platform.SignRequest) (*platform.SignResponse, error) {
+	params := signParams{
+		SignRequest: req,
+		Tenant:      platform.TenantFrom(ctx),
+		Priority:    platform.PriorityFrom(ctx).String(),
+	}
+	res := &platform.SignResponse{}
+	if err := p.client.Call(ctx, "sign", params, res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// HealthCheck 调用插件的 health 方法。
+func (p *Platform) HealthCheck(ctx context.Context) error {
+	return p.client.Call(ctx, "health", nil, nil)
+}
+
+// Close 通知插件退出。
+func (p *Platform) Close() error {
+	if p.client == nil {
+		return nil
+	}
+	return p.client.Close()
+}
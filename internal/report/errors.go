@@ -0,0 +1,82 @@
+package report
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader 为请求 ID 的请求头与响应头。
+const RequestIDHeader = "X-Request-Id"
+
+// RequestIDKey 为请求 ID 在 gin.Context 中的键，访问日志可从 gin.LogFormatterParams.Keys 中读取。
+const RequestIDKey = "go_sign.request_id"
+
+// maxRequestIDLen 为沿用调用方请求 ID 的长度上限。
+const maxRequestIDLen = 128
+
+// 路由与 panic 错误应答中的 code，与 platform 中签名失败的 code 同属一个取值空间。
+const (
+	ErrorCodeNotFound         = "not_found"
+	ErrorCodeMethodNotAllowed = "method_not_allowed"
+	ErrorCodeInternal         = "internal"
+)
+
+// RequestID 返回为每个请求分配请求 ID 的中间件：沿用调用方（或网关）X-Request-Id 中的合法值，否则随机生成，
+// 并在响应头 X-Request-Id 中返回，错误应答的 request_id 与访问日志中的取值相同。
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			b := make([]byte, 16)
+			_, _ = rand.Read(b)
+			id = hex.EncodeToString(b)
+		}
+		c.Set(RequestIDKey, id)
+		c.Header(RequestIDHeader, id)
+		c.Next()
+	}
+}
+
+// validRequestID 判断 id 是否可直接沿用：非空、不超过 maxRequestIDLen，且只含字母、数字与 -_.:。
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// RequestIDFrom 返回 RequestID 为请求分配的 ID，未使用该中间件时为空。
+func RequestIDFrom(c *gin.Context) string {
+	return c.GetString(RequestIDKey)
+}
+
+// ErrorBody 返回路由与 panic 错误应答的 JSON：{"error": msg, "code": code, "request_id": ...}。
+func ErrorBody(c *gin.Context, code, msg string) gin.H {
+	body := gin.H{"error": msg, "code": code}
+	if id := RequestIDFrom(c); id != "" {
+		body["request_id"] = id
+	}
+	return body
+}
+
+// HandleErrors 使 r 上不存在的路由返回 404、路由存在但请求方法不符时返回 405，应答均为 ErrorBody 格式的 JSON，
+// 代替 gin 默认的纯文本应答。已通过 NoRoute 设置其他处理函数的引擎（如降级启动）不应调用。
+func HandleErrors(r *gin.Engine) {
+	r.HandleMethodNotAllowed = true
+	r.NoRoute(func(c *gin.Context) {
+		c.AbortWithStatusJSON(http.StatusNotFound, ErrorBody(c, ErrorCodeNotFound, "路由不存在: "+c.Request.Method+" "+c.Request.URL.Path))
+	})
+	r.NoMethod(func(c *gin.Context) {
+		c.AbortWithStatusJSON(http.StatusMethodNotAllowed, ErrorBody(c, ErrorCodeMethodNotAllowed, "路由不支持请求方法 "+c.Request.Method+": "+c.Request.URL.Path))
+	})
+}
@@ -0,0 +1,70 @@
+package report
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Recovery 返回替代 gin.Recovery 的中间件：请求处理 panic 时上报事件（含调用栈与请求 ID）并返回 500，
+// 应答为 ErrorBody 格式的 JSON，code 为 internal。
+func Recovery() gin.HandlerFunc {
+	return gin.CustomRecovery(func(c *gin.Context, recovered any) {
+		Error("请求处理 panic", fmt.Errorf("%w: %v", errPanic, recovered),
+			"method", c.Request.Method, "route", c.FullPath(), "request_id", RequestIDFrom(c), "stack", string(debug.Stack()))
+		c.AbortWithStatusJSON(http.StatusInternalServerError, ErrorBody(c, ErrorCodeInternal, "服务内部错误"))
+	})
+}
+
+// ObserveSign 统计平台的签名失败，同一平台在窗口期内失败次数达到阈值时上报一次。
+func ObserveSign(platform string, err error) {
+	r := current()
+	if r == nil || err == nil {
+		return
+	}
+	if n, ok := r.signErrors.observe(platform, time.Now()); ok {
+		Error("签名持续失败", err, "platform", platform, "failures", n, "window", r.signErrors.window.String())
+	}
+}
+
+// signErrors 按平台统计窗口期内的签名失败次数。
+type signErrors struct {
+	threshold int
+	window    time.Duration
+
+	mu      sync.Mutex
+	windows map[string]*errorWindow
+}
+
+type errorWindow struct {
+	start time.Time
+	count int
+}
+
+// newSignErrors 创建统计器，threshold、window 为 0 时分别取 10 次、1 分钟。
+func newSignErrors(threshold int, window time.Duration) *signErrors {
+	if threshold <= 0 {
+		threshold = 10
+	}
+	if window <= 0 {
+		window = time.Minute
+	}
+	return &signErrors{threshold: threshold, window: window, windows: make(map[string]*errorWindow)}
+}
+
+// observe 记录一次失败，返回窗口内的失败次数及本次是否恰好达到阈值。
+func (s *signErrors) observe(platform string, now time.Time) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w := s.windows[platform]
+	if w == nil || now.Sub(w.start) > s.window {
+		w = &errorWindow{start: now}
+		s.windows[platform] = w
+	}
+	w.count++
+	return w.count, w.count == s.threshold
+}
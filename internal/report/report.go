@@ -0,0 +1,185 @@
+// Package report 将 panic、初始化失败与持续的签名失败上报到 Sentry 或通用 webhook，
+// 避免长期运行的部署中崩溃与故障无人察觉。未调用 Init 时所有上报均为空操作。
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"go_sign/internal/config"
+)
+
+// 事件级别。
+const (
+	LevelError = "error"
+	LevelFatal = "fatal"
+)
+
+// Event 为上报到 webhook 的事件。
+type Event struct {
+	Time        time.Time      `json:"time"`
+	Level       string         `json:"level"`
+	Message     string         `json:"message"`
+	Error       string         `json:"error,omitempty"`
+	Host        string         `json:"host"`
+	Environment string         `json:"environment,omitempty"`
+	Context     map[string]any `json:"context,omitempty"`
+}
+
+// reporter 为已初始化的上报配置。
+type reporter struct {
+	sentry      bool
+	webhook     string
+	environment string
+	host        string
+	client      *http.Client
+	pending     sync.WaitGroup
+	signErrors  *signErrors
+}
+
+var (
+	mu  sync.RWMutex
+	std *reporter
+)
+
+// Init 按配置启用上报，c 为 nil 时不上报。
+func Init(c *config.ErrorReport) error {
+	if c == nil {
+		return nil
+	}
+	host, _ := os.Hostname()
+	r := &reporter{
+		webhook:     c.WebhookURL,
+		environment: c.Environment,
+		host:        host,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		signErrors:  newSignErrors(c.SignErrorThreshold, c.SignErrorWindow),
+	}
+	if c.SentryDSN != "" {
+		if err := sentry.Init(sentry.ClientOptions{Dsn: c.SentryDSN, Environment: c.Environment, ServerName: host}); err != nil {
+			return fmt.Errorf("初始化 Sentry 失败: %w", err)
+		}
+		r.sentry = true
+	}
+	mu.Lock()
+	std = r
+	mu.Unlock()
+	slog.Info("错误上报已开启", "sentry", r.sentry, "webhook", c.WebhookURL != "", "environment", c.Environment)
+	return nil
+}
+
+// current 返回当前的上报配置，未初始化时为 nil。
+func current() *reporter {
+	mu.RLock()
+	defer mu.RUnlock()
+	return std
+}
+
+// Error 上报一个错误事件，kv 为与 slog 相同的键值对上下文。
+func Error(msg string, err error, kv ...any) {
+	capture(LevelError, msg, err, kv)
+}
+
+// Fatal 上报一个致命错误并等待发送完成，用于进程退出前。
+func Fatal(msg string, err error, kv ...any) {
+	capture(LevelFatal, msg, err, kv)
+	Flush(5 * time.Second)
+}
+
+// capture 异步发送事件到已配置的各上报端。
+func capture(level, msg string, err error, kv []any) {
+	r := current()
+	if r == nil {
+		return
+	}
+	ev := Event{Time: time.Now(), Level: level, Message: msg, Host: r.host, Environment: r.environment, Context: toMap(kv)}
+	if err != nil {
+		ev.Error = err.Error()
+	}
+	if r.sentry {
+		sentry.WithScope(func(scope *sentry.Scope) {
+			scope.SetLevel(sentry.Level(level))
+			scope.SetContext("go_sign", ev.Context)
+			if err != nil {
+				scope.SetExtra("message", msg)
+				sentry.CaptureException(err)
+				return
+			}
+			sentry.CaptureMessage(msg)
+		})
+	}
+	if r.webhook != "" {
+		r.pending.Add(1)
+		go func() {
+			defer r.pending.Done()
+			if err := r.post(ev); err != nil {
+				slog.Warn("错误上报 webhook 发送失败", "err", err, "message", msg)
+			}
+		}()
+	}
+}
+
+// post 以 JSON 发送事件到 webhook。
+func (r *reporter) post(ev Event) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	resp, err := r.client.Post(r.webhook, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook 返回状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Flush 等待已提交的事件发送完成，至多等待 timeout。
+func Flush(timeout time.Duration) {
+	r := current()
+	if r == nil {
+		return
+	}
+	if r.sentry {
+		sentry.Flush(timeout)
+	}
+	done := make(chan struct{})
+	go func() {
+		r.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+}
+
+// toMap 将 slog 风格的键值对转换为 map，值为 error 时取其文本。
+func toMap(kv []any) map[string]any {
+	if len(kv) == 0 {
+		return nil
+	}
+	out := make(map[string]any, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		k := fmt.Sprint(kv[i])
+		if err, ok := kv[i+1].(error); ok {
+			out[k] = err.Error()
+			continue
+		}
+		out[k] = kv[i+1]
+	}
+	return out
+}
+
+// errPanic 包装 panic 的值，使其可作为 error 上报。
+var errPanic = errors.New("panic")
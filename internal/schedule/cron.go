@@ -0,0 +1,135 @@
+// Package schedule 解析标准 5 段 cron 表达式（分 时 日 月 周），用于按维护窗口执行定时任务。
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron 为解析后的 cron 表达式，各字段以位图表示允许的取值。
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+	// domAny、dowAny 表示日、周字段为 *；两者都受限时按 cron 惯例满足其一即可
+	domAny, dowAny bool
+}
+
+// field 为 cron 字段的取值范围。
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = []field{
+	{"分", 0, 59},
+	{"时", 0, 23},
+	{"日", 1, 31},
+	{"月", 1, 12},
+	{"周", 0, 7}, // 0 与 7 均表示周日
+}
+
+// Parse 解析 cron 表达式，如 "0 4 * * *" 表示每天 04:00。
+// 每个字段支持 *、数字、范围 a-b、步长 */n 或 a-b/n，以及逗号分隔的列表。
+func Parse(expr string) (*Cron, error) {
+	parts := strings.Fields(expr)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("cron 表达式 %q 须为 5 段（分 时 日 月 周）", expr)
+	}
+	var bits [5]uint64
+	for i, p := range parts {
+		b, err := parseField(p, fields[i])
+		if err != nil {
+			return nil, fmt.Errorf("cron 表达式 %q: %w", expr, err)
+		}
+		bits[i] = b
+	}
+	// 与常见 cron 实现相同，以 * 开头（含 */n）的日、周字段视为不限制
+	c := &Cron{minute: bits[0], hour: bits[1], dom: bits[2], month: bits[3], dow: bits[4],
+		domAny: strings.HasPrefix(parts[2], "*"), dowAny: strings.HasPrefix(parts[4], "*")}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	// 如 2 月 30 日等永远不会到达的表达式在解析时拒绝；5 年内必然经过一个闰年的 2 月 29 日
+	if c.Next(time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)).IsZero() {
+		return nil, fmt.Errorf("cron 表达式 %q 没有可执行的时间", expr)
+	}
+	return c, nil
+}
+
+// parseField 将单个字段解析为位图。
+func parseField(s string, f field) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(s, ",") {
+		rng, step := item, 1
+		if i := strings.IndexByte(item, '/'); i >= 0 {
+			n, err := strconv.Atoi(item[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("%s字段步长 %q 不合法", f.name, item)
+			}
+			rng, step = item[:i], n
+		}
+		lo, hi := f.min, f.max
+		if rng != "*" {
+			var err error
+			a, b, isRange := strings.Cut(rng, "-")
+			if lo, err = strconv.Atoi(a); err != nil {
+				return 0, fmt.Errorf("%s字段 %q 不合法", f.name, item)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(b); err != nil {
+					return 0, fmt.Errorf("%s字段 %q 不合法", f.name, item)
+				}
+			} else if step > 1 {
+				hi = f.max
+			}
+		}
+		if lo < f.min || hi > f.max || lo > hi {
+			return 0, fmt.Errorf("%s字段 %q 超出范围 %d-%d", f.name, item, f.min, f.max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+// Next 返回 t 之后（不含 t 所在的分钟）第一个满足表达式的时刻，按 t 的时区计算；五年内无匹配时返回零值。
+// 月、日、时不满足时整段跳过，夏令时跳过的时刻顺延到其后第一个存在的时刻。
+func (c *Cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	loc := t.Location()
+	for end := t.AddDate(5, 0, 0); t.Before(end); {
+		switch {
+		case c.month&(1<<int(t.Month())) == 0:
+			t = after(t, time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc))
+		case !c.matchDay(t):
+			t = after(t, time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc))
+		case c.hour&(1<<t.Hour()) == 0:
+			t = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+		case c.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// after 返回跳转的目标时刻 next；夏令时切换使 next 不晚于 t 时改为前进一分钟，保证 Next 的搜索总在前进。
+func after(t, next time.Time) time.Time {
+	if next.After(t) {
+		return next
+	}
+	return t.Add(time.Minute)
+}
+
+// matchDay 判断 t 的日期是否满足日、周字段：两者都受限时按 cron 惯例满足其一即可。
+func (c *Cron) matchDay(t time.Time) bool {
+	dom, dow := c.dom&(1<<t.Day()) != 0, c.dow&(1<<int(t.Weekday())) != 0
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
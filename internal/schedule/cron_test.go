@@ -0,0 +1,93 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"0 4 * *",
+		"0 4 * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"1,,2 * * * *",
+		"a * * * *",
+		"-1 * * * *",
+		"0 0 30 2 *",
+		"0 0 31 4,6,9,11 *",
+	} {
+		if _, err := Parse(expr); err == nil {
+			t.Errorf("Parse(%q) 应返回错误", expr)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	shanghai := time.FixedZone("CST", 8*3600)
+	india := time.FixedZone("IST", 5*3600+1800)
+	at := func(loc *time.Location, y int, m time.Month, d, h, min int) time.Time {
+		return time.Date(y, m, d, h, min, 0, 0, loc)
+	}
+	tests := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"0 4 * * *", at(shanghai, 2024, 1, 1, 3, 59), at(shanghai, 2024, 1, 1, 4, 0)},
+		{"0 4 * * *", at(shanghai, 2024, 1, 1, 4, 0), at(shanghai, 2024, 1, 2, 4, 0)},
+		{"0 4 * * *", at(shanghai, 2024, 12, 31, 5, 0), at(shanghai, 2025, 1, 1, 4, 0)},
+		{"*/15 * * * *", at(shanghai, 2024, 1, 1, 0, 16), at(shanghai, 2024, 1, 1, 0, 30)},
+		{"5/20 * * * *", at(shanghai, 2024, 1, 1, 0, 26), at(shanghai, 2024, 1, 1, 0, 45)},
+		{"0 9-17/4 * * *", at(shanghai, 2024, 1, 1, 14, 0), at(shanghai, 2024, 1, 1, 17, 0)},
+		{"0 0 1,15 * *", at(shanghai, 2024, 1, 2, 0, 0), at(shanghai, 2024, 1, 15, 0, 0)},
+		{"0 0 31 * *", at(shanghai, 2024, 2, 1, 0, 0), at(shanghai, 2024, 3, 31, 0, 0)},
+		{"0 0 29 2 *", at(shanghai, 2024, 3, 1, 0, 0), at(shanghai, 2028, 2, 29, 0, 0)},
+		// 周日可写作 0 或 7；2024-01-07 为周日
+		{"0 3 * * 0", at(shanghai, 2024, 1, 1, 0, 0), at(shanghai, 2024, 1, 7, 3, 0)},
+		{"0 3 * * 7", at(shanghai, 2024, 1, 1, 0, 0), at(shanghai, 2024, 1, 7, 3, 0)},
+		{"0 3 * * 5-7", at(shanghai, 2024, 1, 1, 0, 0), at(shanghai, 2024, 1, 5, 3, 0)},
+		// 日、周都受限时满足其一即可：1 月 10 日之前的第一个周六为 1 月 6 日
+		{"0 0 10 * 6", at(shanghai, 2024, 1, 1, 0, 0), at(shanghai, 2024, 1, 6, 0, 0)},
+		// */2 的日字段视为不限制，须同时满足周字段：2024-01-03 为周三、且为奇数日
+		{"0 0 */2 * 3", at(shanghai, 2024, 1, 1, 0, 0), at(shanghai, 2024, 1, 3, 0, 0)},
+		{"0 0 * 2 *", at(shanghai, 2024, 3, 1, 0, 0), at(shanghai, 2025, 2, 1, 0, 0)},
+		// 非整点偏移的时区按本地时间计算
+		{"0 4 * * *", at(india, 2024, 1, 1, 3, 0), at(india, 2024, 1, 1, 4, 0)},
+		// 秒数不影响结果，且不含 from 所在的分钟
+		{"* * * * *", time.Date(2024, 1, 1, 0, 0, 59, 0, shanghai), at(shanghai, 2024, 1, 1, 0, 1)},
+	}
+	for _, tt := range tests {
+		c, err := Parse(tt.expr)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.expr, err)
+		}
+		if got := c.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("%q.Next(%s) = %s，期望 %s", tt.expr, tt.from, got, tt.want)
+		}
+	}
+}
+
+func TestNextDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("缺少时区数据:", err)
+	}
+	c, err := Parse("30 2 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 2024-03-10 02:00 起跳过一小时，当天没有 02:30，顺延到次日
+	got := c.Next(time.Date(2024, 3, 10, 0, 0, 0, 0, ny))
+	if want := time.Date(2024, 3, 11, 2, 30, 0, 0, ny); !got.Equal(want) {
+		t.Errorf("Next = %s，期望 %s", got, want)
+	}
+}
@@ -0,0 +1,210 @@
+package script
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+// embeddedTimeout 为加载脚本与单次签名的最长执行时间。
+const embeddedTimeout = 10 * time.Second
+
+// embeddedMaxCallStack 为脚本的最大调用栈深度，避免无限递归耗尽 Go 协程栈。
+const embeddedMaxCallStack = 10000
+
+// embeddedPreludeJS 先于脚本执行：补齐 window / self / console 与定时器，
+// 并定义 __goSignCall，以 JSON 文本接收参数、返回结果，调用期间按 timestamp 固定 Date。
+const embeddedPreludeJS = `(() => {
+  const g = globalThis;
+  const noop = () => {};
+  g.window = g.self = g;
+  g.console = { log: noop, info: noop, debug: noop, warn: noop, error: noop };
+  g.setTimeout = g.setInterval = () => 0;
+  g.clearTimeout = g.clearInterval = noop;
+  const RealDate = g.Date;
+  const pinned = (ts) => class extends RealDate {
+    constructor(...args) {
+      if (args.length) {
+        super(...args);
+      } else {
+        super(ts);
+      }
+    }
+    static now() {
+      return ts;
+    }
+  };
+  const call = (fn, msg) => {
+    const params = JSON.parse(msg);
+    const keys = fn.split('.');
+    const name = keys.pop();
+    const obj = keys.reduce((o, k) => (o == null ? o : o[k]), g);
+    if (obj == null || typeof obj[name] !== 'function') {
+      throw new Error('window.' + fn + ' 不是函数');
+    }
+    if (params == null) return 'true';
+    if (typeof params.cookie === 'string' && g.document) g.document.cookie = params.cookie;
+    if (params.timestamp) g.Date = pinned(params.timestamp);
+    try {
+      const result = obj[name].call(obj, params.uri, params.data, params.extra);
+      if (result && typeof result.then === 'function') throw new Error('不支持异步签名函数');
+      return JSON.stringify(result === undefined ? null : result);
+    } finally {
+      g.Date = RealDate;
+    }
+  };
+  Object.defineProperty(g, '__goSignCall', { value: call });
+})();`
+
+// EmbeddedPool 为在进程内嵌的 JS 引擎（goja）中执行签名脚本的一组运行时，每个运行时同一时间只执行一次签名。
+// 运行时中只有 ECMAScript 内置对象与前置代码补齐的对象，脚本无法访问文件系统、网络、环境变量与子进程，
+// 用于执行从站点提取的脚本。
+type EmbeddedPool struct {
+	name    string
+	fn      string
+	prelude *goja.Program
+	program *goja.Program
+	idle    chan *goja.Runtime // nil 表示运行时待重新加载
+	closed  chan struct{}
+	once    sync.Once
+}
+
+// StartEmbeddedPool 创建 size 个运行时，每个运行时加载 file 并通过 fn 签名，签名函数须同步返回。
+func StartEmbeddedPool(name, file, fn string, size int) (*EmbeddedPool, error) {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	program, err := goja.Compile(file, string(src), false)
+	if err != nil {
+		return nil, fmt.Errorf("编译签名脚本失败: %w", err)
+	}
+	p := &EmbeddedPool{
+		name:    name,
+		fn:      fn,
+		prelude: goja.MustCompile("prelude.js", embeddedPreludeJS, false),
+		program: program,
+		idle:    make(chan *goja.Runtime, size),
+		closed:  make(chan struct{}),
+	}
+	for i := 0; i < size; i++ {
+		vm, err := p.load()
+		if err != nil {
+			return nil, fmt.Errorf("运行时 %d 加载签名脚本失败: %w", i, err)
+		}
+		p.idle <- vm
+	}
+	slog.Info("内嵌 JS 签名运行时已就绪", "platform", name, "size", size, "script", file)
+	return p, nil
+}
+
+// load 创建运行时，执行前置代码与签名脚本，并确认签名函数存在。
+func (p *EmbeddedPool) load() (*goja.Runtime, error) {
+	vm := goja.New()
+	vm.SetMaxCallStackSize(embeddedMaxCallStack)
+	ctx, cancel := context.WithTimeout(context.Background(), embeddedTimeout)
+	defer cancel()
+	stop := interruptOnDone(ctx, vm)
+	_, err := vm.RunProgram(p.prelude)
+	if err == nil {
+		_, err = vm.RunProgram(p.program)
+	}
+	stop()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.call(context.Background(), vm, nil); err != nil {
+		return nil, err
+	}
+	return vm, nil
+}
+
+// call 在 vm 中调用签名函数，params 为 nil 时只检查签名函数是否存在。
+// 超时或 ctx 取消时中断脚本并返回错误，此时运行时的全局状态不再可信。
+func (p *EmbeddedPool) call(ctx context.Context, vm *goja.Runtime, params map[string]any) (any, error) {
+	call, ok := goja.AssertFunction(vm.Get("__goSignCall"))
+	if !ok {
+		return nil, errors.New("前置代码未定义 __goSignCall")
+	}
+	msg, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, embeddedTimeout)
+	defer cancel()
+	stop := interruptOnDone(ctx, vm)
+	out, err := call(goja.Undefined(), vm.ToValue(p.fn), vm.ToValue(string(msg)))
+	stop()
+	if err != nil {
+		return nil, err
+	}
+	var v any
+	if err := json.Unmarshal([]byte(out.String()), &v); err != nil {
+		return nil, fmt.Errorf("解析签名结果失败: %w", err)
+	}
+	return v, nil
+}
+
+// interruptOnDone 在 ctx 结束时中断 vm 中正在执行的脚本；返回的 stop 须在脚本返回后调用，
+// 清除脚本返回后才到达的中断，避免影响下一次执行。
+func interruptOnDone(ctx context.Context, vm *goja.Runtime) (stop func()) {
+	var mu sync.Mutex
+	done := false
+	cancel := context.AfterFunc(ctx, func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if !done {
+			vm.Interrupt(ctx.Err())
+		}
+	})
+	return func() {
+		cancel()
+		mu.Lock()
+		done = true
+		mu.Unlock()
+		vm.ClearInterrupt()
+	}
+}
+
+// SignWithCookie 在一个空闲运行时中将 document.cookie 设为 cookie，以 fn(uri, data) 调用签名函数并返回原始结果，
+// timestamp 非 0 时调用期间固定 Date。没有空闲运行时时等待，直到 ctx 取消。
+func (p *EmbeddedPool) SignWithCookie(ctx context.Context, uri string, data any, cookie string, timestamp int64) (any, error) {
+	var vm *goja.Runtime
+	select {
+	case vm = <-p.idle:
+	case <-p.closed:
+		return nil, errors.New("内嵌 JS 运行时已关闭")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if vm == nil {
+		loaded, err := p.load()
+		if err != nil {
+			p.idle <- nil
+			return nil, fmt.Errorf("重新加载签名脚本失败: %w", err)
+		}
+		vm = loaded
+	}
+	v, err := p.call(ctx, vm, map[string]any{"uri": uri, "data": data, "cookie": cookie, "timestamp": timestamp})
+	var ierr *goja.InterruptedError
+	if errors.As(err, &ierr) {
+		// 被中断的脚本可能停在任意位置，丢弃该运行时，下次取用时重新加载
+		slog.Warn("签名脚本执行被中断，丢弃运行时", "platform", p.name, "reason", ierr.Value())
+		vm, err = nil, fmt.Errorf("签名脚本执行被中断: %v", ierr.Value())
+	}
+	p.idle <- vm
+	return v, err
+}
+
+// Close 停止接受新的签名，正在执行的签名不受影响。
+func (p *EmbeddedPool) Close() error {
+	p.once.Do(func() { close(p.closed) })
+	return nil
+}
@@ -0,0 +1,101 @@
+package script
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeScript 将 src 写入临时文件并返回路径。
+func writeScript(t *testing.T, src string) string {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "sign.js")
+	if err := os.WriteFile(file, []byte(src), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func TestEmbeddedPool(t *testing.T) {
+	file := writeScript(t, `
+		globalThis.document = { cookie: '' };
+		window._webmsxyw = function (uri, data) {
+			return { uri, data, cookie: document.cookie, now: Date.now(), year: new Date().getUTCFullYear() };
+		};
+		window.probe = function () {
+			return { require: typeof require, process: typeof process, fetch: typeof fetch, XMLHttpRequest: typeof XMLHttpRequest };
+		};
+	`)
+	ctx := context.Background()
+
+	p, err := StartEmbeddedPool("test", file, "_webmsxyw", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).UnixMilli()
+	v, err := p.SignWithCookie(ctx, "/api/x", map[string]any{"a": 1}, "a1=abc", ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := v.(map[string]any)
+	if m["uri"] != "/api/x" || m["cookie"] != "a1=abc" || m["data"].(map[string]any)["a"] != float64(1) {
+		t.Errorf("签名结果 = %v", m)
+	}
+	if m["now"] != float64(ts) || m["year"] != float64(2024) {
+		t.Errorf("Date 未固定为 timestamp: %v", m)
+	}
+
+	// 运行时中没有宿主对象
+	probe, err := StartEmbeddedPool("test", file, "probe", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err = probe.SignWithCookie(ctx, "", nil, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, typ := range v.(map[string]any) {
+		if typ != "undefined" {
+			t.Errorf("%s 在运行时中可见: %v", k, typ)
+		}
+	}
+
+	if _, err := StartEmbeddedPool("test", file, "missing", 1); err == nil || !strings.Contains(err.Error(), "不是函数") {
+		t.Errorf("签名函数不存在时应加载失败，实际 %v", err)
+	}
+}
+
+func TestEmbeddedPoolInterrupt(t *testing.T) {
+	file := writeScript(t, `
+		let calls = 0;
+		window.sign = function (uri) {
+			calls++;
+			if (uri === 'loop') for (;;) {}
+			return calls;
+		};
+	`)
+	p, err := StartEmbeddedPool("test", file, "sign", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := p.SignWithCookie(ctx, "loop", nil, "", 0); err == nil || !strings.Contains(err.Error(), "中断") {
+		t.Fatalf("死循环的签名应被中断，实际 %v", err)
+	}
+	// 被中断的运行时已丢弃，重新加载后状态从头开始
+	v, err := p.SignWithCookie(context.Background(), "ok", nil, "", 0)
+	if err != nil || v != float64(1) {
+		t.Errorf("中断后签名 = %v, %v，期望在新运行时中执行", v, err)
+	}
+
+	p.Close()
+	if _, err := p.SignWithCookie(context.Background(), "ok", nil, "", 0); err == nil {
+		t.Error("关闭后应拒绝签名")
+	}
+}
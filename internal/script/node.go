@@ -0,0 +1,125 @@
+package script
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"go_sign/internal/plugin"
+)
+
+// nodeRunnerJS 为 Node.js 子进程中执行的签名执行器。
+//
+//go:embed node_runner.js
+var nodeRunnerJS string
+
+// nodePool 为执行签名脚本的一组 Node.js 子进程，进程退出后在下次选中时重启。
+type nodePool struct {
+	name string
+	cmd  plugin.Command
+
+	mu      sync.Mutex
+	clients []*plugin.Client
+	next    int
+}
+
+// startNodePool 启动 size 个 Node.js 子进程，每个进程加载 file 并通过 fn 签名。
+func startNodePool(name, node, file, fn string, size int) (*nodePool, error) {
+	p := &nodePool{
+		name: name,
+		cmd: plugin.Command{
+			Path: node,
+			Args: []string{"-e", nodeRunnerJS},
+			Env:  []string{"GO_SIGN_SCRIPT=" + file, "GO_SIGN_FUNCTION=" + fn},
+		},
+		clients: make([]*plugin.Client, size),
+	}
+	for i := range p.clients {
+		c, err := plugin.Start(name, p.cmd)
+		if err != nil {
+			_ = p.close()
+			return nil, fmt.Errorf("启动 Node.js 进程失败: %w", err)
+		}
+		p.clients[i] = c
+	}
+	// 逐个确认脚本加载成功且签名函数存在
+	for i, c := range p.clients {
+		if err := c.Call(context.Background(), "health", nil, nil); err != nil {
+			_ = p.close()
+			return nil, fmt.Errorf("Node.js 进程 %d 加载签名脚本失败: %w", i, err)
+		}
+	}
+	slog.Info("Node.js 签名进程已就绪", "platform", name, "size", size, "script", file)
+	return p, nil
+}
+
+// pick 选出待处理请求最少的进程，已退出的进程先重启。
+func (p *nodePool) pick() (*plugin.Client, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var best *plugin.Client
+	for n := 0; n < len(p.clients); n++ {
+		i := (p.next + n) % len(p.clients)
+		c := p.clients[i]
+		if c.Exited() {
+			slog.Warn("Node.js 进程已退出，重启", "platform", p.name, "index", i)
+			restarted, err := plugin.Start(p.name, p.cmd)
+			if err != nil {
+				slog.Error("重启 Node.js 进程失败", "err", err, "platform", p.name, "index", i)
+				continue
+			}
+			c, p.clients[i] = restarted, restarted
+		}
+		if best == nil || c.Pending() < best.Pending() {
+			best = c
+		}
+	}
+	p.next = (p.next + 1) % len(p.clients)
+	if best == nil {
+		return nil, errors.New("没有可用的 Node.js 进程")
+	}
+	return best, nil
+}
+
+// sign 在一个进程中调用签名函数并返回原始结果，
+// timestamp 非 0 时调用期间固定 Date。
+func (p *nodePool) sign(ctx context.Context, uri string, data, extra any, timestamp int64) (any, error) {
+	c, err := p.pick()
+	if err != nil {
+		return nil, err
+	}
+	var v any
+	params := map[string]any{"uri": uri, "data": data, "extra": extra, "timestamp": timestamp}
+	if err := c.Call(ctx, "sign", params, &v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// check 检查全部进程上的签名函数是否可用。
+func (p *nodePool) check(ctx context.Context) error {
+	p.mu.Lock()
+	clients := append([]*plugin.Client(nil), p.clients...)
+	p.mu.Unlock()
+	for i, c := range clients {
+		if err := c.Call(ctx, "health", nil, nil); err != nil {
+			return fmt.Errorf("Node.js 进程 %d 不可用: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// close 结束全部进程。
+func (p *nodePool) close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, c := range p.clients {
+		if c != nil {
+			_ = c.Close()
+		}
+	}
+	return nil
+}
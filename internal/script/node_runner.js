@@ -0,0 +1,89 @@
+// go_sign Node.js 签名执行器：加载签名脚本，按 internal/plugin 的 stdio 协议响应调用。
+// 脚本路径与签名函数路径分别由环境变量 GO_SIGN_SCRIPT、GO_SIGN_FUNCTION 传入。
+'use strict';
+
+const fs = require('fs');
+const readline = require('readline');
+const vm = require('vm');
+
+// stdout 专用于协议消息，脚本自身的日志改写到 stderr
+console.log = console.info = console.debug = (...args) => console.error(...args);
+
+const file = process.env.GO_SIGN_SCRIPT;
+const fnPath = process.env.GO_SIGN_FUNCTION;
+
+// 面向浏览器编写的签名脚本通常挂载到 window / self
+globalThis.window = globalThis;
+globalThis.self = globalThis;
+vm.runInThisContext(fs.readFileSync(file, 'utf8'), { filename: file });
+
+function lookup() {
+  const keys = fnPath.split('.');
+  const name = keys.pop();
+  const obj = keys.reduce((o, k) => (o == null ? o : o[k]), globalThis);
+  if (obj == null || typeof obj[name] !== 'function') {
+    throw new Error(`window.${fnPath} 不是函数`);
+  }
+  return [obj, obj[name]];
+}
+
+// withClock 在 fn 同步执行期间将 Date.now() 与 new Date() 固定为 ts 毫秒，ts 为 0 时不固定。
+function withClock(ts, fn) {
+  if (!ts) return fn();
+  const RealDate = globalThis.Date;
+  class PinnedDate extends RealDate {
+    constructor(...args) {
+      if (args.length) {
+        super(...args);
+      } else {
+        super(ts);
+      }
+    }
+    static now() {
+      return ts;
+    }
+  }
+  globalThis.Date = PinnedDate;
+  try {
+    return fn();
+  } finally {
+    globalThis.Date = RealDate;
+  }
+}
+
+function send(msg) {
+  process.stdout.write(JSON.stringify(msg) + '\n');
+}
+
+readline.createInterface({ input: process.stdin }).on('line', async (line) => {
+  let msg;
+  try {
+    msg = JSON.parse(line);
+  } catch (e) {
+    console.error('无法解析请求:', e.message);
+    return;
+  }
+  const { id, method, params } = msg;
+  try {
+    switch (method) {
+      case 'sign': {
+        const [obj, fn] = lookup();
+        const result = await withClock(params.timestamp, () => fn.call(obj, params.uri, params.data, params.extra));
+        send({ id, result: result === undefined ? null : result });
+        break;
+      }
+      case 'health':
+        lookup();
+        send({ id, result: true });
+        break;
+      case 'shutdown':
+        send({ id, result: true });
+        process.exit(0);
+        break;
+      default:
+        send({ id, error: `不支持的方法: ${method}` });
+    }
+  } catch (e) {
+    send({ id, error: String((e && e.message) || e) });
+  }
+});
@@ -0,0 +1,280 @@
+// Package script 提供由配置定义的自定义签名脚本平台。
+//
+// 只要站点页面（或注入的 JS 文件）在 window 上暴露签名函数，
+// 即可复用页面池、租户与优先级等基础设施，无需为站点编写平台模块。
+// 签名函数的调用方式为 fn(uri, data, {method, params, cookies})，
+// 返回字符串或由字符串值组成的对象。请求指定 timestamp 时，调用期间 Date 固定为该时间。
+//
+// 脚本可在浏览器页面中执行（BackendBrowser），也可由 Node.js 子进程池执行（BackendNode），
+// 后者无需启动 Chromium，适用于不依赖 DOM 的签名脚本。
+package script
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"go_sign/internal/browser"
+	"go_sign/internal/pagepool"
+	"go_sign/internal/platform"
+)
+
+// 签名结果的返回位置。
+const (
+	OutputHeaders = "headers"
+	OutputParams  = "params"
+)
+
+// 签名脚本的执行后端。
+const (
+	BackendBrowser = "browser"
+	BackendNode    = "node"
+)
+
+// ResultKey 为签名函数返回字符串时使用的键名。
+const ResultKey = "signature"
+
+// funcExistsJS 检查 window 上按 . 分隔的路径是否为函数，参数为路径。
+const funcExistsJS = `(path) => {
+	const f = path.split('.').reduce((o, k) => o == null ? o : o[k], window);
+	return typeof f === 'function';
+}`
+
+// callJS 调用签名函数，保持 this 为函数所属对象，data 以 JSON 字符串传入后还原。
+const callJS = `([path, uri, dataStr, extra]) => {
+	const keys = path.split('.');
+	const name = keys.pop();
+	const obj = keys.reduce((o, k) => o[k], window);
+	return obj[name](uri, JSON.parse(dataStr), extra);
+}`
+
+// Script 描述一个自定义签名站点。
+type Script struct {
+	// Backend 为执行后端，为空时为 BackendBrowser。
+	Backend string
+	// URL 为浏览器后端预热时访问的页面。
+	URL string
+	// File 为注入页面的 JS 文件，为空时只使用页面自带的函数；Node.js 后端必填。
+	File string
+	// Node 为 Node.js 可执行文件路径，为空时为 node。
+	Node string
+	// Function 为签名函数在 window 上的路径，如 _webmsxyw、byted.sign。
+	Function string
+	// Output 为签名结果的返回位置，为空时为 OutputHeaders。
+	Output string
+}
+
+// Register 将 name 注册为由 s 定义的脚本平台，name 已被占用时返回错误。
+func Register(name string, s Script) error {
+	if slices.Contains(platform.Names(), name) {
+		return fmt.Errorf("脚本平台 %s 与已注册的平台重名", name)
+	}
+	if s.Output == "" {
+		s.Output = OutputHeaders
+	}
+	if s.Backend == "" {
+		s.Backend = BackendBrowser
+	}
+	if s.Node == "" {
+		s.Node = "node"
+	}
+	platform.Register(name, func(decode platform.Decoder) (platform.Platform, error) {
+		p := &Platform{name: name, script: s}
+		if err := decode(&p.options); err != nil {
+			return nil, err
+		}
+		return p, nil
+	})
+	return nil
+}
+
+// options 为脚本平台在配置文件中的可选项。
+type options struct {
+	// PoolSize 覆盖平台默认的页面池大小；Node.js 后端为进程数。
+	PoolSize int `yaml:"pool_size"`
+}
+
+// Platform 为自定义签名脚本平台。
+type Platform struct {
+	name    string
+	script  Script
+	options options
+	browser *browser.Browser
+	env     *platform.Env
+	pools   pagepool.Set
+	nodes   *nodePool
+}
+
+// Name 返回平台名称。
+func (p *Platform) Name() string { return p.name }
+
+// Init 启动 Node.js 进程池，或启动（复用）共享浏览器、注入脚本并预热页面。
+func (p *Platform) Init(ctx context.Context, env *platform.Env) error {
+	if p.script.Backend == BackendNode {
+		size := p.options.PoolSize
+		if size <= 0 {
+			size = max(env.PoolSize, 1)
+		}
+		var err error
+		p.nodes, err = startNodePool(p.name, p.script.Node, p.script.File, p.script.Function, size)
+		return err
+	}
+	b, err := env.Browser.Get()
+	if err != nil {
+		return err
+	}
+	p.browser, p.env = b, env
+	tenants := pagepool.TenantsFromEnv(env, p.options.PoolSize)
+	p.pools, err = pagepool.Warmup(ctx, p.name, tenants, env.WarmupConcurrency, p.newSlot)
+	return err
+}
+
+func (p *Platform) newSlot(ctx context.Context, tenant string, id int) (*pagepool.Slot, error) {
+	o := pagepool.OpenOptions{
+		Platform:          p.name,
+		StealthPath:       p.env.StealthPath,
+		URL:               p.script.URL,
+		WaitUntil:         p.env.WaitUntil,
+		NavigationTimeout: p.env.NavigationTimeout,
+		ReadyJS:           funcExistsJS,
+		ReadyArg:          p.script.Function,
+		ReadyTimeout:      p.env.SignFuncTimeout,
+	}
+	if p.script.File != "" {
+		o.InitScripts = []string{p.script.File}
+	}
+	return pagepool.Open(ctx, p.browser, tenant, id, o)
+}
+
+// Sign 调用页面上的签名函数，结果按 Output 放入请求头或查询参数。
+func (p *Platform) Sign(ctx context.Context, req *platform.SignRequest) (*platform.SignResponse, error) {
+	extra := map[string]any{"method": req.Method, "params": req.Params, "cookies": req.Cookies}
+	var v any
+	var err error
+	if p.nodes != nil {
+		v, err = p.nodes.sign(ctx, req.URI, req.Data, extra, req.Timestamp)
+	} else {
+		v, err = p.signOnPage(ctx, req, extra)
+	}
+	if err != nil {
+		slog.Error("执行自定义签名函数失败", "err", err, "platform", p.name, "function", p.script.Function, "uri", req.URI)
+		return nil, fmt.Errorf("执行 window.%s 失败: %w", p.script.Function, err)
+	}
+	values, err := stringMap(v)
+	if err != nil {
+		return nil, fmt.Errorf("window.%s %w", p.script.Function, err)
+	}
+	res := &platform.SignResponse{Timestamp: platform.SignTime(req.Timestamp).UnixMilli()}
+	if p.script.Output == OutputParams {
+		res.Params = values
+	} else {
+		res.Headers = values
+	}
+	return res, nil
+}
+
+// Mock 返回以 ResultKey 为键的假签名，放在 Output 指定的位置。
+func (p *Platform) Mock(*platform.SignRequest) *platform.SignResponse {
+	values := map[string]string{ResultKey: platform.MockHex(16)}
+	if p.script.Output == OutputParams {
+		return &platform.SignResponse{Params: values}
+	}
+	return &platform.SignResponse{Headers: values}
+}
+
+// signOnPage 从租户页面池取出页面并调用签名函数。
+func (p *Platform) signOnPage(ctx context.Context, req *platform.SignRequest, extra map[string]any) (any, error) {
+	pool, err := p.pools.For(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dataJSON, err := json.Marshal(req.Data)
+	if err != nil {
+		return nil, fmt.Errorf("data 参数序列化失败: %w", err)
+	}
+	slot, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("等待空闲页面失败: %w", err)
+	}
+	args := []any{p.script.Function, req.URI, string(dataJSON), extra}
+	v, err := pagepool.Evaluate(ctx, slot.Page, pagepool.WithClock(callJS), []any{req.Timestamp, args})
+	pool.Done(slot, err)
+	return v, err
+}
+
+// stringMap 将签名函数的返回值转换为键值对，非字符串的标量值按 JSON 格式化。
+func stringMap(v any) (map[string]string, error) {
+	switch r := v.(type) {
+	case string:
+		if r == "" {
+			return nil, fmt.Errorf("返回空字符串")
+		}
+		return map[string]string{ResultKey: r}, nil
+	case map[string]any:
+		out := make(map[string]string, len(r))
+		for k, val := range r {
+			switch s := val.(type) {
+			case string:
+				out[k] = s
+			case nil:
+			default:
+				raw, err := json.Marshal(s)
+				if err != nil {
+					return nil, fmt.Errorf("返回值 %s 无法序列化: %w", k, err)
+				}
+				out[k] = string(raw)
+			}
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("返回值类型不支持: %T", v)
+}
+
+// HealthCheck 检查各租户页面（或各 Node.js 进程）上的签名函数是否存在。
+func (p *Platform) HealthCheck(ctx context.Context) error {
+	if p.nodes != nil {
+		return p.nodes.check(ctx)
+	}
+	return p.pools.Check(ctx, funcExistsJS, p.script.Function)
+}
+
+// ProbeStealth 在注入 path 的临时页面上检查签名函数可用，实现 platform.StealthProber；Node.js 后端不注入 stealth.js。
+func (p *Platform) ProbeStealth(ctx context.Context, path string) error {
+	if p.nodes != nil {
+		return nil
+	}
+	return p.pools.Probe(ctx, path, func(slot *pagepool.Slot) error {
+		return pagepool.CheckSlot(slot, funcExistsJS, p.script.Function)
+	})
+}
+
+// StageUpgrade 在新浏览器 b 上预热与当前规模相同的页面，实现 platform.Upgrader；Node.js 后端不需要升级。
+func (p *Platform) StageUpgrade(ctx context.Context, b *browser.Browser, stealthPath string) (platform.Staged, error) {
+	if p.nodes != nil {
+		return nil, nil
+	}
+	return p.pools.Stage(ctx, pagepool.Generation{Browser: b, StealthPath: stealthPath}, p.env.WarmupConcurrency)
+}
+
+// RenameStealth 将页面池中注入 from 的 stealth.js 路径改为 to，实现 platform.StealthRenamer。
+func (p *Platform) RenameStealth(from, to string) {
+	p.pools.RenameStealth(from, to)
+}
+
+// Recycle 逐个重建各租户的浏览器上下文，实现 platform.Recycler；Node.js 后端不需要回收。
+func (p *Platform) Recycle(ctx context.Context) error {
+	if p.nodes != nil {
+		return nil
+	}
+	return p.pools.Recycle(ctx)
+}
+
+// Close 关闭全部页面或 Node.js 进程。
+func (p *Platform) Close() error {
+	if p.nodes != nil {
+		return p.nodes.close()
+	}
+	return p.pools.Close()
+}
@@ -0,0 +1,112 @@
+// Package secrets 从外部密钥后端读取配置文件中引用的敏感值，避免在配置中保存明文。
+//
+// 配置项的值写作 <后端>:<引用> 时视为密钥引用，支持：
+//   - env:NAME：读取环境变量 NAME；
+//   - vault:<path>#<field>：读取 HashiCorp Vault 中 path 的 field 字段（KV v1 / v2 均可，
+//     v2 的 path 含 data/，如 secret/data/go_sign#prod_key）。
+//
+// 接入其他后端时实现 Backend 并注册到 Resolver 即可。
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"go_sign/internal/config"
+)
+
+// Backend 为密钥后端，ref 为引用中后端名之后的部分。
+type Backend interface {
+	Lookup(ctx context.Context, ref string) (string, error)
+}
+
+// Resolver 按后端名解析密钥引用。
+type Resolver struct {
+	backends map[string]Backend
+}
+
+// New 按配置创建 Resolver，env 后端始终可用，配置了 vault 时启用 vault 后端。
+func New(c *config.Secrets) (*Resolver, error) {
+	r := &Resolver{backends: map[string]Backend{"env": envBackend{}}}
+	if c != nil && c.Vault != nil {
+		v, err := NewVault(c.Vault)
+		if err != nil {
+			return nil, err
+		}
+		r.backends["vault"] = v
+	}
+	return r, nil
+}
+
+// Register 注册名为 name 的后端，同名后端被替换。
+func (r *Resolver) Register(name string, b Backend) {
+	r.backends[name] = b
+}
+
+// Resolve 将 cfg 中支持引用的配置项（api_keys[].key、shadow.api_key、error_report.sentry_dsn、
+// error_report.webhook_url、encryption.key、storage.dsn、webhooks[].secret、jwt.secret）替换为后端中的值，非引用的值保持不变。
+func (r *Resolver) Resolve(ctx context.Context, cfg *config.Config) error {
+	fields := map[string]*string{}
+	for i := range cfg.APIKeys {
+		fields[fmt.Sprintf("api_keys[%d].key", i)] = &cfg.APIKeys[i].Key
+	}
+	if s := cfg.Shadow; s != nil {
+		fields["shadow.api_key"] = &s.APIKey
+	}
+	if e := cfg.ErrorReport; e != nil {
+		fields["error_report.sentry_dsn"] = &e.SentryDSN
+		fields["error_report.webhook_url"] = &e.WebhookURL
+	}
+	if e := cfg.Encryption; e != nil {
+		fields["encryption.key"] = &e.Key
+	}
+	if s := cfg.Storage; s != nil {
+		fields["storage.dsn"] = &s.DSN
+	}
+	if j := cfg.JWT; j != nil {
+		fields["jwt.secret"] = &j.Secret
+	}
+	for i := range cfg.Webhooks {
+		fields[fmt.Sprintf("webhooks[%d].secret", i)] = &cfg.Webhooks[i].Secret
+	}
+	if rs := cfg.ResponseSigning; rs != nil {
+		fields["response_signing.secret"] = &rs.Secret
+	}
+	if re := cfg.RequestEncryption; re != nil {
+		fields["request_encryption.key"] = &re.Key
+	}
+	for name, v := range fields {
+		resolved, err := r.resolve(ctx, *v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		*v = resolved
+	}
+	return nil
+}
+
+// resolve 解析单个值，未注册的后端名视为普通值（如 https://... 中的 https）。
+func (r *Resolver) resolve(ctx context.Context, v string) (string, error) {
+	scheme, ref, ok := strings.Cut(v, ":")
+	b, registered := r.backends[scheme]
+	if !ok || !registered {
+		return v, nil
+	}
+	out, err := b.Lookup(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("读取密钥 %s 失败: %w", v, err)
+	}
+	if out == "" {
+		return "", fmt.Errorf("密钥 %s 为空", v)
+	}
+	return out, nil
+}
+
+// envBackend 从环境变量读取密钥。
+type envBackend struct{}
+
+func (envBackend) Lookup(_ context.Context, name string) (string, error) {
+	return strings.TrimSpace(os.Getenv(name)), nil
+}
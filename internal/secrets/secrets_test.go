@@ -0,0 +1,67 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"go_sign/internal/config"
+)
+
+// mapBackend 为测试用的密钥后端。
+type mapBackend map[string]string
+
+func (m mapBackend) Lookup(_ context.Context, ref string) (string, error) {
+	v, ok := m[ref]
+	if !ok {
+		return "", errors.New("not found")
+	}
+	return v, nil
+}
+
+func TestResolve(t *testing.T) {
+	t.Setenv("GO_SIGN_TEST_KEY", "  from-env\n")
+	t.Setenv("GO_SIGN_TEST_EMPTY", "")
+	backend := mapBackend{"prod#key": "v1"}
+	r, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Register("kv", backend)
+
+	cfg := &config.Config{
+		APIKeys: []config.APIKey{
+			{Name: "env", Key: "env:GO_SIGN_TEST_KEY"},
+			{Name: "kv", Key: "kv:prod#key"},
+			{Name: "plain", Key: "plain-secret"},
+		},
+		Storage: &config.Storage{DSN: "postgres://u:p@db:5432/go_sign"},
+	}
+	if err := r.Resolve(context.Background(), cfg); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"from-env", "v1", "plain-secret"}
+	for i, k := range cfg.APIKeys {
+		if k.Key != want[i] {
+			t.Errorf("api_keys[%d].key = %q，期望 %q", i, k.Key, want[i])
+		}
+	}
+	if cfg.Storage.DSN != "postgres://u:p@db:5432/go_sign" {
+		t.Errorf("未注册的后端名应视为普通值，实际 %q", cfg.Storage.DSN)
+	}
+
+	// 后端中的密钥轮换后重新解析得到新值
+	backend["prod#key"] = "v2"
+	cfg.APIKeys[1].Key = "kv:prod#key"
+	if err := r.Resolve(context.Background(), cfg); err != nil || cfg.APIKeys[1].Key != "v2" {
+		t.Errorf("轮换后 = %q, %v，期望 v2", cfg.APIKeys[1].Key, err)
+	}
+
+	for _, ref := range []string{"env:GO_SIGN_TEST_EMPTY", "kv:missing"} {
+		err := r.Resolve(context.Background(), &config.Config{APIKeys: []config.APIKey{{Key: ref}}})
+		if err == nil || !strings.Contains(err.Error(), "api_keys[0].key") {
+			t.Errorf("%s: 期望带字段名的错误，实际 %v", ref, err)
+		}
+	}
+}
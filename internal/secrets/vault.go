@@ -0,0 +1,105 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"go_sign/internal/config"
+)
+
+// Vault 通过 HTTP API 读取 HashiCorp Vault 中的密钥。
+type Vault struct {
+	address   string
+	token     string
+	namespace string
+	client    *http.Client
+}
+
+// NewVault 按配置创建 Vault 后端，地址与 token 未在配置中指定时读取 VAULT_ADDR、VAULT_TOKEN。
+func NewVault(c *config.Vault) (*Vault, error) {
+	address := c.Address
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	tokenEnv := c.TokenEnv
+	if tokenEnv == "" {
+		tokenEnv = "VAULT_TOKEN"
+	}
+	token := os.Getenv(tokenEnv)
+	if address == "" {
+		return nil, errors.New("未配置 Vault 地址（secrets.vault.address 或 VAULT_ADDR）")
+	}
+	if token == "" {
+		return nil, fmt.Errorf("环境变量 %s 中没有 Vault token", tokenEnv)
+	}
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &Vault{
+		address:   strings.TrimRight(address, "/"),
+		token:     token,
+		namespace: c.Namespace,
+		client:    &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Lookup 读取 ref（<path>#<field>）指定的字段。
+func (v *Vault) Lookup(ctx context.Context, ref string) (string, error) {
+	path, field, ok := strings.Cut(ref, "#")
+	if !ok || path == "" || field == "" {
+		return "", fmt.Errorf("vault 引用须为 <path>#<field>: %s", ref)
+	}
+	data, err := v.read(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	s, ok := data[field].(string)
+	if !ok {
+		return "", fmt.Errorf("%s 中没有字符串字段 %s", path, field)
+	}
+	return s, nil
+}
+
+// read 读取 path 的数据，KV v2 返回 data.data，KV v1 返回 data。
+func (v *Vault) read(ctx context.Context, path string) (map[string]any, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.address+"/v1/"+strings.TrimLeft(path, "/"), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	if v.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求 Vault 失败: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("读取 Vault 响应失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Vault 返回状态码 %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var out struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		return nil, fmt.Errorf("解析 Vault 响应失败: %w", err)
+	}
+	if inner, ok := out.Data["data"].(map[string]any); ok {
+		if _, versioned := out.Data["metadata"]; versioned {
+			return inner, nil
+		}
+	}
+	return out.Data, nil
+}
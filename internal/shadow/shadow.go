@@ -0,0 +1,209 @@
+// Package shadow 将抽样的签名请求镜像到备用签名服务并比较响应，用于签名端变更的灰度验证。
+//
+// 镜像在 HTTP 层进行：原样转发请求体到备用服务的同一路径，与主服务的 JSON 响应逐字段比较，
+// 因此与各平台的请求、响应格式无关。镜像异步执行，不影响主请求的耗时与结果。
+package shadow
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/auth"
+	"go_sign/internal/logging"
+	"go_sign/internal/metrics"
+)
+
+// defaultIgnore 为始终不参与比较的字段，二者每次签名都会变化。
+var defaultIgnore = []string{"server_time", "timestamp"}
+
+// shadowResults 统计镜像结果。
+var shadowResults = metrics.Default.NewCounterVec(
+	"go_sign_shadow_total",
+	"镜像到备用签名服务的请求数，result 为 match、mismatch 或 error",
+	"route", "result",
+)
+
+// Options 为镜像配置。
+type Options struct {
+	// URL 为备用签名服务的地址，如 http://canary:5005，请求路径与主服务相同。
+	URL string
+	// SampleRate 为镜像比例，取值 (0, 1]。
+	SampleRate float64
+	// Ignore 为不参与比较的响应字段，嵌套字段以 . 连接，如 x-t、headers.x-t。
+	Ignore []string
+	// APIKey 为访问备用服务使用的 API Key，为空时转发调用方的鉴权请求头。
+	APIKey string
+	// Timeout 为镜像请求的超时时间，0 表示 10 秒。
+	Timeout time.Duration
+}
+
+// Middleware 返回镜像中间件，需放在鉴权之后、签名处理之前。
+func Middleware(o Options) gin.HandlerFunc {
+	if o.Timeout <= 0 {
+		o.Timeout = 10 * time.Second
+	}
+	o.URL = strings.TrimRight(o.URL, "/")
+	ignore := append(slices.Clone(defaultIgnore), o.Ignore...)
+	client := &http.Client{Timeout: o.Timeout}
+
+	return func(c *gin.Context) {
+		if rand.Float64() >= o.SampleRate {
+			c.Next()
+			return
+		}
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.Next()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		rec := &recorder{ResponseWriter: c.Writer}
+		c.Writer = rec
+		c.Next()
+
+		// 只比较主服务签名成功的请求，配额、鉴权等错误不具可比性
+		if c.Writer.Status() != http.StatusOK {
+			return
+		}
+		route := c.FullPath()
+		req, err := http.NewRequest(http.MethodPost, o.URL+c.Request.URL.RequestURI(), bytes.NewReader(body))
+		if err != nil {
+			slog.Warn("创建镜像请求失败", "err", err, "route", route)
+			return
+		}
+		for _, h := range []string{"Content-Type", "Accept"} {
+			if v := c.GetHeader(h); v != "" {
+				req.Header.Set(h, v)
+			}
+		}
+		if o.APIKey != "" {
+			req.Header.Set("X-API-Key", o.APIKey)
+		} else {
+			for _, h := range []string{"X-API-Key", "Authorization", auth.HeaderKeyID, auth.HeaderTimestamp, auth.HeaderSignature} {
+				if v := c.GetHeader(h); v != "" {
+					req.Header.Set(h, v)
+				}
+			}
+		}
+		want := rec.body.Bytes()
+		go mirror(client, req, route, want, ignore)
+	}
+}
+
+// mirror 发送镜像请求并与主服务的响应比较。
+func mirror(client *http.Client, req *http.Request, route string, want []byte, ignore []string) {
+	resp, err := client.Do(req.WithContext(context.Background()))
+	if err != nil {
+		shadowResults.Inc(route, "error")
+		slog.Warn("镜像请求失败", "err", err, "route", route)
+		return
+	}
+	defer resp.Body.Close()
+	got, err := io.ReadAll(resp.Body)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		shadowResults.Inc(route, "error")
+		slog.Warn("镜像请求失败", "err", err, "route", route, "status", resp.StatusCode, "body", string(got))
+		return
+	}
+	diffs, err := compare(want, got, ignore)
+	if err != nil {
+		shadowResults.Inc(route, "error")
+		slog.Warn("镜像响应无法比较", "err", err, "route", route)
+		return
+	}
+	if len(diffs) == 0 {
+		shadowResults.Inc(route, "match")
+		return
+	}
+	shadowResults.Inc(route, "mismatch")
+	slog.Warn("镜像响应与主服务不一致", "route", route, "diffs", diffs)
+}
+
+// compare 逐字段比较两个 JSON 响应，返回不一致的字段。
+func compare(want, got []byte, ignore []string) ([]string, error) {
+	var a, b any
+	if err := json.Unmarshal(want, &a); err != nil {
+		return nil, fmt.Errorf("解析主服务响应失败: %w", err)
+	}
+	if err := json.Unmarshal(got, &b); err != nil {
+		return nil, fmt.Errorf("解析备用服务响应失败: %w", err)
+	}
+	fa, fb := map[string]string{}, map[string]string{}
+	flatten("", a, fa)
+	flatten("", b, fb)
+	var diffs []string
+	for _, k := range union(fa, fb) {
+		if ignored(k, ignore) {
+			continue
+		}
+		if fa[k] != fb[k] {
+			// 响应中含签名结果与账号凭据，脱敏后仍可从长度与摘要看出差异
+			diffs = append(diffs, fmt.Sprintf("%s: %q != %q", k, logging.Secret(fa[k]), logging.Secret(fb[k])))
+		}
+	}
+	return diffs, nil
+}
+
+// flatten 将嵌套 JSON 展开为以 . 连接的字段路径。
+func flatten(prefix string, v any, out map[string]string) {
+	switch t := v.(type) {
+	case map[string]any:
+		for k, val := range t {
+			p := k
+			if prefix != "" {
+				p = prefix + "." + k
+			}
+			flatten(p, val, out)
+		}
+	default:
+		raw, _ := json.Marshal(t)
+		out[prefix] = string(raw)
+	}
+}
+
+// ignored 判断字段是否忽略：完整路径或最后一级名称在 ignore 中。
+func ignored(path string, ignore []string) bool {
+	name := path[strings.LastIndex(path, ".")+1:]
+	return slices.Contains(ignore, path) || slices.Contains(ignore, name)
+}
+
+func union(a, b map[string]string) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// recorder 在写出响应的同时保留一份响应体。
+type recorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *recorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,136 @@
+package stealth
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/auth"
+	"go_sign/internal/platform"
+)
+
+// 降级期间的状态。
+const (
+	// PendingWaiting 为等待上传 stealth.js。
+	PendingWaiting = "waiting"
+	// PendingInitializing 为已上传、正在初始化签名平台。
+	PendingInitializing = "initializing"
+)
+
+// pendingRetryAfter 为降级期间签名接口建议的重试间隔。
+const pendingRetryAfter = 30 * time.Second
+
+// Pending 为 stealth.js 缺失时的降级启动状态：签名平台尚未初始化，只提供健康检查、状态与上传脚本的接口，
+// 脚本上传后 Ready 关闭，由调用方初始化签名平台。并发安全。
+type Pending struct {
+	path  string
+	since time.Time
+	ready chan struct{}
+
+	mu    sync.Mutex
+	state string
+}
+
+// NewPending 创建等待 path 处 stealth.js 的降级状态。
+func NewPending(path string) *Pending {
+	return &Pending{path: path, since: time.Now(), ready: make(chan struct{}), state: PendingWaiting}
+}
+
+// Ready 返回 stealth.js 上传成功后关闭的 channel。
+func (p *Pending) Ready() <-chan struct{} { return p.ready }
+
+// State 返回当前状态，PendingWaiting 或 PendingInitializing。
+func (p *Pending) State() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.state
+}
+
+// reason 返回当前状态的说明。
+func (p *Pending) reason() string {
+	if p.State() == PendingInitializing {
+		return "stealth.js 已上传，正在初始化签名平台"
+	}
+	return "stealth.js 缺失，等待通过 PUT /admin/stealth 上传"
+}
+
+// body 返回降级状态的 JSON。
+func (p *Pending) body() gin.H {
+	return gin.H{
+		"state":        platform.HealthDegraded,
+		"stage":        p.State(),
+		"reason":       p.reason(),
+		"stealth_path": p.path,
+		"since":        p.since,
+	}
+}
+
+// StatusHandler 返回降级期间的 /healthz 与 /status 接口：进程存活，返回 200 与降级原因。
+func (p *Pending) StatusHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, p.body())
+	}
+}
+
+// ReadyHandler 返回降级期间的 /readyz 接口：始终返回 503，负载均衡不向本实例转发流量。
+func (p *Pending) ReadyHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusServiceUnavailable, p.body())
+	}
+}
+
+// Unavailable 返回降级期间其余路由的处理函数：返回 503、Retry-After 与 recovering 错误码，与签名暂时不可用时的应答一致。
+func (p *Pending) Unavailable() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		err := &platform.UnavailableError{Reason: p.reason(), RetryAfter: pendingRetryAfter}
+		c.AbortWithStatusJSON(platform.SignErrorStatus(c, err), platform.ErrorBody(err))
+	}
+}
+
+// UploadHandler 返回上传 stealth.js 的管理接口：请求体为脚本原文，写入 --stealth 路径后通知调用方初始化签名平台，返回 202；
+// 已上传过时返回 409。
+func (p *Pending) UploadHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxSize))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("stealth.js 超过 %d 字节", maxSize)})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("读取请求体失败: %v", err)})
+			return
+		}
+		if len(bytes.TrimSpace(body)) == 0 || !utf8.Valid(body) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "请求体须为 stealth.js 脚本原文"})
+			return
+		}
+
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if p.state != PendingWaiting {
+			c.JSON(http.StatusConflict, gin.H{"error": "stealth.js 已上传，正在初始化签名平台"})
+			return
+		}
+		if err := writeFile(p.path, body); err != nil {
+			slog.Error("写入上传的 stealth.js 失败", "err", err, "path", p.path)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf("写入 stealth.js 失败: %v", err)})
+			return
+		}
+		p.state = PendingInitializing
+		close(p.ready)
+		actor := ""
+		if key := auth.FromContext(c); key != nil {
+			actor = key.Name
+		}
+		slog.Info("已上传 stealth.js，开始初始化签名平台", "path", p.path, "size", len(body), "sha256", hash(body), "actor", actor)
+		c.JSON(http.StatusAccepted, gin.H{"status": PendingInitializing, "stealth_path": p.path, "sha256": hash(body)})
+	}
+}
@@ -0,0 +1,62 @@
+package stealth_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/platform/platformtest"
+	"go_sign/internal/stealth"
+)
+
+func TestPendingUpload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stealth.min.js")
+	pending := stealth.NewPending(path)
+	h := platformtest.New(t, func(r *gin.Engine) {
+		r.GET("/healthz", pending.StatusHandler())
+		r.GET("/readyz", pending.ReadyHandler())
+		r.PUT("/admin/stealth", pending.UploadHandler())
+		r.NoRoute(pending.Unavailable())
+	})
+
+	if resp := h.Do(http.MethodGet, "/healthz", nil, nil); resp.Status != http.StatusOK || resp.JSON(t)["stage"] != stealth.PendingWaiting {
+		t.Fatalf("/healthz = %d %s，期望 200 与 waiting", resp.Status, resp.Body)
+	}
+	if resp := h.Do(http.MethodGet, "/readyz", nil, nil); resp.Status != http.StatusServiceUnavailable {
+		t.Fatalf("/readyz = %d，期望 503", resp.Status)
+	}
+	resp := h.Do(http.MethodPost, "/v1/sign", map[string]any{"uri": "/api/sns/web/v1/feed"}, nil)
+	if resp.Status != http.StatusServiceUnavailable || resp.Header.Get("Retry-After") == "" || resp.JSON(t)["code"] != "recovering" {
+		t.Fatalf("降级期间签名 = %d %s，期望 503、Retry-After 与 recovering", resp.Status, resp.Body)
+	}
+
+	if resp := h.Do(http.MethodPut, "/admin/stealth", "  \n", nil); resp.Status != http.StatusBadRequest {
+		t.Fatalf("上传空脚本 = %d，期望 400", resp.Status)
+	}
+	select {
+	case <-pending.Ready():
+		t.Fatal("上传失败后 Ready 不应关闭")
+	default:
+	}
+
+	script := "(() => { Object.defineProperty(navigator, 'webdriver', {get: () => undefined}) })()"
+	if resp := h.Do(http.MethodPut, "/admin/stealth", script, nil); resp.Status != http.StatusAccepted {
+		t.Fatalf("上传脚本 = %d %s，期望 202", resp.Status, resp.Body)
+	}
+	select {
+	case <-pending.Ready():
+	default:
+		t.Fatal("上传成功后 Ready 应关闭")
+	}
+	if got, err := os.ReadFile(path); err != nil || string(got) != script {
+		t.Fatalf("写入的脚本 = %q, %v", got, err)
+	}
+	if resp := h.Do(http.MethodPut, "/admin/stealth", script, nil); resp.Status != http.StatusConflict {
+		t.Fatalf("重复上传 = %d，期望 409", resp.Status)
+	}
+	if stage := h.Do(http.MethodGet, "/healthz", nil, nil).JSON(t)["stage"]; stage != stealth.PendingInitializing {
+		t.Errorf("上传后 stage = %v，期望 initializing", stage)
+	}
+}
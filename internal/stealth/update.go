@@ -0,0 +1,203 @@
+// Package stealth 定期检查上游 stealth.min.js 的新版本：下载到暂存路径，在临时页面上验证可以签名后，
+// 提示人工发布或自动以蓝绿方式发布。
+package stealth
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go_sign/internal/metrics"
+	"go_sign/internal/report"
+)
+
+// checks 统计更新检查的结果。
+var checks = metrics.Default.NewCounterVec(
+	"go_sign_stealth_update_checks_total",
+	"上游 stealth.js 更新检查次数，result 为 up_to_date、validated、rejected、promoted 或 error",
+	"result",
+)
+
+// 下载与验证的限制。
+const (
+	maxSize         = 10 << 20
+	downloadTimeout = time.Minute
+	probeTimeout    = 2 * time.Minute
+	// DefaultInterval 为未配置时的检查间隔。
+	DefaultInterval = 6 * time.Hour
+)
+
+// Target 为使用 stealth.js 的签名服务，由 platform.Set 实现。
+type Target interface {
+	// StealthPath 返回当前注入的 stealth.js 路径。
+	StealthPath() string
+	// ProbeStealth 在临时页面上验证 path 指向的 stealth.js 可以签名。
+	ProbeStealth(ctx context.Context, path string) error
+	// Upgrade 以蓝绿方式切换到 path 指向的 stealth.js，path 为空时重新加载当前路径。
+	Upgrade(ctx context.Context, path string) error
+	// RenameStealth 在 from 被改名为 to 后更新当前路径及页面重建时注入的路径。
+	RenameStealth(from, to string)
+}
+
+// Checker 定期检查上游 stealth.js 更新。
+type Checker struct {
+	// URL 为上游 stealth.min.js 的下载地址。
+	URL string
+	// StagingPath 为新版本的暂存路径，为空或与当前路径相同时为当前路径加 .staging 后缀。
+	StagingPath string
+	// AutoPromote 为 true 时验证通过后以暂存文件蓝绿升级，升级成功后再将暂存文件改名为当前文件。
+	AutoPromote bool
+	// UpgradeTimeout 为自动发布时蓝绿升级的最长时间。
+	UpgradeTimeout time.Duration
+	Target         Target
+	Client         *http.Client
+
+	// checked 为最近一次验证失败或已处理完毕的上游版本摘要，上游未变化时不重复验证；
+	// 自动发布失败时不记录，下次检查重试
+	checked string
+}
+
+// Run 立即检查一次，之后每隔 interval 检查，直至 ctx 取消。
+func (c *Checker) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	slog.Info("开启上游 stealth.js 更新检查", "url", c.URL, "interval", interval, "auto_promote", c.AutoPromote)
+	for {
+		if err := c.Check(ctx); err != nil && ctx.Err() == nil {
+			checks.Inc("error")
+			slog.Warn("检查上游 stealth.js 更新失败", "err", err, "url", c.URL)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Check 下载上游 stealth.js，与当前文件不同时写入暂存路径并验证，验证通过且开启自动发布时发布。
+// 验证失败只记录并上报，不返回错误。发布时先以暂存文件升级，成功后才替换当前文件，
+// 升级失败时当前文件与正在服务的页面均保持不变。
+func (c *Checker) Check(ctx context.Context) error {
+	current := c.Target.StealthPath()
+	body, err := c.download(ctx)
+	if err != nil {
+		return err
+	}
+	digest := hash(body)
+	if raw, err := os.ReadFile(current); err == nil && hash(raw) == digest {
+		checks.Inc("up_to_date")
+		slog.Debug("stealth.js 已是最新", "path", current, "sha256", digest)
+		return nil
+	}
+	if digest == c.checked {
+		slog.Debug("上游 stealth.js 未变化，跳过验证", "sha256", digest)
+		return nil
+	}
+	staging := c.StagingPath
+	if staging == "" || staging == current {
+		staging = current + ".staging"
+	}
+	if err := writeFile(staging, body); err != nil {
+		return fmt.Errorf("写入暂存文件失败: %w", err)
+	}
+	slog.Info("发现新版 stealth.js，开始验证", "url", c.URL, "staging_path", staging, "sha256", digest)
+
+	pctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	err = c.Target.ProbeStealth(pctx, staging)
+	cancel()
+	if err != nil {
+		c.checked = digest
+		checks.Inc("rejected")
+		slog.Error("新版 stealth.js 验证失败，未发布", "err", err, "staging_path", staging, "sha256", digest)
+		report.Error("新版 stealth.js 验证失败", err, "staging_path", staging, "sha256", digest)
+		return nil
+	}
+	if !c.AutoPromote {
+		c.checked = digest
+		checks.Inc("validated")
+		slog.Warn("新版 stealth.js 已通过验证，等待人工发布（POST /admin/upgrade）", "staging_path", staging, "sha256", digest)
+		return nil
+	}
+	uctx, cancel := context.WithTimeout(ctx, c.UpgradeTimeout)
+	defer cancel()
+	if err := c.Target.Upgrade(uctx, staging); err != nil {
+		report.Error("自动发布新版 stealth.js 失败", err, "staging_path", staging, "sha256", digest)
+		return fmt.Errorf("自动发布失败: %w", err)
+	}
+	if err := os.Rename(staging, current); err != nil {
+		// 新页面仍注入暂存文件，未记录 checked，下次检查时重新发布
+		return fmt.Errorf("替换 stealth.js 失败: %w", err)
+	}
+	c.Target.RenameStealth(staging, current)
+	c.checked = digest
+	checks.Inc("promoted")
+	slog.Info("新版 stealth.js 已自动发布", "path", current, "sha256", digest)
+	return nil
+}
+
+// download 下载上游 stealth.js，内容超过 maxSize 或为空时返回错误。
+func (c *Checker) download(ctx context.Context) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, downloadTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := c.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("下载失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("下载失败: HTTP %d", resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("下载失败: %w", err)
+	}
+	switch {
+	case len(body) > maxSize:
+		return nil, fmt.Errorf("文件超过 %d MiB", maxSize>>20)
+	case len(bytes.TrimSpace(body)) == 0:
+		return nil, errors.New("文件为空")
+	}
+	return body, nil
+}
+
+// hash 返回内容的 SHA-256 十六进制摘要。
+func hash(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// writeFile 先写入同目录的临时文件再重命名，避免读取方看到写了一半的文件。
+func writeFile(path string, body []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(body); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
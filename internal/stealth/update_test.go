@@ -0,0 +1,77 @@
+package stealth_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go_sign/internal/stealth"
+)
+
+// fakeTarget 记录升级使用的路径，upgradeErr 非 nil 时升级失败。
+type fakeTarget struct {
+	path       string
+	upgraded   []string
+	upgradeErr error
+}
+
+func (f *fakeTarget) StealthPath() string                                 { return f.path }
+func (f *fakeTarget) ProbeStealth(ctx context.Context, path string) error { return nil }
+
+func (f *fakeTarget) Upgrade(ctx context.Context, path string) error {
+	f.upgraded = append(f.upgraded, path)
+	if f.upgradeErr != nil {
+		return f.upgradeErr
+	}
+	f.path = path
+	return nil
+}
+
+func (f *fakeTarget) RenameStealth(from, to string) {
+	if f.path == from {
+		f.path = to
+	}
+}
+
+func TestAutoPromote(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("// v2"))
+	}))
+	defer srv.Close()
+	current := filepath.Join(t.TempDir(), "stealth.min.js")
+	if err := os.WriteFile(current, []byte("// v1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	target := &fakeTarget{path: current, upgradeErr: errors.New("预热失败")}
+	c := &stealth.Checker{URL: srv.URL, AutoPromote: true, UpgradeTimeout: time.Second, Target: target}
+
+	if err := c.Check(context.Background()); err == nil {
+		t.Fatal("升级失败时 Check 应返回错误")
+	}
+	if raw, _ := os.ReadFile(current); string(raw) != "// v1" {
+		t.Fatalf("升级失败后当前文件 = %q，期望保持 v1", raw)
+	}
+
+	// 升级失败的版本在下次检查时重试
+	target.upgradeErr = nil
+	if err := c.Check(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(target.upgraded) != 2 || target.upgraded[1] != current+".staging" {
+		t.Fatalf("升级路径 = %v，期望两次均为暂存文件", target.upgraded)
+	}
+	if raw, _ := os.ReadFile(current); string(raw) != "// v2" {
+		t.Fatalf("发布后当前文件 = %q，期望 v2", raw)
+	}
+	if target.path != current {
+		t.Fatalf("发布后 stealth.js 路径 = %s，期望改回 %s", target.path, current)
+	}
+	if _, err := os.Stat(current + ".staging"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("发布后暂存文件应已改名，err = %v", err)
+	}
+}
@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"context"
+	"encoding/binary"
+	"time"
+
+	"go.etcd.io/bbolt"
+)
+
+// boltStore 以单个 BoltDB 文件保存数据，适合单机部署：每个集合一个 bucket，
+// 每个日志一个以 "log:" 为前缀的 bucket，键为大端序的自增序号。BoltDB 文件只能由一个进程打开，租约只在进程内有效。
+type boltStore struct {
+	localLocks
+
+	db *bbolt.DB
+}
+
+func openBolt(path string) (*boltStore, error) {
+	db, err := bbolt.Open(path, 0o600, &bbolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	return &boltStore{db: db}, nil
+}
+
+func (b *boltStore) Put(_ context.Context, collection, key string, value []byte) error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		bk, err := tx.CreateBucketIfNotExists([]byte(collection))
+		if err != nil {
+			return err
+		}
+		return bk.Put([]byte(key), value)
+	})
+}
+
+func (b *boltStore) Delete(_ context.Context, collection, key string) error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		if bk := tx.Bucket([]byte(collection)); bk != nil {
+			return bk.Delete([]byte(key))
+		}
+		return nil
+	})
+}
+
+func (b *boltStore) List(_ context.Context, collection string) (map[string][]byte, error) {
+	out := make(map[string][]byte)
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		bk := tx.Bucket([]byte(collection))
+		if bk == nil {
+			return nil
+		}
+		return bk.ForEach(func(k, v []byte) error {
+			out[string(k)] = append([]byte(nil), v...)
+			return nil
+		})
+	})
+	return out, err
+}
+
+func (b *boltStore) Append(_ context.Context, log string, value []byte, keep int) error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		bk, err := tx.CreateBucketIfNotExists([]byte("log:" + log))
+		if err != nil {
+			return err
+		}
+		seq, err := bk.NextSequence()
+		if err != nil {
+			return err
+		}
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, seq)
+		if err := bk.Put(key, value); err != nil {
+			return err
+		}
+		if keep <= 0 || seq <= uint64(keep) {
+			return nil
+		}
+		// 删除序号不大于 seq-keep 的旧记录，遍历时删除会使游标跳过元素，先收集再删除
+		var stale [][]byte
+		c := bk.Cursor()
+		for k, _ := c.First(); k != nil && binary.BigEndian.Uint64(k) <= seq-uint64(keep); k, _ = c.Next() {
+			stale = append(stale, k)
+		}
+		for _, k := range stale {
+			if err := bk.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (b *boltStore) Tail(_ context.Context, log string, limit int) ([][]byte, error) {
+	var out [][]byte
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		bk := tx.Bucket([]byte("log:" + log))
+		if bk == nil {
+			return nil
+		}
+		c := bk.Cursor()
+		for k, v := c.Last(); k != nil && len(out) < limit; k, v = c.Prev() {
+			out = append(out, append([]byte(nil), v...))
+		}
+		return nil
+	})
+	return out, err
+}
+
+func (b *boltStore) Close() error { return b.db.Close() }
@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"context"
+	"sync"
+)
+
+// memory 为只保存在内存中的后端，进程退出后数据丢失。
+type memory struct {
+	localLocks
+
+	mu          sync.Mutex
+	collections map[string]map[string][]byte
+	logs        map[string][][]byte // 旧记录在前
+}
+
+// NewMemory 返回只保存在内存中的后端。
+func NewMemory() Store {
+	return &memory{collections: make(map[string]map[string][]byte), logs: make(map[string][][]byte)}
+}
+
+func (m *memory) Put(_ context.Context, collection, key string, value []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c := m.collections[collection]
+	if c == nil {
+		c = make(map[string][]byte)
+		m.collections[collection] = c
+	}
+	c[key] = append([]byte(nil), value...)
+	return nil
+}
+
+func (m *memory) Delete(_ context.Context, collection, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.collections[collection], key)
+	return nil
+}
+
+func (m *memory) List(_ context.Context, collection string) (map[string][]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make(map[string][]byte, len(m.collections[collection]))
+	for k, v := range m.collections[collection] {
+		out[k] = v
+	}
+	return out, nil
+}
+
+func (m *memory) Append(_ context.Context, log string, value []byte, keep int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l := append(m.logs[log], append([]byte(nil), value...))
+	if keep > 0 && len(l) > keep {
+		l = append([][]byte(nil), l[len(l)-keep:]...)
+	}
+	m.logs[log] = l
+	return nil
+}
+
+func (m *memory) Tail(_ context.Context, log string, limit int) ([][]byte, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	l := m.logs[log]
+	out := make([][]byte, 0, min(limit, len(l)))
+	for i := len(l) - 1; i >= 0 && len(out) < limit; i-- {
+		out = append(out, l[i])
+	}
+	return out, nil
+}
+
+func (m *memory) Close() error { return nil }
@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisPrefix 为全部键的前缀。
+const redisPrefix = "go_sign:"
+
+// redisStore 以 Redis 保存数据，可供多个实例共享：集合为哈希 go_sign:<collection>，
+// 日志为列表 go_sign:log:<log>，新记录在表头，租约为带过期时间的字符串 go_sign:lease:<name>，值为持有者的 token。
+type redisStore struct {
+	client *redis.Client
+}
+
+func openRedis(ctx context.Context, url string) (*redisStore, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+	return &redisStore{client: client}, nil
+}
+
+func (r *redisStore) Put(ctx context.Context, collection, key string, value []byte) error {
+	return r.client.HSet(ctx, redisPrefix+collection, key, value).Err()
+}
+
+func (r *redisStore) Delete(ctx context.Context, collection, key string) error {
+	return r.client.HDel(ctx, redisPrefix+collection, key).Err()
+}
+
+func (r *redisStore) List(ctx context.Context, collection string) (map[string][]byte, error) {
+	all, err := r.client.HGetAll(ctx, redisPrefix+collection).Result()
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string][]byte, len(all))
+	for k, v := range all {
+		out[k] = []byte(v)
+	}
+	return out, nil
+}
+
+func (r *redisStore) Append(ctx context.Context, log string, value []byte, keep int) error {
+	key := redisPrefix + "log:" + log
+	_, err := r.client.TxPipelined(ctx, func(p redis.Pipeliner) error {
+		p.LPush(ctx, key, value)
+		if keep > 0 {
+			p.LTrim(ctx, key, 0, int64(keep)-1)
+		}
+		return nil
+	})
+	return err
+}
+
+func (r *redisStore) Tail(ctx context.Context, log string, limit int) ([][]byte, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	vals, err := r.client.LRange(ctx, redisPrefix+"log:"+log, 0, int64(limit)-1).Result()
+	if err != nil {
+		return nil, err
+	}
+	out := make([][]byte, len(vals))
+	for i, v := range vals {
+		out[i] = []byte(v)
+	}
+	return out, nil
+}
+
+// 租约脚本：比较 token 与写入（或删除）在 Redis 内原子执行。
+var (
+	acquireScript = redis.NewScript(`
+local cur = redis.call('GET', KEYS[1])
+if cur == false or cur == ARGV[1] then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+	return 1
+end
+return 0`)
+	releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0`)
+)
+
+func (r *redisStore) Acquire(ctx context.Context, name, token string, ttl time.Duration) (time.Time, error) {
+	expires := time.Now().Add(ttl)
+	ok, err := acquireScript.Run(ctx, r.client, []string{redisPrefix + "lease:" + name}, token, ttl.Milliseconds()).Int()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if ok == 0 {
+		return time.Time{}, ErrLocked
+	}
+	return expires, nil
+}
+
+func (r *redisStore) Release(ctx context.Context, name, token string) error {
+	n, err := releaseScript.Run(ctx, r.client, []string{redisPrefix + "lease:" + name}, token).Int()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// Claim 以 SET NX 标记名称 name，ttl 后自动删除；已标记时返回 false。多个实例共享同一 Redis 时用于
+// 记录一次性的值（如 HMAC 签名的 nonce），见 auth.NonceStore。
+func (r *redisStore) Claim(ctx context.Context, name string, ttl time.Duration) (bool, error) {
+	return r.client.SetNX(ctx, redisPrefix+"claim:"+name, 1, ttl).Result()
+}
+
+func (r *redisStore) Close() error { return r.client.Close() }
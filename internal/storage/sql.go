@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"           // postgres 驱动
+	_ "github.com/mattn/go-sqlite3" // sqlite3 驱动，需要 CGO
+)
+
+// sqlStore 以三张表保存数据：go_sign_kv 保存集合，go_sign_log 保存日志（id 自增），
+// go_sign_lease 保存租约（过期时间为 Unix 毫秒）。SQLite 适合单机部署，Postgres 可供多个实例共享。
+type sqlStore struct {
+	db *sql.DB
+	// placeholder 返回第 n 个（从 1 开始）参数的占位符。
+	placeholder func(n int) string
+}
+
+// sqlite 与 postgres 的建表语句，两者只在自增主键与二进制类型的写法上不同。
+var (
+	sqliteSchema = []string{
+		`CREATE TABLE IF NOT EXISTS go_sign_kv (collection TEXT NOT NULL, key TEXT NOT NULL, value BLOB NOT NULL, PRIMARY KEY (collection, key))`,
+		`CREATE TABLE IF NOT EXISTS go_sign_log (id INTEGER PRIMARY KEY AUTOINCREMENT, log TEXT NOT NULL, value BLOB NOT NULL)`,
+		`CREATE INDEX IF NOT EXISTS go_sign_log_log_id ON go_sign_log (log, id)`,
+		`CREATE TABLE IF NOT EXISTS go_sign_lease (name TEXT PRIMARY KEY, token TEXT NOT NULL, expires INTEGER NOT NULL)`,
+	}
+	postgresSchema = []string{
+		`CREATE TABLE IF NOT EXISTS go_sign_kv (collection TEXT NOT NULL, key TEXT NOT NULL, value BYTEA NOT NULL, PRIMARY KEY (collection, key))`,
+		`CREATE TABLE IF NOT EXISTS go_sign_log (id BIGSERIAL PRIMARY KEY, log TEXT NOT NULL, value BYTEA NOT NULL)`,
+		`CREATE INDEX IF NOT EXISTS go_sign_log_log_id ON go_sign_log (log, id)`,
+		`CREATE TABLE IF NOT EXISTS go_sign_lease (name TEXT PRIMARY KEY, token TEXT NOT NULL, expires BIGINT NOT NULL)`,
+	}
+)
+
+func openSQLite(ctx context.Context, path string) (*sqlStore, error) {
+	// SQLite 同一时刻只允许一个写入者，单连接避免 database is locked
+	db, err := sql.Open("sqlite3", "file:"+path+"?_busy_timeout=5000&_journal_mode=WAL")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	return initSQL(ctx, db, sqliteSchema, func(int) string { return "?" })
+}
+
+func openPostgres(ctx context.Context, dsn string) (*sqlStore, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	return initSQL(ctx, db, postgresSchema, func(n int) string { return fmt.Sprintf("$%d", n) })
+}
+
+// initSQL 检查连接并建表，失败时关闭 db。
+func initSQL(ctx context.Context, db *sql.DB, schema []string, placeholder func(int) string) (*sqlStore, error) {
+	if err := db.PingContext(ctx); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	for _, stmt := range schema {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			_ = db.Close()
+			return nil, fmt.Errorf("建表失败: %w", err)
+		}
+	}
+	return &sqlStore{db: db, placeholder: placeholder}, nil
+}
+
+// query 将语句中的 ? 依次替换为驱动的占位符。
+func (s *sqlStore) query(q string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range q {
+		if r == '?' {
+			n++
+			b.WriteString(s.placeholder(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func (s *sqlStore) Put(ctx context.Context, collection, key string, value []byte) error {
+	_, err := s.db.ExecContext(ctx, s.query(`INSERT INTO go_sign_kv (collection, key, value) VALUES (?, ?, ?)
+		ON CONFLICT (collection, key) DO UPDATE SET value = excluded.value`), collection, key, value)
+	return err
+}
+
+func (s *sqlStore) Delete(ctx context.Context, collection, key string) error {
+	_, err := s.db.ExecContext(ctx, s.query(`DELETE FROM go_sign_kv WHERE collection = ? AND key = ?`), collection, key)
+	return err
+}
+
+func (s *sqlStore) List(ctx context.Context, collection string) (map[string][]byte, error) {
+	rows, err := s.db.QueryContext(ctx, s.query(`SELECT key, value FROM go_sign_kv WHERE collection = ?`), collection)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := make(map[string][]byte)
+	for rows.Next() {
+		var (
+			k string
+			v []byte
+		)
+		if err := rows.Scan(&k, &v); err != nil {
+			return nil, err
+		}
+		out[k] = v
+	}
+	return out, rows.Err()
+}
+
+func (s *sqlStore) Append(ctx context.Context, log string, value []byte, keep int) error {
+	if _, err := s.db.ExecContext(ctx, s.query(`INSERT INTO go_sign_log (log, value) VALUES (?, ?)`), log, value); err != nil {
+		return err
+	}
+	if keep <= 0 {
+		return nil
+	}
+	_, err := s.db.ExecContext(ctx, s.query(`DELETE FROM go_sign_log WHERE log = ? AND id <=
+		(SELECT id FROM go_sign_log WHERE log = ? ORDER BY id DESC LIMIT 1 OFFSET ?)`), log, log, keep)
+	return err
+}
+
+func (s *sqlStore) Tail(ctx context.Context, log string, limit int) ([][]byte, error) {
+	rows, err := s.db.QueryContext(ctx, s.query(`SELECT value FROM go_sign_log WHERE log = ? ORDER BY id DESC LIMIT ?`), log, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out [][]byte
+	for rows.Next() {
+		var v []byte
+		if err := rows.Scan(&v); err != nil {
+			return nil, err
+		}
+		out = append(out, v)
+	}
+	return out, rows.Err()
+}
+
+// Acquire 以一条条件 upsert 取得租约：名称不存在、已过期或由同一 token 持有时写入，
+// 否则不修改任何行。数据库保证同一名称的并发写入互斥，无需额外加锁。
+func (s *sqlStore) Acquire(ctx context.Context, name, token string, ttl time.Duration) (time.Time, error) {
+	now := time.Now()
+	expires := now.Add(ttl)
+	res, err := s.db.ExecContext(ctx, s.query(`INSERT INTO go_sign_lease (name, token, expires) VALUES (?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET token = excluded.token, expires = excluded.expires
+		WHERE go_sign_lease.token = excluded.token OR go_sign_lease.expires <= ?`), name, token, expires.UnixMilli(), now.UnixMilli())
+	if err != nil {
+		return time.Time{}, err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return time.Time{}, err
+	} else if n == 0 {
+		return time.Time{}, ErrLocked
+	}
+	return expires, nil
+}
+
+func (s *sqlStore) Release(ctx context.Context, name, token string) error {
+	res, err := s.db.ExecContext(ctx, s.query(`DELETE FROM go_sign_lease WHERE name = ? AND token = ? AND expires > ?`),
+		name, token, time.Now().UnixMilli())
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+func (s *sqlStore) Close() error { return s.db.Close() }
@@ -0,0 +1,139 @@
+// Package storage 为账号、签名记录等数据提供可替换的持久化后端：单机部署可使用 BoltDB 或 SQLite 文件，
+// 多实例部署可共享 Postgres 或 Redis，使各实例看到同一份数据。
+//
+// 后端只保存不透明的字节值，编码由调用方负责。数据分为两类：
+//   - 集合（collection）：按键读写的记录，如账号；
+//   - 日志（log）：只追加、保留最新若干条的记录，如签名记录。
+//
+// 后端同时提供带有效期的租约（Locker），多个实例共享后端时用于互斥修改同一条数据。
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// 支持的后端。
+const (
+	DriverMemory   = "memory"
+	DriverBolt     = "bolt"
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+	DriverRedis    = "redis"
+)
+
+// Drivers 为全部支持的后端名称。
+var Drivers = []string{DriverMemory, DriverBolt, DriverSQLite, DriverPostgres, DriverRedis}
+
+// ErrUnknownDriver 表示不支持的后端。
+var ErrUnknownDriver = errors.New("不支持的存储后端")
+
+// ErrLocked 表示租约已被其他持有者取得且未过期。
+var ErrLocked = errors.New("租约已被其他持有者取得")
+
+// ErrLeaseLost 表示释放的租约已过期或已被其他持有者取得。
+var ErrLeaseLost = errors.New("租约已过期或已被其他持有者取得")
+
+// Locker 提供按名称互斥的租约。租约以调用方生成的 token 标识持有者，过期后其他持有者可以取得，
+// 持有者崩溃时不会永久占用；租约不绑定连接，同一 token 可在不同实例、不同请求中续期与释放。
+type Locker interface {
+	// Acquire 以 token 取得名为 name 的租约，有效期为 ttl，返回过期时间。
+	// 租约由其他 token 持有且未过期时返回 ErrLocked，已由同一 token 持有时续期。
+	Acquire(ctx context.Context, name, token string, ttl time.Duration) (time.Time, error)
+	// Release 释放 token 持有的租约，租约已过期或不属于 token 时返回 ErrLeaseLost。
+	Release(ctx context.Context, name, token string) error
+}
+
+// Store 为持久化后端，实现须并发安全。
+type Store interface {
+	Locker
+
+	// Put 写入集合 collection 中键 key 的值，已存在时覆盖。
+	Put(ctx context.Context, collection, key string, value []byte) error
+	// Delete 删除集合 collection 中的键 key，不存在时不报错。
+	Delete(ctx context.Context, collection, key string) error
+	// List 返回集合 collection 中的全部键值，集合不存在时返回空 map。
+	List(ctx context.Context, collection string) (map[string][]byte, error)
+	// Append 向日志 log 追加一条记录，并删除最新 keep 条之前的记录。
+	Append(ctx context.Context, log string, value []byte, keep int) error
+	// Tail 返回日志 log 中最新的至多 limit 条记录，新记录在前。
+	Tail(ctx context.Context, log string, limit int) ([][]byte, error)
+	// Close 关闭后端。
+	Close() error
+}
+
+// Open 按 driver 打开后端：bolt、sqlite 的 dsn 为文件路径，postgres 为 lib/pq 连接串或 postgres:// URL，
+// redis 为 redis:// URL，memory 忽略 dsn。
+func Open(ctx context.Context, driver, dsn string) (Store, error) {
+	if driver != DriverMemory && dsn == "" {
+		return nil, fmt.Errorf("存储后端 %s 的 dsn 不能为空", driver)
+	}
+	var (
+		s   Store
+		err error
+	)
+	switch driver {
+	case DriverMemory:
+		return NewMemory(), nil
+	case DriverBolt:
+		s, err = openBolt(dsn)
+	case DriverSQLite:
+		s, err = openSQLite(ctx, dsn)
+	case DriverPostgres:
+		s, err = openPostgres(ctx, dsn)
+	case DriverRedis:
+		s, err = openRedis(ctx, dsn)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownDriver, driver)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("打开存储后端 %s 失败: %w", driver, err)
+	}
+	return s, nil
+}
+
+// localLocks 为进程内的租约，供不与其他实例共享的后端（memory、bolt）使用。
+type localLocks struct {
+	mu     sync.Mutex
+	leases map[string]localLease
+}
+
+// localLease 为进程内租约的持有者与过期时间。
+type localLease struct {
+	token   string
+	expires time.Time
+}
+
+func (l *localLocks) Acquire(_ context.Context, name, token string, ttl time.Duration) (time.Time, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if cur, ok := l.leases[name]; ok && cur.token != token && now.Before(cur.expires) {
+		return time.Time{}, ErrLocked
+	}
+	if l.leases == nil {
+		l.leases = make(map[string]localLease)
+	}
+	for n, cur := range l.leases {
+		if !now.Before(cur.expires) {
+			delete(l.leases, n)
+		}
+	}
+	expires := now.Add(ttl)
+	l.leases[name] = localLease{token: token, expires: expires}
+	return expires, nil
+}
+
+func (l *localLocks) Release(_ context.Context, name, token string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	cur, ok := l.leases[name]
+	if !ok || cur.token != token || !time.Now().Before(cur.expires) {
+		return ErrLeaseLost
+	}
+	delete(l.leases, name)
+	return nil
+}
@@ -0,0 +1,145 @@
+// Package timing 记录单次签名请求各阶段的耗时（排队等待页面、执行签名 JS、编码响应），
+// 在请求超过阈值时输出带耗时分解的慢请求日志，并可按 ?debug=timing 随结果返回。
+package timing
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/metrics"
+)
+
+// Stage 为签名请求的阶段。
+type Stage int
+
+// 签名请求的各阶段。
+const (
+	// QueueWait 为等待空闲页面的耗时。
+	QueueWait Stage = iota
+	// Evaluate 为在页面中执行签名 JS 的耗时。
+	Evaluate
+	// Marshal 为编码并写出响应的耗时。
+	Marshal
+	numStages
+)
+
+// String 返回阶段名称，用于日志字段。
+func (s Stage) String() string {
+	switch s {
+	case QueueWait:
+		return "queue_wait"
+	case Evaluate:
+		return "evaluate"
+	case Marshal:
+		return "marshal"
+	}
+	return "unknown"
+}
+
+// slowRequests 统计超过阈值的慢请求。
+var slowRequests = metrics.Default.NewCounterVec(
+	"go_sign_slow_requests_total",
+	"耗时超过慢请求阈值的签名请求数",
+	"route",
+)
+
+// Timing 累计一次请求各阶段的耗时，并发安全（双跑校验等场景下多个平台可能同时写入）。
+type Timing struct {
+	mu     sync.Mutex
+	start  time.Time
+	stages [numStages]time.Duration
+}
+
+type timingKey struct{}
+
+// With 返回携带新 Timing 的 context，请求总耗时从此时开始计算。
+func With(ctx context.Context) (context.Context, *Timing) {
+	t := &Timing{start: time.Now()}
+	return context.WithValue(ctx, timingKey{}, t), t
+}
+
+// From 返回 context 中的 Timing，未设置时返回 nil。
+func From(ctx context.Context) *Timing {
+	t, _ := ctx.Value(timingKey{}).(*Timing)
+	return t
+}
+
+// Observe 将阶段耗时 d 累加到 context 中的 Timing，未设置时忽略。
+func Observe(ctx context.Context, stage Stage, d time.Duration) {
+	if t := From(ctx); t != nil {
+		t.mu.Lock()
+		t.stages[stage] += d
+		t.mu.Unlock()
+	}
+}
+
+// Get 返回阶段 stage 的累计耗时。
+func (t *Timing) Get(stage Stage) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stages[stage]
+}
+
+// SlowLog 返回中间件：为请求创建 Timing，耗时超过 threshold 时输出带各阶段耗时的告警日志，
+// 其余耗时（鉴权、参数解析、页面外的处理等）计为 other。threshold 为 0 时只创建 Timing。
+// 应放在签名路由中间件的最前面。
+func SlowLog(threshold time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, t := With(c.Request.Context())
+		c.Request = c.Request.WithContext(ctx)
+		start := time.Now()
+		c.Next()
+		total := time.Since(start)
+		if threshold <= 0 || total < threshold {
+			return
+		}
+		route := c.FullPath()
+		slowRequests.Inc(route)
+		args := []any{"route", route, "status", c.Writer.Status(), "total", total}
+		other := total
+		for s := Stage(0); s < numStages; s++ {
+			d := t.Get(s)
+			other -= d
+			args = append(args, s.String(), d)
+		}
+		args = append(args, "other", other, "client_ip", c.ClientIP())
+		slog.Warn("慢签名请求", args...)
+	}
+}
+
+// Breakdown 为 ?debug=timing 时随签名结果返回的耗时分解，单位为毫秒。
+type Breakdown struct {
+	QueueWaitMs float64 `json:"queue_wait_ms"`
+	EvaluateMs  float64 `json:"evaluate_ms"`
+	// TotalMs 为从 SlowLog 中间件开始到生成结果的耗时，不含编码响应。
+	TotalMs float64 `json:"total_ms"`
+}
+
+// Debug 在请求带 ?debug=timing 时返回当前的耗时分解，并以 Server-Timing 响应头同时返回
+// （适用于 Protobuf 等不含该字段的编码），否则返回 nil。须在写出响应前调用。
+func Debug(c *gin.Context) *Breakdown {
+	if c.Query("debug") != "timing" {
+		return nil
+	}
+	t := From(c.Request.Context())
+	if t == nil {
+		return nil
+	}
+	b := &Breakdown{
+		QueueWaitMs: millis(t.Get(QueueWait)),
+		EvaluateMs:  millis(t.Get(Evaluate)),
+		TotalMs:     millis(time.Since(t.start)),
+	}
+	c.Header("Server-Timing", fmt.Sprintf("queue_wait;dur=%.3f, evaluate;dur=%.3f, total;dur=%.3f", b.QueueWaitMs, b.EvaluateMs, b.TotalMs))
+	return b
+}
+
+// millis 将 d 换算为毫秒，保留 3 位小数。
+func millis(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Microsecond)) / 1000
+}
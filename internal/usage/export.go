@@ -0,0 +1,461 @@
+package usage
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/platform"
+	"go_sign/internal/storage"
+)
+
+// statsCollection 为按日汇总的用量在存储后端中的集合。
+const statsCollection = "usage_stats"
+
+// FlushInterval 为将本实例的汇总写入存储后端的间隔。
+const FlushInterval = time.Minute
+
+// MaxExportDays 为一次导出的最大天数。
+const MaxExportDays = retainDays
+
+// 汇总维度。
+const (
+	KindKey     = "key"
+	KindAccount = "account"
+)
+
+// 导出格式。
+const (
+	FormatCSV  = "csv"
+	FormatJSON = "json"
+)
+
+// latencyBounds 为耗时分布各区间的上界（毫秒），超过最后一个上界的计入溢出区间。
+var latencyBounds = []int64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000}
+
+// bucket 为一个实例在一天内一个维度的汇总，按实例分别保存，多个实例共享后端时互不覆盖。
+type bucket struct {
+	Day       string `json:"day"`
+	Host      string `json:"host"`
+	Kind      string `json:"kind"`
+	Tenant    string `json:"tenant,omitempty"`
+	Platform  string `json:"platform,omitempty"`
+	Name      string `json:"name"`
+	Requests  int64  `json:"requests"`
+	Successes int64  `json:"successes"`
+	Failures  int64  `json:"failures"`
+	// Latency 为各耗时区间的请求数，见 latencyBounds，最后一项为溢出区间。
+	Latency []int64 `json:"latency"`
+	MaxMs   int64   `json:"max_ms"`
+}
+
+// id 返回 b 在存储后端中的键。
+func (b *bucket) id() string {
+	parts := []string{b.Day, b.Host, b.Kind, b.Tenant, b.Platform, b.Name}
+	for i, p := range parts {
+		parts[i] = url.PathEscape(p)
+	}
+	return strings.Join(parts, "/")
+}
+
+// add 将 o 的计数累加到 b。
+func (b *bucket) add(o *bucket) {
+	b.Requests += o.Requests
+	b.Successes += o.Successes
+	b.Failures += o.Failures
+	for i := range b.Latency {
+		if i < len(o.Latency) {
+			b.Latency[i] += o.Latency[i]
+		}
+	}
+	b.MaxMs = max(b.MaxMs, o.MaxMs)
+}
+
+// percentile 返回耗时分布的 q 分位数（毫秒），取所在区间的上界，位于溢出区间时为最大耗时。
+func (b *bucket) percentile(q float64) int64 {
+	var total int64
+	for _, n := range b.Latency {
+		total += n
+	}
+	if total == 0 {
+		return 0
+	}
+	rank := int64(q*float64(total) + 0.999999)
+	var seen int64
+	for i, n := range b.Latency {
+		seen += n
+		if seen >= rank {
+			if i < len(latencyBounds) {
+				return min(latencyBounds[i], b.MaxMs)
+			}
+			break
+		}
+	}
+	return b.MaxMs
+}
+
+// Stats 按日汇总各 API Key 与账号的签名请求数、成功与失败数及耗时分布，定期写入存储后端，
+// 供计费核算与容量评估导出。并发安全。
+type Stats struct {
+	store storage.Store
+	host  string
+	now   func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	dirty   map[string]bool
+}
+
+// NewStats 返回写入 store 的用量汇总，调用 Load 恢复本实例已写入的汇总。
+func NewStats(store storage.Store) *Stats {
+	host, _ := os.Hostname()
+	return &Stats{store: store, host: host, now: time.Now, buckets: map[string]*bucket{}, dirty: map[string]bool{}}
+}
+
+// Load 读取本实例此前写入的汇总以便重启后继续累加，并删除超过保留天数的汇总（含其他实例的）。
+func (s *Stats) Load(ctx context.Context) error {
+	raws, err := s.store.List(ctx, statsCollection)
+	if err != nil {
+		return fmt.Errorf("读取用量汇总失败: %w", err)
+	}
+	cutoff := s.now().AddDate(0, 0, -retainDays).Format(dayLayout)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, raw := range raws {
+		var b bucket
+		if err := json.Unmarshal(raw, &b); err != nil {
+			slog.Warn("跳过无法解析的用量汇总", "err", err, "id", id)
+			continue
+		}
+		if b.Day < cutoff {
+			if err := s.store.Delete(ctx, statsCollection, id); err != nil {
+				return fmt.Errorf("删除过期的用量汇总失败: %w", err)
+			}
+			continue
+		}
+		if b.Host == s.host && len(b.Latency) == len(latencyBounds)+1 {
+			s.buckets[id] = &b
+		}
+	}
+	return nil
+}
+
+// Observe 将一次签名请求计入其 API Key 与账号的当日汇总，账号为空时只计入 API Key。
+func (s *Stats) Observe(rec platform.SignRecord) {
+	day := rec.Time.Format(dayLayout)
+	ms := rec.Duration.Milliseconds()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.observe(&bucket{Day: day, Host: s.host, Kind: KindKey, Tenant: rec.Tenant, Name: rec.APIKey}, rec.Err, ms)
+	if rec.Account != "" {
+		s.observe(&bucket{Day: day, Host: s.host, Kind: KindAccount, Platform: rec.Platform, Name: rec.Account}, rec.Err, ms)
+	}
+}
+
+// observe 在持有锁的前提下将一次请求计入与 key 同维度的汇总。
+func (s *Stats) observe(key *bucket, errMsg string, ms int64) {
+	id := key.id()
+	b := s.buckets[id]
+	if b == nil {
+		b = key
+		b.Latency = make([]int64, len(latencyBounds)+1)
+		s.buckets[id] = b
+	}
+	b.Requests++
+	if errMsg == "" {
+		b.Successes++
+	} else {
+		b.Failures++
+	}
+	i := sort.Search(len(latencyBounds), func(i int) bool { return ms <= latencyBounds[i] })
+	b.Latency[i]++
+	b.MaxMs = max(b.MaxMs, ms)
+	s.dirty[id] = true
+}
+
+// Run 每隔 FlushInterval 将汇总写入存储后端，直到 ctx 取消；退出前应再调用一次 Flush。
+func (s *Stats) Run(ctx context.Context) {
+	t := time.NewTicker(FlushInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		if err := s.Flush(ctx); err != nil {
+			slog.Warn("写入用量汇总失败", "err", err)
+		}
+	}
+}
+
+// Flush 将上次写入后有变化的汇总写入存储后端，并从内存中移除前一天之前的汇总。
+func (s *Stats) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	pending := make(map[string][]byte, len(s.dirty))
+	for id := range s.dirty {
+		raw, err := json.Marshal(s.buckets[id])
+		if err != nil {
+			s.mu.Unlock()
+			return fmt.Errorf("序列化用量汇总失败: %w", err)
+		}
+		pending[id] = raw
+	}
+	s.dirty = map[string]bool{}
+	yesterday := s.now().AddDate(0, 0, -1).Format(dayLayout)
+	for id, b := range s.buckets {
+		if b.Day < yesterday && pending[id] == nil {
+			delete(s.buckets, id)
+		}
+	}
+	s.mu.Unlock()
+	for id, raw := range pending {
+		if err := s.store.Put(ctx, statsCollection, id, raw); err != nil {
+			// 未写入的汇总下次重试
+			s.mu.Lock()
+			for id := range pending {
+				if s.buckets[id] != nil {
+					s.dirty[id] = true
+				}
+			}
+			s.mu.Unlock()
+			return fmt.Errorf("写入用量汇总失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// Query 为导出条件，From、To 为日期（含），格式为 2006-01-02。
+type Query struct {
+	From, To string
+	// Kind 为 KindKey 或 KindAccount。
+	Kind string
+	// Tenant 非空时只导出该租户的 API Key，对账号无效。
+	Tenant string
+	// Daily 为 true 时按日分别输出，否则汇总整个区间。
+	Daily bool
+}
+
+// ErrInvalidQuery 表示导出条件不合法。
+var ErrInvalidQuery = errors.New("导出条件不合法")
+
+// check 校验 q 并填入默认值：From、To 默认为当天，Kind 默认为 KindKey。
+func (q *Query) check(now time.Time) error {
+	today := now.Format(dayLayout)
+	if q.To == "" {
+		q.To = today
+	}
+	if q.From == "" {
+		q.From = q.To
+	}
+	if q.Kind == "" {
+		q.Kind = KindKey
+	}
+	if q.Kind != KindKey && q.Kind != KindAccount {
+		return fmt.Errorf("%w: by 须为 %s 或 %s", ErrInvalidQuery, KindKey, KindAccount)
+	}
+	from, err := time.Parse(dayLayout, q.From)
+	if err != nil {
+		return fmt.Errorf("%w: from 须为 YYYY-MM-DD 格式的日期", ErrInvalidQuery)
+	}
+	to, err := time.Parse(dayLayout, q.To)
+	if err != nil {
+		return fmt.Errorf("%w: to 须为 YYYY-MM-DD 格式的日期", ErrInvalidQuery)
+	}
+	if to.Before(from) {
+		return fmt.Errorf("%w: from 不能晚于 to", ErrInvalidQuery)
+	}
+	if to.Sub(from) >= MaxExportDays*24*time.Hour {
+		return fmt.Errorf("%w: 一次至多导出 %d 天", ErrInvalidQuery, MaxExportDays)
+	}
+	return nil
+}
+
+// Row 为导出的一行：一个 API Key（或账号）在区间内的用量，按日分行时 From 与 To 相同；耗时分位数为近似值。
+type Row struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Kind     string `json:"kind"`
+	Tenant   string `json:"tenant,omitempty"`
+	Platform string `json:"platform,omitempty"`
+	// Name 为 API Key 名称，或脱敏后的账号标识（与账号管理接口返回的 identity 一致）。
+	Name         string  `json:"name"`
+	Requests     int64   `json:"requests"`
+	Successes    int64   `json:"successes"`
+	Failures     int64   `json:"failures"`
+	SuccessRate  float64 `json:"success_rate"`
+	LatencyP50Ms int64   `json:"latency_p50_ms"`
+	LatencyP90Ms int64   `json:"latency_p90_ms"`
+	LatencyP99Ms int64   `json:"latency_p99_ms"`
+	LatencyMaxMs int64   `json:"latency_max_ms"`
+}
+
+// Export 先写入本实例的汇总，再合并存储后端中全部实例的汇总，按 q 返回用量，按租户、平台、名称与日期排序。
+func (s *Stats) Export(ctx context.Context, q Query) ([]Row, error) {
+	if err := q.check(s.now()); err != nil {
+		return nil, err
+	}
+	if err := s.Flush(ctx); err != nil {
+		return nil, err
+	}
+	return exportFrom(ctx, s.store, q)
+}
+
+// ExportFrom 从 store 中读取汇总并按 q 返回用量，用于未运行服务时导出。
+func ExportFrom(ctx context.Context, store storage.Store, q Query) ([]Row, error) {
+	if err := q.check(time.Now()); err != nil {
+		return nil, err
+	}
+	return exportFrom(ctx, store, q)
+}
+
+// exportFrom 合并 store 中符合已校验的 q 的汇总。
+func exportFrom(ctx context.Context, store storage.Store, q Query) ([]Row, error) {
+	raws, err := store.List(ctx, statsCollection)
+	if err != nil {
+		return nil, fmt.Errorf("读取用量汇总失败: %w", err)
+	}
+	merged := map[string]*bucket{}
+	for id, raw := range raws {
+		var b bucket
+		if err := json.Unmarshal(raw, &b); err != nil {
+			slog.Warn("跳过无法解析的用量汇总", "err", err, "id", id)
+			continue
+		}
+		if b.Kind != q.Kind || b.Day < q.From || b.Day > q.To || (q.Kind == KindKey && q.Tenant != "" && b.Tenant != q.Tenant) {
+			continue
+		}
+		key := &bucket{Kind: b.Kind, Tenant: b.Tenant, Platform: b.Platform, Name: b.Name}
+		if q.Daily {
+			key.Day = b.Day
+		}
+		m := merged[key.id()]
+		if m == nil {
+			m = key
+			m.Latency = make([]int64, len(latencyBounds)+1)
+			merged[key.id()] = m
+		}
+		m.add(&b)
+	}
+	rows := make([]Row, 0, len(merged))
+	for _, b := range merged {
+		r := Row{
+			From:         q.From,
+			To:           q.To,
+			Kind:         b.Kind,
+			Tenant:       b.Tenant,
+			Platform:     b.Platform,
+			Name:         b.Name,
+			Requests:     b.Requests,
+			Successes:    b.Successes,
+			Failures:     b.Failures,
+			LatencyP50Ms: b.percentile(0.5),
+			LatencyP90Ms: b.percentile(0.9),
+			LatencyP99Ms: b.percentile(0.99),
+			LatencyMaxMs: b.MaxMs,
+		}
+		if b.Day != "" {
+			r.From, r.To = b.Day, b.Day
+		}
+		if b.Requests > 0 {
+			r.SuccessRate = float64(b.Successes) / float64(b.Requests)
+		}
+		rows = append(rows, r)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if a.Tenant != b.Tenant {
+			return a.Tenant < b.Tenant
+		}
+		if a.Platform != b.Platform {
+			return a.Platform < b.Platform
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.From < b.From
+	})
+	return rows, nil
+}
+
+// csvHeader 为 CSV 导出的表头，与 Row 的 JSON 字段一致。
+var csvHeader = []string{"from", "to", "kind", "tenant", "platform", "name", "requests", "successes", "failures", "success_rate", "latency_p50_ms", "latency_p90_ms", "latency_p99_ms", "latency_max_ms"}
+
+// WriteRows 以 format（FormatCSV 或 FormatJSON）将 rows 写入 w。
+func WriteRows(w io.Writer, format string, rows []Row) error {
+	if format == FormatJSON {
+		return json.NewEncoder(w).Encode(map[string]any{"rows": rows})
+	}
+	cw := csv.NewWriter(w)
+	_ = cw.Write(csvHeader)
+	for _, r := range rows {
+		_ = cw.Write([]string{
+			r.From, r.To, r.Kind, r.Tenant, r.Platform, r.Name,
+			strconv.FormatInt(r.Requests, 10),
+			strconv.FormatInt(r.Successes, 10),
+			strconv.FormatInt(r.Failures, 10),
+			strconv.FormatFloat(r.SuccessRate, 'f', 4, 64),
+			strconv.FormatInt(r.LatencyP50Ms, 10),
+			strconv.FormatInt(r.LatencyP90Ms, 10),
+			strconv.FormatInt(r.LatencyP99Ms, 10),
+			strconv.FormatInt(r.LatencyMaxMs, 10),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ExportHandler 返回导出用量的管理接口 GET /admin/usage/export，参数 from、to（YYYY-MM-DD，含，默认为当天）、
+// by（key 或 account，默认 key）、tenant（只导出该租户的 Key）、daily（true 时按日分行）与
+// format（csv 或 json，默认 csv）。
+func (s *Stats) ExportHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		q := Query{From: c.Query("from"), To: c.Query("to"), Kind: c.Query("by"), Tenant: c.Query("tenant")}
+		if v := c.Query("daily"); v != "" {
+			daily, err := strconv.ParseBool(v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "daily 须为 true 或 false"})
+				return
+			}
+			q.Daily = daily
+		}
+		format := c.DefaultQuery("format", FormatCSV)
+		if format != FormatCSV && format != FormatJSON {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "format 须为 csv 或 json"})
+			return
+		}
+		if err := q.check(s.now()); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		rows, err := s.Export(c.Request.Context(), q)
+		if err != nil {
+			slog.Error("导出用量失败", "err", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if format == FormatCSV {
+			c.Header("Content-Type", "text/csv; charset=utf-8")
+			c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=usage-%s-%s-%s.csv", q.Kind, q.From, q.To))
+		} else {
+			c.Header("Content-Type", "application/json; charset=utf-8")
+		}
+		c.Status(http.StatusOK)
+		if err := WriteRows(c.Writer, format, rows); err != nil {
+			slog.Warn("写入用量导出失败", "err", err)
+		}
+	}
+}
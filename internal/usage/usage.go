@@ -0,0 +1,184 @@
+// Package usage 提供按 API Key 与租户统计签名次数与配额控制。
+package usage
+
+import (
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/auth"
+)
+
+// 日、月统计周期的键格式。
+const (
+	dayLayout   = "2006-01-02"
+	monthLayout = "2006-01"
+	// retainDays 为按日统计数据的保留天数。
+	retainDays = 90
+)
+
+// 统计维度前缀。
+const (
+	scopeKey    = "key:"
+	scopeTenant = "tenant:"
+)
+
+// Tracker 记录各 API Key 与租户的日、月签名次数，并发安全。
+type Tracker struct {
+	mu      sync.Mutex
+	daily   map[string]map[string]int64 // 统计维度 -> 日期 -> 次数
+	monthly map[string]map[string]int64 // 统计维度 -> 月份 -> 次数
+	now     func() time.Time
+}
+
+// NewTracker 创建一个空的用量统计器。
+func NewTracker() *Tracker {
+	return &Tracker{
+		daily:   make(map[string]map[string]int64),
+		monthly: make(map[string]map[string]int64),
+		now:     time.Now,
+	}
+}
+
+// Report 为单个 API Key 或租户的用量报告。
+type Report struct {
+	Name             string `json:"name"`
+	Tenant           string `json:"tenant,omitempty"`
+	Day              string `json:"day"`
+	DailyCount       int64  `json:"daily_count"`
+	DailyQuota       int64  `json:"daily_quota,omitempty"`
+	DailyRemaining   *int64 `json:"daily_remaining,omitempty"`
+	Month            string `json:"month"`
+	MonthlyCount     int64  `json:"monthly_count"`
+	MonthlyQuota     int64  `json:"monthly_quota,omitempty"`
+	MonthlyRemaining *int64 `json:"monthly_remaining,omitempty"`
+}
+
+// exceeded 判断报告中的用量是否已达配额。
+func (r Report) exceeded() bool {
+	return (r.DailyQuota > 0 && r.DailyCount >= r.DailyQuota) ||
+		(r.MonthlyQuota > 0 && r.MonthlyCount >= r.MonthlyQuota)
+}
+
+// Take 在 Key 与其租户的配额均允许时计一次用量，返回是否允许及计数后的 Key、租户报告。
+// 配额为 0 表示不限制。
+func (t *Tracker) Take(key *auth.Key) (keyReport, tenantReport Report, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	day, month := now.Format(dayLayout), now.Format(monthLayout)
+	keyScope, tenantScope := scopeKey+key.Tenant.Name+"/"+key.Name, scopeTenant+key.Tenant.Name
+
+	keyReport = t.report(keyScope, key.Name, day, month, key.DailyQuota, key.MonthlyQuota)
+	tenantReport = t.report(tenantScope, key.Tenant.Name, day, month, key.Tenant.DailyQuota, key.Tenant.MonthlyQuota)
+	if keyReport.exceeded() || tenantReport.exceeded() {
+		return keyReport, tenantReport, false
+	}
+	t.incr(keyScope, day, month, now)
+	t.incr(tenantScope, day, month, now)
+	keyReport = t.report(keyScope, key.Name, day, month, key.DailyQuota, key.MonthlyQuota)
+	tenantReport = t.report(tenantScope, key.Tenant.Name, day, month, key.Tenant.DailyQuota, key.Tenant.MonthlyQuota)
+	return keyReport, tenantReport, true
+}
+
+// incr 在持有锁的前提下为统计维度计数，并在跨日时清理过期数据。
+func (t *Tracker) incr(scope, day, month string, now time.Time) {
+	if t.daily[scope] == nil {
+		t.daily[scope] = make(map[string]int64)
+		t.monthly[scope] = make(map[string]int64)
+	}
+	if _, ok := t.daily[scope][day]; !ok {
+		cutoff := now.AddDate(0, 0, -retainDays).Format(dayLayout)
+		for d := range t.daily[scope] {
+			if d < cutoff {
+				delete(t.daily[scope], d)
+			}
+		}
+	}
+	t.daily[scope][day]++
+	t.monthly[scope][month]++
+}
+
+// report 在持有锁的前提下生成用量报告。
+func (t *Tracker) report(scope, name, day, month string, dailyQuota, monthlyQuota int64) Report {
+	r := Report{
+		Name:         name,
+		Day:          day,
+		DailyCount:   t.daily[scope][day],
+		DailyQuota:   dailyQuota,
+		Month:        month,
+		MonthlyCount: t.monthly[scope][month],
+		MonthlyQuota: monthlyQuota,
+	}
+	if dailyQuota > 0 {
+		left := max(dailyQuota-r.DailyCount, 0)
+		r.DailyRemaining = &left
+	}
+	if monthlyQuota > 0 {
+		left := max(monthlyQuota-r.MonthlyCount, 0)
+		r.MonthlyRemaining = &left
+	}
+	return r
+}
+
+// Reports 返回 keys 与 tenants 在当前日、月的用量报告，按名称排序。
+func (t *Tracker) Reports(keys []*auth.Key, tenants []*auth.Tenant) (keyReports, tenantReports []Report) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	day, month := now.Format(dayLayout), now.Format(monthLayout)
+	for _, k := range keys {
+		r := t.report(scopeKey+k.Tenant.Name+"/"+k.Name, k.Name, day, month, k.DailyQuota, k.MonthlyQuota)
+		r.Tenant = k.Tenant.Name
+		keyReports = append(keyReports, r)
+	}
+	for _, tn := range tenants {
+		tenantReports = append(tenantReports, t.report(scopeTenant+tn.Name, tn.Name, day, month, tn.DailyQuota, tn.MonthlyQuota))
+	}
+	sort.Slice(keyReports, func(i, j int) bool { return keyReports[i].Name < keyReports[j].Name })
+	sort.Slice(tenantReports, func(i, j int) bool { return tenantReports[i].Name < tenantReports[j].Name })
+	return keyReports, tenantReports
+}
+
+// Middleware 返回配额控制中间件，需放在鉴权中间件之后。
+// Key 或租户配额耗尽时返回 429，并通过 X-Quota-* 响应头告知 Key 的剩余用量。
+func (t *Tracker) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := auth.FromContext(c)
+		kr, tr, ok := t.Take(key)
+		if kr.DailyRemaining != nil {
+			c.Header("X-Quota-Daily-Remaining", strconv.FormatInt(*kr.DailyRemaining, 10))
+		}
+		if kr.MonthlyRemaining != nil {
+			c.Header("X-Quota-Monthly-Remaining", strconv.FormatInt(*kr.MonthlyRemaining, 10))
+		}
+		if !ok {
+			slog.Warn("配额已耗尽", "api_key", key.Name, "tenant", key.Tenant.Name,
+				"daily_count", kr.DailyCount, "monthly_count", kr.MonthlyCount,
+				"tenant_daily_count", tr.DailyCount, "tenant_monthly_count", tr.MonthlyCount)
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "配额已耗尽", "usage": kr, "tenant_usage": tr})
+			return
+		}
+		c.Next()
+	}
+}
+
+// Handler 返回用量报告接口，列出 Key 与租户的当日与当月用量。
+// 调用方只能看到自己所属租户的数据。
+func (t *Tracker) Handler(kr *auth.Keyring) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		caller := auth.FromContext(c)
+		var keys []*auth.Key
+		for _, k := range kr.Keys() {
+			if k.Tenant == caller.Tenant {
+				keys = append(keys, k)
+			}
+		}
+		keyReports, tenantReports := t.Reports(keys, []*auth.Tenant{caller.Tenant})
+		c.JSON(http.StatusOK, gin.H{"usage": keyReports, "tenants": tenantReports})
+	}
+}
@@ -0,0 +1,281 @@
+// Package webhook 在签名服务的生命周期事件（槽位重建、出现验证码、账号被风控、批量任务完成、配置重新加载）发生时
+// 以带 HMAC 签名的 JSON 回调外部系统，失败时按指数退避重试，外部系统无需轮询即可响应。
+// 未调用 Init 或未配置回调地址时 Emit 为空操作。
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"go_sign/internal/config"
+	"go_sign/internal/metrics"
+)
+
+// 事件类型，与 config.WebhookEvents 一致。
+const (
+	// SignerRestarted 为页面崩溃、关闭或签名函数丢失后重建槽位。
+	SignerRestarted = "signer.restarted"
+	// CaptchaDetected 为页面停留在验证码页，同一槽位至多每 CaptchaInterval 发送一次。
+	CaptchaDetected = "captcha.detected"
+	// AccountBanned 为 a1 多次被上游风控而进入冷却。
+	AccountBanned = "account.banned"
+	// JobCompleted 为批量签名任务完成。
+	JobCompleted = "job.completed"
+	// ConfigReloaded 为配置文件重新加载成功。
+	ConfigReloaded = "config.reloaded"
+)
+
+// CaptchaInterval 为同一槽位的验证码事件的最小间隔，健康检查每轮都会检测到停留在验证码页的槽位。
+const CaptchaInterval = 10 * time.Minute
+
+// 回调的请求头。
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderID        = "X-Webhook-Id"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	// HeaderSignature 为 hex(HMAC-SHA256(secret, "<时间戳>\n<请求体>"))，未配置 secret 时不发送。
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// 回调的默认值。
+const (
+	DefaultTimeout    = 10 * time.Second
+	DefaultMaxRetries = 3
+	// retryBase 为第一次重试前的等待时间，之后每次翻倍。
+	retryBase = time.Second
+)
+
+var deliveries = metrics.Default.NewCounterVec(
+	"go_sign_webhooks_total",
+	"生命周期事件回调的发送次数（含重试后的最终结果），result 为 success 或 error",
+	"event", "result",
+)
+
+// Event 为回调的请求体。
+type Event struct {
+	ID   string         `json:"id"`
+	Type string         `json:"event"`
+	Time time.Time      `json:"time"`
+	Host string         `json:"host"`
+	Data map[string]any `json:"data,omitempty"`
+}
+
+// endpoint 为一个回调地址。
+type endpoint struct {
+	url        string
+	secret     []byte
+	events     map[string]bool // 为空时订阅全部事件
+	maxRetries int
+	client     *http.Client
+}
+
+// dispatcher 为已初始化的回调配置。
+type dispatcher struct {
+	host      string
+	endpoints []*endpoint
+	pending   sync.WaitGroup
+}
+
+var (
+	mu  sync.RWMutex
+	std *dispatcher
+
+	// throttled 记录按键限频的事件最近一次的发送时间，见 EmitThrottled
+	throttleMu sync.Mutex
+	throttled  = map[string]time.Time{}
+)
+
+// Init 按配置启用回调，替换之前的配置，hooks 为空时关闭回调；可在重新加载配置时再次调用。
+// 替换前已提交的回调仍按原配置发送完。
+func Init(hooks []config.Webhook) {
+	var d *dispatcher
+	if len(hooks) > 0 {
+		host, _ := os.Hostname()
+		d = &dispatcher{host: host}
+		for _, h := range hooks {
+			e := &endpoint{url: h.URL, secret: []byte(h.Secret), maxRetries: h.MaxRetries, client: &http.Client{Timeout: h.Timeout}}
+			switch {
+			case e.maxRetries == 0:
+				e.maxRetries = DefaultMaxRetries
+			case e.maxRetries < 0:
+				e.maxRetries = 0
+			}
+			if h.Timeout == 0 {
+				e.client.Timeout = DefaultTimeout
+			}
+			if len(h.Events) > 0 {
+				e.events = make(map[string]bool, len(h.Events))
+				for _, ev := range h.Events {
+					e.events[ev] = true
+				}
+			}
+			d.endpoints = append(d.endpoints, e)
+		}
+	}
+	mu.Lock()
+	std = d
+	mu.Unlock()
+	if d != nil {
+		slog.Info("生命周期事件回调已开启", "endpoints", len(d.endpoints))
+	}
+}
+
+// current 返回当前的回调配置，未初始化时为 nil。
+func current() *dispatcher {
+	mu.RLock()
+	defer mu.RUnlock()
+	return std
+}
+
+// Emit 异步发送类型为 typ 的事件到订阅了该事件的回调地址，kv 为与 slog 相同的键值对。
+func Emit(typ string, kv ...any) {
+	d := current()
+	if d == nil {
+		return
+	}
+	ev := Event{ID: newID(), Type: typ, Time: time.Now(), Host: d.host, Data: toMap(kv)}
+	for _, e := range d.endpoints {
+		if e.events != nil && !e.events[typ] {
+			continue
+		}
+		d.pending.Add(1)
+		go func(e *endpoint) {
+			defer d.pending.Done()
+			d.deliver(e, ev)
+		}(e)
+	}
+}
+
+// EmitThrottled 与 Emit 相同，但同一 key 在 interval 内只发送一次，用于会被反复检测到的状态。
+func EmitThrottled(typ, key string, interval time.Duration, kv ...any) {
+	if current() == nil {
+		return
+	}
+	now := time.Now()
+	k := typ + "\x00" + key
+	throttleMu.Lock()
+	if last, ok := throttled[k]; ok && now.Sub(last) < interval {
+		throttleMu.Unlock()
+		return
+	}
+	for old, last := range throttled {
+		if now.Sub(last) >= interval {
+			delete(throttled, old)
+		}
+	}
+	throttled[k] = now
+	throttleMu.Unlock()
+	Emit(typ, kv...)
+}
+
+// deliver 发送事件，失败时按指数退避重试至多 maxRetries 次。
+func (d *dispatcher) deliver(e *endpoint, ev Event) {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		slog.Error("序列化回调事件失败", "err", err, "event", ev.Type)
+		return
+	}
+	wait := retryBase
+	for attempt := 0; ; attempt++ {
+		err = e.post(ev, body)
+		if err == nil {
+			deliveries.Inc(ev.Type, "success")
+			return
+		}
+		if attempt >= e.maxRetries {
+			break
+		}
+		slog.Debug("回调发送失败，稍后重试", "err", err, "event", ev.Type, "id", ev.ID, "attempt", attempt+1, "wait", wait)
+		time.Sleep(wait)
+		wait *= 2
+	}
+	deliveries.Inc(ev.Type, "error")
+	slog.Warn("回调发送失败", "err", err, "event", ev.Type, "id", ev.ID, "url", e.url, "attempts", e.maxRetries+1)
+}
+
+// post 以 JSON 发送一次事件，2xx 以外的状态码视为失败。
+func (e *endpoint) post(ev Event, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	ts := strconv.FormatInt(ev.Time.Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, ev.Type)
+	req.Header.Set(HeaderID, ev.ID)
+	req.Header.Set(HeaderTimestamp, ts)
+	if len(e.secret) > 0 {
+		req.Header.Set(HeaderSignature, Sign(e.secret, ts, body))
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("回调地址返回状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign 返回回调的签名 hex(HMAC-SHA256(secret, "<timestamp>\n<body>"))，接收方以相同方式计算后比较，
+// 并拒绝时间戳偏差过大的请求以防重放。
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{'\n'})
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Flush 等待已提交的回调发送完成（含重试），至多等待 timeout。
+func Flush(timeout time.Duration) {
+	d := current()
+	if d == nil {
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		d.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+}
+
+// newID 返回随机的事件 ID，接收方据此对重试的回调去重。
+func newID() string {
+	b := make([]byte, 12)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// toMap 将 slog 风格的键值对转换为 map，值为 error 时取其文本。
+func toMap(kv []any) map[string]any {
+	if len(kv) == 0 {
+		return nil
+	}
+	out := make(map[string]any, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		k := fmt.Sprint(kv[i])
+		if err, ok := kv[i+1].(error); ok {
+			out[k] = err.Error()
+			continue
+		}
+		out[k] = kv[i+1]
+	}
+	return out
+}
@@ -0,0 +1,61 @@
+package wire
+
+import (
+	"compress/gzip"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/metrics"
+)
+
+// MaxDecompressedSize 为 gzip 请求体解压后的上限，超出时读取请求体失败，防止压缩炸弹。
+const MaxDecompressedSize = 64 << 20
+
+// compressedRequests 统计 gzip 压缩的请求数。
+var compressedRequests = metrics.Default.NewCounterVec(
+	"go_sign_compressed_requests_total",
+	"Content-Encoding 为 gzip 的请求数",
+	"path",
+)
+
+// Decompress 返回解压请求体的中间件：Content-Encoding 为 gzip 时将请求体替换为解压后的内容并移除该请求头，
+// 后续的解析与流量镜像均看到解压后的请求体；其他编码返回 415。
+// 须放在读取请求体的中间件之前；HMAC 签名鉴权在其之前执行，按压缩后的原始请求体计算。
+func Decompress() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch enc := strings.ToLower(strings.TrimSpace(c.GetHeader("Content-Encoding"))); enc {
+		case "", "identity":
+			c.Next()
+			return
+		case "gzip", "x-gzip":
+		default:
+			c.AbortWithStatusJSON(http.StatusUnsupportedMediaType, gin.H{"error": "不支持的 Content-Encoding: " + enc})
+			return
+		}
+		zr, err := gzip.NewReader(c.Request.Body)
+		if err != nil {
+			slog.Warn("请求体 gzip 解压失败", "err", err, "path", c.FullPath(), "client_ip", c.ClientIP())
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "请求体 gzip 解压失败: " + err.Error()})
+			return
+		}
+		compressedRequests.Inc(c.FullPath())
+		c.Request.Body = http.MaxBytesReader(c.Writer, gzipBody{zr, c.Request.Body}, MaxDecompressedSize)
+		c.Request.Header.Del("Content-Encoding")
+		c.Request.ContentLength = -1
+		c.Next()
+	}
+}
+
+// gzipBody 读取解压后的内容，关闭时同时关闭原请求体。
+type gzipBody struct {
+	*gzip.Reader
+	raw io.Closer
+}
+
+func (b gzipBody) Close() error {
+	_ = b.Reader.Close()
+	return b.raw.Close()
+}
@@ -0,0 +1,115 @@
+package wire
+
+import (
+	"sort"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// 以下为手工编解码 Protobuf 消息的辅助函数，零值字段按 proto3 语义不写出。
+
+// AppendString 追加 string / bytes 字段。
+func AppendString(b []byte, num protowire.Number, v string) []byte {
+	if v == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, v)
+}
+
+// AppendBytes 追加 bytes 字段。
+func AppendBytes(b []byte, num protowire.Number, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+// AppendInt64 追加 int64 字段。
+func AppendInt64(b []byte, num protowire.Number, v int64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, uint64(v))
+}
+
+// AppendMap 追加 map<string, string> 字段，按键排序以保证编码结果稳定。
+func AppendMap(b []byte, num protowire.Number, m map[string]string) []byte {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		entry := AppendString(nil, 1, k)
+		entry = AppendString(entry, 2, m[k])
+		b = protowire.AppendTag(b, num, protowire.BytesType)
+		b = protowire.AppendBytes(b, entry)
+	}
+	return b
+}
+
+// AppendMessage 追加嵌套消息字段，msg 为 nil 时不写出。
+func AppendMessage(b []byte, num protowire.Number, msg []byte) []byte {
+	if msg == nil {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, msg)
+}
+
+// Field 为解码出的一个字段：varint 类型的值在 Varint 中，length-delimited 类型的值在 Bytes 中。
+type Field struct {
+	Num    protowire.Number
+	Varint uint64
+	Bytes  []byte
+}
+
+// RangeFields 依次对消息中的 varint 与 length-delimited 字段调用 fn，跳过其他类型的字段。
+func RangeFields(b []byte, fn func(Field) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		f := Field{Num: num}
+		switch typ {
+		case protowire.VarintType:
+			f.Varint, n = protowire.ConsumeVarint(b)
+		case protowire.BytesType:
+			f.Bytes, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+			if n < 0 {
+				return protowire.ParseError(n)
+			}
+			b = b[n:]
+			continue
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MapEntry 解码 map<string, string> 的一个条目。
+func MapEntry(b []byte) (key, value string, err error) {
+	err = RangeFields(b, func(f Field) error {
+		switch f.Num {
+		case 1:
+			key = string(f.Bytes)
+		case 2:
+			value = string(f.Bytes)
+		}
+		return nil
+	})
+	return key, value, err
+}
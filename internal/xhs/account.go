@@ -0,0 +1,156 @@
+package xhs
+
+import (
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"go_sign/internal/logging"
+	"go_sign/internal/pagepool"
+	"go_sign/internal/platform"
+)
+
+// 账号健康评分参数：评分为上游响应成功率的指数加权平均，初始为 1，每次反馈按 scoreAlpha 向 1（成功）或 0（风控）靠拢；
+// 低于 degradedScore 时视为降级，只在没有更健康的空闲页面时使用。
+const (
+	scoreAlpha    = 0.2
+	degradedScore = 0.5
+)
+
+// accountScore 为单个 a1 的评分与反馈计数。
+type accountScore struct {
+	score     float64
+	successes int
+	failures  int
+	updated   time.Time
+}
+
+// accountScores 按 a1 维护健康评分。
+type accountScores struct {
+	mu     sync.Mutex
+	scores map[string]*accountScore
+}
+
+// record 记录 a1 的一次上游响应，返回更新后的评分及是否由正常转为降级、由降级恢复。
+func (a *accountScores) record(a1 string, ok bool) (score float64, demoted, restored bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.scores == nil {
+		a.scores = make(map[string]*accountScore)
+	}
+	s := a.scores[a1]
+	if s == nil {
+		s = &accountScore{score: 1}
+		a.scores[a1] = s
+	}
+	prev, target := s.score, 0.0
+	if ok {
+		target = 1
+		s.successes++
+	} else {
+		s.failures++
+	}
+	s.score += scoreAlpha * (target - s.score)
+	s.updated = time.Now()
+	return s.score, prev >= degradedScore && s.score < degradedScore, prev < degradedScore && s.score >= degradedScore
+}
+
+// score 返回 a1 的健康评分，没有反馈时为 1。
+func (a *accountScores) score(a1 string) float64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if s := a.scores[a1]; s != nil {
+		return s.score
+	}
+	return 1
+}
+
+// degraded 返回评分低于 degradedScore 的账号数。
+func (a *accountScores) degraded() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	n := 0
+	for _, s := range a.scores {
+		if s.score < degradedScore {
+			n++
+		}
+	}
+	return n
+}
+
+// feedbackOK 将上游响应状态码归类为账号层面的成功或失败：2xx、3xx 为成功，461、406 为风控失败，
+// 其余状态码（参数错误、上游故障等）与账号无关，counted 为 false。
+func feedbackOK(status int) (ok, counted bool) {
+	switch {
+	case status < 400:
+		return true, true
+	case status == StatusBanned || status == StatusRejected:
+		return false, true
+	}
+	return false, false
+}
+
+// scoreAccount 以上游响应更新 a1 的健康评分，评分跨过 degradedScore 时记录日志并更新指标。
+func (s *Signer) scoreAccount(a1 string, status int) {
+	ok, counted := feedbackOK(status)
+	if a1 == "" || !counted {
+		return
+	}
+	score, demoted, restored := s.scores.record(a1, ok)
+	name := s.opts.Profile.PlatformName()
+	switch {
+	case demoted:
+		slog.Warn("账号健康评分过低，降低分配优先级", "platform", name, "a1", a1, "score", score, "status", status)
+	case restored:
+		slog.Info("账号健康评分恢复", "platform", name, "a1", a1, "score", score)
+	default:
+		return
+	}
+	degradedAccounts.Set(float64(s.scores.degraded()), name)
+}
+
+// rankSlot 为页面池的分配优先级：页面自身 a1 的健康评分。
+func (s *Signer) rankSlot(slot *pagepool.Slot) float64 {
+	return s.scores.score(slot.Identity)
+}
+
+// Accounts 返回各页面 a1 及有反馈或签名记录的 a1 的健康评分、冷却状态与签名用量，按评分升序，账号标识已脱敏。
+func (s *Signer) Accounts() []platform.AccountHealth {
+	seen := make(map[string]bool)
+	for _, pool := range s.pools {
+		for _, slot := range pool.AllSlots() {
+			if slot.Identity != "" {
+				seen[slot.Identity] = true
+			}
+		}
+	}
+	s.scores.mu.Lock()
+	stats := make(map[string]accountScore, len(s.scores.scores))
+	for a1, sc := range s.scores.scores {
+		seen[a1] = true
+		stats[a1] = *sc
+	}
+	s.scores.mu.Unlock()
+	usage := s.usage.snapshot(time.Now())
+	for a1 := range usage {
+		seen[a1] = true
+	}
+
+	out := make([]platform.AccountHealth, 0, len(seen))
+	for a1 := range seen {
+		h := platform.AccountHealth{Account: logging.Mask(a1), Score: 1}
+		if sc, ok := stats[a1]; ok {
+			h.Score, h.Successes, h.Failures = sc.score, sc.successes, sc.failures
+		}
+		h.Degraded = h.Score < degradedScore
+		h.Usage = usage[a1]
+		if d := s.bans.remaining(a1); d > 0 {
+			until := time.Now().Add(d)
+			h.CooldownUntil = &until
+		}
+		out = append(out, h)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Score < out[j].Score })
+	return out
+}
@@ -0,0 +1,139 @@
+package xhs
+
+import (
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"go_sign/internal/logging"
+	"go_sign/internal/platform"
+	"go_sign/internal/webhook"
+)
+
+// 小红书对风控账号的响应状态码：461 为账号或设备被限制，406 为签名校验未通过（通常随风控出现）。
+const (
+	StatusBanned   = 461
+	StatusRejected = 406
+)
+
+// banTracker 按 a1 统计上游风控响应，window 内达到 threshold 次时使该 a1 冷却 cooldown。
+type banTracker struct {
+	threshold int
+	window    time.Duration
+	cooldown  time.Duration
+
+	mu      sync.Mutex
+	hits    map[string][]time.Time // a1 -> 窗口内的风控响应时间
+	cooling map[string]platform.Cooldown
+}
+
+// newBanTracker 创建风控跟踪，threshold 或 cooldown <= 0 时返回 nil 表示不冷却。
+func newBanTracker(threshold int, window, cooldown time.Duration) *banTracker {
+	if threshold <= 0 || cooldown <= 0 {
+		return nil
+	}
+	return &banTracker{
+		threshold: threshold,
+		window:    window,
+		cooldown:  cooldown,
+		hits:      make(map[string][]time.Time),
+		cooling:   make(map[string]platform.Cooldown),
+	}
+}
+
+// observe 记录 a1 的一次上游响应，非风控状态码清空该 a1 的计数；达到阈值时进入冷却并返回冷却记录与 true。
+func (b *banTracker) observe(a1 string, status int) (platform.Cooldown, bool) {
+	if b == nil || a1 == "" {
+		return platform.Cooldown{}, false
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if status != StatusBanned && status != StatusRejected {
+		if status < 400 {
+			delete(b.hits, a1)
+		}
+		return platform.Cooldown{}, false
+	}
+	now := time.Now()
+	hits := append(b.hits[a1], now)
+	for len(hits) > 0 && b.window > 0 && now.Sub(hits[0]) > b.window {
+		hits = hits[1:]
+	}
+	if len(hits) < b.threshold {
+		b.hits[a1] = hits
+		return platform.Cooldown{}, false
+	}
+	delete(b.hits, a1)
+	cd := platform.Cooldown{Account: a1, Status: status, Count: len(hits), Until: now.Add(b.cooldown)}
+	b.cooling[a1] = cd
+	return cd, true
+}
+
+// remaining 返回 a1 剩余的冷却时间，未冷却时为 0。
+func (b *banTracker) remaining(a1 string) time.Duration {
+	if b == nil || a1 == "" {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	cd, ok := b.cooling[a1]
+	if !ok {
+		return 0
+	}
+	d := time.Until(cd.Until)
+	if d <= 0 {
+		delete(b.cooling, a1)
+		return 0
+	}
+	return d
+}
+
+// list 返回冷却中的账号，按结束时间排序，账号标识已脱敏。
+func (b *banTracker) list() []platform.Cooldown {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var out []platform.Cooldown
+	now := time.Now()
+	for a1, cd := range b.cooling {
+		if !now.Before(cd.Until) {
+			delete(b.cooling, a1)
+			continue
+		}
+		cd.Account = logging.Mask(a1)
+		out = append(out, cd)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Until.Before(out[j].Until) })
+	return out
+}
+
+// ReportStatus 记录使用 a1 签名的请求在上游得到的状态码并更新其健康评分，短时间内多次收到 461 或 406 时使该 a1 冷却：
+// 页面自身 a1 为该值的槽位暂停分配。签名只使用页面自身的 a1，请求参数中的 a1 不参与冷却判断。
+func (s *Signer) ReportStatus(a1 string, status int) {
+	s.scoreAccount(a1, status)
+	cd, triggered := s.bans.observe(a1, status)
+	if !triggered {
+		return
+	}
+	name := s.opts.Profile.PlatformName()
+	accountCooldowns.Inc(name)
+	benched := 0
+	for _, pool := range s.pools {
+		for _, slot := range pool.AllSlots() {
+			if slot.Identity == a1 {
+				pool.Bench(slot, cd.Until)
+				benched++
+			}
+		}
+	}
+	slog.Warn("账号多次被上游风控，进入冷却", "platform", name, "a1", a1, "status", status, "count", cd.Count, "until", cd.Until, "slots", benched)
+	webhook.Emit(webhook.AccountBanned, "platform", name, "a1", logging.Mask(a1), "status", status, "count", cd.Count, "until", cd.Until)
+}
+
+// Cooldowns 返回冷却中的账号。
+func (s *Signer) Cooldowns() []platform.Cooldown {
+	return s.bans.list()
+}
@@ -0,0 +1,93 @@
+package xhs
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// x-s-common 中的站点脚本版本，随站点更新。
+const (
+	commonSDKVersion = "3.7.8-2"
+	commonAppID      = "xhs-pc-web"
+	commonAppVersion = "4.27.2"
+)
+
+// commonEncoding 为站点生成 x-s-common 使用的 base64 字符表。
+var commonEncoding = base64.NewEncoding("ZmserbBoHQtNP+wOcza/LpngG8yJq42KWYj0DSfdikx3VT16IlUAFM97hECvuRX5")
+
+// pageStorage 为签名页面 localStorage、sessionStorage 中参与 x-s-common 的字段。
+type pageStorage struct {
+	B1       string `json:"b1"`
+	B1B1     string `json:"b1b1"`
+	SigCount int    `json:"sc"`
+}
+
+// pageStorageJS 读取签名页面的 pageStorage 字段。
+const pageStorageJS = `({b1: localStorage.getItem('b1') || '', b1b1: localStorage.getItem('b1b1') || '1', sc: Number(sessionStorage.getItem('sc')) || 0})`
+
+// xsCommon 按站点脚本的规则生成 x-s-common：按固定字段顺序序列化为 JSON 后以站点字符表做 base64。
+// a1 为签名页面的 a1，ua 用于推断平台字段。
+func xsCommon(a1, ua, xs, xt string, st pageStorage) string {
+	code, name := platformCode(ua)
+	common := struct {
+		S0  int    `json:"s0"`
+		S1  string `json:"s1"`
+		X0  string `json:"x0"`
+		X1  string `json:"x1"`
+		X2  string `json:"x2"`
+		X3  string `json:"x3"`
+		X4  string `json:"x4"`
+		X5  string `json:"x5"`
+		X6  string `json:"x6"`
+		X7  string `json:"x7"`
+		X8  string `json:"x8"`
+		X9  int32  `json:"x9"`
+		X10 int    `json:"x10"`
+	}{
+		S0: code, X0: st.B1B1, X1: commonSDKVersion, X2: name, X3: commonAppID, X4: commonAppVersion,
+		X5: a1, X6: xt, X7: xs, X8: st.B1, X9: mrc(xt + xs + st.B1), X10: st.SigCount,
+	}
+	raw, _ := json.Marshal(common)
+	return commonEncoding.EncodeToString(raw)
+}
+
+// mrc 为站点脚本中的校验值：CRC32 与 0xEDB88320 异或后按 JS 有符号 32 位整数取值。
+func mrc(s string) int32 {
+	return int32(crc32.ChecksumIEEE([]byte(s)) ^ crc32.IEEE)
+}
+
+// platformCode 根据 User-Agent 推断站点脚本中的平台编号与名称。
+func platformCode(ua string) (int, string) {
+	switch {
+	case strings.Contains(ua, "Android"):
+		return 2, "Android"
+	case strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPad"):
+		return 1, "iOS"
+	case strings.Contains(ua, "Mac OS"):
+		return 3, "Mac OS"
+	case strings.Contains(ua, "Linux"):
+		return 4, "Linux"
+	}
+	return 5, "Windows"
+}
+
+// traceSeq 为 x-xray-traceid 的序号。
+var traceSeq atomic.Uint32
+
+// traceHeaders 返回站点请求携带的链路追踪请求头 x-b3-traceid 与 x-xray-traceid。
+func traceHeaders() map[string]string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	xray := uint64(time.Now().UnixMilli())<<23 | uint64(traceSeq.Add(1)&(1<<23-1))
+	return map[string]string{
+		"x-b3-traceid":   hex.EncodeToString(b[:8]),
+		"x-xray-traceid": fmt.Sprintf("%016x%s", xray, hex.EncodeToString(b[8:])),
+	}
+}
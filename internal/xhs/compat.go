@@ -0,0 +1,100 @@
+package xhs
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/platform"
+)
+
+// RegisterCompatRoutes 注册与常见 Python 小红书库所用签名服务兼容的路由，
+// 使现有工具只需修改服务地址即可接入：
+//   - POST /signature：请求为 {uri|url, data, a1, web_session} 或以 cookie 字符串代替 a1、web_session，
+//     返回与 /sign 相同；
+//   - POST /signsrv/v1/xhs/sign：MediaCrawler 签名服务格式，请求为 {uri, data, cookies}，
+//     返回 {biz_code, msg, isok, data: {x_s, x_t, x_s_common, x_b3_traceid}}。
+func RegisterCompatRoutes(router gin.IRouter, profile Profile, signer platform.Signer, middlewares ...gin.HandlerFunc) {
+	g := router.Group("/", middlewares...)
+	g.POST("/signature", func(c *gin.Context) {
+		var req compatParams
+		if err := c.ShouldBindJSON(&req); err != nil {
+			slog.Warn("/signature 参数解析失败", "err", err, "client_ip", c.ClientIP())
+			c.JSON(http.StatusBadRequest, gin.H{"error": "参数解析失败: " + err.Error()})
+			return
+		}
+		res, status, err := sign(c, profile, signer, req.signParams())
+		if err != nil {
+			c.JSON(status, platform.ErrorBody(err))
+			return
+		}
+		c.JSON(http.StatusOK, res)
+	})
+	g.POST("/signsrv/v1/xhs/sign", func(c *gin.Context) {
+		var req compatParams
+		if err := c.ShouldBindJSON(&req); err != nil {
+			slog.Warn("/signsrv/v1/xhs/sign 参数解析失败", "err", err, "client_ip", c.ClientIP())
+			c.JSON(http.StatusBadRequest, signSrvResponse{BizCode: 1, Msg: "参数解析失败: " + err.Error()})
+			return
+		}
+		res, status, err := sign(c, profile, signer, req.signParams())
+		if err != nil {
+			c.JSON(status, signSrvResponse{BizCode: 1, Msg: err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, signSrvResponse{BizCode: 0, Msg: "OK!", IsOK: true, Data: &signSrvData{
+			XS:         res.XS,
+			XT:         res.XT,
+			XSCommon:   res.XSCommon,
+			XB3TraceID: traceHeaders()["x-b3-traceid"],
+		}})
+	})
+}
+
+// compatParams 为兼容路由的签名参数，兼容各库的字段名。
+type compatParams struct {
+	URI        string `json:"uri"`
+	URL        string `json:"url"`
+	Data       any    `json:"data"`
+	A1         string `json:"a1"`
+	WebSession string `json:"web_session"`
+	// Cookie、Cookies 为完整的 cookie 字符串，从中读取 a1 与 web_session。
+	Cookie  string `json:"cookie"`
+	Cookies string `json:"cookies"`
+}
+
+// signParams 将兼容参数转换为 SignParams，显式传入的 a1、web_session 优先于 cookie 字符串。
+func (p compatParams) signParams() SignParams {
+	out := SignParams{URI: p.URI, Data: p.Data, A1: p.A1, WebSession: p.WebSession}
+	if out.URI == "" {
+		out.URI = p.URL
+	}
+	for _, raw := range []string{p.Cookie, p.Cookies} {
+		for _, pair := range strings.Split(raw, ";") {
+			name, value, _ := strings.Cut(strings.TrimSpace(pair), "=")
+			switch {
+			case name == "a1" && out.A1 == "":
+				out.A1 = value
+			case name == "web_session" && out.WebSession == "":
+				out.WebSession = value
+			}
+		}
+	}
+	return out
+}
+
+// signSrvResponse 为 MediaCrawler 签名服务格式的返回。
+type signSrvResponse struct {
+	BizCode int          `json:"biz_code"`
+	Msg     string       `json:"msg"`
+	IsOK    bool         `json:"isok"`
+	Data    *signSrvData `json:"data"`
+}
+
+type signSrvData struct {
+	XS         string `json:"x_s"`
+	XT         string `json:"x_t"`
+	XSCommon   string `json:"x_s_common"`
+	XB3TraceID string `json:"x_b3_traceid"`
+}
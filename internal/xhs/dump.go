@@ -0,0 +1,115 @@
+package xhs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go_sign/internal/pagepool"
+	"go_sign/internal/platform"
+)
+
+// dumpScriptsJS 读取签名函数源码与页面上的脚本，参数为 [函数名, 是否返回全部脚本, 单个脚本的最大长度, 时限（毫秒）]。
+// 外链脚本（含动态加载、已从 DOM 移除的）取自资源加载记录，在页面内重新请求以获得内容，
+// 全部请求共用同一时限，超时的请求中止，之后的脚本不再请求，均记为 error；
+// 未要求全部脚本时只保留引用了签名函数的脚本。
+const dumpScriptsJS = `async ([fn, all, limit, budget]) => {
+	const deadline = Date.now() + budget;
+	const f = window[fn];
+	const out = {
+		url: location.href,
+		sign_func_source: typeof f === 'function' ? Function.prototype.toString.call(f) : '',
+		scripts: [],
+	};
+	const keep = (s) => {
+		if (s.body && s.body.length > limit) s.body = s.body.slice(0, limit);
+		if (all || s.error || (s.body || '').includes(fn)) out.scripts.push(s);
+	};
+	const srcs = new Set();
+	for (const el of document.scripts) {
+		if (el.src) srcs.add(el.src);
+		else keep({ body: el.textContent });
+	}
+	for (const e of performance.getEntriesByType('resource')) {
+		if (e.initiatorType === 'script') srcs.add(e.name);
+	}
+	for (const src of srcs) {
+		const left = deadline - Date.now();
+		if (left <= 0) {
+			keep({ src, error: '超过导出时限，未下载' });
+			continue;
+		}
+		const ac = new AbortController();
+		const timer = setTimeout(() => ac.abort(), left);
+		try {
+			const r = await fetch(src, { credentials: 'omit', cache: 'force-cache', signal: ac.signal });
+			if (!r.ok) throw new Error('HTTP ' + r.status);
+			keep({ src, body: await r.text() });
+		} catch (e) {
+			keep({ src, error: ac.signal.aborted ? '超过导出时限，已中止' : String(e) });
+		} finally {
+			clearTimeout(timer);
+		}
+	}
+	return out;
+}`
+
+// maxScriptSize 为导出时单个脚本保留的最大长度，超出部分截断。
+const maxScriptSize = 5 << 20
+
+// defaultDumpBudget 为 ctx 没有截止时间时导出脚本的时限。
+const defaultDumpBudget = 30 * time.Second
+
+// DumpScripts 从 ctx 所属租户的页面池取一个页面，导出签名函数源码与已加载的脚本，实现 platform.ScriptDumper。
+func (p *xhsPlatform) DumpScripts(ctx context.Context, all bool) (*platform.ScriptDump, error) {
+	if p.signer == nil {
+		return nil, errors.New("平台未初始化")
+	}
+	s := p.signer
+	pool, err := s.pools.For(ctx)
+	if err != nil {
+		return nil, err
+	}
+	slot, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("等待空闲页面失败: %w", err)
+	}
+	defer pool.Release(slot)
+
+	dump, err := s.dumpSlot(ctx, slot, all)
+	if err != nil {
+		return nil, err
+	}
+	if dump.SignFuncSource == "" {
+		return nil, fmt.Errorf("window.%s %w", dump.SignFunc, ErrSignFuncMissing)
+	}
+	return dump, nil
+}
+
+// dumpSlot 在槽位页面上读取签名函数源码与脚本，all 为 false 时只保留引用了签名函数的脚本。
+// 页面内下载外链脚本以 ctx 的剩余时间为时限（没有截止时间时为 defaultDumpBudget），避免长时间占用槽位。
+func (s *Signer) dumpSlot(ctx context.Context, slot *pagepool.Slot, all bool) (*platform.ScriptDump, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	budget := defaultDumpBudget
+	if deadline, ok := ctx.Deadline(); ok {
+		budget = time.Until(deadline)
+	}
+	fn := s.opts.Profile.SignFunc
+	v, err := slot.Page.Evaluate(dumpScriptsJS, []any{fn, all, maxScriptSize, budget.Milliseconds()})
+	if err != nil {
+		return nil, fmt.Errorf("读取页面脚本失败: %w", err)
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dump := &platform.ScriptDump{ContextID: slot.ContextID(), SignFunc: fn, ScriptVersion: slot.ScriptVersion()}
+	if err := json.Unmarshal(raw, dump); err != nil {
+		return nil, fmt.Errorf("解析页面脚本失败: %w", err)
+	}
+	return dump, nil
+}
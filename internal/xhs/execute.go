@@ -0,0 +1,309 @@
+package xhs
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/auth"
+	"go_sign/internal/platform"
+	"go_sign/internal/wire"
+)
+
+// 重放模板请求的限制。
+const (
+	executeTimeout = 30 * time.Second
+	// maxExecuteBody 为站点接口应答体的上限，超出部分截断
+	maxExecuteBody = 10 << 20
+)
+
+// executeClient 为重放模板请求使用的 HTTP 客户端，自行协商 gzip 压缩。
+var executeClient = &http.Client{Timeout: executeTimeout}
+
+// 重放请求模板的方式。
+const (
+	// ExecuteServer 由本服务以 Go 的 HTTP 客户端发出请求，携带调用方的 a1、web_session。
+	ExecuteServer = "server"
+	// ExecutePage 在签名页面内以 fetch 发出请求（见 Signer.Fetch），携带页面自身的 cookie 与浏览器请求头。
+	ExecutePage = "page"
+)
+
+// ExecuteParams 为重放请求模板的参数。
+type ExecuteParams struct {
+	// Mode 为 ExecuteServer 或 ExecutePage，为空时为 ExecuteServer。
+	Mode       string `json:"mode"`
+	A1         string `json:"a1"`
+	WebSession string `json:"web_session"`
+	// Query 覆盖或追加模板查询串中的参数，其余参数沿用模板中的取值与顺序。
+	Query map[string]string `json:"query"`
+	// Data 覆盖或追加模板 JSON 请求体顶层的字段，其余字段沿用模板中的取值与顺序；GET 模板不能传入。
+	Data map[string]any `json:"data"`
+	// Timestamp 为固定的 x-t（毫秒），0 表示使用签名端当前时间。
+	Timestamp int64 `json:"timestamp,omitempty"`
+}
+
+// ExecuteResult 为重放请求模板或在签名页面内发出请求（/fetch）的结果。
+type ExecuteResult struct {
+	Template string `json:"template,omitempty"`
+	Method   string `json:"method"`
+	URL      string `json:"url"`
+	// Status 为站点接口的 HTTP 状态码，Body 为 JSON 应答体，应答不是 JSON 时为 Text。
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body,omitempty"`
+	Text   string          `json:"text,omitempty"`
+	// ContextID 为签名（页面内请求时为发出请求）的浏览器上下文。
+	ContextID string `json:"context_id,omitempty"`
+	// A1、UserAgent 为签名页面的 a1 与 User-Agent，请求携带的即为这两个值。
+	A1        string `json:"a1,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+}
+
+// registerExecuteRoute 注册 POST /template/:name/execute：以调用方的参数填充学习到的请求模板，签名后向站点接口发出请求，
+// 返回站点的应答。fetcher 为 nil 时不支持 mode=page。
+func registerExecuteRoute(g gin.IRouter, profile Profile, signer platform.Signer, templates *HeaderTemplates, fetcher pageFetcher) {
+	g.POST("/template/:name/execute", func(c *gin.Context) {
+		name := c.Param("name")
+		var req ExecuteParams
+		if err := wire.Bind(c, &req); err != nil {
+			slog.Warn("/template 参数解析失败", "err", err, "template", name, "client_ip", c.ClientIP())
+			wire.Render(c, http.StatusBadRequest, gin.H{"error": "参数解析失败: " + err.Error()})
+			return
+		}
+		tmpl := templates.Named(name)
+		if tmpl == nil {
+			wire.Render(c, http.StatusNotFound, gin.H{"error": "未学习到请求模板: " + name})
+			return
+		}
+		params, err := req.signParams(tmpl, fetcher != nil)
+		if err != nil {
+			wire.Render(c, http.StatusBadRequest, platform.ErrorBody(err))
+			return
+		}
+		var out *ExecuteResult
+		if req.Mode == ExecutePage {
+			out, err = fetcher.Fetch(c.Request.Context(), FetchParams{URI: params.URI, Method: tmpl.Method, Data: params.Data, Timestamp: params.Timestamp})
+			if err != nil {
+				slog.Error("/template 页面内请求失败", "err", err, "template", name, "uri", params.URI, "api_key", auth.FromContext(c).Name)
+				wire.Render(c, platform.SignErrorStatus(c, err), platform.ErrorBody(err))
+				return
+			}
+		} else {
+			res, status, err := sign(c, profile, signer, params)
+			if err != nil {
+				wire.Render(c, status, platform.ErrorBody(err))
+				return
+			}
+			out, err = execute(c, profile, tmpl.Method, params, headersResponse(params, res, templates))
+			if err != nil {
+				slog.Error("/template 请求站点接口失败", "err", err, "template", name, "uri", params.URI, "api_key", auth.FromContext(c).Name)
+				wire.Render(c, http.StatusBadGateway, gin.H{"error": err.Error()})
+				return
+			}
+			out.ContextID, out.A1, out.UserAgent = res.ContextID, res.A1, res.UserAgent
+		}
+		out.Template = name
+		slog.Info("/template 重放完成", "template", name, "mode", req.Mode, "uri", params.URI, "status", out.Status, "api_key", auth.FromContext(c).Name)
+		wire.Render(c, http.StatusOK, out)
+	})
+}
+
+// signParams 以调用方的参数填充模板的查询串与请求体，返回签名参数；请求体保持模板的字段顺序，
+// 签名与发出的请求体逐字节一致。page 为是否支持 mode=page。
+func (p ExecuteParams) signParams(tmpl *platform.HeaderTemplate, page bool) (SignParams, error) {
+	verr := &platform.ValidationError{}
+	switch p.Mode {
+	case "", ExecuteServer:
+	case ExecutePage:
+		if !page {
+			verr.Add("mode", "平台不支持在签名页面内发出请求")
+		}
+		if p.A1 != "" || p.WebSession != "" {
+			verr.Add("mode", "page 使用签名页面自身的 cookie，不能传入 a1、web_session")
+		}
+	default:
+		verr.Add("mode", "须为 %s 或 %s，实际为 %q", ExecuteServer, ExecutePage, p.Mode)
+	}
+	params := SignParams{URI: tmpl.Path, A1: p.A1, WebSession: p.WebSession, Timestamp: p.Timestamp}
+	if query := mergeQuery(tmpl.Query, p.Query); query != "" {
+		params.URI += "?" + query
+	}
+	switch {
+	case tmpl.Method == http.MethodGet && len(p.Data) > 0:
+		verr.Add("data", "模板 %s 为 GET 请求，不能传入请求体", tmpl.Name)
+	case tmpl.Method != http.MethodGet:
+		body, err := mergeJSON(tmpl.Body, p.Data)
+		if err != nil {
+			verr.Add("data", "%v", err)
+			break
+		}
+		params.Data = body
+	}
+	return params, verr.Err()
+}
+
+// execute 以 h 中的请求头与 cookie 向站点接口发出签名后的请求。
+// Go 的 HTTP 客户端按名称顺序发送请求头，h.Order 无法保留；accept-encoding 由客户端协商，不使用模板中的取值。
+func execute(c *gin.Context, profile Profile, method string, params SignParams, h *platform.HeadersResponse) (*ExecuteResult, error) {
+	target := strings.TrimRight(profile.APIBase, "/") + params.URI
+	var body io.Reader
+	if raw, ok := params.Data.(json.RawMessage); ok {
+		body = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequestWithContext(c.Request.Context(), method, target, body)
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	for name, v := range h.Headers {
+		if name != "accept-encoding" {
+			req.Header.Set(name, v)
+		}
+	}
+	if h.Cookie != "" {
+		req.Header.Set("Cookie", h.Cookie)
+	}
+	if body != nil && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json;charset=UTF-8")
+	}
+	resp, err := executeClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求站点接口失败: %w", err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxExecuteBody))
+	if err != nil {
+		return nil, fmt.Errorf("读取站点应答失败: %w", err)
+	}
+	out := &ExecuteResult{Method: method, URL: target, Status: resp.StatusCode}
+	if json.Valid(raw) {
+		out.Body = raw
+	} else {
+		out.Text = string(raw)
+	}
+	return out, nil
+}
+
+// mergeQuery 以 overrides 覆盖原始查询串 raw 中的同名参数并保持原有顺序，raw 中没有的参数按名称排序追加在后。
+func mergeQuery(raw string, overrides map[string]string) string {
+	var parts []string
+	seen := make(map[string]bool, len(overrides))
+	for _, part := range strings.Split(raw, "&") {
+		if part == "" {
+			continue
+		}
+		key, _, _ := strings.Cut(part, "=")
+		if name, err := url.QueryUnescape(key); err == nil {
+			if v, ok := overrides[name]; ok {
+				if seen[name] {
+					continue
+				}
+				seen[name] = true
+				part = key + "=" + url.QueryEscape(v)
+			}
+		}
+		parts = append(parts, part)
+	}
+	var rest []string
+	for name := range overrides {
+		if !seen[name] {
+			rest = append(rest, name)
+		}
+	}
+	slices.Sort(rest)
+	for _, name := range rest {
+		parts = append(parts, url.QueryEscape(name)+"="+url.QueryEscape(overrides[name]))
+	}
+	return strings.Join(parts, "&")
+}
+
+// mergeJSON 以 overrides 覆盖 JSON 对象 raw 的顶层字段并保持原有字段顺序，raw 中没有的字段按名称排序追加在后；
+// raw 为空时视为空对象。
+func mergeJSON(raw json.RawMessage, overrides map[string]any) (json.RawMessage, error) {
+	type field struct {
+		key   string
+		value json.RawMessage
+	}
+	var fields []field
+	if len(raw) > 0 {
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+			return nil, errors.New("模板的请求体不是 JSON 对象")
+		}
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, fmt.Errorf("解析模板的请求体失败: %w", err)
+			}
+			key, _ := tok.(string)
+			var value json.RawMessage
+			if err := dec.Decode(&value); err != nil {
+				return nil, fmt.Errorf("解析模板的请求体失败: %w", err)
+			}
+			fields = append(fields, field{key, value})
+		}
+	}
+	seen := make(map[string]bool, len(overrides))
+	set := func(i int, key string) error {
+		value, err := marshalJS(overrides[key])
+		if err != nil {
+			return fmt.Errorf("%s 序列化失败: %w", key, err)
+		}
+		seen[key] = true
+		fields[i].value = value
+		return nil
+	}
+	for i, f := range fields {
+		if _, ok := overrides[f.key]; ok {
+			if err := set(i, f.key); err != nil {
+				return nil, err
+			}
+		}
+	}
+	var rest []string
+	for key := range overrides {
+		if !seen[key] {
+			rest = append(rest, key)
+		}
+	}
+	slices.Sort(rest)
+	for _, key := range rest {
+		fields = append(fields, field{key: key})
+		if err := set(len(fields)-1, key); err != nil {
+			return nil, err
+		}
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := marshalJS(f.key)
+		buf.Write(key)
+		buf.WriteByte(':')
+		if err := json.Compact(&buf, f.value); err != nil {
+			return nil, fmt.Errorf("%s 序列化失败: %w", f.key, err)
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// marshalJS 按页面 JSON.stringify 的规则序列化 v：不转义 HTML 字符。
+func marshalJS(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
@@ -0,0 +1,131 @@
+package xhs
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/platform"
+	"go_sign/internal/platform/platformtest"
+)
+
+func TestExecuteTemplate(t *testing.T) {
+	const a1 = "18d1e2f3a4b5c6d7e8f9a0b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e"
+	type received struct {
+		uri, body string
+		header    http.Header
+	}
+	got := make(chan received, 1)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		got <- received{uri: r.URL.RequestURI(), body: string(body), header: r.Header}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = io.WriteString(w, `{"code":0,"success":true}`)
+	}))
+	t.Cleanup(upstream.Close)
+
+	profile := ProfileWeb
+	profile.APIBase = upstream.URL
+	templates, err := NewHeaderTemplates(upstream.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(upstream.URL + "/api/sns/web/v1/feed?source=web&b=2")
+	templates.record(http.MethodPost, u, map[string]string{
+		"user-agent":   mockUserAgent,
+		"sec-ch-ua":    `"Chromium";v="120"`,
+		"content-type": "application/json;charset=UTF-8",
+		"x-s":          "XYW_old",
+		"x-t":          "1700000000000",
+	}, []byte(`{"source_note_id":"old","image_formats":["jpg"]}`), time.Now())
+
+	fake := &platformtest.Fake{SignFunc: func(_ context.Context, req *platform.SignRequest) (*platform.SignResponse, error) {
+		return &platform.SignResponse{
+			Headers: map[string]string{"x-s": "XYW_new", "x-t": "1800000000000"},
+			Source:  &platform.SignSource{Identity: a1, UserAgent: mockUserAgent, URI: req.URI},
+		}, nil
+	}}
+	h := platformtest.New(t, func(r *gin.Engine) {
+		registerSignRoutes(r, profile, fake, signRoutes{templates: templates})
+	})
+
+	if resp := h.Do(http.MethodPost, "/template/get_unknown/execute", map[string]any{}, nil); resp.Status != http.StatusNotFound {
+		t.Fatalf("未学习到的模板 = %d，期望 404", resp.Status)
+	}
+	resp := h.Do(http.MethodPost, "/template/post_sns_web_v1_feed/execute", map[string]any{
+		"query": map[string]string{"b": "3", "c": "<4>"},
+		"data":  map[string]any{"source_note_id": "a&b", "xsec_token": "t"},
+	}, nil)
+	if resp.Status != http.StatusOK {
+		t.Fatalf("重放模板 = %d %s", resp.Status, resp.Body)
+	}
+	out := resp.JSON(t)
+	if out["status"] != float64(http.StatusOK) || out["body"].(map[string]any)["success"] != true {
+		t.Errorf("重放结果 = %s", resp.Body)
+	}
+
+	r := <-got
+	if want := "/api/sns/web/v1/feed?source=web&b=3&c=%3C4%3E"; r.uri != want {
+		t.Errorf("请求地址 = %s，期望 %s", r.uri, want)
+	}
+	if want := `{"source_note_id":"a&b","image_formats":["jpg"],"xsec_token":"t"}`; r.body != want {
+		t.Errorf("请求体 = %s，期望 %s", r.body, want)
+	}
+	if r.header.Get("x-s") != "XYW_new" || r.header.Get("sec-ch-ua") == "" || r.header.Get("Cookie") != "a1="+a1 {
+		t.Errorf("请求头 = %v", r.header)
+	}
+	if data := string(fake.Requests()[0].Data.(json.RawMessage)); data != r.body {
+		t.Errorf("参与签名的请求体 = %s，期望与发出的一致", data)
+	}
+}
+
+// fetcherFunc 将函数适配为 pageFetcher。
+type fetcherFunc func(ctx context.Context, p FetchParams) (*ExecuteResult, error)
+
+func (f fetcherFunc) Fetch(ctx context.Context, p FetchParams) (*ExecuteResult, error) {
+	return f(ctx, p)
+}
+
+func TestExecuteTemplateInPage(t *testing.T) {
+	templates, err := NewHeaderTemplates(ProfileWeb.APIBase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse("https://edith.xiaohongshu.com/api/sns/web/v1/feed")
+	templates.record(http.MethodPost, u, map[string]string{"user-agent": mockUserAgent}, []byte(`{"source_note_id":"old","image_formats":["jpg"]}`), time.Now())
+
+	got := make(chan FetchParams, 1)
+	fetcher := fetcherFunc(func(_ context.Context, p FetchParams) (*ExecuteResult, error) {
+		got <- p
+		return &ExecuteResult{Method: p.method(), Status: http.StatusOK, Body: json.RawMessage(`{"success":true}`)}, nil
+	})
+	fake := &platformtest.Fake{}
+	h := platformtest.New(t, func(r *gin.Engine) {
+		registerSignRoutes(r, ProfileWeb, fake, signRoutes{templates: templates, fetcher: fetcher})
+	})
+
+	if resp := h.Do(http.MethodPost, "/template/post_sns_web_v1_feed/execute", map[string]any{"mode": ExecutePage, "web_session": "x"}, nil); resp.Status != http.StatusBadRequest {
+		t.Fatalf("mode=page 传入 web_session = %d，期望 400", resp.Status)
+	}
+	resp := h.Do(http.MethodPost, "/template/post_sns_web_v1_feed/execute", map[string]any{"mode": ExecutePage, "data": map[string]any{"source_note_id": "new"}}, nil)
+	if resp.Status != http.StatusOK || resp.JSON(t)["template"] != "post_sns_web_v1_feed" {
+		t.Fatalf("mode=page 重放 = %d %s", resp.Status, resp.Body)
+	}
+	p := <-got
+	if p.URI != "/api/sns/web/v1/feed" || p.Method != http.MethodPost || string(p.Data.(json.RawMessage)) != `{"source_note_id":"new","image_formats":["jpg"]}` {
+		t.Errorf("页面内请求参数 = %+v", p)
+	}
+	if n := len(fake.Requests()); n != 0 {
+		t.Errorf("mode=page 不应经 /sign 的签名器签名，实际 %d 次", n)
+	}
+
+	if resp := h.Do(http.MethodPost, "/fetch", map[string]any{"uri": "/api/sns/web/v1/feed", "method": "GET", "data": map[string]any{}}, nil); resp.Status != http.StatusBadRequest {
+		t.Errorf("/fetch GET 带请求体 = %d，期望 400", resp.Status)
+	}
+}
@@ -0,0 +1,299 @@
+package xhs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"go_sign/internal/pagepool"
+	"go_sign/internal/platform"
+	"go_sign/internal/script"
+)
+
+// FallbackOptions 为浏览器不可用时的降级签名配置：页面加载新版本签名脚本时，将引用签名函数的脚本提取并缓存到磁盘，
+// 浏览器崩溃或页面正在重建时改由内嵌的 JS 引擎执行缓存的脚本签名，不依赖外部进程。
+type FallbackOptions struct {
+	// CachePath 为签名脚本缓存文件的路径，启动时存在则直接加载。
+	CachePath string
+	// Size 为执行缓存脚本的 JS 运行时数，即降级签名的并发数，默认为 1。
+	Size int
+}
+
+// fallbackContextID 为降级签名结果中的 ContextID。
+const fallbackContextID = "fallback"
+
+// cacheHeader 为缓存文件首行的前缀，其后为 cacheMeta 的 JSON。
+const cacheHeader = "// go_sign: "
+
+// cacheMeta 为缓存脚本的来源页面信息。降级签名沿用来源页面的 User-Agent，a1 优先取调用方携带的值，
+// 否则取来源页面的 a1；后者只保存在内存中，不写入缓存文件，重启后调用方须自行携带 a1。
+type cacheMeta struct {
+	ScriptVersion string    `json:"script_version"`
+	URL           string    `json:"url"`
+	A1            string    `json:"-"`
+	UserAgent     string    `json:"user_agent,omitempty"`
+	SavedAt       time.Time `json:"saved_at"`
+}
+
+// cacheShimJS 为缓存脚本的前置部分，在隔离上下文中补齐签名脚本读取的浏览器对象，参数为 cacheMeta。
+// 运行时中没有 URL 等宿主对象，location 由正则拆分来源页面的地址。
+const cacheShimJS = `(function (meta) {
+	const define = (name, value) => Object.defineProperty(globalThis, name, { value, configurable: true, writable: true });
+	const storage = () => {
+		const m = new Map();
+		return {
+			getItem: (k) => (m.has(k) ? m.get(k) : null),
+			setItem: (k, v) => m.set(k, String(v)),
+			removeItem: (k) => m.delete(k),
+			clear: () => m.clear(),
+			key: (i) => [...m.keys()][i] ?? null,
+			get length() { return m.size; },
+		};
+	};
+	define('navigator', { userAgent: meta.user_agent || '', language: 'zh-CN', languages: ['zh-CN', 'zh'], platform: 'Win32', webdriver: false });
+	const u = /^([a-z][a-z0-9+.-]*:)\/\/([^/?#]*)([^?#]*)(\?[^#]*)?(#.*)?$/i.exec(meta.url) || [];
+	const host = u[2] || '';
+	define('location', {
+		href: meta.url, protocol: u[1] || '', host, hostname: host.replace(/:\d+$/, ''), port: (/:(\d+)$/.exec(host) || [])[1] || '',
+		pathname: u[3] || '/', search: u[4] || '', hash: u[5] || '', origin: (u[1] || '') + '//' + host, toString() { return this.href; },
+	});
+	define('document', { cookie: '', referrer: '', location: globalThis.location, createElement: () => ({ style: {} }), addEventListener() {}, getElementsByTagName: () => [] });
+	define('localStorage', storage());
+	define('sessionStorage', storage());
+	define('addEventListener', () => {});
+})`
+
+// fallback 管理签名脚本缓存与执行缓存脚本的 JS 运行时。
+type fallback struct {
+	opts FallbackOptions
+	name string
+	fn   string
+
+	mu      sync.Mutex
+	meta    cacheMeta
+	vms     *script.EmbeddedPool
+	pending string // 正在缓存的版本，避免多个页面重复提取
+}
+
+// newFallback 创建降级签名，缓存文件存在时加载，加载失败只记录告警，待页面加载签名脚本后重新缓存。
+func newFallback(opts FallbackOptions, name, fn string) *fallback {
+	if opts.Size <= 0 {
+		opts.Size = 1
+	}
+	f := &fallback{opts: opts, name: name, fn: fn}
+	meta, err := readCacheMeta(opts.CachePath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		slog.Info("签名脚本缓存不存在，待页面加载后生成", "platform", name, "path", opts.CachePath)
+	case err != nil:
+		slog.Warn("读取签名脚本缓存失败", "err", err, "platform", name, "path", opts.CachePath)
+	default:
+		vms, err := script.StartEmbeddedPool(name, opts.CachePath, fn, opts.Size)
+		if err != nil {
+			slog.Warn("加载签名脚本缓存失败，降级签名暂不可用", "err", err, "platform", name, "path", opts.CachePath)
+			break
+		}
+		f.meta, f.vms = meta, vms
+		slog.Info("已加载签名脚本缓存", "platform", name, "path", opts.CachePath, "script_version", meta.ScriptVersion, "saved_at", meta.SavedAt)
+	}
+	return f
+}
+
+// readCacheMeta 读取缓存文件首行的来源信息。
+func readCacheMeta(path string) (cacheMeta, error) {
+	var meta cacheMeta
+	file, err := os.Open(path)
+	if err != nil {
+		return meta, err
+	}
+	defer file.Close()
+	line, err := bufio.NewReader(file).ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, cacheHeader) {
+		return meta, errors.New("不是 go_sign 生成的缓存文件")
+	}
+	err = json.Unmarshal([]byte(strings.TrimPrefix(line, cacheHeader)), &meta)
+	return meta, err
+}
+
+// stale 判断 slot 加载的签名脚本是否尚未缓存，是则标记为正在缓存并返回 true。
+func (f *fallback) stale(slot *pagepool.Slot) bool {
+	v := slot.ScriptVersion()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if v == "" || v == f.meta.ScriptVersion || v == f.pending {
+		return false
+	}
+	f.pending = v
+	return true
+}
+
+// cacheDumpTimeout 为缓存签名脚本时提取页面脚本的最长时间，期间占用槽位。
+const cacheDumpTimeout = 20 * time.Second
+
+// cacheScript 提取 slot 页面上引用签名函数的脚本并在后台更新缓存，slot 的签名脚本版本已缓存时直接返回。
+// 调用方须持有 slot（或 slot 尚未加入页面池），提取在返回前完成，避免与签名争用页面。
+func (s *Signer) cacheScript(ctx context.Context, slot *pagepool.Slot) {
+	f := s.fallback
+	if f == nil || !f.stale(slot) {
+		return
+	}
+	done := func(err error) {
+		f.mu.Lock()
+		f.pending = ""
+		f.mu.Unlock()
+		if err != nil {
+			slog.Warn("缓存签名脚本失败", "err", err, "platform", f.name, "context_id", slot.ContextID(), "script_version", slot.ScriptVersion())
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, cacheDumpTimeout)
+	defer cancel()
+	dump, err := s.dumpSlot(ctx, slot, false)
+	if err != nil {
+		done(err)
+		return
+	}
+	meta := cacheMeta{ScriptVersion: slot.ScriptVersion(), URL: dump.URL, A1: slot.Identity, UserAgent: slot.UserAgent, SavedAt: time.Now()}
+	go func() { done(f.refresh(meta, dump)) }()
+}
+
+// refresh 将提取的脚本写入暂存文件，确认 JS 运行时可加载且签名函数存在后替换缓存文件并切换运行时。
+// 缓存文件只有属主可读写。
+func (f *fallback) refresh(meta cacheMeta, dump *platform.ScriptDump) error {
+	var body bytes.Buffer
+	header, _ := json.Marshal(meta)
+	fmt.Fprintf(&body, "%s%s\n%s(%s);\n", cacheHeader, header, cacheShimJS, header)
+	n := 0
+	for _, sc := range dump.Scripts {
+		if sc.Body == "" {
+			continue
+		}
+		fmt.Fprintf(&body, "// %s\n;%s\n", sc.Src, sc.Body)
+		n++
+	}
+	if n == 0 {
+		return errors.New("页面上没有引用签名函数的脚本")
+	}
+
+	staging := f.opts.CachePath + ".staging"
+	if err := os.MkdirAll(filepath.Dir(staging), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(staging, body.Bytes(), 0o600); err != nil {
+		return err
+	}
+	defer os.Remove(staging)
+	if _, err := script.StartEmbeddedPool(f.name, staging, f.fn, 1); err != nil {
+		return fmt.Errorf("无法执行提取的脚本: %w", err)
+	}
+	if err := os.Rename(staging, f.opts.CachePath); err != nil {
+		return err
+	}
+	// 旧版本写入的缓存文件可能为 0644
+	if err := os.Chmod(f.opts.CachePath, 0o600); err != nil {
+		return err
+	}
+	vms, err := script.StartEmbeddedPool(f.name, f.opts.CachePath, f.fn, f.opts.Size)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	old := f.vms
+	f.meta, f.vms = meta, vms
+	f.mu.Unlock()
+	if old != nil {
+		_ = old.Close()
+	}
+	slog.Info("已更新签名脚本缓存", "platform", f.name, "path", f.opts.CachePath, "script_version", meta.ScriptVersion, "scripts", n, "size", body.Len())
+	return nil
+}
+
+// available 判断是否已有可执行的缓存脚本。
+func (f *fallback) available() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.vms != nil
+}
+
+// sign 由内嵌的 JS 引擎执行缓存的签名脚本，a1 优先取调用方携带的值，User-Agent 为缓存来源页面的值。
+func (f *fallback) sign(ctx context.Context, params SignParams) (*SignResult, error) {
+	f.mu.Lock()
+	vms, meta := f.vms, f.meta
+	f.mu.Unlock()
+	if vms == nil {
+		return nil, errors.New("没有可用的签名脚本缓存")
+	}
+	a1 := params.A1
+	if a1 == "" {
+		a1 = meta.A1
+	}
+	if a1 == "" {
+		return nil, errors.New("缓存的签名脚本需要调用方携带 a1")
+	}
+	v, err := vms.SignWithCookie(ctx, params.URI, params.Data, "a1="+a1, params.Timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("执行缓存的签名脚本失败: %w", err)
+	}
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, errors.New("签名结果类型断言失败")
+	}
+	res := signResultOf(m)
+	res.A1, res.ContextID, res.UserAgent, res.URI, res.ScriptVersion = a1, fallbackContextID, meta.UserAgent, params.URI, meta.ScriptVersion
+	if err := res.validate(params.Timestamp); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// close 关闭 JS 运行时。
+func (f *fallback) close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.vms == nil {
+		return nil
+	}
+	return f.vms.Close()
+}
+
+// browserConnected 判断 ctx 所属租户页面所在的浏览器（蓝绿升级后为新浏览器）是否仍然连接。
+func (s *Signer) browserConnected(ctx context.Context) bool {
+	b := s.browser
+	if pool, err := s.pools.For(ctx); err == nil {
+		if pb := pool.Browser(); pb != nil {
+			b = pb
+		}
+	}
+	return b.Connected()
+}
+
+// browserUnavailable 判断签名失败是否因浏览器不可用：浏览器已断开，或页面正在重建。
+func (s *Signer) browserUnavailable(ctx context.Context, err error) bool {
+	var uerr *platform.UnavailableError
+	return errors.As(err, &uerr) || !s.browserConnected(ctx)
+}
+
+// signFallback 在浏览器不可用时以缓存的签名脚本降级签名，降级失败时返回原错误。
+func (s *Signer) signFallback(ctx context.Context, params SignParams, err error) (*SignResult, error) {
+	if s.fallback == nil || !s.browserUnavailable(ctx, err) || ctx.Err() != nil {
+		return nil, err
+	}
+	name := s.opts.Profile.PlatformName()
+	res, ferr := s.fallback.sign(ctx, params)
+	if ferr != nil {
+		fallbackSigns.Inc(name, "error")
+		slog.Error("降级签名失败", "err", ferr, "uri", params.URI, "browser_err", err)
+		return nil, err
+	}
+	fallbackSigns.Inc(name, "success")
+	slog.Warn("浏览器不可用，已使用缓存的签名脚本降级签名", "uri", params.URI, "browser_err", err, "script_version", res.ScriptVersion)
+	return res, nil
+}
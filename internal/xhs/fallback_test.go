@@ -0,0 +1,74 @@
+package xhs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go_sign/internal/platform"
+)
+
+// fakeSignJS 模拟站点的签名脚本：读取补齐的 location、navigator 与 document.cookie 生成 x-s。
+const fakeSignJS = `window._webmsxyw = function (uri, data) {
+	const a1 = /a1=([^;]*)/.exec(document.cookie)[1];
+	const payload = [location.hostname, location.pathname, navigator.userAgent, uri, a1, localStorage.length].join('|');
+	return { 'X-s': 'XYW_' + payload.padEnd(64, '='), 'X-t': Date.now() };
+};`
+
+func TestFallbackRefresh(t *testing.T) {
+	cache := filepath.Join(t.TempDir(), "cache", "xhs-sign.js")
+	f := newFallback(FallbackOptions{CachePath: cache}, "xhs", "_webmsxyw")
+	if f.available() {
+		t.Fatal("缓存不存在时不应可用")
+	}
+
+	// 提取的脚本中没有签名函数时不替换缓存
+	err := f.refresh(cacheMeta{ScriptVersion: "v0"}, &platform.ScriptDump{Scripts: []platform.Script{{Body: "var x = 1;"}}})
+	if err == nil || f.available() {
+		t.Fatalf("缺少签名函数的脚本应加载失败，实际 %v", err)
+	}
+
+	meta := cacheMeta{ScriptVersion: "v1", URL: "https://www.xiaohongshu.com/explore?x=1", A1: "page-a1", UserAgent: "Mozilla/5.0 test", SavedAt: time.Now()}
+	if err := f.refresh(meta, &platform.ScriptDump{Scripts: []platform.Script{{Src: "https://fe-static.xhscdn.com/sign.js", Body: fakeSignJS}}}); err != nil {
+		t.Fatal(err)
+	}
+	body, err := os.ReadFile(cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(body), "page-a1") {
+		t.Error("a1 不应写入缓存文件")
+	}
+
+	ts := time.Now().UnixMilli()
+	res, err := f.sign(context.Background(), SignParams{URI: "/api/sns/web/v1/feed", Timestamp: ts})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "XYW_www.xiaohongshu.com|/explore|Mozilla/5.0 test|/api/sns/web/v1/feed|page-a1|0"
+	if !strings.HasPrefix(res.XS, want) {
+		t.Errorf("x-s = %s，期望以 %s 开头", res.XS, want)
+	}
+	if res.XT != strconv.FormatInt(ts, 10) {
+		t.Errorf("x-t = %s，期望固定为 %d", res.XT, ts)
+	}
+	if res.ContextID != fallbackContextID || res.A1 != "page-a1" || res.ScriptVersion != "v1" {
+		t.Errorf("降级签名结果 = %+v", res)
+	}
+
+	// 重启后从缓存文件加载，a1 须由调用方携带
+	f.close()
+	f = newFallback(FallbackOptions{CachePath: cache}, "xhs", "_webmsxyw")
+	defer f.close()
+	if _, err := f.sign(context.Background(), SignParams{URI: "/api/x"}); err == nil {
+		t.Error("重启后未携带 a1 应失败")
+	}
+	res, err = f.sign(context.Background(), SignParams{URI: "/api/x", A1: "caller-a1"})
+	if err != nil || !strings.Contains(res.XS, "|caller-a1|") {
+		t.Errorf("重启后签名 = %+v, %v", res, err)
+	}
+}
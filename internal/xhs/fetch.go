@@ -0,0 +1,166 @@
+package xhs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/auth"
+	"go_sign/internal/pagepool"
+	"go_sign/internal/platform"
+	"go_sign/internal/wire"
+)
+
+// fetchJS 在签名页面内以 fetch 发出请求，参数为地址、请求方法、请求头、data 的 JSON（无请求体时为 null）与超时毫秒数。
+// 请求体按页面的 JSON.stringify 重新序列化，与签名函数内部序列化的结果一致；cookie、User-Agent、sec-ch-*、
+// Origin 与 Referer 由浏览器按页面自身的请求附加。
+const fetchJS = `async ([url, method, headers, dataStr, timeout]) => {
+	const ctrl = new AbortController();
+	const timer = setTimeout(() => ctrl.abort(), timeout);
+	try {
+		const init = { method, headers, credentials: 'include', signal: ctrl.signal };
+		if (dataStr !== null) init.body = JSON.stringify(JSON.parse(dataStr));
+		const resp = await fetch(url, init);
+		return { status: resp.status, text: await resp.text() };
+	} finally {
+		clearTimeout(timer);
+	}
+}`
+
+// fetchAccept 为站点前端请求接口时的 Accept 请求头。
+const fetchAccept = "application/json, text/plain, */*"
+
+// FetchParams 为在签名页面内发出请求的参数。
+type FetchParams struct {
+	URI string `json:"uri"`
+	// Method 为 GET 或 POST，为空时有 data 则为 POST，否则为 GET。
+	Method string `json:"method"`
+	Data   any    `json:"data"`
+	// Timestamp 为固定的 x-t（毫秒），0 表示使用签名端当前时间。
+	Timestamp int64 `json:"timestamp,omitempty"`
+}
+
+// method 返回请求方法。
+func (p FetchParams) method() string {
+	switch {
+	case p.Method != "":
+		return strings.ToUpper(p.Method)
+	case p.Data != nil:
+		return http.MethodPost
+	}
+	return http.MethodGet
+}
+
+// Validate 校验请求方法与签名参数。
+func (p FetchParams) Validate() error {
+	verr := &platform.ValidationError{}
+	switch p.method() {
+	case http.MethodGet:
+		if p.Data != nil {
+			verr.Add("data", "GET 请求不能传入请求体")
+		}
+	case http.MethodPost:
+	default:
+		verr.Add("method", "须为 GET 或 POST，实际为 %q", p.Method)
+	}
+	var sverr *platform.ValidationError
+	if errors.As(SignParams{URI: p.URI, Data: p.Data, Timestamp: p.Timestamp}.Validate(), &sverr) {
+		verr.Fields = append(verr.Fields, sverr.Fields...)
+	}
+	return verr.Err()
+}
+
+// pageFetcher 在签名页面内发出请求，由 xhsPlatform 实现。
+type pageFetcher interface {
+	Fetch(ctx context.Context, p FetchParams) (*ExecuteResult, error)
+}
+
+// Fetch 从 ctx 所属租户的页面池取一个页面签名后，在该页面内以 fetch 向站点接口发出请求并返回站点的应答。
+// 请求携带页面自身的 cookie 与浏览器请求头，站点的状态码计入该页面 a1 的健康评分与风控冷却（见 ReportStatus）。
+func (s *Signer) Fetch(ctx context.Context, p FetchParams) (*ExecuteResult, error) {
+	params := SignParams{URI: normalizeURI(p.URI), Data: p.Data, Timestamp: p.Timestamp}
+	var out *ExecuteResult
+	_, err := s.signInBrowser(ctx, params, func(slot *pagepool.Slot, res *SignResult) error {
+		var err error
+		out, err = s.fetchOnPage(ctx, slot, p.method(), params, res)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.ReportStatus(out.A1, out.Status)
+	return out, nil
+}
+
+// fetchOnPage 在 slot 的页面内发出携带签名结果 res 的请求。
+func (s *Signer) fetchOnPage(ctx context.Context, slot *pagepool.Slot, method string, params SignParams, res *SignResult) (*ExecuteResult, error) {
+	headers := map[string]string{"accept": fetchAccept, "x-s": res.XS, "x-t": res.XT}
+	if res.XSCommon != "" {
+		headers["x-s-common"] = res.XSCommon
+	}
+	for k, v := range traceHeaders() {
+		headers[k] = v
+	}
+	var data any
+	if params.Data != nil {
+		raw, err := json.Marshal(params.Data)
+		if err != nil {
+			return nil, fmt.Errorf("data 参数序列化失败: %w", err)
+		}
+		data = string(raw)
+		headers["content-type"] = "application/json;charset=UTF-8"
+	}
+	timeout := executeTimeout
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < timeout {
+		timeout = time.Until(deadline)
+	}
+	target := strings.TrimRight(s.opts.Profile.APIBase, "/") + params.URI
+	v, err := slot.Page.Evaluate(fetchJS, []any{target, method, headers, data, timeout.Milliseconds()})
+	if err != nil {
+		slog.Warn("页面内请求失败", "err", err, "url", target, "context_id", res.ContextID)
+		return nil, fmt.Errorf("页面内请求失败: %w", err)
+	}
+	m, _ := v.(map[string]any)
+	status, _ := m["status"].(float64)
+	text, _ := m["text"].(string)
+	if len(text) > maxExecuteBody {
+		text = text[:maxExecuteBody]
+	}
+	out := &ExecuteResult{Method: method, URL: target, Status: int(status), ContextID: res.ContextID, A1: res.A1, UserAgent: res.UserAgent}
+	if json.Valid([]byte(text)) {
+		out.Body = json.RawMessage(text)
+	} else {
+		out.Text = text
+	}
+	return out, nil
+}
+
+// registerFetchRoute 注册 POST /fetch：在签名页面内发出请求并返回站点的应答。
+func registerFetchRoute(g gin.IRouter, fetcher pageFetcher) {
+	g.POST("/fetch", func(c *gin.Context) {
+		var req FetchParams
+		if err := wire.Bind(c, &req); err != nil {
+			slog.Warn("/fetch 参数解析失败", "err", err, "client_ip", c.ClientIP())
+			wire.Render(c, http.StatusBadRequest, gin.H{"error": "参数解析失败: " + err.Error()})
+			return
+		}
+		if err := req.Validate(); err != nil {
+			wire.Render(c, http.StatusBadRequest, platform.ErrorBody(err))
+			return
+		}
+		out, err := fetcher.Fetch(c.Request.Context(), req)
+		if err != nil {
+			slog.Error("/fetch 失败", "err", err, "uri", req.URI, "api_key", auth.FromContext(c).Name, "client_ip", c.ClientIP())
+			wire.Render(c, platform.SignErrorStatus(c, err), platform.ErrorBody(err))
+			return
+		}
+		slog.Info("/fetch 完成", "uri", req.URI, "status", out.Status, "context_id", out.ContextID, "api_key", auth.FromContext(c).Name)
+		wire.Render(c, http.StatusOK, out)
+	})
+}
@@ -0,0 +1,132 @@
+package xhs
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go_sign/internal/pagepool"
+	"go_sign/internal/platform"
+	"go_sign/internal/webhook"
+)
+
+// Hooks 为 Signer 生命周期事件的回调，供嵌入本包的应用接入自己的指标、告警或恢复逻辑。
+// 回调在触发事件的协程中同步执行，不应阻塞；未设置的回调忽略。
+type Hooks struct {
+	// OnInit 在页面池预热完成后调用。
+	OnInit func(InitEvent)
+	// OnRestart 在页面崩溃或关闭后重建槽位、或签名函数丢失后重新加载首页时调用。
+	OnRestart func(RestartEvent)
+	// OnSignError 在签名失败时调用。
+	OnSignError func(SignErrorEvent)
+	// OnCaptchaDetected 在签名失败或健康检查时发现页面停留在验证码页时调用，每次检测到都会调用。
+	OnCaptchaDetected func(CaptchaEvent)
+}
+
+// InitEvent 为页面池预热完成事件。
+type InitEvent struct {
+	Profile string
+	// Pages 为预热成功的页面数。
+	Pages   int
+	Elapsed time.Duration
+}
+
+// RestartEvent 为槽位重建事件。
+type RestartEvent struct {
+	Profile string
+	// ContextID 为重建的槽位，格式为 <租户>/<分组>/<槽位>。
+	ContextID string
+	Reason    string
+	// Err 为重建失败的原因，成功时为 nil。
+	Err error
+}
+
+// SignErrorEvent 为签名失败事件。
+type SignErrorEvent struct {
+	Profile   string
+	Tenant    string
+	ContextID string
+	URI       string
+	Err       error
+}
+
+// CaptchaEvent 为验证码检测事件。
+type CaptchaEvent struct {
+	Profile   string
+	ContextID string
+	// URL 为页面当前地址。
+	URL string
+}
+
+// isCaptchaURL 判断页面地址是否为验证码页，如 /website-login/captcha。
+func isCaptchaURL(u string) bool {
+	return strings.Contains(strings.ToLower(u), "captcha")
+}
+
+// signFailed 在签名失败后触发 OnSignError 并归还槽位：页面已关闭时在后台重建槽位后归还，
+// 页面停留在验证码页时触发 OnCaptchaDetected。
+func (s *Signer) signFailed(ctx context.Context, pool *pagepool.Pool, slot *pagepool.Slot, uri string, err error) {
+	if h := s.opts.Hooks.OnSignError; h != nil {
+		h(SignErrorEvent{Profile: s.opts.Profile.Name, Tenant: slot.Tenant, ContextID: slot.ContextID(), URI: uri, Err: err})
+	}
+	if slot.Page.IsClosed() {
+		go func() { pool.Done(s.restartSlot(ctx, pool, slot, "页面已关闭"), err) }()
+		return
+	}
+	s.checkCaptcha(slot)
+	pool.Done(slot, err)
+}
+
+// restartSlot 重建槽位并触发 OnRestart，返回调用方应归还的槽位。
+func (s *Signer) restartSlot(ctx context.Context, pool *pagepool.Pool, slot *pagepool.Slot, reason string) *pagepool.Slot {
+	id := slot.ContextID()
+	slog.Warn("重建槽位", "profile", s.opts.Profile.Name, "context_id", id, "reason", reason)
+	next, err := pool.Recreate(context.WithoutCancel(ctx), slot)
+	if err != nil {
+		slog.Error("重建槽位失败", "err", err, "profile", s.opts.Profile.Name, "context_id", id)
+	}
+	s.restarted(RestartEvent{Profile: s.opts.Profile.Name, ContextID: id, Reason: reason, Err: err})
+	return next
+}
+
+// restarted 触发 OnRestart 并发送 signer.restarted 回调。
+func (s *Signer) restarted(ev RestartEvent) {
+	if h := s.opts.Hooks.OnRestart; h != nil {
+		h(ev)
+	}
+	webhook.Emit(webhook.SignerRestarted, "platform", s.opts.Profile.PlatformName(), "context_id", ev.ContextID, "reason", ev.Reason, "error", ev.Err)
+}
+
+// checkCaptcha 检查槽位页面是否停留在验证码页，是则记录并触发 OnCaptchaDetected。
+func (s *Signer) checkCaptcha(slot *pagepool.Slot) bool {
+	u := slot.Page.URL()
+	if !isCaptchaURL(u) {
+		return false
+	}
+	captchaDetected.Inc(s.opts.Profile.PlatformName())
+	slog.Warn("页面出现验证码", "profile", s.opts.Profile.Name, "context_id", slot.ContextID(), "url", u)
+	if h := s.opts.Hooks.OnCaptchaDetected; h != nil {
+		h(CaptchaEvent{Profile: s.opts.Profile.Name, ContextID: slot.ContextID(), URL: u})
+	}
+	webhook.EmitThrottled(webhook.CaptchaDetected, slot.ContextID(), webhook.CaptchaInterval,
+		"platform", s.opts.Profile.PlatformName(), "context_id", slot.ContextID(), "url", u)
+	return true
+}
+
+// captchaError 检查全部槽位的页面地址，存在验证码页面时返回包装 platform.ErrDegraded 的错误。
+func (s *Signer) captchaError() error {
+	var ids []string
+	for _, pool := range s.pools {
+		for _, slot := range pool.AllSlots() {
+			if s.checkCaptcha(slot) {
+				ids = append(ids, slot.ContextID())
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %d 个页面出现验证码: %s", platform.ErrDegraded, len(ids), strings.Join(ids, ", "))
+}
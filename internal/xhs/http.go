@@ -2,31 +2,158 @@
 package xhs
 
 import (
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"go_sign/internal/auth"
+	"go_sign/internal/platform"
+	"go_sign/internal/timing"
+	"go_sign/internal/wire"
 )
 
-// RegisterRoutes 注册小红书相关路由。
-// router: gin 路由引擎，signer: 签名服务实例。
-func RegisterRoutes(router *gin.Engine, signer *Signer) {
-	router.POST("/sign", func(c *gin.Context) {
+// RegisterRoutes 在 router 下注册签名路由 POST /sign。
+// router: gin 路由引擎或路由组（如 /creator），profile: 签名站点，
+// signer: 签名使用的平台实例（可能经录制、回放等包装），
+// middlewares: 签名路由使用的中间件（如鉴权、platform.CallerContext）。
+func RegisterRoutes(router gin.IRouter, profile Profile, signer platform.Signer, middlewares ...gin.HandlerFunc) {
+	registerSignRoutes(router, profile, signer, signRoutes{}, middlewares...)
+}
+
+// signRoutes 为 registerSignRoutes 的可选接口，零值时只注册 /sign。
+type signRoutes struct {
+	// templates 非 nil 时 ?format=headers 与 ?format=curl 按学习到的请求头模板输出，并注册 /template/:name/execute
+	templates *HeaderTemplates
+	// fetcher 非 nil 时注册在签名页面内发出请求的 /fetch，/template/:name/execute 支持 mode=page
+	fetcher pageFetcher
+}
+
+// registerSignRoutes 注册 POST /sign 及 extra 中开启的接口。
+func registerSignRoutes(router gin.IRouter, profile Profile, signer platform.Signer, extra signRoutes, middlewares ...gin.HandlerFunc) {
+	templates := extra.templates
+	g := router.Group("/", middlewares...)
+	g.POST("/sign", func(c *gin.Context) {
 		var req SignParams
-		if err := c.ShouldBindJSON(&req); err != nil {
+		if err := wire.Bind(c, &req); err != nil {
 			slog.Warn("/sign 参数解析失败", "err", err, "client_ip", c.ClientIP())
-			c.JSON(http.StatusBadRequest, gin.H{"error": "参数解析失败: " + err.Error()})
+			wire.Render(c, http.StatusBadRequest, gin.H{"error": "参数解析失败: " + err.Error()})
 			return
 		}
-		slog.Info("/sign 请求", "uri", req.URI, "client_ip", c.ClientIP())
-		ctx := c.Request.Context()
-		res, err := signer.Sign(ctx, req)
+		format := c.Query("format")
+		if err := platform.CheckFormat(format); err != nil {
+			wire.Render(c, http.StatusBadRequest, gin.H{"error": "参数校验失败: " + err.Error()})
+			return
+		}
+		res, status, err := sign(c, profile, signer, req)
 		if err != nil {
-			slog.Error("/sign 签名失败", "err", err, "uri", req.URI, "client_ip", c.ClientIP())
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "签名失败: " + err.Error()})
+			wire.Render(c, status, platform.ErrorBody(err))
+			return
+		}
+		switch format {
+		case platform.FormatHeaders:
+			wire.Render(c, http.StatusOK, headersResponse(req, res, templates))
+			return
+		case platform.FormatCurl:
+			sreq := req.signRequest()
+			if !strings.Contains(req.URI, "://") {
+				sreq.URI = res.URI
+			}
+			cmd, err := platform.Curl(sreq, headersResponse(req, res, templates), profile.APIBase)
+			if err != nil {
+				wire.Render(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			c.String(http.StatusOK, cmd+"\n")
 			return
 		}
-		slog.Info("/sign 成功", "uri", req.URI, "x-s", res.XS, "x-t", res.XT, "client_ip", c.ClientIP())
-		c.JSON(http.StatusOK, res)
+		res.Timing = timing.Debug(c)
+		wire.Render(c, http.StatusOK, res)
 	})
+	if templates != nil {
+		registerExecuteRoute(g, profile, signer, templates, extra.fetcher)
+	}
+	if extra.fetcher != nil {
+		registerFetchRoute(g, extra.fetcher)
+	}
+}
+
+// sign 校验请求并调用 signer 签名，失败时返回应答的 HTTP 状态码与错误。
+func sign(c *gin.Context, profile Profile, signer platform.Signer, req SignParams) (*SignResult, int, error) {
+	key := auth.FromContext(c)
+	if err := req.decryptCredentials(); err != nil {
+		slog.Warn("/sign 凭据解密失败", "err", err, "profile", profile.Name, "api_key", key.Name, "client_ip", c.ClientIP())
+		return nil, http.StatusBadRequest, err
+	}
+	if err := req.Validate(); err != nil {
+		slog.Warn("/sign 参数校验失败", "err", err, "profile", profile.Name, "api_key", key.Name, "client_ip", c.ClientIP())
+		return nil, http.StatusBadRequest, err
+	}
+	ctx := c.Request.Context()
+	slog.Info("/sign 请求", "profile", profile.Name, "path", c.FullPath(), "uri", req.URI, "api_key", key.Name, "tenant", key.Tenant.Name, "priority", platform.PriorityFrom(ctx).String(), "client_ip", c.ClientIP())
+	start := time.Now()
+	out, err := signer.Sign(ctx, req.signRequest())
+	platform.ObserveSign(platform.SignRecord{
+		Time:     start,
+		Platform: profile.PlatformName(),
+		Tenant:   key.Tenant.Name,
+		APIKey:   key.Name,
+		URI:      req.URI,
+		Account:  platform.AccountOf(out),
+		Result:   out,
+		Duration: time.Since(start),
+	}, err)
+	if err != nil {
+		status := platform.SignErrorStatus(c, err)
+		if errors.Is(err, ErrRateLimited) || errors.Is(err, ErrAccountLimited) {
+			status = http.StatusTooManyRequests
+		}
+		slog.Error("/sign 签名失败", "err", err, "profile", profile.Name, "uri", req.URI, "client_ip", c.ClientIP())
+		return nil, status, fmt.Errorf("签名失败: %w", err)
+	}
+	res := signResult(out)
+	res.ServerTime = time.Now().UnixMilli()
+	slog.Info("/sign 成功", "profile", profile.Name, "uri", req.URI, "x-s", res.XS, "x-t", res.XT, "client_ip", c.ClientIP())
+	return res, http.StatusOK, nil
+}
+
+// headersResponse 将签名结果整理为 ?format=headers 的扁平格式：请求头含 x-s、x-t、x-s-common、
+// 链路追踪 ID 与签名页面的 user-agent，cookie 使用签名页面的 a1 与请求中的 web_session（加密传入时不回显）。
+// templates 中有签名页面学习到的模板时，请求头按模板的顺序与取值输出（见 fillHeaders）。
+func headersResponse(req SignParams, res *SignResult, templates *HeaderTemplates) *platform.HeadersResponse {
+	out := res.signResponse()
+	for k, v := range traceHeaders() {
+		out.Headers[k] = v
+	}
+	a1 := res.A1
+	if a1 == "" {
+		a1 = req.A1
+	}
+	h := platform.Headers(req.signRequest(), out, platform.EchoCookies(map[string]string{"a1": a1, "web_session": req.WebSession}))
+	method := http.MethodGet
+	if req.Data != nil {
+		method = http.MethodPost
+	}
+	uri := res.URI
+	if uri == "" {
+		uri = normalizeURI(req.URI)
+	}
+	path, _, _ := strings.Cut(uri, "?")
+	if tmpl := templates.Lookup(res.UserAgent, method, path); tmpl != nil {
+		fillHeaders(h, tmpl)
+	}
+	return h
+}
+
+// decryptCredentials 解密加密传入的 a1 与 web_session，见 platform.DecryptCookies。
+func (p *SignParams) decryptCredentials() error {
+	cookies, err := platform.DecryptCookies("", map[string]string{"a1": p.A1, "web_session": p.WebSession})
+	if err != nil {
+		return err
+	}
+	p.A1, p.WebSession = cookies["a1"], cookies["web_session"]
+	return nil
 }
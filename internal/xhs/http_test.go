@@ -0,0 +1,204 @@
+package xhs_test
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/config"
+	"go_sign/internal/crypt"
+	"go_sign/internal/platform"
+	"go_sign/internal/platform/platformtest"
+	"go_sign/internal/xhs"
+)
+
+const testA1 = "18d1e2f3a4b5c6d7e8f9a0b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e"
+
+// newSignHarness 注册 /sign 与兼容路由，签名由 fake 完成。
+func newSignHarness(t *testing.T, fake *platformtest.Fake) *platformtest.Harness {
+	return platformtest.New(t, func(r *gin.Engine) {
+		xhs.RegisterRoutes(r, xhs.ProfileWeb, fake)
+		xhs.RegisterCompatRoutes(r, xhs.ProfileWeb, fake)
+	})
+}
+
+// failWith 返回以 err 失败的 SignFunc。
+func failWith(err error) func(context.Context, *platform.SignRequest) (*platform.SignResponse, error) {
+	return func(context.Context, *platform.SignRequest) (*platform.SignResponse, error) { return nil, err }
+}
+
+func TestSignValidation(t *testing.T) {
+	fake := &platformtest.Fake{}
+	h := newSignHarness(t, fake)
+	tests := []struct {
+		name   string
+		path   string
+		body   any
+		fields []string
+		error  string
+	}{
+		{name: "非 JSON", path: "/sign", body: "{", error: "参数解析失败"},
+		{name: "缺少 uri", path: "/sign", body: map[string]any{"data": map[string]any{}}, fields: []string{"uri"}},
+		{name: "uri 不是接口路径", path: "/sign", body: map[string]any{"uri": "/explore"}, fields: []string{"uri"}},
+		{name: "a1 格式错误", path: "/sign", body: map[string]any{"uri": "/api/sns/web/v1/feed", "a1": "ABC"}, fields: []string{"a1"}},
+		{name: "多个字段错误", path: "/sign", body: map[string]any{"a1": "ABC", "timestamp": 1}, fields: []string{"uri", "a1", "timestamp"}},
+		{name: "不支持的 format", path: "/sign?format=xml", body: map[string]any{"uri": "/api/sns/web/v1/feed"}, error: "参数校验失败"},
+		{name: "兼容路由缺少 uri", path: "/signature", body: map[string]any{"cookie": "a1=" + testA1}, fields: []string{"uri"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := h.Do(http.MethodPost, tt.path, tt.body, nil)
+			if resp.Status != http.StatusBadRequest {
+				t.Fatalf("状态码 = %d，期望 400: %s", resp.Status, resp.Body)
+			}
+			body := resp.JSON(t)
+			msg, _ := body["error"].(string)
+			if tt.error != "" && !strings.Contains(msg, tt.error) {
+				t.Errorf("error = %q，期望包含 %q", msg, tt.error)
+			}
+			if tt.fields == nil {
+				return
+			}
+			var got []string
+			fields, _ := body["fields"].([]any)
+			for _, f := range fields {
+				got = append(got, f.(map[string]any)["field"].(string))
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.fields) {
+				t.Errorf("fields = %v，期望 %v", got, tt.fields)
+			}
+		})
+	}
+	if n := len(fake.Requests()); n != 0 {
+		t.Errorf("校验失败的请求不应调用签名，实际调用 %d 次", n)
+	}
+}
+
+func TestSignErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		status     int
+		code       string
+		retryAfter string
+	}{
+		{name: "暂时不可用", err: &platform.UnavailableError{Reason: "页面重建中", RetryAfter: 1500 * time.Millisecond}, status: http.StatusServiceUnavailable, code: platform.ErrorCodeRecovering, retryAfter: "2"},
+		{name: "排队过载", err: fmt.Errorf("排队: %w", platform.ErrOverloaded), status: http.StatusTooManyRequests, code: platform.ErrorCodeOverloaded, retryAfter: "1"},
+		{name: "结果无效", err: platform.ErrInvalidResult, status: http.StatusBadGateway, code: platform.ErrorCodeInvalidResult},
+		{name: "超时", err: context.DeadlineExceeded, status: http.StatusGatewayTimeout},
+		{name: "a1 节流", err: xhs.ErrRateLimited, status: http.StatusTooManyRequests},
+		{name: "其他错误", err: fmt.Errorf("页面崩溃"), status: http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newSignHarness(t, &platformtest.Fake{SignFunc: failWith(tt.err)})
+			resp := h.Do(http.MethodPost, "/sign", map[string]any{"uri": "/api/sns/web/v1/feed", "a1": testA1}, nil)
+			if resp.Status != tt.status {
+				t.Fatalf("状态码 = %d，期望 %d: %s", resp.Status, tt.status, resp.Body)
+			}
+			body := resp.JSON(t)
+			if msg, _ := body["error"].(string); !strings.HasPrefix(msg, "签名失败: ") {
+				t.Errorf("error = %q，期望以「签名失败: 」开头", msg)
+			}
+			if code, _ := body["code"].(string); code != tt.code {
+				t.Errorf("code = %q，期望 %q", code, tt.code)
+			}
+			if got := resp.Header.Get("Retry-After"); got != tt.retryAfter {
+				t.Errorf("Retry-After = %q，期望 %q", got, tt.retryAfter)
+			}
+		})
+	}
+}
+
+func TestSignSuccess(t *testing.T) {
+	fake := &platformtest.Fake{}
+	h := newSignHarness(t, fake)
+	tests := []struct {
+		name   string
+		path   string
+		body   any
+		header string
+	}{
+		{name: "签名", path: "/sign", body: map[string]any{"uri": "https://edith.xiaohongshu.com/api/sns/web/v1/feed", "a1": testA1, "web_session": "s"}},
+		{name: "headers 格式", path: "/sign?format=headers", body: map[string]any{"uri": "/api/sns/web/v1/feed", "a1": testA1, "web_session": "s"}, header: "x-s"},
+		{name: "兼容路由读取 cookie 字符串", path: "/signature", body: map[string]any{"url": "/api/sns/web/v1/feed", "cookie": "a1=" + testA1 + "; web_session=s"}},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := h.Do(http.MethodPost, tt.path, tt.body, nil)
+			if resp.Status != http.StatusOK {
+				t.Fatalf("状态码 = %d，期望 200: %s", resp.Status, resp.Body)
+			}
+			body := resp.JSON(t)
+			if tt.header != "" {
+				headers, _ := body["headers"].(map[string]any)
+				if headers[tt.header] == nil {
+					t.Errorf("headers 缺少 %s: %s", tt.header, resp.Body)
+				}
+			}
+			reqs := fake.Requests()
+			if len(reqs) != i+1 {
+				t.Fatalf("签名调用 %d 次，期望 %d 次", len(reqs), i+1)
+			}
+			got := reqs[i]
+			if !strings.HasSuffix(got.URI, "/api/sns/web/v1/feed") {
+				t.Errorf("uri = %q", got.URI)
+			}
+			if got.Cookies["a1"] != testA1 || got.Cookies["web_session"] != "s" {
+				t.Errorf("cookies = %v", got.Cookies)
+			}
+		})
+	}
+}
+
+func TestSignEncryptedCredentials(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	cipher, err := crypt.ForRequests(&config.RequestEncryption{Key: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	platform.SetCredentialCipher(cipher, true)
+	t.Cleanup(func() { platform.SetCredentialCipher(nil, false) })
+	sealed, err := cipher.Encrypt([]byte(testA1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &platformtest.Fake{}
+	h := newSignHarness(t, fake)
+	resp := h.Do(http.MethodPost, "/sign", map[string]any{"uri": "/api/sns/web/v1/feed", "a1": testA1}, nil)
+	if resp.Status != http.StatusBadRequest {
+		t.Fatalf("明文 a1 状态码 = %d，期望 400: %s", resp.Status, resp.Body)
+	}
+	resp = h.Do(http.MethodPost, "/sign", map[string]any{"uri": "/api/sns/web/v1/feed", "a1": sealed}, nil)
+	if resp.Status != http.StatusOK {
+		t.Fatalf("加密 a1 状态码 = %d，期望 200: %s", resp.Status, resp.Body)
+	}
+	if reqs := fake.Requests(); len(reqs) != 1 || reqs[0].Cookies["a1"] != testA1 {
+		t.Errorf("签名收到的请求 = %+v，期望解密后的 a1", reqs)
+	}
+}
+
+func TestPublishBodyNotHTMLEscaped(t *testing.T) {
+	fake := &platformtest.Fake{}
+	h := platformtest.New(t, func(r *gin.Engine) {
+		xhs.RegisterPublishRoutes(r, xhs.ProfileCreator, fake)
+	})
+	note := map[string]any{"common": map[string]any{"title": "A & B <C>", "desc": "x"}}
+	resp := h.Do(http.MethodPost, "/publish", map[string]any{"step": "publish", "a1": testA1, "note": note}, nil)
+	if resp.Status != http.StatusOK {
+		t.Fatalf("状态码 = %d，期望 200: %s", resp.Status, resp.Body)
+	}
+	body, _ := resp.JSON(t)["body"].(string)
+	if want := `{"common":{"desc":"x","title":"A & B <C>"}}`; body != want {
+		t.Fatalf("body = %s，期望 %s", body, want)
+	}
+	if n := len(fake.Requests()); n != 1 {
+		t.Fatalf("签名调用 %d 次，期望 1 次", n)
+	}
+}
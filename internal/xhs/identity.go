@@ -0,0 +1,78 @@
+package xhs
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"hash/crc32"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/auth"
+	"go_sign/internal/platform"
+)
+
+// MaxGenerate 为 /generate/a1 一次生成的最大数量。
+const MaxGenerate = 100
+
+// identityAlphabet 为 a1 中随机部分的字符集。
+const identityAlphabet = "abcdefghijklmnopqrstuvwxyz1234567890"
+
+// Identity 为站点脚本首次访问时生成的设备标识：a1 与 webId cookie。
+type Identity struct {
+	A1    string `json:"a1"`
+	WebID string `json:"web_id"`
+}
+
+// NewIdentity 按站点脚本的算法离线生成设备标识，无需浏览器：
+// a1 为毫秒时间戳的十六进制、30 位随机字符、由 ua 推断的平台编号与固定的 "0000"，追加其 CRC32（十进制）后截取前 52 位；
+// webId 为 a1 的 MD5。
+func NewIdentity(ua string) Identity {
+	code, _ := platformCode(ua)
+	prefix := strconv.FormatInt(time.Now().UnixMilli(), 16) + randomString(30) + strconv.Itoa(code) + "0000"
+	a1 := prefix + strconv.FormatUint(uint64(crc32.ChecksumIEEE([]byte(prefix))), 10)
+	if len(a1) > 52 {
+		a1 = a1[:52]
+	}
+	sum := md5.Sum([]byte(a1))
+	return Identity{A1: a1, WebID: hex.EncodeToString(sum[:])}
+}
+
+// randomString 返回 n 位取自 identityAlphabet 的随机字符。
+func randomString(n int) string {
+	out := make([]byte, n)
+	size := big.NewInt(int64(len(identityAlphabet)))
+	for i := range out {
+		v, _ := rand.Int(rand.Reader, size)
+		out[i] = identityAlphabet[v.Int64()]
+	}
+	return string(out)
+}
+
+// GenerateHandler 返回离线生成设备标识的接口 GET /generate/a1，可选参数 count（默认 1，至多 MaxGenerate）
+// 与 user_agent（决定 a1 中的平台编号，默认为 Windows），返回 {"identities": [{"a1", "web_id"}]}。
+func GenerateHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		count := 1
+		if v := c.Query("count"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 1 || n > MaxGenerate {
+				verr := &platform.ValidationError{}
+				verr.Add("count", "须为 1～%d 的整数，实际为 %q", MaxGenerate, v)
+				c.JSON(http.StatusBadRequest, platform.ErrorBody(verr))
+				return
+			}
+			count = n
+		}
+		out := make([]Identity, count)
+		for i := range out {
+			out[i] = NewIdentity(c.Query("user_agent"))
+		}
+		slog.Info("离线生成设备标识", "count", count, "api_key", auth.FromContext(c).Name, "client_ip", c.ClientIP())
+		c.JSON(http.StatusOK, gin.H{"identities": out})
+	}
+}
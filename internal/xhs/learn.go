@@ -0,0 +1,374 @@
+package xhs
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/mxschmitt/playwright-go"
+	"go_sign/internal/platform"
+)
+
+// 请求头学习：签名页面加载后，站点前端会向接口域名（Profile.APIBase，如 edith.xiaohongshu.com）发出
+// 带 x-s、x-s-common 的真实请求。开启 learn_headers 后记录这些请求的请求头作为模板，?format=headers
+// 按模板的顺序与取值输出，使调用方发出的请求头（顺序、sec-ch-*、x-s-common 结构）与站点前端一致。
+
+// headerOrderJS 在页面脚本之前执行，记录页面经 XMLHttpRequest 向接口域名发出的请求设置请求头的顺序，
+// 参数为接口域名。Playwright 的请求事件中请求头不保留顺序，只能在页面内记录。
+const headerOrderJS = `((host) => {
+	const log = [];
+	Object.defineProperty(window, '__goSignHeaderOrder', { value: log });
+	const proto = XMLHttpRequest.prototype, open = proto.open, set = proto.setRequestHeader, send = proto.send;
+	const pending = new WeakMap();
+	proto.open = function (method, url) {
+		try { pending.set(this, { method: String(method).toUpperCase(), url: new URL(url, location.href).href, names: [] }); } catch (e) {}
+		return open.apply(this, arguments);
+	};
+	proto.setRequestHeader = function (name) {
+		const r = pending.get(this);
+		if (r) r.names.push(String(name).toLowerCase());
+		return set.apply(this, arguments);
+	};
+	proto.send = function () {
+		const r = pending.get(this);
+		if (r && new URL(r.url).host === host && log.length < 256) log.push(r);
+		return send.apply(this, arguments);
+	};
+})(%s)`
+
+// takeHeaderOrderJS 取出并清空 headerOrderJS 记录的请求头顺序。
+const takeHeaderOrderJS = `() => (window.__goSignHeaderOrder || []).splice(0)`
+
+// maxTemplates 为每个站点最多记录的模板数，超出后不再记录新的接口。
+const maxTemplates = 256
+
+// dynamicHeaders 为每次请求取值不同的请求头，模板中记录为 {名称} 形式的占位符，填充时取签名结果。
+var dynamicHeaders = []string{"x-s", "x-t", "x-s-common", "x-b3-traceid", "x-xray-traceid"}
+
+// skippedHeaders 为由浏览器或调用方的 HTTP 客户端管理、不记录到模板的请求头。
+var skippedHeaders = []string{"cookie", "host", "content-length", "connection"}
+
+// browserHeaderOrder 为浏览器自行添加的请求头的顺序（Chromium 的常见顺序），排在页面脚本设置的请求头之后。
+var browserHeaderOrder = []string{
+	"sec-ch-ua", "sec-ch-ua-mobile", "sec-ch-ua-platform", "user-agent", "origin",
+	"sec-fetch-site", "sec-fetch-mode", "sec-fetch-dest", "referer", "accept-encoding", "accept-language",
+}
+
+// generatedCommonKeys 为本服务生成的 x-s-common 的字段顺序，学习到的结构与之不同时告警。
+var generatedCommonKeys = commonKeys(xsCommon("", "", "", "", pageStorage{}))
+
+// learnedHeaders 为页面向一个接口发出的请求的学习结果。
+type learnedHeaders struct {
+	name, method, path, ua string
+	// values 为请求头取值（名称小写），dynamicHeaders 已替换为占位符
+	values map[string]string
+	// common 为 x-s-common 解码后的字段顺序
+	common []string
+	// query 与 body 为最近一次请求的原始查询串与 JSON 请求体
+	query    string
+	body     json.RawMessage
+	requests int
+	at       time.Time
+}
+
+// HeaderTemplates 记录签名页面向站点接口发出的真实请求的请求头，按页面 User-Agent 与接口分组。并发安全。
+type HeaderTemplates struct {
+	host string
+
+	mu      sync.Mutex
+	learned map[string]*learnedHeaders // 键为 <User-Agent>\x00<模板名称>
+	// orders 为各接口页面脚本设置请求头的顺序，键为模板名称
+	orders map[string][]string
+	// warned 为已告警过的 x-s-common 字段顺序
+	warned map[string]bool
+}
+
+// NewHeaderTemplates 创建请求头模板，只记录发往 apiBase 所在域名的请求。
+func NewHeaderTemplates(apiBase string) (*HeaderTemplates, error) {
+	u, err := url.Parse(apiBase)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("接口地址 %q 不合法", apiBase)
+	}
+	return &HeaderTemplates{
+		host:    u.Host,
+		learned: make(map[string]*learnedHeaders),
+		orders:  make(map[string][]string),
+		warned:  make(map[string]bool),
+	}, nil
+}
+
+// observe 在页面上注入 headerOrderJS 并监听发往接口域名的 xhr、fetch 请求，用作 pagepool.OpenOptions.OnPage。
+func (t *HeaderTemplates) observe(page playwright.Page) error {
+	host, _ := json.Marshal(t.host)
+	if err := page.AddInitScript(playwright.BrowserContextAddInitScriptOptions{Script: playwright.String(fmt.Sprintf(headerOrderJS, host))}); err != nil {
+		return fmt.Errorf("注入请求头学习脚本失败: %w", err)
+	}
+	var draining atomic.Bool
+	page.On("request", func(req playwright.Request) {
+		if rt := req.ResourceType(); rt != "xhr" && rt != "fetch" {
+			return
+		}
+		u, err := url.Parse(req.URL())
+		if err != nil || u.Host != t.host {
+			return
+		}
+		body, _ := req.PostDataBuffer()
+		t.record(req.Method(), u, req.Headers(), body, time.Now())
+		// 事件回调中不能同步调用页面，另起协程读取页面记录的请求头顺序；未读取到的记录留待下一个请求
+		if draining.CompareAndSwap(false, true) {
+			go func() {
+				defer draining.Store(false)
+				t.drainOrders(page)
+			}()
+		}
+	})
+	return nil
+}
+
+// drainOrders 取出页面记录的请求头顺序。
+func (t *HeaderTemplates) drainOrders(page playwright.Page) {
+	v, err := page.Evaluate(takeHeaderOrderJS)
+	if err != nil {
+		slog.Debug("读取页面请求头顺序失败", "err", err, "host", t.host)
+		return
+	}
+	entries, _ := v.([]interface{})
+	for _, e := range entries {
+		m, _ := e.(map[string]interface{})
+		method, _ := m["method"].(string)
+		raw, _ := m["url"].(string)
+		u, err := url.Parse(raw)
+		if err != nil {
+			continue
+		}
+		list, _ := m["names"].([]interface{})
+		names := make([]string, 0, len(list))
+		for _, n := range list {
+			if name, ok := n.(string); ok && !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+		t.mu.Lock()
+		t.orders[templateName(method, u.Path)] = names
+		t.mu.Unlock()
+	}
+}
+
+// record 记录一个请求的请求头、查询串与请求体，headers 的名称为小写；没有 user-agent 的请求不记录，
+// 请求体不是 JSON 对象时不记录请求体。
+func (t *HeaderTemplates) record(method string, u *url.URL, headers map[string]string, body []byte, at time.Time) {
+	ua := headers["user-agent"]
+	if ua == "" {
+		return
+	}
+	values := make(map[string]string, len(headers))
+	for name, v := range headers {
+		switch {
+		case slices.Contains(skippedHeaders, name):
+		case slices.Contains(dynamicHeaders, name):
+			values[name] = "{" + name + "}"
+		default:
+			values[name] = v
+		}
+	}
+	common := commonKeys(headers["x-s-common"])
+	path := u.Path
+	name := templateName(method, path)
+	key := ua + "\x00" + name
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	l, ok := t.learned[key]
+	if !ok {
+		if len(t.learned) >= maxTemplates {
+			return
+		}
+		l = &learnedHeaders{name: name, method: method, path: path, ua: ua}
+		t.learned[key] = l
+		slog.Info("学习到请求头模板", "template", name, "host", t.host, "user_agent", ua)
+	}
+	l.values, l.requests, l.at = values, l.requests+1, at
+	l.query, l.body = u.RawQuery, nil
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '{' && json.Valid(trimmed) {
+		l.body = slices.Clone(trimmed)
+	}
+	if common != nil {
+		l.common = common
+		if sig := strings.Join(common, ","); !slices.Equal(common, generatedCommonKeys) && !t.warned[sig] {
+			t.warned[sig] = true
+			slog.Warn("站点 x-s-common 的字段与本服务生成的不一致，站点脚本可能已更新", "template", name, "site", common, "generated", generatedCommonKeys)
+		}
+	}
+}
+
+// Lookup 返回 User-Agent 为 ua 的页面学习到的 method path 接口的模板；该接口未学习到时返回同一请求方法最近更新的模板，
+// 均没有时返回 nil。t 为 nil（未开启学习）时返回 nil。
+func (t *HeaderTemplates) Lookup(ua, method, path string) *platform.HeaderTemplate {
+	if t == nil || ua == "" {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	l := t.learned[ua+"\x00"+templateName(method, path)]
+	if l == nil {
+		for _, c := range t.learned {
+			if c.ua == ua && c.method == method && (l == nil || c.at.After(l.at)) {
+				l = c
+			}
+		}
+	}
+	if l == nil {
+		return nil
+	}
+	tmpl := t.template(l)
+	return &tmpl
+}
+
+// Named 返回名为 name 的模板，多个页面 User-Agent 均学习到时返回最近更新的一个，没有时返回 nil。
+func (t *HeaderTemplates) Named(name string) *platform.HeaderTemplate {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	var l *learnedHeaders
+	for _, c := range t.learned {
+		if c.name == name && (l == nil || c.at.After(l.at)) {
+			l = c
+		}
+	}
+	if l == nil {
+		return nil
+	}
+	tmpl := t.template(l)
+	return &tmpl
+}
+
+// List 返回全部模板，按名称与 User-Agent 排序。
+func (t *HeaderTemplates) List() []platform.HeaderTemplate {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]platform.HeaderTemplate, 0, len(t.learned))
+	for _, l := range t.learned {
+		out = append(out, t.template(l))
+	}
+	slices.SortFunc(out, func(a, b platform.HeaderTemplate) int {
+		if c := strings.Compare(a.Name, b.Name); c != 0 {
+			return c
+		}
+		return strings.Compare(a.UserAgent, b.UserAgent)
+	})
+	return out
+}
+
+// template 将学习结果整理为模板：页面脚本设置的请求头按设置顺序在前，浏览器添加的请求头按 browserHeaderOrder 在后，
+// 其余按名称排序。调用方须持有 t.mu。
+func (t *HeaderTemplates) template(l *learnedHeaders) platform.HeaderTemplate {
+	names := make([]string, 0, len(l.values))
+	for _, list := range [][]string{t.orders[l.name], browserHeaderOrder} {
+		for _, name := range list {
+			if _, ok := l.values[name]; ok && !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	var rest []string
+	for name := range l.values {
+		if !slices.Contains(names, name) {
+			rest = append(rest, name)
+		}
+	}
+	slices.Sort(rest)
+	tmpl := platform.HeaderTemplate{
+		Name: l.name, Method: l.method, Path: l.path, UserAgent: l.ua,
+		Query: l.query, Body: l.body, Requests: l.requests, UpdatedAt: l.at,
+	}
+	for _, name := range append(names, rest...) {
+		tmpl.Headers = append(tmpl.Headers, platform.TemplateHeader{Name: name, Value: l.values[name]})
+	}
+	if l.common != nil {
+		tmpl.Structure = map[string][]string{"x-s-common": l.common}
+	}
+	return tmpl
+}
+
+// fillHeaders 按模板填充 h：请求头按模板的顺序与取值输出，占位符替换为 h 中的签名结果与链路追踪 ID（h 中没有时省略），
+// 模板中没有的请求头按名称排序排在最后。
+func fillHeaders(h *platform.HeadersResponse, tmpl *platform.HeaderTemplate) {
+	headers := make(map[string]string, len(tmpl.Headers)+len(h.Headers))
+	order := make([]string, 0, len(tmpl.Headers)+len(h.Headers))
+	for _, th := range tmpl.Headers {
+		v := th.Value
+		if v == "{"+th.Name+"}" {
+			var ok bool
+			if v, ok = h.Headers[th.Name]; !ok {
+				continue
+			}
+		}
+		headers[th.Name] = v
+		order = append(order, th.Name)
+	}
+	var rest []string
+	for name := range h.Headers {
+		if _, ok := headers[name]; !ok {
+			rest = append(rest, name)
+		}
+	}
+	slices.Sort(rest)
+	for _, name := range rest {
+		headers[name] = h.Headers[name]
+		order = append(order, name)
+	}
+	h.Headers, h.Order, h.Template = headers, order, tmpl.Name
+}
+
+// templateName 返回模板名称：小写的请求方法与去掉 /api/ 前缀的接口路径，以下划线连接，如 post_sns_web_v1_feed。
+func templateName(method, path string) string {
+	path = strings.TrimPrefix(path, "/api/")
+	name := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, path)
+	return strings.ToLower(method) + "_" + strings.Trim(name, "_")
+}
+
+// commonKeys 返回 x-s-common 解码后的 JSON 字段顺序，无法解码时返回 nil。
+func commonKeys(v string) []string {
+	if v == "" {
+		return nil
+	}
+	raw, err := commonEncoding.DecodeString(v)
+	if err != nil {
+		if raw, err = commonEncoding.WithPadding(base64.NoPadding).DecodeString(strings.TrimRight(v, "=")); err != nil {
+			return nil
+		}
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil
+	}
+	var keys []string
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil
+		}
+		key, _ := tok.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
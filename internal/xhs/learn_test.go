@@ -0,0 +1,60 @@
+package xhs
+
+import (
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"go_sign/internal/platform"
+)
+
+func TestHeaderTemplates(t *testing.T) {
+	const ua = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+	templates, err := NewHeaderTemplates(ProfileWeb.APIBase)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse("https://edith.xiaohongshu.com/api/sns/web/v1/feed")
+	templates.record(http.MethodPost, u, map[string]string{
+		"user-agent":         ua,
+		"sec-ch-ua":          `"Chromium";v="120"`,
+		"sec-ch-ua-platform": `"Windows"`,
+		"referer":            "https://www.xiaohongshu.com/",
+		"accept":             "application/json, text/plain, */*",
+		"content-type":       "application/json;charset=UTF-8",
+		"x-s":                "XYW_old",
+		"x-t":                "1700000000000",
+		"x-s-common":         xsCommon("a1", ua, "XYW_old", "1700000000000", pageStorage{}),
+		"cookie":             "a1=old",
+	}, []byte(`{"source_note_id":"abc","image_formats":["jpg"]}`), time.Now())
+	templates.orders["post_sns_web_v1_feed"] = []string{"content-type", "x-s", "x-t", "x-s-common", "accept"}
+
+	tmpl := templates.Lookup(ua, http.MethodPost, "/api/sns/web/v1/feed")
+	if tmpl == nil || tmpl.Name != "post_sns_web_v1_feed" {
+		t.Fatalf("Lookup = %+v，期望 post_sns_web_v1_feed", tmpl)
+	}
+	if got := tmpl.Structure["x-s-common"]; !slices.Equal(got, generatedCommonKeys) {
+		t.Errorf("x-s-common 结构 = %v，期望 %v", got, generatedCommonKeys)
+	}
+	if templates.Lookup(ua, http.MethodPost, "/api/sns/web/v1/homefeed") == nil {
+		t.Error("未学习到的接口应返回同一请求方法的模板")
+	}
+	if templates.Lookup(ua, http.MethodGet, "/api/sns/web/v1/feed") != nil || templates.Lookup("other", http.MethodPost, "/api/sns/web/v1/feed") != nil {
+		t.Error("请求方法或 User-Agent 不同时不应返回模板")
+	}
+
+	h := &platform.HeadersResponse{Headers: map[string]string{"x-s": "XYW_new", "x-t": "1800000000000", "x-b3-traceid": "abc", "user-agent": ua}}
+	fillHeaders(h, tmpl)
+	want := []string{"content-type", "x-s", "x-t", "accept", "sec-ch-ua", "sec-ch-ua-platform", "user-agent", "referer", "x-b3-traceid"}
+	if !slices.Equal(h.Order, want) {
+		t.Errorf("Order = %v，期望 %v", h.Order, want)
+	}
+	if h.Headers["x-s"] != "XYW_new" || h.Headers["sec-ch-ua"] != `"Chromium";v="120"` || h.Headers["cookie"] != "" {
+		t.Errorf("Headers = %v", h.Headers)
+	}
+	if _, ok := h.Headers["x-s-common"]; ok {
+		t.Error("签名结果中没有 x-s-common 时不应输出占位符")
+	}
+}
@@ -0,0 +1,57 @@
+package xhs
+
+import "go_sign/internal/metrics"
+
+// 签名服务相关指标。
+var (
+	paceWaitSeconds = metrics.Default.NewHistogramVec(
+		"go_sign_pace_wait_seconds",
+		"因 a1 节流而延迟签名的耗时（秒）",
+		nil, "tenant",
+	)
+	pacedRejects = metrics.Default.NewCounterVec(
+		"go_sign_paced_rejects_total",
+		"因 a1 节流等待超限而拒绝的签名请求数",
+		"tenant",
+	)
+	captchaDetected = metrics.Default.NewCounterVec(
+		"go_sign_captcha_detected_total",
+		"检测到页面停留在验证码页的次数",
+		"platform",
+	)
+	signFuncReloads = metrics.Default.NewCounterVec(
+		"go_sign_sign_func_reloads_total",
+		"签名函数丢失后重新加载首页的次数，result 为 success 或 error",
+		"platform", "result",
+	)
+	invalidResultReloads = metrics.Default.NewCounterVec(
+		"go_sign_invalid_result_reloads_total",
+		"签名结果无效（x-s、x-t 为空或格式不符）后重新加载首页的次数，result 为 success 或 error",
+		"platform", "result",
+	)
+	redirectRecoveries = metrics.Default.NewCounterVec(
+		"go_sign_redirect_recoveries_total",
+		"健康检查发现页面离开站点后重新加载首页的次数，result 为 success 或 error",
+		"platform", "result",
+	)
+	accountCooldowns = metrics.Default.NewCounterVec(
+		"go_sign_account_cooldowns_total",
+		"a1 因多次收到上游风控响应（461、406）而进入冷却的次数",
+		"platform",
+	)
+	degradedAccounts = metrics.Default.NewGaugeVec(
+		"go_sign_degraded_accounts",
+		"健康评分低于 0.5、只在没有更健康的空闲页面时使用的 a1 数",
+		"platform",
+	)
+	accountLimited = metrics.Default.NewCounterVec(
+		"go_sign_account_limited_total",
+		"因 a1 在当前分钟、小时或天内的签名次数达到上限而拒绝的签名请求数，window 为 minute、hour 或 day",
+		"platform", "window",
+	)
+	fallbackSigns = metrics.Default.NewCounterVec(
+		"go_sign_fallback_signs_total",
+		"浏览器不可用时以缓存的签名脚本降级签名的次数，result 为 success 或 error",
+		"platform", "result",
+	)
+)
@@ -0,0 +1,86 @@
+package xhs
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited 表示同一 a1 的签名频率超出全局节流上限且等待时间超过允许范围。
+var ErrRateLimited = errors.New("a1 签名频率超出限制")
+
+// pacer 按 a1 对签名做全局匀速节流，并叠加随机抖动，避免单个账号被客户端推到封禁边缘。
+// 与调用方自身的限流无关，所有 Key、租户共享同一份节流状态。a1 为签名页面自身的 a1（slot.Identity），
+// 不取调用方传入的值，否则调用方换一个 a1 即可绕过节流。
+type pacer struct {
+	interval time.Duration // 同一 a1 相邻两次签名的最小间隔
+	jitter   time.Duration // 额外随机延迟上限
+	maxWait  time.Duration // 允许的最长等待，超过则返回 ErrRateLimited（见 Signer.acquirePaced）
+
+	mu   sync.Mutex
+	next map[string]time.Time // a1 -> 下一次可签名的时间
+	rnd  *rand.Rand
+}
+
+// newPacer 创建节流器，perMinute <= 0 且 jitter <= 0 时返回 nil 表示不节流。
+func newPacer(perMinute int, jitter, maxWait time.Duration) *pacer {
+	if perMinute <= 0 && jitter <= 0 {
+		return nil
+	}
+	p := &pacer{
+		jitter:  jitter,
+		maxWait: maxWait,
+		next:    make(map[string]time.Time),
+		rnd:     rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	if perMinute > 0 {
+		p.interval = time.Minute / time.Duration(perMinute)
+	}
+	return p
+}
+
+// reserve 在 a1 已到下一个签名时间点时为其预留本次签名，返回 0 与撤销预留的函数；
+// 否则不预留，返回还需等待的时长。下一个时间点为本次之后 interval 再加随机抖动。
+func (p *pacer) reserve(a1 string) (wait time.Duration, undo func()) {
+	if p == nil || a1 == "" {
+		return 0, func() {}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	prev, ok := p.next[a1]
+	if ok && prev.After(now) {
+		return prev.Sub(now), nil
+	}
+	next := now.Add(p.interval)
+	if p.jitter > 0 {
+		next = next.Add(time.Duration(p.rnd.Int63n(int64(p.jitter))))
+	}
+	p.gc(now)
+	p.next[a1] = next
+	return 0, func() {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		// 期间已被其他请求改写时保留其预留
+		if p.next[a1].Equal(next) {
+			if ok {
+				p.next[a1] = prev
+			} else {
+				delete(p.next, a1)
+			}
+		}
+	}
+}
+
+// gc 在持有锁的前提下清理已过期的 a1 记录，避免 map 无限增长。
+func (p *pacer) gc(now time.Time) {
+	if len(p.next) < 1024 {
+		return
+	}
+	for a1, n := range p.next {
+		if n.Before(now) {
+			delete(p.next, a1)
+		}
+	}
+}
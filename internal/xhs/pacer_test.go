@@ -0,0 +1,39 @@
+package xhs
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"go_sign/internal/platform"
+)
+
+func TestPacerUndoOnAccountLimit(t *testing.T) {
+	p := newPacer(60, 0, time.Second)
+	u := &accountUsage{limits: platform.AccountLimits{PerMinute: 1}}
+	now := time.Now()
+
+	if wait, _ := p.reserve("a1-x"); wait != 0 {
+		t.Fatalf("首次预留等待 %v，期望 0", wait)
+	}
+	if _, err := u.acquire("a1-x", now); err != nil {
+		t.Fatal(err)
+	}
+	if wait, _ := p.reserve("a1-x"); wait <= 0 {
+		t.Fatal("间隔内再次预留应需要等待")
+	}
+
+	// 超出签名次数上限的请求撤销预留，不占用下一个节流时间点
+	p.next["a1-x"] = now.Add(-time.Millisecond)
+	wait, undo := p.reserve("a1-x")
+	if wait != 0 {
+		t.Fatalf("到期后预留等待 %v，期望 0", wait)
+	}
+	if _, err := u.acquire("a1-x", now); !errors.Is(err, ErrAccountLimited) {
+		t.Fatalf("超出每分钟上限应返回 ErrAccountLimited，实际 %v", err)
+	}
+	undo()
+	if wait, _ := p.reserve("a1-x"); wait != 0 {
+		t.Fatalf("撤销预留后等待 %v，期望 0", wait)
+	}
+}
@@ -0,0 +1,499 @@
+package xhs
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/browser"
+	"go_sign/internal/geo"
+	"go_sign/internal/pagepool"
+	"go_sign/internal/platform"
+)
+
+// PlatformWeb 为主站在平台注册表中的名称。
+var PlatformWeb = ProfileWeb.PlatformName()
+
+func init() {
+	for _, p := range []Profile{ProfileWeb, ProfileCreator, ProfileArk, ProfileMobile} {
+		platform.Register(p.PlatformName(), newPlatformFactory(p))
+	}
+}
+
+// platformOptions 为小红书平台在配置文件中的可选项。
+type platformOptions struct {
+	// PoolSize 覆盖该站点的默认页面池大小。
+	PoolSize int `yaml:"pool_size"`
+	// HomeURL 覆盖站点首页，签名在该页面上执行。
+	HomeURL string `yaml:"home_url"`
+	// WarmupURLs 为预热时在首页之前依次访问的页面。
+	WarmupURLs []string `yaml:"warmup_urls"`
+	// Devices 为各浏览器上下文模拟的设备预设名称，编号为 i 的槽位使用 Devices[i % len(Devices)]，为空时使用站点默认设备。
+	Devices []string `yaml:"devices"`
+	// ExtraHeaders 为浏览器上下文发出的每个请求附加的请求头，如 Accept-Language、sec-ch-ua。
+	ExtraHeaders map[string]string `yaml:"extra_headers"`
+	// Proxies 为各浏览器上下文的出口代理（http、https 或 socks5 地址），编号为 i 的槽位使用 Proxies[i % len(Proxies)]，为空时直连。
+	Proxies []string `yaml:"proxies"`
+	// Timezone 与 Locale 为浏览器上下文模拟的时区与语言，为空时使用浏览器默认值。
+	Timezone string `yaml:"timezone"`
+	Locale   string `yaml:"locale"`
+	// GeoCheck 为代理出口位置与时区、语言的一致性检查，为空时不检查。
+	GeoCheck *geoCheckOptions `yaml:"geo_check"`
+	// LearnHeaders 为 true 时记录签名页面向站点接口发出的真实请求的请求头，作为 ?format=headers 的模板。
+	LearnHeaders bool `yaml:"learn_headers"`
+	// Behavior 为预热时每次导航后模拟的真人浏览行为，为空时不模拟。
+	Behavior *behaviorOptions `yaml:"behavior"`
+	// Fallback 为浏览器不可用时的降级签名，为空时不降级。
+	Fallback *fallbackOptions `yaml:"fallback"`
+}
+
+// fallbackOptions 为降级签名的配置，见 FallbackOptions。
+type fallbackOptions struct {
+	CachePath string `yaml:"cache_path"`
+	Size      int    `yaml:"size"`
+}
+
+// geoCheckOptions 为出口位置检查的配置，见 geo.NewChecker。
+type geoCheckOptions struct {
+	Mode string `yaml:"mode"`
+	URL  string `yaml:"url"`
+}
+
+// checker 创建出口位置检查器，未配置时返回 nil。
+func (o *geoCheckOptions) checker() (*geo.Checker, error) {
+	if o == nil {
+		return nil, nil
+	}
+	return geo.NewChecker(o.URL, o.Mode)
+}
+
+// proxies 解析出口代理地址。
+func (o platformOptions) proxies() ([]*url.URL, error) {
+	out := make([]*url.URL, 0, len(o.Proxies))
+	for i, raw := range o.Proxies {
+		u, err := url.Parse(raw)
+		if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5") {
+			return nil, fmt.Errorf("proxies[%d]: 不是合法的 http、https 或 socks5 代理地址", i)
+		}
+		out = append(out, u)
+	}
+	return out, nil
+}
+
+// behaviorOptions 为模拟浏览行为的配置，见 pagepool.Behavior。
+type behaviorOptions struct {
+	MouseMoves int           `yaml:"mouse_moves"`
+	Scrolls    int           `yaml:"scrolls"`
+	Dwell      time.Duration `yaml:"dwell"`
+	// Script 为行为脚本文件路径，内容为在页面中执行的 JS 函数。
+	Script string `yaml:"script"`
+}
+
+// behavior 将配置转换为 pagepool.Behavior 并读取行为脚本，未配置时返回 nil。
+func (o *behaviorOptions) behavior() (*pagepool.Behavior, error) {
+	if o == nil {
+		return nil, nil
+	}
+	b := &pagepool.Behavior{MouseMoves: o.MouseMoves, Scrolls: o.Scrolls, Dwell: o.Dwell}
+	if o.Script != "" {
+		raw, err := os.ReadFile(o.Script)
+		if err != nil {
+			return nil, fmt.Errorf("读取行为脚本失败: %w", err)
+		}
+		b.Script = string(raw)
+	}
+	return b, nil
+}
+
+// validate 校验首页与预热页面均为 http(s) 地址，行为脚本文件存在，且降级签名配置了缓存路径。
+func (o platformOptions) validate() error {
+	if o.HomeURL != "" && !validPageURL(o.HomeURL) {
+		return fmt.Errorf("home_url: %q 不是合法的 http(s) 地址", o.HomeURL)
+	}
+	for i, u := range o.WarmupURLs {
+		if !validPageURL(u) {
+			return fmt.Errorf("warmup_urls[%d]: %q 不是合法的 http(s) 地址", i, u)
+		}
+	}
+	for i, name := range o.Devices {
+		if _, err := browser.LookupDevice(name); err != nil {
+			return fmt.Errorf("devices[%d]: %w", i, err)
+		}
+	}
+	if _, err := o.proxies(); err != nil {
+		return err
+	}
+	if o.Timezone != "" {
+		if _, err := time.LoadLocation(o.Timezone); err != nil {
+			return fmt.Errorf("timezone: 未知的时区 %q", o.Timezone)
+		}
+	}
+	if o.GeoCheck != nil {
+		if len(o.Proxies) == 0 {
+			return errors.New("geo_check: 须同时配置 proxies")
+		}
+		if _, err := o.GeoCheck.checker(); err != nil {
+			return fmt.Errorf("geo_check.%w", err)
+		}
+	}
+	names := make([]string, 0, len(o.ExtraHeaders))
+	for name := range o.ExtraHeaders {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		if err := validExtraHeader(name, o.ExtraHeaders[name]); err != nil {
+			return fmt.Errorf("extra_headers.%s: %w", name, err)
+		}
+	}
+	if b := o.Behavior; b != nil && b.Script != "" {
+		if _, err := os.Stat(b.Script); err != nil {
+			return fmt.Errorf("behavior.script: %w", err)
+		}
+	}
+	if f := o.Fallback; f != nil && f.CachePath == "" {
+		return errors.New("fallback.cache_path 不能为空")
+	}
+	return nil
+}
+
+// reservedHeaders 为由浏览器或上下文自身管理、不能通过 extra_headers 覆盖的请求头。
+var reservedHeaders = []string{"cookie", "host", "content-length", "content-type", "connection"}
+
+// validExtraHeader 校验附加请求头的名称为合法的 token、不是保留的请求头，值不含换行。
+func validExtraHeader(name, value string) error {
+	if name == "" || strings.ContainsFunc(name, func(r rune) bool { return r <= ' ' || r >= 0x7f || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r) }) {
+		return errors.New("不是合法的请求头名称")
+	}
+	if slices.Contains(reservedHeaders, strings.ToLower(name)) {
+		return errors.New("由浏览器管理，不能覆盖")
+	}
+	if strings.ContainsAny(value, "\r\n") {
+		return errors.New("值不能包含换行")
+	}
+	return nil
+}
+
+// validPageURL 判断 u 是否为带主机名的 http(s) 地址。
+func validPageURL(u string) bool {
+	parsed, err := url.Parse(u)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+// xhsPlatform 将 Signer 适配为 platform.Platform。
+type xhsPlatform struct {
+	profile Profile
+	options platformOptions
+	signer  *Signer
+	// templates 为学习到的请求头模板，未开启 learn_headers 时为 nil
+	templates *HeaderTemplates
+}
+
+// newPlatformFactory 返回指定站点的平台工厂。
+func newPlatformFactory(profile Profile) platform.Factory {
+	return func(decode platform.Decoder) (platform.Platform, error) {
+		p := &xhsPlatform{profile: profile}
+		if err := decode(&p.options); err != nil {
+			return nil, err
+		}
+		if err := p.options.validate(); err != nil {
+			return nil, err
+		}
+		if p.options.HomeURL != "" {
+			p.profile.HomeURL = p.options.HomeURL
+		}
+		if p.options.LearnHeaders {
+			var err error
+			if p.templates, err = NewHeaderTemplates(p.profile.APIBase); err != nil {
+				return nil, err
+			}
+		}
+		return p, nil
+	}
+}
+
+func (p *xhsPlatform) Name() string { return p.profile.PlatformName() }
+
+// Init 启动（或复用）共享浏览器并预热页面池。
+func (p *xhsPlatform) Init(ctx context.Context, env *platform.Env) error {
+	behavior, err := p.options.Behavior.behavior()
+	if err != nil {
+		return err
+	}
+	b, err := env.Browser.Get()
+	if err != nil {
+		return err
+	}
+	opts := Options{
+		Profile:           p.profile,
+		Browser:           b,
+		StealthPath:       env.StealthPath,
+		WarmupURLs:        p.options.WarmupURLs,
+		ExtraHeaders:      p.options.ExtraHeaders,
+		Timezone:          p.options.Timezone,
+		Locale:            p.options.Locale,
+		Behavior:          behavior,
+		HeaderTemplates:   p.templates,
+		CanaryStealthPath: env.CanaryStealthPath,
+		CanaryWeight:      env.CanaryWeight,
+		WaitUntil:         env.WaitUntil,
+		NavigationTimeout: env.NavigationTimeout,
+		SignFuncTimeout:   env.SignFuncTimeout,
+		PoolSize:          env.PoolSize,
+		WarmupConcurrency: env.WarmupConcurrency,
+		PacePerMinute:     env.PacePerMinute,
+		PaceJitter:        env.PaceJitter,
+		PaceMaxWait:       env.PaceMaxWait,
+		ShedBudget:        env.ShedBudget,
+		BanThreshold:      env.BanThreshold,
+		BanWindow:         env.BanWindow,
+		BanCooldown:       env.BanCooldown,
+		AccountLimits:     env.AccountLimits,
+	}
+	if p.options.PoolSize > 0 {
+		opts.PoolSize = p.options.PoolSize
+	}
+	for _, name := range p.options.Devices {
+		d, _ := browser.LookupDevice(name) // 已在创建平台时校验
+		opts.Devices = append(opts.Devices, d)
+	}
+	if opts.Proxies, err = p.options.proxies(); err != nil {
+		return err
+	}
+	if opts.Geo, err = p.options.GeoCheck.checker(); err != nil {
+		return err
+	}
+	if f := p.options.Fallback; f != nil {
+		opts.Fallback = &FallbackOptions{CachePath: f.CachePath, Size: f.Size}
+	}
+	for _, t := range env.Tenants {
+		opts.Tenants = append(opts.Tenants, TenantOptions{Name: t.Name, PoolSize: t.PoolSize})
+	}
+	p.signer, err = NewSigner(ctx, opts)
+	return err
+}
+
+// Sign 将通用请求转换为 SignParams，结果以 x-s、x-t 请求头返回。
+func (p *xhsPlatform) Sign(ctx context.Context, req *platform.SignRequest) (*platform.SignResponse, error) {
+	params := SignParams{
+		URI:        req.URI,
+		Data:       req.Data,
+		A1:         req.Cookies["a1"],
+		WebSession: req.Cookies["web_session"],
+		Timestamp:  req.Timestamp,
+	}
+	if err := params.Validate(); err != nil {
+		return nil, err
+	}
+	res, err := p.signer.Sign(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	return res.signResponse(), nil
+}
+
+// signRequest 将 SignParams 转换为通用签名请求，a1、web_session 作为 cookie。
+func (p SignParams) signRequest() *platform.SignRequest {
+	cookies := make(map[string]string, 2)
+	if p.A1 != "" {
+		cookies["a1"] = p.A1
+	}
+	if p.WebSession != "" {
+		cookies["web_session"] = p.WebSession
+	}
+	return &platform.SignRequest{URI: p.URI, Data: p.Data, Cookies: cookies, Timestamp: p.Timestamp}
+}
+
+// signResponse 将签名结果转换为通用格式，x-s、x-t 作为请求头，x-t 同时作为签名时间戳，
+// a1 等签名页面信息放入 Source。
+func (r *SignResult) signResponse() *platform.SignResponse {
+	ts, _ := strconv.ParseInt(r.XT, 10, 64)
+	headers := map[string]string{"x-s": r.XS, "x-t": r.XT}
+	if r.XSCommon != "" {
+		headers["x-s-common"] = r.XSCommon
+	}
+	return &platform.SignResponse{
+		Headers:   headers,
+		Timestamp: ts,
+		Source: &platform.SignSource{
+			Identity:  r.A1,
+			ContextID: r.ContextID,
+			UserAgent: r.UserAgent,
+			URI:       r.URI,
+		},
+		ScriptVersion: r.ScriptVersion,
+	}
+}
+
+// signResult 将通用格式的签名结果还原为 SignResult。
+func signResult(r *platform.SignResponse) *SignResult {
+	res := &SignResult{XS: r.Headers["x-s"], XT: r.Headers["x-t"], XSCommon: r.Headers["x-s-common"], ScriptVersion: r.ScriptVersion}
+	if s := r.Source; s != nil {
+		res.A1, res.ContextID, res.UserAgent, res.URI = s.Identity, s.ContextID, s.UserAgent, s.URI
+	}
+	return res
+}
+
+// mockUserAgent 为 mock 结果中的签名页面 User-Agent。
+const mockUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+
+// Mock 返回格式与真实签名一致的假 x-s、x-t 与 x-s-common。
+func (p *xhsPlatform) Mock(req *platform.SignRequest) *platform.SignResponse {
+	payload, _ := json.Marshal(map[string]string{
+		"signSvn":     "56",
+		"signType":    "x2",
+		"appId":       "xhs-pc-web",
+		"signVersion": "1",
+		"payload":     platform.MockHex(128),
+	})
+	res := &SignResult{
+		XS:        "XYW_" + base64.StdEncoding.EncodeToString(payload),
+		XT:        strconv.FormatInt(platform.SignTime(req.Timestamp).UnixMilli(), 10),
+		A1:        req.Cookies["a1"],
+		UserAgent: mockUserAgent,
+		URI:       normalizeURI(req.URI),
+	}
+	res.XSCommon = xsCommon(res.A1, res.UserAgent, res.XS, res.XT, pageStorage{B1B1: "1"})
+	return res.signResponse()
+}
+
+func (p *xhsPlatform) HealthCheck(ctx context.Context) error {
+	if p.signer == nil {
+		return errors.New("平台未初始化")
+	}
+	return p.signer.HealthCheck(ctx)
+}
+
+// RegisterRoutes 保持既有的 /sign、/<站点>/sign 路由与请求、响应格式。
+// 创作平台另注册发布笔记的辅助接口 /creator/publish。
+func (p *xhsPlatform) RegisterRoutes(router gin.IRouter, signer platform.Signer, middlewares ...gin.HandlerFunc) {
+	group := router.Group(p.profile.RoutePrefix())
+	registerSignRoutes(group, p.profile, signer, signRoutes{templates: p.templates, fetcher: p}, middlewares...)
+	if p.profile.Name == ProfileCreator.Name {
+		RegisterPublishRoutes(group, p.profile, signer, middlewares...)
+	}
+}
+
+func (p *xhsPlatform) Close() error {
+	if p.signer == nil {
+		return nil
+	}
+	return p.signer.Close()
+}
+
+// Fetch 在签名页面内发出请求，见 Signer.Fetch。
+func (p *xhsPlatform) Fetch(ctx context.Context, params FetchParams) (*ExecuteResult, error) {
+	if p.signer == nil {
+		return nil, errors.New("平台未初始化")
+	}
+	return p.signer.Fetch(ctx, params)
+}
+
+// HeaderTemplates 返回学习到的请求头模板，实现 platform.HeaderLearner。
+func (p *xhsPlatform) HeaderTemplates() ([]platform.HeaderTemplate, error) {
+	if p.templates == nil {
+		return nil, errors.New("平台未开启 learn_headers")
+	}
+	return p.templates.List(), nil
+}
+
+// Bind 将 API Key 绑定到专属槽位，实现 platform.Binder。
+func (p *xhsPlatform) Bind(ctx context.Context, tenant, key string, slot int) error {
+	if p.signer == nil {
+		return errors.New("平台未初始化")
+	}
+	return p.signer.pools.Bind(ctx, tenant, key, slot)
+}
+
+// Unbind 解除 API Key 的专属槽位绑定，实现 platform.Binder。
+func (p *xhsPlatform) Unbind(ctx context.Context, tenant, key string) error {
+	if p.signer == nil {
+		return errors.New("平台未初始化")
+	}
+	return p.signer.pools.Unbind(ctx, tenant, key)
+}
+
+// Bindings 返回当前的专属槽位绑定，实现 platform.Binder。
+func (p *xhsPlatform) Bindings() []platform.Binding {
+	if p.signer == nil {
+		return nil
+	}
+	return p.signer.pools.Bindings()
+}
+
+// probeURI 为验证 stealth.js 时自检签名的请求路径。
+const probeURI = "/api/go_sign/self-test"
+
+// ProbeStealth 在注入 path 的临时页面上执行一次自检签名，实现 platform.StealthProber。
+func (p *xhsPlatform) ProbeStealth(ctx context.Context, path string) error {
+	if p.signer == nil {
+		return errors.New("平台未初始化")
+	}
+	return p.signer.pools.Probe(ctx, path, func(slot *pagepool.Slot) error {
+		_, err := signOnPage(ctx, slot.Page, p.signer.opts.Profile.SignFunc, SignParams{URI: probeURI})
+		return err
+	})
+}
+
+// ReportStatus 记录 a1 的上游响应状态码，实现 platform.AccountReporter。
+func (p *xhsPlatform) ReportStatus(account string, status int) {
+	if p.signer != nil {
+		p.signer.ReportStatus(account, status)
+	}
+}
+
+// Cooldowns 返回冷却中的 a1，实现 platform.AccountReporter。
+func (p *xhsPlatform) Cooldowns() []platform.Cooldown {
+	if p.signer == nil {
+		return nil
+	}
+	return p.signer.Cooldowns()
+}
+
+// Accounts 返回各 a1 的健康评分，实现 platform.AccountReporter。
+func (p *xhsPlatform) Accounts() []platform.AccountHealth {
+	if p.signer == nil {
+		return nil
+	}
+	return p.signer.Accounts()
+}
+
+// ScriptVersions 返回各签名脚本版本的页面数，实现 platform.ScriptVersioner。
+func (p *xhsPlatform) ScriptVersions() map[string]int {
+	if p.signer == nil {
+		return nil
+	}
+	return p.signer.ScriptVersions()
+}
+
+// StageUpgrade 在新浏览器 b 上预热与当前规模相同的页面，实现 platform.Upgrader。
+func (p *xhsPlatform) StageUpgrade(ctx context.Context, b *browser.Browser, stealthPath string) (platform.Staged, error) {
+	if p.signer == nil {
+		return nil, errors.New("平台未初始化")
+	}
+	return p.signer.pools.Stage(ctx, pagepool.Generation{Browser: b, StealthPath: stealthPath}, p.signer.opts.WarmupConcurrency)
+}
+
+// RenameStealth 将页面池中注入 from 的 stealth.js 路径改为 to，实现 platform.StealthRenamer。
+func (p *xhsPlatform) RenameStealth(from, to string) {
+	if p.signer != nil {
+		p.signer.pools.RenameStealth(from, to)
+	}
+}
+
+// Recycle 逐个重建页面池中的浏览器上下文，实现 platform.Recycler；未初始化时无需回收。
+func (p *xhsPlatform) Recycle(ctx context.Context) error {
+	if p.signer == nil {
+		return nil
+	}
+	return p.signer.pools.Recycle(ctx)
+}
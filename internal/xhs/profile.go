@@ -0,0 +1,46 @@
+package xhs
+
+import "go_sign/internal/browser"
+
+// Profile 描述一个签名站点：预热时访问的首页及页面上的签名函数。
+type Profile struct {
+	// Name 为站点名称，用于日志与指标标签。
+	Name string
+	// HomeURL 为预热时访问的页面地址。
+	HomeURL string
+	// SignFunc 为 window 上签名函数的名称。
+	SignFunc string
+	// APIBase 为站点接口地址，?format=curl 以其为相对 uri 的前缀。
+	APIBase string
+	// Device 为模拟的设备，为 nil 时使用桌面 Chromium 默认值；平台配置了 devices 时以其为准。
+	Device *browser.Device
+}
+
+// 内置站点。
+var (
+	// ProfileWeb 为小红书主站（www.xiaohongshu.com）。
+	ProfileWeb = Profile{Name: "web", HomeURL: "https://www.xiaohongshu.com", SignFunc: "_webmsxyw", APIBase: "https://edith.xiaohongshu.com"}
+	// ProfileCreator 为创作服务平台（creator.xiaohongshu.com），发布笔记等接口需在该域名下签名。
+	ProfileCreator = Profile{Name: "creator", HomeURL: "https://creator.xiaohongshu.com", SignFunc: "_webmsxyw", APIBase: "https://creator.xiaohongshu.com"}
+	// ProfileArk 为商家管理后台（ark.xiaohongshu.com），订单、履约等商家侧接口需在该域名下签名。
+	ProfileArk = Profile{Name: "ark", HomeURL: "https://ark.xiaohongshu.com", SignFunc: "_webmsxyw", APIBase: "https://ark.xiaohongshu.com"}
+	// ProfileMobile 为移动端网页（m.xiaohongshu.com），以 iPhone 设备模拟访问，
+	// 部分接口仅移动端站点可访问。移动端页面同样在 window 上暴露签名函数。
+	ProfileMobile = Profile{Name: "mobile", HomeURL: "https://m.xiaohongshu.com", SignFunc: "_webmsxyw", APIBase: "https://edith.xiaohongshu.com", Device: browser.DeviceIPhoneSafari}
+)
+
+// PlatformName 返回站点在平台注册表中的名称：主站为 xhs，其余站点为 xhs-<name>。
+func (p Profile) PlatformName() string {
+	if p.Name == ProfileWeb.Name {
+		return "xhs"
+	}
+	return "xhs-" + p.Name
+}
+
+// RoutePrefix 返回站点签名路由的前缀：主站为根路径，其余站点为 /<name>。
+func (p Profile) RoutePrefix() string {
+	if p.Name == ProfileWeb.Name {
+		return "/"
+	}
+	return "/" + p.Name
+}
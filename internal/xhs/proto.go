@@ -0,0 +1,83 @@
+package xhs
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"go_sign/internal/wire"
+)
+
+// Protobuf 编解码，消息定义见 api/sign.proto 中的 XhsSignRequest、XhsSignResult。
+
+// MarshalProto 编码 SignParams。
+func (p *SignParams) MarshalProto() ([]byte, error) {
+	b := wire.AppendString(nil, 1, p.URI)
+	if p.Data != nil {
+		data, err := json.Marshal(p.Data)
+		if err != nil {
+			return nil, fmt.Errorf("data 参数序列化失败: %w", err)
+		}
+		b = wire.AppendBytes(b, 2, data)
+	}
+	b = wire.AppendString(b, 3, p.A1)
+	b = wire.AppendString(b, 4, p.WebSession)
+	return wire.AppendInt64(b, 5, p.Timestamp), nil
+}
+
+// UnmarshalProto 解码 SignParams。
+func (p *SignParams) UnmarshalProto(b []byte) error {
+	return wire.RangeFields(b, func(f wire.Field) error {
+		switch f.Num {
+		case 1:
+			p.URI = string(f.Bytes)
+		case 2:
+			if err := json.Unmarshal(f.Bytes, &p.Data); err != nil {
+				return fmt.Errorf("解析 data_json 失败: %w", err)
+			}
+		case 3:
+			p.A1 = string(f.Bytes)
+		case 4:
+			p.WebSession = string(f.Bytes)
+		case 5:
+			p.Timestamp = int64(f.Varint)
+		}
+		return nil
+	})
+}
+
+// MarshalProto 编码 SignResult。
+func (r *SignResult) MarshalProto() ([]byte, error) {
+	b := wire.AppendString(nil, 1, r.XS)
+	b = wire.AppendString(b, 2, r.XT)
+	b = wire.AppendString(b, 3, r.XSCommon)
+	b = wire.AppendInt64(b, 4, r.ServerTime)
+	b = wire.AppendString(b, 5, r.A1)
+	b = wire.AppendString(b, 6, r.ContextID)
+	b = wire.AppendString(b, 7, r.UserAgent)
+	return wire.AppendString(b, 8, r.URI), nil
+}
+
+// UnmarshalProto 解码 SignResult。
+func (r *SignResult) UnmarshalProto(b []byte) error {
+	return wire.RangeFields(b, func(f wire.Field) error {
+		switch f.Num {
+		case 1:
+			r.XS = string(f.Bytes)
+		case 2:
+			r.XT = string(f.Bytes)
+		case 3:
+			r.XSCommon = string(f.Bytes)
+		case 4:
+			r.ServerTime = int64(f.Varint)
+		case 5:
+			r.A1 = string(f.Bytes)
+		case 6:
+			r.ContextID = string(f.Bytes)
+		case 7:
+			r.UserAgent = string(f.Bytes)
+		case 8:
+			r.URI = string(f.Bytes)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,176 @@
+package xhs
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/platform"
+	"go_sign/internal/wire"
+)
+
+// 发布笔记流程的步骤：先在创作平台申请上传凭证，再将图片或视频上传到对象存储，最后以上传得到的文件 ID 发布笔记。
+const (
+	StepPermit  = "permit"
+	StepUpload  = "upload"
+	StepPublish = "publish"
+)
+
+// 发布流程涉及的接口。申请凭证在创作平台域名下签名，发布笔记的接口位于 edith 域名但同样须由创作平台页面签名；
+// 上传文件使用凭证中的 token 鉴权，不需要 x-s。
+const (
+	permitPath  = "/api/media/v1/upload/creator/permit"
+	publishPath = "/web_api/sns/v2/note"
+	uploadBase  = "https://ros-upload.xiaohongshu.com"
+)
+
+// maxPublishFiles 为一次申请上传凭证的最大文件数（图文笔记最多 18 张图片）。
+const maxPublishFiles = 18
+
+// PublishParams 为发布流程中单个步骤的参数。
+type PublishParams struct {
+	// Step 为 permit、upload 或 publish。
+	Step       string `json:"step"`
+	A1         string `json:"a1"`
+	WebSession string `json:"web_session"`
+	// Scene 为上传的媒体类型 image 或 video，permit 步骤使用，默认为 image。
+	Scene string `json:"scene"`
+	// FileCount 为 permit 步骤申请的文件数，默认为 1。
+	FileCount int `json:"file_count"`
+	// FileID、Token 为 permit 返回的 fileIds 之一与 token，upload 步骤使用。
+	FileID string `json:"file_id"`
+	Token  string `json:"token"`
+	// ContentType 为 upload 步骤上传文件的类型，默认为 application/octet-stream。
+	ContentType string `json:"content_type"`
+	// Note 为 publish 步骤的请求体（含上传得到的文件 ID），序列化为 JSON 后参与签名。
+	Note any `json:"note"`
+	// Timestamp 为固定的 x-t（毫秒），0 表示使用签名端当前时间。
+	Timestamp int64 `json:"timestamp,omitempty"`
+}
+
+// PublishStep 为可直接发出的请求：upload 以外的步骤已签名，Headers 含 x-s、x-t、x-s-common 与签名页面的 user-agent。
+type PublishStep struct {
+	Step    string            `json:"step"`
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Cookie  string            `json:"cookie,omitempty"`
+	// Body 为 publish 步骤须原样发送的请求体，与参与签名的 JSON 逐字节一致；upload 步骤的请求体为文件内容，由调用方填充。
+	Body string `json:"body,omitempty"`
+	// Signed 为请求是否带 x-s 签名。
+	Signed bool `json:"signed"`
+	// A1、UserAgent 为签名页面的 a1 与 User-Agent，后续步骤须使用相同的值。
+	A1        string `json:"a1,omitempty"`
+	UserAgent string `json:"user_agent,omitempty"`
+}
+
+// validate 校验步骤及其所需字段，并补齐默认值。
+func (p *PublishParams) validate() error {
+	verr := &platform.ValidationError{}
+	switch p.Step {
+	case StepPermit:
+		if p.Scene == "" {
+			p.Scene = "image"
+		}
+		if p.Scene != "image" && p.Scene != "video" {
+			verr.Add("scene", "须为 image 或 video，实际为 %q", p.Scene)
+		}
+		if p.FileCount == 0 {
+			p.FileCount = 1
+		}
+		if p.FileCount < 1 || p.FileCount > maxPublishFiles {
+			verr.Add("file_count", "须为 1～%d，实际为 %d", maxPublishFiles, p.FileCount)
+		}
+	case StepUpload:
+		if p.FileID == "" {
+			verr.Add("file_id", "不能为空")
+		}
+		if p.Token == "" {
+			verr.Add("token", "不能为空")
+		}
+		if p.ContentType == "" {
+			p.ContentType = "application/octet-stream"
+		}
+	case StepPublish:
+		if p.Note == nil {
+			verr.Add("note", "不能为空")
+		}
+	default:
+		verr.Add("step", "须为 permit、upload 或 publish，实际为 %q", p.Step)
+	}
+	return verr.Err()
+}
+
+// creatorHeaders 为创作平台页面发出的请求携带的来源请求头。
+func creatorHeaders(profile Profile) map[string]string {
+	return map[string]string{"origin": profile.HomeURL, "referer": profile.HomeURL + "/"}
+}
+
+// RegisterPublishRoutes 在 router 下注册发布笔记的辅助接口 POST /publish：按步骤返回可直接发出的请求，
+// 需要签名的步骤（申请上传凭证、发布笔记）由 profile 站点（创作平台）的页面签名。
+func RegisterPublishRoutes(router gin.IRouter, profile Profile, signer platform.Signer, middlewares ...gin.HandlerFunc) {
+	router.Group("/", middlewares...).POST("/publish", func(c *gin.Context) {
+		var req PublishParams
+		if err := wire.Bind(c, &req); err != nil {
+			slog.Warn("/publish 参数解析失败", "err", err, "client_ip", c.ClientIP())
+			wire.Render(c, http.StatusBadRequest, gin.H{"error": "参数解析失败: " + err.Error()})
+			return
+		}
+		if err := req.validate(); err != nil {
+			wire.Render(c, http.StatusBadRequest, platform.ErrorBody(err))
+			return
+		}
+		if req.Step == StepUpload {
+			// 上传只校验凭证中的 token，请求体为文件内容（分片上传时为各分片），不参与签名
+			wire.Render(c, http.StatusOK, &PublishStep{
+				Step:    StepUpload,
+				Method:  http.MethodPut,
+				URL:     uploadBase + "/" + req.FileID,
+				Headers: map[string]string{"x-cos-security-token": req.Token, "content-type": req.ContentType},
+			})
+			return
+		}
+
+		params := SignParams{A1: req.A1, WebSession: req.WebSession, Timestamp: req.Timestamp}
+		out := &PublishStep{Step: req.Step, Signed: true}
+		switch req.Step {
+		case StepPermit:
+			query := url.Values{
+				"biz_name":   {"spectrum"},
+				"scene":      {req.Scene},
+				"file_count": {strconv.Itoa(req.FileCount)},
+				"version":    {"1"},
+				"source":     {"web"},
+			}
+			params.URI = permitPath + "?" + query.Encode()
+			out.Method, out.URL = http.MethodGet, profile.APIBase+params.URI
+		case StepPublish:
+			// 页面按 JSON.stringify 的结果签名，返回同一份字节避免调用方重新序列化后字段顺序不同；
+			// 不转义 HTML 字符，否则标题中的 & < > 与签名时的字节不一致
+			body, err := marshalJS(req.Note)
+			if err != nil {
+				wire.Render(c, http.StatusBadRequest, gin.H{"error": "note 序列化失败: " + err.Error()})
+				return
+			}
+			params.URI, params.Data = publishPath, req.Note
+			out.Method, out.URL, out.Body = http.MethodPost, ProfileWeb.APIBase+publishPath, string(body)
+		}
+		res, status, err := sign(c, profile, signer, params)
+		if err != nil {
+			wire.Render(c, status, platform.ErrorBody(err))
+			return
+		}
+		h := headersResponse(params, res, nil)
+		for k, v := range creatorHeaders(profile) {
+			h.Headers[k] = v
+		}
+		if out.Body != "" {
+			h.Headers["content-type"] = "application/json;charset=UTF-8"
+		}
+		out.Headers, out.Cookie = h.Headers, h.Cookie
+		out.A1, out.UserAgent = res.A1, res.UserAgent
+		wire.Render(c, http.StatusOK, out)
+	})
+}
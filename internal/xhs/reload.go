@@ -0,0 +1,87 @@
+package xhs
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"go_sign/internal/metrics"
+	"go_sign/internal/pagepool"
+)
+
+// siteDomain 返回站点首页的主域名，如 www.xiaohongshu.com 对应 xiaohongshu.com。
+func (p Profile) siteDomain() string {
+	u, err := url.Parse(p.HomeURL)
+	if err != nil {
+		return ""
+	}
+	labels := strings.Split(u.Hostname(), ".")
+	if len(labels) > 2 {
+		labels = labels[len(labels)-2:]
+	}
+	return strings.Join(labels, ".")
+}
+
+// redirectReason 判断页面地址是否已离开站点（跳转到站外、登录页、浏览器错误页或页面崩溃后的 about:blank），
+// 是则返回原因，否则返回空。验证码页由 checkCaptcha 处理，不视为离开站点。
+func (s *Signer) redirectReason(pageURL string) string {
+	if isCaptchaURL(pageURL) {
+		return ""
+	}
+	u, err := url.Parse(pageURL)
+	if err != nil || u.Scheme == "about" || u.Scheme == "chrome-error" {
+		return "页面为空白页或错误页"
+	}
+	host, domain := u.Hostname(), s.opts.Profile.siteDomain()
+	if host != domain && !strings.HasSuffix(host, "."+domain) {
+		return "页面跳转到站外"
+	}
+	if strings.Contains(u.Path, "login") {
+		return "页面跳转到登录页"
+	}
+	return ""
+}
+
+// recoverRedirects 检查全部空闲槽位，页面已离开站点时记录跳转链并重新加载首页。
+// 正在签名的槽位跳过，留待下次检查。
+func (s *Signer) recoverRedirects(ctx context.Context) {
+	for _, pool := range s.pools {
+		for _, slot := range pool.AllSlots() {
+			reason := s.redirectReason(slot.Page.URL())
+			if reason == "" || !pool.TryAcquire(slot) {
+				continue
+			}
+			_ = s.reloadSlot(ctx, slot, reason, redirectRecoveries)
+			pool.Release(slot)
+		}
+	}
+}
+
+// defaultReloadReadyTimeout 为重新加载首页后等待签名函数就绪的默认时长。
+const defaultReloadReadyTimeout = 10 * time.Second
+
+// reloadSlot 将槽位页面重新加载到首页并等待签名函数就绪，计入 counter 并触发 OnRestart。
+// 未配置 SignFuncTimeout 时至多等待 defaultReloadReadyTimeout。
+func (s *Signer) reloadSlot(ctx context.Context, slot *pagepool.Slot, reason string, counter *metrics.CounterVec) error {
+	id := slot.ContextID()
+	log := slog.With("profile", s.opts.Profile.Name, "context_id", id)
+	log.Warn("重新加载首页", "reason", reason, "url", slot.Page.URL(), "redirect_chain", slot.Navigations())
+	o := s.openOptions()
+	if o.ReadyTimeout <= 0 {
+		o.ReadyTimeout = defaultReloadReadyTimeout
+	}
+	err := slot.Navigate(ctx, o, log)
+	result := "success"
+	if err != nil {
+		result = "error"
+		log.Error("重新加载首页失败", "err", err, "reason", reason)
+	} else {
+		s.detectScriptVersion(slot)
+		s.cacheScript(ctx, slot)
+	}
+	counter.Inc(s.opts.Profile.PlatformName(), result)
+	s.restarted(RestartEvent{Profile: s.opts.Profile.Name, ContextID: id, Reason: reason + "，重新加载首页", Err: err})
+	return err
+}
@@ -3,136 +3,461 @@ package xhs
 
 import (
 	"context"
-	"errors"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net/url"
 	"os"
-	"sync"
+	"strings"
+	"time"
 
 	"github.com/mxschmitt/playwright-go"
+	"go_sign/internal/browser"
+	"go_sign/internal/config"
+	"go_sign/internal/geo"
+	"go_sign/internal/pagepool"
+	"go_sign/internal/platform"
+	"go_sign/internal/timing"
 )
 
-// Signer 封装了 Playwright 浏览器上下文和页面，用于生成小红书签名。
+// Signer 封装了 Playwright 浏览器及各租户的页面池，用于生成小红书签名。
 type Signer struct {
-	pw        *playwright.Playwright
-	browser   playwright.Browser
-	context   playwright.BrowserContext
-	page      playwright.Page
-	stealthJS string
-	initOnce  sync.Once
-	initErr   error
+	browser     *browser.Browser
+	ownsBrowser bool // browser 由本实例启动，Close 时一并关闭
+	opts        Options
+	pools       pagepool.Set
+	pacer       *pacer
+	bans        *banTracker
+	scores      accountScores
+	usage       accountUsage
+	versions    scriptVersions
+	fallback    *fallback // 浏览器不可用时的降级签名，未配置时为 nil
 }
 
-// NewSigner 创建一个新的 Signer 实例。
-// stealthJSPath 为 stealth.min.js 的文件路径。
-func NewSigner(ctx context.Context, stealthJSPath string) (*Signer, error) {
-	var s Signer
-	var err error
+// 首页导航等待策略，对应 Playwright Goto 的 waitUntil 取值。
+const (
+	WaitUntilLoad             = "load"
+	WaitUntilDOMContentLoaded = "domcontentloaded"
+	WaitUntilNetworkIdle      = "networkidle"
+)
 
-	s.initOnce.Do(func() {
-		s.stealthJS = stealthJSPath
-		slog.Info("启动 Playwright...")
-		// 启动 Playwright
-		s.pw, err = playwright.Run()
-		if err != nil {
-			s.initErr = fmt.Errorf("启动 Playwright 失败: %w", err)
-			slog.Error("Playwright 启动失败", "err", err)
-			return
-		}
-		slog.Info("启动 Chromium...")
-		s.browser, err = s.pw.Chromium.Launch(playwright.BrowserTypeLaunchOptions{
-			Headless: playwright.Bool(true),
-		})
-		if err != nil {
-			s.initErr = fmt.Errorf("启动 Chromium 失败: %w", err)
-			slog.Error("Chromium 启动失败", "err", err)
-			return
-		}
-		s.context, err = s.browser.NewContext()
-		if err != nil {
-			s.initErr = fmt.Errorf("创建浏览器上下文失败: %w", err)
-			slog.Error("创建浏览器上下文失败", "err", err)
-			return
+// Options 定义 Signer 的初始化配置。
+type Options struct {
+	// Profile 为签名站点，为空时使用 ProfileWeb。
+	Profile Profile
+	// Browser 为共享的浏览器实例，为空时 Signer 自行启动并在 Close 时关闭。
+	Browser *browser.Browser
+	// StealthPath 为 stealth.min.js 的文件路径。
+	StealthPath string
+	// WarmupURLs 为预热时在访问 Profile.HomeURL 之前依次访问的页面，如发现页、笔记页，
+	// 用于降低新访客特征并填充 x-s-common 所需的 localStorage。
+	WarmupURLs []string
+	// Devices 为各浏览器上下文模拟的设备，编号为 i 的槽位使用 Devices[i % len(Devices)]，
+	// 为空时使用 Profile.Device。绑定到某一槽位的 API Key 固定使用该槽位的设备。
+	Devices []*browser.Device
+	// Proxies 为各浏览器上下文的出口代理，编号为 i 的槽位使用 Proxies[i % len(Proxies)]，为空时直连。
+	Proxies []*url.URL
+	// Timezone 与 Locale 为浏览器上下文模拟的时区与语言，为空时使用浏览器默认值。
+	Timezone string
+	Locale   string
+	// Geo 非 nil 时，创建使用代理的上下文前检查出口位置与 Timezone、Locale 是否一致，不一致时告警或调整。
+	Geo *geo.Checker
+	// ExtraHeaders 为浏览器上下文发出的每个请求附加的请求头，使页面请求与调用方携带签名发出的请求一致，
+	// 如 Accept-Language: zh-CN 与 sec-ch-ua；为空时使用浏览器默认值。
+	ExtraHeaders map[string]string
+	// Behavior 为预热时每次导航后模拟的真人浏览行为，为 nil 时不模拟。
+	Behavior *pagepool.Behavior
+	// HeaderTemplates 非 nil 时在每个页面上记录发往 Profile.APIBase 的请求的请求头，见 HeaderTemplates。
+	HeaderTemplates *HeaderTemplates
+	// CanaryStealthPath 为灰度 stealth.js 的文件路径，非空时每个租户额外预热一组注入该脚本的页面，
+	// 并将 CanaryWeight 比例的请求分流到这组页面。
+	CanaryStealthPath string
+	CanaryWeight      float64
+	// WaitUntil 为首页导航的等待策略，为空时使用 domcontentloaded。
+	// 签名函数通常在页面完全加载前就已可用，无需等待 load 或 networkidle。
+	WaitUntil string
+	// NavigationTimeout 为首页导航超时时间，0 表示使用 Playwright 默认值（30s）。
+	NavigationTimeout time.Duration
+	// SignFuncTimeout 为导航完成后等待签名函数就绪的最长时间，0 表示不等待。
+	SignFuncTimeout time.Duration
+	// PoolSize 为页面池大小，每个页面独占一个浏览器上下文，默认为 1。
+	PoolSize int
+	// WarmupConcurrency 为启动预热时并发初始化页面的上限，默认为 4。
+	WarmupConcurrency int
+	// Tenants 为各租户的页面池配置，为空时只创建 config.DefaultTenant 一个页面池。
+	// 不同租户的页面使用独立的浏览器上下文，cookie 与存储互不可见。
+	Tenants []TenantOptions
+	// PacePerMinute 为同一 a1 每分钟最多签名次数，超出部分匀速排队，0 表示不限制。
+	PacePerMinute int
+	// PaceJitter 为每次签名前额外叠加的随机延迟上限，0 表示不加抖动。
+	PaceJitter time.Duration
+	// PaceMaxWait 为节流允许的最长等待，超过时返回 ErrRateLimited，0 表示不限制。
+	PaceMaxWait time.Duration
+	// ShedBudget 为页面池的排队预算，超出时提前拒绝低优先级请求，0 表示不限制。
+	ShedBudget time.Duration
+	// BanThreshold 为 BanWindow 内同一 a1 收到风控响应（461、406，见 ReportStatus）的次数上限，
+	// 达到时该 a1 冷却 BanCooldown；BanThreshold 或 BanCooldown 为 0 时不冷却。
+	BanThreshold int
+	BanWindow    time.Duration
+	BanCooldown  time.Duration
+	// AccountLimits 为同一 a1 在各时间窗口内的签名次数上限，超出时返回 ErrAccountLimited。
+	AccountLimits platform.AccountLimits
+	// Fallback 为浏览器不可用时的降级签名配置，为 nil 时不降级。
+	Fallback *FallbackOptions
+	// Hooks 为生命周期事件的回调。
+	Hooks Hooks
+}
+
+// TenantOptions 定义单个租户的页面池配置。
+type TenantOptions struct {
+	Name string
+	// PoolSize 为租户独占的页面池大小，0 表示使用 Options.PoolSize。
+	PoolSize int
+}
+
+// validWaitUntil 判断导航等待策略是否合法。
+func validWaitUntil(v string) bool {
+	switch v {
+	case WaitUntilLoad, WaitUntilDOMContentLoaded, WaitUntilNetworkIdle:
+		return true
+	}
+	return false
+}
+
+// NewSigner 创建一个新的 Signer 实例。
+// opts 为初始化配置，StealthPath 必填。
+func NewSigner(ctx context.Context, opts Options) (*Signer, error) {
+	if opts.Profile.Name == "" {
+		opts.Profile = ProfileWeb
+	}
+	if opts.WaitUntil == "" {
+		opts.WaitUntil = WaitUntilDOMContentLoaded
+	}
+	if !validWaitUntil(opts.WaitUntil) {
+		return nil, fmt.Errorf("不支持的导航等待策略: %s", opts.WaitUntil)
+	}
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = 1
+	}
+	if opts.WarmupConcurrency <= 0 {
+		opts.WarmupConcurrency = 4
+	}
+	if len(opts.Tenants) == 0 {
+		opts.Tenants = []TenantOptions{{Name: config.DefaultTenant}}
+	}
+	for i := range opts.Tenants {
+		if opts.Tenants[i].PoolSize <= 0 {
+			opts.Tenants[i].PoolSize = opts.PoolSize
 		}
-		// 注入 stealth.js
-		if _, err := os.Stat(stealthJSPath); err != nil {
-			s.initErr = fmt.Errorf("stealth.js 文件不存在: %w", err)
-			slog.Error("stealth.js 文件不存在", "path", stealthJSPath, "err", err)
-			return
+	}
+	if _, err := os.Stat(opts.StealthPath); err != nil {
+		slog.Error("stealth.js 文件不存在", "path", opts.StealthPath, "err", err)
+		return nil, fmt.Errorf("stealth.js 文件不存在: %w", err)
+	}
+
+	s := &Signer{
+		browser: opts.Browser,
+		opts:    opts,
+		pacer:   newPacer(opts.PacePerMinute, opts.PaceJitter, opts.PaceMaxWait),
+		bans:    newBanTracker(opts.BanThreshold, opts.BanWindow, opts.BanCooldown),
+		usage:   accountUsage{limits: opts.AccountLimits},
+	}
+	if opts.Fallback != nil {
+		s.fallback = newFallback(*opts.Fallback, opts.Profile.PlatformName(), opts.Profile.SignFunc)
+	}
+	var err error
+	if s.browser == nil {
+		if s.browser, err = browser.Launch(); err != nil {
+			return nil, err
 		}
-		slog.Info("注入 stealth.js", "path", stealthJSPath)
-		err = s.context.AddInitScript(playwright.BrowserContextAddInitScriptOptions{
-			Path: playwright.String(stealthJSPath),
+		s.ownsBrowser = true
+	}
+
+	start := time.Now()
+	tenants := make([]pagepool.Tenant, 0, len(opts.Tenants))
+	for _, t := range opts.Tenants {
+		tenants = append(tenants, pagepool.Tenant{
+			Name:       t.Name,
+			Size:       t.PoolSize,
+			Canary:     pagepool.NewCanary(opts.CanaryStealthPath, opts.CanaryWeight, t.PoolSize),
+			ShedBudget: opts.ShedBudget,
 		})
-		if err != nil {
-			s.initErr = fmt.Errorf("注入 stealth.js 失败: %w", err)
-			slog.Error("注入 stealth.js 失败", "err", err)
-			return
-		}
-		// 新建页面并访问小红书首页
-		s.page, err = s.context.NewPage()
-		if err != nil {
-			s.initErr = fmt.Errorf("新建页面失败: %w", err)
-			slog.Error("新建页面失败", "err", err)
-			return
-		}
-		slog.Info("跳转小红书首页...")
-		if _, err = s.page.Goto("https://www.xiaohongshu.com"); err != nil {
-			s.initErr = fmt.Errorf("跳转小红书首页失败: %w", err)
-			slog.Error("跳转小红书首页失败", "err", err)
-			return
+	}
+	if s.pools, err = pagepool.Warmup(ctx, opts.Profile.PlatformName(), tenants, opts.WarmupConcurrency, s.newSlot); err != nil {
+		_ = s.Close()
+		return nil, err
+	}
+	// 优先分配页面自身 a1 健康评分更高的页面
+	s.pools.SetRank(s.rankSlot)
+	if h := opts.Hooks.OnInit; h != nil {
+		pages := 0
+		for _, pool := range s.pools {
+			pages += len(pool.AllSlots())
 		}
-		// 打印 a1 cookie
-		cookies, err := s.context.Cookies()
-		if err == nil {
-			for _, c := range cookies {
-				if c.Name == "a1" {
-					slog.Info("当前浏览器 cookie 中 a1 值", "a1", c.Value)
-				}
+		h(InitEvent{Profile: opts.Profile.Name, Pages: pages, Elapsed: time.Since(start)})
+	}
+	return s, nil
+}
+
+// openOptions 返回创建槽位与重新加载首页的参数。
+func (s *Signer) openOptions() pagepool.OpenOptions {
+	o := pagepool.OpenOptions{
+		Platform:          s.opts.Profile.PlatformName(),
+		Context:           playwright.BrowserNewContextOptions{ExtraHTTPHeaders: s.opts.ExtraHeaders},
+		Device:            s.opts.Profile.Device,
+		Timezone:          s.opts.Timezone,
+		Locale:            s.opts.Locale,
+		Geo:               s.opts.Geo,
+		StealthPath:       s.opts.StealthPath,
+		URL:               s.opts.Profile.HomeURL,
+		WarmupURLs:        s.opts.WarmupURLs,
+		Behavior:          s.opts.Behavior,
+		WaitUntil:         s.opts.WaitUntil,
+		NavigationTimeout: s.opts.NavigationTimeout,
+		ReadyJS:           signFuncExistsJS,
+		ReadyArg:          s.opts.Profile.SignFunc,
+		ReadyTimeout:      s.opts.SignFuncTimeout,
+	}
+	if t := s.opts.HeaderTemplates; t != nil {
+		o.OnPage = t.observe
+	}
+	return o
+}
+
+// newSlot 为租户创建一个浏览器上下文与页面，注入 stealth.js 并跳转站点首页。
+func (s *Signer) newSlot(ctx context.Context, tenant string, id int) (*pagepool.Slot, error) {
+	o := s.openOptions()
+	if n := len(s.opts.Devices); n > 0 {
+		o.Device = s.opts.Devices[id%n]
+	}
+	if n := len(s.opts.Proxies); n > 0 {
+		o.Proxy = s.opts.Proxies[id%n]
+	}
+	slot, err := pagepool.Open(ctx, s.browser, tenant, id, o)
+	if err != nil {
+		return nil, err
+	}
+	// 打印 a1 cookie
+	log := slog.With("profile", s.opts.Profile.Name, "tenant", tenant, "slot", id)
+	cookies, err := slot.Context.Cookies()
+	if err == nil {
+		for _, c := range cookies {
+			if c.Name == "a1" {
+				slot.Identity = c.Value
+				log.Info("当前浏览器 cookie 中 a1 值", "a1", c.Value)
 			}
-		} else {
-			slog.Warn("获取 cookie 失败", "err", err)
 		}
-	})
-	if s.initErr != nil {
-		return nil, s.initErr
+	} else {
+		log.Warn("获取 cookie 失败", "err", err)
 	}
-	return &s, nil
+	if ua, err := slot.Page.Evaluate(`() => navigator.userAgent`); err == nil {
+		slot.UserAgent, _ = ua.(string)
+	} else {
+		log.Warn("读取页面 User-Agent 失败", "err", err)
+	}
+	s.detectScriptVersion(slot)
+	s.cacheScript(ctx, slot)
+	return slot, nil
 }
 
+// ErrSignFuncMissing 表示页面上的签名函数未定义，通常是页面被站点跳转或刷新，或未注入签名 JS。
+var ErrSignFuncMissing = errors.New("未定义或未注入签名 JS")
+
+// signFuncExistsJS 检查 window 上的签名函数是否存在，参数为函数名。
+const signFuncExistsJS = `(fn) => typeof window[fn] === 'function'`
+
 // SignParams 定义签名所需的参数。
 type SignParams struct {
 	URI        string `json:"uri"`
 	Data       any    `json:"data"`
 	A1         string `json:"a1"`
 	WebSession string `json:"web_session"`
+	// Timestamp 为固定的 x-t（毫秒），0 表示使用签名端当前时间。
+	Timestamp int64 `json:"timestamp,omitempty"`
 }
 
 // SignResult 定义签名结果。
 type SignResult struct {
 	XS string `json:"x-s"`
 	XT string `json:"x-t"`
+	// XSCommon 为根据 a1、x-s、x-t 与页面存储生成的 x-s-common 请求头。
+	XSCommon string `json:"x-s-common,omitempty"`
+	// ServerTime 为签名端返回结果时的毫秒时间，调用方可据此估算与签名端的时钟偏差。
+	ServerTime int64 `json:"server_time,omitempty"`
+	// A1 为签名页面 cookie 中的 a1，x-s 基于该值生成，可能与请求中的 a1 不同；
+	// 请求需携带该 a1 与 UserAgent 才能通过校验。
+	A1 string `json:"a1,omitempty"`
+	// ContextID 为签名使用的浏览器上下文，格式为 <租户>/<分组>/<槽位>。
+	ContextID string `json:"context_id,omitempty"`
+	// UserAgent 为签名页面的 User-Agent。
+	UserAgent string `json:"user_agent,omitempty"`
+	// URI 为实际参与签名的规范化 uri。
+	URI string `json:"uri,omitempty"`
+	// ScriptVersion 为签名页面加载的签名脚本的版本指纹，站点更新签名算法时随之变化。
+	ScriptVersion string `json:"script_version,omitempty"`
+	// Timing 为请求带 ?debug=timing 时的耗时分解。
+	Timing *timing.Breakdown `json:"timing,omitempty"`
 }
 
-// Sign 调用页面 JS 生成签名。
+// normalizeURI 规范化待签名的 uri：去除首尾空白，完整 URL 只保留路径与查询串，并确保以 / 开头。
+func normalizeURI(uri string) string {
+	uri = strings.TrimSpace(uri)
+	if u, err := url.Parse(uri); err == nil && u.Host != "" {
+		uri = u.RequestURI()
+	}
+	if !strings.HasPrefix(uri, "/") {
+		uri = "/" + uri
+	}
+	return uri
+}
+
+// Sign 从 ctx 所属租户的页面池取出一个页面并调用页面 JS 生成签名。
+// 租户与优先级通过 platform.WithTenant、platform.WithPriority 写入 ctx。
 // uri: 请求路径，data: 请求数据，a1/web_session: 相关 cookie。
+// 配置了 Options.Fallback 且浏览器不可用时，改由内嵌的 JS 引擎执行缓存的签名脚本。
 func (s *Signer) Sign(ctx context.Context, params SignParams) (*SignResult, error) {
-	if s.page == nil {
-		slog.Error("页面未初始化，无法签名")
-		return nil, errors.New("页面未初始化")
+	params.URI = normalizeURI(params.URI)
+	res, err := s.signInBrowser(ctx, params, nil)
+	if err != nil {
+		return s.signFallback(ctx, params, err)
+	}
+	return res, nil
+}
+
+// acquirePaced 从 pool 取出一个页面自身 a1 已到节流时间点的页面，并为该 a1 计入一次签名用量。
+// 取到的页面还需等待时推迟归还（pool.Defer）后重新排队，等待期间不占用页面、其他账号的页面照常分配；
+// 累计等待超过 --pace-max-wait 时返回 ErrRateLimited。a1 超出签名次数上限时撤销本次节流预留并返回 ErrAccountLimited。
+func (s *Signer) acquirePaced(ctx context.Context, pool *pagepool.Pool, tenant, uri string) (*pagepool.Slot, error) {
+	start := time.Now()
+	paced := false
+	for {
+		slot, err := pool.Acquire(ctx)
+		if err != nil {
+			slog.Warn("等待空闲页面失败", "err", err, "uri", uri, "tenant", tenant)
+			return nil, fmt.Errorf("等待空闲页面失败: %w", err)
+		}
+		a1 := slot.Identity
+		wait, undo := s.pacer.reserve(a1)
+		if wait > 0 {
+			pool.Defer(slot, time.Now().Add(wait))
+			if maxWait := s.pacer.maxWait; maxWait > 0 && time.Since(start)+wait > maxWait {
+				pacedRejects.Inc(tenant)
+				slog.Warn("a1 节流等待超出上限", "uri", uri, "tenant", tenant, "a1", a1, "wait", wait)
+				return nil, fmt.Errorf("a1 节流等待失败: %w", ErrRateLimited)
+			}
+			paced = true
+			continue
+		}
+		if paced {
+			paceWaitSeconds.Observe(time.Since(start).Seconds(), tenant)
+		}
+		if window, err := s.usage.acquire(a1, time.Now()); err != nil {
+			undo()
+			pool.Release(slot)
+			accountLimited.Inc(s.opts.Profile.PlatformName(), window)
+			slog.Warn("a1 签名次数超出上限", "err", err, "uri", uri, "tenant", tenant, "a1", a1)
+			return nil, err
+		}
+		return slot, nil
+	}
+}
+
+// signInBrowser 在 ctx 所属租户的页面上签名。then 非 nil 时在签名成功后、归还页面前以同一页面调用，其错误原样返回。
+func (s *Signer) signInBrowser(ctx context.Context, params SignParams, then func(*pagepool.Slot, *SignResult) error) (*SignResult, error) {
+	tenant := platform.TenantFrom(ctx)
+	pool, err := s.pools.For(ctx)
+	if err != nil {
+		slog.Error("租户页面未初始化，无法签名", "tenant", tenant)
+		return nil, err
+	}
+	// x-s 基于页面自身 cookie 中的 a1 生成，节流、用量与上限均按页面的 a1 计
+	slot, err := s.acquirePaced(ctx, pool, tenant, params.URI)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	res, err := signOnPage(ctx, slot.Page, s.opts.Profile.SignFunc, params)
+	switch {
+	case errors.Is(err, ErrSignFuncMissing):
+		// 页面被站点跳转或刷新后签名函数丢失：重新加载首页并重试一次
+		if rerr := s.reloadSlot(ctx, slot, "签名函数丢失", signFuncReloads); rerr == nil {
+			res, err = signOnPage(ctx, slot.Page, s.opts.Profile.SignFunc, params)
+		}
+	case errors.Is(err, ErrInvalidResult):
+		// 签名函数返回空值或格式不符，页面状态可能已损坏：重新加载首页并重试一次
+		if rerr := s.reloadSlot(ctx, slot, "签名结果无效", invalidResultReloads); rerr == nil {
+			res, err = signOnPage(ctx, slot.Page, s.opts.Profile.SignFunc, params)
+		}
+	}
+	s.usage.record(slot.Identity, time.Since(start), err)
+	if err != nil {
+		s.signFailed(ctx, pool, slot, params.URI, err)
+		return nil, err
+	}
+	res.A1, res.ContextID, res.UserAgent, res.URI = slot.Identity, slot.ContextID(), slot.UserAgent, params.URI
+	res.ScriptVersion = slot.ScriptVersion()
+	if st, err := readPageStorage(slot.Page); err == nil {
+		res.XSCommon = xsCommon(res.A1, res.UserAgent, res.XS, res.XT, st)
+	} else {
+		slog.Warn("读取页面存储失败，不生成 x-s-common", "err", err, "context_id", res.ContextID)
 	}
-	slog.Info("执行签名 JS", "uri", params.URI)
+	if then != nil {
+		err = then(slot, res)
+	}
+	pool.Done(slot, nil)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// readPageStorage 读取签名页面中参与 x-s-common 的存储字段。
+func readPageStorage(page playwright.Page) (pageStorage, error) {
+	var st pageStorage
+	v, err := page.Evaluate(`() => ` + pageStorageJS)
+	if err != nil {
+		return st, err
+	}
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return st, err
+	}
+	err = json.Unmarshal(raw, &st)
+	return st, err
+}
+
+// HealthCheck 逐个检查各租户页面池中一个空闲页面的签名函数是否可用，
+// 签名函数可用但有页面停留在验证码页时视为降级。检查前先将离开站点的空闲页面重新加载到首页。
+func (s *Signer) HealthCheck(ctx context.Context) error {
+	s.recoverRedirects(ctx)
+	if err := s.pools.Check(ctx, signFuncExistsJS, s.opts.Profile.SignFunc); err != nil {
+		if s.fallback != nil && s.fallback.available() && !s.browserConnected(ctx) {
+			return fmt.Errorf("%w: 浏览器不可用，使用缓存的签名脚本降级签名: %v", platform.ErrDegraded, err)
+		}
+		return err
+	}
+	return s.captchaError()
+}
+
+// Profile 返回 Signer 对应的签名站点。
+func (s *Signer) Profile() Profile {
+	return s.opts.Profile
+}
+
+// signOnPage 在指定页面上调用 window[fn] 执行签名 JS。
+func signOnPage(ctx context.Context, page playwright.Page, fn string, params SignParams) (*SignResult, error) {
+	slog.Info("执行签名 JS", "uri", params.URI, "sign_func", fn)
 
-	// 1. 检查 window._webmsxyw 是否存在
-	exists, err := s.page.Evaluate("() => typeof window._webmsxyw === 'function'", nil)
+	// 1. 检查签名函数是否存在
+	exists, err := page.Evaluate(signFuncExistsJS, fn)
 	if err != nil {
-		slog.Error("检查 window._webmsxyw 失败", "err", err)
-		return nil, fmt.Errorf("检查 window._webmsxyw 失败: %w", err)
+		slog.Error("检查签名函数失败", "err", err, "sign_func", fn)
+		return nil, fmt.Errorf("检查 window.%s 失败: %w", fn, err)
 	}
 	if exists != true {
-		slog.Error("window._webmsxyw 未定义或未注入签名 JS")
-		return nil, errors.New("window._webmsxyw 未定义或未注入签名 JS")
+		slog.Error("签名函数未定义或未注入签名 JS", "sign_func", fn, "url", page.URL())
+		return nil, fmt.Errorf("window.%s %w", fn, ErrSignFuncMissing)
 	}
 
 	// 2. data 参数序列化为 JSON 字符串
@@ -142,9 +467,9 @@ func (s *Signer) Sign(ctx context.Context, params SignParams) (*SignResult, erro
 		return nil, fmt.Errorf("data 参数序列化失败: %w", err)
 	}
 
-	// 3. JS 端用 JSON.parse 还原 data
-	js := `([url, dataStr]) => window._webmsxyw(url, JSON.parse(dataStr))`
-	res, err := s.page.Evaluate(js, []any{params.URI, string(dataJSON)})
+	// 3. JS 端用 JSON.parse 还原 data，指定 timestamp 时固定页面时钟
+	js := pagepool.WithClock(`([fn, url, dataStr]) => window[fn](url, JSON.parse(dataStr))`)
+	res, err := pagepool.Evaluate(ctx, page, js, []any{params.Timestamp, []any{fn, params.URI, string(dataJSON)}})
 	if err != nil {
 		slog.Error("执行签名 JS 失败", "err", err, "uri", params.URI, "data", string(dataJSON))
 		return nil, fmt.Errorf("执行签名 JS 失败: %w", err)
@@ -154,46 +479,28 @@ func (s *Signer) Sign(ctx context.Context, params SignParams) (*SignResult, erro
 		slog.Error("签名结果类型断言失败", "res", res)
 		return nil, errors.New("签名结果类型断言失败")
 	}
-	xs, _ := m["X-s"].(string)
-	xt, _ := m["X-t"].(string)
-	slog.Info("签名成功", "x-s", xs, "x-t", xt, "uri", params.URI)
-	return &SignResult{XS: xs, XT: xt}, nil
+	out := signResultOf(m)
+	if err := out.validate(params.Timestamp); err != nil {
+		slog.Error("签名结果无效", "err", err, "uri", params.URI, "x-s", out.XS, "x-t", out.XT)
+		return nil, err
+	}
+	slog.Info("签名成功", "x-s", out.XS, "x-t", out.XT, "uri", params.URI)
+	return out, nil
 }
 
 // Close 释放 Playwright 相关资源，防止资源泄漏。
 // 应在服务优雅退出时调用。
 func (s *Signer) Close() error {
 	var firstErr error
-	if s.page != nil {
-		if err := s.page.Close(); err != nil {
-			slog.Warn("关闭页面失败", "err", err)
-			if firstErr == nil {
-				firstErr = fmt.Errorf("关闭页面失败: %w", err)
-			}
-		}
+	if err := s.pools.Close(); err != nil {
+		firstErr = err
 	}
-	if s.context != nil {
-		if err := s.context.Close(); err != nil {
-			slog.Warn("关闭浏览器上下文失败", "err", err)
-			if firstErr == nil {
-				firstErr = fmt.Errorf("关闭浏览器上下文失败: %w", err)
-			}
-		}
-	}
-	if s.browser != nil {
-		if err := s.browser.Close(); err != nil {
-			slog.Warn("关闭浏览器失败", "err", err)
-			if firstErr == nil {
-				firstErr = fmt.Errorf("关闭浏览器失败: %w", err)
-			}
-		}
+	if s.fallback != nil {
+		_ = s.fallback.close()
 	}
-	if s.pw != nil {
-		if err := s.pw.Stop(); err != nil {
-			slog.Warn("关闭 Playwright 失败", "err", err)
-			if firstErr == nil {
-				firstErr = fmt.Errorf("关闭 Playwright 失败: %w", err)
-			}
+	if s.ownsBrowser && s.browser != nil {
+		if err := s.browser.Close(); err != nil && firstErr == nil {
+			firstErr = err
 		}
 	}
 	return firstErr
@@ -0,0 +1,153 @@
+package xhs
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go_sign/internal/platform"
+)
+
+// ErrAccountLimited 表示同一 a1 在当前分钟、小时或天内的签名次数已达到 Options.AccountLimits 的上限。
+var ErrAccountLimited = errors.New("账号签名次数超出上限")
+
+// usageWindow 为一个自然时间窗口内的签名计数。
+type usageWindow struct {
+	start time.Time
+	n     int
+}
+
+// roll 在窗口起点变化时清零计数。
+func (w *usageWindow) roll(start time.Time) {
+	if !w.start.Equal(start) {
+		w.start, w.n = start, 0
+	}
+}
+
+// accountUse 为单个 a1 的签名用量。
+type accountUse struct {
+	minute, hour, day usageWindow
+	signs, errors     int
+	limited           int
+	latency           time.Duration // 累计签名耗时
+	maxLatency        time.Duration
+	lastUsed          time.Time
+}
+
+// accountUsage 按 a1 统计签名用量，并按 limits 限制各自然分钟、小时、天内的签名次数。
+type accountUsage struct {
+	limits platform.AccountLimits
+
+	mu   sync.Mutex
+	uses map[string]*accountUse
+}
+
+// windowStarts 返回 now 所在的自然分钟、小时与天（本地时区）的起点。
+func windowStarts(now time.Time) (minute, hour, day time.Time) {
+	y, m, d := now.Date()
+	return now.Truncate(time.Minute), now.Truncate(time.Hour), time.Date(y, m, d, 0, 0, 0, 0, now.Location())
+}
+
+// acquire 为 a1 计入一次签名，任一窗口已达上限时不计入并返回 ErrAccountLimited，window 为触发上限的窗口
+// （minute、hour 或 day）。a1 为空时不统计。
+func (u *accountUsage) acquire(a1 string, now time.Time) (window string, err error) {
+	if a1 == "" {
+		return "", nil
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.uses == nil {
+		u.uses = make(map[string]*accountUse)
+	}
+	use := u.uses[a1]
+	if use == nil {
+		u.gc(now)
+		use = &accountUse{}
+		u.uses[a1] = use
+	}
+	minute, hour, day := windowStarts(now)
+	use.minute.roll(minute)
+	use.hour.roll(hour)
+	use.day.roll(day)
+	for _, w := range []struct {
+		name, unit string
+		n, limit   int
+	}{
+		{"minute", "分钟", use.minute.n, u.limits.PerMinute},
+		{"hour", "小时", use.hour.n, u.limits.PerHour},
+		{"day", "天", use.day.n, u.limits.PerDay},
+	} {
+		if w.limit > 0 && w.n >= w.limit {
+			use.limited++
+			return w.name, fmt.Errorf("%w: 每%s至多 %d 次", ErrAccountLimited, w.unit, w.limit)
+		}
+	}
+	use.minute.n++
+	use.hour.n++
+	use.day.n++
+	use.lastUsed = now
+	return "", nil
+}
+
+// record 记录 a1 一次签名的耗时与结果。
+func (u *accountUsage) record(a1 string, d time.Duration, err error) {
+	if a1 == "" {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	use := u.uses[a1]
+	if use == nil {
+		return
+	}
+	use.signs++
+	if err != nil {
+		use.errors++
+	}
+	use.latency += d
+	use.maxLatency = max(use.maxLatency, d)
+}
+
+// gc 在持有锁的前提下清理一天以上未使用的 a1 记录，避免 map 无限增长。
+func (u *accountUsage) gc(now time.Time) {
+	if len(u.uses) < 1024 {
+		return
+	}
+	for a1, use := range u.uses {
+		if now.Sub(use.lastUsed) > 24*time.Hour {
+			delete(u.uses, a1)
+		}
+	}
+}
+
+// snapshot 返回各 a1 的用量，窗口起点已过期的计数视为 0。
+func (u *accountUsage) snapshot(now time.Time) map[string]*platform.AccountUsage {
+	minute, hour, day := windowStarts(now)
+	count := func(w usageWindow, start time.Time) int {
+		if w.start.Equal(start) {
+			return w.n
+		}
+		return 0
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	out := make(map[string]*platform.AccountUsage, len(u.uses))
+	for a1, use := range u.uses {
+		v := &platform.AccountUsage{
+			Minute:       count(use.minute, minute),
+			Hour:         count(use.hour, hour),
+			Day:          count(use.day, day),
+			Signs:        use.signs,
+			Errors:       use.errors,
+			Limited:      use.limited,
+			MaxLatencyMs: float64(use.maxLatency) / float64(time.Millisecond),
+			LastUsed:     use.lastUsed,
+		}
+		if use.signs > 0 {
+			v.AvgLatencyMs = float64(use.latency) / float64(use.signs) / float64(time.Millisecond)
+		}
+		out[a1] = v
+	}
+	return out
+}
@@ -0,0 +1,102 @@
+package xhs
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go_sign/internal/platform"
+)
+
+// MaxDataSize 为 data 编码为 JSON 后允许的最大字节数。
+const MaxDataSize = 1 << 20
+
+// a1Pattern 为 a1 cookie 的格式：小写字母与数字，长度 40～64（站点生成的 a1 一般为 52 位）。
+var a1Pattern = regexp.MustCompile(`^[0-9a-z]{40,64}$`)
+
+// Validate 在签名前校验参数：uri 须为 /api/ 或 /web_api/ 下的接口路径（可为完整 URL），a1 须符合站点格式，
+// data 须可编码为 JSON 且不超过 MaxDataSize。校验失败时返回 *platform.ValidationError。
+func (p SignParams) Validate() error {
+	verr := &platform.ValidationError{}
+	uri := strings.TrimSpace(p.URI)
+	switch {
+	case uri == "":
+		verr.Add("uri", "不能为空")
+	case !strings.HasPrefix(normalizeURI(uri), "/api/") && !strings.HasPrefix(normalizeURI(uri), "/web_api/"):
+		verr.Add("uri", "须为 /api/ 或 /web_api/ 开头的接口路径或完整 URL，实际为 %q", uri)
+	}
+	if p.A1 != "" && !a1Pattern.MatchString(p.A1) {
+		verr.Add("a1", "须为 40～64 位小写字母与数字，实际长度 %d", len(p.A1))
+	}
+	if p.Data != nil {
+		raw, err := json.Marshal(p.Data)
+		switch {
+		case err != nil:
+			verr.Add("data", "无法编码为 JSON: %v", err)
+		case len(raw) > MaxDataSize:
+			verr.Add("data", "编码后 %d 字节，超过上限 %d 字节", len(raw), MaxDataSize)
+		}
+	}
+	if err := platform.CheckTimestamp(p.Timestamp); err != nil {
+		verr.Add("timestamp", "%s", strings.TrimPrefix(err.Error(), "timestamp "))
+	}
+	return verr.Err()
+}
+
+// ErrInvalidResult 表示签名函数返回的结果不符合站点格式，见 platform.ErrInvalidResult。
+var ErrInvalidResult = platform.ErrInvalidResult
+
+// 签名结果的格式约束。
+const (
+	// xsPrefix 为 x-s 的前缀，其后为 base64 编码的签名载荷。
+	xsPrefix = "XYW_"
+	// minXSLength 为 x-s 的最短长度，站点生成的 x-s 一般在 200 字符以上。
+	minXSLength = 64
+	// maxResultSkew 为 x-t 与签名时间的最大允许偏差。
+	maxResultSkew = time.Minute
+)
+
+// signResultOf 从签名函数的返回值 m 中取出 x-s 与 x-t。页面的 _webmsxyw 以 JS 数字返回 X-t，
+// Playwright 将其解码为 float64，node 等后端可能为字符串或 json.Number，均转为十进制整数字符串。
+func signResultOf(m map[string]any) *SignResult {
+	xs, _ := m["X-s"].(string)
+	var xt string
+	switch v := m["X-t"].(type) {
+	case string:
+		xt = v
+	case float64:
+		xt = strconv.FormatInt(int64(v), 10)
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			xt = strconv.FormatInt(n, 10)
+		} else if f, err := v.Float64(); err == nil {
+			xt = strconv.FormatInt(int64(f), 10)
+		}
+	}
+	return &SignResult{XS: xs, XT: xt}
+}
+
+// validate 校验签名结果：x-s、x-t 均非空，x-t 为与签名时间（ts 非 0 时为 ts，否则为当前时间）相差不超过
+// maxResultSkew 的毫秒时间戳，x-s 以 xsPrefix 开头且不短于 minXSLength。校验失败时返回包装 ErrInvalidResult 的错误。
+func (r *SignResult) validate(ts int64) error {
+	switch {
+	case r.XS == "":
+		return fmt.Errorf("%w: x-s 为空", ErrInvalidResult)
+	case r.XT == "":
+		return fmt.Errorf("%w: x-t 为空", ErrInvalidResult)
+	}
+	xt, err := strconv.ParseInt(r.XT, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: x-t %q 不是毫秒时间戳", ErrInvalidResult, r.XT)
+	}
+	if skew := time.UnixMilli(xt).Sub(platform.SignTime(ts)); skew > maxResultSkew || skew < -maxResultSkew {
+		return fmt.Errorf("%w: x-t 与签名时间相差 %s", ErrInvalidResult, skew.Round(time.Second))
+	}
+	if !strings.HasPrefix(r.XS, xsPrefix) || len(r.XS) < minXSLength {
+		return fmt.Errorf("%w: x-s 格式不符（前缀 %q、长度 %d）", ErrInvalidResult, r.XS[:min(len(r.XS), len(xsPrefix))], len(r.XS))
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package xhs
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignResultNumericXT(t *testing.T) {
+	now := time.Now().UnixMilli()
+	xs := xsPrefix + strings.Repeat("a", minXSLength)
+	want := strconv.FormatInt(now, 10)
+	for _, xt := range []any{float64(now), json.Number(want), want} {
+		res := signResultOf(map[string]any{"X-s": xs, "X-t": xt})
+		if res.XT != want {
+			t.Errorf("X-t %T 解析为 %q，期望 %q", xt, res.XT, want)
+		}
+		if err := res.validate(0); err != nil {
+			t.Errorf("X-t %T 校验失败: %v", xt, err)
+		}
+	}
+	if err := signResultOf(map[string]any{"X-s": xs}).validate(0); !errors.Is(err, ErrInvalidResult) {
+		t.Errorf("缺少 X-t 应返回 ErrInvalidResult，实际 %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package xhs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"sync"
+
+	"go_sign/internal/metrics"
+	"go_sign/internal/pagepool"
+)
+
+// scriptPages 统计加载各版本签名脚本的页面数，便于将失败率上升与站点更新签名算法对应起来。
+var scriptPages = metrics.Default.NewGaugeVec(
+	"go_sign_sign_script_pages",
+	"加载各版本签名脚本的页面数，version 为签名函数源码的 SHA-256 前缀",
+	"platform", "version",
+)
+
+// signFuncSourceJS 返回 window 上签名函数的源码，参数为函数名，函数不存在时返回空串。
+const signFuncSourceJS = `(fn) => typeof window[fn] === 'function' ? Function.prototype.toString.call(window[fn]) : ''`
+
+// scriptVersionLen 为版本指纹保留的十六进制位数。
+const scriptVersionLen = 12
+
+// scriptVersions 记录已见过的签名脚本版本，首次出现新版本时告警。
+type scriptVersions struct {
+	mu   sync.Mutex
+	seen map[string]bool
+}
+
+// detectScriptVersion 读取槽位页面上签名函数的源码，以其 SHA-256 前缀作为版本指纹写入槽位。
+// 站点更新签名算法时函数源码随之变化，首次出现新版本时记录告警日志。
+func (s *Signer) detectScriptVersion(slot *pagepool.Slot) {
+	log := slog.With("profile", s.opts.Profile.Name, "context_id", slot.ContextID())
+	v, err := slot.Page.Evaluate(signFuncSourceJS, s.opts.Profile.SignFunc)
+	if err != nil {
+		log.Warn("读取签名函数源码失败", "err", err)
+		return
+	}
+	src, _ := v.(string)
+	if src == "" {
+		slot.SetScriptVersion("")
+		return
+	}
+	sum := sha256.Sum256([]byte(src))
+	version := hex.EncodeToString(sum[:])[:scriptVersionLen]
+	if prev := slot.ScriptVersion(); prev != "" && prev != version {
+		log.Warn("页面签名脚本版本已变化", "from", prev, "to", version)
+	}
+	slot.SetScriptVersion(version)
+
+	s.versions.mu.Lock()
+	defer s.versions.mu.Unlock()
+	if s.versions.seen[version] {
+		return
+	}
+	if len(s.versions.seen) > 0 {
+		log.Warn("检测到新的签名脚本版本，站点可能更新了签名算法", "version", version, "size", len(src))
+	} else {
+		log.Info("签名脚本版本", "version", version, "size", len(src))
+	}
+	if s.versions.seen == nil {
+		s.versions.seen = make(map[string]bool)
+	}
+	s.versions.seen[version] = true
+}
+
+// ScriptVersions 统计全部页面当前加载的签名脚本版本及各版本的页面数，并更新 go_sign_sign_script_pages 指标。
+func (s *Signer) ScriptVersions() map[string]int {
+	counts := make(map[string]int)
+	for _, pool := range s.pools {
+		for _, slot := range pool.AllSlots() {
+			if v := slot.ScriptVersion(); v != "" {
+				counts[v]++
+			}
+		}
+	}
+	name := s.opts.Profile.PlatformName()
+	// 已不再使用的版本置 0，而不是保留最后一次的页面数
+	s.versions.mu.Lock()
+	for v := range s.versions.seen {
+		scriptPages.Set(float64(counts[v]), name, v)
+	}
+	s.versions.mu.Unlock()
+	return counts
+}
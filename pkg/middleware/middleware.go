@@ -0,0 +1,93 @@
+// Package middleware 为签名端口的自定义中间件注册表。
+//
+// 本包位于 pkg 下，可由其他模块导入：嵌入本服务的团队可在自己的模块中实现 Factory，并在 init 中通过 Register 注册
+// （与 platform.Register 相同），在 cmd/server 中导入后即可在配置文件 server.middlewares 中按名称启用，
+// 中间件位于访问日志与 panic 恢复之后、全部签名路由之前。
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"go_sign/internal/config"
+)
+
+// Decoder 将中间件的配置项解码到 v，未配置时不修改 v。
+type Decoder func(v any) error
+
+// Factory 根据配置项创建中间件。
+type Factory func(options Decoder) (gin.HandlerFunc, error)
+
+var (
+	mu        sync.RWMutex
+	factories = map[string]Factory{"response_headers": responseHeaders}
+)
+
+// Register 注册中间件工厂，通常在包的 init 中调用。重复注册会 panic。
+func Register(name string, f Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	if _, ok := factories[name]; ok {
+		panic("middleware: 重复注册中间件 " + name)
+	}
+	factories[name] = f
+}
+
+// Names 返回全部已注册的中间件名称。
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(factories))
+	for n := range factories {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Build 按配置顺序创建中间件，名称未注册或配置项无效时返回错误，由 cmd/server 在启动时调用。
+func Build(list []config.Middleware) ([]gin.HandlerFunc, error) {
+	out := make([]gin.HandlerFunc, 0, len(list))
+	for i, m := range list {
+		mu.RLock()
+		f, ok := factories[m.Name]
+		mu.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("server.middlewares[%d]: 未注册的中间件 %q，可用的中间件: %v", i, m.Name, Names())
+		}
+		h, err := f(m.Decode)
+		if err != nil {
+			return nil, fmt.Errorf("server.middlewares[%d]: 创建中间件 %s 失败: %w", i, m.Name, err)
+		}
+		out = append(out, h)
+	}
+	return out, nil
+}
+
+// responseHeaders 为内置中间件 response_headers：为全部响应设置 options.headers 中的响应头，
+// 如 Strict-Transport-Security、X-Frame-Options 等团队统一要求的安全响应头。
+func responseHeaders(options Decoder) (gin.HandlerFunc, error) {
+	var opts struct {
+		Headers map[string]string `yaml:"headers"`
+	}
+	if err := options(&opts); err != nil {
+		return nil, err
+	}
+	if len(opts.Headers) == 0 {
+		return nil, errors.New("headers 不能为空")
+	}
+	headers := make(http.Header, len(opts.Headers))
+	for k, v := range opts.Headers {
+		headers.Set(k, v)
+	}
+	return func(c *gin.Context) {
+		for k, v := range headers {
+			c.Writer.Header()[k] = v
+		}
+		c.Next()
+	}, nil
+}